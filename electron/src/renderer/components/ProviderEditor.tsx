@@ -7,7 +7,7 @@ interface ProviderEditorProps {
   provider: ProviderConfig;
   onSave: (provider: ProviderConfig) => void;
   onTest: (provider: ProviderConfig) => Promise<{ success: boolean; latency?: number; error?: string }>;
-  onFetchModels?: (provider: ProviderConfig) => Promise<{ success: boolean; models?: Array<{ id: string; name: string }>; error?: string }>;
+  onFetchModels?: (provider: ProviderConfig) => Promise<{ success: boolean; models?: Array<{ id: string; name: string; vision?: boolean }>; error?: string }>;
   onCancel: () => void;
 }
 
@@ -111,7 +111,7 @@ export function ProviderEditor({ provider, onSave, onTest, onFetchModels, onCanc
                         id: m.id,
                         name: m.name || m.id,
                         enabled: true,
-                        supportsImageInput: false,
+                        supportsImageInput: m.vision === true,
                       }));
                       setConfig((prev) => ({ ...prev, models: newModels }));
                       setFetchResult({ success: true, message: t('settings.providerEditor.fetchSuccess', { count: result.models.length }) });
@@ -110,6 +110,7 @@ const PROVIDER_DEFAULT_MODELS: Record<string, string[]> = {
 
 const PROVIDER_KEYWORDS: Array<{ provider: string; keywords: string[] }> = [
   { provider: 'openrouter', keywords: ['openrouter'] },
+  { provider: 'ollama', keywords: ['ollama'] },
   { provider: 'deepseek', keywords: ['deepseek'] },
   { provider: 'zhipu', keywords: ['zhipu', 'glm', 'zai'] },
   { provider: 'anthropic', keywords: ['anthropic', 'claude'] },
@@ -210,7 +211,8 @@ export function useGateway() {
       throw new Error(parsed.error || 'Gateway stream error');
     }
 
-    if (parsed.delta) {
+    // 推理片段单独展示，不计入回复正文
+    if (parsed.delta && parsed.type !== 'reasoning_delta') {
       state.sawDelta = true;
       const merged = mergeStreamDelta(state.fullResponse, parsed.delta);
       state.fullResponse = merged.next;
@@ -47,6 +47,11 @@ type TimelineEntry =
       id: string;
       kind: 'text';
       text: string;
+    }
+  | {
+      id: string;
+      kind: 'reasoning';
+      text: string;
     };
 
 const iterationStatusPattern = /^Iteration\s+\d+$/i;
@@ -892,7 +897,7 @@ export function ChatView() {
     messages.forEach((message) => {
       addReferences(message.content);
       (message.timeline || []).forEach((entry) => {
-        if (entry.kind === 'text') {
+        if (entry.kind !== 'activity') {
           addReferences(entry.text || '');
           return;
         }
@@ -902,7 +907,7 @@ export function ChatView() {
     });
 
     streamingTimeline.forEach((entry) => {
-      if (entry.kind === 'text') {
+      if (entry.kind !== 'activity') {
         addReferences(entry.text || '');
         return;
       }
@@ -1044,6 +1049,26 @@ export function ChatView() {
     });
   };
 
+  const appendReasoningToTimeline = (sessionKey: string, chunk: string) => {
+    if (!chunk) {
+      return;
+    }
+    setStreamingTimelineForSession(sessionKey, (prev) => {
+      const last = prev[prev.length - 1];
+      if (last && last.kind === 'reasoning') {
+        return [...prev.slice(0, -1), { ...last, text: last.text + chunk }];
+      }
+      return [
+        ...prev,
+        {
+          id: nextEntryID('reasoning'),
+          kind: 'reasoning',
+          text: chunk
+        }
+      ];
+    });
+  };
+
   const nextEntryID = (prefix: string) => {
     entrySeqRef.current += 1;
     return `${prefix}-${entrySeqRef.current}`;
@@ -1317,7 +1342,7 @@ export function ChatView() {
 
   const normalizeStoredTimeline = (
     entries: Array<{
-      kind: 'activity' | 'text';
+      kind: 'activity' | 'text' | 'reasoning';
       activity?: {
         type: 'status' | 'tool_start' | 'tool_result' | 'skill_start' | 'skill_result' | 'error';
         summary: string;
@@ -1363,6 +1388,15 @@ export function ChatView() {
             text: entry.text
           });
         }
+        return;
+      }
+
+      if (entry.kind === 'reasoning' && entry.text) {
+        normalized.push({
+          id: `${prefix}-reasoning-${index}`,
+          kind: 'reasoning',
+          text: entry.text
+        });
       }
     });
 
@@ -1549,6 +1583,10 @@ export function ChatView() {
           appendTextToTimeline(requestSessionKey, delta);
         },
         (event) => {
+          if (event.type === 'reasoning_delta') {
+            appendReasoningToTimeline(requestSessionKey, event.delta || '');
+            return;
+          }
           if (event.type === 'tool_result' && event.toolName === 'spawn') {
             waitingForBackgroundResult = true;
           }
@@ -1875,6 +1913,21 @@ export function ChatView() {
     const activityItems = items.filter(
       (entry): entry is Extract<TimelineEntry, { kind: 'activity' }> => entry.kind === 'activity'
     );
+    const reasoningItems = items.filter(
+      (entry): entry is Extract<TimelineEntry, { kind: 'reasoning' }> => entry.kind === 'reasoning'
+    );
+    const thinkTitle = language === 'zh' ? '思考' : 'Thinking';
+
+    const renderReasoningItem = (entry: Extract<TimelineEntry, { kind: 'reasoning' }>) => (
+      <details key={entry.id} className="overflow-hidden rounded-lg border border-border bg-secondary">
+        <summary className="cursor-pointer list-none px-3 py-2 text-sm font-medium text-secondary-foreground">
+          {thinkTitle}
+        </summary>
+        <div className="border-t border-border/60 px-3 py-2 text-sm text-muted">
+          <MarkdownRenderer content={entry.text} onFileLinkClick={handleFileLinkPreview} />
+        </div>
+      </details>
+    );
 
     const renderActivityItem = (
       entry: Extract<TimelineEntry, { kind: 'activity' }>,
@@ -1915,6 +1968,7 @@ export function ChatView() {
     if (!streaming) {
       return (
         <div className="space-y-3">
+          {reasoningItems.map((entry) => renderReasoningItem(entry))}
           {activityItems.length > 0 && (
             <details className="overflow-hidden rounded-lg border border-border bg-secondary">
               <summary className="cursor-pointer list-none px-4 py-3">
@@ -1937,17 +1991,23 @@ export function ChatView() {
     return (
       <div className="space-y-3">
         <div className="space-y-2">
-          {items.map((entry, index) =>
-            entry.kind === 'activity' ? renderActivityItem(entry, index === openIndex) : (
+          {items.map((entry, index) => {
+            if (entry.kind === 'activity') {
+              return renderActivityItem(entry, index === openIndex);
+            }
+            if (entry.kind === 'reasoning') {
+              return renderReasoningItem(entry);
+            }
+            return (
               <div key={entry.id} className="text-foreground">
                 {renderMarkdownWithActions(entry.text, entry.id)}
               </div>
-            )
-          )}
+            );
+          })}
         </div>
       </div>
     );
-  }, [getActivityLabel, renderFileActions, renderMarkdownWithActions]);
+  }, [getActivityLabel, handleFileLinkPreview, language, renderFileActions, renderMarkdownWithActions]);
 
   const browserCopilotURL = browserActivityContext.latestURL || extractFirstURL(browserCopilotOutput);
   const browserCopilotVisible = Boolean(
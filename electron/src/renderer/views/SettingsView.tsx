@@ -110,7 +110,7 @@ export function SettingsView() {
         if (config.providers) {
           const knownProviders = new Set([
             'openrouter', 'anthropic', 'openai', 'deepseek', 'zhipu',
-            'groq', 'gemini', 'dashscope', 'moonshot', 'minimax', 'vllm'
+            'groq', 'gemini', 'dashscope', 'moonshot', 'minimax', 'vllm', 'ollama'
           ]);
           const loadedProviders: ProviderConfig[] = [];
           Object.entries(config.providers).forEach(([key, value]: [string, any]) => {
@@ -410,7 +410,7 @@ export function SettingsView() {
       });
 
       if (response.ok) {
-        const data = await response.json() as { models?: Array<{ id: string; name: string }> };
+        const data = await response.json() as { models?: Array<{ id: string; name: string; vision?: boolean }> };
         return { success: true, models: data.models || [] };
       } else {
         const error = await response.text();
@@ -0,0 +1,25 @@
+package agent
+
+import (
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/pkg/tools"
+)
+
+// ValidateRuntimeConfig 用各运行时组件的构建器做 config.Validate 之外的深度校验（正则、代理、后端可用性等）
+func ValidateRuntimeConfig(cfg *config.Config) config.ValidationErrors {
+	var errs config.ValidationErrors
+	check := func(path string, err error) {
+		if err != nil {
+			errs = append(errs, config.ValidationError{Path: path, Message: err.Error()})
+		}
+	}
+
+	_, err := BuildToolPolicy(cfg)
+	check("tools.policy", err)
+	check("tools.web.search", ValidateWebSearchConfig(cfg))
+	_, err = BuildEgressGuard(cfg)
+	check("tools.egress", err)
+	check("tools.retrieval", ValidateRetrievalConfig(cfg))
+	check("tools.exec", tools.ValidateExecSandboxOptions(BuildExecSandboxOptions(cfg.Tools.Exec)))
+	return errs
+}
@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/media"
+	"github.com/Lichas/maxclaw/internal/memory"
 	"github.com/Lichas/maxclaw/internal/providers"
 )
 
@@ -71,20 +73,21 @@ func (b *ContextBuilder) SetExecutionMode(mode string) {
 
 // BuildMessages 构建消息列表
 func (b *ContextBuilder) BuildMessages(history []providers.Message, currentMessage string, media *bus.MediaAttachment, channel, chatID string) []providers.Message {
-	return b.BuildMessagesWithSkillRefs(history, currentMessage, nil, media, channel, chatID)
+	return b.BuildMessagesWithSkillRefs(history, currentMessage, nil, media, memory.ScopeContext{Channel: channel, ChatID: chatID})
 }
 
+// BuildMessagesWithSkillRefs 构建消息列表；scope 决定注入哪些作用域的长期记忆
 func (b *ContextBuilder) BuildMessagesWithSkillRefs(
 	history []providers.Message,
 	currentMessage string,
 	explicitSkillRefs []string,
 	media *bus.MediaAttachment,
-	channel, chatID string,
+	scope memory.ScopeContext,
 ) []providers.Message {
 	messages := make([]providers.Message, 0)
 
 	// 系统提示
-	systemPrompt := b.buildSystemPrompt(channel, chatID, currentMessage, explicitSkillRefs)
+	systemPrompt := b.buildSystemPrompt(scope, currentMessage, explicitSkillRefs)
 	messages = append(messages, providers.Message{
 		Role:    "system",
 		Content: systemPrompt,
@@ -132,10 +135,16 @@ func normalizeInboundUserContent(currentMessage string, media *bus.MediaAttachme
 	}
 }
 
+// inboundDocumentMaxChars 入站文档附件直接放入上下文的最大字符数，超出部分由 read_file 分页读取
+const inboundDocumentMaxChars = 20000
+
 func buildInboundContentParts(content string, media *bus.MediaAttachment) []providers.ContentPart {
-	if media == nil || media.Type != "image" {
+	if media == nil {
 		return nil
 	}
+	if media.Type != "image" {
+		return buildInboundDocumentParts(content, media)
+	}
 
 	text := strings.TrimSpace(content)
 	if text == "" {
@@ -156,6 +165,26 @@ func buildInboundContentParts(content string, media *bus.MediaAttachment) []prov
 	}
 }
 
+// buildInboundDocumentParts 将 PDF/Office/EPUB 附件提取为文本部分；无法解析时退回纯文本消息
+func buildInboundDocumentParts(content string, attachment *bus.MediaAttachment) []providers.ContentPart {
+	path := strings.TrimSpace(attachment.LocalPath)
+	if path == "" || media.DetectDocumentFormat(path, attachment.MimeType) == "" {
+		return nil
+	}
+	text, err := media.DocumentContext(path, attachment.Filename, attachment.MimeType, inboundDocumentMaxChars)
+	if err != nil {
+		return nil
+	}
+	prompt := strings.TrimSpace(content)
+	if prompt == "" {
+		prompt = "User sent a document."
+	}
+	return []providers.ContentPart{
+		{Type: "text", Text: prompt},
+		{Type: "text", Text: text},
+	}
+}
+
 func isMediaPlaceholder(content string) bool {
 	normalized := strings.TrimSpace(content)
 	switch normalized {
@@ -166,11 +195,12 @@ func isMediaPlaceholder(content string) bool {
 	}
 }
 
-// AddAssistantMessage 添加助手消息
-func (b *ContextBuilder) AddAssistantMessage(messages []providers.Message, content string, toolCalls []providers.ToolCall) []providers.Message {
+// AddAssistantMessage 添加助手消息，reasoning 为本次回复的推理块
+func (b *ContextBuilder) AddAssistantMessage(messages []providers.Message, content string, toolCalls []providers.ToolCall, reasoning []providers.ReasoningBlock) []providers.Message {
 	msg := providers.Message{
-		Role:    "assistant",
-		Content: content,
+		Role:      "assistant",
+		Content:   content,
+		Reasoning: reasoning,
 	}
 	// 如果有工具调用，正确设置
 	if len(toolCalls) > 0 {
@@ -191,7 +221,7 @@ func (b *ContextBuilder) AddToolResult(messages []providers.Message, toolCallID,
 }
 
 // buildSystemPrompt 构建系统提示
-func (b *ContextBuilder) buildSystemPrompt(channel, chatID, currentMessage string, explicitSkillRefs []string) string {
+func (b *ContextBuilder) buildSystemPrompt(scope memory.ScopeContext, currentMessage string, explicitSkillRefs []string) string {
 	var parts []string
 
 	// 1. 嵌入的基础系统提示
@@ -214,11 +244,8 @@ func (b *ContextBuilder) buildSystemPrompt(channel, chatID, currentMessage strin
 		parts = append(parts, "## User Information\n"+string(content))
 	}
 
-	// 5. 读取 MEMORY.md
-	memoryPath := filepath.Join(b.workspace, "memory", "MEMORY.md")
-	if content, err := os.ReadFile(memoryPath); err == nil {
-		parts = append(parts, "## Long-term Memory\n"+string(content))
-	}
+	// 5. 读取适用于当前对话的 MEMORY.md（全局 → agent → 用户 → 会话）
+	parts = append(parts, b.buildMemorySections(scope)...)
 
 	// 6. 读取 heartbeat.md（OpenClaw 风格：短周期状态/优先级）
 	// 优先读取 memory/heartbeat.md，兼容根目录 heartbeat.md
@@ -232,11 +259,11 @@ func (b *ContextBuilder) buildSystemPrompt(channel, chatID, currentMessage strin
 	}
 
 	// 8. 动态环境信息
-	envSection := b.buildEnvironmentSection(channel, chatID)
+	envSection := b.buildEnvironmentSection(scope.Channel, scope.ChatID)
 	parts = append(parts, envSection)
 
 	// 9. 两层内存提示（HISTORY.md 不自动注入上下文，按需 grep）
-	parts = append(parts, b.buildMemoryHintsSection())
+	parts = append(parts, b.buildMemoryHintsSection(scope))
 
 	return strings.Join(parts, "\n\n")
 }
@@ -464,15 +491,40 @@ func boolYesNo(v bool) string {
 	return "no"
 }
 
-func (b *ContextBuilder) buildMemoryHintsSection() string {
+// buildMemorySections 按从宽到窄的顺序读取适用作用域的 MEMORY.md，不存在的文件跳过
+func (b *ContextBuilder) buildMemorySections(scope memory.ScopeContext) []string {
+	var sections []string
+	for _, s := range scope.Scopes() {
+		content, err := os.ReadFile(s.MemoryPath(b.workspace))
+		if err != nil {
+			continue
+		}
+		if s.IsGlobal() {
+			sections = append(sections, "## Long-term Memory\n"+string(content))
+			continue
+		}
+		sections = append(sections, fmt.Sprintf("## Long-term Memory (%s)\n%s", s, content))
+	}
+	return sections
+}
+
+func (b *ContextBuilder) buildMemoryHintsSection(scope memory.ScopeContext) string {
 	memoryPath := filepath.Join(b.workspace, "memory", "MEMORY.md")
 	historyPath := filepath.Join(b.workspace, "memory", "HISTORY.md")
-	return strings.Join([]string{
+	lines := []string{
 		"## Memory System",
 		fmt.Sprintf("- Long-term memory: %s (always loaded)", memoryPath),
+	}
+	for _, s := range scope.Scopes()[1:] {
+		lines = append(lines, fmt.Sprintf("- %s memory: %s (%s; more specific than the global memory)", s, s.MemoryPath(b.workspace), s.Description()))
+	}
+	lines = append(lines,
+		"- Use the memory tool to add, update or remove single facts, preferences, lessons and todos; they are rendered into the long-term memory with their ids",
+		"- Memory tool scopes: global is shared by every chat, user follows the current user across chats, chat stays in this conversation, agent belongs to this agent profile. Facts default to global; preferences, lessons and todos default to user. Keep personal details out of global memory",
 		fmt.Sprintf("- History log: %s (append-only, grep-searchable, not auto-loaded)", historyPath),
 		fmt.Sprintf("- To recall past events, use exec with grep, for example: grep -i \"keyword\" %s", historyPath),
-	}, "\n")
+	)
+	return strings.Join(lines, "\n")
 }
 
 func (b *ContextBuilder) sourceSearchRoots(absWorkspace string) []string {
@@ -700,7 +752,11 @@ func globPaths(pattern string) []string {
 
 // BuildSystemPromptWithPlan creates system prompt with plan context
 func (cb *ContextBuilder) BuildSystemPromptWithPlan(plan *Plan) string {
-	basePrompt := cb.buildSystemPrompt("", "", "", nil)
+	return cb.buildSystemPromptWithPlan(memory.ScopeContext{}, plan)
+}
+
+func (cb *ContextBuilder) buildSystemPromptWithPlan(scope memory.ScopeContext, plan *Plan) string {
+	basePrompt := cb.buildSystemPrompt(scope, "", nil)
 
 	if plan == nil {
 		return basePrompt
@@ -731,12 +787,12 @@ func (cb *ContextBuilder) BuildMessagesWithPlanAndSkillRefs(
 	userContent string,
 	skillRefs []string,
 	media *bus.MediaAttachment,
-	channel, chatID string,
+	scope memory.ScopeContext,
 	plan *Plan,
 ) []providers.Message {
-	systemPrompt := cb.BuildSystemPromptWithPlan(plan)
+	systemPrompt := cb.buildSystemPromptWithPlan(scope, plan)
 	// Reuse existing logic from BuildMessagesWithSkillRefs but with our systemPrompt
-	messages := cb.BuildMessagesWithSkillRefs(history, userContent, skillRefs, media, channel, chatID)
+	messages := cb.BuildMessagesWithSkillRefs(history, userContent, skillRefs, media, scope)
 	if len(messages) > 0 && messages[0].Role == "system" {
 		messages[0].Content = systemPrompt
 	}
@@ -568,24 +568,11 @@ func getModelContextLength(model string, configContextLength int) int {
 	if configContextLength > 0 {
 		return configContextLength
 	}
-	// Default context lengths for known models
-	modelLower := strings.ToLower(model)
-	switch {
-	case strings.Contains(modelLower, "claude-3-opus"):
-		return 200000
-	case strings.Contains(modelLower, "claude-3-sonnet"):
-		return 200000
-	case strings.Contains(modelLower, "claude-3-haiku"):
-		return 200000
-	case strings.Contains(modelLower, "gpt-4-turbo"):
-		return 128000
-	case strings.Contains(modelLower, "gpt-4"):
-		return 8192
-	case strings.Contains(modelLower, "gpt-3.5-turbo"):
-		return 16385
-	default:
-		return 128000 // Conservative default
+	// 模型目录（含 Ollama /api/show 等运行时探测结果）
+	if info, ok := providers.LookupModel(providers.DetectProviderName(model), model); ok && info.ContextWindow > 0 {
+		return info.ContextWindow
 	}
+	return 128000 // Conservative default
 }
 
 func estimateMessagesTokensRough(messages []CompressorMessage) int {
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -204,3 +205,42 @@ func TestContextBuilderBuildsImagePartsForInboundMedia(t *testing.T) {
 	assert.Equal(t, "https://example.com/image.png", messages[1].Parts[1].ImageURL)
 	assert.Equal(t, "/tmp/image.png", messages[1].Parts[1].ImagePath)
 }
+
+func TestContextBuilderBuildsDocumentPartsForInboundMedia(t *testing.T) {
+	workspace := t.TempDir()
+	builder := NewContextBuilder(workspace)
+
+	content := "BT /F1 12 Tf 72 700 Td (Invoice total: 42 USD) Tj ET"
+	pdf := "%PDF-1.4\n" +
+		"1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n" +
+		"2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n" +
+		"3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>\nendobj\n" +
+		fmt.Sprintf("4 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(content), content) +
+		"5 0 obj\n<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>\nendobj\n" +
+		"trailer\n<< /Root 1 0 R >>\n%%EOF\n"
+	path := filepath.Join(workspace, ".uploads", "20260301_abc.pdf")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(pdf), 0644))
+
+	messages := builder.BuildMessages(nil, "[Document]", &bus.MediaAttachment{
+		Type:      "document",
+		Filename:  "invoice.pdf",
+		LocalPath: path,
+		MimeType:  "application/pdf",
+	}, "telegram", "123")
+
+	require.Len(t, messages, 2)
+	assert.Equal(t, "User sent a document.", messages[1].Content)
+	require.Len(t, messages[1].Parts, 2)
+	assert.Equal(t, "User sent a document.", messages[1].Parts[0].Text)
+	assert.Equal(t, "text", messages[1].Parts[1].Type)
+	assert.Contains(t, messages[1].Parts[1].Text, `Attached document "invoice.pdf"`)
+	assert.Contains(t, messages[1].Parts[1].Text, "## Page 1\n\nInvoice total: 42 USD")
+
+	// 无法解析的附件保持纯文本消息
+	messages = builder.BuildMessages(nil, "see file", &bus.MediaAttachment{
+		Type:      "document",
+		LocalPath: filepath.Join(workspace, "missing.pdf"),
+	}, "telegram", "123")
+	assert.Empty(t, messages[1].Parts)
+}
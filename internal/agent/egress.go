@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/logging"
+	"github.com/Lichas/maxclaw/pkg/tools"
+)
+
+// BuildEgressGuard converts tools.egress config to an egress guard whose
+// blocked requests are written to the audit log.
+func BuildEgressGuard(cfg *config.Config) (*tools.EgressGuard, error) {
+	egress := cfg.Tools.Egress
+	return tools.NewEgressGuard(tools.EgressOptions{
+		AllowPrivate: egress.AllowPrivate,
+		AllowCIDRs:   append([]string(nil), egress.AllowCIDRs...),
+		AllowDomains: append([]string(nil), egress.AllowDomains...),
+		DenyDomains:  append([]string(nil), egress.DenyDomains...),
+		Proxy:        egress.Proxy,
+		Audit:        auditEgressBlock,
+	})
+}
+
+func auditEgressBlock(ctx context.Context, block tools.EgressBlock) {
+	lg := logging.Get()
+	if lg == nil || lg.Audit == nil {
+		return
+	}
+	agentName := block.Agent
+	if agentName == "" {
+		agentName = config.DefaultAgentName
+	}
+	lg.Audit.WarnContext(ctx, "egress blocked", "agent", agentName, "source", block.Source,
+		"url", logging.Truncate(block.URL, 300), "host", block.Host, "ip", block.IP, "reason", block.Reason)
+}
+
+// defaultEgressGuard blocks private ranges until the configured guard is applied.
+func defaultEgressGuard() *tools.EgressGuard {
+	guard, _ := tools.NewEgressGuard(tools.EgressOptions{Audit: auditEgressBlock})
+	return guard
+}
+
+// SetEgressGuard applies the outbound network guard to web_fetch, browser and
+// MCP HTTP servers. Profile loops receive a guard narrowed by their domain lists.
+func (a *AgentLoop) SetEgressGuard(guard *tools.EgressGuard) {
+	if guard == nil {
+		guard = defaultEgressGuard()
+	}
+	a.runtimeMu.Lock()
+	defer a.runtimeMu.Unlock()
+	a.egress = guard
+	if a.mcpConnector != nil {
+		a.mcpConnector.SetEgressGuard(guard)
+	}
+}
+
+func (a *AgentLoop) egressGuardSnapshot() *tools.EgressGuard {
+	a.runtimeMu.RLock()
+	defer a.runtimeMu.RUnlock()
+	return a.egress
+}
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildEgressGuardFromConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Tools.Egress = config.EgressConfig{
+		AllowCIDRs:  []string{"10.0.5.0/24"},
+		DenyDomains: []string{"metadata.internal"},
+		Proxy:       "http://proxy.local:3128",
+	}
+
+	guard, err := BuildEgressGuard(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "http://proxy.local:3128", guard.ProxyURL())
+	assert.NoError(t, guard.CheckURL(context.Background(), "web_fetch", "http://10.0.5.7/"))
+	assert.Error(t, guard.CheckURL(context.Background(), "web_fetch", "http://10.0.6.7/"))
+
+	cfg.Tools.Egress.Proxy = "gopher://proxy"
+	_, err = BuildEgressGuard(cfg)
+	assert.Error(t, err)
+}
+
+func TestToolContextCarriesProfileEgressGuard(t *testing.T) {
+	base := NewAgentLoop(bus.NewMessageBus(10), &staticProvider{}, t.TempDir(), "test-model", 3, "",
+		tools.WebFetchOptions{}, config.ExecToolConfig{Timeout: 5}, false, nil, nil, false)
+
+	// 未配置时默认拦截内网地址
+	ctx := base.toolContext(context.Background(), "cli", "direct", "cli:direct", "user")
+	guard := tools.EgressGuardFrom(ctx)
+	require.NotNil(t, guard)
+	assert.Error(t, guard.CheckURL(ctx, "web_fetch", "http://127.0.0.1:8080/"))
+
+	cfg := config.DefaultConfig()
+	cfg.Tools.Egress.DenyDomains = []string{"blocked.example"}
+	configured, err := BuildEgressGuard(cfg)
+	require.NoError(t, err)
+	base.SetEgressGuard(configured)
+
+	profile := NewProfileAgentLoop(base, config.ResolvedAgent{
+		Name:        "researcher",
+		Workspace:   t.TempDir(),
+		DenyDomains: []string{"social.example"},
+	}, nil)
+	profileGuard := tools.EgressGuardFrom(profile.toolContext(context.Background(), "cli", "direct", "cli:direct", "user"))
+	require.NotNil(t, profileGuard)
+	assert.Equal(t, "researcher", profileGuard.Options().Agent)
+	assert.ErrorContains(t, profileGuard.CheckURL(ctx, "web_fetch", "https://www.social.example/"), "denied")
+	assert.ErrorContains(t, profileGuard.CheckURL(ctx, "web_fetch", "https://blocked.example/"), "denied")
+}
@@ -212,6 +212,16 @@ func (ec *ErrorClassifier) ClassifyError(
 		})
 	}
 
+	// Gemini: google.rpc status / ErrorInfo reason is more precise than the HTTP code
+	if reason, ok := classifyGoogleRPCError(body, errorMsg); ok {
+		return result(reason)
+	}
+
+	// Safety-filter blocks are deterministic; retrying the same prompt won't help
+	if strings.Contains(errorMsg, "blocked by safety") {
+		return result(ErrorReasonFormatError)
+	}
+
 	// 2. HTTP status code classification
 	if statusCode > 0 {
 		classified := ec.classifyByStatus(statusCode, errorMsg, body, provider, model, approxTokens, contextLength, numMessages, result)
@@ -458,6 +468,51 @@ func (ec *ErrorClassifier) classifyByMessage(
 	return nil
 }
 
+// classifyGoogleRPCError maps Google API error bodies
+// ({"error":{"code":..,"status":"RESOURCE_EXHAUSTED","details":[{"reason":"API_KEY_INVALID"}]}})
+// to error reasons
+func classifyGoogleRPCError(body map[string]interface{}, errorMsg string) (ErrorReason, bool) {
+	errObj, ok := body["error"].(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	details, _ := errObj["details"].([]interface{})
+	for _, item := range details {
+		detail, _ := item.(map[string]interface{})
+		switch reason, _ := detail["reason"].(string); reason {
+		case "API_KEY_INVALID", "API_KEY_SERVICE_BLOCKED", "ACCESS_TOKEN_EXPIRED":
+			return ErrorReasonAuth, true
+		case "RATE_LIMIT_EXCEEDED":
+			return ErrorReasonRateLimit, true
+		case "BILLING_DISABLED":
+			return ErrorReasonBilling, true
+		}
+	}
+
+	status, _ := errObj["status"].(string)
+	switch status {
+	case "UNAUTHENTICATED", "PERMISSION_DENIED":
+		return ErrorReasonAuth, true
+	case "RESOURCE_EXHAUSTED":
+		return ErrorReasonRateLimit, true
+	case "NOT_FOUND":
+		return ErrorReasonModelNotFound, true
+	case "FAILED_PRECONDITION":
+		// e.g. "User location is not supported" / free tier unavailable, billing required
+		if strings.Contains(errorMsg, "billing") || strings.Contains(errorMsg, "not supported") || strings.Contains(errorMsg, "not available") {
+			return ErrorReasonBilling, true
+		}
+		return ErrorReasonFormatError, true
+	case "UNAVAILABLE":
+		return ErrorReasonOverloaded, true
+	case "INTERNAL":
+		return ErrorReasonServerError, true
+	case "DEADLINE_EXCEEDED":
+		return ErrorReasonTimeout, true
+	}
+	return "", false
+}
+
 // matchesAnyPattern checks if error message matches any pattern
 func (ec *ErrorClassifier) matchesAnyPattern(errorMsg string, patterns []string) bool {
 	for _, pattern := range patterns {
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyErrorMapsGoogleRPCStatus(t *testing.T) {
+	ec := NewErrorClassifier()
+	classify := func(msg string) *ClassifiedError {
+		return ec.ClassifyError(errors.New(msg), "gemini", "gemini-2.5-pro", 1000, 1000000, 4)
+	}
+
+	// API key 错误在 Gemini 上是 400 INVALID_ARGUMENT，需要按 details.reason 识别
+	ce := classify(`chat request failed provider=gemini: gemini API error (status code 400): {"error":{"code":400,"message":"API key not valid.","status":"INVALID_ARGUMENT","details":[{"reason":"API_KEY_INVALID"}]}}`)
+	assert.Equal(t, ErrorReasonAuth, ce.Reason)
+	assert.Equal(t, 400, ce.StatusCode)
+	assert.Equal(t, "API key not valid.", ce.Message)
+	assert.True(t, ce.ShouldRotateCredential)
+
+	ce = classify(`gemini API error (status code 429): {"error":{"code":429,"message":"Quota exceeded","status":"RESOURCE_EXHAUSTED"}}`)
+	assert.Equal(t, ErrorReasonRateLimit, ce.Reason)
+	assert.True(t, ce.Retryable)
+
+	ce = classify(`gemini API error (status code 404): {"error":{"code":404,"message":"models/gemini-9 is not found","status":"NOT_FOUND"}}`)
+	assert.Equal(t, ErrorReasonModelNotFound, ce.Reason)
+
+	ce = classify(`gemini API error (status code 400): {"error":{"code":400,"message":"User location is not supported for the API use.","status":"FAILED_PRECONDITION"}}`)
+	assert.Equal(t, ErrorReasonBilling, ce.Reason)
+
+	ce = classify(`gemini API error (status code 503): {"error":{"code":503,"message":"The model is overloaded.","status":"UNAVAILABLE"}}`)
+	assert.Equal(t, ErrorReasonOverloaded, ce.Reason)
+	assert.True(t, ce.IsTransient())
+
+	// 上下文超限仍由 400 + 消息模式识别
+	ce = classify(`gemini API error (status code 400): {"error":{"code":400,"message":"The input token count exceeds the maximum number of tokens allowed","status":"INVALID_ARGUMENT"}}`)
+	assert.Equal(t, ErrorReasonContextOverflow, ce.Reason)
+
+	ce = classify("chat request failed provider=gemini: gemini prompt blocked by safety filters: blockReason=SAFETY")
+	assert.Equal(t, ErrorReasonFormatError, ce.Reason)
+	assert.False(t, ce.Retryable)
+}
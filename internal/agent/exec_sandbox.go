@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/pkg/tools"
+)
+
+// BuildExecSandboxOptions converts tools.exec config into exec backend options.
+func BuildExecSandboxOptions(cfg config.ExecToolConfig) tools.ExecSandboxOptions {
+	opts := tools.ExecSandboxOptions{
+		Backend:        cfg.Backend,
+		CPUs:           cfg.CPUs,
+		MemoryMB:       cfg.MemoryMB,
+		MaxOutputBytes: cfg.MaxOutputBytes,
+		Image:          cfg.Image,
+	}
+	if cfg.Network != nil {
+		network := *cfg.Network
+		opts.Network = &network
+	}
+	if len(cfg.ModeBackends) > 0 {
+		opts.ModeBackends = make(map[string]string, len(cfg.ModeBackends))
+		for mode, backend := range cfg.ModeBackends {
+			opts.ModeBackends[strings.ToLower(strings.TrimSpace(mode))] = backend
+		}
+	}
+	return opts
+}
+
+// ValidateExecConfig checks tools.exec and every profile execBackend before startup.
+func ValidateExecConfig(cfg *config.Config) error {
+	if cfg == nil {
+		return nil
+	}
+	if err := tools.ValidateExecSandboxOptions(BuildExecSandboxOptions(cfg.Tools.Exec)); err != nil {
+		return err
+	}
+	for _, name := range cfg.AgentProfileNames() {
+		resolved, ok := cfg.ResolveAgent(name)
+		if !ok || strings.TrimSpace(resolved.ExecBackend) == "" {
+			continue
+		}
+		if _, err := tools.NewExecBackend(resolved.ExecBackend); err != nil {
+			return fmt.Errorf("agents.profiles.%s.execBackend: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// profileExecConfig applies a profile execBackend: an explicit backend wins over modeBackends.
+func profileExecConfig(base config.ExecToolConfig, backend string) config.ExecToolConfig {
+	backend = strings.TrimSpace(backend)
+	if backend == "" {
+		return base
+	}
+	out := base
+	out.Backend = backend
+	out.ModeBackends = nil
+	return out
+}
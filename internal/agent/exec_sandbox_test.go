@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildExecSandboxOptions(t *testing.T) {
+	off := false
+	opts := BuildExecSandboxOptions(config.ExecToolConfig{
+		Backend:      "bwrap",
+		ModeBackends: map[string]string{" Auto ": "docker"},
+		Network:      &off,
+		CPUs:         2,
+		MemoryMB:     1024,
+	})
+	assert.Equal(t, "bwrap", opts.Backend)
+	assert.Equal(t, "docker", opts.ModeBackends["auto"])
+	if assert.NotNil(t, opts.Network) {
+		assert.False(t, *opts.Network)
+	}
+	assert.Equal(t, 2.0, opts.CPUs)
+	assert.Equal(t, 1024, opts.MemoryMB)
+}
+
+func TestProfileExecConfigOverridesModeBackends(t *testing.T) {
+	base := config.ExecToolConfig{Timeout: 30, Backend: "host", ModeBackends: map[string]string{"auto": "docker"}}
+	assert.Equal(t, base, profileExecConfig(base, ""))
+
+	out := profileExecConfig(base, "bwrap")
+	assert.Equal(t, "bwrap", out.Backend)
+	assert.Nil(t, out.ModeBackends)
+	assert.Equal(t, 30, out.Timeout)
+	assert.Equal(t, "docker", base.ModeBackends["auto"])
+}
+
+func TestValidateExecConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	assert.NoError(t, ValidateExecConfig(cfg))
+
+	cfg.Agents.Profiles = map[string]config.AgentProfile{"coder": {ExecBackend: "qemu"}}
+	assert.ErrorContains(t, ValidateExecConfig(cfg), "agents.profiles.coder.execBackend")
+
+	cfg.Agents.Profiles = nil
+	cfg.Tools.Exec.Backend = "docker"
+	cfg.Tools.Exec.ModeBackends = map[string]string{"safe": "nope"}
+	assert.ErrorContains(t, ValidateExecConfig(cfg), "modeBackends.safe")
+}
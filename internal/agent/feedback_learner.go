@@ -25,6 +25,8 @@ type FeedbackLesson struct {
 	Occurrences   int       `json:"occurrences"`
 	LastApplied   time.Time `json:"last_applied,omitempty"`
 	Tags          []string  `json:"tags,omitempty"`
+	// Scope 教训所属的记忆作用域（global / user:<id> / chat:<channel>:<id>），为空视为 global
+	Scope string `json:"scope,omitempty"`
 }
 
 // FeedbackLearner learns from user feedback and applies lessons to future tasks
@@ -57,8 +59,10 @@ func NewFeedbackLearner(workspace string) *FeedbackLearner {
 	return fl
 }
 
-// RecordFeedback records user feedback and extracts lesson
+// RecordFeedback records user feedback and extracts lesson.
+// The lesson is kept in the personal scope of the conversation (user, then chat).
 func (fl *FeedbackLearner) RecordFeedback(
+	scope memory.ScopeContext,
 	result *FeedbackResult,
 	taskContext string,
 	agentOutput string,
@@ -78,7 +82,11 @@ func (fl *FeedbackLearner) RecordFeedback(
 	lesson := fl.generateLesson(result, agentOutput, userFeedback)
 
 	// Create lesson key for deduplication
+	lessonScope := scope.PersonalScope()
 	key := fl.generateLessonKey(taskType, result.IssueType, lesson)
+	if !lessonScope.IsGlobal() {
+		key = lessonScope.String() + "|" + key
+	}
 
 	if existing, ok := fl.lessons[key]; ok {
 		// Update existing lesson
@@ -99,6 +107,7 @@ func (fl *FeedbackLearner) RecordFeedback(
 		Lesson:           lesson,
 		Occurrences:      1,
 		Tags:             fl.extractTags(taskContext, result),
+		Scope:            lessonScope.String(),
 	}
 
 	fl.lessons[key] = newLesson
@@ -114,14 +123,17 @@ func (fl *FeedbackLearner) RecordFeedback(
 	return newLesson
 }
 
-// GetRelevantLessons retrieves lessons relevant to a task
-func (fl *FeedbackLearner) GetRelevantLessons(taskType string, maxResults int) []*FeedbackLesson {
+// GetRelevantLessons retrieves lessons relevant to a task from the scopes of the conversation
+func (fl *FeedbackLearner) GetRelevantLessons(scope memory.ScopeContext, taskType string, maxResults int) []*FeedbackLesson {
 	fl.mu.RLock()
 	defer fl.mu.RUnlock()
 
 	var relevant []*FeedbackLesson
 
 	for _, lesson := range fl.lessons {
+		if !scope.Allows(lesson.memoryScope()) {
+			continue
+		}
 		// Match by task type or tags
 		if fl.matchesTaskType(lesson.TaskType, taskType) ||
 			fl.hasMatchingTags(lesson.Tags, taskType) {
@@ -139,8 +151,8 @@ func (fl *FeedbackLearner) GetRelevantLessons(taskType string, maxResults int) [
 }
 
 // BuildSystemPromptEnhancement generates prompt text from relevant lessons
-func (fl *FeedbackLearner) BuildSystemPromptEnhancement(taskType string) string {
-	lessons := fl.GetRelevantLessons(taskType, 3)
+func (fl *FeedbackLearner) BuildSystemPromptEnhancement(scope memory.ScopeContext, taskType string) string {
+	lessons := fl.GetRelevantLessons(scope, taskType, 3)
 	if len(lessons) == 0 {
 		return ""
 	}
@@ -406,8 +418,8 @@ func (fl *FeedbackLearner) sortByRelevance(lessons []*FeedbackLesson) {
 // Persistence methods
 
 func (fl *FeedbackLearner) persistToMemory(lesson *FeedbackLesson) {
-	// Write to MEMORY.md in the workspace
-	store := memory.NewStore(fl.workspace)
+	// Write to the MEMORY.md of the lesson's scope
+	store := memory.NewScopedStore(fl.workspace, lesson.memoryScope())
 
 	entry := fmt.Sprintf("\n## User Feedback Lesson [%s]\n\n"+
 		"- **Task Type**: %s\n"+
@@ -429,6 +441,15 @@ func (fl *FeedbackLearner) persistToMemory(lesson *FeedbackLesson) {
 	store.WriteLongTerm(content)
 }
 
+// memoryScope 解析教训的作用域，旧数据或无法解析时视为全局
+func (l *FeedbackLesson) memoryScope() memory.Scope {
+	scope, err := memory.ParseScope(l.Scope)
+	if err != nil {
+		return memory.GlobalScope()
+	}
+	return scope
+}
+
 func (fl *FeedbackLearner) autoSaveIfNeeded() {
 	if !fl.autoSave || time.Since(fl.lastSave) < fl.saveInterval {
 		return
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/Lichas/maxclaw/pkg/tools"
+)
+
+// maxFileDiffEventChars 单个 file_diff 事件（及时间线）保留的 diff 长度
+const maxFileDiffEventChars = 20000
+
+// SetRequireReadBeforeEdit toggles the read-before-edit check for edit_file and apply_patch.
+func (a *AgentLoop) SetRequireReadBeforeEdit(require bool) {
+	if a.fileTracker != nil {
+		a.fileTracker.SetRequireRead(require)
+	}
+}
+
+func (a *AgentLoop) requireReadBeforeEdit() bool {
+	return a.fileTracker != nil && a.fileTracker.RequireRead()
+}
+
+// forgetFileReads drops read stamps for a session, e.g. after /new.
+func (a *AgentLoop) forgetFileReads(sessionKey string) {
+	if a.fileTracker != nil {
+		a.fileTracker.Forget(sessionKey)
+	}
+}
+
+func fileDiffEvent(iteration int, toolID string, change tools.FileChange) StreamEvent {
+	verb := "Edited"
+	switch {
+	case change.Created:
+		verb = "Created"
+	case change.Deleted:
+		verb = "Deleted"
+	}
+	return StreamEvent{
+		Type:      "file_diff",
+		Iteration: iteration,
+		ToolID:    toolID,
+		ToolName:  change.Tool,
+		Summary:   fmt.Sprintf("%s %s (+%d -%d)", verb, change.Path, change.Added, change.Removed),
+		FilePath:  change.Path,
+		Diff:      truncateEventText(change.Diff, maxFileDiffEventChars),
+	}
+}
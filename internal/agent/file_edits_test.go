@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/Lichas/maxclaw/internal/session"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type writeFileProvider struct {
+	callCount int
+}
+
+func (p *writeFileProvider) Chat(ctx context.Context, messages []providers.Message, defs []map[string]interface{}, model string) (*providers.Response, error) {
+	return nil, nil
+}
+
+func (p *writeFileProvider) ChatStream(ctx context.Context, messages []providers.Message, defs []map[string]interface{}, model string, handler providers.StreamHandler) error {
+	if p.callCount == 0 {
+		handler.OnToolCallStart("tool_1", "write_file")
+		handler.OnToolCallDelta("tool_1", `{"path":"notes.md","content":"# Notes\nhello\n"}`)
+		handler.OnToolCallEnd("tool_1")
+		handler.OnComplete()
+		p.callCount++
+		return nil
+	}
+	handler.OnContent("done")
+	handler.OnComplete()
+	p.callCount++
+	return nil
+}
+
+func (p *writeFileProvider) GetDefaultModel() string {
+	return "test-model"
+}
+
+func (p *writeFileProvider) SupportsImageInput(model string) bool {
+	return false
+}
+
+func TestAgentLoopEmitsFileDiffEvents(t *testing.T) {
+	workspace := t.TempDir()
+	loop := NewAgentLoop(
+		bus.NewMessageBus(10),
+		&writeFileProvider{},
+		workspace,
+		"test-model",
+		3,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		nil,
+		nil,
+		false,
+	)
+
+	var diffs []StreamEvent
+	_, err := loop.ProcessDirectEventStream(context.Background(), "write notes", "desktop:diff", "desktop", "chat-1", func(event StreamEvent) {
+		if event.Type == "file_diff" {
+			diffs = append(diffs, event)
+		}
+	})
+	require.NoError(t, err)
+
+	require.Len(t, diffs, 1)
+	assert.Equal(t, "notes.md", diffs[0].FilePath)
+	assert.Equal(t, "write_file", diffs[0].ToolName)
+	assert.Equal(t, "Created notes.md (+2 -0)", diffs[0].Summary)
+	assert.Contains(t, diffs[0].Diff, "+# Notes")
+
+	sess := session.NewManager(workspace).GetOrCreate("desktop:diff")
+	require.Len(t, sess.Messages, 2)
+	var found bool
+	for _, entry := range sess.Messages[1].Timeline {
+		if entry.Activity != nil && entry.Activity.Type == "file_diff" {
+			found = true
+			assert.Contains(t, entry.Activity.Detail, "+hello")
+		}
+	}
+	assert.True(t, found, "file_diff should be persisted in the assistant timeline")
+}
+
+func TestProfileLoopInheritsRequireReadBeforeEdit(t *testing.T) {
+	base := NewAgentLoop(
+		bus.NewMessageBus(10),
+		&staticProvider{},
+		t.TempDir(),
+		"test-model",
+		3,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		nil,
+		nil,
+		false,
+	)
+	base.SetRequireReadBeforeEdit(true)
+
+	profile := NewProfileAgentLoop(base, config.ResolvedAgent{Name: "coder", Workspace: t.TempDir()}, nil)
+	assert.True(t, profile.requireReadBeforeEdit())
+	assert.NotSame(t, base.fileTracker, profile.fileTracker)
+}
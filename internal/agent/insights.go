@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Lichas/maxclaw/internal/providers"
 	"github.com/Lichas/maxclaw/internal/session"
 )
 
@@ -249,7 +250,8 @@ func (ie *InsightsEngine) computeModelBreakdown(sessions []SessionData) []ModelU
 		}
 
 		if _, ok := modelData[displayModel]; !ok {
-			modelData[displayModel] = &ModelUsage{Model: displayModel}
+			info, _ := providers.LookupModel(providers.DetectProviderName(model), model)
+			modelData[displayModel] = &ModelUsage{Model: displayModel, HasPricing: info.Pricing != nil}
 		}
 
 		m := modelData[displayModel]
@@ -672,43 +674,15 @@ func barChart(values []int, maxWidth int) []string {
 	return result
 }
 
-// EstimateCost estimates the USD cost for a model/token tuple
-// This is a simplified implementation - would need actual pricing data
+// EstimateCost estimates the USD cost for a model/token tuple using catalog pricing.
+// Cache tokens are counted separately from input tokens.
 func EstimateCost(model string, inputTokens, outputTokens, cacheReadTokens, cacheWriteTokens int) float64 {
-	// Default pricing per 1K tokens (simplified)
-	var inputRate, outputRate float64
-
-	modelLower := strings.ToLower(model)
-	switch {
-	case strings.Contains(modelLower, "claude-3-opus"):
-		inputRate = 0.015
-		outputRate = 0.075
-	case strings.Contains(modelLower, "claude-3-sonnet"):
-		inputRate = 0.003
-		outputRate = 0.015
-	case strings.Contains(modelLower, "claude-3-haiku"):
-		inputRate = 0.00025
-		outputRate = 0.00125
-	case strings.Contains(modelLower, "gpt-4-turbo"):
-		inputRate = 0.01
-		outputRate = 0.03
-	case strings.Contains(modelLower, "gpt-4"):
-		inputRate = 0.03
-		outputRate = 0.06
-	case strings.Contains(modelLower, "gpt-3.5-turbo"):
-		inputRate = 0.0005
-		outputRate = 0.0015
-	default:
-		// Default rates
-		inputRate = 0.01
-		outputRate = 0.03
-	}
-
-	// Apply cache discounts (typical: cache read is 10% of input cost, cache write is 25% extra)
-	cost := float64(inputTokens) / 1000 * inputRate
-	cost += float64(outputTokens) / 1000 * outputRate
-	cost += float64(cacheReadTokens) / 1000 * inputRate * 0.1
-	cost += float64(cacheWriteTokens) / 1000 * inputRate * 0.25
-
+	info, _ := providers.LookupModel(providers.DetectProviderName(model), model)
+	if cost, ok := info.Cost(inputTokens, outputTokens, cacheReadTokens, cacheWriteTokens); ok {
+		return cost
+	}
+	// 目录无价格数据时按默认费率估算（$10 / $30 每百万 token）
+	fallback := providers.ModelInfo{Pricing: &providers.ModelPricing{Input: 10, Output: 30}}
+	cost, _ := fallback.Cost(inputTokens, outputTokens, cacheReadTokens, cacheWriteTokens)
 	return cost
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Lichas/maxclaw/internal/memory"
 	"github.com/Lichas/maxclaw/internal/providers"
 	"github.com/Lichas/maxclaw/internal/session"
 )
@@ -388,6 +389,7 @@ func (al *AgentLifecycle) DetectUserFeedback(ctx context.Context, userMsg, agent
 
 // LearnFromFeedback records user feedback and extracts lesson
 func (al *AgentLifecycle) LearnFromFeedback(
+	scope memory.ScopeContext,
 	result *FeedbackResult,
 	taskContext string,
 	agentOutput string,
@@ -396,23 +398,23 @@ func (al *AgentLifecycle) LearnFromFeedback(
 	if !al.Enabled || !al.EnableFeedback || al.FeedbackLearner == nil {
 		return nil
 	}
-	return al.FeedbackLearner.RecordFeedback(result, taskContext, agentOutput, userFeedback)
+	return al.FeedbackLearner.RecordFeedback(scope, result, taskContext, agentOutput, userFeedback)
 }
 
 // GetFeedbackLessons retrieves relevant lessons for a task
-func (al *AgentLifecycle) GetFeedbackLessons(taskType string, maxResults int) []*FeedbackLesson {
+func (al *AgentLifecycle) GetFeedbackLessons(scope memory.ScopeContext, taskType string, maxResults int) []*FeedbackLesson {
 	if !al.Enabled || !al.EnableFeedback || al.FeedbackLearner == nil {
 		return nil
 	}
-	return al.FeedbackLearner.GetRelevantLessons(taskType, maxResults)
+	return al.FeedbackLearner.GetRelevantLessons(scope, taskType, maxResults)
 }
 
 // BuildFeedbackEnhancedPrompt generates system prompt with learned lessons
-func (al *AgentLifecycle) BuildFeedbackEnhancedPrompt(taskType string) string {
+func (al *AgentLifecycle) BuildFeedbackEnhancedPrompt(scope memory.ScopeContext, taskType string) string {
 	if !al.Enabled || !al.EnableFeedback || al.FeedbackLearner == nil {
 		return ""
 	}
-	return al.FeedbackLearner.BuildSystemPromptEnhancement(taskType)
+	return al.FeedbackLearner.BuildSystemPromptEnhancement(scope, taskType)
 }
 
 // GetFeedbackStats returns feedback detection and learning statistics
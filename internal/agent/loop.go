@@ -18,9 +18,12 @@ import (
 	"github.com/Lichas/maxclaw/internal/logging"
 	"github.com/Lichas/maxclaw/internal/memory"
 	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/Lichas/maxclaw/internal/rag"
 	"github.com/Lichas/maxclaw/internal/session"
 	"github.com/Lichas/maxclaw/internal/skills"
+	"github.com/Lichas/maxclaw/internal/telemetry"
 	"github.com/Lichas/maxclaw/pkg/tools"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -45,15 +48,26 @@ type AgentLoop struct {
 	RestrictToWorkspace bool
 	CronService         *cron.Service
 	MCPServers          map[string]config.MCPServerConfig
+	ProfileName         string
 
 	context  *ContextBuilder
 	sessions *session.Manager
 	tools    *tools.Registry
 
 	mcpConnector   *tools.MCPConnector
+	processes      *tools.ProcessManager
+	fileTracker    *tools.FileReadTracker
 	mcpConnectOnce sync.Once
 	runtimeMu      sync.RWMutex
 	executionMode  string
+	toolAllowlist  []string
+	defaultSkills  []string
+	toolPolicy     *tools.ToolPolicy
+	webSearch      *tools.WebSearchOptions
+	egress         *tools.EgressGuard
+	retrieval      *rag.Options
+	// memoryIdentities 跨频道用户身份映射，决定用户记忆作用域
+	memoryIdentities memory.Identities
 
 	// 中断处理相关
 	intentAnalyzer *IntentAnalyzer
@@ -79,8 +93,17 @@ type StreamEvent struct {
 	SkillDetail string `json:"skillDetail,omitempty"`
 	Summary     string `json:"summary,omitempty"`
 	ToolResult  string `json:"toolResult,omitempty"`
+	FilePath    string `json:"filePath,omitempty"`
+	Diff        string `json:"diff,omitempty"`
 	Response    string `json:"response,omitempty"`
 	Done        bool   `json:"done,omitempty"`
+	// IsError 标记执行失败的 tool_result
+	IsError bool `json:"isError,omitempty"`
+	// usage 事件携带本轮 LLM 调用的模型与 token 用量
+	Model            string `json:"model,omitempty"`
+	PromptTokens     int    `json:"promptTokens,omitempty"`
+	CompletionTokens int    `json:"completionTokens,omitempty"`
+	CachedTokens     int    `json:"cachedTokens,omitempty"`
 }
 
 // NewAgentLoop 创建 Agent 循环
@@ -98,16 +121,46 @@ func NewAgentLoop(
 	mcpServers map[string]config.MCPServerConfig,
 	enableGlobalSkills bool,
 ) *AgentLoop {
-	if maxIterations <= 0 {
-		maxIterations = 200
-	}
-
 	// 设置工具允许的目录
 	if restrictToWorkspace {
 		tools.SetAllowedDir(workspace)
 	}
 	tools.SetWorkspaceDir(workspace)
 
+	return buildAgentLoop(
+		bus,
+		provider,
+		workspace,
+		model,
+		maxIterations,
+		braveAPIKey,
+		webFetch,
+		execConfig,
+		restrictToWorkspace,
+		cronService,
+		mcpServers,
+		enableGlobalSkills,
+	)
+}
+
+func buildAgentLoop(
+	bus *bus.MessageBus,
+	provider providers.LLMProvider,
+	workspace string,
+	model string,
+	maxIterations int,
+	braveAPIKey string,
+	webFetch tools.WebFetchOptions,
+	execConfig config.ExecToolConfig,
+	restrictToWorkspace bool,
+	cronService *cron.Service,
+	mcpServers map[string]config.MCPServerConfig,
+	enableGlobalSkills bool,
+) *AgentLoop {
+	if maxIterations <= 0 {
+		maxIterations = 200
+	}
+
 	loop := &AgentLoop{
 		Bus:                 bus,
 		Provider:            provider,
@@ -127,10 +180,14 @@ func NewAgentLoop(
 		PlanManager:         NewPlanManager(workspace),
 		executionMode:       config.ExecutionModeAsk,
 	}
+	loop.processes = tools.NewProcessManager(workspace, restrictToWorkspace, BuildExecSandboxOptions(execConfig))
+	loop.fileTracker = tools.NewFileReadTracker(false)
+	loop.egress = defaultEgressGuard()
 	loop.context.SetExecutionMode(loop.executionMode)
 
 	if len(loop.MCPServers) > 0 {
 		loop.mcpConnector = tools.NewMCPConnector(convertMCPServers(loop.MCPServers))
+		loop.mcpConnector.SetEgressGuard(loop.egress)
 	}
 
 	loop.registerDefaultTools()
@@ -152,16 +209,23 @@ func (a *AgentLoop) registerDefaultTools() {
 	a.tools.Register(tools.NewReadFileTool())
 	a.tools.Register(tools.NewWriteFileTool())
 	a.tools.Register(tools.NewEditFileTool())
+	a.tools.Register(tools.NewApplyPatchTool())
 	a.tools.Register(tools.NewListDirTool())
+	a.tools.Register(tools.NewSearchWorkspaceTool(a.retrievalOptionsSnapshot()))
 
 	// Shell 工具
-	a.tools.Register(tools.NewExecTool(a.Workspace, a.ExecConfig.Timeout, a.RestrictToWorkspace))
+	a.tools.Register(tools.NewExecToolWithSandbox(a.Workspace, a.ExecConfig.Timeout, a.RestrictToWorkspace, BuildExecSandboxOptions(a.ExecConfig)))
+	a.tools.Register(tools.NewProcessTool(a.processes))
 
 	// Web 工具
-	a.tools.Register(tools.NewWebSearchTool(a.BraveAPIKey, 5))
-	a.tools.Register(tools.NewWebFetchTool(a.WebFetchOptions))
+	webFetch := tools.NewWebFetchTool(a.WebFetchOptions)
+	a.tools.Register(tools.NewWebSearchToolWithOptions(a.webSearchOptionsSnapshot(), webFetch))
+	a.tools.Register(webFetch)
 	a.tools.Register(tools.NewBrowserTool(tools.BrowserOptionsFromWebFetch(a.WebFetchOptions)))
 
+	// 记忆工具
+	a.tools.Register(tools.NewMemoryTool(a.Workspace))
+
 	// 消息工具
 	a.tools.Register(tools.NewMessageTool(func(channel, chatID, content string) error {
 		return a.Bus.PublishOutbound(bus.NewOutboundMessage(channel, chatID, content))
@@ -244,6 +308,11 @@ type streamHandler struct {
 	toolCalls         []providers.ToolCall
 	accumulatingCalls map[string]*providers.ToolCall
 	onDelta           func(string)
+	// 推理内容单独累积，不计入回复正文
+	reasoningBlocks []providers.ReasoningBlock
+	onReasoning     func(string)
+	// 提供商上报的 token 用量（部分提供商流式接口不上报）
+	usage *providers.Usage
 }
 
 func newStreamHandler(channel, chatID string, msgBus *bus.MessageBus, onDelta func(string)) *streamHandler {
@@ -284,11 +353,21 @@ func (h *streamHandler) OnToolCallEnd(id string) {
 	}
 }
 
+func (h *streamHandler) OnReasoningDelta(delta string) {
+	if h.onReasoning != nil {
+		h.onReasoning(delta)
+	}
+}
+
+func (h *streamHandler) OnReasoningBlock(block providers.ReasoningBlock) {
+	h.reasoningBlocks = append(h.reasoningBlocks, block)
+}
+
 func (h *streamHandler) OnComplete() {}
 
 func (h *streamHandler) OnError(err error) {
 	if lg := logging.Get(); lg != nil && lg.Session != nil {
-		lg.Session.Printf("stream error channel=%s chat=%s err=%v", h.channel, h.chatID, err)
+		lg.Session.Error("stream error", "channel", h.channel, "chat_id", h.chatID, "err", err)
 	}
 }
 
@@ -296,10 +375,18 @@ func (h *streamHandler) GetContent() string {
 	return h.content.String()
 }
 
+func (h *streamHandler) OnUsage(usage providers.Usage) {
+	h.usage = &usage
+}
+
 func (h *streamHandler) GetToolCalls() []providers.ToolCall {
 	return h.toolCalls
 }
 
+func (h *streamHandler) GetReasoning() []providers.ReasoningBlock {
+	return h.reasoningBlocks
+}
+
 func providerIdentity(provider providers.LLMProvider) string {
 	if provider == nil {
 		return ""
@@ -388,6 +475,29 @@ func (a *AgentLoop) runtimeSnapshot() (providers.LLMProvider, string, int) {
 	return a.Provider, a.Model, a.MaxIterations
 }
 
+// modelForPurpose 返回指定用途（summary/subagent 等）路由到的模型，未配置时使用主模型
+func (a *AgentLoop) modelForPurpose(purpose string) string {
+	a.runtimeMu.RLock()
+	defer a.runtimeMu.RUnlock()
+	if router, ok := a.Provider.(*ProviderRouter); ok {
+		if model := router.ModelFor(purpose); model != "" {
+			return model
+		}
+	}
+	return a.Model
+}
+
+// ProviderHealth 返回路由 Provider 的健康状态；未启用路由时 ok 为 false
+func (a *AgentLoop) ProviderHealth() ([]BackendHealth, bool) {
+	a.runtimeMu.RLock()
+	defer a.runtimeMu.RUnlock()
+	router, ok := a.Provider.(*ProviderRouter)
+	if !ok {
+		return nil, false
+	}
+	return router.Health(), true
+}
+
 func (a *AgentLoop) executionModeSnapshot() string {
 	a.runtimeMu.RLock()
 	defer a.runtimeMu.RUnlock()
@@ -405,7 +515,14 @@ func (a *AgentLoop) ListToolNames() []string {
 	if a == nil || a.tools == nil {
 		return nil
 	}
-	return a.tools.List()
+	names := a.tools.List()
+	allowed := names[:0]
+	for _, name := range names {
+		if a.toolAllowed(name) {
+			allowed = append(allowed, name)
+		}
+	}
+	return allowed
 }
 
 // HandleInterruption 处理插话请求
@@ -502,9 +619,23 @@ func (a *AgentLoop) checkIncomingMessages(ic *InterruptibleContext, currentMsg *
 	}
 }
 
-func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.InboundMessage, onDelta func(string), onEvent func(StreamEvent), modelOverride string) (*bus.OutboundMessage, error) {
-	// 使用 InterruptibleContext 的底层 context
-	ctx := ic.Context()
+func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.InboundMessage, onDelta func(string), onEvent func(StreamEvent), modelOverride string) (out *bus.OutboundMessage, err error) {
+	// 使用 InterruptibleContext 的底层 context，并以入站消息为根 span 串联 LLM / 工具调用
+	ctx, span := telemetry.StartSpan(
+		telemetry.ExtractTraceParent(ic.Context(), msg.TraceParent),
+		"agent.message",
+		attribute.String("channel", msg.Channel),
+		attribute.String("session", msg.SessionKey),
+		attribute.String("agent", a.profileLabel()),
+	)
+	defer func() {
+		if out != nil {
+			out.TraceParent = telemetry.InjectTraceParent(ctx)
+		}
+		telemetry.EndSpan(span, err)
+	}()
+	// 本次处理的日志都带上 request_id / session，工具与 LLM 日志还会带上 turn
+	ctx = logging.WithRequestID(logging.WithSession(ctx, msg.Channel, msg.ChatID, msg.SessionKey), logging.NewRequestID())
 	a.ensureMCPConnected(ctx)
 
 	timeline := make([]session.TimelineEntry, 0, 64)
@@ -523,7 +654,7 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 	}
 
 	if lg := logging.Get(); lg != nil && lg.Session != nil {
-		lg.Session.Printf("inbound channel=%s chat=%s sender=%s content=%q", msg.Channel, msg.ChatID, msg.SenderID, logging.Truncate(msg.Content, 400))
+		lg.Session.InfoContext(ctx, "inbound", "sender", msg.SenderID, "content", logging.Truncate(msg.Content, 400))
 	}
 
 	// 获取或创建会话
@@ -570,11 +701,13 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 	case "/new":
 		if _, err := memory.ArchiveSessionAll(a.Workspace, sess); err != nil {
 			if lg := logging.Get(); lg != nil && lg.Session != nil {
-				lg.Session.Printf("archive session on /new failed: %v", err)
+				lg.Session.ErrorContext(ctx, "archive session on /new failed", "err", err)
 			}
 		}
 		sess.Clear()
 		_ = a.sessions.Save(sess)
+		a.KillSessionProcesses(msg.SessionKey)
+		a.forgetFileReads(msg.SessionKey)
 		return bus.NewOutboundMessage(msg.Channel, msg.ChatID, "New session started."), nil
 	case "/help":
 		return bus.NewOutboundMessage(
@@ -590,7 +723,7 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 		sess.AddMessage("user", msg.Content)
 		if err := a.sessions.Save(sess); err != nil {
 			if lg := logging.Get(); lg != nil && lg.Session != nil {
-				lg.Session.Printf("save user message failed: %v", err)
+				lg.Session.ErrorContext(ctx, "save user message failed", "err", err)
 			}
 		}
 	}
@@ -600,6 +733,9 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 
 	// 构建消息
 	selectedSkillRefs := normalizeSkillRefs(msg.SelectedSkills)
+	if len(selectedSkillRefs) == 0 && !skillSelectorPattern.MatchString(msg.Content) {
+		selectedSkillRefs = a.defaultSkillsSnapshot()
+	}
 	shouldEmitSkillEvents := len(selectedSkillRefs) > 0 || skillSelectorPattern.MatchString(msg.Content)
 	if shouldEmitSkillEvents {
 		for _, entry := range a.context.resolveSkillEntries(msg.Content, selectedSkillRefs) {
@@ -623,17 +759,18 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 	}
 
 	// Build messages with plan context if exists
+	memoryScope := a.MemoryScope(msg.Channel, msg.ChatID, msg.SenderID)
 	var messages []providers.Message
 	if plan != nil && plan.Status == PlanStatusRunning {
-		messages = a.context.BuildMessagesWithPlanAndSkillRefs(history, msg.Content, selectedSkillRefs, msg.Media, msg.Channel, msg.ChatID, plan)
+		messages = a.context.BuildMessagesWithPlanAndSkillRefs(history, msg.Content, selectedSkillRefs, msg.Media, memoryScope, plan)
 	} else {
-		messages = a.context.BuildMessagesWithSkillRefs(history, msg.Content, selectedSkillRefs, msg.Media, msg.Channel, msg.ChatID)
+		messages = a.context.BuildMessagesWithSkillRefs(history, msg.Content, selectedSkillRefs, msg.Media, memoryScope)
 	}
 
 	// Agent 循环
 	var finalContent string
 	maxIterationReached := true
-	toolDefs := a.tools.GetDefinitions()
+	toolDefs := a.toolDefinitions()
 	_, activeModel, maxIterations := a.runtimeSnapshot()
 	if strings.TrimSpace(modelOverride) != "" {
 		activeModel = strings.TrimSpace(modelOverride)
@@ -654,6 +791,7 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 
 	for i := 0; i < effectiveMaxIterations; i++ {
 		iteration := i + 1
+		turnCtx := logging.WithTurn(ctx, iteration)
 
 		// 检查是否被取消
 		select {
@@ -669,7 +807,7 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 		})
 
 		deltaCallback := onDelta
-		if deltaCallback == nil && msg.Channel == "cli" {
+		if deltaCallback == nil && onEvent == nil && msg.Channel == "cli" {
 			deltaCallback = func(delta string) {
 				fmt.Print(delta)
 			}
@@ -684,17 +822,26 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 				Iteration: iteration,
 			})
 		}
+		reasoningCallback := func(delta string) {
+			emitEvent(StreamEvent{
+				Type:      "reasoning_delta",
+				Delta:     delta,
+				Iteration: iteration,
+			})
+		}
 
 		// 流式调用 LLM
 		handler := newStreamHandler(msg.Channel, msg.ChatID, a.Bus, streamCallback)
+		handler.onReasoning = reasoningCallback
 		provider, model, _ := a.runtimeSnapshot()
+		model = a.requestModel(model, modelOverride)
 		if provider == nil {
 			return nil, fmt.Errorf("LLM provider is not configured")
 		}
 
 		var chatErr error
 		for {
-			chatErr = provider.ChatStream(ctx, messages, toolDefs, model, handler)
+			chatErr = a.chatStreamWithTelemetry(turnCtx, provider, messages, toolDefs, model, iteration, handler)
 			if chatErr == nil {
 				a.RecordAPICallSuccess(0, 0)
 				break
@@ -718,8 +865,21 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 			}
 			// Refresh runtime state in case fallback changed provider/model
 			provider, model, _ = a.runtimeSnapshot()
+			model = a.requestModel(model, modelOverride)
 			// Reset handler for retry
 			handler = newStreamHandler(msg.Channel, msg.ChatID, a.Bus, streamCallback)
+			handler.onReasoning = reasoningCallback
+		}
+
+		if handler.usage != nil {
+			emitEvent(StreamEvent{
+				Type:             "usage",
+				Iteration:        iteration,
+				Model:            model,
+				PromptTokens:     handler.usage.PromptTokens,
+				CompletionTokens: handler.usage.CompletionTokens,
+				CachedTokens:     handler.usage.CachedTokens,
+			})
 		}
 
 		// CLI 换行
@@ -750,7 +910,7 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 			a.PlanManager.Save(msg.SessionKey, plan)
 
 			// Rebuild messages with plan context
-			messages = a.context.BuildMessagesWithPlanAndSkillRefs(history, msg.Content, selectedSkillRefs, msg.Media, msg.Channel, msg.ChatID, plan)
+			messages = a.context.BuildMessagesWithPlanAndSkillRefs(history, msg.Content, selectedSkillRefs, msg.Media, memoryScope, plan)
 		}
 
 		// 处理工具调用
@@ -762,7 +922,8 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 			})
 
 			// 添加助手消息（带工具调用）
-			messages = a.context.AddAssistantMessage(messages, content, toolCalls)
+			// 推理块（含签名）随助手消息保留到本轮结束，供需要的提供商回传
+			messages = a.context.AddAssistantMessage(messages, content, toolCalls, handler.GetReasoning())
 
 			// 执行工具调用并显示结果
 			for _, tc := range toolCalls {
@@ -787,8 +948,12 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 					args = map[string]interface{}{}
 				}
 
-				toolCtx := tools.WithRuntimeContextWithSession(ctx, msg.Channel, msg.ChatID, msg.SessionKey)
-				result, execErr := a.tools.Execute(toolCtx, tc.Function.Name, args)
+				var fileChanges []tools.FileChange
+				toolCtx := tools.WithFileChangeRecorder(
+					tools.WithRuntimeMessage(a.toolContext(turnCtx, msg.Channel, msg.ChatID, msg.SessionKey, msg.SenderID), msg.Content),
+					func(change tools.FileChange) { fileChanges = append(fileChanges, change) },
+				)
+				result, execErr := a.executeTool(toolCtx, tc.Function.Name, args)
 				toolSuccess := execErr == nil
 				a.RecordToolExecution(tc.Function.Name, toolSuccess, 0)
 				if execErr != nil {
@@ -796,11 +961,16 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 				}
 
 				if lg := logging.Get(); lg != nil && lg.Tools != nil {
-					lg.Tools.Printf("tool name=%s args=%q result_len=%d", tc.Function.Name, logging.Truncate(tc.Function.Arguments, 300), len(result))
+					attrs := []any{"tool", tc.Function.Name, "args", logging.Truncate(tc.Function.Arguments, 300), "result_len", len(result)}
+					if execErr != nil {
+						lg.Tools.WarnContext(toolCtx, "tool failed", append(attrs, "err", execErr)...)
+					} else {
+						lg.Tools.InfoContext(toolCtx, "tool executed", attrs...)
+					}
 				}
 
-				// 显示工具执行结果
-				if msg.Channel == "cli" {
+				// 显示工具执行结果（事件流调用方自行渲染）
+				if msg.Channel == "cli" && onEvent == nil {
 					fmt.Printf("[Result: %s]\n%s\n\n", tc.Function.Name, result)
 				}
 
@@ -811,7 +981,11 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 					ToolName:   tc.Function.Name,
 					ToolResult: truncateEventText(result, 2000),
 					Summary:    summarizeToolResult(tc.Function.Name, result, execErr),
+					IsError:    execErr != nil,
 				})
+				for _, change := range fileChanges {
+					emitEvent(fileDiffEvent(iteration, tc.ID, change))
+				}
 
 				messages = a.context.AddToolResult(messages, tc.ID, tc.Function.Name, result)
 			}
@@ -839,7 +1013,7 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 
 				// Update system message with latest plan context for next iteration
 				if len(messages) > 0 && messages[0].Role == "system" {
-					messages[0].Content = a.context.BuildSystemPromptWithPlan(plan)
+					messages[0].Content = a.context.buildSystemPromptWithPlan(memoryScope, plan)
 				}
 			}
 		} else {
@@ -884,6 +1058,11 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 	if finalContent == "" {
 		if maxIterationReached {
 			finalContent = fmt.Sprintf("Reached %d iterations without completion.", effectiveMaxIterations)
+			emitEvent(StreamEvent{
+				Type:      "iteration_limit",
+				Iteration: effectiveMaxIterations,
+				Message:   finalContent,
+			})
 
 			// Pause plan if exists
 			if plan != nil && plan.Status == PlanStatusRunning {
@@ -904,7 +1083,7 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 	}
 
 	if lg := logging.Get(); lg != nil && lg.Session != nil {
-		lg.Session.Printf("outbound channel=%s chat=%s content=%q", msg.Channel, msg.ChatID, logging.Truncate(finalContent, 400))
+		lg.Session.InfoContext(ctx, "outbound", "content", logging.Truncate(finalContent, 400))
 	}
 
 	// 保存到会话
@@ -919,13 +1098,13 @@ func (a *AgentLoop) processMessageWithIC(ic *InterruptibleContext, msg *bus.Inbo
 	if len(sess.Messages) > sessionConsolidateKeepRecent {
 		if _, err := memory.ConsolidateSession(a.Workspace, sess, sessionConsolidateKeepRecent); err != nil {
 			if lg := logging.Get(); lg != nil && lg.Session != nil {
-				lg.Session.Printf("memory consolidation failed: %v", err)
+				lg.Session.ErrorContext(ctx, "memory consolidation failed", "err", err)
 			}
 		}
 	} else if len(sess.Messages) > sess.LastConsolidated {
 		if _, err := memory.ArchiveSessionAll(a.Workspace, sess); err != nil {
 			if lg := logging.Get(); lg != nil && lg.Session != nil {
-				lg.Session.Printf("memory archive failed: %v", err)
+				lg.Session.ErrorContext(ctx, "memory archive failed", "err", err)
 			}
 		}
 	}
@@ -963,6 +1142,17 @@ func (a *AgentLoop) UpdateRuntimeModel(provider providers.LLMProvider, model str
 	}
 }
 
+// RuntimeModel returns the model used by new requests.
+func (a *AgentLoop) RuntimeModel() string {
+	_, model, _ := a.runtimeSnapshot()
+	return model
+}
+
+// ExecutionMode returns the execution mode used by new requests.
+func (a *AgentLoop) ExecutionMode() string {
+	return a.executionModeSnapshot()
+}
+
 // UpdateRuntimeMaxIterations updates the max iteration limit used by new requests.
 func (a *AgentLoop) UpdateRuntimeMaxIterations(maxIterations int) {
 	if maxIterations <= 0 {
@@ -997,13 +1187,17 @@ func (a *AgentLoop) UpdateRuntimeMCPServers(mcpServers map[string]config.MCPServ
 
 	a.MCPServers = cloneMCPServerConfigs(mcpServers)
 	a.mcpConnectOnce = sync.Once{}
+	telemetry.ResetMCPServers()
 
 	if len(a.MCPServers) == 0 {
 		return nil
 	}
 
 	connector := tools.NewMCPConnector(convertMCPServers(a.MCPServers))
-	if err := connector.Connect(context.Background(), a.tools); err != nil {
+	connector.SetEgressGuard(a.egress)
+	err := connector.Connect(context.Background(), a.tools)
+	reportMCPStatus(connector)
+	if err != nil {
 		a.mcpConnector = connector
 		return err
 	}
@@ -1119,6 +1313,11 @@ func (a *AgentLoop) executeSpawnRequest(ctx context.Context, request tools.Spawn
 		))
 	}
 
+	model := request.Model
+	if model == "" {
+		model = a.modelForPurpose(PurposeSubagent)
+	}
+
 	runCtx := context.Background()
 	resultText, err := a.ProcessDirectWithOptions(
 		runCtx,
@@ -1127,7 +1326,7 @@ func (a *AgentLoop) executeSpawnRequest(ctx context.Context, request tools.Spawn
 		channel,
 		chatID,
 		request.SelectedSkills,
-		request.Model,
+		model,
 	)
 	if err != nil {
 		if a.enqueueSpawnCallback(request, childSessionKey, "", err) {
@@ -1173,6 +1372,7 @@ func (a *AgentLoop) enqueueSpawnCallback(request tools.SpawnRequest, childSessio
 	callback := bus.NewInboundMessage(channel, "subagent", chatID, formatSpawnCallbackContent(request.Label, request.Task, childSessionKey, resultText, runErr))
 	callback.SessionKey = parentSessionKey
 	callback.Internal = true
+	callback.Agent = a.ProfileName
 	return a.Bus.PublishInbound(callback) == nil
 }
 
@@ -1215,8 +1415,8 @@ func (a *AgentLoop) ExecuteToolWithSession(
 		chatID = sessionKey
 	}
 
-	toolCtx := tools.WithRuntimeContextWithSession(ctx, channel, chatID, sessionKey)
-	return a.tools.Execute(toolCtx, toolName, params)
+	toolCtx := a.toolContext(ctx, channel, chatID, sessionKey, "")
+	return a.executeTool(toolCtx, toolName, params)
 }
 
 // ProcessDirectStream 直接处理消息并按 delta 回调流式输出。
@@ -1386,7 +1586,21 @@ func appendTimelineFromEvent(timeline []session.TimelineEntry, event StreamEvent
 			Kind: "text",
 			Text: event.Delta,
 		})
-	case "status", "tool_start", "tool_result", "skill_start", "skill_result", "error":
+	case "reasoning_delta":
+		if event.Delta == "" {
+			return timeline
+		}
+		if len(timeline) > 0 && timeline[len(timeline)-1].Kind == "reasoning" {
+			last := timeline[len(timeline)-1]
+			last.Text += event.Delta
+			timeline[len(timeline)-1] = last
+			return timeline
+		}
+		return append(timeline, session.TimelineEntry{
+			Kind: "reasoning",
+			Text: event.Delta,
+		})
+	case "status", "tool_start", "tool_result", "file_diff", "skill_start", "skill_result", "error":
 		summary := strings.TrimSpace(event.Summary)
 		if summary == "" {
 			summary = strings.TrimSpace(event.Message)
@@ -1397,6 +1611,8 @@ func appendTimelineFromEvent(timeline []session.TimelineEntry, event StreamEvent
 			detail = strings.TrimSpace(event.ToolArgs)
 		case "tool_result":
 			detail = strings.TrimSpace(event.ToolResult)
+		case "file_diff":
+			detail = event.Diff
 		case "skill_start", "skill_result":
 			detail = strings.TrimSpace(event.SkillDetail)
 		}
@@ -1451,6 +1667,9 @@ func (a *AgentLoop) LoadSkills() error {
 
 // Close 释放 AgentLoop 资源（主要是 MCP 连接）。
 func (a *AgentLoop) Close() error {
+	if a.processes != nil {
+		_ = a.processes.Close()
+	}
 	if a.mcpConnector == nil {
 		return nil
 	}
@@ -1462,19 +1681,27 @@ func (a *AgentLoop) ensureMCPConnected(ctx context.Context) {
 		return
 	}
 	a.mcpConnectOnce.Do(func() {
+		defer reportMCPStatus(a.mcpConnector)
 		if err := a.mcpConnector.Connect(ctx, a.tools); err != nil {
 			if lg := logging.Get(); lg != nil && lg.Tools != nil {
-				lg.Tools.Printf("mcp connect warning: %v", err)
+				lg.Tools.WarnContext(ctx, "mcp connect warning", "err", err)
 			}
 		} else if lg := logging.Get(); lg != nil && lg.Tools != nil {
 			registered := a.mcpConnector.RegisteredTools()
 			if len(registered) > 0 {
-				lg.Tools.Printf("mcp connected tools=%v", registered)
+				lg.Tools.InfoContext(ctx, "mcp connected", "tools", registered)
 			}
 		}
 	})
 }
 
+// reportMCPStatus 将各 MCP 服务器的连接结果写入 maxclaw_mcp_server_up
+func reportMCPStatus(connector *tools.MCPConnector) {
+	for name, up := range connector.ServerStatus() {
+		telemetry.SetMCPServerUp(name, up)
+	}
+}
+
 func cloneMCPServerConfigs(in map[string]config.MCPServerConfig) map[string]config.MCPServerConfig {
 	if len(in) == 0 {
 		return map[string]config.MCPServerConfig{}
@@ -1537,7 +1764,7 @@ func (a *AgentLoop) InitializeLifecycle() {
 
 	// Initialize feedback detector with current provider
 	if a.Provider != nil {
-		a.Lifecycle.InitializeFeedback(a.Provider, a.Model)
+		a.Lifecycle.InitializeFeedback(a.Provider, a.modelForPurpose(PurposeSummary))
 	}
 }
 
@@ -1657,6 +1884,14 @@ func (a *AgentLoop) compressMessagesForRetry(ctx context.Context, messages []pro
 	return convertCompressorMessagesToProvider(compressedMessages), nil
 }
 
+// requestModel 返回本次请求使用的模型：显式指定的模型优先，fallback 生效期间使用 fallback 模型
+func (a *AgentLoop) requestModel(runtimeModel, modelOverride string) string {
+	if override := strings.TrimSpace(modelOverride); override != "" && !a.IsFallbackActive() {
+		return override
+	}
+	return runtimeModel
+}
+
 // IsFallbackActive returns true if a fallback provider is currently active
 func (a *AgentLoop) IsFallbackActive() bool {
 	if a.Lifecycle == nil {
@@ -1690,20 +1925,21 @@ func (a *AgentLoop) DetectUserFeedback(ctx context.Context, userMsg, agentOutput
 	return a.Lifecycle.DetectUserFeedback(ctx, userMsg, agentOutput)
 }
 
-// LearnFromFeedback records user feedback and extracts lesson
-func (a *AgentLoop) LearnFromFeedback(result *FeedbackResult, taskContext, agentOutput, userFeedback string) *FeedbackLesson {
+// LearnFromFeedback records user feedback and extracts lesson.
+// scope comes from MemoryScope and decides which memory the lesson is written to.
+func (a *AgentLoop) LearnFromFeedback(scope memory.ScopeContext, result *FeedbackResult, taskContext, agentOutput, userFeedback string) *FeedbackLesson {
 	if a.Lifecycle == nil {
 		return nil
 	}
-	return a.Lifecycle.LearnFromFeedback(result, taskContext, agentOutput, userFeedback)
+	return a.Lifecycle.LearnFromFeedback(scope, result, taskContext, agentOutput, userFeedback)
 }
 
-// GetFeedbackLessons retrieves relevant lessons for a task
-func (a *AgentLoop) GetFeedbackLessons(taskType string, maxResults int) []*FeedbackLesson {
+// GetFeedbackLessons retrieves relevant lessons for a task from the scopes of the conversation
+func (a *AgentLoop) GetFeedbackLessons(scope memory.ScopeContext, taskType string, maxResults int) []*FeedbackLesson {
 	if a.Lifecycle == nil {
 		return nil
 	}
-	return a.Lifecycle.GetFeedbackLessons(taskType, maxResults)
+	return a.Lifecycle.GetFeedbackLessons(scope, taskType, maxResults)
 }
 
 // InitializeFeedbackDetector initializes the feedback detector
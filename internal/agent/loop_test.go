@@ -126,8 +126,51 @@ func (p *streamEventProvider) SupportsImageInput(model string) bool {
 	return false
 }
 
+// reasoningProvider 先返回推理与工具调用，再检查工具轮次回传的推理块
+type reasoningProvider struct {
+	callCount int
+	replayed  [][]providers.ReasoningBlock
+}
+
+func (p *reasoningProvider) Chat(ctx context.Context, messages []providers.Message, defs []map[string]interface{}, model string) (*providers.Response, error) {
+	return nil, nil
+}
+
+func (p *reasoningProvider) ChatStream(ctx context.Context, messages []providers.Message, defs []map[string]interface{}, model string, handler providers.StreamHandler) error {
+	var replayed []providers.ReasoningBlock
+	for _, msg := range messages {
+		replayed = append(replayed, msg.Reasoning...)
+	}
+	p.replayed = append(p.replayed, replayed)
+	p.callCount++
+
+	rh := handler.(providers.ReasoningHandler)
+	if p.callCount%2 == 1 {
+		rh.OnReasoningDelta("list the ")
+		rh.OnReasoningDelta("workspace")
+		rh.OnReasoningBlock(providers.ReasoningBlock{Text: "list the workspace", Signature: "sig-1"})
+		handler.OnToolCallStart("tool_1", "list_dir")
+		handler.OnToolCallDelta("tool_1", `{"path":"."}`)
+		handler.OnToolCallEnd("tool_1")
+		handler.OnComplete()
+		return nil
+	}
+	handler.OnContent("done")
+	handler.OnComplete()
+	return nil
+}
+
+func (p *reasoningProvider) GetDefaultModel() string {
+	return "test-model"
+}
+
+func (p *reasoningProvider) SupportsImageInput(model string) bool {
+	return false
+}
+
 type captureSkillsProvider struct {
 	systemPrompt string
+	model        string
 }
 
 func (p *captureSkillsProvider) Chat(ctx context.Context, messages []providers.Message, defs []map[string]interface{}, model string) (*providers.Response, error) {
@@ -138,6 +181,7 @@ func (p *captureSkillsProvider) ChatStream(ctx context.Context, messages []provi
 	if len(messages) > 0 && messages[0].Role == "system" {
 		p.systemPrompt = messages[0].Content
 	}
+	p.model = model
 	handler.OnContent("ok")
 	handler.OnComplete()
 	return nil
@@ -574,6 +618,40 @@ func TestAgentLoopProcessMessageMaxIterationFallback(t *testing.T) {
 	assert.Contains(t, resp.Content, "Reached 2 iterations without completion.")
 }
 
+func TestAgentLoopEventStreamReportsToolErrorsAndIterationLimit(t *testing.T) {
+	workspace := t.TempDir()
+	loop := NewAgentLoop(
+		bus.NewMessageBus(10),
+		&endlessToolProvider{},
+		workspace,
+		"test-model",
+		2,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		nil,
+		nil,
+		false,
+	)
+
+	var failed, limits int
+	_, err := loop.ProcessDirectEventStream(context.Background(), "hello", "desktop:limit", "desktop", "limit", func(event StreamEvent) {
+		switch event.Type {
+		case "tool_result":
+			if event.IsError {
+				failed++
+			}
+		case "iteration_limit":
+			limits++
+			assert.Equal(t, 2, event.Iteration)
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, failed, "unknown tool calls are reported as failed")
+	assert.Equal(t, 1, limits)
+}
+
 func TestAgentLoopProcessMessageMaxIterationAutoModeUsesExpandedBudget(t *testing.T) {
 	workspace := t.TempDir()
 	messageBus := bus.NewMessageBus(10)
@@ -762,6 +840,58 @@ func TestAgentLoopProcessDirectEventStreamEmitsStructuredEvents(t *testing.T) {
 	assert.True(t, timelineHasText)
 }
 
+func TestAgentLoopReasoningStreamsSeparatelyAndStaysInTurn(t *testing.T) {
+	workspace := t.TempDir()
+	provider := &reasoningProvider{}
+	loop := NewAgentLoop(
+		bus.NewMessageBus(10),
+		provider,
+		workspace,
+		"test-model",
+		3,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		nil,
+		nil,
+		false,
+	)
+
+	var reasoning strings.Builder
+	resp, err := loop.ProcessDirectEventStream(context.Background(), "what is here?", "desktop:think", "desktop", "chat-1", func(event StreamEvent) {
+		if event.Type == "reasoning_delta" {
+			reasoning.WriteString(event.Delta)
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "done", resp)
+	assert.Equal(t, "list the workspace", reasoning.String())
+
+	// 工具轮次内回传带签名的推理块
+	require.Len(t, provider.replayed, 2)
+	assert.Empty(t, provider.replayed[0])
+	require.Len(t, provider.replayed[1], 1)
+	assert.Equal(t, "sig-1", provider.replayed[1][0].Signature)
+
+	sess := session.NewManager(workspace).GetOrCreate("desktop:think")
+	require.Len(t, sess.Messages, 2)
+	assert.Equal(t, "done", sess.Messages[1].Content)
+	var stored string
+	for _, entry := range sess.Messages[1].Timeline {
+		if entry.Kind == "reasoning" {
+			stored += entry.Text
+		}
+	}
+	assert.Equal(t, "list the workspace", stored)
+
+	// 下一轮从会话历史构建消息，不再携带推理内容
+	_, err = loop.ProcessDirectEventStream(context.Background(), "again", "desktop:think", "desktop", "chat-1", nil)
+	require.NoError(t, err)
+	require.Len(t, provider.replayed, 4)
+	assert.Empty(t, provider.replayed[2])
+}
+
 func TestTruncateEventTextPreservesUTF8Boundaries(t *testing.T) {
 	input := "从零开始理解🌟AI入门课程"
 	truncated := truncateEventText(input, 7)
@@ -807,6 +937,32 @@ func TestProcessDirectWithSkillsUsesOnlySelectedSkills(t *testing.T) {
 	assert.NotContains(t, sess.Messages[0].Content, "@skill:")
 }
 
+func TestAgentLoopProcessDirectWithOptionsUsesModelOverride(t *testing.T) {
+	provider := &captureSkillsProvider{}
+	loop := NewAgentLoop(
+		bus.NewMessageBus(10),
+		provider,
+		t.TempDir(),
+		"test-model",
+		2,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		nil,
+		nil,
+		false,
+	)
+
+	_, err := loop.ProcessDirectWithOptions(context.Background(), "hello", "desktop:override", "desktop", "chat-1", nil, "small-model")
+	require.NoError(t, err)
+	assert.Equal(t, "small-model", provider.model)
+
+	_, err = loop.ProcessDirectWithOptions(context.Background(), "hello", "desktop:default", "desktop", "chat-1", nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, "test-model", provider.model)
+}
+
 func TestAgentLoopProcessDirectEventStreamWithSkillsEmitsSkillEvents(t *testing.T) {
 	workspace := t.TempDir()
 	require.NoError(t, os.MkdirAll(filepath.Join(workspace, "skills"), 0755))
@@ -1002,3 +1158,57 @@ func TestAgentLoop_PlanManagerIntegration(t *testing.T) {
 		t.Error("expected plan to not exist after delete")
 	}
 }
+
+type usageProvider struct{}
+
+func (p *usageProvider) Chat(ctx context.Context, messages []providers.Message, defs []map[string]interface{}, model string) (*providers.Response, error) {
+	return nil, nil
+}
+
+func (p *usageProvider) ChatStream(ctx context.Context, messages []providers.Message, defs []map[string]interface{}, model string, handler providers.StreamHandler) error {
+	handler.OnContent("usage ok")
+	if uh, ok := handler.(providers.UsageHandler); ok {
+		uh.OnUsage(providers.Usage{PromptTokens: 120, CompletionTokens: 8, CachedTokens: 100})
+	}
+	handler.OnComplete()
+	return nil
+}
+
+func (p *usageProvider) GetDefaultModel() string {
+	return "test-model"
+}
+
+func (p *usageProvider) SupportsImageInput(model string) bool {
+	return false
+}
+
+func TestAgentLoopEventStreamReportsUsage(t *testing.T) {
+	loop := NewAgentLoop(
+		bus.NewMessageBus(10),
+		&usageProvider{},
+		t.TempDir(),
+		"test-model",
+		3,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		nil,
+		nil,
+		false,
+	)
+
+	var usage []StreamEvent
+	resp, err := loop.ProcessDirectEventStream(context.Background(), "hello", "cli:direct", "cli", "direct", func(event StreamEvent) {
+		if event.Type == "usage" {
+			usage = append(usage, event)
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "usage ok", resp)
+	require.Len(t, usage, 1)
+	assert.Equal(t, "test-model", usage[0].Model)
+	assert.Equal(t, 120, usage[0].PromptTokens)
+	assert.Equal(t, 8, usage[0].CompletionTokens)
+	assert.Equal(t, 100, usage[0].CachedTokens)
+}
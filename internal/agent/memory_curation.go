@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/memory"
+	"github.com/Lichas/maxclaw/internal/providers"
+)
+
+const memoryCondensePrompt = `You curate the long-term memory file of a personal AI assistant. The file is injected into every conversation, so it must stay short.
+
+Rewrite the Markdown below:
+- Merge duplicate and overlapping entries into one, keeping the most specific wording.
+- Combine related lessons; when facts conflict, keep the newer one.
+- Keep every distinct fact, preference, lesson and open todo. Do not invent anything.
+- Keep "## " section headings and bullet lists. Keep "## User Feedback Lesson [id]" blocks in their original field format.
+- Stay under about %d tokens.
+
+Return only the rewritten Markdown, without commentary.
+
+---
+%s`
+
+// NewMemoryCondenser 用指定 provider 与模型整理 MEMORY.md 正文
+func NewMemoryCondenser(provider providers.LLMProvider, model string) memory.Condenser {
+	return func(ctx context.Context, content string, maxTokens int) (string, error) {
+		if provider == nil {
+			return "", fmt.Errorf("LLM provider not configured")
+		}
+		messages := []providers.Message{
+			{Role: "user", Content: fmt.Sprintf(memoryCondensePrompt, maxTokens, content)},
+		}
+		resp, err := provider.Chat(ctx, messages, nil, model)
+		if err != nil {
+			return "", err
+		}
+		if resp == nil || strings.TrimSpace(resp.Content) == "" {
+			return "", fmt.Errorf("empty response from model")
+		}
+		return resp.Content, nil
+	}
+}
+
+// MemoryCondenser 返回使用 summary 用途模型的记忆整理器；每次调用时读取当前 provider，热加载后同样生效
+func (a *AgentLoop) MemoryCondenser() memory.Condenser {
+	return func(ctx context.Context, content string, maxTokens int) (string, error) {
+		provider, _, _ := a.runtimeSnapshot()
+		return NewMemoryCondenser(provider, a.modelForPurpose(PurposeSummary))(ctx, content, maxTokens)
+	}
+}
+
+// NewMemoryCondenserFromConfig 按配置创建整理器，优先使用 routing.purposes.summary 指定的模型
+func NewMemoryCondenserFromConfig(cfg *config.Config) (memory.Condenser, error) {
+	provider, err := NewProviderFromConfig(cfg, cfg.Agents.Defaults.Model, cfg.Agents.Defaults.MaxTokens, cfg.Agents.Defaults.Temperature)
+	if err != nil {
+		return nil, err
+	}
+	model := cfg.Agents.Defaults.Model
+	if router, ok := provider.(*ProviderRouter); ok {
+		if purposeModel := router.ModelFor(PurposeSummary); purposeModel != "" {
+			model = purposeModel
+		}
+	}
+	return NewMemoryCondenser(provider, model), nil
+}
@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"github.com/Lichas/maxclaw/internal/memory"
+)
+
+// SetMemoryIdentities sets the cross-channel user identities (memory.identities)
+// used to pick the user memory scope of a conversation.
+func (a *AgentLoop) SetMemoryIdentities(identities map[string][]string) {
+	ids := make(memory.Identities, len(identities))
+	for name, accounts := range identities {
+		ids[name] = append([]string(nil), accounts...)
+	}
+	a.runtimeMu.Lock()
+	defer a.runtimeMu.Unlock()
+	a.memoryIdentities = ids
+}
+
+func (a *AgentLoop) memoryIdentitiesSnapshot() memory.Identities {
+	a.runtimeMu.RLock()
+	defer a.runtimeMu.RUnlock()
+	return a.memoryIdentities
+}
+
+// MemoryScope returns the memory scopes that apply to a conversation of this agent.
+func (a *AgentLoop) MemoryScope(channel, chatID, sender string) memory.ScopeContext {
+	return memory.NewScopeContext(a.ProfileName, channel, chatID, sender, a.memoryIdentitiesSnapshot())
+}
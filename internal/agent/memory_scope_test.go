@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeScopedMemory(t *testing.T, workspace string, scope memory.Scope, content string) {
+	t.Helper()
+	path := scope.MemoryPath(workspace)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestContextBuilderInjectsOnlyApplicableMemoryScopes(t *testing.T) {
+	workspace := t.TempDir()
+	writeScopedMemory(t, workspace, memory.GlobalScope(), "team uses Go")
+	writeScopedMemory(t, workspace, memory.AgentScope("coder"), "coder agent notes")
+	writeScopedMemory(t, workspace, memory.UserScope("alice"), "alice prefers Chinese")
+	writeScopedMemory(t, workspace, memory.UserScope("slack:U2"), "bob prefers emojis")
+	writeScopedMemory(t, workspace, memory.ChatScope("telegram", "1001"), "DM planning notes")
+
+	loop := &AgentLoop{ProfileName: "coder"}
+	loop.SetMemoryIdentities(map[string][]string{"alice": {"telegram:1001", "slack:U1"}})
+	builder := NewContextBuilder(workspace)
+
+	// alice 在 Slack 群里：全局、agent 与她自己的记忆可见，DM 会话记忆与同事的偏好不可见
+	scope := loop.MemoryScope("slack", "C42", "U1")
+	assert.Equal(t, "alice", scope.User)
+	prompt := builder.BuildMessagesWithSkillRefs(nil, "hello", nil, nil, scope)[0].Content
+	assert.Contains(t, prompt, "## Long-term Memory\nteam uses Go")
+	assert.Contains(t, prompt, "## Long-term Memory (agent:coder)\ncoder agent notes")
+	assert.Contains(t, prompt, "## Long-term Memory (user:alice)\nalice prefers Chinese")
+	assert.NotContains(t, prompt, "DM planning notes")
+	assert.NotContains(t, prompt, "bob prefers emojis")
+	assert.Contains(t, prompt, "- user:alice memory: "+memory.UserScope("alice").MemoryPath(workspace))
+	assert.Contains(t, prompt, "- chat:slack:C42 memory: ")
+
+	// 同一用户回到 Telegram DM：会话记忆也可见
+	prompt = builder.BuildMessagesWithSkillRefs(nil, "hello", nil, nil, loop.MemoryScope("telegram", "1001", "1001"))[0].Content
+	assert.Contains(t, prompt, "alice prefers Chinese")
+	assert.Contains(t, prompt, "DM planning notes")
+
+	// 定时任务没有用户作用域
+	cron := loop.MemoryScope("telegram", "1001", "cron")
+	assert.Empty(t, cron.User)
+	prompt = builder.BuildMessagesWithSkillRefs(nil, "hello", nil, nil, cron)[0].Content
+	assert.NotContains(t, prompt, "alice prefers Chinese")
+}
+
+func TestFeedbackLearnerWritesLessonsToPersonalScope(t *testing.T) {
+	workspace := t.TempDir()
+	learner := NewFeedbackLearner(workspace)
+	ids := memory.Identities{"alice": {"telegram:1001"}}
+	alice := memory.NewScopeContext("", "telegram", "1001", "1001", ids)
+	colleague := memory.NewScopeContext("", "slack", "C42", "U2", ids)
+
+	lesson := learner.RecordFeedback(alice, &FeedbackResult{Type: FeedbackNegative, IssueType: "style"},
+		"write a summary", "Here is a long summary...", "too long, keep it to three bullets")
+	require.NotNil(t, lesson)
+	assert.Equal(t, "user:alice", lesson.Scope)
+
+	userMemory, err := os.ReadFile(memory.UserScope("alice").MemoryPath(workspace))
+	require.NoError(t, err)
+	assert.Contains(t, string(userMemory), "## User Feedback Lesson ["+lesson.ID[:8]+"]")
+	_, err = os.Stat(filepath.Join(workspace, "memory", "MEMORY.md"))
+	assert.True(t, os.IsNotExist(err), "lessons stay out of the global memory")
+
+	assert.Len(t, learner.GetRelevantLessons(alice, lesson.TaskType, 5), 1)
+	assert.Empty(t, learner.GetRelevantLessons(colleague, lesson.TaskType, 5))
+	assert.Empty(t, learner.BuildSystemPromptEnhancement(colleague, lesson.TaskType))
+
+	// 同样的反馈来自另一个用户时单独计数
+	other := learner.RecordFeedback(colleague, &FeedbackResult{Type: FeedbackNegative, IssueType: "style"},
+		"write a summary", "Here is a long summary...", "too long, keep it to three bullets")
+	require.NotNil(t, other)
+	assert.Equal(t, "user:slack:U2", other.Scope)
+	assert.Equal(t, 1, lesson.Occurrences)
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/Lichas/maxclaw/internal/logging"
+	"github.com/Lichas/maxclaw/pkg/tools"
+)
+
+// Processes returns background processes and shells started by the process tool.
+// An empty sessionKey lists every session.
+func (a *AgentLoop) Processes(sessionKey string) []tools.ProcessInfo {
+	if a.processes == nil {
+		return nil
+	}
+	return a.processes.List(sessionKey)
+}
+
+// ProcessOutput returns process output from offset without moving the agent's read cursor.
+func (a *AgentLoop) ProcessOutput(id string, offset int64, maxBytes int) (tools.ProcessOutput, error) {
+	if a.processes == nil {
+		return tools.ProcessOutput{}, fmt.Errorf("process %s not found", id)
+	}
+	return a.processes.Output(id, offset, maxBytes)
+}
+
+// HasProcess reports whether the process belongs to this agent.
+func (a *AgentLoop) HasProcess(id string) bool {
+	if a.processes == nil {
+		return false
+	}
+	_, err := a.processes.Get("", id)
+	return err == nil
+}
+
+// KillProcess terminates a process regardless of its session.
+func (a *AgentLoop) KillProcess(id string) (tools.ProcessInfo, error) {
+	if a.processes == nil {
+		return tools.ProcessInfo{}, fmt.Errorf("process %s not found", id)
+	}
+	return a.processes.Kill("", id)
+}
+
+// KillSessionProcesses terminates every process owned by a session, e.g. when it is deleted.
+func (a *AgentLoop) KillSessionProcesses(sessionKey string) int {
+	if a.processes == nil {
+		return 0
+	}
+	n := a.processes.KillSession(sessionKey)
+	if n > 0 {
+		if lg := logging.Get(); lg != nil && lg.Tools != nil {
+			lg.Tools.Info("process cleanup", "session", sessionKey, "killed", n)
+		}
+	}
+	return n
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/Lichas/maxclaw/internal/telemetry"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// NewProfileAgentLoop 基于主循环创建命名代理循环。
+// 新循环共享主循环的消息总线、定时任务、Web/Exec/MCP 配置，但拥有独立的
+// 工作区（SOUL.md/AGENTS.md/skills/sessions）、模型、工具白名单与执行模式。
+// 与 NewAgentLoop 不同，它不会修改 tools 包的全局工作区设置。
+func NewProfileAgentLoop(base *AgentLoop, profile config.ResolvedAgent, provider providers.LLMProvider) *AgentLoop {
+	if provider == nil {
+		provider, _, _ = base.runtimeSnapshot()
+	}
+	workspace := strings.TrimSpace(profile.Workspace)
+	if workspace == "" {
+		workspace = base.Workspace
+	}
+	model := strings.TrimSpace(profile.Model)
+	if model == "" {
+		_, model, _ = base.runtimeSnapshot()
+	}
+
+	loop := buildAgentLoop(
+		base.Bus,
+		provider,
+		workspace,
+		model,
+		profile.MaxToolIterations,
+		base.BraveAPIKey,
+		base.WebFetchOptions,
+		profileExecConfig(base.ExecConfig, profile.ExecBackend),
+		base.RestrictToWorkspace,
+		base.CronService,
+		base.MCPServers,
+		profile.EnableGlobalSkills,
+	)
+	loop.ProfileName = profile.Name
+	loop.UpdateRuntimeExecutionMode(profile.ExecutionMode)
+	loop.SetToolAllowlist(profile.Tools)
+	loop.SetDefaultSkills(profile.Skills)
+	loop.SetToolPolicy(base.toolPolicySnapshot())
+	loop.SetRequireReadBeforeEdit(base.requireReadBeforeEdit())
+	loop.SetWebSearchOptions(base.webSearchOptionsSnapshot())
+	loop.SetRetrievalOptions(base.retrievalOptionsSnapshot())
+	loop.SetMemoryIdentities(base.memoryIdentitiesSnapshot())
+	loop.SetEgressGuard(base.egressGuardSnapshot().ForAgent(profile.Name, profile.AllowDomains, profile.DenyDomains))
+	return loop
+}
+
+// SetToolAllowlist restricts the tools exposed to the model. Entries are tool
+// names or path-style globs such as "mcp_*"; an empty list allows every tool.
+func (a *AgentLoop) SetToolAllowlist(patterns []string) {
+	cleaned := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		if p = strings.TrimSpace(p); p != "" {
+			cleaned = append(cleaned, p)
+		}
+	}
+	a.runtimeMu.Lock()
+	defer a.runtimeMu.Unlock()
+	a.toolAllowlist = cleaned
+}
+
+// SetDefaultSkills sets the skills loaded when a request selects none explicitly.
+func (a *AgentLoop) SetDefaultSkills(skills []string) {
+	refs := normalizeSkillRefs(skills)
+	a.runtimeMu.Lock()
+	defer a.runtimeMu.Unlock()
+	a.defaultSkills = refs
+}
+
+func (a *AgentLoop) defaultSkillsSnapshot() []string {
+	a.runtimeMu.RLock()
+	defer a.runtimeMu.RUnlock()
+	return append([]string(nil), a.defaultSkills...)
+}
+
+func (a *AgentLoop) toolAllowed(name string) bool {
+	a.runtimeMu.RLock()
+	defer a.runtimeMu.RUnlock()
+	return toolNameAllowed(a.toolAllowlist, name)
+}
+
+func toolNameAllowed(allowlist []string, name string) bool {
+	return tools.MatchesToolPatterns(allowlist, name)
+}
+
+func (a *AgentLoop) toolDefinitions() []map[string]interface{} {
+	return a.tools.GetDefinitionsFiltered(a.toolAllowed)
+}
+
+func (a *AgentLoop) toolContext(ctx context.Context, channel, chatID, sessionKey, sender string) context.Context {
+	ctx = tools.WithRuntimeContextWithSession(ctx, channel, chatID, sessionKey)
+	ctx = tools.WithRuntimeSender(ctx, sender)
+	ctx = tools.WithMemoryScope(ctx, a.MemoryScope(channel, chatID, sender))
+	ctx = tools.WithRuntimeExecutionMode(ctx, a.executionModeSnapshot())
+	ctx = tools.WithFileReadTracker(ctx, a.fileTracker)
+	ctx = tools.WithEgressGuard(ctx, a.egressGuardSnapshot())
+	return tools.WithRuntimeWorkspace(ctx, a.Workspace, a.RestrictToWorkspace)
+}
+
+func (a *AgentLoop) executeTool(ctx context.Context, name string, params map[string]interface{}) (result string, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "tool.execute", attribute.String("tool.name", name))
+	start := time.Now()
+	defer func() {
+		telemetry.RecordTool(name, time.Since(start), err)
+		telemetry.EndSpan(span, err)
+	}()
+
+	if !a.toolAllowed(name) {
+		return "", fmt.Errorf("tool %s is not enabled for agent %s", name, a.profileLabel())
+	}
+	if err := a.checkToolPolicy(ctx, name, params); err != nil {
+		return "", err
+	}
+	return a.tools.Execute(ctx, name, params)
+}
+
+func (a *AgentLoop) profileLabel() string {
+	if strings.TrimSpace(a.ProfileName) == "" {
+		return config.DefaultAgentName
+	}
+	return a.ProfileName
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/providers"
+)
+
+// NewProviderFromConfig 按配置创建模型 Provider。
+// 配置了 routing.backends / routing.purposes 或模型有多个 API Key 时返回 ProviderRouter，否则返回单个 Provider。
+// routing.backends 只作用于 agents.defaults.model，其他模型（如 agent profile）只做 key 轮换。
+func NewProviderFromConfig(cfg *config.Config, model string, maxTokens int, temperature float64) (providers.LLMProvider, error) {
+	if model == "" {
+		model = cfg.Agents.Defaults.Model
+	}
+
+	mainBackends := []config.RoutingBackend{{Model: model}}
+	if model == cfg.Agents.Defaults.Model && len(cfg.Routing.Backends) > 0 {
+		mainBackends = cfg.Routing.Backends
+	}
+	var main []RouteBackend
+	for _, b := range mainBackends {
+		backend, err := buildRouteBackend(cfg, b, maxTokens, temperature)
+		if err != nil {
+			return nil, err
+		}
+		main = append(main, backend)
+	}
+
+	purposes := make([]string, 0, len(cfg.Routing.Purposes))
+	for purpose, purposeModel := range cfg.Routing.Purposes {
+		if strings.TrimSpace(purposeModel) != "" {
+			purposes = append(purposes, purpose)
+		}
+	}
+	sort.Strings(purposes)
+
+	if len(main) == 1 && len(main[0].Keys) == 1 && len(purposes) == 0 {
+		return main[0].Keys[0].Provider, nil
+	}
+
+	router := NewProviderRouter(model, main, cfg.Routing.FailureThreshold, time.Duration(cfg.Routing.CooldownSeconds)*time.Second)
+	for _, purpose := range purposes {
+		purposeModel := strings.TrimSpace(cfg.Routing.Purposes[purpose])
+		backend, err := buildRouteBackend(cfg, config.RoutingBackend{Model: purposeModel}, maxTokens, temperature)
+		if err != nil {
+			return nil, fmt.Errorf("routing purpose %s: %w", purpose, err)
+		}
+		router.SetPurpose(purpose, purposeModel, []RouteBackend{backend})
+	}
+	return router, nil
+}
+
+func buildRouteBackend(cfg *config.Config, b config.RoutingBackend, maxTokens int, temperature float64) (RouteBackend, error) {
+	keys := b.APIKeys
+	if len(keys) == 0 {
+		keys = cfg.GetAPIKeys(b.Model)
+	}
+	apiBase := b.APIBase
+	if apiBase == "" {
+		apiBase = cfg.GetAPIBase(b.Model)
+	}
+
+	name := b.Model
+	if b.APIBase != "" {
+		name += "@" + b.APIBase
+	}
+	backend := RouteBackend{Name: name, Model: b.Model, Weight: b.Weight}
+	for _, key := range keys {
+		if strings.TrimSpace(key) == "" {
+			continue
+		}
+		provider, err := providers.NewProvider(
+			key,
+			apiBase,
+			cfg.GetAPIFormat(b.Model),
+			b.Model,
+			maxTokens,
+			temperature,
+			cfg.SupportsImageInput,
+			providers.WithSafetySettings(cfg.Providers.Gemini.SafetySettings),
+			providers.WithReasoning(cfg.ReasoningFor),
+		)
+		if err != nil {
+			return backend, fmt.Errorf("failed to create provider for %s: %w", b.Model, err)
+		}
+		backend.Keys = append(backend.Keys, RouteKey{Label: maskAPIKey(key), Provider: provider})
+	}
+	if len(backend.Keys) == 0 {
+		return backend, fmt.Errorf("no API key configured for model %s", b.Model)
+	}
+	return backend, nil
+}
+
+// maskAPIKey 仅保留首尾少量字符用于状态展示
+func maskAPIKey(key string) string {
+	key = strings.TrimSpace(key)
+	if key == providers.LocalAPIKey || len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + "…" + key[len(key)-4:]
+}
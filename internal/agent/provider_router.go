@@ -0,0 +1,580 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/providers"
+)
+
+// 路由用途
+const (
+	PurposeMain     = "main"
+	PurposeSummary  = "summary"
+	PurposeTitle    = "title"
+	PurposeSubagent = "subagent"
+)
+
+const (
+	defaultRouterFailureThreshold = 3
+	defaultRouterCooldown         = 30 * time.Second
+	// 鉴权 / 欠费 / 模型不存在短时间内不会恢复，冷却时间按倍数延长
+	longCooldownFactor = 10
+)
+
+// RouteKey 后端下的一个凭据，Provider 以该 key 创建
+type RouteKey struct {
+	Label    string // 脱敏后的 key，用于状态展示
+	Provider providers.LLMProvider
+}
+
+// RouteBackend 路由后端：同一模型 / 端点下的一组凭据
+type RouteBackend struct {
+	Name   string
+	Model  string
+	Weight int
+	Keys   []RouteKey
+}
+
+// BackendHealth 后端健康状态（/api/status 展示）
+type BackendHealth struct {
+	Pool                string      `json:"pool"`
+	Name                string      `json:"name"`
+	Model               string      `json:"model"`
+	Weight              int         `json:"weight"`
+	State               string      `json:"state"`
+	Requests            int64       `json:"requests"`
+	Failures            int64       `json:"failures"`
+	ConsecutiveFailures int         `json:"consecutiveFailures"`
+	LastReason          string      `json:"lastReason,omitempty"`
+	LastError           string      `json:"lastError,omitempty"`
+	OpenUntil           *time.Time  `json:"openUntil,omitempty"`
+	Keys                []KeyHealth `json:"keys"`
+}
+
+// KeyHealth 凭据健康状态
+type KeyHealth struct {
+	Label      string     `json:"label"`
+	State      string     `json:"state"`
+	Requests   int64      `json:"requests"`
+	Failures   int64      `json:"failures"`
+	LastReason string     `json:"lastReason,omitempty"`
+	OpenUntil  *time.Time `json:"openUntil,omitempty"`
+}
+
+// circuitBreaker 熔断器：连续失败达到阈值后打开，冷却结束进入半开，半开期间一次失败即重新打开
+type circuitBreaker struct {
+	failures   int
+	openUntil  time.Time
+	lastReason ErrorReason
+	lastError  string
+}
+
+func (cb *circuitBreaker) allows(now time.Time) bool {
+	return cb.openUntil.IsZero() || !now.Before(cb.openUntil)
+}
+
+func (cb *circuitBreaker) state(now time.Time) string {
+	switch {
+	case cb.openUntil.IsZero():
+		return "closed"
+	case now.Before(cb.openUntil):
+		return "open"
+	default:
+		return "half_open"
+	}
+}
+
+func (cb *circuitBreaker) trip(now time.Time, cooldown time.Duration) {
+	cb.failures = 0
+	cb.openUntil = now.Add(cooldown)
+}
+
+func (cb *circuitBreaker) fail(now time.Time, threshold int, cooldown time.Duration) {
+	cb.failures++
+	if !cb.openUntil.IsZero() || cb.failures >= threshold {
+		cb.trip(now, cooldown)
+	}
+}
+
+func (cb *circuitBreaker) succeed() {
+	cb.failures = 0
+	cb.openUntil = time.Time{}
+}
+
+func (cb *circuitBreaker) openUntilPtr(now time.Time) *time.Time {
+	if cb.openUntil.IsZero() || !now.Before(cb.openUntil) {
+		return nil
+	}
+	t := cb.openUntil
+	return &t
+}
+
+type routeKey struct {
+	RouteKey
+	breaker  circuitBreaker
+	requests int64
+	failures int64
+}
+
+type routeBackend struct {
+	name     string
+	model    string
+	weight   int
+	current  int // 平滑加权轮询的当前权重
+	cursor   int // 下一个使用的 key
+	keys     []*routeKey
+	breaker  circuitBreaker
+	requests int64
+	failures int64
+}
+
+type routePool struct {
+	label    string
+	models   map[string]bool
+	backends []*routeBackend
+}
+
+// ProviderRouter 将多个后端包装为一个 LLMProvider：
+// 按权重轮询分摊请求，同一后端的多个 key 轮换使用，按 ErrorClassifier 的错误原因熔断并故障转移，
+// 并支持按用途（summary/title/subagent）路由到不同模型
+type ProviderRouter struct {
+	mu         sync.Mutex
+	classifier *ErrorClassifier
+	threshold  int
+	cooldown   time.Duration
+	now        func() time.Time
+
+	mainModel string
+	main      *routePool
+	pools     []*routePool
+	purposes  map[string]string
+}
+
+// NewProviderRouter 创建路由器，mainModel 为主模型名（agents.defaults.model）
+func NewProviderRouter(mainModel string, backends []RouteBackend, failureThreshold int, cooldown time.Duration) *ProviderRouter {
+	if failureThreshold <= 0 {
+		failureThreshold = defaultRouterFailureThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultRouterCooldown
+	}
+	r := &ProviderRouter{
+		classifier: NewErrorClassifier(),
+		threshold:  failureThreshold,
+		cooldown:   cooldown,
+		now:        time.Now,
+		mainModel:  mainModel,
+		purposes:   make(map[string]string),
+	}
+	r.main = newRoutePool(PurposeMain, backends)
+	r.main.models[routeModelKey(mainModel)] = true
+	r.pools = []*routePool{r.main}
+	return r
+}
+
+// SetPurpose 为用途指定模型及其后端；模型已在某个池中时直接复用
+func (r *ProviderRouter) SetPurpose(purpose, model string, backends []RouteBackend) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.purposes[purpose] = model
+	if pool := r.poolForModelLocked(model); pool != nil {
+		if pool != r.main && !strings.Contains(","+pool.label+",", ","+purpose+",") {
+			pool.label += "," + purpose
+		}
+		return
+	}
+	if len(backends) > 0 {
+		r.pools = append(r.pools, newRoutePool(purpose, backends))
+	}
+}
+
+// ModelFor 返回用途对应的模型，未配置时返回空
+func (r *ProviderRouter) ModelFor(purpose string) string {
+	if purpose == PurposeMain {
+		return r.mainModel
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.purposes[purpose]
+}
+
+func newRoutePool(label string, backends []RouteBackend) *routePool {
+	pool := &routePool{label: label, models: make(map[string]bool)}
+	for _, b := range backends {
+		if len(b.Keys) == 0 {
+			continue
+		}
+		backend := &routeBackend{name: b.Name, model: b.Model, weight: b.Weight}
+		if backend.weight <= 0 {
+			backend.weight = 1
+		}
+		if backend.name == "" {
+			backend.name = b.Model
+		}
+		for _, k := range b.Keys {
+			backend.keys = append(backend.keys, &routeKey{RouteKey: k})
+		}
+		pool.backends = append(pool.backends, backend)
+		pool.models[routeModelKey(b.Model)] = true
+	}
+	return pool
+}
+
+func routeModelKey(model string) string {
+	return strings.ToLower(strings.TrimSpace(model))
+}
+
+func (r *ProviderRouter) poolForModelLocked(model string) *routePool {
+	key := routeModelKey(model)
+	for _, pool := range r.pools {
+		if pool.models[key] {
+			return pool
+		}
+	}
+	return nil
+}
+
+// resolvePool 按请求的模型选择后端池；passthrough 为 true 时该模型不属于任何池，原样交给主池
+func (r *ProviderRouter) resolvePool(model string) (pool *routePool, passthrough bool) {
+	if strings.TrimSpace(model) == "" {
+		return r.main, false
+	}
+	if pool := r.poolForModelLocked(model); pool != nil {
+		return pool, false
+	}
+	return r.main, true
+}
+
+// plan 返回本次请求依次尝试的后端：平滑加权轮询选出首选，其余按权重作为故障转移顺序；
+// 全部熔断时按最早恢复的顺序尽力尝试，避免单后端配置下请求被直接拒绝
+func (r *ProviderRouter) plan(pool *routePool) []*routeBackend {
+	now := r.now()
+	var available, blocked []*routeBackend
+	for _, b := range pool.backends {
+		if b.breaker.allows(now) && b.hasUsableKey(now) {
+			available = append(available, b)
+		} else {
+			blocked = append(blocked, b)
+		}
+	}
+	if len(available) == 0 {
+		sort.SliceStable(blocked, func(i, j int) bool {
+			return blocked[i].recoversAt().Before(blocked[j].recoversAt())
+		})
+		return blocked
+	}
+
+	total := 0
+	var best *routeBackend
+	for _, b := range available {
+		b.current += b.weight
+		total += b.weight
+		if best == nil || b.current > best.current {
+			best = b
+		}
+	}
+	best.current -= total
+
+	order := []*routeBackend{best}
+	rest := make([]*routeBackend, 0, len(available)-1)
+	for _, b := range available {
+		if b != best {
+			rest = append(rest, b)
+		}
+	}
+	sort.SliceStable(rest, func(i, j int) bool { return rest[i].weight > rest[j].weight })
+	return append(order, rest...)
+}
+
+func (b *routeBackend) hasUsableKey(now time.Time) bool {
+	for _, k := range b.keys {
+		if k.breaker.allows(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// recoversAt 后端熔断结束且至少一个 key 恢复的时间
+func (b *routeBackend) recoversAt() time.Time {
+	keyAt := b.keys[0].breaker.openUntil
+	for _, k := range b.keys[1:] {
+		if k.breaker.openUntil.Before(keyAt) {
+			keyAt = k.breaker.openUntil
+		}
+	}
+	if keyAt.After(b.breaker.openUntil) {
+		return keyAt
+	}
+	return b.breaker.openUntil
+}
+
+// keyOrder 从轮换游标开始依次返回可用的 key；没有可用 key 时返回最早恢复的一个
+func (b *routeBackend) keyOrder(now time.Time) []*routeKey {
+	var order []*routeKey
+	for i := range b.keys {
+		k := b.keys[(b.cursor+i)%len(b.keys)]
+		if k.breaker.allows(now) {
+			order = append(order, k)
+		}
+	}
+	b.cursor = (b.cursor + 1) % len(b.keys)
+	if len(order) > 0 {
+		return order
+	}
+	earliest := b.keys[0]
+	for _, k := range b.keys[1:] {
+		if k.breaker.openUntil.Before(earliest.breaker.openUntil) {
+			earliest = k
+		}
+	}
+	return []*routeKey{earliest}
+}
+
+type routeFailover int
+
+const (
+	failoverKey     routeFailover = iota // 换同一后端的下一个 key
+	failoverBackend                      // 换下一个后端
+	failoverAbort                        // 请求本身的问题，换后端无益
+)
+
+// recordFailure 按错误原因更新熔断状态并决定下一步
+func (r *ProviderRouter) recordFailure(b *routeBackend, k *routeKey, err error) routeFailover {
+	ce := r.classifier.ClassifyError(err, providers.DetectProviderName(b.model), b.model, 0, 0, 0)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	b.failures++
+	k.failures++
+	b.breaker.lastReason, b.breaker.lastError = ce.Reason, ce.Message
+	k.breaker.lastReason, k.breaker.lastError = ce.Reason, ce.Message
+
+	switch {
+	case ce.ShouldRotateCredential || ce.Reason == ErrorReasonAuthPermanent:
+		cooldown := r.cooldown
+		if ce.Reason != ErrorReasonRateLimit {
+			cooldown *= longCooldownFactor
+		}
+		k.breaker.trip(now, cooldown)
+		return failoverKey
+	case ce.Reason == ErrorReasonModelNotFound:
+		b.breaker.trip(now, r.cooldown*longCooldownFactor)
+		return failoverBackend
+	case ce.IsTransient():
+		b.breaker.fail(now, r.threshold, r.cooldown)
+		return failoverBackend
+	default:
+		return failoverAbort
+	}
+}
+
+func (r *ProviderRouter) recordSuccess(b *routeBackend, k *routeKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b.breaker.succeed()
+	k.breaker.succeed()
+}
+
+// route 按计划依次尝试后端与 key；call 返回 emitted 表示已向调用方输出内容，此时不再重试
+func (r *ProviderRouter) route(ctx context.Context, model string, call func(p providers.LLMProvider, model string) (bool, error)) error {
+	r.mu.Lock()
+	pool, passthrough := r.resolvePool(model)
+	backends := r.plan(pool)
+	r.mu.Unlock()
+	if len(backends) == 0 {
+		return fmt.Errorf("no backend configured for model %s", model)
+	}
+
+	var lastErr error
+	for _, b := range backends {
+		r.mu.Lock()
+		keys := b.keyOrder(r.now())
+		r.mu.Unlock()
+
+	keyLoop:
+		for _, k := range keys {
+			callModel := b.model
+			if passthrough {
+				callModel = model
+			}
+			r.mu.Lock()
+			b.requests++
+			k.requests++
+			r.mu.Unlock()
+
+			emitted, err := call(k.Provider, callModel)
+			if err == nil {
+				r.recordSuccess(b, k)
+				return nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				return err
+			}
+			decision := r.recordFailure(b, k, err)
+			if emitted {
+				return err
+			}
+			switch decision {
+			case failoverKey:
+				continue
+			case failoverBackend:
+				break keyLoop
+			default:
+				return err
+			}
+		}
+	}
+	return lastErr
+}
+
+// Chat 实现 LLMProvider
+func (r *ProviderRouter) Chat(ctx context.Context, messages []providers.Message, tools []map[string]interface{}, model string) (*providers.Response, error) {
+	var resp *providers.Response
+	err := r.route(ctx, model, func(p providers.LLMProvider, callModel string) (bool, error) {
+		var err error
+		resp, err = p.Chat(ctx, messages, tools, callModel)
+		return false, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ChatStream 实现 LLMProvider；尚未输出任何内容时失败可无缝切换到下一个后端
+func (r *ProviderRouter) ChatStream(ctx context.Context, messages []providers.Message, tools []map[string]interface{}, model string, handler providers.StreamHandler) error {
+	err := r.route(ctx, model, func(p providers.LLMProvider, callModel string) (bool, error) {
+		wrapped := &routedStreamHandler{StreamHandler: handler}
+		err := p.ChatStream(ctx, messages, tools, callModel, wrapped)
+		return wrapped.emitted, err
+	})
+	if err != nil && handler != nil {
+		handler.OnError(err)
+	}
+	return err
+}
+
+// GetDefaultModel 实现 LLMProvider
+func (r *ProviderRouter) GetDefaultModel() string {
+	return r.mainModel
+}
+
+// SupportsImageInput 实现 LLMProvider，以所在池的首个后端为准
+func (r *ProviderRouter) SupportsImageInput(model string) bool {
+	r.mu.Lock()
+	pool, _ := r.resolvePool(model)
+	r.mu.Unlock()
+	if len(pool.backends) == 0 || len(pool.backends[0].keys) == 0 {
+		return false
+	}
+	if strings.TrimSpace(model) == "" {
+		model = r.mainModel
+	}
+	return pool.backends[0].keys[0].Provider.SupportsImageInput(model)
+}
+
+// Health 返回各后端与 key 的健康状态
+func (r *ProviderRouter) Health() []BackendHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	var result []BackendHealth
+	for _, pool := range r.pools {
+		for _, b := range pool.backends {
+			h := BackendHealth{
+				Pool:                pool.label,
+				Name:                b.name,
+				Model:               b.model,
+				Weight:              b.weight,
+				State:               b.breaker.state(now),
+				Requests:            b.requests,
+				Failures:            b.failures,
+				ConsecutiveFailures: b.breaker.failures,
+				LastReason:          string(b.breaker.lastReason),
+				LastError:           b.breaker.lastError,
+				OpenUntil:           b.breaker.openUntilPtr(now),
+			}
+			for _, k := range b.keys {
+				h.Keys = append(h.Keys, KeyHealth{
+					Label:      k.Label,
+					State:      k.breaker.state(now),
+					Requests:   k.requests,
+					Failures:   k.failures,
+					LastReason: string(k.breaker.lastReason),
+					OpenUntil:  k.breaker.openUntilPtr(now),
+				})
+			}
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
+// routedStreamHandler 拦截 OnError 以便故障转移，并记录是否已向调用方输出内容
+type routedStreamHandler struct {
+	providers.StreamHandler
+	emitted bool
+}
+
+func (h *routedStreamHandler) OnContent(token string) {
+	h.emitted = true
+	if h.StreamHandler != nil {
+		h.StreamHandler.OnContent(token)
+	}
+}
+
+func (h *routedStreamHandler) OnToolCallStart(id, name string) {
+	h.emitted = true
+	if h.StreamHandler != nil {
+		h.StreamHandler.OnToolCallStart(id, name)
+	}
+}
+
+func (h *routedStreamHandler) OnToolCallDelta(id, delta string) {
+	h.emitted = true
+	if h.StreamHandler != nil {
+		h.StreamHandler.OnToolCallDelta(id, delta)
+	}
+}
+
+func (h *routedStreamHandler) OnToolCallEnd(id string) {
+	if h.StreamHandler != nil {
+		h.StreamHandler.OnToolCallEnd(id)
+	}
+}
+
+func (h *routedStreamHandler) OnComplete() {
+	if h.StreamHandler != nil {
+		h.StreamHandler.OnComplete()
+	}
+}
+
+// OnError 由路由器在最终失败时统一转发
+func (h *routedStreamHandler) OnError(err error) {}
+
+func (h *routedStreamHandler) OnUsage(usage providers.Usage) {
+	if uh, ok := h.StreamHandler.(providers.UsageHandler); ok {
+		uh.OnUsage(usage)
+	}
+}
+
+func (h *routedStreamHandler) OnReasoningDelta(delta string) {
+	h.emitted = true
+	if rh, ok := h.StreamHandler.(providers.ReasoningHandler); ok {
+		rh.OnReasoningDelta(delta)
+	}
+}
+
+func (h *routedStreamHandler) OnReasoningBlock(block providers.ReasoningBlock) {
+	if rh, ok := h.StreamHandler.(providers.ReasoningHandler); ok {
+		rh.OnReasoningBlock(block)
+	}
+}
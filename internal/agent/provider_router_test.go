@@ -0,0 +1,243 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// routeTestProvider 按队列依次返回错误，队列耗尽后成功
+type routeTestProvider struct {
+	name   string
+	errs   []error
+	emit   bool
+	calls  int
+	models []string
+}
+
+func (p *routeTestProvider) next(model string) error {
+	p.calls++
+	p.models = append(p.models, model)
+	if len(p.errs) == 0 {
+		return nil
+	}
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+	return err
+}
+
+func (p *routeTestProvider) Chat(ctx context.Context, messages []providers.Message, tools []map[string]interface{}, model string) (*providers.Response, error) {
+	if err := p.next(model); err != nil {
+		return nil, err
+	}
+	return &providers.Response{Content: p.name}, nil
+}
+
+func (p *routeTestProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []map[string]interface{}, model string, handler providers.StreamHandler) error {
+	err := p.next(model)
+	if err == nil || p.emit {
+		handler.OnContent(p.name)
+	}
+	if err != nil {
+		handler.OnError(err)
+		return err
+	}
+	handler.OnComplete()
+	return nil
+}
+
+func (p *routeTestProvider) GetDefaultModel() string { return "" }
+
+func (p *routeTestProvider) SupportsImageInput(model string) bool { return p.name == "vision" }
+
+type routeTestHandler struct {
+	content string
+	errs    int
+}
+
+func (h *routeTestHandler) OnContent(token string)           { h.content += token }
+func (h *routeTestHandler) OnToolCallStart(id, name string)  {}
+func (h *routeTestHandler) OnToolCallDelta(id, delta string) {}
+func (h *routeTestHandler) OnToolCallEnd(id string)          {}
+func (h *routeTestHandler) OnComplete()                      {}
+func (h *routeTestHandler) OnError(err error)                { h.errs++ }
+
+var (
+	errRateLimited = errors.New("API error (status code 429): rate limit exceeded")
+	errOverloaded  = errors.New("API error (status code 503): service unavailable")
+	errBadRequest  = errors.New("API error (status code 400): invalid tool schema")
+)
+
+func routeBackendOf(model string, weight int, ps ...*routeTestProvider) RouteBackend {
+	b := RouteBackend{Name: model, Model: model, Weight: weight}
+	for _, p := range ps {
+		b.Keys = append(b.Keys, RouteKey{Label: p.name, Provider: p})
+	}
+	return b
+}
+
+func chatContent(t *testing.T, r *ProviderRouter, model string) string {
+	t.Helper()
+	resp, err := r.Chat(context.Background(), nil, nil, model)
+	require.NoError(t, err)
+	return resp.Content
+}
+
+func TestProviderRouterWeightedRoundRobin(t *testing.T) {
+	a := &routeTestProvider{name: "a"}
+	b := &routeTestProvider{name: "b"}
+	r := NewProviderRouter("model-a", []RouteBackend{
+		routeBackendOf("model-a", 3, a),
+		routeBackendOf("model-b", 1, b),
+	}, 0, 0)
+
+	var got []string
+	for i := 0; i < 8; i++ {
+		got = append(got, chatContent(t, r, "model-a"))
+	}
+	assert.Equal(t, []string{"a", "a", "b", "a", "a", "a", "b", "a"}, got)
+	assert.Equal(t, []string{"model-b", "model-b"}, b.models, "each backend should be called with its own model")
+}
+
+func TestProviderRouterRotatesKeysOnRateLimit(t *testing.T) {
+	k1 := &routeTestProvider{name: "k1", errs: []error{errRateLimited}}
+	k2 := &routeTestProvider{name: "k2"}
+	r := NewProviderRouter("m", []RouteBackend{routeBackendOf("m", 1, k1, k2)}, 0, time.Minute)
+
+	assert.Equal(t, "k2", chatContent(t, r, "m"))
+	// k1 冷却期间始终使用 k2
+	assert.Equal(t, "k2", chatContent(t, r, "m"))
+	assert.Equal(t, "k2", chatContent(t, r, "m"))
+	assert.Equal(t, 1, k1.calls)
+
+	health := r.Health()
+	require.Len(t, health, 1)
+	assert.Equal(t, "closed", health[0].State)
+	assert.Equal(t, "open", health[0].Keys[0].State)
+	assert.Equal(t, string(ErrorReasonRateLimit), health[0].Keys[0].LastReason)
+	assert.Equal(t, "closed", health[0].Keys[1].State)
+}
+
+func TestProviderRouterCircuitBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	a := &routeTestProvider{name: "a", errs: []error{errOverloaded, errOverloaded, errOverloaded}}
+	b := &routeTestProvider{name: "b"}
+	r := NewProviderRouter("m", []RouteBackend{
+		routeBackendOf("primary", 100, a),
+		routeBackendOf("secondary", 1, b),
+	}, 2, 30*time.Second)
+	r.now = func() time.Time { return now }
+
+	// 前两次 a 失败后故障转移到 b，第二次失败打开熔断
+	assert.Equal(t, "b", chatContent(t, r, "m"))
+	assert.Equal(t, "b", chatContent(t, r, "m"))
+	assert.Equal(t, "open", r.Health()[0].State)
+
+	// 熔断期间不再调用 a
+	assert.Equal(t, "b", chatContent(t, r, "m"))
+	assert.Equal(t, 2, a.calls)
+
+	// 冷却结束进入半开，一次失败立即重新打开
+	now = now.Add(31 * time.Second)
+	assert.Equal(t, "half_open", r.Health()[0].State)
+	assert.Equal(t, "b", chatContent(t, r, "m"))
+	assert.Equal(t, "open", r.Health()[0].State)
+
+	// 再次冷却后探测成功，熔断关闭
+	now = now.Add(31 * time.Second)
+	assert.Equal(t, "a", chatContent(t, r, "m"))
+	assert.Equal(t, "closed", r.Health()[0].State)
+	assert.Equal(t, int64(3), r.Health()[0].Failures)
+}
+
+func TestProviderRouterDoesNotFailoverRequestErrors(t *testing.T) {
+	a := &routeTestProvider{name: "a", errs: []error{errBadRequest}}
+	b := &routeTestProvider{name: "b"}
+	r := NewProviderRouter("m", []RouteBackend{
+		routeBackendOf("a", 10, a),
+		routeBackendOf("b", 1, b),
+	}, 0, 0)
+
+	_, err := r.Chat(context.Background(), nil, nil, "m")
+	require.ErrorIs(t, err, errBadRequest)
+	assert.Equal(t, 0, b.calls)
+}
+
+func TestProviderRouterStreamFailover(t *testing.T) {
+	a := &routeTestProvider{name: "a", errs: []error{errOverloaded}}
+	b := &routeTestProvider{name: "b"}
+	r := NewProviderRouter("m", []RouteBackend{
+		routeBackendOf("a", 10, a),
+		routeBackendOf("b", 1, b),
+	}, 0, 0)
+
+	handler := &routeTestHandler{}
+	require.NoError(t, r.ChatStream(context.Background(), nil, nil, "m", handler))
+	assert.Equal(t, "b", handler.content)
+	assert.Equal(t, 0, handler.errs, "errors from failed-over backends must not reach the caller")
+
+	// 已经输出内容后失败不能再切换，否则调用方会收到重复内容
+	a.errs, a.emit = []error{errOverloaded}, true
+	handler = &routeTestHandler{}
+	r2 := NewProviderRouter("m", []RouteBackend{
+		routeBackendOf("a", 10, a),
+		routeBackendOf("b", 1, b),
+	}, 0, 0)
+	require.Error(t, r2.ChatStream(context.Background(), nil, nil, "m", handler))
+	assert.Equal(t, "a", handler.content)
+	assert.Equal(t, 1, handler.errs)
+	assert.Equal(t, 1, b.calls)
+}
+
+func TestProviderRouterPurposeRouting(t *testing.T) {
+	main := &routeTestProvider{name: "main"}
+	cheap := &routeTestProvider{name: "cheap"}
+	r := NewProviderRouter("big-model", []RouteBackend{routeBackendOf("big-model", 1, main)}, 0, 0)
+	r.SetPurpose(PurposeSummary, "small-model", []RouteBackend{routeBackendOf("small-model", 1, cheap)})
+	r.SetPurpose(PurposeSubagent, "small-model", nil)
+
+	assert.Equal(t, "big-model", r.ModelFor(PurposeMain))
+	assert.Equal(t, "small-model", r.ModelFor(PurposeSummary))
+	assert.Equal(t, "", r.ModelFor(PurposeTitle))
+
+	assert.Equal(t, "cheap", chatContent(t, r, r.ModelFor(PurposeSubagent)))
+	assert.Equal(t, "main", chatContent(t, r, ""))
+	// 未配置的模型交给主池并保留原模型名
+	assert.Equal(t, "main", chatContent(t, r, "other-model"))
+	assert.Equal(t, "other-model", main.models[len(main.models)-1])
+
+	health := r.Health()
+	require.Len(t, health, 2)
+	assert.Equal(t, "summary,subagent", health[1].Pool)
+}
+
+func TestNewProviderFromConfigBuildsRouter(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Model = "gpt-4o-mini"
+	cfg.Providers.OpenAI.APIKey = "sk-test-primary"
+
+	provider, err := NewProviderFromConfig(cfg, "", 1024, 0.7)
+	require.NoError(t, err)
+	_, isRouter := provider.(*ProviderRouter)
+	assert.False(t, isRouter, "a single key without routing should not be wrapped")
+
+	cfg.Providers.OpenAI.APIKeys = []string{"sk-test-primary", "sk-test-secondary"}
+	cfg.Routing.Purposes = map[string]string{PurposeSummary: "gpt-4o-mini"}
+	provider, err = NewProviderFromConfig(cfg, "", 1024, 0.7)
+	require.NoError(t, err)
+	router, ok := provider.(*ProviderRouter)
+	require.True(t, ok)
+	assert.Equal(t, "gpt-4o-mini", router.ModelFor(PurposeSummary))
+
+	health := router.Health()
+	require.Len(t, health, 1)
+	require.Len(t, health[0].Keys, 2)
+	assert.Equal(t, "sk-t…mary", health[0].Keys[0].Label)
+	assert.Equal(t, "sk-t…dary", health[0].Keys[1].Label)
+}
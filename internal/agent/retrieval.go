@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/Lichas/maxclaw/internal/rag"
+	"github.com/Lichas/maxclaw/pkg/tools"
+)
+
+// DefaultIndexDir is where workspace retrieval indexes are stored.
+func DefaultIndexDir() string {
+	return filepath.Join(config.GetDataDir(), "index")
+}
+
+// BuildRetrievalOptions converts tools.retrieval config to index options. The
+// embedder is nil (keyword-only search) when no embedding model is configured.
+func BuildRetrievalOptions(cfg *config.Config) (rag.Options, error) {
+	retrieval := cfg.Tools.Retrieval
+	opts := rag.Options{
+		IndexDir:    DefaultIndexDir(),
+		ChunkChars:  retrieval.ChunkChars,
+		MaxFileSize: int64(retrieval.MaxFileSizeKB) * 1024,
+		MaxFiles:    retrieval.MaxFiles,
+		Exclude:     append([]string(nil), retrieval.Exclude...),
+	}
+	embedding := retrieval.Embedding
+	model := strings.TrimSpace(embedding.Model)
+	if model == "" {
+		return opts, nil
+	}
+
+	apiBase := strings.TrimSpace(embedding.APIBase)
+	apiKey := strings.TrimSpace(embedding.APIKey)
+	if name := strings.TrimSpace(embedding.Provider); name != "" {
+		provider, ok := cfg.Providers.ToMap()[name]
+		if !ok {
+			return opts, fmt.Errorf("tools.retrieval.embedding.provider %q is not a configured provider", name)
+		}
+		if apiBase == "" {
+			apiBase = provider.APIBase
+		}
+		if apiBase == "" {
+			for _, spec := range providers.ProviderSpecs {
+				if spec.Name == name {
+					apiBase = spec.DefaultAPIBase
+				}
+			}
+		}
+		if apiKey == "" {
+			apiKey = provider.APIKey
+		}
+	}
+	if apiBase == "" {
+		return opts, fmt.Errorf("tools.retrieval.embedding needs apiBase or a provider with an API base")
+	}
+	opts.Embedder = rag.NewOpenAIEmbedder(apiBase, apiKey, model, embedding.BatchSize, time.Duration(embedding.Timeout)*time.Second)
+	return opts, nil
+}
+
+// ValidateRetrievalConfig checks that the embedding endpoint can be resolved.
+func ValidateRetrievalConfig(cfg *config.Config) error {
+	_, err := BuildRetrievalOptions(cfg)
+	return err
+}
+
+// SetRetrievalOptions replaces the search_workspace tool with one using the given index options.
+func (a *AgentLoop) SetRetrievalOptions(opts rag.Options) {
+	a.runtimeMu.Lock()
+	a.retrieval = &opts
+	a.runtimeMu.Unlock()
+
+	a.tools.Register(tools.NewSearchWorkspaceTool(opts))
+}
+
+// retrievalOptionsSnapshot returns the configured index options; before
+// SetRetrievalOptions it uses keyword-only search with the default index dir.
+func (a *AgentLoop) retrievalOptionsSnapshot() rag.Options {
+	a.runtimeMu.RLock()
+	defer a.runtimeMu.RUnlock()
+	if a.retrieval != nil {
+		return *a.retrieval
+	}
+	return rag.Options{IndexDir: DefaultIndexDir()}
+}
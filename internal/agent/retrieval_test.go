@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/rag"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildRetrievalOptionsResolvesEmbeddingEndpoint(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Tools.Retrieval.Exclude = []string{"archive/"}
+	cfg.Tools.Retrieval.MaxFileSizeKB = 256
+
+	opts, err := BuildRetrievalOptions(cfg)
+	require.NoError(t, err)
+	assert.Nil(t, opts.Embedder)
+	assert.Equal(t, DefaultIndexDir(), opts.IndexDir)
+	assert.Equal(t, int64(256*1024), opts.MaxFileSize)
+	assert.Equal(t, []string{"archive/"}, opts.Exclude)
+
+	cfg.Tools.Retrieval.Embedding = config.EmbeddingConfig{Provider: "vllm", Model: "bge-m3"}
+	cfg.Providers.VLLM = config.ProviderConfig{APIKey: "local", APIBase: "http://127.0.0.1:8000/v1"}
+	opts, err = BuildRetrievalOptions(cfg)
+	require.NoError(t, err)
+	embedder, ok := opts.Embedder.(*rag.OpenAIEmbedder)
+	require.True(t, ok)
+	assert.Equal(t, "http://127.0.0.1:8000/v1", embedder.BaseURL)
+	assert.Equal(t, "local", embedder.APIKey)
+	assert.Equal(t, "bge-m3", embedder.Name())
+
+	// 未设置 apiBase 时使用 provider 的默认地址
+	cfg.Tools.Retrieval.Embedding = config.EmbeddingConfig{Provider: "openai", Model: "text-embedding-3-small"}
+	cfg.Providers.OpenAI.APIKey = "sk-openai"
+	opts, err = BuildRetrievalOptions(cfg)
+	require.NoError(t, err)
+	embedder = opts.Embedder.(*rag.OpenAIEmbedder)
+	assert.Equal(t, "https://api.openai.com/v1", embedder.BaseURL)
+	assert.Equal(t, "sk-openai", embedder.APIKey)
+
+	cfg.Tools.Retrieval.Embedding = config.EmbeddingConfig{Provider: "nope", Model: "m"}
+	assert.Error(t, ValidateRetrievalConfig(cfg))
+	cfg.Tools.Retrieval.Embedding = config.EmbeddingConfig{Model: "m"}
+	assert.Error(t, ValidateRetrievalConfig(cfg))
+	cfg.Tools.Retrieval.Embedding = config.EmbeddingConfig{APIBase: "http://localhost:11434/v1", Model: "nomic-embed-text"}
+	require.NoError(t, ValidateRetrievalConfig(cfg))
+}
+
+func TestProfileLoopInheritsRetrievalOptions(t *testing.T) {
+	base := NewAgentLoop(bus.NewMessageBus(10), &staticProvider{}, t.TempDir(), "test-model", 3, "",
+		tools.WebFetchOptions{}, config.ExecToolConfig{Timeout: 5}, false, nil, nil, false)
+	_, ok := base.tools.Get("search_workspace")
+	assert.True(t, ok)
+
+	indexDir := t.TempDir()
+	base.SetRetrievalOptions(rag.Options{IndexDir: indexDir, Exclude: []string{"drafts/"}})
+	profile := NewProfileAgentLoop(base, config.ResolvedAgent{Name: "researcher", Workspace: t.TempDir()}, nil)
+	opts := profile.retrievalOptionsSnapshot()
+	assert.Equal(t, indexDir, opts.IndexDir)
+	assert.Equal(t, []string{"drafts/"}, opts.Exclude)
+}
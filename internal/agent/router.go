@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/logging"
+)
+
+// AgentRouter 将入站消息分发到命名代理循环。
+// 选择顺序：消息显式指定的 Agent → 路由规则（channel/chatId/sender）→ 默认代理。
+type AgentRouter struct {
+	Bus *bus.MessageBus
+
+	defaultLoop *AgentLoop
+	mu          sync.RWMutex
+	profiles    map[string]*AgentLoop
+	routes      []config.AgentRoute
+}
+
+// NewAgentRouter 创建代理路由器
+func NewAgentRouter(defaultLoop *AgentLoop, routes []config.AgentRoute) *AgentRouter {
+	r := &AgentRouter{
+		defaultLoop: defaultLoop,
+		profiles:    make(map[string]*AgentLoop),
+		routes:      append([]config.AgentRoute(nil), routes...),
+	}
+	if defaultLoop != nil {
+		r.Bus = defaultLoop.Bus
+	}
+	return r
+}
+
+// AddProfile 注册命名代理
+func (r *AgentRouter) AddProfile(name string, loop *AgentLoop) {
+	name = strings.TrimSpace(name)
+	if name == "" || loop == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.profiles[strings.ToLower(name)] = loop
+}
+
+// SetRoutes replaces the routing rules (used by config hot reload).
+func (r *AgentRouter) SetRoutes(routes []config.AgentRoute) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append([]config.AgentRoute(nil), routes...)
+}
+
+// Default returns the default agent loop.
+func (r *AgentRouter) Default() *AgentLoop {
+	return r.defaultLoop
+}
+
+// Get returns the loop for the named profile. Empty or "default" selects the default loop.
+func (r *AgentRouter) Get(name string) (*AgentLoop, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == config.DefaultAgentName {
+		return r.defaultLoop, r.defaultLoop != nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	loop, ok := r.profiles[name]
+	return loop, ok
+}
+
+// Names returns registered profile names (excluding the default agent) in stable order.
+func (r *AgentRouter) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.profiles))
+	for _, loop := range r.profiles {
+		names = append(names, loop.ProfileName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve picks the agent loop responsible for an inbound message.
+func (r *AgentRouter) Resolve(msg *bus.InboundMessage) *AgentLoop {
+	if msg == nil {
+		return r.defaultLoop
+	}
+	if loop, ok := r.Get(msg.Agent); ok && strings.TrimSpace(msg.Agent) != "" {
+		return loop
+	}
+	r.mu.RLock()
+	routes := r.routes
+	r.mu.RUnlock()
+	if name := config.MatchAgentRoute(routes, msg.Channel, msg.ChatID, msg.SenderID); name != "" {
+		if loop, ok := r.Get(name); ok {
+			return loop
+		}
+		if lg := logging.Get(); lg != nil && lg.Session != nil {
+			lg.Session.Warn("agent route target not found, using default agent", "target", name, "channel", msg.Channel, "chat_id", msg.ChatID)
+		}
+	}
+	return r.defaultLoop
+}
+
+// HandleInterruption forwards an interruption to the agent owning the message.
+func (r *AgentRouter) HandleInterruption(msg *bus.InboundMessage, explicitMode ...InterruptMode) InterruptMode {
+	loop := r.Resolve(msg)
+	if loop == nil {
+		return InterruptNone
+	}
+	return loop.HandleInterruption(msg, explicitMode...)
+}
+
+// Run 消费入站消息并按路由分发给对应代理
+func (r *AgentRouter) Run(ctx context.Context) error {
+	if r.defaultLoop == nil || r.Bus == nil {
+		return fmt.Errorf("agent router has no default agent")
+	}
+
+	r.defaultLoop.ensureMCPConnected(ctx)
+	for _, name := range r.Names() {
+		if loop, ok := r.Get(name); ok {
+			loop.ensureMCPConnected(ctx)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		msg, err := r.Bus.ConsumeInbound(ctx)
+		if err != nil {
+			if err == context.Canceled || err == context.DeadlineExceeded {
+				return nil
+			}
+			continue
+		}
+
+		loop := r.Resolve(msg)
+		response, err := loop.ProcessMessage(ctx, msg)
+		if err != nil {
+			r.Bus.PublishOutbound(bus.NewOutboundMessage(
+				msg.Channel,
+				msg.ChatID,
+				fmt.Sprintf("Error: %v", err),
+			))
+			continue
+		}
+
+		if response != nil {
+			r.Bus.PublishOutbound(response)
+		}
+	}
+}
+
+// Close 释放所有代理资源
+func (r *AgentRouter) Close() error {
+	var firstErr error
+	r.mu.RLock()
+	loops := make([]*AgentLoop, 0, len(r.profiles))
+	for _, loop := range r.profiles {
+		loops = append(loops, loop)
+	}
+	r.mu.RUnlock()
+
+	for _, loop := range loops {
+		if err := loop.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if r.defaultLoop != nil {
+		if err := r.defaultLoop.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
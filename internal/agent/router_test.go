@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRouterTestLoop(t *testing.T) *AgentLoop {
+	t.Helper()
+	return NewAgentLoop(
+		bus.NewMessageBus(10),
+		&staticProvider{},
+		t.TempDir(),
+		"test-model",
+		3,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		nil,
+		nil,
+		false,
+	)
+}
+
+func TestAgentRouterResolvesExplicitAgentThenRoutes(t *testing.T) {
+	base := newRouterTestLoop(t)
+	research := NewProfileAgentLoop(base, config.ResolvedAgent{
+		Name:      "research",
+		Workspace: t.TempDir(),
+		Model:     "research-model",
+		Tools:     []string{"web_*"},
+	}, nil)
+
+	router := NewAgentRouter(base, []config.AgentRoute{
+		{Agent: "research", Channel: "telegram", ChatID: "42"},
+	})
+	router.AddProfile("research", research)
+
+	assert.Same(t, research, router.Resolve(bus.NewInboundMessage("telegram", "u", "42", "hi")))
+	assert.Same(t, base, router.Resolve(bus.NewInboundMessage("telegram", "u", "7", "hi")))
+
+	explicit := bus.NewInboundMessage("telegram", "u", "7", "hi")
+	explicit.Agent = "Research"
+	assert.Same(t, research, router.Resolve(explicit))
+
+	unknown := bus.NewInboundMessage("telegram", "u", "7", "hi")
+	unknown.Agent = "missing"
+	assert.Same(t, base, router.Resolve(unknown))
+
+	assert.Equal(t, []string{"research"}, router.Names())
+
+	// 热加载替换路由规则
+	router.SetRoutes([]config.AgentRoute{{Agent: "research", Channel: "discord"}})
+	assert.Same(t, base, router.Resolve(bus.NewInboundMessage("telegram", "u", "42", "hi")))
+	assert.Same(t, research, router.Resolve(bus.NewInboundMessage("discord", "u", "1", "hi")))
+}
+
+func TestProfileAgentLoopEnforcesToolAllowlist(t *testing.T) {
+	base := newRouterTestLoop(t)
+	profile := NewProfileAgentLoop(base, config.ResolvedAgent{
+		Name:  "research",
+		Tools: []string{"web_*", "message"},
+	}, nil)
+
+	names := profile.ListToolNames()
+	assert.Contains(t, names, "web_search")
+	assert.Contains(t, names, "web_fetch")
+	assert.Contains(t, names, "message")
+	assert.NotContains(t, names, "exec")
+	assert.NotContains(t, names, "write_file")
+
+	for _, def := range profile.toolDefinitions() {
+		fn, _ := def["function"].(map[string]interface{})
+		assert.NotEqual(t, "exec", fn["name"])
+	}
+
+	_, err := profile.ExecuteToolWithSession(context.Background(), "exec", map[string]interface{}{"command": "echo hi"}, "s", "cli", "c")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not enabled for agent research")
+
+	assert.Contains(t, base.ListToolNames(), "exec")
+}
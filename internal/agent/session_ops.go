@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/memory"
+	"github.com/Lichas/maxclaw/internal/session"
+)
+
+// ListSessions 列出已保存的会话
+func (a *AgentLoop) ListSessions() ([]session.Summary, error) {
+	return a.sessions.List()
+}
+
+// SessionHistory 返回会话中的消息
+func (a *AgentLoop) SessionHistory(sessionKey string) []session.Message {
+	return a.sessions.GetOrCreate(sessionKey).GetHistory()
+}
+
+// UndoLastTurn 从会话中删除最后一轮对话并保存，返回被删除的消息；不会回滚该轮工具造成的文件改动
+func (a *AgentLoop) UndoLastTurn(sessionKey string) ([]session.Message, error) {
+	sess := a.sessions.GetOrCreate(sessionKey)
+	removed := sess.RemoveLastTurn()
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	if err := a.sessions.Save(sess); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// CompressSession 用上下文压缩器把会话中较早的对话替换为摘要，返回压缩前后的消息数；
+// 压缩前会先把整段对话归档到 memory，原始内容不会丢失
+func (a *AgentLoop) CompressSession(ctx context.Context, sessionKey string) (int, int, error) {
+	if a.Lifecycle == nil || !a.Lifecycle.Enabled || !a.Lifecycle.EnableCompression || a.Lifecycle.ContextCompressor == nil {
+		return 0, 0, fmt.Errorf("context compression is not available")
+	}
+	sess := a.sessions.GetOrCreate(sessionKey)
+	before := len(sess.Messages)
+	if before == 0 {
+		return 0, 0, nil
+	}
+
+	original := append([]session.Message(nil), sess.Messages...)
+	compressed, err := a.Lifecycle.CompressContext(ctx, convertProviderMessagesToCompressor(a.convertSessionMessages(original)), "")
+	if err != nil {
+		return before, before, err
+	}
+	if len(compressed) >= before {
+		return before, before, nil
+	}
+
+	if _, err := memory.ArchiveSessionAll(a.Workspace, sess); err != nil {
+		return before, before, fmt.Errorf("archive session before compression: %w", err)
+	}
+	sess.Messages = restoreCompressedMessages(original, compressed)
+	sess.LastConsolidated = len(sess.Messages)
+	if err := a.sessions.Save(sess); err != nil {
+		return before, before, err
+	}
+	return before, len(sess.Messages), nil
+}
+
+// restoreCompressedMessages 把压缩结果转回会话消息：保留下来的消息沿用原有时间与时间线，摘要使用其前一条消息的时间
+func restoreCompressedMessages(original []session.Message, compressed []CompressorMessage) []session.Message {
+	result := make([]session.Message, 0, len(compressed))
+	next := 0
+	lastTime := time.Now()
+	if len(original) > 0 {
+		lastTime = original[0].Timestamp
+	}
+	for _, msg := range compressed {
+		matched := false
+		for i := next; i < len(original); i++ {
+			if original[i].Role == msg.Role && original[i].Content == msg.Content {
+				result = append(result, original[i])
+				lastTime = original[i].Timestamp
+				next = i + 1
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			result = append(result, session.Message{Role: msg.Role, Content: msg.Content, Timestamp: lastTime})
+		}
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSessionOpsTestLoop(t *testing.T) *AgentLoop {
+	t.Helper()
+	return NewAgentLoop(
+		bus.NewMessageBus(10),
+		&staticProvider{},
+		t.TempDir(),
+		"test-model",
+		3,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		nil,
+		nil,
+		false,
+	)
+}
+
+func TestAgentLoopUndoLastTurn(t *testing.T) {
+	loop := newSessionOpsTestLoop(t)
+
+	removed, err := loop.UndoLastTurn("cli:direct")
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+
+	_, err = loop.ProcessDirect(context.Background(), "first", "cli:direct", "cli_plain", "direct")
+	require.NoError(t, err)
+	_, err = loop.ProcessDirect(context.Background(), "second", "cli:direct", "cli_plain", "direct")
+	require.NoError(t, err)
+	require.Len(t, loop.SessionHistory("cli:direct"), 4)
+
+	removed, err = loop.UndoLastTurn("cli:direct")
+	require.NoError(t, err)
+	require.Len(t, removed, 2)
+	assert.Equal(t, "second", removed[0].Content)
+
+	summaries, err := loop.ListSessions()
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	assert.Equal(t, "cli:direct", summaries[0].Key)
+	assert.Equal(t, 2, summaries[0].MessageCount, "undo is persisted")
+}
+
+func TestAgentLoopCompressSession(t *testing.T) {
+	loop := newSessionOpsTestLoop(t)
+
+	_, _, err := loop.CompressSession(context.Background(), "cli:direct")
+	require.Error(t, err, "compression needs the lifecycle")
+
+	loop.InitializeLifecycle()
+	before, after, err := loop.CompressSession(context.Background(), "cli:direct")
+	require.NoError(t, err)
+	assert.Zero(t, before)
+	assert.Zero(t, after)
+
+	sess := loop.sessions.GetOrCreate("cli:direct")
+	for i := 0; i < 20; i++ {
+		sess.AddMessage("user", "question "+strings.Repeat("u", 400))
+		sess.AddMessage("assistant", "answer "+strings.Repeat("a", 400))
+	}
+	require.NoError(t, loop.sessions.Save(sess))
+	firstTimestamp := sess.Messages[0].Timestamp
+
+	before, after, err = loop.CompressSession(context.Background(), "cli:direct")
+	require.NoError(t, err)
+	assert.Equal(t, 40, before)
+	assert.Less(t, after, before)
+
+	history := loop.SessionHistory("cli:direct")
+	require.Len(t, history, after)
+	assert.Equal(t, firstTimestamp, history[0].Timestamp, "kept messages keep their metadata")
+	assert.Equal(t, "system", history[3].Role, "summary follows the protected head")
+	assert.Equal(t, len(history), loop.sessions.GetOrCreate("cli:direct").LastConsolidated)
+
+	body, err := os.ReadFile(filepath.Join(loop.Workspace, "memory", "HISTORY.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "session: cli:direct", "original turns are archived first")
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/Lichas/maxclaw/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// telemetryClassifier 仅用于给失败的 LLM 请求打上错误原因标签
+var telemetryClassifier = NewErrorClassifier()
+
+// chatStreamWithTelemetry 调用 ChatStream，并记录 llm.chat span、延迟、token 与按原因分类的错误
+func (a *AgentLoop) chatStreamWithTelemetry(
+	ctx context.Context,
+	provider providers.LLMProvider,
+	messages []providers.Message,
+	toolDefs []map[string]interface{},
+	model string,
+	iteration int,
+	handler *streamHandler,
+) error {
+	providerName := providers.DetectProviderName(model)
+	ctx, span := telemetry.StartSpan(ctx, "llm.chat",
+		attribute.String("llm.provider", providerName),
+		attribute.String("llm.model", model),
+		attribute.Int("agent.iteration", iteration),
+		attribute.Int("llm.messages", len(messages)),
+	)
+
+	start := time.Now()
+	err := provider.ChatStream(ctx, messages, toolDefs, model, handler)
+	reason := ""
+	if err != nil {
+		reason = string(telemetryClassifier.ClassifyError(err, providerName, model, 0, 0, 0).Reason)
+		span.SetAttributes(attribute.String("llm.error_reason", reason))
+	}
+	telemetry.RecordLLMCall(providerName, model, time.Since(start), reason)
+
+	if handler.usage != nil {
+		telemetry.RecordLLMTokens(providerName, model, handler.usage.PromptTokens, handler.usage.CompletionTokens)
+		span.SetAttributes(
+			attribute.Int("llm.prompt_tokens", handler.usage.PromptTokens),
+			attribute.Int("llm.completion_tokens", handler.usage.CompletionTokens),
+		)
+	}
+	span.SetAttributes(attribute.Int("llm.tool_calls", len(handler.toolCalls)))
+	telemetry.EndSpan(span, err)
+	return err
+}
@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/cron"
+	"github.com/Lichas/maxclaw/internal/logging"
+	"github.com/Lichas/maxclaw/internal/telemetry"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestProcessMessageTracesLLMAndToolCalls(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(prev)
+
+	workspace := t.TempDir()
+	loop := NewAgentLoop(
+		bus.NewMessageBus(10),
+		&testProvider{},
+		workspace,
+		"anthropic/claude-sonnet-4-5",
+		3,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		cron.NewService(filepath.Join(workspace, ".cron", "jobs.json")),
+		nil,
+		false,
+	)
+
+	// 上游（如渠道）已开启链路时，消息处理挂在其下
+	upstreamCtx, upstream := telemetry.StartSpan(context.Background(), "channel.receive")
+	msg := bus.NewInboundMessage("telegram", "user-1", "chat-42", "set a reminder")
+	msg.TraceParent = telemetry.InjectTraceParent(upstreamCtx)
+
+	resp, err := loop.ProcessMessage(context.Background(), msg)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	upstream.End()
+
+	byName := map[string][]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+	require.Len(t, byName["agent.message"], 1)
+	root := byName["agent.message"][0]
+	assert.Equal(t, upstream.SpanContext().SpanID(), root.Parent.SpanID())
+
+	require.Len(t, byName["llm.chat"], 2)
+	require.Len(t, byName["tool.execute"], 1)
+	for _, span := range append(byName["llm.chat"], byName["tool.execute"]...) {
+		assert.Equal(t, root.SpanContext.SpanID(), span.Parent.SpanID(), span.Name)
+	}
+	assert.Contains(t, byName["llm.chat"][0].Attributes, attribute.String("llm.provider", "anthropic"))
+	assert.Contains(t, byName["tool.execute"][0].Attributes, attribute.String("tool.name", "cron"))
+
+	// 出站消息携带 traceparent，投递 span 与入站处理同属一条链路
+	require.NotEmpty(t, resp.TraceParent)
+	_, send := telemetry.StartSpan(telemetry.ExtractTraceParent(context.Background(), resp.TraceParent), "channel.send")
+	send.End()
+	assert.Equal(t, root.SpanContext.TraceID(), send.SpanContext().TraceID())
+}
+
+func TestProcessMessageLogsCarryCorrelationIDs(t *testing.T) {
+	lg, err := logging.Init(t.TempDir())
+	require.NoError(t, err)
+	var sessionBuf, toolsBuf bytes.Buffer
+	prevSession, prevTools := lg.Session, lg.Tools
+	lg.Session = logging.New(&sessionBuf, logging.ComponentSession)
+	lg.Tools = logging.New(&toolsBuf, logging.ComponentTools)
+	defer func() { lg.Session, lg.Tools = prevSession, prevTools }()
+
+	workspace := t.TempDir()
+	loop := NewAgentLoop(
+		bus.NewMessageBus(10),
+		&testProvider{},
+		workspace,
+		"anthropic/claude-sonnet-4-5",
+		3,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		cron.NewService(filepath.Join(workspace, ".cron", "jobs.json")),
+		nil,
+		false,
+	)
+
+	_, err = loop.ProcessMessage(context.Background(), bus.NewInboundMessage("telegram", "user-1", "chat-42", "set a reminder"))
+	require.NoError(t, err)
+
+	records := func(buf *bytes.Buffer) []map[string]any {
+		var out []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var record map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &record), line)
+			out = append(out, record)
+		}
+		return out
+	}
+
+	sessionRecords := records(&sessionBuf)
+	require.Len(t, sessionRecords, 2)
+	inbound, outbound := sessionRecords[0], sessionRecords[1]
+	assert.Equal(t, "inbound", inbound["msg"])
+	assert.Equal(t, "telegram:chat-42", inbound["session"])
+	assert.Equal(t, "telegram", inbound["channel"])
+	assert.Equal(t, "user-1", inbound["sender"])
+	requestID, _ := inbound["request_id"].(string)
+	require.NotEmpty(t, requestID)
+	assert.Equal(t, requestID, outbound["request_id"])
+
+	var executed map[string]any
+	for _, record := range records(&toolsBuf) {
+		if record["msg"] == "tool executed" {
+			executed = record
+		}
+	}
+	require.NotNil(t, executed)
+	assert.Equal(t, "cron", executed["tool"])
+	assert.Equal(t, requestID, executed["request_id"])
+	assert.Equal(t, "telegram:chat-42", executed["session"])
+	assert.Equal(t, "chat-42", executed["chat_id"])
+	assert.Equal(t, float64(1), executed["turn"])
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/logging"
+	"github.com/Lichas/maxclaw/pkg/tools"
+)
+
+// BuildToolPolicy compiles tools.policy from config. An empty policy yields nil (allow all).
+func BuildToolPolicy(cfg *config.Config) (*tools.ToolPolicy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	return CompileToolPolicy(cfg.Tools.Policy)
+}
+
+// CompileToolPolicy converts config policy rules into a tools.ToolPolicy.
+func CompileToolPolicy(policy config.ToolPolicyConfig) (*tools.ToolPolicy, error) {
+	if len(policy.Rules) == 0 && policy.Default == "" {
+		return nil, nil
+	}
+
+	opts := tools.ToolPolicyOptions{
+		Default: policy.Default,
+		Rules:   make([]tools.ToolPolicyRuleOptions, 0, len(policy.Rules)),
+	}
+	for _, rule := range policy.Rules {
+		converted := tools.ToolPolicyRuleOptions{
+			Name:     rule.Name,
+			Effect:   rule.Effect,
+			Tools:    append([]string(nil), rule.Tools...),
+			Agents:   append([]string(nil), rule.Agents...),
+			Channels: append([]string(nil), rule.Channels...),
+			Senders:  append([]string(nil), rule.Senders...),
+			Reason:   rule.Reason,
+		}
+		if len(rule.Args) > 0 {
+			converted.Args = make(map[string]tools.ToolArgCondition, len(rule.Args))
+			for arg, cond := range rule.Args {
+				converted.Args[arg] = tools.ToolArgCondition{
+					Pattern:      cond.Pattern,
+					PathPrefixes: append([]string(nil), cond.PathPrefixes...),
+					Domains:      append([]string(nil), cond.Domains...),
+					Negate:       cond.Negate,
+				}
+			}
+		}
+		opts.Rules = append(opts.Rules, converted)
+	}
+	return tools.NewToolPolicy(opts)
+}
+
+// SetToolPolicy installs the declarative tool policy evaluated before each tool call.
+func (a *AgentLoop) SetToolPolicy(policy *tools.ToolPolicy) {
+	a.runtimeMu.Lock()
+	defer a.runtimeMu.Unlock()
+	a.toolPolicy = policy
+}
+
+func (a *AgentLoop) toolPolicySnapshot() *tools.ToolPolicy {
+	a.runtimeMu.RLock()
+	defer a.runtimeMu.RUnlock()
+	return a.toolPolicy
+}
+
+// EvaluateToolPolicy evaluates a tool call against the policy without executing it.
+func (a *AgentLoop) EvaluateToolPolicy(ctx context.Context, name string, params map[string]interface{}) tools.PolicyDecision {
+	channel, chatID := tools.RuntimeContextFrom(ctx)
+	return a.toolPolicySnapshot().Evaluate(tools.PolicyRequest{
+		Tool:      name,
+		Agent:     a.profileLabel(),
+		Channel:   channel,
+		ChatID:    chatID,
+		Sender:    tools.RuntimeSenderFrom(ctx),
+		Workspace: a.Workspace,
+		Args:      params,
+	})
+}
+
+func (a *AgentLoop) checkToolPolicy(ctx context.Context, name string, params map[string]interface{}) error {
+	policy := a.toolPolicySnapshot()
+	if policy == nil {
+		return nil
+	}
+
+	decision := a.EvaluateToolPolicy(ctx, name, params)
+	if lg := logging.Get(); lg != nil && lg.Tools != nil {
+		lg.Tools.InfoContext(ctx, "policy", "tool", name, "agent", a.profileLabel(),
+			"allowed", decision.Allowed, "rule", decision.Rule, "reason", decision.Reason)
+	}
+	if decision.Allowed {
+		return nil
+	}
+	return &tools.PolicyDeniedError{Tool: name, Decision: decision}
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentLoopToolPolicyDeniesWithStructuredError(t *testing.T) {
+	loop := newRouterTestLoop(t)
+	policy, err := CompileToolPolicy(config.ToolPolicyConfig{
+		Rules: []config.ToolPolicyRuleConfig{
+			{Name: "git-only", Effect: "allow", Tools: []string{"exec"}, Args: map[string]config.ToolArgRuleConfig{
+				"command": {Pattern: `^git\s`},
+			}},
+			{Name: "no-exec", Effect: "deny", Tools: []string{"exec"}, Channels: []string{"telegram"}, Reason: "only git commands"},
+		},
+	})
+	require.NoError(t, err)
+	loop.SetToolPolicy(policy)
+
+	_, err = loop.ExecuteToolWithSession(context.Background(), "exec", map[string]interface{}{"command": "curl http://x"}, "s", "telegram", "42")
+	require.Error(t, err)
+	var denied *tools.PolicyDeniedError
+	require.True(t, errors.As(err, &denied))
+	assert.Equal(t, "no-exec", denied.Decision.Rule)
+	assert.Contains(t, err.Error(), `"error":"tool_policy_denied"`)
+
+	// process 执行的命令同样受 exec 规则约束
+	_, err = loop.ExecuteToolWithSession(context.Background(), "process", map[string]interface{}{"action": "shell", "command": "curl http://x"}, "s", "telegram", "42")
+	require.True(t, errors.As(err, &denied))
+	assert.Equal(t, "no-exec", denied.Decision.Rule)
+	assert.Equal(t, "process", denied.Tool)
+
+	// 其他渠道不受 telegram 规则影响
+	decision := loop.EvaluateToolPolicy(tools.WithRuntimeContext(context.Background(), "cli", "c"), "exec", map[string]interface{}{"command": "ls"})
+	assert.True(t, decision.Allowed)
+}
+
+func TestProfileAgentLoopInheritsToolPolicy(t *testing.T) {
+	base := newRouterTestLoop(t)
+	policy, err := CompileToolPolicy(config.ToolPolicyConfig{
+		Rules: []config.ToolPolicyRuleConfig{{Name: "coder-no-web", Effect: "deny", Tools: []string{"web_*"}, Agents: []string{"coder"}}},
+	})
+	require.NoError(t, err)
+	base.SetToolPolicy(policy)
+
+	coder := NewProfileAgentLoop(base, config.ResolvedAgent{Name: "coder"}, nil)
+	assert.False(t, coder.EvaluateToolPolicy(context.Background(), "web_fetch", nil).Allowed)
+	assert.True(t, base.EvaluateToolPolicy(context.Background(), "web_fetch", nil).Allowed)
+}
+
+func TestCompileToolPolicyEmptyIsNil(t *testing.T) {
+	policy, err := CompileToolPolicy(config.ToolPolicyConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+
+	_, err = CompileToolPolicy(config.ToolPolicyConfig{Default: "nope"})
+	assert.Error(t, err)
+}
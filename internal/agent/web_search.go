@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/pkg/tools"
+)
+
+// BuildWebSearchOptions converts tools.web.search config to search tool options.
+func BuildWebSearchOptions(cfg *config.Config) tools.WebSearchOptions {
+	search := cfg.Tools.Web.Search
+	brave := search.Brave
+	if brave.APIKey == "" {
+		brave.APIKey = search.APIKey
+	}
+	backend := func(b config.WebSearchBackendConfig) tools.SearchBackendOptions {
+		return tools.SearchBackendOptions{APIKey: b.APIKey, BaseURL: b.BaseURL, EngineID: b.EngineID}
+	}
+	return tools.WebSearchOptions{
+		Providers: append([]string(nil), search.Providers...),
+		Backends: map[string]tools.SearchBackendOptions{
+			tools.SearchProviderBrave:      backend(brave),
+			tools.SearchProviderSearXNG:    backend(search.SearXNG),
+			tools.SearchProviderTavily:     backend(search.Tavily),
+			tools.SearchProviderBing:       backend(search.Bing),
+			tools.SearchProviderGoogle:     backend(search.Google),
+			tools.SearchProviderSerper:     backend(search.Serper),
+			tools.SearchProviderDuckDuckGo: backend(search.DuckDuckGo),
+		},
+		MaxResults:   search.MaxResults,
+		TimeoutSec:   search.Timeout,
+		UserAgent:    cfg.Tools.Web.Fetch.UserAgent,
+		DeepFetchTop: search.DeepFetchTop,
+		DeepMaxChars: search.DeepMaxChars,
+	}
+}
+
+// ValidateWebSearchConfig rejects unknown provider names in tools.web.search.providers.
+func ValidateWebSearchConfig(cfg *config.Config) error {
+	return tools.ValidateWebSearchOptions(BuildWebSearchOptions(cfg))
+}
+
+// SetWebSearchOptions replaces the web_search tool with one using the given backends.
+func (a *AgentLoop) SetWebSearchOptions(opts tools.WebSearchOptions) {
+	a.runtimeMu.Lock()
+	a.webSearch = &opts
+	a.runtimeMu.Unlock()
+
+	fetcher, _ := a.tools.Get("web_fetch")
+	a.tools.Register(tools.NewWebSearchToolWithOptions(opts, fetcher))
+}
+
+// webSearchOptionsSnapshot returns the configured search options; before
+// SetWebSearchOptions it falls back to the legacy Brave API key.
+func (a *AgentLoop) webSearchOptionsSnapshot() tools.WebSearchOptions {
+	a.runtimeMu.RLock()
+	defer a.runtimeMu.RUnlock()
+	if a.webSearch != nil {
+		return *a.webSearch
+	}
+	return tools.WebSearchOptions{
+		Backends:   map[string]tools.SearchBackendOptions{tools.SearchProviderBrave: {APIKey: a.BraveAPIKey}},
+		MaxResults: 5,
+	}
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildWebSearchOptionsMapsBackends(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Tools.Web.Search.APIKey = "legacy-brave"
+	cfg.Tools.Web.Search.Providers = []string{"searxng", "brave"}
+	cfg.Tools.Web.Search.SearXNG.BaseURL = "http://searx.local"
+	cfg.Tools.Web.Search.Google = config.WebSearchBackendConfig{APIKey: "g", EngineID: "cx"}
+	cfg.Tools.Web.Search.DeepFetchTop = 2
+
+	opts := BuildWebSearchOptions(cfg)
+	assert.Equal(t, "legacy-brave", opts.Backends[tools.SearchProviderBrave].APIKey)
+	assert.Equal(t, "cx", opts.Backends[tools.SearchProviderGoogle].EngineID)
+	assert.Equal(t, 5, opts.MaxResults)
+	assert.Equal(t, 2, opts.DeepFetchTop)
+	assert.Equal(t, []string{"searxng", "brave"}, tools.ResolveSearchProviders(opts))
+
+	cfg.Tools.Web.Search.Brave.APIKey = "explicit"
+	assert.Equal(t, "explicit", BuildWebSearchOptions(cfg).Backends[tools.SearchProviderBrave].APIKey)
+
+	require.NoError(t, ValidateWebSearchConfig(cfg))
+	cfg.Tools.Web.Search.Providers = []string{"lycos"}
+	assert.Error(t, ValidateWebSearchConfig(cfg))
+}
+
+func TestProfileLoopInheritsWebSearchOptions(t *testing.T) {
+	base := NewAgentLoop(bus.NewMessageBus(10), &staticProvider{}, t.TempDir(), "test-model", 3, "",
+		tools.WebFetchOptions{}, config.ExecToolConfig{Timeout: 5}, false, nil, nil, false)
+	assert.Equal(t, []string{"duckduckgo"}, tools.ResolveSearchProviders(base.webSearchOptionsSnapshot()))
+
+	base.SetWebSearchOptions(tools.WebSearchOptions{
+		Providers: []string{"searxng"},
+		Backends:  map[string]tools.SearchBackendOptions{"searxng": {BaseURL: "http://searx.local"}},
+	})
+	profile := NewProfileAgentLoop(base, config.ResolvedAgent{Name: "researcher", Workspace: t.TempDir()}, nil)
+	assert.Equal(t, []string{"searxng"}, tools.ResolveSearchProviders(profile.webSearchOptionsSnapshot()))
+	_, ok := profile.tools.Get("web_search")
+	assert.True(t, ok)
+}
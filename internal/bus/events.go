@@ -20,6 +20,9 @@ type InboundMessage struct {
 	Media          *MediaAttachment `json:"media,omitempty"`
 	SessionKey     string           `json:"sessionKey"` // channel:chatId
 	Internal       bool             `json:"internal,omitempty"`
+	Agent          string           `json:"agent,omitempty"` // optional explicit agent profile
+	// TraceParent W3C traceparent，由渠道或上游调用方设置，用于串联链路
+	TraceParent string `json:"-"`
 }
 
 // NewInboundMessage 创建入站消息
@@ -39,6 +42,8 @@ type OutboundMessage struct {
 	ChatID  string           `json:"chatId"`
 	Content string           `json:"content"`
 	Media   *MediaAttachment `json:"media,omitempty"`
+	// TraceParent 产生该消息的 span，出站投递作为其子 span
+	TraceParent string `json:"-"`
 }
 
 // NewOutboundMessage 创建出站消息
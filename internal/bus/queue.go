@@ -3,6 +3,8 @@ package bus
 import (
 	"context"
 	"sync"
+
+	"github.com/Lichas/maxclaw/internal/telemetry"
 )
 
 // MessageBus 消息总线
@@ -40,6 +42,7 @@ func (b *MessageBus) PublishInbound(msg *InboundMessage) error {
 
 	select {
 	case b.inbound <- msg:
+		telemetry.RecordInbound(msg.Channel)
 		return nil
 	default:
 		return ErrBufferFull
@@ -147,6 +150,16 @@ func (b *MessageBus) Close() {
 	}
 }
 
+// InboundDepth 返回入站队列中等待处理的消息数
+func (b *MessageBus) InboundDepth() int {
+	return len(b.inbound)
+}
+
+// OutboundDepth 返回出站队列中等待投递的消息数
+func (b *MessageBus) OutboundDepth() int {
+	return len(b.outbound)
+}
+
 // IsClosed 检查是否已关闭
 func (b *MessageBus) IsClosed() bool {
 	b.mu.RLock()
@@ -2,6 +2,7 @@ package channels
 
 import (
 	"context"
+	"sync"
 
 	"github.com/Lichas/maxclaw/internal/bus"
 )
@@ -33,8 +34,9 @@ type Channel interface {
 	IsEnabled() bool
 }
 
-// Registry 频道注册表
+// Registry 频道注册表（配置热加载时会替换其中的频道，读写均加锁）
 type Registry struct {
+	mu       sync.RWMutex
 	channels map[string]Channel
 }
 
@@ -45,19 +47,34 @@ func NewRegistry() *Registry {
 	}
 }
 
-// Register 注册频道
+// Register 注册频道，同名频道会被替换
 func (r *Registry) Register(channel Channel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.channels[channel.Name()] = channel
 }
 
+// Unregister 移除频道并返回被移除的实例（调用方负责 Stop）
+func (r *Registry) Unregister(name string) (Channel, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ch, ok := r.channels[name]
+	delete(r.channels, name)
+	return ch, ok
+}
+
 // Get 获取频道
 func (r *Registry) Get(name string) (Channel, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	ch, ok := r.channels[name]
 	return ch, ok
 }
 
 // GetAll 获取所有频道
 func (r *Registry) GetAll() []Channel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	channels := make([]Channel, 0, len(r.channels))
 	for _, ch := range r.channels {
 		channels = append(channels, ch)
@@ -67,6 +84,8 @@ func (r *Registry) GetAll() []Channel {
 
 // GetEnabled 获取启用的频道
 func (r *Registry) GetEnabled() []Channel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	channels := make([]Channel, 0)
 	for _, ch := range r.channels {
 		if ch.IsEnabled() {
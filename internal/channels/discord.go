@@ -131,12 +131,12 @@ func (d *DiscordChannel) SendMessage(channelID string, text string) error {
 	_, err := d.session.ChannelMessageSend(channelID, text)
 	if err != nil {
 		if lg := logging.Get(); lg != nil && lg.Channels != nil {
-			lg.Channels.Printf("discord send error chat=%s err=%v", channelID, err)
+			lg.Channels.Error("send error", "channel", "discord", "chat_id", channelID, "err", err)
 		}
 		return err
 	}
 	if lg := logging.Get(); lg != nil && lg.Channels != nil {
-		lg.Channels.Printf("discord send chat=%s text=%q", channelID, logging.Truncate(text, 300))
+		lg.Channels.Info("send", "channel", "discord", "chat_id", channelID, "text", logging.Truncate(text, 300))
 	}
 	return nil
 }
@@ -222,7 +222,7 @@ func (d *DiscordChannel) handleMessage(_ *discordgo.Session, m *discordgo.Messag
 	}
 	d.messageHandler(msg)
 	if lg := logging.Get(); lg != nil && lg.Channels != nil {
-		lg.Channels.Printf("discord inbound chat=%s sender=%s text=%q", msg.ChatID, msg.Sender, logging.Truncate(msg.Text, 300))
+		lg.Channels.Info("inbound", "channel", "discord", "chat_id", msg.ChatID, "sender", msg.Sender, "text", logging.Truncate(msg.Text, 300))
 	}
 }
 
@@ -181,7 +181,7 @@ func (t *TelegramChannel) fetchUpdates() {
 		st := t.Status()
 		t.setStatus("error", st.Username, st.Name, err.Error())
 		if lg := logging.Get(); lg != nil && lg.Channels != nil {
-			lg.Channels.Printf("telegram getUpdates error: %v", err)
+			lg.Channels.Error("getUpdates error", "channel", "telegram", "err", err)
 		}
 		return
 	}
@@ -192,7 +192,7 @@ func (t *TelegramChannel) fetchUpdates() {
 		st := t.Status()
 		t.setStatus("error", st.Username, st.Name, err.Error())
 		if lg := logging.Get(); lg != nil && lg.Channels != nil {
-			lg.Channels.Printf("telegram readUpdates error: %v", err)
+			lg.Channels.Error("readUpdates error", "channel", "telegram", "err", err)
 		}
 		return
 	}
@@ -203,7 +203,7 @@ func (t *TelegramChannel) fetchUpdates() {
 		st := t.Status()
 		t.setStatus("error", st.Username, st.Name, err.Error())
 		if lg := logging.Get(); lg != nil && lg.Channels != nil {
-			lg.Channels.Printf("telegram parseUpdates error: %v", err)
+			lg.Channels.Error("parseUpdates error", "channel", "telegram", "err", err)
 		}
 		return
 	}
@@ -212,7 +212,7 @@ func (t *TelegramChannel) fetchUpdates() {
 		st := t.Status()
 		t.setStatus("error", st.Username, st.Name, "getUpdates returned not ok")
 		if lg := logging.Get(); lg != nil && lg.Channels != nil {
-			lg.Channels.Printf("telegram getUpdates not ok")
+			lg.Channels.Warn("getUpdates not ok", "channel", "telegram")
 		}
 		return
 	}
@@ -236,9 +236,9 @@ func (t *TelegramChannel) fetchUpdates() {
 			t.messageHandler(msg)
 			if lg := logging.Get(); lg != nil && lg.Channels != nil {
 				if msg.Media != nil && msg.Media.Type != "" {
-					lg.Channels.Printf("telegram inbound chat=%s sender=%s text=%q media=%s", msg.ChatID, msg.Sender, logging.Truncate(msg.Text, 300), msg.Media.Type)
+					lg.Channels.Info("inbound", "channel", "telegram", "chat_id", msg.ChatID, "sender", msg.Sender, "text", logging.Truncate(msg.Text, 300), "media", msg.Media.Type)
 				} else {
-					lg.Channels.Printf("telegram inbound chat=%s sender=%s text=%q", msg.ChatID, msg.Sender, logging.Truncate(msg.Text, 300))
+					lg.Channels.Info("inbound", "channel", "telegram", "chat_id", msg.ChatID, "sender", msg.Sender, "text", logging.Truncate(msg.Text, 300))
 				}
 			}
 		}
@@ -365,7 +365,7 @@ func (t *TelegramChannel) SendMessage(chatID string, text string) error {
 	}
 
 	if lg := logging.Get(); lg != nil && lg.Channels != nil {
-		lg.Channels.Printf("telegram send chat=%s text=%q", chatID, logging.Truncate(text, 300))
+		lg.Channels.Info("send", "channel", "telegram", "chat_id", chatID, "text", logging.Truncate(text, 300))
 	}
 	return nil
 }
@@ -472,7 +472,7 @@ func (t *TelegramChannel) sendFile(chatID, filePath, apiMethod, fileField, capti
 	}
 
 	if lg := logging.Get(); lg != nil && lg.Channels != nil {
-		lg.Channels.Printf("telegram send file chat=%s file=%s type=%s", chatID, filepath.Base(filePath), fileField)
+		lg.Channels.Info("send file", "channel", "telegram", "chat_id", chatID, "file", filepath.Base(filePath), "type", fileField)
 	}
 
 	return nil
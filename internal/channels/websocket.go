@@ -183,12 +183,12 @@ func (w *WebSocketChannel) SendMessage(chatID string, text string) error {
 	}
 	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
 		if lg := logging.Get(); lg != nil && lg.Channels != nil {
-			lg.Channels.Printf("websocket send error chat=%s err=%v", chatID, err)
+			lg.Channels.Error("send error", "channel", "websocket", "chat_id", chatID, "err", err)
 		}
 		return err
 	}
 	if lg := logging.Get(); lg != nil && lg.Channels != nil {
-		lg.Channels.Printf("websocket send chat=%s text=%q", chatID, logging.Truncate(text, 300))
+		lg.Channels.Info("send", "channel", "websocket", "chat_id", chatID, "text", logging.Truncate(text, 300))
 	}
 	return nil
 }
@@ -257,7 +257,7 @@ func (w *WebSocketChannel) handleInbound(defaultChatID string, data []byte) {
 		})
 	}
 	if lg := logging.Get(); lg != nil && lg.Channels != nil {
-		lg.Channels.Printf("websocket inbound chat=%s sender=%s text=%q", chatID, sender, logging.Truncate(content, 300))
+		lg.Channels.Info("inbound", "channel", "websocket", "chat_id", chatID, "sender", sender, "text", logging.Truncate(content, 300))
 	}
 }
 
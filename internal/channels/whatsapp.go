@@ -124,14 +124,14 @@ func (w *WhatsAppChannel) SendMessage(chatID string, text string) error {
 	}
 	if err := w.conn.WriteMessage(websocket.TextMessage, data); err != nil {
 		if lg := logging.Get(); lg != nil && lg.Channels != nil {
-			lg.Channels.Printf("whatsapp send error chat=%s err=%v", chatID, err)
+			lg.Channels.Error("send error", "channel", "whatsapp", "chat_id", chatID, "err", err)
 		}
 		return err
 	}
 
 	w.rememberOutbound(chatID, text)
 	if lg := logging.Get(); lg != nil && lg.Channels != nil {
-		lg.Channels.Printf("whatsapp send chat=%s text=%q", chatID, logging.Truncate(text, 300))
+		lg.Channels.Info("send", "channel", "whatsapp", "chat_id", chatID, "text", logging.Truncate(text, 300))
 	}
 	return nil
 }
@@ -161,7 +161,7 @@ func (w *WhatsAppChannel) connectLoop(ctx context.Context) {
 			_ = conn.Close()
 			w.setConnected(false, nil)
 			if lg := logging.Get(); lg != nil && lg.Channels != nil {
-				lg.Channels.Printf("whatsapp auth handshake failed: %v", err)
+				lg.Channels.Error("auth handshake failed", "channel", "whatsapp", "err", err)
 			}
 			if !w.waitRetry(ctx, 5*time.Second) {
 				return
@@ -251,7 +251,7 @@ func (w *WhatsAppChannel) handleBridgeMessage(data []byte) {
 			})
 		}
 		if lg := logging.Get(); lg != nil && lg.Channels != nil {
-			lg.Channels.Printf("whatsapp inbound chat=%s sender=%s fromMe=%v text=%q", chatID, senderID, msg.FromMe, logging.Truncate(msg.Content, 300))
+			lg.Channels.Info("inbound", "channel", "whatsapp", "chat_id", chatID, "sender", senderID, "from_me", msg.FromMe, "text", logging.Truncate(msg.Content, 300))
 		}
 	case "status":
 		if msg.Status == "connected" {
@@ -2,23 +2,15 @@ package cli
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"io"
-	"os"
-	"os/signal"
 	"path/filepath"
 	"regexp"
 	"strings"
-	"syscall"
 
 	"github.com/Lichas/maxclaw/internal/agent"
 	"github.com/Lichas/maxclaw/internal/bus"
 	"github.com/Lichas/maxclaw/internal/config"
 	"github.com/Lichas/maxclaw/internal/cron"
-	"github.com/Lichas/maxclaw/internal/logging"
-	"github.com/Lichas/maxclaw/internal/providers"
-	"github.com/peterh/liner"
 	"github.com/spf13/cobra"
 )
 
@@ -97,57 +89,15 @@ var agentCmd = &cobra.Command{
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		if _, err := logging.Init(config.GetDataDir()); err != nil {
-			fmt.Printf("⚠ logging init error: %v\n", err)
-		}
+		initLogging(cfg)
 		if logsFlag {
 			fmt.Printf("Logs: %s\n", config.GetLogsDir())
 		}
 
-		// 检查 API key
-		apiKey := cfg.GetAPIKey("")
-		apiBase := cfg.GetAPIBase("")
-		if apiKey == "" {
-			return fmt.Errorf("no API key configured. Set one in ~/.maxclaw/config.json")
-		}
-
-		// 创建 Provider
-		provider, err := providers.NewProvider(
-			apiKey,
-			apiBase,
-			cfg.GetAPIFormat(cfg.Agents.Defaults.Model),
-			cfg.Agents.Defaults.Model,
-			cfg.Agents.Defaults.MaxTokens,
-			cfg.Agents.Defaults.Temperature,
-			cfg.SupportsImageInput,
-		)
+		agentLoop, err := newCLIAgentLoop(cfg)
 		if err != nil {
-			return fmt.Errorf("failed to create provider: %w", err)
+			return err
 		}
-
-		// 创建组件
-		messageBus := bus.NewMessageBus(100)
-
-		// 创建 Cron 服务（agent 模式下也需要，但不启动）
-		storePath := filepath.Join(cfg.Agents.Defaults.Workspace, ".cron", "jobs.json")
-		cronService := cron.NewService(storePath)
-
-		agentLoop := agent.NewAgentLoop(
-			messageBus,
-			provider,
-			cfg.Agents.Defaults.Workspace,
-			cfg.Agents.Defaults.Model,
-			cfg.Agents.Defaults.MaxToolIterations,
-			cfg.Tools.Web.Search.APIKey,
-			agent.BuildWebFetchOptions(cfg),
-			cfg.Tools.Exec,
-			cfg.Tools.RestrictToWorkspace,
-			cronService,
-			cfg.Tools.MCPServers,
-			cfg.Agents.Defaults.EnableGlobalSkills,
-		)
-		agentLoop.InitializeLifecycle()
-		agentLoop.UpdateRuntimeExecutionMode(cfg.Agents.Defaults.ExecutionMode)
 		defer agentLoop.Close()
 
 		if messageFlag != "" {
@@ -164,91 +114,72 @@ var agentCmd = &cobra.Command{
 			}
 		} else {
 			// 交互模式
-			fmt.Printf("%s Interactive mode (type 'exit' or Ctrl+C to exit)\n\n", logo)
-
-			ctx, cancel := context.WithCancel(context.Background())
-			defer cancel()
-
-			// 处理 Ctrl+C
-			sigChan := make(chan os.Signal, 1)
-			signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-			go func() {
-				<-sigChan
-				cancel()
-			}()
-
-			line := liner.NewLiner()
-			defer line.Close()
-			line.SetCtrlCAborts(true)
-
-			historyPath := filepath.Join(config.GetDataDir(), ".agent_history")
-			if f, err := os.Open(historyPath); err == nil {
-				_, _ = line.ReadHistory(f)
-				_ = f.Close()
-			}
-			defer func() {
-				_ = os.MkdirAll(filepath.Dir(historyPath), 0755)
-				if f, err := os.Create(historyPath); err == nil {
-					_, _ = line.WriteHistory(f)
-					_ = f.Close()
-				}
-			}()
-
-			channel := resolveCLIChannel(markdownFlag)
-			for {
-				select {
-				case <-ctx.Done():
-					fmt.Println("\nGoodbye!")
-					return nil
-				default:
-				}
-
-				input, err := line.Prompt("You: ")
-				if err != nil {
-					if errors.Is(err, os.ErrClosed) {
-						fmt.Println("\nGoodbye!")
-						return nil
-					}
-					if errors.Is(err, liner.ErrPromptAborted) {
-						fmt.Println("\nGoodbye!")
-						return nil
-					}
-					if errors.Is(err, context.Canceled) {
-						fmt.Println("\nGoodbye!")
-						return nil
-					}
-					if errors.Is(err, io.EOF) {
-						fmt.Println("\nGoodbye!")
-						return nil
-					}
-					return err
-				}
-
-				input = normalizeInteractiveInput(input)
-				if input == "" {
-					continue
-				}
-				line.AppendHistory(input)
-				if isExitCommand(input) {
-					fmt.Println("Goodbye!")
-					return nil
-				}
-
-				response, err := agentLoop.ProcessDirect(ctx, input, sessionIDFlag, channel, "direct")
-				if err != nil {
-					fmt.Printf("Error: %v\n", err)
-					continue
-				}
-
-				// 流式输出已实时显示，仅在有内容时打印
-				if response != "" {
-					fmt.Printf("\n%s %s\n\n", logo, formatAgentResponse(response, markdownFlag))
-				} else {
-					fmt.Println()
-				}
-			}
+			return runInteractiveAgent(agentLoop, cfg, sessionIDFlag, markdownFlag)
 		}
 
 		return nil
 	},
 }
+
+// newCLIAgentLoop 按配置创建 CLI 使用的 agent（provider、工具策略、执行模式等）
+func newCLIAgentLoop(cfg *config.Config) (*agent.AgentLoop, error) {
+	// 检查 API key
+	if cfg.GetAPIKey("") == "" && len(cfg.Routing.Backends) == 0 {
+		return nil, fmt.Errorf("no API key configured. Set one in ~/.maxclaw/config.json")
+	}
+
+	// 创建 Provider
+	provider, err := agent.NewProviderFromConfig(cfg, cfg.Agents.Defaults.Model, cfg.Agents.Defaults.MaxTokens, cfg.Agents.Defaults.Temperature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+
+	// 创建组件
+	messageBus := bus.NewMessageBus(100)
+
+	// 创建 Cron 服务（agent 模式下也需要，但不启动）
+	storePath := filepath.Join(cfg.Agents.Defaults.Workspace, ".cron", "jobs.json")
+	cronService := cron.NewService(storePath)
+
+	agentLoop := agent.NewAgentLoop(
+		messageBus,
+		provider,
+		cfg.Agents.Defaults.Workspace,
+		cfg.Agents.Defaults.Model,
+		cfg.Agents.Defaults.MaxToolIterations,
+		cfg.Tools.Web.Search.APIKey,
+		agent.BuildWebFetchOptions(cfg),
+		cfg.Tools.Exec,
+		cfg.Tools.RestrictToWorkspace,
+		cronService,
+		cfg.Tools.MCPServers,
+		cfg.Agents.Defaults.EnableGlobalSkills,
+	)
+	agentLoop.InitializeLifecycle()
+	toolPolicy, err := agent.BuildToolPolicy(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tools.policy: %w", err)
+	}
+	agentLoop.SetToolPolicy(toolPolicy)
+	agentLoop.SetRequireReadBeforeEdit(cfg.Tools.Edit.RequireRead)
+	if err := agent.ValidateWebSearchConfig(cfg); err != nil {
+		return nil, fmt.Errorf("invalid tools.web.search: %w", err)
+	}
+	agentLoop.SetWebSearchOptions(agent.BuildWebSearchOptions(cfg))
+	egressGuard, err := agent.BuildEgressGuard(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tools.egress: %w", err)
+	}
+	agentLoop.SetEgressGuard(egressGuard)
+	retrievalOptions, err := agent.BuildRetrievalOptions(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tools.retrieval: %w", err)
+	}
+	agentLoop.SetRetrievalOptions(retrievalOptions)
+	agentLoop.SetMemoryIdentities(cfg.Memory.Identities)
+	if err := agent.ValidateExecConfig(cfg); err != nil {
+		return nil, fmt.Errorf("invalid tools.exec: %w", err)
+	}
+	agentLoop.UpdateRuntimeExecutionMode(cfg.Agents.Defaults.ExecutionMode)
+	return agentLoop, nil
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configJSONFlag bool
+
+func init() {
+	configValidateCmd.Flags().BoolVar(&configJSONFlag, "json", false, "Print problems as JSON")
+
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// configCmd 配置文件命令
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Validate and edit ~/.maxclaw/config.json",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check config.json for syntax, unknown fields, invalid values and unresolved secrets",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems, err := validateConfigFile(config.GetConfigPath())
+		if err != nil {
+			return err
+		}
+		return printConfigProblems(cmd.OutOrStdout(), config.GetConfigPath(), problems, configJSONFlag)
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <path>",
+	Short: "Print a config value (secret references are shown unresolved)",
+	Example: `  maxclaw config get agents.defaults.model
+  maxclaw config get agents.routes[0]`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := config.GetValue(args[0])
+		if err != nil {
+			return err
+		}
+		if s, ok := value.(string); ok {
+			fmt.Fprintln(cmd.OutOrStdout(), s)
+			return nil
+		}
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <path> <value>",
+	Short: "Set a config value; the file is validated before it is written",
+	Example: `  maxclaw config set agents.defaults.model gpt-4o
+  maxclaw config set providers.openai.apiKey '${OPENAI_API_KEY}'
+  maxclaw config set channels.telegram.allowFrom alice,bob
+  maxclaw config set tools.mcpServers.fs '{"command": "npx", "args": ["-y", "@modelcontextprotocol/server-filesystem", "."]}'`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.SetValue(args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "✓ Set %s (a running gateway picks this up automatically)\n", args[0])
+		return nil
+	},
+}
+
+// validateConfigFile 合并文件级校验与运行时组件的深度校验；文件不存在时视为使用默认配置
+func validateConfigFile(path string) (config.ValidationErrors, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return config.DefaultConfig().Validate(), nil
+	}
+	problems, err := config.ValidateFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if len(problems) > 0 {
+		return problems, nil
+	}
+	cfg, err := config.LoadConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return agent.ValidateRuntimeConfig(cfg), nil
+}
+
+func printConfigProblems(w io.Writer, path string, problems config.ValidationErrors, asJSON bool) error {
+	if asJSON {
+		if problems == nil {
+			problems = config.ValidationErrors{}
+		}
+		data, err := json.MarshalIndent(problems, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(data))
+	} else if len(problems) == 0 {
+		fmt.Fprintf(w, "✓ %s is valid\n", path)
+	} else {
+		for _, problem := range problems {
+			fmt.Fprintf(w, "✗ %s\n", problem.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d config problem(s) found", len(problems))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/channels"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/media"
+	"github.com/Lichas/maxclaw/internal/webui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfigFileCombinesFileAndRuntimeChecks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	problems, err := validateConfigFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, problems, "missing file means defaults")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"agents": {"defaults": {"modle": "x"}}}`), 0600))
+	problems, err = validateConfigFile(path)
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "agents.defaults.modle", problems[0].Path)
+
+	// 文件级校验通过后再跑运行时构建器
+	require.NoError(t, os.WriteFile(path, []byte(`{"tools": {"exec": {"memoryMb": -1}}}`), 0600))
+	problems, err = validateConfigFile(path)
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "tools.exec", problems[0].Path)
+
+	var out bytes.Buffer
+	err = printConfigProblems(&out, path, problems, false)
+	assert.EqualError(t, err, "1 config problem(s) found")
+	assert.Contains(t, out.String(), "✗ tools.exec: exec limits must not be negative")
+
+	out.Reset()
+	require.NoError(t, printConfigProblems(&out, path, nil, true))
+	assert.Equal(t, "[]\n", out.String())
+}
+
+func TestConfigReloaderRestartsOnlyChangedChannels(t *testing.T) {
+	registry := channels.NewRegistry()
+	registry.Register(&mockChannel{name: "telegram", enabled: true})
+	registry.Register(&mockChannel{name: "discord", enabled: true})
+
+	cfg := config.DefaultConfig()
+	reloader := &configReloader{
+		ctx:          context.Background(),
+		web:          webui.NewServer(cfg, nil, nil, registry),
+		registry:     registry,
+		messageBus:   bus.NewMessageBus(1),
+		mediaManager: media.NewManager(t.TempDir()),
+	}
+
+	// telegram 在新配置中被禁用：旧实例被移除；discord 未变化，保持原实例
+	updated := config.DefaultConfig()
+	updated.Channels.Telegram.Token = "changed"
+	discord, _ := registry.Get("discord")
+	reloader.apply(updated, config.Diff(cfg, updated))
+
+	_, ok := registry.Get("telegram")
+	assert.False(t, ok)
+	current, ok := registry.Get("discord")
+	require.True(t, ok)
+	assert.Same(t, discord, current)
+}
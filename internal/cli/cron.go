@@ -16,7 +16,6 @@ import (
 	"github.com/Lichas/maxclaw/internal/config"
 	"github.com/Lichas/maxclaw/internal/cron"
 	"github.com/Lichas/maxclaw/internal/logging"
-	"github.com/Lichas/maxclaw/internal/providers"
 	"github.com/spf13/cobra"
 )
 
@@ -72,9 +71,7 @@ var cronAddCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
-		if _, err := logging.Init(config.GetDataDir()); err != nil {
-			fmt.Printf("⚠ logging init error: %v\n", err)
-		}
+		initLogging(cfg)
 
 		storePath := filepath.Join(cfg.Agents.Defaults.Workspace, ".cron", "jobs.json")
 		service := cron.NewService(storePath)
@@ -272,9 +269,7 @@ var cronRunCmd = &cobra.Command{
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		apiKey := cfg.GetAPIKey("")
-		apiBase := cfg.GetAPIBase("")
-		if apiKey == "" {
+		if cfg.GetAPIKey("") == "" && len(cfg.Routing.Backends) == 0 {
 			return fmt.Errorf("no API key configured")
 		}
 
@@ -283,7 +278,7 @@ var cronRunCmd = &cobra.Command{
 
 		// 设置任务处理器
 		service.SetJobHandler(func(job *cron.Job) (string, error) {
-			return executeCronJob(cfg, apiKey, apiBase, service, job)
+			return executeCronJob(cfg, service, job)
 		})
 
 		// 启动服务
@@ -309,17 +304,9 @@ var cronRunCmd = &cobra.Command{
 }
 
 // executeCronJob 执行定时任务
-func executeCronJob(cfg *config.Config, apiKey, apiBase string, cronService *cron.Service, job *cron.Job) (string, error) {
+func executeCronJob(cfg *config.Config, cronService *cron.Service, job *cron.Job) (string, error) {
 	// 创建 Provider
-	provider, err := providers.NewProvider(
-		apiKey,
-		apiBase,
-		cfg.GetAPIFormat(cfg.Agents.Defaults.Model),
-		cfg.Agents.Defaults.Model,
-		cfg.Agents.Defaults.MaxTokens,
-		cfg.Agents.Defaults.Temperature,
-		cfg.SupportsImageInput,
-	)
+	provider, err := agent.NewProviderFromConfig(cfg, cfg.Agents.Defaults.Model, cfg.Agents.Defaults.MaxTokens, cfg.Agents.Defaults.Temperature)
 	if err != nil {
 		return "", fmt.Errorf("failed to create provider: %w", err)
 	}
@@ -343,6 +330,30 @@ func executeCronJob(cfg *config.Config, apiKey, apiBase string, cronService *cro
 		cfg.Agents.Defaults.EnableGlobalSkills,
 	)
 	agentLoop.InitializeLifecycle()
+	toolPolicy, err := agent.BuildToolPolicy(cfg)
+	if err != nil {
+		return "", fmt.Errorf("invalid tools.policy: %w", err)
+	}
+	agentLoop.SetToolPolicy(toolPolicy)
+	agentLoop.SetRequireReadBeforeEdit(cfg.Tools.Edit.RequireRead)
+	if err := agent.ValidateWebSearchConfig(cfg); err != nil {
+		return "", fmt.Errorf("invalid tools.web.search: %w", err)
+	}
+	agentLoop.SetWebSearchOptions(agent.BuildWebSearchOptions(cfg))
+	egressGuard, err := agent.BuildEgressGuard(cfg)
+	if err != nil {
+		return "", fmt.Errorf("invalid tools.egress: %w", err)
+	}
+	agentLoop.SetEgressGuard(egressGuard)
+	retrievalOptions, err := agent.BuildRetrievalOptions(cfg)
+	if err != nil {
+		return "", fmt.Errorf("invalid tools.retrieval: %w", err)
+	}
+	agentLoop.SetRetrievalOptions(retrievalOptions)
+	agentLoop.SetMemoryIdentities(cfg.Memory.Identities)
+	if err := agent.ValidateExecConfig(cfg); err != nil {
+		return "", fmt.Errorf("invalid tools.exec: %w", err)
+	}
 	executionMode := job.GetExecutionMode()
 	if executionMode == cron.ExecutionModeAsk || executionMode == "" {
 		// Cron jobs should default to auto mode to prevent hanging
@@ -475,11 +486,11 @@ func deliverCronResult(cfg *config.Config, job *cron.Job, result string, execErr
 				})
 				if err := tgChannel.SendMessage(job.Payload.To, content); err != nil {
 					if lg := logging.Get(); lg != nil && lg.Cron != nil {
-						lg.Cron.Printf("cron deliver failed channel=telegram job=%s err=%v", job.ID, err)
+						lg.Cron.Error("cron deliver failed", "channel", "telegram", "job_id", job.ID, "err", err)
 					}
 				} else {
 					if lg := logging.Get(); lg != nil && lg.Cron != nil {
-						lg.Cron.Printf("cron delivered channel=telegram job=%s", job.ID)
+						lg.Cron.Info("cron delivered", "channel", "telegram", "job_id", job.ID)
 					}
 				}
 			}
@@ -491,11 +502,11 @@ func deliverCronResult(cfg *config.Config, job *cron.Job, result string, execErr
 				})
 				if err := dcChannel.SendMessage(job.Payload.To, content); err != nil {
 					if lg := logging.Get(); lg != nil && lg.Cron != nil {
-						lg.Cron.Printf("cron deliver failed channel=discord job=%s err=%v", job.ID, err)
+						lg.Cron.Error("cron deliver failed", "channel", "discord", "job_id", job.ID, "err", err)
 					}
 				} else {
 					if lg := logging.Get(); lg != nil && lg.Cron != nil {
-						lg.Cron.Printf("cron delivered channel=discord job=%s", job.ID)
+						lg.Cron.Info("cron delivered", "channel", "discord", "job_id", job.ID)
 					}
 				}
 			}
@@ -508,11 +519,11 @@ func deliverCronResult(cfg *config.Config, job *cron.Job, result string, execErr
 				})
 				if err := waChannel.SendMessage(job.Payload.To, content); err != nil {
 					if lg := logging.Get(); lg != nil && lg.Cron != nil {
-						lg.Cron.Printf("cron deliver failed channel=whatsapp job=%s err=%v", job.ID, err)
+						lg.Cron.Error("cron deliver failed", "channel", "whatsapp", "job_id", job.ID, "err", err)
 					}
 				} else {
 					if lg := logging.Get(); lg != nil && lg.Cron != nil {
-						lg.Cron.Printf("cron delivered channel=whatsapp job=%s", job.ID)
+						lg.Cron.Info("cron delivered", "channel", "whatsapp", "job_id", job.ID)
 					}
 				}
 			}
@@ -525,11 +536,11 @@ func deliverCronResult(cfg *config.Config, job *cron.Job, result string, execErr
 				})
 				if err := slackChannel.SendMessage(job.Payload.To, content); err != nil {
 					if lg := logging.Get(); lg != nil && lg.Cron != nil {
-						lg.Cron.Printf("cron deliver failed channel=slack job=%s err=%v", job.ID, err)
+						lg.Cron.Error("cron deliver failed", "channel", "slack", "job_id", job.ID, "err", err)
 					}
 				} else {
 					if lg := logging.Get(); lg != nil && lg.Cron != nil {
-						lg.Cron.Printf("cron delivered channel=slack job=%s", job.ID)
+						lg.Cron.Info("cron delivered", "channel", "slack", "job_id", job.ID)
 					}
 				}
 			}
@@ -548,17 +559,17 @@ func deliverCronResult(cfg *config.Config, job *cron.Job, result string, execErr
 				})
 				if err := emailChannel.SendMessage(job.Payload.To, content); err != nil {
 					if lg := logging.Get(); lg != nil && lg.Cron != nil {
-						lg.Cron.Printf("cron deliver failed channel=email job=%s err=%v", job.ID, err)
+						lg.Cron.Error("cron deliver failed", "channel", "email", "job_id", job.ID, "err", err)
 					}
 				} else {
 					if lg := logging.Get(); lg != nil && lg.Cron != nil {
-						lg.Cron.Printf("cron delivered channel=email job=%s", job.ID)
+						lg.Cron.Info("cron delivered", "channel", "email", "job_id", job.ID)
 					}
 				}
 			}
 		default:
 			if lg := logging.Get(); lg != nil && lg.Cron != nil {
-				lg.Cron.Printf("cron deliver skipped unsupported channel=%s job=%s", channelName, job.ID)
+				lg.Cron.Warn("cron deliver skipped unsupported channel", "channel", channelName, "job_id", job.ID)
 			}
 		}
 	}
@@ -15,12 +15,15 @@ import (
 	"github.com/Lichas/maxclaw/internal/channels"
 	"github.com/Lichas/maxclaw/internal/config"
 	"github.com/Lichas/maxclaw/internal/cron"
+	"github.com/Lichas/maxclaw/internal/heartbeat"
 	"github.com/Lichas/maxclaw/internal/logging"
 	"github.com/Lichas/maxclaw/internal/media"
 	"github.com/Lichas/maxclaw/internal/memory"
 	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/Lichas/maxclaw/internal/telemetry"
 	"github.com/Lichas/maxclaw/internal/webui"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var gatewayPort int
@@ -39,17 +42,19 @@ var gatewayCmd = &cobra.Command{
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		if _, err := logging.Init(config.GetDataDir()); err != nil {
-			fmt.Printf("⚠ logging init error: %v\n", err)
+		initLogging(cfg)
+		for _, problem := range cfg.Validate() {
+			fmt.Printf("⚠ config %s\n", problem.Error())
+			if lg := logging.Get(); lg != nil && lg.Gateway != nil {
+				lg.Gateway.Warn("config problem", "path", problem.Path, "err", problem.Message)
+			}
 		}
 
 		if lg := logging.Get(); lg != nil && lg.Gateway != nil {
-			lg.Gateway.Printf("gateway starting port=%d model=%s workspace=%s", gatewayPort, cfg.Agents.Defaults.Model, cfg.Agents.Defaults.Workspace)
+			lg.Gateway.Info("gateway starting", "port", gatewayPort, "model", cfg.Agents.Defaults.Model, "workspace", cfg.Agents.Defaults.Workspace)
 		}
 
-		apiKey := cfg.GetAPIKey("")
-		apiBase := cfg.GetAPIBase("")
-		provider, bootWarning, err := buildGatewayProvider(cfg, apiKey, apiBase)
+		provider, bootWarning, err := buildGatewayProvider(cfg)
 		if err != nil {
 			return err
 		}
@@ -58,12 +63,33 @@ var gatewayCmd = &cobra.Command{
 		if bootWarning != "" {
 			fmt.Printf("⚠ %s\n", bootWarning)
 			if lg := logging.Get(); lg != nil && lg.Gateway != nil {
-				lg.Gateway.Printf("startup warning: %s", bootWarning)
+				lg.Gateway.Warn("startup warning", "warning", bootWarning)
+			}
+		}
+
+		if cfg.Telemetry.Tracing.Enabled {
+			shutdownTracing, err := telemetry.InitTracing(context.Background(), telemetry.TracingOptions{
+				Endpoint:    cfg.Telemetry.Tracing.Endpoint,
+				Insecure:    cfg.Telemetry.Tracing.Insecure,
+				Headers:     cfg.Telemetry.Tracing.Headers,
+				ServiceName: cfg.Telemetry.Tracing.ServiceName,
+				SampleRatio: cfg.Telemetry.Tracing.SampleRatio,
+			})
+			if err != nil {
+				return fmt.Errorf("invalid telemetry.tracing: %w", err)
 			}
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = shutdownTracing(ctx)
+			}()
+			fmt.Printf("✓ Tracing: exporting to %s\n", cfg.Telemetry.Tracing.Endpoint)
 		}
 
 		// 创建组件
 		messageBus := bus.NewMessageBus(100)
+		telemetry.RegisterQueueDepth("inbound", messageBus.InboundDepth)
+		telemetry.RegisterQueueDepth("outbound", messageBus.OutboundDepth)
 
 		// 创建 Cron 服务（需要先创建，传给 agent）
 		storePath := filepath.Join(cfg.Agents.Defaults.Workspace, ".cron", "jobs.json")
@@ -71,13 +97,13 @@ var gatewayCmd = &cobra.Command{
 		cronService.SetJobHandler(func(job *cron.Job) (string, error) {
 			// Manual runs should execute directly and return results immediately.
 			if job != nil && job.IsManualRun {
-				return executeCronJob(cfg, apiKey, apiBase, cronService, job)
+				return executeCronJob(cfg, cronService, job)
 			}
 			// Scheduled jobs with delivery config go through the message bus for async processing.
 			if job != nil && job.Payload.Deliver && len(job.Payload.Channels) > 0 && job.Payload.To != "" {
 				return enqueueCronJob(messageBus, job)
 			}
-			return executeCronJob(cfg, apiKey, apiBase, cronService, job)
+			return executeCronJob(cfg, cronService, job)
 		})
 
 		agentLoop := agent.NewAgentLoop(
@@ -95,159 +121,49 @@ var gatewayCmd = &cobra.Command{
 			cfg.Agents.Defaults.EnableGlobalSkills,
 		)
 		agentLoop.InitializeLifecycle()
-		agentLoop.UpdateRuntimeExecutionMode(cfg.Agents.Defaults.ExecutionMode)
-		defer agentLoop.Close()
-
-		// 创建频道注册表
-		channelRegistry := channels.NewRegistry()
-		mediaManager := media.NewManager(filepath.Join(config.GetDataDir(), "media", "inbound"))
-
-		// 注册 Telegram
-		if cfg.Channels.Telegram.Enabled {
-			mediaManager.Register("telegram", media.NewTelegramResolver(filepath.Join(config.GetDataDir(), "media", "inbound"), cfg.Channels.Telegram.Token, cfg.Channels.Telegram.Proxy))
-			tgChannel := channels.NewTelegramChannel(&channels.TelegramConfig{
-				Token:     cfg.Channels.Telegram.Token,
-				Enabled:   cfg.Channels.Telegram.Enabled,
-				AllowFrom: cfg.Channels.Telegram.AllowFrom,
-				Proxy:     cfg.Channels.Telegram.Proxy,
-			})
-			tgChannel.SetMessageHandler(func(msg *channels.Message) {
-				// 转发到消息总线
-				inboundMsg := bus.NewInboundMessage("telegram", msg.Sender, msg.ChatID, msg.Text)
-				inboundMsg.Media = stageInboundMedia(mediaManager, "telegram", msg.Media)
-				messageBus.PublishInbound(inboundMsg)
-			})
-			channelRegistry.Register(tgChannel)
+		toolPolicy, err := agent.BuildToolPolicy(cfg)
+		if err != nil {
+			return fmt.Errorf("invalid tools.policy: %w", err)
 		}
-
-		// 注册 Discord
-		if cfg.Channels.Discord.Enabled {
-			dcChannel := channels.NewDiscordChannel(&channels.DiscordConfig{
-				Token:     cfg.Channels.Discord.Token,
-				Enabled:   cfg.Channels.Discord.Enabled,
-				AllowFrom: cfg.Channels.Discord.AllowFrom,
-			})
-			dcChannel.SetMessageHandler(func(msg *channels.Message) {
-				inboundMsg := bus.NewInboundMessage("discord", msg.Sender, msg.ChatID, msg.Text)
-				messageBus.PublishInbound(inboundMsg)
-			})
-			channelRegistry.Register(dcChannel)
+		agentLoop.SetToolPolicy(toolPolicy)
+		agentLoop.SetRequireReadBeforeEdit(cfg.Tools.Edit.RequireRead)
+		if err := agent.ValidateWebSearchConfig(cfg); err != nil {
+			return fmt.Errorf("invalid tools.web.search: %w", err)
 		}
-
-		// 注册 WhatsApp (Bridge)
-		if cfg.Channels.WhatsApp.Enabled {
-			waChannel := channels.NewWhatsAppChannel(&channels.WhatsAppConfig{
-				Enabled:     cfg.Channels.WhatsApp.Enabled,
-				BridgeURL:   cfg.Channels.WhatsApp.BridgeURL,
-				BridgeToken: cfg.Channels.WhatsApp.BridgeToken,
-				AllowFrom:   cfg.Channels.WhatsApp.AllowFrom,
-				AllowSelf:   cfg.Channels.WhatsApp.AllowSelf,
-			})
-			waChannel.SetMessageHandler(func(msg *channels.Message) {
-				inboundMsg := bus.NewInboundMessage("whatsapp", msg.Sender, msg.ChatID, msg.Text)
-				messageBus.PublishInbound(inboundMsg)
-			})
-			channelRegistry.Register(waChannel)
+		agentLoop.SetWebSearchOptions(agent.BuildWebSearchOptions(cfg))
+		egressGuard, err := agent.BuildEgressGuard(cfg)
+		if err != nil {
+			return fmt.Errorf("invalid tools.egress: %w", err)
+		}
+		agentLoop.SetEgressGuard(egressGuard)
+		retrievalOptions, err := agent.BuildRetrievalOptions(cfg)
+		if err != nil {
+			return fmt.Errorf("invalid tools.retrieval: %w", err)
 		}
+		agentLoop.SetRetrievalOptions(retrievalOptions)
+		agentLoop.SetMemoryIdentities(cfg.Memory.Identities)
+		if err := agent.ValidateExecConfig(cfg); err != nil {
+			return fmt.Errorf("invalid tools.exec: %w", err)
+		}
+		agentLoop.UpdateRuntimeExecutionMode(cfg.Agents.Defaults.ExecutionMode)
 
-		// 注册 WebSocket
-		if cfg.Channels.WebSocket.Enabled {
-			wsChannel := channels.NewWebSocketChannel(&channels.WebSocketConfig{
-				Enabled:      cfg.Channels.WebSocket.Enabled,
-				Host:         cfg.Channels.WebSocket.Host,
-				Port:         cfg.Channels.WebSocket.Port,
-				Path:         cfg.Channels.WebSocket.Path,
-				AllowOrigins: cfg.Channels.WebSocket.AllowOrigins,
-			})
-			wsChannel.SetMessageHandler(func(msg *channels.Message) {
-				inboundMsg := bus.NewInboundMessage("websocket", msg.Sender, msg.ChatID, msg.Text)
-				messageBus.PublishInbound(inboundMsg)
-			})
-			channelRegistry.Register(wsChannel)
+		agentRouter := buildAgentRouter(cfg, agentLoop)
+		defer agentRouter.Close()
+		if names := agentRouter.Names(); len(names) > 0 {
+			fmt.Printf("✓ Agent profiles: %v\n", names)
+			if lg := logging.Get(); lg != nil && lg.Gateway != nil {
+				lg.Gateway.Info("agent profiles", "agents", names, "routes", len(cfg.Agents.Routes))
+			}
 		}
 
-		// 注册 Slack（Socket Mode）
-		if cfg.Channels.Slack.Enabled {
-			slackChannel := channels.NewSlackChannel(&channels.SlackConfig{
-				Enabled:   cfg.Channels.Slack.Enabled,
-				BotToken:  cfg.Channels.Slack.BotToken,
-				AppToken:  cfg.Channels.Slack.AppToken,
-				AllowFrom: cfg.Channels.Slack.AllowFrom,
-			})
-			slackChannel.SetMessageHandler(func(msg *channels.Message) {
-				inboundMsg := bus.NewInboundMessage("slack", msg.Sender, msg.ChatID, msg.Text)
-				messageBus.PublishInbound(inboundMsg)
-			})
-			channelRegistry.Register(slackChannel)
-		}
-
-		// 注册 Email（IMAP/SMTP）
-		if cfg.Channels.Email.Enabled {
-			emailChannel := channels.NewEmailChannel(&channels.EmailConfig{
-				Enabled:             cfg.Channels.Email.Enabled,
-				ConsentGranted:      cfg.Channels.Email.ConsentGranted,
-				IMAPHost:            cfg.Channels.Email.IMAPHost,
-				IMAPPort:            cfg.Channels.Email.IMAPPort,
-				IMAPUsername:        cfg.Channels.Email.IMAPUsername,
-				IMAPPassword:        cfg.Channels.Email.IMAPPassword,
-				IMAPMailbox:         cfg.Channels.Email.IMAPMailbox,
-				IMAPUseSSL:          cfg.Channels.Email.IMAPUseSSL,
-				SMTPHost:            cfg.Channels.Email.SMTPHost,
-				SMTPPort:            cfg.Channels.Email.SMTPPort,
-				SMTPUsername:        cfg.Channels.Email.SMTPUsername,
-				SMTPPassword:        cfg.Channels.Email.SMTPPassword,
-				SMTPUseTLS:          cfg.Channels.Email.SMTPUseTLS,
-				SMTPUseSSL:          cfg.Channels.Email.SMTPUseSSL,
-				FromAddress:         cfg.Channels.Email.FromAddress,
-				AutoReplyEnabled:    cfg.Channels.Email.AutoReplyEnabled,
-				PollIntervalSeconds: cfg.Channels.Email.PollIntervalSeconds,
-				MarkSeen:            cfg.Channels.Email.MarkSeen,
-				AllowFrom:           cfg.Channels.Email.AllowFrom,
-			})
-			emailChannel.SetMessageHandler(func(msg *channels.Message) {
-				inboundMsg := bus.NewInboundMessage("email", msg.Sender, msg.ChatID, msg.Text)
-				messageBus.PublishInbound(inboundMsg)
-			})
-			channelRegistry.Register(emailChannel)
-		}
-
-		// 注册 QQ（腾讯官方 QQBot）
-		if cfg.Channels.QQ.Enabled {
-			mediaManager.Register("qq", media.NewQQResolver(filepath.Join(config.GetDataDir(), "media", "inbound"), nil))
-			qqChannel := channels.NewQQChannel(&channels.QQConfig{
-				Enabled:     cfg.Channels.QQ.Enabled,
-				AppID:       cfg.Channels.QQ.AppID,
-				AppSecret:   cfg.Channels.QQ.AppSecret,
-				AccessToken: cfg.Channels.QQ.AccessToken,
-				ListenAddr:  cfg.Channels.QQ.ListenAddr,
-				WebhookPath: cfg.Channels.QQ.WebhookPath,
-				WSURL:       cfg.Channels.QQ.WSURL,
-				AllowFrom:   cfg.Channels.QQ.AllowFrom,
-			})
-			qqChannel.SetMessageHandler(func(msg *channels.Message) {
-				inboundMsg := bus.NewInboundMessage("qq", msg.Sender, msg.ChatID, msg.Text)
-				inboundMsg.Media = stageInboundMedia(mediaManager, "qq", msg.Media)
-				messageBus.PublishInbound(inboundMsg)
-			})
-			channelRegistry.Register(qqChannel)
-		}
-
-		// 注册 Feishu（Webhook + OpenAPI）
-		if cfg.Channels.Feishu.Enabled {
-			feishuChannel := channels.NewFeishuChannel(&channels.FeishuConfig{
-				Enabled:           cfg.Channels.Feishu.Enabled,
-				AppID:             cfg.Channels.Feishu.AppID,
-				AppSecret:         cfg.Channels.Feishu.AppSecret,
-				VerificationToken: cfg.Channels.Feishu.VerificationToken,
-				ListenAddr:        cfg.Channels.Feishu.ListenAddr,
-				WebhookPath:       cfg.Channels.Feishu.WebhookPath,
-				AllowFrom:         cfg.Channels.Feishu.AllowFrom,
-			})
-			feishuChannel.SetMessageHandler(func(msg *channels.Message) {
-				inboundMsg := bus.NewInboundMessage("feishu", msg.Sender, msg.ChatID, msg.Text)
-				messageBus.PublishInbound(inboundMsg)
-			})
-			channelRegistry.Register(feishuChannel)
+		// 创建频道注册表
+		channelRegistry := channels.NewRegistry()
+		mediaManager := media.NewManager(filepath.Join(config.GetDataDir(), "media", "inbound"))
+
+		for _, name := range gatewayChannelNames {
+			if ch := newGatewayChannel(cfg, name, messageBus, mediaManager); ch != nil {
+				channelRegistry.Register(ch)
+			}
 		}
 
 		// 检查启用的频道
@@ -259,12 +175,12 @@ var gatewayCmd = &cobra.Command{
 		if len(enabledChannels) > 0 {
 			fmt.Printf("✓ Channels enabled: %v\n", enabledChannels)
 			if lg := logging.Get(); lg != nil && lg.Gateway != nil {
-				lg.Gateway.Printf("channels enabled: %v", enabledChannels)
+				lg.Gateway.Info("channels enabled", "channels", enabledChannels)
 			}
 		} else {
 			fmt.Println("⚠ Warning: No channels enabled")
 			if lg := logging.Get(); lg != nil && lg.Gateway != nil {
-				lg.Gateway.Printf("warning: no channels enabled")
+				lg.Gateway.Warn("no channels enabled")
 			}
 		}
 
@@ -272,7 +188,21 @@ var gatewayCmd = &cobra.Command{
 		cronStatus := cronService.Status()
 		fmt.Printf("✓ Cron jobs: %d total, %d enabled\n", cronStatus["totalJobs"], cronStatus["enabledJobs"])
 		if lg := logging.Get(); lg != nil && lg.Gateway != nil {
-			lg.Gateway.Printf("cron jobs total=%v enabled=%v", cronStatus["totalJobs"], cronStatus["enabledJobs"])
+			lg.Gateway.Info("cron jobs", "total", cronStatus["totalJobs"], "enabled", cronStatus["enabledJobs"])
+		}
+
+		// 创建事件触发器服务（与定时任务共享执行历史）
+		triggerService := newGatewayTriggerService(cfg, agentLoop, messageBus, cronService)
+		fmt.Printf("✓ Triggers: %d\n", len(triggerService.List()))
+
+		// 创建心跳服务（heartbeat.enabled 时按 memory/heartbeat.md 定期主动检查）
+		var heartbeatService *heartbeat.Service
+		if cfg.Heartbeat.Enabled {
+			heartbeatService, err = newGatewayHeartbeat(cfg, agentLoop, messageBus, cronService)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("✓ Heartbeat: %s\n", heartbeatSummary(cfg.Heartbeat))
 		}
 
 		// 启动所有服务
@@ -281,11 +211,13 @@ var gatewayCmd = &cobra.Command{
 
 		// 启动 Web UI/API 服务器
 		webServer := webui.NewServer(cfg, agentLoop, cronService, channelRegistry)
+		webServer.SetAgentRouter(agentRouter)
+		webServer.SetTriggerService(triggerService)
 		go func() {
 			if err := webServer.Start(ctx, cfg.Gateway.Host, gatewayPort); err != nil && err != context.Canceled {
 				fmt.Printf("⚠ Web UI server error: %v\n", err)
 				if lg := logging.Get(); lg != nil && lg.Web != nil {
-					lg.Web.Printf("webui error: %v", err)
+					lg.Web.Error("webui error", "err", err)
 				}
 			}
 		}()
@@ -309,14 +241,14 @@ var gatewayCmd = &cobra.Command{
 					_ = conn.Close()
 					fmt.Printf("READY:%s\n", addr)
 					if lg := logging.Get(); lg != nil && lg.Gateway != nil {
-						lg.Gateway.Printf("ready protocol sent: %s", addr)
+						lg.Gateway.Info("ready protocol sent", "addr", addr)
 					}
 					return
 				}
 				time.Sleep(readyPollInterval)
 			}
 			if lg := logging.Get(); lg != nil && lg.Gateway != nil {
-				lg.Gateway.Printf("ready protocol timeout: port %d not reachable after 10s", gatewayPort)
+				lg.Gateway.Warn("ready protocol timeout: port not reachable after 10s", "port", gatewayPort)
 			}
 		}()
 
@@ -324,6 +256,14 @@ var gatewayCmd = &cobra.Command{
 		cronService.SetNotificationHandler(func(title, body string, data map[string]interface{}) {
 			webServer.AddNotification(title, body, data)
 		})
+		triggerService.SetNotificationHandler(func(title, body string, data map[string]interface{}) {
+			webServer.AddNotification(title, body, data)
+		})
+		if heartbeatService != nil {
+			heartbeatService.SetNotificationHandler(func(title, body string, data map[string]interface{}) {
+				webServer.AddNotification(title, body, data)
+			})
+		}
 
 		fmt.Println("✓ Gateway ready")
 		fmt.Println("\nPress Ctrl+C to stop")
@@ -333,21 +273,42 @@ var gatewayCmd = &cobra.Command{
 			if err := ch.Start(ctx); err != nil {
 				fmt.Printf("⚠ Failed to start %s channel: %v\n", ch.Name(), err)
 				if lg := logging.Get(); lg != nil && lg.Channels != nil {
-					lg.Channels.Printf("start channel=%s error=%v", ch.Name(), err)
+					lg.Channels.Error("start channel failed", "channel", ch.Name(), "err", err)
 				}
 			}
 		}
 
+		// 监听 config.json，外部修改按差异热加载
+		reloader := &configReloader{
+			ctx:          ctx,
+			web:          webServer,
+			registry:     channelRegistry,
+			messageBus:   messageBus,
+			mediaManager: mediaManager,
+		}
+		go config.NewWatcher(config.GetConfigPath(), cfg, reloader.apply, reloader.reportError).Run(ctx)
+
 		// 启动 Cron 服务
 		if err := cronService.Start(); err != nil {
 			fmt.Printf("⚠ Failed to start cron service: %v\n", err)
 			if lg := logging.Get(); lg != nil && lg.Cron != nil {
-				lg.Cron.Printf("cron start error: %v", err)
+				lg.Cron.Error("cron start error", "err", err)
 			}
 		}
 
+		// 启动事件触发器（文件与邮件监听；webhook 由 Web 服务接收）
+		triggerService.Start(ctx)
+
+		// 启动心跳检查
+		if heartbeatService != nil {
+			go heartbeatService.Start(ctx)
+		}
+
 		// 启动每日 Memory 汇总器（每小时检查一次，幂等写入 memory/MEMORY.md）
 		dailySummary := memory.NewDailySummaryService(cfg.Agents.Defaults.Workspace, time.Hour)
+		if cfg.Memory.AutoCurateEnabled() {
+			dailySummary.EnableCuration(memoryCurateOptions(cfg, agentLoop.MemoryCondenser()))
+		}
 		go dailySummary.Start(ctx)
 
 		// 启动出站消息处理器
@@ -362,22 +323,23 @@ var gatewayCmd = &cobra.Command{
 			cancel()
 		}()
 
-		// 运行 Agent
-		if err := agentLoop.Run(ctx); err != nil && err != context.Canceled {
+		// 运行 Agent（按 agents.routes 分发到命名代理）
+		if err := agentRouter.Run(ctx); err != nil && err != context.Canceled {
 			if lg := logging.Get(); lg != nil && lg.Gateway != nil {
-				lg.Gateway.Printf("agent loop error: %v", err)
+				lg.Gateway.Error("agent loop error", "err", err)
 			}
 			return fmt.Errorf("agent error: %w", err)
 		}
 
 		// 停止所有服务
 		cronService.Stop()
+		triggerService.Stop()
 		for _, ch := range channelRegistry.GetAll() {
 			ch.Stop()
 		}
 
 		if lg := logging.Get(); lg != nil && lg.Gateway != nil {
-			lg.Gateway.Printf("gateway shutdown")
+			lg.Gateway.Info("gateway shutdown")
 		}
 
 		return nil
@@ -392,36 +354,74 @@ func stageInboundMedia(manager *media.Manager, channel string, attachment *bus.M
 	staged, err := manager.StageInbound(context.Background(), channel, attachment)
 	if err != nil {
 		if lg := logging.Get(); lg != nil && lg.Channels != nil {
-			lg.Channels.Printf("stage inbound media failed channel=%s type=%s err=%v", channel, attachment.Type, err)
+			lg.Channels.Error("stage inbound media failed", "channel", channel, "type", attachment.Type, "err", err)
 		}
 		return attachment
 	}
 	return staged
 }
 
-func buildGatewayProvider(cfg *config.Config, apiKey, apiBase string) (providers.LLMProvider, string, error) {
-	if apiKey == "" {
+func buildGatewayProvider(cfg *config.Config) (providers.LLMProvider, string, error) {
+	if cfg.GetAPIKey("") == "" && len(cfg.Routing.Backends) == 0 {
 		return &unavailableProvider{
 			model:  cfg.Agents.Defaults.Model,
 			reason: "no API key configured. Set one in ~/.maxclaw/config.json (or via Web UI settings) to enable model requests",
 		}, "No API key configured. Gateway started in configuration-only mode; model requests will fail until key is set.", nil
 	}
 
-	provider, err := providers.NewProvider(
-		apiKey,
-		apiBase,
-		cfg.GetAPIFormat(cfg.Agents.Defaults.Model),
-		cfg.Agents.Defaults.Model,
-		cfg.Agents.Defaults.MaxTokens,
-		cfg.Agents.Defaults.Temperature,
-		cfg.SupportsImageInput,
-	)
+	provider, err := agent.NewProviderFromConfig(cfg, cfg.Agents.Defaults.Model, cfg.Agents.Defaults.MaxTokens, cfg.Agents.Defaults.Temperature)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create provider: %w", err)
 	}
 	return provider, "", nil
 }
 
+// buildAgentRouter 为 agents.profiles 中的每个命名代理创建独立的 AgentLoop
+func buildAgentRouter(cfg *config.Config, defaultLoop *agent.AgentLoop) *agent.AgentRouter {
+	router := agent.NewAgentRouter(defaultLoop, cfg.Agents.Routes)
+	for _, name := range cfg.AgentProfileNames() {
+		profile, ok := cfg.ResolveAgent(name)
+		if !ok {
+			continue
+		}
+		if err := os.MkdirAll(profile.Workspace, 0755); err != nil {
+			fmt.Printf("⚠ Agent %s disabled: %v\n", name, err)
+			if lg := logging.Get(); lg != nil && lg.Gateway != nil {
+				lg.Gateway.Error("agent profile workspace error", "agent", name, "err", err)
+			}
+			continue
+		}
+		provider, err := buildAgentProvider(cfg, profile)
+		if err != nil {
+			fmt.Printf("⚠ Agent %s disabled: %v\n", name, err)
+			if lg := logging.Get(); lg != nil && lg.Gateway != nil {
+				lg.Gateway.Error("agent profile provider error", "agent", name, "err", err)
+			}
+			continue
+		}
+		loop := agent.NewProfileAgentLoop(defaultLoop, profile, provider)
+		loop.InitializeLifecycle()
+		router.AddProfile(name, loop)
+	}
+	return router
+}
+
+func buildAgentProvider(cfg *config.Config, profile config.ResolvedAgent) (providers.LLMProvider, error) {
+	apiKey := cfg.GetAPIKey(profile.Model)
+	if apiKey == "" {
+		return &unavailableProvider{
+			model:  profile.Model,
+			reason: fmt.Sprintf("no API key configured for agent %s (model %s)", profile.Name, profile.Model),
+		}, nil
+	}
+
+	provider, err := agent.NewProviderFromConfig(cfg, profile.Model, profile.MaxTokens, profile.Temperature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	return provider, nil
+}
+
 type unavailableProvider struct {
 	model  string
 	reason string
@@ -472,7 +472,7 @@ func handleOutboundMessages(ctx context.Context, bus *bus.MessageBus, registry *
 		}
 		if msg.Channel == "" || msg.ChatID == "" {
 			if lg := logging.Get(); lg != nil && lg.Gateway != nil {
-				lg.Gateway.Printf("drop outbound: channel=%q chat=%q content=%q", msg.Channel, msg.ChatID, logging.Truncate(msg.Content, 200))
+				lg.Gateway.Warn("drop outbound: missing channel or chat", "channel", msg.Channel, "chat_id", msg.ChatID, "content", logging.Truncate(msg.Content, 200))
 			}
 			continue
 		}
@@ -480,27 +480,35 @@ func handleOutboundMessages(ctx context.Context, bus *bus.MessageBus, registry *
 		ch, ok := registry.Get(msg.Channel)
 		if !ok {
 			if lg := logging.Get(); lg != nil && lg.Gateway != nil {
-				lg.Gateway.Printf("drop outbound: channel %q not registered", msg.Channel)
+				lg.Gateway.Warn("drop outbound: channel not registered", "channel", msg.Channel)
 			}
 			continue
 		}
 
+		sendCtx, span := telemetry.StartSpan(
+			telemetry.ExtractTraceParent(ctx, msg.TraceParent),
+			"channel.send",
+			attribute.String("channel", msg.Channel),
+		)
+		var sendErr error
 		// 检查是否有媒体附件
 		if msg.Media != nil && msg.Media.Type != "" {
 			// 尝试发送带附件的消息
-			if err := sendMessageWithMedia(ch, msg); err != nil {
+			if sendErr = sendMessageWithMedia(ch, msg); sendErr != nil {
 				if lg := logging.Get(); lg != nil && lg.Channels != nil {
-					lg.Channels.Printf("send media failed channel=%s chat=%s type=%s err=%v", msg.Channel, msg.ChatID, msg.Media.Type, err)
+					lg.Channels.ErrorContext(sendCtx, "send media failed", "channel", msg.Channel, "chat_id", msg.ChatID, "type", msg.Media.Type, "err", sendErr)
 				}
 			}
 		} else {
 			// 发送普通文本消息
-			if err := ch.SendMessage(msg.ChatID, msg.Content); err != nil {
+			if sendErr = ch.SendMessage(msg.ChatID, msg.Content); sendErr != nil {
 				if lg := logging.Get(); lg != nil && lg.Channels != nil {
-					lg.Channels.Printf("send failed channel=%s chat=%s err=%v", msg.Channel, msg.ChatID, err)
+					lg.Channels.ErrorContext(sendCtx, "send failed", "channel", msg.Channel, "chat_id", msg.ChatID, "err", sendErr)
 				}
 			}
 		}
+		telemetry.RecordOutbound(msg.Channel, sendErr)
+		telemetry.EndSpan(span, sendErr)
 	}
 }
 
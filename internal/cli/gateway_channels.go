@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"path/filepath"
+
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/channels"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/media"
+)
+
+// gatewayChannelNames gateway 支持的频道，顺序即注册顺序
+var gatewayChannelNames = []string{"telegram", "discord", "whatsapp", "websocket", "slack", "email", "qq", "feishu"}
+
+// newGatewayChannel 按配置创建单个频道并接入消息总线；未启用时返回 nil
+func newGatewayChannel(cfg *config.Config, name string, messageBus *bus.MessageBus, mediaManager *media.Manager) channels.Channel {
+	inboundMediaDir := filepath.Join(config.GetDataDir(), "media", "inbound")
+	forward := func(msg *channels.Message) {
+		inboundMsg := bus.NewInboundMessage(name, msg.Sender, msg.ChatID, msg.Text)
+		messageBus.PublishInbound(inboundMsg)
+	}
+
+	var ch channels.Channel
+	switch name {
+	case "telegram":
+		if !cfg.Channels.Telegram.Enabled {
+			return nil
+		}
+		mediaManager.Register("telegram", media.NewTelegramResolver(inboundMediaDir, cfg.Channels.Telegram.Token, cfg.Channels.Telegram.Proxy))
+		ch = channels.NewTelegramChannel(&channels.TelegramConfig{
+			Token:     cfg.Channels.Telegram.Token,
+			Enabled:   cfg.Channels.Telegram.Enabled,
+			AllowFrom: cfg.Channels.Telegram.AllowFrom,
+			Proxy:     cfg.Channels.Telegram.Proxy,
+		})
+		forward = func(msg *channels.Message) {
+			inboundMsg := bus.NewInboundMessage("telegram", msg.Sender, msg.ChatID, msg.Text)
+			inboundMsg.Media = stageInboundMedia(mediaManager, "telegram", msg.Media)
+			messageBus.PublishInbound(inboundMsg)
+		}
+
+	case "discord":
+		if !cfg.Channels.Discord.Enabled {
+			return nil
+		}
+		ch = channels.NewDiscordChannel(&channels.DiscordConfig{
+			Token:     cfg.Channels.Discord.Token,
+			Enabled:   cfg.Channels.Discord.Enabled,
+			AllowFrom: cfg.Channels.Discord.AllowFrom,
+		})
+
+	case "whatsapp":
+		// WhatsApp (Bridge)
+		if !cfg.Channels.WhatsApp.Enabled {
+			return nil
+		}
+		ch = channels.NewWhatsAppChannel(&channels.WhatsAppConfig{
+			Enabled:     cfg.Channels.WhatsApp.Enabled,
+			BridgeURL:   cfg.Channels.WhatsApp.BridgeURL,
+			BridgeToken: cfg.Channels.WhatsApp.BridgeToken,
+			AllowFrom:   cfg.Channels.WhatsApp.AllowFrom,
+			AllowSelf:   cfg.Channels.WhatsApp.AllowSelf,
+		})
+
+	case "websocket":
+		if !cfg.Channels.WebSocket.Enabled {
+			return nil
+		}
+		ch = channels.NewWebSocketChannel(&channels.WebSocketConfig{
+			Enabled:      cfg.Channels.WebSocket.Enabled,
+			Host:         cfg.Channels.WebSocket.Host,
+			Port:         cfg.Channels.WebSocket.Port,
+			Path:         cfg.Channels.WebSocket.Path,
+			AllowOrigins: cfg.Channels.WebSocket.AllowOrigins,
+		})
+
+	case "slack":
+		// Slack（Socket Mode）
+		if !cfg.Channels.Slack.Enabled {
+			return nil
+		}
+		ch = channels.NewSlackChannel(&channels.SlackConfig{
+			Enabled:   cfg.Channels.Slack.Enabled,
+			BotToken:  cfg.Channels.Slack.BotToken,
+			AppToken:  cfg.Channels.Slack.AppToken,
+			AllowFrom: cfg.Channels.Slack.AllowFrom,
+		})
+
+	case "email":
+		// Email（IMAP/SMTP）
+		if !cfg.Channels.Email.Enabled {
+			return nil
+		}
+		ch = channels.NewEmailChannel(&channels.EmailConfig{
+			Enabled:             cfg.Channels.Email.Enabled,
+			ConsentGranted:      cfg.Channels.Email.ConsentGranted,
+			IMAPHost:            cfg.Channels.Email.IMAPHost,
+			IMAPPort:            cfg.Channels.Email.IMAPPort,
+			IMAPUsername:        cfg.Channels.Email.IMAPUsername,
+			IMAPPassword:        cfg.Channels.Email.IMAPPassword,
+			IMAPMailbox:         cfg.Channels.Email.IMAPMailbox,
+			IMAPUseSSL:          cfg.Channels.Email.IMAPUseSSL,
+			SMTPHost:            cfg.Channels.Email.SMTPHost,
+			SMTPPort:            cfg.Channels.Email.SMTPPort,
+			SMTPUsername:        cfg.Channels.Email.SMTPUsername,
+			SMTPPassword:        cfg.Channels.Email.SMTPPassword,
+			SMTPUseTLS:          cfg.Channels.Email.SMTPUseTLS,
+			SMTPUseSSL:          cfg.Channels.Email.SMTPUseSSL,
+			FromAddress:         cfg.Channels.Email.FromAddress,
+			AutoReplyEnabled:    cfg.Channels.Email.AutoReplyEnabled,
+			PollIntervalSeconds: cfg.Channels.Email.PollIntervalSeconds,
+			MarkSeen:            cfg.Channels.Email.MarkSeen,
+			AllowFrom:           cfg.Channels.Email.AllowFrom,
+		})
+
+	case "qq":
+		// QQ（腾讯官方 QQBot）
+		if !cfg.Channels.QQ.Enabled {
+			return nil
+		}
+		mediaManager.Register("qq", media.NewQQResolver(inboundMediaDir, nil))
+		ch = channels.NewQQChannel(&channels.QQConfig{
+			Enabled:     cfg.Channels.QQ.Enabled,
+			AppID:       cfg.Channels.QQ.AppID,
+			AppSecret:   cfg.Channels.QQ.AppSecret,
+			AccessToken: cfg.Channels.QQ.AccessToken,
+			ListenAddr:  cfg.Channels.QQ.ListenAddr,
+			WebhookPath: cfg.Channels.QQ.WebhookPath,
+			WSURL:       cfg.Channels.QQ.WSURL,
+			AllowFrom:   cfg.Channels.QQ.AllowFrom,
+		})
+		forward = func(msg *channels.Message) {
+			inboundMsg := bus.NewInboundMessage("qq", msg.Sender, msg.ChatID, msg.Text)
+			inboundMsg.Media = stageInboundMedia(mediaManager, "qq", msg.Media)
+			messageBus.PublishInbound(inboundMsg)
+		}
+
+	case "feishu":
+		// Feishu（Webhook + OpenAPI）
+		if !cfg.Channels.Feishu.Enabled {
+			return nil
+		}
+		ch = channels.NewFeishuChannel(&channels.FeishuConfig{
+			Enabled:           cfg.Channels.Feishu.Enabled,
+			AppID:             cfg.Channels.Feishu.AppID,
+			AppSecret:         cfg.Channels.Feishu.AppSecret,
+			VerificationToken: cfg.Channels.Feishu.VerificationToken,
+			ListenAddr:        cfg.Channels.Feishu.ListenAddr,
+			WebhookPath:       cfg.Channels.Feishu.WebhookPath,
+			AllowFrom:         cfg.Channels.Feishu.AllowFrom,
+		})
+
+	default:
+		return nil
+	}
+
+	ch.SetMessageHandler(forward)
+	return ch
+}
@@ -132,7 +132,7 @@ func TestHandleOutboundMessagesContinueAfterError(t *testing.T) {
 
 func TestBuildGatewayProviderWithoutAPIKeyFallsBack(t *testing.T) {
 	cfg := config.DefaultConfig()
-	provider, warning, err := buildGatewayProvider(cfg, "", "")
+	provider, warning, err := buildGatewayProvider(cfg)
 	if err != nil {
 		t.Fatalf("buildGatewayProvider returned error: %v", err)
 	}
@@ -155,8 +155,10 @@ func TestBuildGatewayProviderWithoutAPIKeyFallsBack(t *testing.T) {
 func TestBuildGatewayProviderWithAPIKeyUsesOpenAIProvider(t *testing.T) {
 	cfg := config.DefaultConfig()
 	cfg.Agents.Defaults.Model = "gpt-4o-mini"
+	cfg.Providers.OpenAI.APIKey = "sk-test"
+	cfg.Providers.OpenAI.APIBase = "https://example.com/v1"
 
-	provider, warning, err := buildGatewayProvider(cfg, "sk-test", "https://example.com/v1")
+	provider, warning, err := buildGatewayProvider(cfg)
 	if err != nil {
 		t.Fatalf("buildGatewayProvider returned error: %v", err)
 	}
@@ -174,8 +176,9 @@ func TestBuildGatewayProviderWithAPIKeyUsesOpenAIProvider(t *testing.T) {
 func TestBuildGatewayProviderWithAnthropicModelUsesAnthropicProvider(t *testing.T) {
 	cfg := config.DefaultConfig()
 	cfg.Agents.Defaults.Model = "anthropic/claude-sonnet-4-5"
+	cfg.Providers.Anthropic.APIKey = "sk-ant"
 
-	provider, warning, err := buildGatewayProvider(cfg, "sk-ant", "https://api.anthropic.com")
+	provider, warning, err := buildGatewayProvider(cfg)
 	if err != nil {
 		t.Fatalf("buildGatewayProvider returned error: %v", err)
 	}
@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/cron"
+	"github.com/Lichas/maxclaw/internal/heartbeat"
+	"github.com/Lichas/maxclaw/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var heartbeatJSONFlag bool
+
+func init() {
+	heartbeatRunCmd.Flags().BoolVar(&heartbeatJSONFlag, "json", false, "Print the result as JSON")
+
+	heartbeatCmd.AddCommand(heartbeatStatusCmd)
+	heartbeatCmd.AddCommand(heartbeatRunCmd)
+	rootCmd.AddCommand(heartbeatCmd)
+}
+
+// heartbeatCmd heartbeat 根命令
+var heartbeatCmd = &cobra.Command{
+	Use:   "heartbeat",
+	Short: "Proactive check-ins driven by memory/heartbeat.md",
+	Long:  "When heartbeat.enabled is set, the gateway periodically runs the agent against memory/heartbeat.md and only messages you when something needs attention.",
+}
+
+var heartbeatStatusCmd = &cobra.Command{
+	Use:          "status",
+	Short:        "Show heartbeat settings and the last check",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		service, err := heartbeat.NewService(cfg.Agents.Defaults.Workspace, cfg.Heartbeat, nil)
+		if err != nil {
+			return err
+		}
+		printHeartbeatStatus(cmd.OutOrStdout(), cfg.Heartbeat, service.State())
+		return nil
+	},
+}
+
+var heartbeatRunCmd = &cobra.Command{
+	Use:          "run",
+	Short:        "Run one check now and show what would be delivered",
+	Long:         "Runs the heartbeat check once, ignoring the interval and active hours. Nothing is delivered and the gateway's schedule and dedupe state are not changed.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		initLogging(cfg)
+		service, err := heartbeat.NewService(cfg.Agents.Defaults.Workspace, cfg.Heartbeat, nil)
+		if err != nil {
+			return err
+		}
+		agentLoop, err := newCLIAgentLoop(cfg)
+		if err != nil {
+			return err
+		}
+		defer agentLoop.Close()
+		// 试运行使用一次性会话，结束后删除，不影响 gateway 的 heartbeat 会话
+		sessionKey := fmt.Sprintf("%s:manual:%d", heartbeat.SessionKey, time.Now().UnixNano())
+		defer func() { _ = session.NewManager(cfg.Agents.Defaults.Workspace).Delete(sessionKey) }()
+		service.SetRunner(runHeartbeatPrompt(agentLoop, cfg.Heartbeat, sessionKey))
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		result := service.Check(ctx)
+		if heartbeatJSONFlag {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(result)
+		}
+		printHeartbeatResult(cmd.OutOrStdout(), result)
+		if result.Status == heartbeat.StatusFailed {
+			return fmt.Errorf("heartbeat check failed: %s", result.Error)
+		}
+		return nil
+	},
+}
+
+func printHeartbeatStatus(out io.Writer, hb config.HeartbeatConfig, state heartbeat.State) {
+	enabled := "disabled (set heartbeat.enabled to true)"
+	if hb.Enabled {
+		enabled = "enabled"
+	}
+	orAlways := func(v string) string {
+		if v == "" {
+			return "always"
+		}
+		return v
+	}
+	target := "web ui notifications"
+	if hb.Channel != "" {
+		target = hb.Channel + " " + hb.To
+	}
+	fmt.Fprintf(out, "Heartbeat: %s\n", enabled)
+	fmt.Fprintf(out, "  Every: %s\n", formatHeartbeatDuration(hb.Interval()))
+	fmt.Fprintf(out, "  Active hours: %s (%s)\n", orAlways(hb.ActiveHours), hb.Location())
+	if hb.QuietHours != "" {
+		fmt.Fprintf(out, "  Quiet hours: %s\n", hb.QuietHours)
+	}
+	fmt.Fprintf(out, "  Deliver to: %s\n", target)
+	fmt.Fprintf(out, "  Dedupe: %s\n", formatHeartbeatDuration(hb.DedupeWindow()))
+	if !state.LastRun.IsZero() {
+		fmt.Fprintf(out, "  Last check: %s (%s)\n", state.LastRun.In(hb.Location()).Format("2006-01-02 15:04"), state.LastStatus)
+	}
+	if !state.LastAlert.IsZero() {
+		fmt.Fprintf(out, "  Last alert: %s\n", state.LastAlert.In(hb.Location()).Format("2006-01-02 15:04"))
+	}
+	if state.Held != nil {
+		fmt.Fprintf(out, "  Held until quiet hours end: %s\n", state.Held.Text)
+	}
+}
+
+func printHeartbeatResult(out io.Writer, result heartbeat.Result) {
+	switch result.Status {
+	case heartbeat.StatusEmpty:
+		fmt.Fprintln(out, "memory/heartbeat.md has no checklist, the model was not called")
+	case heartbeat.StatusOK:
+		fmt.Fprintf(out, "✓ Nothing to report (%s), no message would be sent\n", heartbeat.Sentinel)
+	case heartbeat.StatusDuplicate:
+		fmt.Fprintf(out, "Already delivered recently, would be suppressed:\n%s\n", result.Alert)
+	case heartbeat.StatusAlert:
+		fmt.Fprintf(out, "Would deliver:\n%s\n", result.Alert)
+	case heartbeat.StatusFailed:
+		if result.Reply != "" {
+			fmt.Fprintln(out, result.Reply)
+		}
+	}
+}
+
+// newGatewayHeartbeat 创建 gateway 使用的心跳服务：检查走共享的 agentLoop，提醒经消息总线投递
+func newGatewayHeartbeat(cfg *config.Config, agentLoop *agent.AgentLoop, messageBus *bus.MessageBus, cronService *cron.Service) (*heartbeat.Service, error) {
+	service, err := heartbeat.NewService(cfg.Agents.Defaults.Workspace, cfg.Heartbeat, cronService.GetHistoryStore())
+	if err != nil {
+		return nil, err
+	}
+	service.SetRunner(runHeartbeatPrompt(agentLoop, cfg.Heartbeat, heartbeat.SessionKey))
+	service.SetDeliverer(func(channel, to, content string) error {
+		return messageBus.PublishOutbound(bus.NewOutboundMessage(channel, to, content))
+	})
+	return service, nil
+}
+
+// runHeartbeatPrompt 在独立会话 sessionKey 中执行检查，频道与会话取投递目标，便于加载对应的会话记忆
+func runHeartbeatPrompt(agentLoop *agent.AgentLoop, hb config.HeartbeatConfig, sessionKey string) heartbeat.RunFunc {
+	return func(ctx context.Context, prompt string) (string, error) {
+		channel := hb.Channel
+		if channel == "" {
+			channel = "desktop"
+		}
+		msg := bus.NewInboundMessage(channel, "heartbeat", hb.To, prompt)
+		msg.SessionKey = sessionKey
+		resp, err := agentLoop.ProcessMessage(ctx, msg)
+		if err != nil {
+			return "", err
+		}
+		if resp == nil {
+			return "", nil
+		}
+		return resp.Content, nil
+	}
+}
+
+// heartbeatSummary gateway 启动时显示的心跳设置
+func heartbeatSummary(hb config.HeartbeatConfig) string {
+	s := "every " + formatHeartbeatDuration(hb.Interval())
+	if hb.ActiveHours != "" {
+		s += ", active " + hb.ActiveHours
+	}
+	if hb.QuietHours != "" {
+		s += ", quiet " + hb.QuietHours
+	}
+	if hb.Channel != "" {
+		s += ", to " + hb.Channel
+	}
+	return s
+}
+
+// formatHeartbeatDuration 以整小时或分钟显示间隔
+func formatHeartbeatDuration(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
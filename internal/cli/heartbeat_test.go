@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/heartbeat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeatSummaryAndStatus(t *testing.T) {
+	hb := config.HeartbeatConfig{Enabled: true, ActiveHours: "08:00-22:00", QuietHours: "22:30-07:00", Timezone: "UTC", Channel: "telegram", To: "1001"}
+	assert.Equal(t, "every 30m, active 08:00-22:00, quiet 22:30-07:00, to telegram", heartbeatSummary(hb))
+
+	var out bytes.Buffer
+	printHeartbeatStatus(&out, hb, heartbeat.State{
+		LastRun:    time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+		LastStatus: heartbeat.StatusHeld,
+		Held:       &heartbeat.HeldAlert{Text: "Backup failed."},
+	})
+	assert.Contains(t, out.String(), "Heartbeat: enabled\n")
+	assert.Contains(t, out.String(), "Dedupe: 24h\n")
+	assert.Contains(t, out.String(), "Deliver to: telegram 1001\n")
+	assert.Contains(t, out.String(), "Last check: 2026-10-19 09:00 (held)\n")
+	assert.Contains(t, out.String(), "Held until quiet hours end: Backup failed.\n")
+
+	out.Reset()
+	printHeartbeatStatus(&out, config.HeartbeatConfig{IntervalMinutes: 90}, heartbeat.State{})
+	assert.Contains(t, out.String(), "disabled")
+	assert.Contains(t, out.String(), "Every: 90m\n")
+	assert.Contains(t, out.String(), "Active hours: always")
+	assert.Contains(t, out.String(), "Deliver to: web ui notifications\n")
+}
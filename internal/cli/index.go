@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/rag"
+	"github.com/spf13/cobra"
+)
+
+var (
+	indexAgentFlag string
+	indexFullFlag  bool
+	indexLimitFlag int
+	indexPathFlag  string
+	indexModeFlag  string
+)
+
+func init() {
+	indexCmd.PersistentFlags().StringVar(&indexAgentFlag, "agent", "", "Agent profile whose workspace is indexed (default agent when empty)")
+	indexBuildCmd.Flags().BoolVar(&indexFullFlag, "full", false, "Discard the existing index and rebuild from scratch")
+	indexSearchCmd.Flags().IntVar(&indexLimitFlag, "limit", 5, "Number of results")
+	indexSearchCmd.Flags().StringVar(&indexPathFlag, "path", "", "Only search under this workspace-relative path")
+	indexSearchCmd.Flags().StringVar(&indexModeFlag, "mode", rag.ModeHybrid, "hybrid, keyword or semantic")
+
+	indexCmd.AddCommand(indexBuildCmd)
+	indexCmd.AddCommand(indexStatusCmd)
+	indexCmd.AddCommand(indexSearchCmd)
+	rootCmd.AddCommand(indexCmd)
+}
+
+// indexCmd 工作区检索索引命令
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Build and inspect the workspace search index (search_workspace)",
+}
+
+var indexBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Index new and changed workspace files and compute embeddings",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		index, err := openWorkspaceIndex(indexAgentFlag)
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		var stats rag.UpdateStats
+		if indexFullFlag {
+			stats, err = index.Rebuild(cmd.Context())
+		} else {
+			stats, err = index.Update(cmd.Context())
+		}
+		if err != nil && !errors.Is(err, rag.ErrEmbedding) {
+			return fmt.Errorf("index build failed: %w", err)
+		}
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "✓ Indexed in %s: %d added, %d updated, %d removed, %d unchanged, %d chunks embedded\n",
+			time.Since(start).Round(time.Millisecond), stats.Added, stats.Updated, stats.Removed, stats.Unchanged, stats.Embedded)
+		if stats.Truncated {
+			fmt.Fprintln(out, "! File limit reached; raise tools.retrieval.maxFiles or add exclude rules")
+		}
+		if err != nil {
+			return fmt.Errorf("index incomplete (keyword search still works): %w", err)
+		}
+		printIndexStatus(out, index.Status())
+		return nil
+	},
+}
+
+var indexStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show index location, size and embedding coverage",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		index, err := openWorkspaceIndex(indexAgentFlag)
+		if err != nil {
+			return err
+		}
+		printIndexStatus(cmd.OutOrStdout(), index.Status())
+		return nil
+	},
+}
+
+var indexSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the index without updating it",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		index, err := openWorkspaceIndex(indexAgentFlag)
+		if err != nil {
+			return err
+		}
+		if index.Status().UpdatedAt.IsZero() {
+			return fmt.Errorf("workspace index not built yet; run `maxclaw index build` first")
+		}
+		results, note, err := index.Search(cmd.Context(), strings.Join(args, " "), rag.SearchOptions{
+			Limit: indexLimitFlag,
+			Path:  indexPathFlag,
+			Mode:  indexModeFlag,
+		})
+		if err != nil {
+			return err
+		}
+		printIndexResults(cmd.OutOrStdout(), results, note)
+		return nil
+	},
+}
+
+// openWorkspaceIndex 打开指定代理工作区的索引
+func openWorkspaceIndex(agentName string) (*rag.Index, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	resolved, ok := cfg.ResolveAgent(agentName)
+	if !ok {
+		return nil, fmt.Errorf("agent profile %q not found", agentName)
+	}
+	opts, err := agent.BuildRetrievalOptions(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tools.retrieval: %w", err)
+	}
+	return rag.Open(resolved.Workspace, opts)
+}
+
+func printIndexStatus(out io.Writer, st rag.Status) {
+	fmt.Fprintf(out, "Workspace: %s\n", st.Workspace)
+	fmt.Fprintf(out, "Index:     %s\n", st.Path)
+	model := st.Model
+	if model == "" {
+		model = "(none, keyword search only)"
+	}
+	fmt.Fprintf(out, "Model:     %s\n", model)
+	fmt.Fprintf(out, "Files:     %d\n", st.Files)
+	fmt.Fprintf(out, "Chunks:    %d (%d embedded)\n", st.Chunks, st.Embedded)
+	if st.UpdatedAt.IsZero() {
+		fmt.Fprintln(out, "Updated:   never (run `maxclaw index build`)")
+		return
+	}
+	fmt.Fprintf(out, "Updated:   %s\n", st.UpdatedAt.Format(time.RFC3339))
+}
+
+func printIndexResults(out io.Writer, results []rag.Result, note string) {
+	if note != "" {
+		fmt.Fprintf(out, "! %s\n", note)
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(out, "No matches.")
+		return
+	}
+	for i, r := range results {
+		location := fmt.Sprintf("lines %d-%d", r.StartLine, r.EndLine)
+		if r.Anchor != "" {
+			location = r.Anchor
+		}
+		fmt.Fprintf(out, "%d. %s (%s) score=%.4f\n", i+1, r.Path, location, r.Score)
+		snippet := strings.TrimSpace(r.Text)
+		if lines := strings.SplitN(snippet, "\n", 4); len(lines) > 3 {
+			snippet = strings.Join(lines[:3], "\n") + "\n..."
+		}
+		for _, line := range strings.Split(snippet, "\n") {
+			fmt.Fprintf(out, "   %s\n", line)
+		}
+	}
+}
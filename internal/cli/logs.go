@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsComponentsFlag []string
+	logsSessionFlag    string
+	logsChannelFlag    string
+	logsToolFlag       string
+	logsLevelFlag      string
+	logsRequestFlag    string
+	logsGrepFlag       string
+	logsSinceFlag      time.Duration
+	logsLinesFlag      int
+	logsFollowFlag     bool
+	logsJSONFlag       bool
+)
+
+func init() {
+	logsCmd.Flags().StringSliceVarP(&logsComponentsFlag, "component", "c", nil,
+		"Log files to read: "+strings.Join(logging.Components, ", ")+" (default all)")
+	logsCmd.Flags().StringVar(&logsSessionFlag, "session", "", "Only entries for this session key")
+	logsCmd.Flags().StringVar(&logsChannelFlag, "channel", "", "Only entries for this channel")
+	logsCmd.Flags().StringVar(&logsToolFlag, "tool", "", "Only entries for this tool")
+	logsCmd.Flags().StringVar(&logsLevelFlag, "level", "", "Minimum level: debug, info, warn, error")
+	logsCmd.Flags().StringVar(&logsRequestFlag, "request", "", "Only entries for this request ID")
+	logsCmd.Flags().StringVar(&logsGrepFlag, "grep", "", "Case-insensitive substring match on message and fields")
+	logsCmd.Flags().DurationVar(&logsSinceFlag, "since", 0, "Only entries newer than this duration (e.g. 30m)")
+	logsCmd.Flags().IntVarP(&logsLinesFlag, "lines", "n", 50, "Number of recent entries to show")
+	logsCmd.Flags().BoolVarP(&logsFollowFlag, "follow", "f", false, "Keep printing new entries")
+	logsCmd.Flags().BoolVar(&logsJSONFlag, "json", false, "Print entries as JSON lines")
+
+	rootCmd.AddCommand(logsCmd)
+}
+
+// logsCmd 查看与筛选结构化日志
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Tail and filter maxclaw logs",
+	Example: `  maxclaw logs --session telegram:123 -n 100
+  maxclaw logs -c tools --tool exec --level warn
+  maxclaw logs -f --channel discord`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := logsFilterFromFlags()
+		if err != nil {
+			return err
+		}
+
+		dir := config.GetLogsDir()
+		out := cmd.OutOrStdout()
+		entries, err := logging.ReadEntries(dir, filter, logsLinesFlag)
+		if err != nil {
+			return fmt.Errorf("failed to read logs: %w", err)
+		}
+		for _, entry := range entries {
+			printLogEntry(out, entry, logsJSONFlag)
+		}
+		if !logsFollowFlag {
+			return nil
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		return logging.Follow(ctx, dir, filter, func(entry logging.Entry) {
+			printLogEntry(out, entry, logsJSONFlag)
+		})
+	},
+}
+
+func logsFilterFromFlags() (logging.Filter, error) {
+	if logsLevelFlag != "" {
+		if _, err := logging.ParseLevel(logsLevelFlag); err != nil {
+			return logging.Filter{}, err
+		}
+	}
+	for _, component := range logsComponentsFlag {
+		if !logging.IsComponent(component) {
+			return logging.Filter{}, fmt.Errorf("unknown log component %q (available: %s)", component, strings.Join(logging.Components, ", "))
+		}
+	}
+
+	filter := logging.Filter{
+		Components: logsComponentsFlag,
+		Level:      logsLevelFlag,
+		Session:    logsSessionFlag,
+		Channel:    logsChannelFlag,
+		Tool:       logsToolFlag,
+		RequestID:  logsRequestFlag,
+		Contains:   logsGrepFlag,
+	}
+	if logsSinceFlag > 0 {
+		filter.Since = time.Now().Add(-logsSinceFlag)
+	}
+	return filter, nil
+}
+
+func printLogEntry(w io.Writer, entry logging.Entry, asJSON bool) {
+	if asJSON {
+		data, err := json.Marshal(entry)
+		if err == nil {
+			fmt.Fprintln(w, string(data))
+		}
+		return
+	}
+	fmt.Fprintln(w, formatLogEntry(entry))
+}
+
+// formatLogEntry 输出形如 "2026-03-08 10:21:38.999 INFO  [tools] tool executed session=… tool=exec"
+func formatLogEntry(entry logging.Entry) string {
+	var b strings.Builder
+	b.WriteString(entry.Time.Local().Format("2006-01-02 15:04:05.000"))
+	fmt.Fprintf(&b, " %-5s [%s] %s", entry.Level, entry.Component, entry.Message)
+
+	keys := make([]string, 0, len(entry.Attrs))
+	for key := range entry.Attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := entry.Attr(key)
+		if strings.ContainsAny(value, " \t\n\"") || value == "" {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&b, " %s=%s", key, value)
+	}
+	return b.String()
+}
+
+// initLogging 按 cfg.logging 初始化日志，失败时仅提示
+func initLogging(cfg *config.Config) {
+	opts := logging.Options{
+		Level:      cfg.Logging.Level,
+		MaxSizeMB:  cfg.Logging.MaxSizeMB,
+		MaxBackups: cfg.Logging.MaxBackups,
+		MaxAgeDays: cfg.Logging.MaxAgeDays,
+	}
+	if _, err := logging.InitWithOptions(config.GetDataDir(), opts); err != nil {
+		fmt.Printf("⚠ logging init error: %v\n", err)
+	}
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLogEntrySortsAndQuotesAttrs(t *testing.T) {
+	entry := logging.Entry{
+		Time:      time.Date(2026, 3, 8, 10, 21, 38, 999_000_000, time.Local),
+		Level:     "WARN",
+		Component: logging.ComponentTools,
+		Message:   "tool failed",
+		Attrs: map[string]any{
+			"tool":    "exec",
+			"err":     "exit status 1",
+			"turn":    float64(2),
+			"session": "telegram:42",
+		},
+	}
+	assert.Equal(t,
+		`2026-03-08 10:21:38.999 WARN  [tools] tool failed err="exit status 1" session=telegram:42 tool=exec turn=2`,
+		formatLogEntry(entry))
+}
+
+func TestLogsFilterFromFlagsRejectsUnknownValues(t *testing.T) {
+	defer func() { logsComponentsFlag, logsLevelFlag = nil, "" }()
+
+	logsComponentsFlag = []string{"tools", "nope"}
+	_, err := logsFilterFromFlags()
+	assert.Error(t, err)
+
+	logsComponentsFlag, logsLevelFlag = []string{"tools"}, "loud"
+	_, err = logsFilterFromFlags()
+	assert.Error(t, err)
+
+	logsLevelFlag = "warn"
+	filter, err := logsFilterFromFlags()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tools"}, filter.Components)
+	assert.Equal(t, "warn", filter.Level)
+}
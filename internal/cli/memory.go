@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/memory"
+	"github.com/spf13/cobra"
+)
+
+var (
+	memoryDryRunFlag    bool
+	memoryNoLLMFlag     bool
+	memoryForceLLMFlag  bool
+	memoryMaxTokensFlag int
+	memoryKeepDaysFlag  int
+	memoryJSONFlag      bool
+)
+
+func init() {
+	memoryCurateCmd.Flags().BoolVar(&memoryDryRunFlag, "dry-run", false, "Show the diff without writing any file")
+	memoryCurateCmd.Flags().BoolVar(&memoryNoLLMFlag, "no-llm", false, "Use deterministic rules only")
+	memoryCurateCmd.Flags().BoolVar(&memoryForceLLMFlag, "llm", false, "Let the model merge entries even when no duplicates were found")
+	memoryCurateCmd.Flags().IntVar(&memoryMaxTokensFlag, "max-tokens", 0, "Token budget for MEMORY.md (default memory.maxTokens or 4000)")
+	memoryCurateCmd.Flags().IntVar(&memoryKeepDaysFlag, "keep-days", 0, "Daily summaries kept in MEMORY.md (default memory.keepDailyDays or 7)")
+	memoryCurateCmd.Flags().BoolVar(&memoryJSONFlag, "json", false, "Print the report as JSON")
+	memoryCurateCmd.Flags().StringVarP(&memoryScopeFlag, "scope", "s", "", "Memory scope to curate (default global; see 'maxclaw memory scopes')")
+
+	memoryCmd.AddCommand(memoryCurateCmd)
+	rootCmd.AddCommand(memoryCmd)
+}
+
+// memoryCmd 长期记忆管理命令
+var memoryCmd = &cobra.Command{
+	Use:   "memory",
+	Short: "Manage long-term memory (memory/MEMORY.md)",
+}
+
+var memoryCurateCmd = &cobra.Command{
+	Use:   "curate",
+	Short: "Deduplicate, consolidate and cap MEMORY.md",
+	Long: `Curate memory/MEMORY.md:
+- Daily summaries older than the retention window move to HISTORY.md.
+- Duplicate feedback lessons and list items are merged.
+- The file is kept under the token budget.
+
+When a model is configured, it merges similar entries. Otherwise only
+deterministic rules are used. Every rewrite keeps a backup and a diff in
+memory/backups/.
+
+Use --scope to curate the MEMORY.md of an agent, user or chat scope.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		var condense memory.Condenser
+		if !memoryNoLLMFlag && (cfg.GetAPIKey("") != "" || len(cfg.Routing.Backends) > 0) {
+			condense, err = agent.NewMemoryCondenserFromConfig(cfg)
+			if err != nil {
+				return fmt.Errorf("failed to create provider: %w", err)
+			}
+		}
+		if memoryForceLLMFlag && condense == nil {
+			return fmt.Errorf("--llm needs a configured model (and no --no-llm)")
+		}
+
+		opts := memoryCurateOptions(cfg, condense)
+		if memoryNoLLMFlag {
+			opts.Condense = nil
+		}
+		if memoryForceLLMFlag {
+			opts.Condense = condense
+			opts.ForceLLM = true
+		}
+		if memoryMaxTokensFlag > 0 {
+			opts.MaxTokens = memoryMaxTokensFlag
+		}
+		if memoryKeepDaysFlag > 0 {
+			opts.KeepDailyDays = memoryKeepDaysFlag
+		}
+		opts.DryRun = memoryDryRunFlag
+		if opts.Scope, err = memory.ParseScope(memoryScopeFlag); err != nil {
+			return err
+		}
+
+		report, err := memory.Curate(context.Background(), cfg.Agents.Defaults.Workspace, opts)
+		if err != nil {
+			return err
+		}
+		return printCurationReport(cmd.OutOrStdout(), report, memoryDryRunFlag, memoryJSONFlag)
+	},
+}
+
+// memoryCurateOptions 由 memory 配置生成整理参数；memory.useLLM 关闭时不使用 condense
+func memoryCurateOptions(cfg *config.Config, condense memory.Condenser) memory.CurateOptions {
+	opts := memory.CurateOptions{
+		MaxTokens:     cfg.Memory.MaxTokens,
+		KeepDailyDays: cfg.Memory.KeepDailyDays,
+	}
+	if cfg.Memory.LLMEnabled() {
+		opts.Condense = condense
+	}
+	return opts
+}
+
+func printCurationReport(out io.Writer, report *memory.CurationReport, dryRun, asJSON bool) error {
+	if asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	}
+
+	if !report.Changed {
+		fmt.Fprintf(out, "MEMORY.md is already curated (~%d/%d tokens).\n", report.AfterTokens, report.MaxTokens)
+	} else {
+		fmt.Fprint(out, report.Diff)
+		if !strings.HasSuffix(report.Diff, "\n") {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out)
+		fmt.Fprintf(out, "Tokens: ~%d → ~%d (budget %d)\n", report.BeforeTokens, report.AfterTokens, report.MaxTokens)
+		if len(report.ArchivedDays) > 0 {
+			fmt.Fprintf(out, "Archived daily summaries to HISTORY.md: %s\n", strings.Join(report.ArchivedDays, ", "))
+		}
+		if report.ArchivedLessons > 0 {
+			fmt.Fprintf(out, "Archived feedback lessons to HISTORY.md: %d\n", report.ArchivedLessons)
+		}
+		fmt.Fprintf(out, "Merged lessons: %d, removed duplicate items: %d\n", report.MergedLessons, report.RemovedDuplicates)
+		if report.UsedLLM {
+			fmt.Fprintln(out, "Entries were merged by the model; review the diff above.")
+		}
+		if dryRun {
+			fmt.Fprintln(out, "Dry run: no files were written.")
+		} else {
+			fmt.Fprintf(out, "Backup: %s\nDiff:   %s\n", report.BackupPath, report.DiffPath)
+		}
+	}
+	if report.LLMError != "" {
+		fmt.Fprintf(out, "⚠ Model merge skipped: %s\n", report.LLMError)
+	}
+	if report.OverBudget {
+		fmt.Fprintf(out, "⚠ MEMORY.md is still over budget; shorten the hand-written sections.\n")
+	}
+	return nil
+}
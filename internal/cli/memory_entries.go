@@ -0,0 +1,359 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/memory"
+	"github.com/spf13/cobra"
+)
+
+var (
+	memoryTypeFlag       string
+	memoryAddTypeFlag    string
+	memoryTagsFlag       []string
+	memoryQueryFlag      string
+	memoryAllFlag        bool
+	memoryConfidenceFlag float64
+	memoryExpiresFlag    string
+	memoryContentFlag    string
+	memoryDoneFlag       bool
+	memoryScopeFlag      string
+)
+
+func init() {
+	memoryListCmd.Flags().StringVarP(&memoryTypeFlag, "type", "t", "", "Only show entries of this type: fact, preference, lesson, todo")
+	memoryListCmd.Flags().StringSliceVar(&memoryTagsFlag, "tag", nil, "Only show entries with this tag")
+	memoryListCmd.Flags().StringVarP(&memoryQueryFlag, "query", "q", "", "Only show entries containing this text")
+	memoryListCmd.Flags().BoolVar(&memoryAllFlag, "all", false, "Include expired entries")
+	memoryListCmd.Flags().BoolVar(&memoryJSONFlag, "json", false, "Print entries as JSON")
+	memoryListCmd.Flags().StringVarP(&memoryScopeFlag, "scope", "s", "", "Only list this scope (default: every scope)")
+
+	memoryAddCmd.Flags().StringVarP(&memoryAddTypeFlag, "type", "t", "fact", "Entry type: fact, preference, lesson, todo")
+	memoryAddCmd.Flags().StringSliceVar(&memoryTagsFlag, "tag", nil, "Tags (repeatable or comma-separated)")
+	memoryAddCmd.Flags().Float64Var(&memoryConfidenceFlag, "confidence", 1, "Confidence between 0 and 1")
+	memoryAddCmd.Flags().StringVar(&memoryExpiresFlag, "expires", "", "Expiry: 7d, 2w, 36h, YYYY-MM-DD or RFC3339")
+	memoryAddCmd.Flags().StringVarP(&memoryScopeFlag, "scope", "s", "", "Scope: global (default), agent:<name>, user:<identity> or chat:<channel>:<chatID>")
+
+	memoryRemoveCmd.Flags().StringVarP(&memoryScopeFlag, "scope", "s", "", "Scope of the entries (default: search every scope)")
+
+	memoryEditCmd.Flags().StringVarP(&memoryTypeFlag, "type", "t", "", "New entry type")
+	memoryEditCmd.Flags().StringVarP(&memoryContentFlag, "content", "c", "", "New content")
+	memoryEditCmd.Flags().StringSliceVar(&memoryTagsFlag, "tag", nil, "Replace tags (pass --tag= to clear)")
+	memoryEditCmd.Flags().Float64Var(&memoryConfidenceFlag, "confidence", 1, "New confidence between 0 and 1")
+	memoryEditCmd.Flags().StringVar(&memoryExpiresFlag, "expires", "", "New expiry, or never")
+	memoryEditCmd.Flags().BoolVar(&memoryDoneFlag, "done", false, "Mark a todo as done (--done=false to reopen)")
+	memoryEditCmd.Flags().StringVarP(&memoryScopeFlag, "scope", "s", "", "Scope of the entry (default: search every scope)")
+
+	memoryCmd.AddCommand(memoryListCmd)
+	memoryCmd.AddCommand(memoryAddCmd)
+	memoryCmd.AddCommand(memoryRemoveCmd)
+	memoryCmd.AddCommand(memoryEditCmd)
+	memoryCmd.AddCommand(memoryScopesCmd)
+}
+
+var memoryListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List structured memory entries",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace, scopes, err := loadMemoryScopes()
+		if err != nil {
+			return err
+		}
+		filter := memory.EntryFilter{Query: memoryQueryFlag, IncludeExpired: memoryAllFlag}
+		if memoryTypeFlag != "" {
+			if filter.Type, err = memory.ParseEntryType(memoryTypeFlag); err != nil {
+				return err
+			}
+		}
+		if len(memoryTagsFlag) > 0 {
+			filter.Tag = memoryTagsFlag[0]
+		}
+		entries, err := memory.ListScopedEntries(workspace, scopes, filter)
+		if err != nil {
+			return err
+		}
+		return printMemoryEntries(cmd.OutOrStdout(), entries, memoryJSONFlag)
+	},
+}
+
+var memoryAddCmd = &cobra.Command{
+	Use:   "add <content>",
+	Short: "Add a memory entry",
+	Example: `  maxclaw memory add "Deploys happen on Fridays" --tag ops
+  maxclaw memory add -t todo "Renew the TLS certificate" --expires 2026-12-01
+  maxclaw memory add -t preference "Answers in Chinese" --scope user:alice`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace, scopes, err := loadMemoryScopes()
+		if err != nil {
+			return err
+		}
+		store := memory.NewScopedEntryStore(workspace, scopes[0])
+		entry, err := buildMemoryEntry(strings.Join(args, " "), time.Now())
+		if err != nil {
+			return err
+		}
+		saved, created, err := store.Add(entry)
+		if err != nil {
+			return err
+		}
+		if created {
+			fmt.Fprintf(cmd.OutOrStdout(), "✓ Added %s %s to %s\n", saved.Type, saved.ID, store.Scope())
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "✓ Already remembered as %s %s in %s (tags merged)\n", saved.Type, saved.ID, store.Scope())
+		}
+		return nil
+	},
+}
+
+var memoryRemoveCmd = &cobra.Command{
+	Use:          "rm <id>...",
+	Aliases:      []string{"remove"},
+	Short:        "Remove memory entries",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace, scopes, err := loadMemoryScopes()
+		if err != nil {
+			return err
+		}
+		for _, id := range args {
+			store, err := memory.FindEntryStore(workspace, scopes, id)
+			if err != nil {
+				return err
+			}
+			entry, err := store.Remove(id)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "✓ Removed %s %s from %s: %s\n", entry.Type, entry.ID, store.Scope(), entry.Content)
+		}
+		return nil
+	},
+}
+
+var memoryEditCmd = &cobra.Command{
+	Use:   "edit <id>",
+	Short: "Edit a memory entry",
+	Long: `Edit a memory entry with flags. Without flags, the content opens in $EDITOR.
+
+MEMORY.md can also be edited directly: changes inside the entries block are
+picked up the next time memory entries are read or written.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace, scopes, err := loadMemoryScopes()
+		if err != nil {
+			return err
+		}
+		store, err := memory.FindEntryStore(workspace, scopes, args[0])
+		if err != nil {
+			return err
+		}
+		patch, changed, err := buildMemoryPatch(cmd, time.Now())
+		if err != nil {
+			return err
+		}
+		if !changed {
+			current, err := store.Get(args[0])
+			if err != nil {
+				return err
+			}
+			content, err := editInEditor(current.Content)
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(content) == current.Content {
+				fmt.Fprintln(cmd.OutOrStdout(), "No changes.")
+				return nil
+			}
+			patch.Content = &content
+		}
+		entry, err := store.Update(args[0], patch)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "✓ Updated %s\n", memory.FormatEntry(entry))
+		return nil
+	},
+}
+
+var memoryScopesCmd = &cobra.Command{
+	Use:   "scopes",
+	Short: "List memory scopes that have memory files",
+	Long: `List memory scopes. Each scope has its own MEMORY.md:
+- global: loaded in every conversation
+- agent:<name>: loaded only by that agent profile
+- user:<identity>: loaded only when talking to that user, on any channel
+  (map accounts to one identity with memory.identities)
+- chat:<channel>:<chatID>: loaded only in that chat`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		workspace := cfg.Agents.Defaults.Workspace
+		scopes, err := memory.ListScopes(workspace)
+		if err != nil {
+			return err
+		}
+		for _, scope := range scopes {
+			fmt.Fprintf(cmd.OutOrStdout(), "%-32s %s\n", scope, scope.MemoryPath(workspace))
+		}
+		return nil
+	},
+}
+
+// loadMemoryScopes 返回 workspace 与要操作的作用域：指定 --scope 时只有该作用域，
+// 否则为 workspace 中已有的全部作用域（全局在首位）
+func loadMemoryScopes() (string, []memory.Scope, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	workspace := cfg.Agents.Defaults.Workspace
+	if memoryScopeFlag != "" {
+		scope, err := memory.ParseScope(memoryScopeFlag)
+		if err != nil {
+			return "", nil, err
+		}
+		return workspace, []memory.Scope{scope}, nil
+	}
+	scopes, err := memory.ListScopes(workspace)
+	if err != nil {
+		return "", nil, err
+	}
+	return workspace, scopes, nil
+}
+
+// buildMemoryEntry 由 add 的参数构造条目，来源记为 cli
+func buildMemoryEntry(content string, now time.Time) (memory.Entry, error) {
+	typ, err := memory.ParseEntryType(memoryAddTypeFlag)
+	if err != nil {
+		return memory.Entry{}, err
+	}
+	entry := memory.Entry{
+		Type:       typ,
+		Content:    content,
+		Tags:       memoryTagsFlag,
+		Confidence: memoryConfidenceFlag,
+		Source:     memory.EntrySource{Origin: memory.SourceCLI},
+	}
+	if memoryExpiresFlag != "" {
+		expires, err := memory.ParseExpiry(memoryExpiresFlag, now)
+		if err != nil {
+			return memory.Entry{}, err
+		}
+		entry.ExpiresAt = memory.ExpiryPtr(expires)
+	}
+	return entry, nil
+}
+
+// buildMemoryPatch 只把显式传入的 flag 转为更新字段
+func buildMemoryPatch(cmd *cobra.Command, now time.Time) (memory.EntryPatch, bool, error) {
+	var patch memory.EntryPatch
+	flags := cmd.Flags()
+	if flags.Changed("type") {
+		typ, err := memory.ParseEntryType(memoryTypeFlag)
+		if err != nil {
+			return patch, false, err
+		}
+		patch.Type = &typ
+	}
+	if flags.Changed("content") {
+		patch.Content = &memoryContentFlag
+	}
+	if flags.Changed("tag") {
+		tags := append([]string(nil), memoryTagsFlag...)
+		patch.Tags = &tags
+	}
+	if flags.Changed("confidence") {
+		patch.Confidence = &memoryConfidenceFlag
+	}
+	if flags.Changed("expires") {
+		expires, err := memory.ParseExpiry(memoryExpiresFlag, now)
+		if err != nil {
+			return patch, false, err
+		}
+		patch.ExpiresAt = &expires
+	}
+	if flags.Changed("done") {
+		patch.Done = &memoryDoneFlag
+	}
+	changed := patch.Type != nil || patch.Content != nil || patch.Tags != nil ||
+		patch.Confidence != nil || patch.ExpiresAt != nil || patch.Done != nil
+	return patch, changed, nil
+}
+
+func printMemoryEntries(out io.Writer, entries []memory.ScopedEntry, asJSON bool) error {
+	if asJSON {
+		if entries == nil {
+			entries = []memory.ScopedEntry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "No memory entries.")
+		return nil
+	}
+	for i, e := range entries {
+		if i == 0 || entries[i-1].Scope != e.Scope {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			fmt.Fprintf(out, "[%s]\n", e.Scope)
+		}
+		fmt.Fprintln(out, memory.FormatEntry(e.Entry))
+	}
+	return nil
+}
+
+// editInEditor 在 $VISUAL / $EDITOR（默认 vi）中编辑文本
+func editInEditor(content string) (string, error) {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	dir, err := os.MkdirTemp("", "maxclaw-memory-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "entry.md")
+	if err := os.WriteFile(path, []byte(content+"\n"), 0600); err != nil {
+		return "", err
+	}
+
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
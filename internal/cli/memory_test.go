@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCurateOptionsFollowsConfig(t *testing.T) {
+	condense := func(ctx context.Context, content string, maxTokens int) (string, error) { return content, nil }
+
+	cfg := config.DefaultConfig()
+	cfg.Memory.MaxTokens = 2000
+	cfg.Memory.KeepDailyDays = 3
+	opts := memoryCurateOptions(cfg, condense)
+	assert.Equal(t, 2000, opts.MaxTokens)
+	assert.Equal(t, 3, opts.KeepDailyDays)
+	assert.NotNil(t, opts.Condense)
+
+	disabled := false
+	cfg.Memory.UseLLM = &disabled
+	assert.Nil(t, memoryCurateOptions(cfg, condense).Condense)
+}
+
+func TestPrintCurationReport(t *testing.T) {
+	report := &memory.CurationReport{
+		Changed:       true,
+		BeforeTokens:  5000,
+		AfterTokens:   3000,
+		MaxTokens:     4000,
+		ArchivedDays:  []string{"2026-10-01", "2026-10-02"},
+		MergedLessons: 2,
+		Diff:          "--- a/memory/MEMORY.md\n+++ b/memory/MEMORY.md\n",
+		BackupPath:    "/ws/memory/backups/MEMORY-1.md",
+		DiffPath:      "/ws/memory/backups/MEMORY-1.diff",
+		LLMError:      "timeout",
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, printCurationReport(&out, report, false, false))
+	text := out.String()
+	assert.Contains(t, text, "+++ b/memory/MEMORY.md")
+	assert.Contains(t, text, "Tokens: ~5000 → ~3000 (budget 4000)")
+	assert.Contains(t, text, "2026-10-01, 2026-10-02")
+	assert.Contains(t, text, "Backup: /ws/memory/backups/MEMORY-1.md")
+	assert.Contains(t, text, "Model merge skipped: timeout")
+
+	out.Reset()
+	require.NoError(t, printCurationReport(&out, report, true, false))
+	assert.Contains(t, out.String(), "Dry run: no files were written.")
+	assert.NotContains(t, out.String(), "Backup:")
+
+	out.Reset()
+	require.NoError(t, printCurationReport(&out, report, false, true))
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.EqualValues(t, 3000, decoded["afterTokens"])
+
+	out.Reset()
+	require.NoError(t, printCurationReport(&out, &memory.CurationReport{AfterTokens: 10, MaxTokens: 4000}, false, false))
+	assert.Equal(t, "MEMORY.md is already curated (~10/4000 tokens).\n", out.String())
+}
+
+func TestPrintMemoryEntriesGroupsByScope(t *testing.T) {
+	entries := []memory.ScopedEntry{
+		{Entry: memory.Entry{ID: "aaaa1111", Type: memory.EntryFact, Content: "Deploys happen on Fridays"}, Scope: memory.GlobalScope()},
+		{Entry: memory.Entry{ID: "bbbb2222", Type: memory.EntryPreference, Content: "Answers in Chinese"}, Scope: memory.UserScope("alice")},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, printMemoryEntries(&out, entries, false))
+	text := out.String()
+	assert.Contains(t, text, "[global]\n")
+	assert.Contains(t, text, "\n\n[user:alice]\n")
+	assert.Less(t, bytes.Index(out.Bytes(), []byte("Fridays")), bytes.Index(out.Bytes(), []byte("[user:alice]")))
+
+	out.Reset()
+	require.NoError(t, printMemoryEntries(&out, entries, true))
+	var decoded []map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Len(t, decoded, 2)
+	assert.Equal(t, "user:alice", decoded[1]["scope"])
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	modelsBaseFlag     string
+	modelsCatalogFlag  bool
+	modelsProviderFlag string
+)
+
+func init() {
+	modelsCmd.PersistentFlags().StringVar(&modelsBaseFlag, "base", "", "Ollama API base (default providers.ollama.apiBase or http://localhost:11434)")
+	modelsListCmd.Flags().BoolVar(&modelsCatalogFlag, "catalog", false, "List the model catalog (context window, capabilities, pricing) instead of local Ollama models")
+	modelsListCmd.Flags().StringVar(&modelsProviderFlag, "provider", "", "With --catalog, only show entries matching this provider (e.g. anthropic, ollama)")
+
+	modelsCmd.AddCommand(modelsListCmd)
+	modelsCmd.AddCommand(modelsPullCmd)
+	rootCmd.AddCommand(modelsCmd)
+}
+
+// modelsCmd 模型管理命令：本地 Ollama 模型与模型目录
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List local Ollama models, download models, and inspect the model catalog",
+}
+
+var modelsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed models with context length and capabilities",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if modelsCatalogFlag {
+			return printModelCatalog(cmd.OutOrStdout(), modelsProviderFlag)
+		}
+		client, err := ollamaClientFromConfig()
+		if err != nil {
+			return err
+		}
+		models, err := client.ListModels(cmd.Context())
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		if len(models) == 0 {
+			fmt.Fprintln(out, "No local models. Download one with `maxclaw models pull <name>`.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MODEL\tSIZE\tPARAMS\tQUANT\tCONTEXT\tCAPABILITIES")
+		for _, m := range models {
+			context, capabilities := "?", "?"
+			if info, err := client.ShowModel(cmd.Context(), m.Name); err == nil {
+				context = formatContextLength(info.ContextLength)
+				capabilities = strings.Join(info.Capabilities, ",")
+			}
+			fmt.Fprintf(w, "ollama/%s\t%s\t%s\t%s\t%s\t%s\n", m.Name, formatBytes(m.Size),
+				m.Details.ParameterSize, m.Details.QuantizationLevel, context, capabilities)
+		}
+		return w.Flush()
+	},
+}
+
+var modelsPullCmd = &cobra.Command{
+	Use:   "pull <name>",
+	Short: "Download a model from the Ollama library",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := ollamaClientFromConfig()
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(args[0], "ollama/")
+		out := cmd.OutOrStdout()
+		lastStatus := ""
+		err = client.PullModel(cmd.Context(), name, func(p providers.OllamaPullProgress) {
+			printPullProgress(out, p, &lastStatus)
+		})
+		if lastStatus != "" {
+			fmt.Fprintln(out)
+		}
+		if err != nil {
+			return err
+		}
+
+		info, err := client.ShowModel(cmd.Context(), name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "✓ Pulled ollama/%s (context %s, capabilities: %s)\n",
+			strings.TrimSuffix(info.Name, ":latest"), formatContextLength(info.ContextLength), strings.Join(info.Capabilities, ", "))
+		if !info.Has("tools") {
+			fmt.Fprintln(out, "! This model does not support tool calling; maxclaw tools will be unavailable with it")
+		}
+		return nil
+	},
+}
+
+// printModelCatalog 输出模型目录（内置 + 配置覆盖）
+func printModelCatalog(out io.Writer, provider string) error {
+	// 加载配置以应用 modelCatalog 与 providers.*.models 中的覆盖
+	if _, err := config.LoadConfig(); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	provider = strings.ToLower(strings.TrimSpace(provider))
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tCONTEXT\tMAX OUTPUT\tCAPABILITIES\tPRICE IN/OUT ($/1M)")
+	for _, m := range providers.CatalogModels() {
+		entryProvider := m.Provider
+		if entryProvider == "" {
+			entryProvider = providers.DetectProviderName(m.ID)
+		}
+		if provider != "" && !strings.EqualFold(entryProvider, provider) {
+			continue
+		}
+		id := m.ID
+		if m.Provider != "" {
+			id = m.Provider + "/" + id
+		}
+		capabilities := strings.Join(m.Capabilities(), ",")
+		if capabilities == "" {
+			capabilities = "-"
+		}
+		price := "-"
+		if m.Pricing != nil {
+			price = fmt.Sprintf("%g / %g", m.Pricing.Input, m.Pricing.Output)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", id, formatContextLength(m.ContextWindow),
+			formatContextLength(m.MaxOutput), capabilities, price)
+	}
+	return w.Flush()
+}
+
+// ollamaClientFromConfig --base 优先，其次 providers.ollama 配置
+func ollamaClientFromConfig() (*providers.OllamaClient, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	base := modelsBaseFlag
+	if base == "" {
+		base = cfg.Providers.Ollama.APIBase
+	}
+	return providers.NewOllamaClient(base, cfg.Providers.Ollama.APIKey), nil
+}
+
+// printPullProgress 同一阶段的进度在同一行刷新
+func printPullProgress(out io.Writer, p providers.OllamaPullProgress, lastStatus *string) {
+	if p.Total > 0 {
+		fmt.Fprintf(out, "\r%s %s / %s (%d%%)", p.Status, formatBytes(p.Completed), formatBytes(p.Total), p.Completed*100/p.Total)
+		*lastStatus = p.Status
+		return
+	}
+	if p.Status == *lastStatus {
+		return
+	}
+	if *lastStatus != "" {
+		fmt.Fprintln(out)
+	}
+	fmt.Fprint(out, p.Status)
+	*lastStatus = p.Status
+}
+
+func formatContextLength(n int) string {
+	if n <= 0 {
+		return "?"
+	}
+	switch {
+	case n%1000 == 0:
+		return fmt.Sprintf("%dK", n/1000)
+	case n%1024 == 0:
+		return fmt.Sprintf("%dK", n/1024)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
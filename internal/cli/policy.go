@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"github.com/spf13/cobra"
+)
+
+var (
+	policyArgsFlag    string
+	policyAgentFlag   string
+	policyChannelFlag string
+	policyChatFlag    string
+	policySenderFlag  string
+	policyJSONFlag    bool
+)
+
+func init() {
+	policyTestCmd.Flags().StringVar(&policyArgsFlag, "args", "{}", "Tool arguments as a JSON object")
+	policyTestCmd.Flags().StringVar(&policyAgentFlag, "agent", "", "Agent profile name (default agent when empty)")
+	policyTestCmd.Flags().StringVar(&policyChannelFlag, "channel", "cli", "Inbound channel")
+	policyTestCmd.Flags().StringVar(&policyChatFlag, "chat", "", "Inbound chat ID")
+	policyTestCmd.Flags().StringVar(&policySenderFlag, "sender", "", "Inbound sender ID")
+	policyTestCmd.Flags().BoolVar(&policyJSONFlag, "json", false, "Print the decision as JSON")
+
+	policyCmd.AddCommand(policyTestCmd)
+	rootCmd.AddCommand(policyCmd)
+}
+
+// policyCmd 工具策略命令
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Inspect the tool policy (tools.policy)",
+}
+
+var policyTestCmd = &cobra.Command{
+	Use:   "test <tool>",
+	Short: "Dry-run a tool call against tools.policy",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		var toolArgs map[string]interface{}
+		if err := json.Unmarshal([]byte(policyArgsFlag), &toolArgs); err != nil {
+			return fmt.Errorf("invalid --args JSON: %w", err)
+		}
+
+		decision, err := evaluatePolicyTest(cfg, tools.PolicyRequest{
+			Tool:    strings.TrimSpace(args[0]),
+			Agent:   policyAgentFlag,
+			Channel: policyChannelFlag,
+			ChatID:  policyChatFlag,
+			Sender:  policySenderFlag,
+			Args:    toolArgs,
+		})
+		if err != nil {
+			return err
+		}
+		return printPolicyDecision(cmd.OutOrStdout(), args[0], decision, policyJSONFlag)
+	},
+}
+
+// evaluatePolicyTest resolves the agent workspace and evaluates the request without executing the tool.
+func evaluatePolicyTest(cfg *config.Config, req tools.PolicyRequest) (tools.PolicyDecision, error) {
+	policy, err := agent.BuildToolPolicy(cfg)
+	if err != nil {
+		return tools.PolicyDecision{}, fmt.Errorf("invalid tools.policy: %w", err)
+	}
+
+	resolved, ok := cfg.ResolveAgent(req.Agent)
+	if !ok {
+		return tools.PolicyDecision{}, fmt.Errorf("agent profile %q not found", req.Agent)
+	}
+	req.Agent = resolved.Name
+	req.Workspace = resolved.Workspace
+
+	if len(resolved.Tools) > 0 && !tools.MatchesToolPatterns(resolved.Tools, req.Tool) {
+		return tools.PolicyDecision{
+			Allowed: false,
+			Rule:    "agent.tools",
+			Reason:  fmt.Sprintf("tool %s is not enabled for agent %s", req.Tool, resolved.Name),
+		}, nil
+	}
+	return policy.Evaluate(req), nil
+}
+
+func printPolicyDecision(out io.Writer, tool string, decision tools.PolicyDecision, asJSON bool) error {
+	if asJSON {
+		body, err := json.MarshalIndent(decision, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(body))
+		return nil
+	}
+
+	if decision.Allowed {
+		fmt.Fprintf(out, "✓ allow %s (rule: %s)\n", tool, decision.Rule)
+		return nil
+	}
+	fmt.Fprintf(out, "✗ deny %s (rule: %s)\n  reason: %s\n", tool, decision.Rule, decision.Reason)
+	return nil
+}
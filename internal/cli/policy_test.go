@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluatePolicyTest(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Agents.Profiles = map[string]config.AgentProfile{
+		"research": {Tools: []string{"web_*"}},
+	}
+	cfg.Tools.Policy = config.ToolPolicyConfig{
+		Rules: []config.ToolPolicyRuleConfig{
+			{Name: "no-curl", Effect: "deny", Tools: []string{"exec"}, Args: map[string]config.ToolArgRuleConfig{
+				"command": {Pattern: `\bcurl\b`},
+			}},
+		},
+	}
+
+	decision, err := evaluatePolicyTest(cfg, tools.PolicyRequest{Tool: "exec", Channel: "cli", Args: map[string]interface{}{"command": "curl x"}})
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "no-curl", decision.Rule)
+
+	decision, err = evaluatePolicyTest(cfg, tools.PolicyRequest{Tool: "exec", Agent: "research", Channel: "cli"})
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "agent.tools", decision.Rule)
+
+	decision, err = evaluatePolicyTest(cfg, tools.PolicyRequest{Tool: "web_search", Agent: "research", Channel: "cli"})
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	_, err = evaluatePolicyTest(cfg, tools.PolicyRequest{Tool: "exec", Agent: "missing"})
+	assert.Error(t, err)
+}
+
+func TestPrintPolicyDecision(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printPolicyDecision(&out, "exec", tools.PolicyDecision{Rule: "no-exec", Reason: "blocked"}, false))
+	assert.Contains(t, out.String(), "deny exec")
+	assert.Contains(t, out.String(), "blocked")
+
+	out.Reset()
+	require.NoError(t, printPolicyDecision(&out, "exec", tools.PolicyDecision{Allowed: true, Rule: "default"}, true))
+	assert.Contains(t, out.String(), `"allowed": true`)
+}
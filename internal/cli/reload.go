@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/channels"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/logging"
+	"github.com/Lichas/maxclaw/internal/media"
+	"github.com/Lichas/maxclaw/internal/webui"
+)
+
+// restartRequiredPrefixes 无法热加载、修改后需要重启 gateway 才生效的配置
+var restartRequiredPrefixes = []string{
+	"gateway",
+	"agents.defaults.workspace",
+	"agents.defaults.enableGlobalSkills",
+	"agents.defaults.globalSkillsPaths",
+	"agents.profiles",
+	"tools.exec",
+	"tools.restrictToWorkspace",
+	"tools.web.fetch",
+	"telemetry",
+	"logging.maxSizeMB",
+	"logging.maxBackups",
+	"logging.maxAgeDays",
+	"memory",
+	"heartbeat",
+}
+
+// configReloader 把 config.json 的外部改动应用到运行中的 gateway：
+// 运行时组件交给 webui.Server.ApplyConfig，频道只重建配置有变化的那几个
+type configReloader struct {
+	ctx          context.Context
+	web          *webui.Server
+	registry     *channels.Registry
+	messageBus   *bus.MessageBus
+	mediaManager *media.Manager
+}
+
+func (r *configReloader) apply(cfg *config.Config, changes config.Changes) {
+	applied := r.web.ApplyConfig(cfg, changes)
+
+	if changes.Touches("logging.level") {
+		if err := logging.SetLevel(cfg.Logging.Level); err == nil {
+			applied = append(applied, "logLevel")
+		}
+	}
+	for _, name := range gatewayChannelNames {
+		if changes.Touches("channels." + name) {
+			if r.restartChannel(cfg, name) {
+				applied = append(applied, "channels."+name)
+			}
+		}
+	}
+
+	var restartRequired []string
+	for _, path := range changes {
+		if config.Changes([]string{path}).Touches(restartRequiredPrefixes...) {
+			restartRequired = append(restartRequired, path)
+		}
+	}
+
+	fmt.Printf("✓ Config reloaded (%d changes): %s\n", len(changes), strings.Join(applied, ", "))
+	if len(restartRequired) > 0 {
+		fmt.Printf("⚠ Restart the gateway to apply: %s\n", strings.Join(restartRequired, ", "))
+	}
+	if lg := logging.Get(); lg != nil && lg.Gateway != nil {
+		lg.Gateway.Info("config reloaded", "changes", []string(changes), "applied", applied, "restart_required", restartRequired)
+	}
+}
+
+// restartChannel 停止旧实例并按新配置重建；频道被禁用时只停止
+func (r *configReloader) restartChannel(cfg *config.Config, name string) bool {
+	if old, ok := r.registry.Unregister(name); ok {
+		if err := old.Stop(); err != nil {
+			if lg := logging.Get(); lg != nil && lg.Channels != nil {
+				lg.Channels.Warn("stop channel failed", "channel", name, "err", err)
+			}
+		}
+	}
+
+	ch := newGatewayChannel(cfg, name, r.messageBus, r.mediaManager)
+	if ch == nil {
+		if lg := logging.Get(); lg != nil && lg.Channels != nil {
+			lg.Channels.Info("channel disabled by config reload", "channel", name)
+		}
+		return true
+	}
+	r.registry.Register(ch)
+	if err := ch.Start(r.ctx); err != nil {
+		fmt.Printf("⚠ Failed to restart %s channel: %v\n", name, err)
+		if lg := logging.Get(); lg != nil && lg.Channels != nil {
+			lg.Channels.Error("restart channel failed", "channel", name, "err", err)
+		}
+		return false
+	}
+	return true
+}
+
+func (r *configReloader) reportError(err error) {
+	fmt.Printf("⚠ %v\n", err)
+	if lg := logging.Get(); lg != nil && lg.Gateway != nil {
+		lg.Gateway.Error("config reload failed", "err", err)
+	}
+}
@@ -0,0 +1,333 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/peterh/liner"
+)
+
+const (
+	replPrompt         = "You: "
+	replContinuePrompt = "...  "
+	replBlockDelimiter = `"""`
+)
+
+// repl 交互式会话：普通输入发送给 agent，以 / 开头的输入作为本地命令处理
+type repl struct {
+	loop       *agent.AgentLoop
+	cfg        *config.Config
+	out        io.Writer
+	channel    string
+	markdown   bool
+	animate    bool
+	sessionKey string
+
+	// newProvider 为 /model 创建 provider，测试中可替换
+	newProvider func(model string) (providers.LLMProvider, error)
+
+	// 仅作用于接下来发送的消息
+	skills      []string
+	media       *bus.MediaAttachment
+	attachments []string
+
+	usage map[string]map[string]*replUsage
+
+	mu      sync.Mutex
+	running bool
+}
+
+func newREPL(loop *agent.AgentLoop, cfg *config.Config, out io.Writer, sessionKey string, renderMarkdown bool) *repl {
+	return &repl{
+		loop:       loop,
+		cfg:        cfg,
+		out:        out,
+		channel:    resolveCLIChannel(renderMarkdown),
+		markdown:   renderMarkdown,
+		sessionKey: sessionKey,
+		newProvider: func(model string) (providers.LLMProvider, error) {
+			return agent.NewProviderFromConfig(cfg, model, cfg.Agents.Defaults.MaxTokens, cfg.Agents.Defaults.Temperature)
+		},
+		usage: make(map[string]map[string]*replUsage),
+	}
+}
+
+// runInteractiveAgent 运行交互模式，直到用户退出或收到 SIGTERM
+func runInteractiveAgent(agentLoop *agent.AgentLoop, cfg *config.Config, sessionKey string, renderMarkdown bool) error {
+	r := newREPL(agentLoop, cfg, os.Stdout, sessionKey, renderMarkdown)
+	r.animate = isTerminal(os.Stdout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Ctrl+C 只打断正在执行的请求，不退出进程
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		for sig := range sigChan {
+			if sig == syscall.SIGTERM {
+				cancel()
+				return
+			}
+			r.interrupt()
+		}
+	}()
+
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetTabCompletionStyle(liner.TabPrints)
+	line.SetWordCompleter(r.complete)
+
+	historyPath := filepath.Join(config.GetDataDir(), ".agent_history")
+	if f, err := os.Open(historyPath); err == nil {
+		_, _ = line.ReadHistory(f)
+		_ = f.Close()
+	}
+	defer func() {
+		_ = os.MkdirAll(filepath.Dir(historyPath), 0755)
+		if f, err := os.Create(historyPath); err == nil {
+			_, _ = line.WriteHistory(f)
+			_ = f.Close()
+		}
+	}()
+
+	fmt.Fprintf(r.out, "%s Interactive mode (session %s)\n", logo, r.sessionKey)
+	fmt.Fprintln(r.out, "Type /help for commands. End a line with \\ or wrap text in \"\"\" for multi-line input.")
+	fmt.Fprintln(r.out, "Ctrl+C interrupts the current reply; Ctrl+D or /exit quits.")
+	fmt.Fprintln(r.out)
+
+	for {
+		if ctx.Err() != nil {
+			fmt.Fprintln(r.out, "\nGoodbye!")
+			return nil
+		}
+
+		input, err := readREPLInput(line.Prompt)
+		if err != nil {
+			if errors.Is(err, liner.ErrPromptAborted) {
+				fmt.Fprintln(r.out, "(input discarded — Ctrl+D or /exit to quit)")
+				continue
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, os.ErrClosed) || errors.Is(err, context.Canceled) {
+				fmt.Fprintln(r.out, "\nGoodbye!")
+				return nil
+			}
+			return err
+		}
+		if input == "" {
+			continue
+		}
+		if !strings.Contains(input, "\n") {
+			line.AppendHistory(input)
+		}
+		if isExitCommand(input) {
+			fmt.Fprintln(r.out, "Goodbye!")
+			return nil
+		}
+		if name, args, ok := parseREPLCommand(input); ok {
+			if r.runCommand(ctx, name, args) {
+				fmt.Fprintln(r.out, "Goodbye!")
+				return nil
+			}
+			continue
+		}
+		r.send(ctx, input)
+	}
+}
+
+// readREPLInput 读取一条输入：行尾的 \ 表示续行，单独一行 """ 开始和结束多行块
+func readREPLInput(prompt func(string) (string, error)) (string, error) {
+	first, err := prompt(replPrompt)
+	if err != nil {
+		return "", err
+	}
+	first = strings.TrimRight(first, "\r\n")
+
+	if strings.TrimSpace(first) == replBlockDelimiter {
+		var lines []string
+		for {
+			next, err := prompt(replContinuePrompt)
+			if err != nil {
+				return "", err
+			}
+			next = strings.TrimRight(next, "\r\n")
+			if strings.TrimSpace(next) == replBlockDelimiter {
+				break
+			}
+			lines = append(lines, next)
+		}
+		return strings.TrimSpace(strings.Join(lines, "\n")), nil
+	}
+
+	var lines []string
+	for strings.HasSuffix(first, `\`) {
+		lines = append(lines, strings.TrimSuffix(first, `\`))
+		next, err := prompt(replContinuePrompt)
+		if err != nil {
+			return "", err
+		}
+		first = strings.TrimRight(next, "\r\n")
+	}
+	lines = append(lines, first)
+	return normalizeInteractiveInput(strings.Join(lines, "\n")), nil
+}
+
+// send 把输入（连同待发送的附件与技能）交给 agent，并渲染事件流
+func (r *repl) send(ctx context.Context, input string) {
+	content := input
+	for _, path := range r.attachments {
+		block, err := attachmentTextBlock(path)
+		if err != nil {
+			fmt.Fprintf(r.out, "Error: %v\n", err)
+			return
+		}
+		content += "\n\n" + block
+	}
+	media := r.media
+	r.media, r.attachments = nil, nil
+
+	renderer := newStreamRenderer(r.out, r.markdown, r.animate, r.recordUsage)
+	renderer.begin()
+	r.setRunning(true)
+	response, err := r.loop.ProcessDirectEventStreamWithMediaAndSkills(ctx, content, r.sessionKey, r.channel, "direct", r.skills, media, renderer.handle)
+	r.setRunning(false)
+	if err != nil {
+		renderer.finish("")
+		if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+			fmt.Fprintln(r.out, "⏹ Interrupted")
+			return
+		}
+		fmt.Fprintf(r.out, "Error: %v\n", err)
+		return
+	}
+	renderer.finish(response)
+}
+
+func (r *repl) setRunning(running bool) {
+	r.mu.Lock()
+	r.running = running
+	r.mu.Unlock()
+}
+
+// interrupt 以 InterruptCancel 打断正在执行的请求；空闲时不做任何事
+func (r *repl) interrupt() bool {
+	r.mu.Lock()
+	running := r.running
+	r.mu.Unlock()
+	if !running {
+		return false
+	}
+	msg := bus.NewInboundMessage(r.channel, "user", "direct", "/stop")
+	msg.SessionKey = r.sessionKey
+	return r.loop.HandleInterruption(msg, agent.InterruptCancel) == agent.InterruptCancel
+}
+
+// parseREPLCommand 识别 /command 输入；/etc/hosts 这类路径不算命令
+func parseREPLCommand(input string) (string, []string, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") || strings.Contains(fields[0][1:], "/") || len(fields[0]) < 2 {
+		return "", nil, false
+	}
+	return strings.ToLower(fields[0]), fields[1:], true
+}
+
+// complete 为命令名、命令参数、@skill: 选择器与 /attach 路径提供 Tab 补全
+func (r *repl) complete(line string, pos int) (string, []string, string) {
+	runes := []rune(line)
+	head, tail := string(runes[:pos]), string(runes[pos:])
+	start := strings.LastIndexAny(head, " \t") + 1
+	word := head[start:]
+	prefix := head[:start]
+
+	var candidates []string
+	fields := strings.Fields(prefix)
+	switch {
+	case strings.HasPrefix(word, "@") && (strings.HasPrefix(strings.ToLower(word), "@skill:") || strings.HasPrefix("@skill:", strings.ToLower(word))):
+		for _, name := range r.skillNames() {
+			candidates = append(candidates, "@skill:"+name)
+		}
+	case len(fields) == 0:
+		if !strings.HasPrefix(word, "/") {
+			return prefix, nil, tail
+		}
+		for _, cmd := range replCommands {
+			candidates = append(candidates, cmd.name)
+		}
+	default:
+		candidates = r.argumentCandidates(strings.ToLower(fields[0]), fields[1:], word)
+	}
+
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(strings.ToLower(c), strings.ToLower(word)) {
+			matches = append(matches, c)
+		}
+	}
+	sort.Strings(matches)
+	if len(matches) == 1 && !strings.HasSuffix(matches[0], string(filepath.Separator)) {
+		matches[0] += " "
+	}
+	return prefix, matches, tail
+}
+
+func (r *repl) argumentCandidates(cmd string, args []string, word string) []string {
+	switch cmd {
+	case "/mode":
+		if len(args) == 0 {
+			return []string{config.ExecutionModeSafe, config.ExecutionModeAsk, config.ExecutionModeAuto}
+		}
+	case "/model":
+		if len(args) == 0 {
+			var names []string
+			for _, m := range providers.CatalogModels() {
+				names = append(names, m.ID)
+			}
+			return names
+		}
+	case "/session":
+		if len(args) == 0 {
+			return []string{"list", "new", "switch"}
+		}
+		if len(args) == 1 && args[0] == "switch" {
+			summaries, _ := r.loop.ListSessions()
+			var keys []string
+			for _, s := range summaries {
+				keys = append(keys, s.Key)
+			}
+			return keys
+		}
+	case "/skills":
+		return append(r.skillNames(), "off")
+	case "/attach":
+		if len(args) == 0 {
+			return completePath(word)
+		}
+	}
+	return nil
+}
+
+// completePath 列出以 word 开头的文件路径，目录带结尾分隔符
+func completePath(word string) []string {
+	matches, _ := filepath.Glob(word + "*")
+	for i, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			matches[i] = m + string(filepath.Separator)
+		}
+	}
+	return matches
+}
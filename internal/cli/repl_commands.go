@@ -0,0 +1,461 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/media"
+	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/Lichas/maxclaw/internal/skills"
+)
+
+// replAttachmentMaxChars /attach 文本文件附入消息的最大字符数
+const replAttachmentMaxChars = 20000
+
+type replCommand struct {
+	name  string
+	args  string
+	usage string
+}
+
+var replCommands = []replCommand{
+	{"/help", "", "Show this help"},
+	{"/model", "[name]", "Show or switch the model for this REPL"},
+	{"/mode", "[safe|ask|auto]", "Show or switch the execution mode for this REPL"},
+	{"/session", "[list|new [name]|switch <key>]", "List, start or switch conversations"},
+	{"/new", "", "Archive and clear the current session"},
+	{"/skills", "[name...|off]", "List skills, or pin skills for the next messages"},
+	{"/plan", "", "Show the task plan of the current session"},
+	{"/compress", "", "Summarize older turns to free up context"},
+	{"/usage", "", "Show token usage and estimated cost"},
+	{"/undo", "", "Drop the last turn from the session (file changes are kept)"},
+	{"/attach", "<file>|clear", "Attach a file to the next message"},
+	{"/tools", "", "List the tools the agent can use"},
+	{"/exit", "", "Quit"},
+}
+
+// replUsage 单个模型在本次 REPL 中累计的 token 用量
+type replUsage struct {
+	calls      int
+	prompt     int
+	completion int
+	cached     int
+}
+
+// runCommand 执行一条 / 命令，返回 true 表示退出 REPL
+func (r *repl) runCommand(ctx context.Context, name string, args []string) bool {
+	var err error
+	switch name {
+	case "/help":
+		r.printHelp()
+	case "/exit", "/quit":
+		return true
+	case "/model":
+		err = r.cmdModel(args)
+	case "/mode":
+		err = r.cmdMode(args)
+	case "/session":
+		err = r.cmdSession(args)
+	case "/new":
+		r.send(ctx, "/new")
+	case "/skills":
+		err = r.cmdSkills(args)
+	case "/plan":
+		err = r.cmdPlan()
+	case "/compress":
+		err = r.cmdCompress(ctx)
+	case "/usage":
+		r.cmdUsage()
+	case "/undo":
+		err = r.cmdUndo()
+	case "/attach":
+		err = r.cmdAttach(args)
+	case "/tools":
+		r.cmdTools()
+	default:
+		err = fmt.Errorf("unknown command %s (type /help for the list)", name)
+	}
+	if err != nil {
+		fmt.Fprintf(r.out, "Error: %v\n", err)
+	}
+	return false
+}
+
+func (r *repl) printHelp() {
+	fmt.Fprintln(r.out, "Commands:")
+	for _, cmd := range replCommands {
+		fmt.Fprintf(r.out, "  %-44s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.usage)
+	}
+	fmt.Fprintln(r.out, "\nUse @skill:<name> in a message to load a skill for that message only.")
+}
+
+func (r *repl) cmdModel(args []string) error {
+	if len(args) == 0 {
+		model := r.loop.RuntimeModel()
+		fmt.Fprintf(r.out, "Model: %s\n", model)
+		if info, ok := providers.LookupModel(providers.DetectProviderName(model), model); ok {
+			if info.ContextWindow > 0 {
+				fmt.Fprintf(r.out, "Context window: %d tokens\n", info.ContextWindow)
+			}
+			if caps := info.Capabilities(); len(caps) > 0 {
+				fmt.Fprintf(r.out, "Capabilities: %s\n", strings.Join(caps, ", "))
+			}
+		}
+		return nil
+	}
+
+	model := args[0]
+	provider, err := r.newProvider(model)
+	if err != nil {
+		return fmt.Errorf("switch model: %w", err)
+	}
+	r.loop.UpdateRuntimeModel(provider, model)
+	fmt.Fprintf(r.out, "✓ Model set to %s for this REPL\n", model)
+	return nil
+}
+
+func (r *repl) cmdMode(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(r.out, "Execution mode: %s\n", r.loop.ExecutionMode())
+		return nil
+	}
+	mode := strings.ToLower(args[0])
+	switch mode {
+	case config.ExecutionModeSafe, config.ExecutionModeAsk, config.ExecutionModeAuto:
+	default:
+		return fmt.Errorf("unknown mode %q (use safe, ask or auto)", args[0])
+	}
+	r.loop.UpdateRuntimeExecutionMode(mode)
+	fmt.Fprintf(r.out, "✓ Execution mode set to %s for this REPL\n", mode)
+	return nil
+}
+
+func (r *repl) cmdSession(args []string) error {
+	sub := "list"
+	if len(args) > 0 {
+		sub = strings.ToLower(args[0])
+	}
+	switch sub {
+	case "list":
+		summaries, err := r.loop.ListSessions()
+		if err != nil {
+			return err
+		}
+		if len(summaries) == 0 {
+			fmt.Fprintln(r.out, "No saved sessions.")
+			return nil
+		}
+		for _, s := range summaries {
+			marker := " "
+			if s.Key == r.sessionKey {
+				marker = "*"
+			}
+			updated := ""
+			if !s.UpdatedAt.IsZero() {
+				updated = s.UpdatedAt.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(r.out, "%s %-28s %3d msgs  %s  %s\n", marker, s.Key, s.MessageCount, updated, s.Title)
+		}
+		return nil
+	case "new":
+		name := time.Now().Format("20060102-150405")
+		if len(args) > 1 {
+			name = strings.Join(args[1:], "-")
+		}
+		r.switchSession("cli:" + name)
+		return nil
+	case "switch":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: /session switch <key>")
+		}
+		key, err := r.resolveSessionKey(args[1])
+		if err != nil {
+			return err
+		}
+		r.switchSession(key)
+		return nil
+	default:
+		return fmt.Errorf("unknown subcommand %q (use list, new or switch)", args[0])
+	}
+}
+
+// resolveSessionKey 接受完整的 session key，或省略 cli: 前缀的名称
+func (r *repl) resolveSessionKey(ref string) (string, error) {
+	summaries, err := r.loop.ListSessions()
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range []string{ref, "cli:" + ref} {
+		for _, s := range summaries {
+			if s.Key == candidate {
+				return s.Key, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("session %q not found (see /session list)", ref)
+}
+
+func (r *repl) switchSession(key string) {
+	r.sessionKey = key
+	history := r.loop.SessionHistory(key)
+	fmt.Fprintf(r.out, "✓ Switched to session %s (%d messages)\n", key, len(history))
+	if len(history) > 0 {
+		last := history[len(history)-1]
+		fmt.Fprintf(r.out, "  last %s: %s\n", last.Role, truncateREPLText(last.Content, 120))
+	}
+}
+
+// skillEntries 返回当前可用（未被禁用）的技能
+func (r *repl) skillEntries() []skills.Entry {
+	workspace := r.cfg.Agents.Defaults.Workspace
+	entries, err := skills.DiscoverAll(filepath.Join(workspace, "skills"), r.cfg.Agents.Defaults.EnableGlobalSkills)
+	if err != nil {
+		return nil
+	}
+	return skills.NewStateManager(filepath.Join(workspace, ".skills_state.json")).FilterEnabled(entries)
+}
+
+func (r *repl) skillNames() []string {
+	var names []string
+	for _, entry := range r.skillEntries() {
+		names = append(names, entry.Name)
+	}
+	return names
+}
+
+func (r *repl) cmdSkills(args []string) error {
+	entries := r.skillEntries()
+	if len(args) == 0 {
+		if len(entries) == 0 {
+			fmt.Fprintln(r.out, "No skills found.")
+			return nil
+		}
+		pinned := make(map[string]bool)
+		for _, name := range r.skills {
+			pinned[name] = true
+		}
+		for _, entry := range entries {
+			marker := " "
+			if pinned[entry.Name] {
+				marker = "*"
+			}
+			fmt.Fprintf(r.out, "%s %-24s %s\n", marker, entry.Name, truncateREPLText(entry.Description, 80))
+		}
+		return nil
+	}
+
+	if len(args) == 1 && strings.EqualFold(args[0], "off") {
+		r.skills = nil
+		fmt.Fprintln(r.out, "✓ Skills unpinned")
+		return nil
+	}
+	var selected []string
+	for _, ref := range args {
+		entry := resolveSkill(entries, ref)
+		if entry == nil {
+			return fmt.Errorf("skill not found: %s", ref)
+		}
+		selected = append(selected, entry.Name)
+	}
+	r.skills = selected
+	fmt.Fprintf(r.out, "✓ Skills pinned for the next messages: %s\n", strings.Join(selected, ", "))
+	return nil
+}
+
+func (r *repl) cmdPlan() error {
+	plan, err := r.loop.PlanManager.Load(r.sessionKey)
+	if err != nil {
+		return err
+	}
+	if plan == nil {
+		fmt.Fprintln(r.out, "No plan for this session.")
+		return nil
+	}
+	fmt.Fprintf(r.out, "Status: %s\n%s", plan.Status, plan.GenerateProgressSummary())
+	return nil
+}
+
+func (r *repl) cmdCompress(ctx context.Context) error {
+	before, after, err := r.loop.CompressSession(ctx, r.sessionKey)
+	if err != nil {
+		return err
+	}
+	if after >= before {
+		fmt.Fprintf(r.out, "Nothing to compress (%d messages).\n", before)
+		return nil
+	}
+	fmt.Fprintf(r.out, "✓ Compressed session from %d to %d messages (full history archived to memory)\n", before, after)
+	return nil
+}
+
+func (r *repl) recordUsage(event agent.StreamEvent) {
+	models := r.usage[r.sessionKey]
+	if models == nil {
+		models = make(map[string]*replUsage)
+		r.usage[r.sessionKey] = models
+	}
+	u := models[event.Model]
+	if u == nil {
+		u = &replUsage{}
+		models[event.Model] = u
+	}
+	u.calls++
+	u.prompt += event.PromptTokens
+	u.completion += event.CompletionTokens
+	u.cached += event.CachedTokens
+}
+
+func (r *repl) cmdUsage() {
+	if len(r.usage) == 0 {
+		fmt.Fprintln(r.out, "No usage reported yet.")
+		return
+	}
+	var totalTokens int
+	var totalCost float64
+	costKnown := true
+	for _, key := range sortedKeys(r.usage) {
+		marker := " "
+		if key == r.sessionKey {
+			marker = "*"
+		}
+		fmt.Fprintf(r.out, "%s %s\n", marker, key)
+		for _, model := range sortedKeys(r.usage[key]) {
+			u := r.usage[key][model]
+			totalTokens += u.prompt + u.completion
+			cost := "cost unknown"
+			info, _ := providers.LookupModel(providers.DetectProviderName(model), model)
+			// 缓存命中的 token 包含在 prompt 中，按缓存读取价格单独计费
+			if c, ok := info.Cost(u.prompt-u.cached, u.completion, u.cached, 0); ok {
+				totalCost += c
+				cost = fmt.Sprintf("~$%.4f", c)
+			} else {
+				costKnown = false
+			}
+			fmt.Fprintf(r.out, "    %s: %d calls, %d prompt (%d cached) + %d completion tokens, %s\n",
+				model, u.calls, u.prompt, u.cached, u.completion, cost)
+		}
+	}
+	total := fmt.Sprintf("~$%.4f", totalCost)
+	if !costKnown {
+		total += " (some models have no pricing data)"
+	}
+	fmt.Fprintf(r.out, "Total: %d tokens, %s\n", totalTokens, total)
+}
+
+func (r *repl) cmdUndo() error {
+	removed, err := r.loop.UndoLastTurn(r.sessionKey)
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		fmt.Fprintln(r.out, "Nothing to undo.")
+		return nil
+	}
+	fmt.Fprintf(r.out, "✓ Removed the last turn (%d messages): %s\n", len(removed), truncateREPLText(removed[0].Content, 80))
+	return nil
+}
+
+func (r *repl) cmdAttach(args []string) error {
+	if len(args) == 0 {
+		if r.media == nil && len(r.attachments) == 0 {
+			fmt.Fprintln(r.out, "No attachments. Usage: /attach <file>")
+			return nil
+		}
+		if r.media != nil {
+			fmt.Fprintf(r.out, "  %s (%s)\n", r.media.LocalPath, r.media.Type)
+		}
+		for _, path := range r.attachments {
+			fmt.Fprintf(r.out, "  %s (text)\n", path)
+		}
+		return nil
+	}
+	if len(args) == 1 && strings.EqualFold(args[0], "clear") {
+		r.media, r.attachments = nil, nil
+		fmt.Fprintln(r.out, "✓ Attachments cleared")
+		return nil
+	}
+
+	path := expandCLIPath(strings.Join(args, " "))
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+
+	// 图片与文档作为媒体附件发送（每条消息一个），其他文本文件附在消息正文后
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	attachment := &bus.MediaAttachment{Filename: filepath.Base(path), LocalPath: path, MimeType: mimeType}
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		attachment.Type = "image"
+	case media.DetectDocumentFormat(path, mimeType) != "":
+		attachment.Type = "document"
+	default:
+		if _, err := attachmentTextBlock(path); err != nil {
+			return err
+		}
+		r.attachments = append(r.attachments, path)
+		fmt.Fprintf(r.out, "✓ Attached %s to the next message\n", path)
+		return nil
+	}
+	if r.media != nil {
+		fmt.Fprintf(r.out, "Replacing %s: one image or document per message\n", r.media.LocalPath)
+	}
+	r.media = attachment
+	fmt.Fprintf(r.out, "✓ Attached %s (%s) to the next message\n", path, attachment.Type)
+	return nil
+}
+
+// attachmentTextBlock 读取文本附件并格式化为消息片段，超长部分截断
+func attachmentTextBlock(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("%s is not a text, image or document file", path)
+	}
+	text := string(data)
+	if utf8.RuneCountInString(text) > replAttachmentMaxChars {
+		text = string([]rune(text)[:replAttachmentMaxChars]) + "\n... (truncated, use read_file for the rest)"
+	}
+	return fmt.Sprintf("[Attached file: %s]\n```\n%s\n```", path, strings.TrimRight(text, "\n")), nil
+}
+
+func (r *repl) cmdTools() {
+	names := r.loop.ListToolNames()
+	sort.Strings(names)
+	fmt.Fprintf(r.out, "%d tools:\n", len(names))
+	for _, name := range names {
+		fmt.Fprintf(r.out, "  %s\n", name)
+	}
+}
+
+func truncateREPLText(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return string([]rune(text)[:max]) + "..."
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
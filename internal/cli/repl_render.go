@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// spinner 在当前行循环绘制进度动画，stop 会清掉该行
+type spinner struct {
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+func startSpinner(out io.Writer, label string) *spinner {
+	s := &spinner{stopCh: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			fmt.Fprintf(out, "\r\033[K  %s %s", spinnerFrames[i%len(spinnerFrames)], label)
+			select {
+			case <-s.stopCh:
+				fmt.Fprint(out, "\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return s
+}
+
+func (s *spinner) stop() {
+	close(s.stopCh)
+	<-s.done
+}
+
+// isTerminal 判断输出是否为终端，非终端（管道、文件）时不绘制动画
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// streamRenderer 把 agent 事件流渲染到终端：正文 token 实时输出，工具调用显示进度与结果摘要
+type streamRenderer struct {
+	out      io.Writer
+	markdown bool // 关闭时正文不流式输出，结束后统一去掉 Markdown 打印
+	animate  bool
+	onUsage  func(agent.StreamEvent)
+
+	spin     *spinner
+	lineOpen bool
+	// segment 最近一次工具调用之后流式输出的正文，用于判断最终回复是否已显示
+	segment strings.Builder
+}
+
+func newStreamRenderer(out io.Writer, markdown, animate bool, onUsage func(agent.StreamEvent)) *streamRenderer {
+	return &streamRenderer{out: out, markdown: markdown, animate: animate, onUsage: onUsage}
+}
+
+// begin 在等待首个 token 期间显示 thinking 动画
+func (r *streamRenderer) begin() {
+	r.startProgress("thinking...", false)
+}
+
+func (r *streamRenderer) handle(event agent.StreamEvent) {
+	switch event.Type {
+	case "content_delta":
+		if !r.markdown || event.Delta == "" {
+			return
+		}
+		r.stopProgress()
+		if r.segment.Len() == 0 {
+			fmt.Fprintf(r.out, "\n%s ", logo)
+		}
+		fmt.Fprint(r.out, event.Delta)
+		r.segment.WriteString(event.Delta)
+		r.lineOpen = !strings.HasSuffix(event.Delta, "\n")
+	case "tool_start":
+		r.stopProgress()
+		r.endLine()
+		r.segment.Reset()
+		r.startProgress(event.Summary, true)
+	case "tool_result":
+		r.stopProgress()
+		mark := "✓"
+		if event.IsError {
+			mark = "✗"
+		}
+		fmt.Fprintf(r.out, "  %s %s\n", mark, event.Summary)
+		r.startProgress("thinking...", false)
+	case "file_diff":
+		r.stopProgress()
+		r.endLine()
+		fmt.Fprintf(r.out, "  ~ %s\n", event.FilePath)
+	case "skill_start":
+		r.stopProgress()
+		r.endLine()
+		fmt.Fprintf(r.out, "  • %s\n", event.Summary)
+		r.startProgress("thinking...", false)
+	case "error":
+		r.stopProgress()
+		r.endLine()
+		fmt.Fprintf(r.out, "  ✗ %s\n", event.Message)
+	case "usage":
+		if r.onUsage != nil {
+			r.onUsage(event)
+		}
+	}
+}
+
+// finish 收尾：未流式显示过的最终回复（纯文本模式、命令回复、迭代上限提示等）在这里打印
+func (r *streamRenderer) finish(response string) {
+	r.stopProgress()
+	r.endLine()
+	response = strings.TrimSpace(response)
+	if response != "" && (!r.markdown || strings.TrimSpace(r.segment.String()) != response) {
+		fmt.Fprintf(r.out, "\n%s %s\n", logo, formatAgentResponse(response, r.markdown))
+	}
+	fmt.Fprintln(r.out)
+}
+
+// startProgress 显示进度：终端中为动画，否则只为工具调用打印一行
+func (r *streamRenderer) startProgress(label string, tool bool) {
+	if r.animate {
+		r.spin = startSpinner(r.out, label)
+		return
+	}
+	if tool {
+		fmt.Fprintf(r.out, "  → %s\n", label)
+	}
+}
+
+func (r *streamRenderer) stopProgress() {
+	if r.spin != nil {
+		r.spin.stop()
+		r.spin = nil
+	}
+}
+
+func (r *streamRenderer) endLine() {
+	if r.lineOpen {
+		fmt.Fprintln(r.out)
+		r.lineOpen = false
+	}
+}
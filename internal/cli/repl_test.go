@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replTestProvider 回复固定内容并上报用量；block 为 true 时一直等到请求被取消
+type replTestProvider struct {
+	mu      sync.Mutex
+	reply   string
+	block   bool
+	started chan struct{}
+	last    []providers.Message
+}
+
+func (p *replTestProvider) Chat(ctx context.Context, messages []providers.Message, defs []map[string]interface{}, model string) (*providers.Response, error) {
+	return nil, nil
+}
+
+func (p *replTestProvider) ChatStream(ctx context.Context, messages []providers.Message, defs []map[string]interface{}, model string, handler providers.StreamHandler) error {
+	p.mu.Lock()
+	p.last = messages
+	p.mu.Unlock()
+	if p.block {
+		close(p.started)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	handler.OnContent(p.reply)
+	if uh, ok := handler.(providers.UsageHandler); ok {
+		uh.OnUsage(providers.Usage{PromptTokens: 1000, CompletionTokens: 100, CachedTokens: 400})
+	}
+	handler.OnComplete()
+	return nil
+}
+
+func (p *replTestProvider) GetDefaultModel() string { return "gpt-4o" }
+
+func (p *replTestProvider) SupportsImageInput(model string) bool { return true }
+
+func (p *replTestProvider) lastUserMessage() providers.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.last[len(p.last)-1]
+}
+
+func newTestREPL(t *testing.T, provider *replTestProvider) (*repl, *bytes.Buffer) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Agents.Defaults.EnableGlobalSkills = false
+	loop := agent.NewAgentLoop(
+		bus.NewMessageBus(10),
+		provider,
+		cfg.Agents.Defaults.Workspace,
+		"gpt-4o",
+		3,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		nil,
+		nil,
+		false,
+	)
+	t.Cleanup(func() { _ = loop.Close() })
+
+	var out bytes.Buffer
+	r := newREPL(loop, cfg, &out, "cli:direct", true)
+	return r, &out
+}
+
+func TestReadREPLInputSupportsContinuationAndBlocks(t *testing.T) {
+	feed := func(lines ...string) func(string) (string, error) {
+		return func(string) (string, error) {
+			if len(lines) == 0 {
+				return "", errors.New("no more input")
+			}
+			line := lines[0]
+			lines = lines[1:]
+			return line, nil
+		}
+	}
+
+	got, err := readREPLInput(feed("  hello  "))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", got)
+
+	got, err = readREPLInput(feed(`first \`, "second"))
+	require.NoError(t, err)
+	assert.Equal(t, "first \nsecond", got)
+
+	got, err = readREPLInput(feed(`"""`, "func main() {", "    fmt.Println()", "}", `"""`))
+	require.NoError(t, err)
+	assert.Equal(t, "func main() {\n    fmt.Println()\n}", got)
+
+	_, err = readREPLInput(feed(`"""`, "unterminated"))
+	assert.Error(t, err)
+}
+
+func TestParseREPLCommand(t *testing.T) {
+	name, args, ok := parseREPLCommand("/Session switch cli:work")
+	require.True(t, ok)
+	assert.Equal(t, "/session", name)
+	assert.Equal(t, []string{"switch", "cli:work"}, args)
+
+	for _, input := range []string{"hello", "/etc/hosts looks wrong", "/", "use @skill:x"} {
+		_, _, ok := parseREPLCommand(input)
+		assert.False(t, ok, input)
+	}
+}
+
+func TestREPLCompletion(t *testing.T) {
+	r, _ := newTestREPL(t, &replTestProvider{reply: "ok"})
+	skillDir := filepath.Join(r.cfg.Agents.Defaults.Workspace, "skills", "release-notes")
+	require.NoError(t, os.MkdirAll(skillDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("# Release Notes\n\nWrite release notes."), 0644))
+
+	head, matches, tail := r.complete("/se", 3)
+	assert.Equal(t, "", head)
+	assert.Equal(t, []string{"/session "}, matches)
+	assert.Equal(t, "", tail)
+
+	_, matches, _ = r.complete("/mode a", 7)
+	assert.Equal(t, []string{"ask", "auto"}, matches)
+
+	head, matches, _ = r.complete("please use @sk", 14)
+	assert.Equal(t, "please use ", head)
+	assert.Equal(t, []string{"@skill:release-notes "}, matches)
+
+	_, matches, _ = r.complete("/skills re", 10)
+	assert.Equal(t, []string{"release-notes "}, matches)
+
+	_, matches, _ = r.complete("hello wor", 9)
+	assert.Empty(t, matches)
+}
+
+func TestREPLSendStreamsReplyAndTracksUsage(t *testing.T) {
+	provider := &replTestProvider{reply: "**hi** there"}
+	r, out := newTestREPL(t, provider)
+
+	r.send(context.Background(), "hello")
+	assert.Equal(t, 1, strings.Count(out.String(), "**hi** there"), "streamed reply is not printed twice")
+
+	out.Reset()
+	r.runCommand(context.Background(), "/usage", nil)
+	assert.Contains(t, out.String(), "* cli:direct")
+	assert.Contains(t, out.String(), "gpt-4o: 1 calls, 1000 prompt (400 cached) + 100 completion tokens, ~$")
+
+	// 纯文本模式不流式输出，结束后打印去掉 Markdown 的回复
+	r.markdown = false
+	r.channel = resolveCLIChannel(false)
+	out.Reset()
+	r.send(context.Background(), "again")
+	assert.Contains(t, out.String(), "hi there")
+	assert.NotContains(t, out.String(), "**")
+}
+
+func TestREPLModeModelAndSessionCommands(t *testing.T) {
+	r, out := newTestREPL(t, &replTestProvider{reply: "ok"})
+	ctx := context.Background()
+
+	r.runCommand(ctx, "/mode", []string{"auto"})
+	assert.Equal(t, config.ExecutionModeAuto, r.loop.ExecutionMode())
+	r.runCommand(ctx, "/mode", []string{"yolo"})
+	assert.Contains(t, out.String(), `unknown mode "yolo"`)
+
+	var requested string
+	r.newProvider = func(model string) (providers.LLMProvider, error) {
+		requested = model
+		return &replTestProvider{reply: "ok"}, nil
+	}
+	r.runCommand(ctx, "/model", []string{"claude-sonnet-4-5"})
+	assert.Equal(t, "claude-sonnet-4-5", requested)
+	assert.Equal(t, "claude-sonnet-4-5", r.loop.RuntimeModel())
+
+	r.send(ctx, "hello")
+	r.runCommand(ctx, "/session", []string{"new", "work"})
+	assert.Equal(t, "cli:work", r.sessionKey)
+	r.send(ctx, "work item")
+
+	out.Reset()
+	r.runCommand(ctx, "/session", []string{"list"})
+	assert.Contains(t, out.String(), "* cli:work")
+	assert.Contains(t, out.String(), "  cli:direct")
+
+	r.runCommand(ctx, "/session", []string{"switch", "direct"})
+	assert.Equal(t, "cli:direct", r.sessionKey)
+	r.runCommand(ctx, "/session", []string{"switch", "missing"})
+	assert.Contains(t, out.String(), `session "missing" not found`)
+
+	out.Reset()
+	r.runCommand(ctx, "/undo", nil)
+	assert.Contains(t, out.String(), "Removed the last turn (2 messages): hello")
+	assert.Empty(t, r.loop.SessionHistory("cli:direct"))
+	r.runCommand(ctx, "/undo", nil)
+	assert.Contains(t, out.String(), "Nothing to undo.")
+
+	out.Reset()
+	assert.True(t, r.runCommand(ctx, "/exit", nil))
+	assert.False(t, r.runCommand(ctx, "/bogus", nil))
+	assert.Contains(t, out.String(), "unknown command /bogus")
+}
+
+func TestREPLAttachAddsFilesToNextMessageOnly(t *testing.T) {
+	provider := &replTestProvider{reply: "ok"}
+	r, out := newTestREPL(t, provider)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	notes := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(notes, []byte("line one\nline two\n"), 0644))
+	image := filepath.Join(dir, "shot.png")
+	require.NoError(t, os.WriteFile(image, []byte("\x89PNG\r\n\x1a\n"), 0644))
+	binary := filepath.Join(dir, "blob.bin")
+	require.NoError(t, os.WriteFile(binary, []byte{0xff, 0xfe, 0x00}, 0644))
+
+	r.runCommand(ctx, "/attach", []string{notes})
+	r.runCommand(ctx, "/attach", []string{image})
+	r.runCommand(ctx, "/attach", []string{binary})
+	assert.Contains(t, out.String(), "is not a text, image or document file")
+
+	r.send(ctx, "summarize")
+	msg := provider.lastUserMessage()
+	assert.Contains(t, msg.Content, "[Attached file: "+notes+"]\n```\nline one\nline two\n```")
+	var imagePath string
+	for _, part := range msg.Parts {
+		if part.Type == "image_url" {
+			imagePath = part.ImagePath
+		}
+	}
+	assert.Equal(t, image, imagePath)
+
+	r.send(ctx, "next")
+	msg = provider.lastUserMessage()
+	assert.NotContains(t, msg.Content, "Attached file")
+	assert.Empty(t, msg.Parts)
+}
+
+func TestREPLInterruptCancelsRunningRequest(t *testing.T) {
+	provider := &replTestProvider{block: true, started: make(chan struct{})}
+	r, out := newTestREPL(t, provider)
+	assert.False(t, r.interrupt(), "nothing to interrupt while idle")
+
+	done := make(chan struct{})
+	go func() {
+		r.send(context.Background(), "long task")
+		close(done)
+	}()
+	<-provider.started
+	require.True(t, r.interrupt())
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request was not interrupted")
+	}
+	assert.Contains(t, out.String(), "⏹ Interrupted")
+}
@@ -0,0 +1,511 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/spf13/cobra"
+)
+
+// maxclaw run 的退出码
+const (
+	runExitOK            = 0
+	runExitError         = 1
+	runExitToolFailure   = 2
+	runExitMaxIterations = 3
+)
+
+// 单个任务的结束状态
+const (
+	runStatusOK            = "ok"
+	runStatusError         = "error"
+	runStatusToolFailure   = "tool_failed"
+	runStatusMaxIterations = "max_iterations"
+)
+
+var runOutputFormats = []string{"text", "json", "jsonl"}
+
+var (
+	runFileFlag          string
+	runBatchFlag         string
+	runConcurrencyFlag   int
+	runOutputFlag        string
+	runSessionFlag       string
+	runMaxIterationsFlag int
+	runModeFlag          string
+	runModelFlag         string
+	runAllowedToolsFlag  []string
+)
+
+// ExitError 让命令以指定退出码结束；Err 为空时结果已输出，不再打印错误
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func init() {
+	runCmd.Flags().StringVarP(&runFileFlag, "file", "f", "", "Read the prompt from a file (- for stdin)")
+	runCmd.Flags().StringVar(&runBatchFlag, "batch", "", "Run every prompt in a JSONL file ({\"id\",\"prompt\",\"session\",\"skills\"} per line)")
+	runCmd.Flags().IntVar(&runConcurrencyFlag, "concurrency", 4, "Maximum prompts running at once in batch mode")
+	runCmd.Flags().StringVarP(&runOutputFlag, "output", "o", "text", "Output format: text, json or jsonl")
+	runCmd.Flags().StringVarP(&runSessionFlag, "session", "s", "", "Session ID (default: a new run:<timestamp> session)")
+	runCmd.Flags().IntVar(&runMaxIterationsFlag, "max-iterations", 0, "Override agents.defaults.maxToolIterations")
+	runCmd.Flags().StringVar(&runModeFlag, "mode", "", "Override the execution mode: safe, ask or auto")
+	runCmd.Flags().StringVar(&runModelFlag, "model", "", "Override the model")
+	runCmd.Flags().StringSliceVar(&runAllowedToolsFlag, "allowed-tools", nil, "Only expose these tools (names or globs such as mcp_*)")
+
+	rootCmd.AddCommand(runCmd)
+}
+
+// runCmd 非交互地执行 prompt，供 CI 与脚本使用
+var runCmd = &cobra.Command{
+	Use:   "run [prompt]",
+	Short: "Run a prompt non-interactively (for CI and scripts)",
+	Long: `Run a prompt without the REPL and exit.
+
+The prompt comes from the arguments, --file, or stdin. --batch runs every
+prompt of a JSONL file with bounded concurrency.
+
+Exit codes: 0 success, 1 error, 2 a tool call failed, 3 max iterations reached.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !containsString(runOutputFormats, runOutputFlag) {
+			return fmt.Errorf("unknown --output %q (use text, json or jsonl)", runOutputFlag)
+		}
+		if runModeFlag != "" && config.NormalizeExecutionMode(runModeFlag) != strings.ToLower(strings.TrimSpace(runModeFlag)) {
+			return fmt.Errorf("unknown --mode %q (use safe, ask or auto)", runModeFlag)
+		}
+		if cmd.Flags().Changed("max-iterations") && runMaxIterationsFlag <= 0 {
+			return fmt.Errorf("--max-iterations must be positive")
+		}
+
+		tasks, batch, err := loadRunTasks(args, os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		initLogging(cfg)
+		if runModelFlag != "" {
+			cfg.Agents.Defaults.Model = runModelFlag
+		}
+		if runMaxIterationsFlag > 0 {
+			cfg.Agents.Defaults.MaxToolIterations = runMaxIterationsFlag
+		}
+		if runModeFlag != "" {
+			cfg.Agents.Defaults.ExecutionMode = config.NormalizeExecutionMode(runModeFlag)
+		}
+
+		agentLoop, err := newCLIAgentLoop(cfg)
+		if err != nil {
+			return err
+		}
+		defer agentLoop.Close()
+		if len(runAllowedToolsFlag) > 0 {
+			agentLoop.SetToolAllowlist(runAllowedToolsFlag)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		runner := &taskRunner{
+			loop:        agentLoop,
+			output:      runOutputFlag,
+			batch:       batch,
+			concurrency: runConcurrencyFlag,
+			stdout:      cmd.OutOrStdout(),
+			stderr:      cmd.ErrOrStderr(),
+		}
+		if code := runner.run(ctx, tasks); code != runExitOK {
+			return &ExitError{Code: code}
+		}
+		return nil
+	},
+}
+
+// runTask 一个待执行的 prompt；batch 文件每行一个
+type runTask struct {
+	ID      string   `json:"id,omitempty"`
+	Prompt  string   `json:"prompt"`
+	Session string   `json:"session,omitempty"`
+	Skills  []string `json:"skills,omitempty"`
+}
+
+// runUsage 任务内所有 LLM 调用的 token 用量；CostUSD 在任一模型缺少价格时为空
+type runUsage struct {
+	PromptTokens     int      `json:"promptTokens"`
+	CompletionTokens int      `json:"completionTokens"`
+	CachedTokens     int      `json:"cachedTokens"`
+	CostUSD          *float64 `json:"costUsd,omitempty"`
+}
+
+// runResult 任务结果：json 输出的主体，也是 jsonl 的 final 事件
+type runResult struct {
+	ID           string   `json:"id,omitempty"`
+	Session      string   `json:"session"`
+	Status       string   `json:"status"`
+	ExitCode     int      `json:"exitCode"`
+	Response     string   `json:"response"`
+	Error        string   `json:"error,omitempty"`
+	Iterations   int      `json:"iterations"`
+	ToolCalls    int      `json:"toolCalls"`
+	ToolFailures int      `json:"toolFailures"`
+	FilesChanged []string `json:"filesChanged"`
+	Usage        runUsage `json:"usage"`
+	DurationMs   int64    `json:"durationMs"`
+
+	costUnknown bool
+	files       map[string]bool
+	limit       bool
+}
+
+// runEventLine jsonl 输出中的一行：事件本身加上所属任务
+type runEventLine struct {
+	ID      string `json:"id,omitempty"`
+	Session string `json:"session"`
+	agent.StreamEvent
+}
+
+type runFinalLine struct {
+	Type string `json:"type"`
+	*runResult
+}
+
+// loadRunTasks 按 --batch、参数、--file、stdin 的顺序确定要执行的 prompt
+func loadRunTasks(args []string, stdin io.Reader) ([]runTask, bool, error) {
+	stamp := time.Now().Format("20060102-150405")
+
+	if runBatchFlag != "" {
+		if len(args) > 0 || runFileFlag != "" {
+			return nil, false, fmt.Errorf("--batch cannot be combined with a prompt or --file")
+		}
+		if runSessionFlag != "" {
+			return nil, false, fmt.Errorf("--session cannot be used with --batch; set \"session\" per line instead")
+		}
+		r, closeFn, err := openRunInput(runBatchFlag, stdin)
+		if err != nil {
+			return nil, false, err
+		}
+		defer closeFn()
+		tasks, err := parseRunBatch(r, stamp)
+		return tasks, true, err
+	}
+
+	var prompt string
+	switch {
+	case len(args) > 0 && !(len(args) == 1 && args[0] == "-"):
+		if runFileFlag != "" {
+			return nil, false, fmt.Errorf("pass the prompt either as arguments or with --file, not both")
+		}
+		prompt = strings.Join(args, " ")
+	case runFileFlag != "" || len(args) == 1:
+		source := runFileFlag
+		if source == "" {
+			source = "-"
+		}
+		r, closeFn, err := openRunInput(source, stdin)
+		if err != nil {
+			return nil, false, err
+		}
+		defer closeFn()
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, false, fmt.Errorf("read prompt: %w", err)
+		}
+		prompt = string(data)
+	default:
+		if f, ok := stdin.(*os.File); ok && isTerminal(f) {
+			return nil, false, fmt.Errorf("no prompt given: pass it as an argument, with --file, or on stdin")
+		}
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, false, fmt.Errorf("read prompt from stdin: %w", err)
+		}
+		prompt = string(data)
+	}
+
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return nil, false, fmt.Errorf("prompt is empty")
+	}
+	session := runSessionFlag
+	if session == "" {
+		session = "run:" + stamp
+	}
+	return []runTask{{Prompt: prompt, Session: session}}, false, nil
+}
+
+func openRunInput(path string, stdin io.Reader) (io.Reader, func(), error) {
+	if path == "-" {
+		return stdin, func() {}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { _ = f.Close() }, nil
+}
+
+// parseRunBatch 解析 batch 文件：空行与 # 开头的行跳过，未指定 id 时使用行号，未指定 session 时每个任务使用独立会话
+func parseRunBatch(r io.Reader, stamp string) ([]runTask, error) {
+	var tasks []runTask
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var task runTask
+		if err := json.Unmarshal([]byte(line), &task); err != nil {
+			return nil, fmt.Errorf("batch line %d: %w", lineNo, err)
+		}
+		task.Prompt = strings.TrimSpace(task.Prompt)
+		if task.Prompt == "" {
+			return nil, fmt.Errorf("batch line %d: prompt is empty", lineNo)
+		}
+		if task.ID == "" {
+			task.ID = fmt.Sprintf("line-%d", lineNo)
+		}
+		if seen[task.ID] {
+			return nil, fmt.Errorf("batch line %d: duplicate id %q", lineNo, task.ID)
+		}
+		seen[task.ID] = true
+		if task.Session == "" {
+			task.Session = fmt.Sprintf("run:%s:%s", stamp, task.ID)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read batch file: %w", err)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("batch file has no prompts")
+	}
+	return tasks, nil
+}
+
+// taskRunner 执行任务并按输出格式写出事件与结果
+type taskRunner struct {
+	loop        *agent.AgentLoop
+	output      string
+	batch       bool
+	concurrency int
+	stdout      io.Writer
+	stderr      io.Writer
+
+	mu sync.Mutex // 串行化并发任务的输出
+}
+
+// run 执行全部任务并返回退出码：有错误时为 1，其次是达到迭代上限（3）与工具失败（2）
+func (t *taskRunner) run(ctx context.Context, tasks []runTask) int {
+	results := make([]*runResult, len(tasks))
+	limit := t.concurrency
+	if limit <= 0 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, task runTask) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = t.execute(ctx, task)
+			t.writeResult(results[i])
+		}(i, task)
+	}
+	wg.Wait()
+
+	if t.output == "json" {
+		var payload interface{} = results[0]
+		if t.batch {
+			payload = results
+		}
+		data, _ := json.MarshalIndent(payload, "", "  ")
+		fmt.Fprintln(t.stdout, string(data))
+	}
+
+	code := runExitOK
+	for _, r := range results {
+		code = worseRunExitCode(code, r.ExitCode)
+	}
+	return code
+}
+
+func worseRunExitCode(a, b int) int {
+	rank := map[int]int{runExitOK: 0, runExitToolFailure: 1, runExitMaxIterations: 2, runExitError: 3}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// execute 执行单个任务，统计工具调用、改动文件与用量
+func (t *taskRunner) execute(ctx context.Context, task runTask) *runResult {
+	result := &runResult{
+		ID:           task.ID,
+		Session:      task.Session,
+		FilesChanged: []string{},
+		files:        make(map[string]bool),
+	}
+	started := time.Now()
+	onEvent := func(event agent.StreamEvent) {
+		result.record(event)
+		t.writeEvent(task, event)
+	}
+	response, err := t.loop.ProcessDirectEventStreamWithSkills(ctx, task.Prompt, task.Session, resolveCLIChannel(true), "run", task.Skills, onEvent)
+	result.DurationMs = time.Since(started).Milliseconds()
+	result.Response = strings.TrimSpace(response)
+	for path := range result.files {
+		result.FilesChanged = append(result.FilesChanged, path)
+	}
+	sort.Strings(result.FilesChanged)
+	if result.costUnknown {
+		result.Usage.CostUSD = nil
+	}
+
+	switch {
+	case err != nil:
+		result.Status, result.ExitCode = runStatusError, runExitError
+		result.Error = err.Error()
+		if errors.Is(err, context.Canceled) {
+			result.Error = "interrupted"
+		}
+	case result.limit:
+		result.Status, result.ExitCode = runStatusMaxIterations, runExitMaxIterations
+	case result.ToolFailures > 0:
+		result.Status, result.ExitCode = runStatusToolFailure, runExitToolFailure
+	default:
+		result.Status, result.ExitCode = runStatusOK, runExitOK
+	}
+	return result
+}
+
+func (r *runResult) record(event agent.StreamEvent) {
+	if event.Iteration > r.Iterations && event.Type != "iteration_limit" {
+		r.Iterations = event.Iteration
+	}
+	switch event.Type {
+	case "tool_start":
+		r.ToolCalls++
+	case "tool_result":
+		if event.IsError {
+			r.ToolFailures++
+		}
+	case "file_diff":
+		if event.FilePath != "" {
+			r.files[event.FilePath] = true
+		}
+	case "iteration_limit":
+		r.limit = true
+	case "usage":
+		r.Usage.PromptTokens += event.PromptTokens
+		r.Usage.CompletionTokens += event.CompletionTokens
+		r.Usage.CachedTokens += event.CachedTokens
+		info, _ := providers.LookupModel(providers.DetectProviderName(event.Model), event.Model)
+		// 缓存命中的 token 包含在 prompt 中，按缓存读取价格单独计费
+		cost, ok := info.Cost(event.PromptTokens-event.CachedTokens, event.CompletionTokens, event.CachedTokens, 0)
+		if !ok {
+			r.costUnknown = true
+			return
+		}
+		if r.Usage.CostUSD == nil {
+			r.Usage.CostUSD = new(float64)
+		}
+		*r.Usage.CostUSD += cost
+	}
+}
+
+// writeEvent jsonl 模式逐行输出事件；text 模式把工具进度写到 stderr，stdout 只留回复
+func (t *taskRunner) writeEvent(task runTask, event agent.StreamEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch t.output {
+	case "jsonl":
+		data, err := json.Marshal(runEventLine{ID: task.ID, Session: task.Session, StreamEvent: event})
+		if err == nil {
+			fmt.Fprintln(t.stdout, string(data))
+		}
+	case "text":
+		prefix := ""
+		if t.batch {
+			prefix = "[" + task.ID + "] "
+		}
+		switch event.Type {
+		case "tool_start":
+			fmt.Fprintf(t.stderr, "%s→ %s\n", prefix, event.Summary)
+		case "tool_result":
+			mark := "✓"
+			if event.IsError {
+				mark = "✗"
+			}
+			fmt.Fprintf(t.stderr, "%s%s %s\n", prefix, mark, event.Summary)
+		}
+	}
+}
+
+func (t *taskRunner) writeResult(result *runResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch t.output {
+	case "jsonl":
+		data, err := json.Marshal(runFinalLine{Type: "final", runResult: result})
+		if err == nil {
+			fmt.Fprintln(t.stdout, string(data))
+		}
+	case "text":
+		if t.batch {
+			fmt.Fprintf(t.stdout, "=== %s (%s) ===\n", result.ID, result.Status)
+		}
+		if result.Error != "" {
+			fmt.Fprintf(t.stderr, "Error: %s\n", result.Error)
+		}
+		if result.Response != "" {
+			fmt.Fprintln(t.stdout, result.Response)
+		}
+		if t.batch {
+			fmt.Fprintln(t.stdout)
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/providers"
+	"github.com/Lichas/maxclaw/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runTestProvider 按 prompt 决定行为：write 写文件、fail 调用不存在的工具、loop 一直调用工具，其余直接回复
+type runTestProvider struct{}
+
+func (p *runTestProvider) Chat(ctx context.Context, messages []providers.Message, defs []map[string]interface{}, model string) (*providers.Response, error) {
+	return nil, nil
+}
+
+func (p *runTestProvider) ChatStream(ctx context.Context, messages []providers.Message, defs []map[string]interface{}, model string, handler providers.StreamHandler) error {
+	var prompt string
+	for _, m := range messages {
+		if m.Role == "user" {
+			prompt = m.Content
+		}
+	}
+	afterTool := messages[len(messages)-1].Role == "tool"
+
+	callTool := func(name, args string) {
+		handler.OnToolCallStart("call_1", name)
+		handler.OnToolCallDelta("call_1", args)
+		handler.OnToolCallEnd("call_1")
+	}
+	switch {
+	case strings.Contains(prompt, "loop"):
+		callTool("read_file", `{"path":"missing.txt"}`)
+	case strings.Contains(prompt, "write") && !afterTool:
+		callTool("write_file", `{"path":"out.txt","content":"hello\n"}`)
+	case strings.Contains(prompt, "fail") && !afterTool:
+		callTool("does_not_exist", `{}`)
+	default:
+		handler.OnContent("done: " + prompt)
+	}
+	if uh, ok := handler.(providers.UsageHandler); ok {
+		uh.OnUsage(providers.Usage{PromptTokens: 100, CompletionTokens: 10})
+	}
+	handler.OnComplete()
+	return nil
+}
+
+func (p *runTestProvider) GetDefaultModel() string { return "gpt-4o" }
+
+func (p *runTestProvider) SupportsImageInput(model string) bool { return false }
+
+func newTestTaskRunner(t *testing.T, output string, batch bool) (*taskRunner, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	loop := agent.NewAgentLoop(
+		bus.NewMessageBus(10),
+		&runTestProvider{},
+		t.TempDir(),
+		"gpt-4o",
+		2,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		nil,
+		nil,
+		false,
+	)
+	t.Cleanup(func() { _ = loop.Close() })
+
+	var stdout, stderr bytes.Buffer
+	return &taskRunner{loop: loop, output: output, batch: batch, concurrency: 2, stdout: &stdout, stderr: &stderr}, &stdout, &stderr
+}
+
+func resetRunFlags(t *testing.T) {
+	t.Cleanup(func() {
+		runFileFlag, runBatchFlag, runSessionFlag = "", "", ""
+	})
+}
+
+func TestLoadRunTasksFromArgsFileAndStdin(t *testing.T) {
+	resetRunFlags(t)
+
+	tasks, batch, err := loadRunTasks([]string{"fix", "the", "build"}, strings.NewReader(""))
+	require.NoError(t, err)
+	assert.False(t, batch)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "fix the build", tasks[0].Prompt)
+	assert.True(t, strings.HasPrefix(tasks[0].Session, "run:"))
+
+	tasks, _, err = loadRunTasks([]string{"-"}, strings.NewReader("  from stdin\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "from stdin", tasks[0].Prompt)
+
+	tasks, _, err = loadRunTasks(nil, strings.NewReader("piped prompt"))
+	require.NoError(t, err)
+	assert.Equal(t, "piped prompt", tasks[0].Prompt)
+
+	path := filepath.Join(t.TempDir(), "prompt.md")
+	require.NoError(t, os.WriteFile(path, []byte("from file"), 0644))
+	runFileFlag, runSessionFlag = path, "ci:job"
+	tasks, _, err = loadRunTasks(nil, strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Equal(t, runTask{Prompt: "from file", Session: "ci:job"}, tasks[0])
+
+	_, _, err = loadRunTasks([]string{"both"}, strings.NewReader(""))
+	assert.Error(t, err)
+
+	runFileFlag, runSessionFlag = "", ""
+	_, _, err = loadRunTasks(nil, strings.NewReader("  \n"))
+	assert.EqualError(t, err, "prompt is empty")
+}
+
+func TestParseRunBatch(t *testing.T) {
+	input := `# nightly checks
+{"id":"lint","prompt":"run the linter"}
+
+{"prompt":"summarize the changelog","session":"ci:docs","skills":["release-notes"]}
+`
+	tasks, err := parseRunBatch(strings.NewReader(input), "20260101-000000")
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, runTask{ID: "lint", Prompt: "run the linter", Session: "run:20260101-000000:lint"}, tasks[0])
+	assert.Equal(t, runTask{ID: "line-4", Prompt: "summarize the changelog", Session: "ci:docs", Skills: []string{"release-notes"}}, tasks[1])
+
+	_, err = parseRunBatch(strings.NewReader(`{"id":"a","prompt":"x"}`+"\n"+`{"id":"a","prompt":"y"}`), "s")
+	assert.EqualError(t, err, `batch line 2: duplicate id "a"`)
+	_, err = parseRunBatch(strings.NewReader(`{"id":"a"}`), "s")
+	assert.EqualError(t, err, "batch line 1: prompt is empty")
+	_, err = parseRunBatch(strings.NewReader("not json"), "s")
+	assert.Error(t, err)
+	_, err = parseRunBatch(strings.NewReader("\n# only comments\n"), "s")
+	assert.EqualError(t, err, "batch file has no prompts")
+}
+
+func TestTaskRunnerJSONReportsResponseUsageAndFiles(t *testing.T) {
+	runner, stdout, _ := newTestTaskRunner(t, "json", false)
+
+	code := runner.run(context.Background(), []runTask{{Prompt: "write a file", Session: "run:test"}})
+	assert.Equal(t, runExitOK, code)
+
+	var result map[string]interface{}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &result))
+	assert.Equal(t, "ok", result["status"])
+	assert.Equal(t, "done: write a file", result["response"])
+	assert.Equal(t, "run:test", result["session"])
+	assert.Equal(t, []interface{}{"out.txt"}, result["filesChanged"])
+	assert.EqualValues(t, 1, result["toolCalls"])
+	assert.EqualValues(t, 2, result["iterations"])
+	usage := result["usage"].(map[string]interface{})
+	assert.EqualValues(t, 200, usage["promptTokens"])
+	assert.EqualValues(t, 20, usage["completionTokens"])
+	assert.Contains(t, usage, "costUsd")
+}
+
+func TestTaskRunnerJSONLStreamsEventsAndFinal(t *testing.T) {
+	runner, stdout, _ := newTestTaskRunner(t, "jsonl", false)
+
+	code := runner.run(context.Background(), []runTask{{Prompt: "fail please", Session: "run:test"}})
+	assert.Equal(t, runExitToolFailure, code)
+
+	var types []string
+	var final map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &event), line)
+		assert.Equal(t, "run:test", event["session"])
+		types = append(types, event["type"].(string))
+		if event["type"] == "tool_result" {
+			assert.Equal(t, true, event["isError"])
+		}
+		if event["type"] == "final" {
+			final = event
+		}
+	}
+	assert.Contains(t, types, "tool_start")
+	assert.Contains(t, types, "content_delta")
+	assert.Equal(t, "final", types[len(types)-1])
+	assert.Equal(t, "tool_failed", final["status"])
+	assert.EqualValues(t, runExitToolFailure, final["exitCode"])
+	assert.EqualValues(t, 1, final["toolFailures"])
+}
+
+func TestTaskRunnerBatchKeepsInputOrderAndWorstExitCode(t *testing.T) {
+	runner, stdout, _ := newTestTaskRunner(t, "json", true)
+
+	code := runner.run(context.Background(), []runTask{
+		{ID: "a", Prompt: "hello", Session: "run:a"},
+		{ID: "b", Prompt: "loop forever", Session: "run:b"},
+		{ID: "c", Prompt: "fail once", Session: "run:c"},
+	})
+	assert.Equal(t, runExitMaxIterations, code)
+
+	var results []runResult
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &results))
+	require.Len(t, results, 3)
+	assert.Equal(t, []string{"a", "b", "c"}, []string{results[0].ID, results[1].ID, results[2].ID})
+	assert.Equal(t, runStatusOK, results[0].Status)
+	assert.Equal(t, runStatusMaxIterations, results[1].Status)
+	assert.Equal(t, runStatusToolFailure, results[2].Status)
+}
+
+func TestTaskRunnerTextKeepsProgressOffStdout(t *testing.T) {
+	runner, stdout, stderr := newTestTaskRunner(t, "text", false)
+
+	code := runner.run(context.Background(), []runTask{{Prompt: "write it", Session: "run:test"}})
+	assert.Equal(t, runExitOK, code)
+	assert.Equal(t, "done: write it\n", stdout.String())
+	assert.Contains(t, stderr.String(), "→ write_file")
+	assert.Contains(t, stderr.String(), "✓ write_file")
+}
+
+func TestWorseRunExitCode(t *testing.T) {
+	assert.Equal(t, runExitToolFailure, worseRunExitCode(runExitOK, runExitToolFailure))
+	assert.Equal(t, runExitMaxIterations, worseRunExitCode(runExitMaxIterations, runExitToolFailure))
+	assert.Equal(t, runExitError, worseRunExitCode(runExitMaxIterations, runExitError))
+	assert.Equal(t, runExitError, worseRunExitCode(runExitError, runExitOK))
+}
@@ -0,0 +1,385 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/bus"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/cron"
+	"github.com/Lichas/maxclaw/internal/triggers"
+	"github.com/spf13/cobra"
+)
+
+// triggerAddOptions trigger add 的参数
+type triggerAddOptions struct {
+	prompt   string
+	secret   string
+	extract  []string
+	paths    []string
+	patterns []string
+	events   []string
+	interval int
+	from     string
+	subject  string
+	mailbox  string
+	channel  string
+	to       string
+	callback string
+	notify   bool
+}
+
+var (
+	triggerAddOpts      triggerAddOptions
+	triggerHistoryLimit int
+)
+
+func init() {
+	f := triggerAddCmd.Flags()
+	f.StringVarP(&triggerAddOpts.prompt, "prompt", "p", "", "Prompt template, e.g. \"Triage: {{issue.title}}\" (default: built-in per type)")
+	f.StringVar(&triggerAddOpts.secret, "secret", "", "Webhook HMAC secret (default: generated)")
+	f.StringArrayVar(&triggerAddOpts.extract, "extract", nil, "Webhook template variable name=json.path (repeatable)")
+	f.StringSliceVar(&triggerAddOpts.paths, "path", nil, "File: workspace-relative paths to watch (repeatable)")
+	f.StringSliceVar(&triggerAddOpts.patterns, "pattern", nil, "File: glob patterns, e.g. *.md (repeatable)")
+	f.StringSliceVar(&triggerAddOpts.events, "events", nil, "File: create, write, remove (default: all)")
+	f.IntVar(&triggerAddOpts.interval, "interval", 0, "File/IMAP: poll interval in seconds (default 5 / 60)")
+	f.StringVar(&triggerAddOpts.from, "from", "", "IMAP: only mail from this address, or from this domain (example.com)")
+	f.StringVar(&triggerAddOpts.subject, "subject", "", "IMAP: only mail whose subject contains this text")
+	f.StringVar(&triggerAddOpts.mailbox, "mailbox", "", "IMAP: mailbox to watch (default INBOX)")
+	f.StringVarP(&triggerAddOpts.channel, "channel", "c", "", "Deliver the result to this channel")
+	f.StringVar(&triggerAddOpts.to, "to", "", "Chat ID for --channel")
+	f.StringVar(&triggerAddOpts.callback, "callback", "", "POST the result as JSON to this URL")
+	f.BoolVar(&triggerAddOpts.notify, "notify", false, "Add the result to the Web UI notifications")
+
+	triggerHistoryCmd.Flags().IntVarP(&triggerHistoryLimit, "limit", "n", 20, "Number of records to show")
+
+	triggerCmd.AddCommand(triggerAddCmd)
+	triggerCmd.AddCommand(triggerListCmd)
+	triggerCmd.AddCommand(triggerShowCmd)
+	triggerCmd.AddCommand(triggerRemoveCmd)
+	triggerCmd.AddCommand(triggerEnableCmd)
+	triggerCmd.AddCommand(triggerDisableCmd)
+	triggerCmd.AddCommand(triggerHistoryCmd)
+
+	rootCmd.AddCommand(triggerCmd)
+}
+
+// triggerCmd trigger 根命令
+var triggerCmd = &cobra.Command{
+	Use:   "trigger",
+	Short: "Manage webhook, file and IMAP triggers",
+	Long:  "Event triggers start agent runs from inbound webhooks (POST /hooks/<name>), workspace file changes or new mail. The running gateway picks up changes automatically.",
+}
+
+var triggerAddCmd = &cobra.Command{
+	Use:   "add <webhook|file|imap> <name>",
+	Short: "Add a trigger",
+	Example: `  maxclaw trigger add webhook github --prompt "Triage issue: {{issue.title}}" --channel telegram --to 123456
+  maxclaw trigger add webhook deploy --extract env=deployment.environment --callback https://example.com/done
+  maxclaw trigger add file docs --path docs --pattern "*.md" --events create,write --notify
+  maxclaw trigger add imap alerts --from alerts@example.com --subject "[ALERT]" --channel slack --to C123`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		t, err := buildTrigger(args[0], args[1], triggerAddOpts)
+		if err != nil {
+			return err
+		}
+		service, err := loadTriggerService()
+		if err != nil {
+			return err
+		}
+		saved, err := service.Add(t)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "✓ Trigger added: %s (%s)\n", saved.Name, saved.ID)
+		printTrigger(cmd.OutOrStdout(), saved)
+		return nil
+	},
+}
+
+var triggerListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List triggers",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, err := loadTriggerService()
+		if err != nil {
+			return err
+		}
+		list := service.List()
+		out := cmd.OutOrStdout()
+		if len(list) == 0 {
+			fmt.Fprintln(out, "No triggers")
+			return nil
+		}
+		fmt.Fprintf(out, "%-20s %-8s %-9s %s\n", "NAME", "TYPE", "STATUS", "SOURCE")
+		for _, t := range list {
+			status := "disabled"
+			if t.Enabled {
+				status = "enabled"
+			}
+			fmt.Fprintf(out, "%-20s %-8s %-9s %s\n", t.Name, t.Type, status, t.Summary())
+		}
+		return nil
+	},
+}
+
+var triggerShowCmd = &cobra.Command{
+	Use:          "show <name>",
+	Short:        "Show a trigger, including its webhook secret",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, err := loadTriggerService()
+		if err != nil {
+			return err
+		}
+		t, ok := service.Get(args[0])
+		if !ok {
+			return fmt.Errorf("%w: %s", triggers.ErrNotFound, args[0])
+		}
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "%s (%s)\n", t.Name, t.ID)
+		printTrigger(out, t)
+		return nil
+	},
+}
+
+var triggerRemoveCmd = &cobra.Command{
+	Use:          "rm <name>",
+	Aliases:      []string{"remove"},
+	Short:        "Remove a trigger",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, err := loadTriggerService()
+		if err != nil {
+			return err
+		}
+		if err := service.Remove(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "✓ Trigger removed: %s\n", args[0])
+		return nil
+	},
+}
+
+var triggerEnableCmd = &cobra.Command{
+	Use:          "enable <name>",
+	Short:        "Enable a trigger",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setTriggerEnabled(cmd.OutOrStdout(), args[0], true)
+	},
+}
+
+var triggerDisableCmd = &cobra.Command{
+	Use:          "disable <name>",
+	Short:        "Disable a trigger",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setTriggerEnabled(cmd.OutOrStdout(), args[0], false)
+	},
+}
+
+var triggerHistoryCmd = &cobra.Command{
+	Use:          "history [name]",
+	Short:        "Show trigger runs",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, err := loadTriggerService()
+		if err != nil {
+			return err
+		}
+		var records []cron.ExecutionRecord
+		if len(args) == 1 {
+			t, ok := service.Get(args[0])
+			if !ok {
+				return fmt.Errorf("%w: %s", triggers.ErrNotFound, args[0])
+			}
+			records = service.History().GetRecords(t.ID, triggerHistoryLimit)
+		} else {
+			ids := make(map[string]bool)
+			for _, t := range service.List() {
+				ids[t.ID] = true
+			}
+			for _, r := range service.History().GetRecords("", 0) {
+				if ids[r.JobID] {
+					records = append(records, r)
+				}
+				if triggerHistoryLimit > 0 && len(records) >= triggerHistoryLimit {
+					break
+				}
+			}
+		}
+		printTriggerHistory(cmd.OutOrStdout(), records)
+		return nil
+	},
+}
+
+func loadTriggerService() (*triggers.Service, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return triggers.NewService(cfg.Agents.Defaults.Workspace, nil), nil
+}
+
+func setTriggerEnabled(out io.Writer, name string, enabled bool) error {
+	service, err := loadTriggerService()
+	if err != nil {
+		return err
+	}
+	t, err := service.Enable(name, enabled)
+	if err != nil {
+		return err
+	}
+	state := "disabled"
+	if t.Enabled {
+		state = "enabled"
+	}
+	fmt.Fprintf(out, "✓ Trigger %s: %s\n", state, t.Name)
+	return nil
+}
+
+// buildTrigger 根据命令行参数构建触发器，校验交给 Service.Add
+func buildTrigger(kind, name string, opts triggerAddOptions) (*triggers.Trigger, error) {
+	t := &triggers.Trigger{
+		Name:   name,
+		Type:   triggers.Type(strings.ToLower(kind)),
+		Prompt: opts.prompt,
+		Route: triggers.Route{
+			Channel:     opts.channel,
+			To:          opts.to,
+			CallbackURL: opts.callback,
+			Notify:      opts.notify,
+		},
+	}
+	switch t.Type {
+	case triggers.TypeWebhook:
+		t.Webhook = &triggers.WebhookSpec{Secret: opts.secret}
+		for _, item := range opts.extract {
+			key, path, ok := strings.Cut(item, "=")
+			key, path = strings.TrimSpace(key), strings.TrimSpace(path)
+			if !ok || key == "" || path == "" {
+				return nil, fmt.Errorf("invalid --extract %q, use name=json.path", item)
+			}
+			if t.Webhook.Extract == nil {
+				t.Webhook.Extract = make(map[string]string)
+			}
+			t.Webhook.Extract[key] = path
+		}
+	case triggers.TypeFile:
+		t.File = &triggers.FileSpec{
+			Paths:           opts.paths,
+			Patterns:        opts.patterns,
+			Events:          opts.events,
+			IntervalSeconds: opts.interval,
+		}
+	case triggers.TypeIMAP:
+		t.IMAP = &triggers.IMAPSpec{
+			Mailbox:         opts.mailbox,
+			From:            opts.from,
+			Subject:         opts.subject,
+			IntervalSeconds: opts.interval,
+		}
+	default:
+		return nil, fmt.Errorf("invalid trigger type %q, use: webhook, file or imap", kind)
+	}
+	return t, nil
+}
+
+func printTrigger(out io.Writer, t *triggers.Trigger) {
+	state := "disabled"
+	if t.Enabled {
+		state = "enabled"
+	}
+	fmt.Fprintf(out, "  Type: %s (%s)\n", t.Type, state)
+	fmt.Fprintf(out, "  Source: %s\n", t.Summary())
+	if t.Webhook != nil {
+		fmt.Fprintf(out, "  Secret: %s\n", t.Webhook.Secret)
+		fmt.Fprintf(out, "  Signature header: %s: sha256=<hex hmac of body>\n", triggers.SignatureHeader)
+		for key, path := range t.Webhook.Extract {
+			fmt.Fprintf(out, "  Extract: {{%s}} <- %s\n", key, path)
+		}
+	}
+	if t.Prompt != "" {
+		fmt.Fprintf(out, "  Prompt: %s\n", t.Prompt)
+	}
+	if t.Route.Channel != "" {
+		fmt.Fprintf(out, "  Deliver: %s %s\n", t.Route.Channel, t.Route.To)
+	}
+	if t.Route.CallbackURL != "" {
+		fmt.Fprintf(out, "  Callback: %s\n", t.Route.CallbackURL)
+	}
+	if t.Route.Notify {
+		fmt.Fprintln(out, "  Notify: web ui")
+	}
+}
+
+func printTriggerHistory(out io.Writer, records []cron.ExecutionRecord) {
+	if len(records) == 0 {
+		fmt.Fprintln(out, "No trigger runs")
+		return
+	}
+	fmt.Fprintf(out, "%-16s %-20s %-8s %-8s %s\n", "STARTED", "TRIGGER", "EVENT", "STATUS", "RESULT")
+	for _, r := range records {
+		result := r.Output
+		if r.Error != "" {
+			result = r.Error
+		}
+		result = strings.Join(strings.Fields(result), " ")
+		if len([]rune(result)) > 60 {
+			result = string([]rune(result)[:60]) + "..."
+		}
+		fmt.Fprintf(out, "%-16s %-20s %-8s %-8s %s\n", r.StartedAt.Format("01-02 15:04:05"), r.JobTitle, r.Trigger, r.Status, result)
+	}
+}
+
+// newGatewayTriggerService 创建 gateway 使用的触发器服务：运行走共享的 agentLoop，结果经消息总线投递
+func newGatewayTriggerService(cfg *config.Config, agentLoop *agent.AgentLoop, messageBus *bus.MessageBus, cronService *cron.Service) *triggers.Service {
+	service := triggers.NewService(cfg.Agents.Defaults.Workspace, cronService.GetHistoryStore())
+	service.SetRunner(runTriggerPrompt(agentLoop))
+	service.SetDeliverer(func(channel, to, content string) error {
+		return messageBus.PublishOutbound(bus.NewOutboundMessage(channel, to, content))
+	})
+	if email := cfg.Channels.Email; email.IMAPHost != "" {
+		service.SetMailSource(triggers.NewIMAPSource(triggers.IMAPAccount{
+			Host:     email.IMAPHost,
+			Port:     email.IMAPPort,
+			Username: email.IMAPUsername,
+			Password: email.IMAPPassword,
+			UseSSL:   email.IMAPUseSSL,
+		}))
+	}
+	return service
+}
+
+// runTriggerPrompt 每个触发器使用独立会话，避免与聊天记录混在一起
+func runTriggerPrompt(agentLoop *agent.AgentLoop) triggers.RunFunc {
+	return func(ctx context.Context, t *triggers.Trigger, prompt string) (string, error) {
+		channel := t.Route.Channel
+		if channel == "" {
+			channel = "desktop"
+		}
+		msg := bus.NewInboundMessage(channel, "trigger", t.Route.To, fmt.Sprintf("[Trigger: %s] %s", t.Name, prompt))
+		msg.SessionKey = "trigger:" + t.ID
+		resp, err := agentLoop.ProcessMessage(ctx, msg)
+		if err != nil {
+			return "", err
+		}
+		if resp == nil {
+			return "", nil
+		}
+		return resp.Content, nil
+	}
+}
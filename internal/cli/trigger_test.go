@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/cron"
+	"github.com/Lichas/maxclaw/internal/triggers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTrigger(t *testing.T) {
+	hook, err := buildTrigger("Webhook", "github", triggerAddOptions{
+		extract: []string{"title=issue.title", " num = issue.number "},
+		channel: "telegram",
+		to:      "1001",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, triggers.TypeWebhook, hook.Type)
+	assert.Equal(t, map[string]string{"title": "issue.title", "num": "issue.number"}, hook.Webhook.Extract)
+	assert.Equal(t, "telegram", hook.Route.Channel)
+
+	file, err := buildTrigger("file", "docs", triggerAddOptions{paths: []string{"docs"}, events: []string{"write"}, interval: 10})
+	require.NoError(t, err)
+	assert.Equal(t, &triggers.FileSpec{Paths: []string{"docs"}, Events: []string{"write"}, IntervalSeconds: 10}, file.File)
+
+	_, err = buildTrigger("webhook", "x", triggerAddOptions{extract: []string{"title"}})
+	assert.Error(t, err)
+	_, err = buildTrigger("cron", "x", triggerAddOptions{})
+	assert.Error(t, err)
+}
+
+func TestPrintTriggerHistory(t *testing.T) {
+	var out bytes.Buffer
+	printTriggerHistory(&out, nil)
+	assert.Equal(t, "No trigger runs\n", out.String())
+
+	out.Reset()
+	printTriggerHistory(&out, []cron.ExecutionRecord{{
+		JobTitle:  "github",
+		Trigger:   "webhook",
+		Status:    "failed",
+		Output:    "ignored",
+		Error:     "provider\nunavailable",
+		StartedAt: time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local),
+	}})
+	assert.Contains(t, out.String(), "10-18 09:30:00")
+	assert.Contains(t, out.String(), "webhook  failed   provider unavailable")
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultAgentName 未配置 profile 时使用的代理名称
+const DefaultAgentName = "default"
+
+// ResolvedAgent 合并 defaults 后的单个代理运行时配置
+type ResolvedAgent struct {
+	Name               string
+	Description        string
+	Workspace          string
+	Model              string
+	MaxTokens          int
+	Temperature        float64
+	MaxToolIterations  int
+	ExecutionMode      string
+	ExecBackend        string // 为空时沿用 tools.exec
+	EnableGlobalSkills bool
+	Tools              []string
+	Skills             []string
+	AllowDomains       []string // 追加到 tools.egress 的域名限制
+	DenyDomains        []string
+}
+
+// AgentProfileNames returns the configured profile names in stable order.
+func (c *Config) AgentProfileNames() []string {
+	names := make([]string, 0, len(c.Agents.Profiles))
+	for name := range c.Agents.Profiles {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.EqualFold(name, DefaultAgentName) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveAgent merges the named profile over agents.defaults.
+// An empty name or "default" returns the defaults themselves.
+func (c *Config) ResolveAgent(name string) (ResolvedAgent, bool) {
+	defaults := c.Agents.Defaults
+	resolved := ResolvedAgent{
+		Name:               DefaultAgentName,
+		Workspace:          defaults.Workspace,
+		Model:              defaults.Model,
+		MaxTokens:          defaults.MaxTokens,
+		Temperature:        defaults.Temperature,
+		MaxToolIterations:  defaults.MaxToolIterations,
+		ExecutionMode:      NormalizeExecutionMode(defaults.ExecutionMode),
+		EnableGlobalSkills: defaults.EnableGlobalSkills,
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, DefaultAgentName) {
+		return resolved, true
+	}
+
+	profile, ok := c.lookupAgentProfile(name)
+	if !ok {
+		return resolved, false
+	}
+
+	resolved.Name = name
+	resolved.Description = strings.TrimSpace(profile.Description)
+	if ws := strings.TrimSpace(profile.Workspace); ws != "" {
+		resolved.Workspace = ws
+	}
+	if model := strings.TrimSpace(profile.Model); model != "" {
+		resolved.Model = model
+	}
+	if profile.MaxTokens > 0 {
+		resolved.MaxTokens = profile.MaxTokens
+	}
+	if profile.Temperature != nil {
+		resolved.Temperature = *profile.Temperature
+	}
+	if profile.MaxToolIterations > 0 {
+		resolved.MaxToolIterations = profile.MaxToolIterations
+	}
+	if strings.TrimSpace(profile.ExecutionMode) != "" {
+		resolved.ExecutionMode = NormalizeExecutionMode(profile.ExecutionMode)
+	}
+	if backend := strings.TrimSpace(profile.ExecBackend); backend != "" {
+		resolved.ExecBackend = backend
+	}
+	resolved.Tools = trimNonEmpty(profile.Tools)
+	resolved.Skills = trimNonEmpty(profile.Skills)
+	resolved.AllowDomains = trimNonEmpty(profile.AllowDomains)
+	resolved.DenyDomains = trimNonEmpty(profile.DenyDomains)
+	return resolved, true
+}
+
+// MatchAgentRoute returns the profile name of the first route matching the
+// inbound channel/chat/sender, or "" when no route applies.
+func (c *Config) MatchAgentRoute(channel, chatID, sender string) string {
+	return MatchAgentRoute(c.Agents.Routes, channel, chatID, sender)
+}
+
+// MatchAgentRoute evaluates routes in order; empty route fields act as wildcards.
+func MatchAgentRoute(routes []AgentRoute, channel, chatID, sender string) string {
+	channel = strings.TrimSpace(channel)
+	chatID = strings.TrimSpace(chatID)
+	sender = strings.TrimSpace(sender)
+
+	for _, route := range routes {
+		agentName := strings.TrimSpace(route.Agent)
+		if agentName == "" {
+			continue
+		}
+		if !routeFieldMatches(route.Channel, channel) {
+			continue
+		}
+		if !routeFieldMatches(route.ChatID, chatID) {
+			continue
+		}
+		if !routeFieldMatches(route.Sender, sender) {
+			continue
+		}
+		return agentName
+	}
+	return ""
+}
+
+func routeFieldMatches(pattern, value string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	return strings.EqualFold(pattern, value)
+}
+
+func (c *Config) lookupAgentProfile(name string) (AgentProfile, bool) {
+	if profile, ok := c.Agents.Profiles[name]; ok {
+		return profile, true
+	}
+	for key, profile := range c.Agents.Profiles {
+		if strings.EqualFold(strings.TrimSpace(key), name) {
+			return profile, true
+		}
+	}
+	return AgentProfile{}, false
+}
+
+func trimNonEmpty(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
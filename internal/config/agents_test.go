@@ -0,0 +1,66 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveAgentMergesProfileOverDefaults(t *testing.T) {
+	cfg := DefaultConfig()
+	temp := 0.2
+	cfg.Agents.Profiles = map[string]AgentProfile{
+		"research": {
+			Model:       "openai/gpt-4o",
+			Temperature: &temp,
+			Tools:       []string{" web_search ", "", "web_fetch"},
+			Skills:      []string{"summarize"},
+			DenyDomains: []string{" social.example "},
+		},
+	}
+
+	resolved, ok := cfg.ResolveAgent("research")
+	require.True(t, ok)
+	assert.Equal(t, "research", resolved.Name)
+	assert.Equal(t, "openai/gpt-4o", resolved.Model)
+	assert.Equal(t, 0.2, resolved.Temperature)
+	assert.Equal(t, cfg.Agents.Defaults.Workspace, resolved.Workspace)
+	assert.Equal(t, cfg.Agents.Defaults.MaxTokens, resolved.MaxTokens)
+	assert.Equal(t, []string{"web_search", "web_fetch"}, resolved.Tools)
+	assert.Equal(t, []string{"summarize"}, resolved.Skills)
+	assert.Equal(t, []string{"social.example"}, resolved.DenyDomains)
+	assert.Empty(t, resolved.AllowDomains)
+
+	def, ok := cfg.ResolveAgent("")
+	require.True(t, ok)
+	assert.Equal(t, DefaultAgentName, def.Name)
+	assert.Equal(t, cfg.Agents.Defaults.Model, def.Model)
+
+	_, ok = cfg.ResolveAgent("missing")
+	assert.False(t, ok)
+}
+
+func TestMatchAgentRouteFirstMatchWins(t *testing.T) {
+	routes := []AgentRoute{
+		{Agent: "family", Channel: "whatsapp", ChatID: "family-group"},
+		{Agent: "coder", Channel: "telegram", Sender: "alice"},
+		{Agent: "research", Channel: "telegram"},
+	}
+
+	assert.Equal(t, "family", MatchAgentRoute(routes, "whatsapp", "family-group", "bob"))
+	assert.Equal(t, "", MatchAgentRoute(routes, "whatsapp", "other", "bob"))
+	assert.Equal(t, "coder", MatchAgentRoute(routes, "telegram", "42", "Alice"))
+	assert.Equal(t, "research", MatchAgentRoute(routes, "telegram", "42", "carol"))
+	assert.Equal(t, "", MatchAgentRoute(routes, "slack", "42", "alice"))
+}
+
+func TestAgentProfileNamesSkipsDefault(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Agents.Profiles = map[string]AgentProfile{
+		"zeta":    {},
+		"alpha":   {},
+		"default": {},
+	}
+	assert.Equal(t, []string{"alpha", "zeta"}, cfg.AgentProfileNames())
+}
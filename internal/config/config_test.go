@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/Lichas/maxclaw/internal/providers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -117,6 +118,30 @@ func TestGetAPIBase(t *testing.T) {
 	}
 }
 
+func TestOllamaNeedsNoAPIKey(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, providers.LocalAPIKey, cfg.GetAPIKey("ollama/deepseek-r1:8b"))
+	assert.Equal(t, "http://localhost:11434", cfg.GetAPIBase("ollama/qwen3:8b"))
+
+	cfg.Providers.Ollama = ProviderConfig{APIKey: "proxy-token", APIBase: "http://gpu-box:11434"}
+	assert.Equal(t, "proxy-token", cfg.GetAPIKey("ollama/qwen3:8b"))
+	assert.Equal(t, "http://gpu-box:11434", cfg.GetAPIBase("ollama/qwen3:8b"))
+}
+
+func TestReasoningForModel(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Agents.Defaults.Model = "anthropic/claude-sonnet-4-5"
+	cfg.Providers.Anthropic.Models = []ProviderModelConfig{
+		{ID: "claude-sonnet-4-5", Enabled: true, Reasoning: &providers.ReasoningConfig{BudgetTokens: 8000}},
+	}
+	cfg.Providers.OpenAI.Models = []ProviderModelConfig{{ID: "gpt-4.1", Enabled: true}}
+
+	assert.Equal(t, 8000, cfg.ReasoningFor("anthropic/claude-sonnet-4-5").BudgetTokens)
+	assert.Equal(t, 8000, cfg.ReasoningFor("").BudgetTokens)
+	assert.False(t, cfg.ReasoningFor("openai/gpt-4.1").Enabled())
+	assert.False(t, cfg.ReasoningFor("deepseek-reasoner").Enabled())
+}
+
 func TestGetAPIBaseMiniMaxDefault(t *testing.T) {
 	cfg := DefaultConfig()
 	got := cfg.GetAPIBase("minimax/MiniMax-M2")
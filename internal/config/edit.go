@@ -0,0 +1,147 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// GetValue 读取配置项：优先取 config.json 中的原始值（密钥引用不会被解析），缺省时回退到默认配置
+func GetValue(path string) (any, error) {
+	segs, err := parseConfigPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := typeAtConfigPath(segs); !ok {
+		return nil, fmt.Errorf("unknown config key %q", path)
+	}
+
+	raw, err := readRawConfig(GetConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := lookupConfigPath(raw, segs); ok {
+		return value, nil
+	}
+
+	var defaults any
+	data, err := json.Marshal(DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &defaults); err != nil {
+		return nil, err
+	}
+	if value, ok := lookupConfigPath(defaults, segs); ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("config key %q is not set", path)
+}
+
+// SetValue 修改单个配置项并写回 config.json；value 按目标字段类型解析，
+// 写入前整体校验，未知字段之外的任何错误都会拒绝写入
+func SetValue(path, value string) error {
+	segs, err := parseConfigPath(path)
+	if err != nil {
+		return err
+	}
+	t, ok := typeAtConfigPath(segs)
+	if !ok {
+		return fmt.Errorf("unknown config key %q", path)
+	}
+	parsed, err := parseValueForType(t, value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	configPath := GetConfigPath()
+	raw, err := readRawConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if err := setConfigPath(raw, segs, parsed); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	// 密钥引用只在 gateway 的运行环境中解析，这里不要求其可用
+	_, errs := validateData(data, false)
+	if errs = blockingErrors(errs); len(errs) > 0 {
+		return errs
+	}
+	if err := os.MkdirAll(GetConfigDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return writeConfigFile(configPath, data)
+}
+
+// readRawConfig 读取 config.json 为通用结构，文件不存在时返回空对象
+func readRawConfig(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]any{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	raw := map[string]any{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return raw, nil
+}
+
+// parseValueForType 将命令行字符串解析为字段类型对应的 JSON 值；
+// 字符串字段原样保存，其余类型按 JSON 解析
+func parseValueForType(t reflect.Type, value string) (any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false, got %q", value)
+		}
+		return b, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected an integer, got %q", value)
+		}
+		return n, nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got %q", value)
+		}
+		return f, nil
+	}
+
+	// 切片、map 与结构体接受 JSON；字符串切片也可写成逗号分隔
+	var parsed any
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String {
+			items := []any{}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			return items, nil
+		}
+		return nil, fmt.Errorf("expected JSON for %s: %w", t, err)
+	}
+	return parsed, nil
+}
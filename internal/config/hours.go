@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HoursWindow 一天中的时段，以从 0 点起的分钟数表示；End 小于 Start 时跨越午夜
+type HoursWindow struct {
+	Start int
+	End   int
+}
+
+// ParseHoursWindow 解析 "HH:MM-HH:MM"，结束时间可写 24:00
+func ParseHoursWindow(value string) (HoursWindow, error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(value), "-")
+	if !ok {
+		return HoursWindow{}, fmt.Errorf("%q must look like HH:MM-HH:MM", value)
+	}
+	var w HoursWindow
+	var err error
+	if w.Start, err = parseClock(start); err != nil || w.Start == 24*60 {
+		return HoursWindow{}, fmt.Errorf("%q must look like HH:MM-HH:MM", value)
+	}
+	if w.End, err = parseClock(end); err != nil {
+		return HoursWindow{}, fmt.Errorf("%q must look like HH:MM-HH:MM", value)
+	}
+	if w.Start == w.End {
+		return HoursWindow{}, fmt.Errorf("%q is an empty window", value)
+	}
+	return w, nil
+}
+
+func parseClock(value string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok || len(m) != 2 || len(h) == 0 || len(h) > 2 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	hour, err := strconv.Atoi(h)
+	if err != nil {
+		return 0, err
+	}
+	minute, err := strconv.Atoi(m)
+	if err != nil {
+		return 0, err
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return hour*60 + minute, nil
+}
+
+// Contains t 的本地时刻是否落在时段内
+func (w HoursWindow) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start < w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHoursWindow(t *testing.T) {
+	at := func(clock string) time.Time {
+		parsed, err := time.Parse("15:04", clock)
+		require.NoError(t, err)
+		return parsed
+	}
+
+	day, err := ParseHoursWindow("08:00-22:00")
+	require.NoError(t, err)
+	assert.True(t, day.Contains(at("08:00")))
+	assert.True(t, day.Contains(at("21:59")))
+	assert.False(t, day.Contains(at("22:00")))
+	assert.False(t, day.Contains(at("07:59")))
+
+	night, err := ParseHoursWindow(" 22:30 - 7:00 ")
+	require.NoError(t, err)
+	assert.True(t, night.Contains(at("23:00")))
+	assert.True(t, night.Contains(at("06:59")))
+	assert.False(t, night.Contains(at("12:00")))
+
+	all, err := ParseHoursWindow("00:00-24:00")
+	require.NoError(t, err)
+	assert.True(t, all.Contains(at("23:59")))
+
+	for _, bad := range []string{"", "8-22", "08:00", "25:00-01:00", "08:60-09:00", "09:00-09:00", "24:00-08:00"} {
+		_, err := ParseHoursWindow(bad)
+		assert.Error(t, err, bad)
+	}
+}
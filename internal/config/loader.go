@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/Lichas/maxclaw/internal/providers"
 )
 
 const (
@@ -64,8 +66,12 @@ func GetWorkspacePath() string {
 
 // LoadConfig 从文件加载配置
 func LoadConfig() (*Config, error) {
-	configPath := GetConfigPath()
+	return LoadConfigFile(GetConfigPath())
+}
 
+// LoadConfigFile 从指定文件加载配置，文件不存在时返回默认配置；
+// 密钥引用在此解析，解析失败的字段留空并由 Validate 报告
+func LoadConfigFile(configPath string) (*Config, error) {
 	// 如果配置文件不存在，返回默认配置
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		return DefaultConfig(), nil
@@ -76,9 +82,36 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	config, err := decodeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	finishLoad(config)
+
+	return config, nil
+}
+
+// finishLoad 归一化执行模式并应用模型目录覆盖
+func finishLoad(config *Config) {
+	config.Agents.Defaults.ExecutionMode = NormalizeExecutionMode(config.Agents.Defaults.ExecutionMode)
+	providers.SetModelOverrides(config.CatalogOverrides())
+}
+
+// decodeConfig 在默认配置上解码 JSON，合并顶层 mcpServers、展开路径并解析密钥引用
+func decodeConfig(data []byte) (*Config, error) {
+	config, err := decodeConfigRefs(data)
+	if err != nil {
+		return nil, err
+	}
+	resolveSecrets(config)
+	return config, nil
+}
+
+// decodeConfigRefs 同 decodeConfig，但密钥引用保持原样
+func decodeConfigRefs(data []byte) (*Config, error) {
 	config := DefaultConfig()
 	if err := json.Unmarshal(data, config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return nil, err
 	}
 
 	// 兼容 Claude Desktop / Cursor 风格的顶层 mcpServers 配置
@@ -99,7 +132,10 @@ func LoadConfig() (*Config, error) {
 
 	// Expand workspace path (supports ~ and $HOME)
 	config.Agents.Defaults.Workspace = expandPath(config.Agents.Defaults.Workspace)
-	config.Agents.Defaults.ExecutionMode = NormalizeExecutionMode(config.Agents.Defaults.ExecutionMode)
+	for name, profile := range config.Agents.Profiles {
+		profile.Workspace = expandPath(profile.Workspace)
+		config.Agents.Profiles[name] = profile
+	}
 
 	return config, nil
 }
@@ -139,14 +175,20 @@ func SaveConfig(config *Config) error {
 	}
 
 	configPath := GetConfigPath()
-	data, err := json.MarshalIndent(config, "", "  ")
+	// 未被修改的密钥字段写回原始引用，避免把解析后的明文落盘
+	toSave, err := withSecretRefs(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	data, err := json.MarshalIndent(toSave, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, data, 0600); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	if err := writeConfigFile(configPath, data); err != nil {
+		return err
 	}
+	providers.SetModelOverrides(config.CatalogOverrides())
 
 	return nil
 }
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// pathSegment JSON 路径中的一段：对象键或数组下标
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseConfigPath 解析 agents.routes[0].agent 形式的路径
+func parseConfigPath(path string) ([]pathSegment, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("empty config path")
+	}
+	var segs []pathSegment
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" && (len(segs) == 0 || rest == "") {
+			return nil, fmt.Errorf("invalid config path %q", path)
+		}
+		if key != "" {
+			segs = append(segs, pathSegment{key: key})
+		}
+		for rest != "" {
+			num, tail, ok := strings.Cut(rest, "]")
+			index, err := strconv.Atoi(num)
+			if !ok || err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index in config path %q", path)
+			}
+			segs = append(segs, pathSegment{index: index, isIndex: true})
+			if tail == "" {
+				break
+			}
+			if !strings.HasPrefix(tail, "[") {
+				return nil, fmt.Errorf("invalid config path %q", path)
+			}
+			rest = tail[1:]
+		}
+	}
+	return segs, nil
+}
+
+// lookupConfigPath 在 JSON 解码后的通用结构中按路径取值
+func lookupConfigPath(root any, segs []pathSegment) (any, bool) {
+	cur := root
+	for _, seg := range segs {
+		if seg.isIndex {
+			list, ok := cur.([]any)
+			if !ok || seg.index >= len(list) {
+				return nil, false
+			}
+			cur = list[seg.index]
+			continue
+		}
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[seg.key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// setConfigPath 按路径写入值，缺失的中间对象自动创建；下标最多比现有长度大 1（追加）
+func setConfigPath(root map[string]any, segs []pathSegment, value any) error {
+	if len(segs) == 0 || segs[0].isIndex {
+		return fmt.Errorf("config path must start with a key")
+	}
+	_, err := setPathValue(root, segs, value)
+	return err
+}
+
+func setPathValue(cur any, segs []pathSegment, value any) (any, error) {
+	if len(segs) == 0 {
+		return value, nil
+	}
+	seg := segs[0]
+	if seg.isIndex {
+		list, ok := cur.([]any)
+		if cur != nil && !ok {
+			return nil, fmt.Errorf("[%d]: not an array", seg.index)
+		}
+		if seg.index > len(list) {
+			return nil, fmt.Errorf("[%d]: index out of range (length %d)", seg.index, len(list))
+		}
+		var child any
+		if seg.index < len(list) {
+			child = list[seg.index]
+		}
+		next, err := setPathValue(child, segs[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		if seg.index == len(list) {
+			return append(list, next), nil
+		}
+		list[seg.index] = next
+		return list, nil
+	}
+
+	obj, ok := cur.(map[string]any)
+	if cur != nil && !ok {
+		return nil, fmt.Errorf("%s: parent is not an object", seg.key)
+	}
+	if obj == nil {
+		obj = map[string]any{}
+	}
+	next, err := setPathValue(obj[seg.key], segs[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	obj[seg.key] = next
+	return obj, nil
+}
+
+// typeAtConfigPath 返回路径在 Config 中对应的 Go 类型；未知字段返回 false
+func typeAtConfigPath(segs []pathSegment) (reflect.Type, bool) {
+	t := reflect.TypeOf(Config{})
+	for _, seg := range segs {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch {
+		case seg.isIndex:
+			if t.Kind() != reflect.Slice {
+				return nil, false
+			}
+			t = t.Elem()
+		case t == providersConfigType:
+			t = reflect.TypeOf(ProviderConfig{})
+		case t.Kind() == reflect.Map:
+			t = t.Elem()
+		case t.Kind() == reflect.Struct:
+			field, ok := fieldByJSONName(t, seg.key)
+			if !ok {
+				return nil, false
+			}
+			t = field.Type
+		default:
+			return nil, false
+		}
+	}
+	return t, true
+}
+
+func fieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && jsonFieldName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
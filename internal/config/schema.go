@@ -3,17 +3,23 @@ package config
 import (
 	"encoding/json"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/Lichas/maxclaw/internal/providers"
 )
 
 // ProviderConfig  LLM 提供商配置
 type ProviderConfig struct {
-	APIKey    string                `json:"apiKey" mapstructure:"apiKey"`
+	APIKey    string                `json:"apiKey" mapstructure:"apiKey" secret:"true"`
 	APIBase   string                `json:"apiBase,omitempty" mapstructure:"apiBase"`
 	APIFormat string                `json:"apiFormat,omitempty" mapstructure:"apiFormat"`
 	Models    []ProviderModelConfig `json:"models,omitempty" mapstructure:"models"`
+	// APIKeys 额外的 API Key，与 apiKey 一起轮换使用，单个 key 限流或失效时自动切换
+	APIKeys []string `json:"apiKeys,omitempty" mapstructure:"apiKeys" secret:"true"`
+	// SafetySettings 仅 Gemini 原生接口使用：类别 → 阈值，如 {"dangerous_content": "BLOCK_ONLY_HIGH"}
+	SafetySettings map[string]string `json:"safetySettings,omitempty" mapstructure:"safetySettings"`
 }
 
 type ProviderModelConfig struct {
@@ -22,6 +28,12 @@ type ProviderModelConfig struct {
 	MaxTokens          int    `json:"maxTokens,omitempty" mapstructure:"maxTokens"`
 	Enabled            bool   `json:"enabled" mapstructure:"enabled"`
 	SupportsImageInput *bool  `json:"supportsImageInput,omitempty" mapstructure:"supportsImageInput"`
+	// ContextWindow 上下文窗口（token），覆盖模型目录
+	ContextWindow int `json:"contextWindow,omitempty" mapstructure:"contextWindow"`
+	// Pricing 价格（美元 / 百万 token），覆盖模型目录
+	Pricing *providers.ModelPricing `json:"pricing,omitempty" mapstructure:"pricing"`
+	// Reasoning 推理模型配置，如 {"effort": "high"} 或 {"budgetTokens": 8000}
+	Reasoning *providers.ReasoningConfig `json:"reasoning,omitempty" mapstructure:"reasoning"`
 }
 
 // ChannelsConfig 聊天频道配置
@@ -39,7 +51,7 @@ type ChannelsConfig struct {
 // TelegramConfig Telegram 配置
 type TelegramConfig struct {
 	Enabled   bool     `json:"enabled" mapstructure:"enabled"`
-	Token     string   `json:"token" mapstructure:"token"`
+	Token     string   `json:"token" mapstructure:"token" secret:"true"`
 	AllowFrom []string `json:"allowFrom" mapstructure:"allowFrom"`
 	Proxy     string   `json:"proxy,omitempty" mapstructure:"proxy"`
 }
@@ -47,7 +59,7 @@ type TelegramConfig struct {
 // DiscordConfig Discord 配置
 type DiscordConfig struct {
 	Enabled   bool     `json:"enabled" mapstructure:"enabled"`
-	Token     string   `json:"token" mapstructure:"token"`
+	Token     string   `json:"token" mapstructure:"token" secret:"true"`
 	AllowFrom []string `json:"allowFrom" mapstructure:"allowFrom"`
 }
 
@@ -55,7 +67,7 @@ type DiscordConfig struct {
 type WhatsAppConfig struct {
 	Enabled     bool     `json:"enabled" mapstructure:"enabled"`
 	BridgeURL   string   `json:"bridgeUrl,omitempty" mapstructure:"bridgeUrl"`
-	BridgeToken string   `json:"bridgeToken,omitempty" mapstructure:"bridgeToken"`
+	BridgeToken string   `json:"bridgeToken,omitempty" mapstructure:"bridgeToken" secret:"true"`
 	AllowFrom   []string `json:"allowFrom" mapstructure:"allowFrom"`
 	AllowSelf   bool     `json:"allowSelf,omitempty" mapstructure:"allowSelf"`
 }
@@ -72,8 +84,8 @@ type WebSocketConfig struct {
 // SlackConfig Slack Socket Mode 配置
 type SlackConfig struct {
 	Enabled   bool     `json:"enabled" mapstructure:"enabled"`
-	BotToken  string   `json:"botToken,omitempty" mapstructure:"botToken"`
-	AppToken  string   `json:"appToken,omitempty" mapstructure:"appToken"`
+	BotToken  string   `json:"botToken,omitempty" mapstructure:"botToken" secret:"true"`
+	AppToken  string   `json:"appToken,omitempty" mapstructure:"appToken" secret:"true"`
 	AllowFrom []string `json:"allowFrom" mapstructure:"allowFrom"`
 }
 
@@ -84,13 +96,13 @@ type EmailConfig struct {
 	IMAPHost            string   `json:"imapHost,omitempty" mapstructure:"imapHost"`
 	IMAPPort            int      `json:"imapPort,omitempty" mapstructure:"imapPort"`
 	IMAPUsername        string   `json:"imapUsername,omitempty" mapstructure:"imapUsername"`
-	IMAPPassword        string   `json:"imapPassword,omitempty" mapstructure:"imapPassword"`
+	IMAPPassword        string   `json:"imapPassword,omitempty" mapstructure:"imapPassword" secret:"true"`
 	IMAPMailbox         string   `json:"imapMailbox,omitempty" mapstructure:"imapMailbox"`
 	IMAPUseSSL          bool     `json:"imapUseSSL,omitempty" mapstructure:"imapUseSSL"`
 	SMTPHost            string   `json:"smtpHost,omitempty" mapstructure:"smtpHost"`
 	SMTPPort            int      `json:"smtpPort,omitempty" mapstructure:"smtpPort"`
 	SMTPUsername        string   `json:"smtpUsername,omitempty" mapstructure:"smtpUsername"`
-	SMTPPassword        string   `json:"smtpPassword,omitempty" mapstructure:"smtpPassword"`
+	SMTPPassword        string   `json:"smtpPassword,omitempty" mapstructure:"smtpPassword" secret:"true"`
 	SMTPUseTLS          bool     `json:"smtpUseTLS,omitempty" mapstructure:"smtpUseTLS"`
 	SMTPUseSSL          bool     `json:"smtpUseSSL,omitempty" mapstructure:"smtpUseSSL"`
 	FromAddress         string   `json:"fromAddress,omitempty" mapstructure:"fromAddress"`
@@ -104,9 +116,9 @@ type EmailConfig struct {
 type QQConfig struct {
 	Enabled     bool     `json:"enabled" mapstructure:"enabled"`
 	AppID       string   `json:"appId,omitempty" mapstructure:"appId"`
-	AppSecret   string   `json:"appSecret,omitempty" mapstructure:"appSecret"`
+	AppSecret   string   `json:"appSecret,omitempty" mapstructure:"appSecret" secret:"true"`
 	WSURL       string   `json:"wsUrl,omitempty" mapstructure:"wsUrl"`
-	AccessToken string   `json:"accessToken,omitempty" mapstructure:"accessToken"`
+	AccessToken string   `json:"accessToken,omitempty" mapstructure:"accessToken" secret:"true"`
 	ListenAddr  string   `json:"listenAddr,omitempty" mapstructure:"listenAddr"`
 	WebhookPath string   `json:"webhookPath,omitempty" mapstructure:"webhookPath"`
 	AllowFrom   []string `json:"allowFrom" mapstructure:"allowFrom"`
@@ -116,8 +128,8 @@ type QQConfig struct {
 type FeishuConfig struct {
 	Enabled           bool     `json:"enabled" mapstructure:"enabled"`
 	AppID             string   `json:"appId,omitempty" mapstructure:"appId"`
-	AppSecret         string   `json:"appSecret,omitempty" mapstructure:"appSecret"`
-	VerificationToken string   `json:"verificationToken,omitempty" mapstructure:"verificationToken"`
+	AppSecret         string   `json:"appSecret,omitempty" mapstructure:"appSecret" secret:"true"`
+	VerificationToken string   `json:"verificationToken,omitempty" mapstructure:"verificationToken" secret:"true"`
 	ListenAddr        string   `json:"listenAddr,omitempty" mapstructure:"listenAddr"`
 	WebhookPath       string   `json:"webhookPath,omitempty" mapstructure:"webhookPath"`
 	AllowFrom         []string `json:"allowFrom" mapstructure:"allowFrom"`
@@ -135,15 +147,64 @@ type AgentDefaults struct {
 	GlobalSkillsPaths  []string `json:"globalSkillsPaths,omitempty" mapstructure:"globalSkillsPaths"`
 }
 
+// AgentProfile 命名代理配置，未设置的字段回退到 defaults
+type AgentProfile struct {
+	Description       string   `json:"description,omitempty" mapstructure:"description"`
+	Workspace         string   `json:"workspace,omitempty" mapstructure:"workspace"`
+	Model             string   `json:"model,omitempty" mapstructure:"model"`
+	MaxTokens         int      `json:"maxTokens,omitempty" mapstructure:"maxTokens"`
+	Temperature       *float64 `json:"temperature,omitempty" mapstructure:"temperature"`
+	MaxToolIterations int      `json:"maxToolIterations,omitempty" mapstructure:"maxToolIterations"`
+	ExecutionMode     string   `json:"executionMode,omitempty" mapstructure:"executionMode"`
+	ExecBackend       string   `json:"execBackend,omitempty" mapstructure:"execBackend"`
+	Tools             []string `json:"tools,omitempty" mapstructure:"tools"`
+	Skills            []string `json:"skills,omitempty" mapstructure:"skills"`
+	AllowDomains      []string `json:"allowDomains,omitempty" mapstructure:"allowDomains"`
+	DenyDomains       []string `json:"denyDomains,omitempty" mapstructure:"denyDomains"`
+}
+
+// AgentRoute 将频道/会话/发送者映射到命名代理，空字段表示任意匹配
+type AgentRoute struct {
+	Agent   string `json:"agent" mapstructure:"agent"`
+	Channel string `json:"channel,omitempty" mapstructure:"channel"`
+	ChatID  string `json:"chatId,omitempty" mapstructure:"chatId"`
+	Sender  string `json:"sender,omitempty" mapstructure:"sender"`
+}
+
 // AgentsConfig 代理配置
 type AgentsConfig struct {
-	Defaults AgentDefaults `json:"defaults" mapstructure:"defaults"`
+	Defaults AgentDefaults           `json:"defaults" mapstructure:"defaults"`
+	Profiles map[string]AgentProfile `json:"profiles,omitempty" mapstructure:"profiles"`
+	Routes   []AgentRoute            `json:"routes,omitempty" mapstructure:"routes"`
 }
 
 // WebSearchConfig 网页搜索配置
 type WebSearchConfig struct {
-	APIKey     string `json:"apiKey" mapstructure:"apiKey"`
+	// APIKey Brave Search API key（兼容旧配置，等同于 brave.apiKey）
+	APIKey     string `json:"apiKey" mapstructure:"apiKey" secret:"true"`
 	MaxResults int    `json:"maxResults" mapstructure:"maxResults"`
+	// Providers 按顺序尝试的后端；为空时使用所有已配置的后端并以 DuckDuckGo 兜底
+	Providers  []string               `json:"providers,omitempty" mapstructure:"providers"`
+	Timeout    int                    `json:"timeout,omitempty" mapstructure:"timeout"`
+	Brave      WebSearchBackendConfig `json:"brave,omitempty" mapstructure:"brave"`
+	SearXNG    WebSearchBackendConfig `json:"searxng,omitempty" mapstructure:"searxng"`
+	Tavily     WebSearchBackendConfig `json:"tavily,omitempty" mapstructure:"tavily"`
+	Bing       WebSearchBackendConfig `json:"bing,omitempty" mapstructure:"bing"`
+	Google     WebSearchBackendConfig `json:"google,omitempty" mapstructure:"google"`
+	Serper     WebSearchBackendConfig `json:"serper,omitempty" mapstructure:"serper"`
+	DuckDuckGo WebSearchBackendConfig `json:"duckduckgo,omitempty" mapstructure:"duckduckgo"`
+	// DeepFetchTop / DeepMaxChars 深度模式抓取的结果数与每页保留字符数
+	DeepFetchTop int `json:"deepFetchTop,omitempty" mapstructure:"deepFetchTop"`
+	DeepMaxChars int `json:"deepMaxChars,omitempty" mapstructure:"deepMaxChars"`
+}
+
+// WebSearchBackendConfig 单个搜索后端配置
+type WebSearchBackendConfig struct {
+	APIKey string `json:"apiKey,omitempty" mapstructure:"apiKey" secret:"true"`
+	// BaseURL 覆盖 API 地址；SearXNG 为实例地址（必填）
+	BaseURL string `json:"baseUrl,omitempty" mapstructure:"baseUrl"`
+	// EngineID Google Programmable Search 的 cx
+	EngineID string `json:"engineId,omitempty" mapstructure:"engineId"`
 }
 
 // WebFetchConfig 网页抓取配置
@@ -186,22 +247,103 @@ type WebToolsConfig struct {
 type MCPServerConfig struct {
 	Command string            `json:"command,omitempty" mapstructure:"command"`
 	Args    []string          `json:"args,omitempty" mapstructure:"args"`
-	Env     map[string]string `json:"env,omitempty" mapstructure:"env"`
+	Env     map[string]string `json:"env,omitempty" mapstructure:"env" secret:"true"`
 	URL     string            `json:"url,omitempty" mapstructure:"url"`
-	Headers map[string]string `json:"headers,omitempty" mapstructure:"headers"`
+	Headers map[string]string `json:"headers,omitempty" mapstructure:"headers" secret:"true"`
 }
 
 // ExecToolConfig Shell 执行配置
 type ExecToolConfig struct {
 	Timeout int `json:"timeout" mapstructure:"timeout"`
+	// Backend 执行后端：host（默认）/ bwrap / docker / podman
+	Backend string `json:"backend,omitempty" mapstructure:"backend"`
+	// ModeBackends 按执行模式覆盖后端，例如 {"auto": "docker"}
+	ModeBackends   map[string]string `json:"modeBackends,omitempty" mapstructure:"modeBackends"`
+	Network        *bool             `json:"network,omitempty" mapstructure:"network"`
+	CPUs           float64           `json:"cpus,omitempty" mapstructure:"cpus"`
+	MemoryMB       int               `json:"memoryMb,omitempty" mapstructure:"memoryMb"`
+	MaxOutputBytes int               `json:"maxOutputBytes,omitempty" mapstructure:"maxOutputBytes"`
+	Image          string            `json:"image,omitempty" mapstructure:"image"`
+}
+
+// EditToolConfig 文件编辑配置
+type EditToolConfig struct {
+	// RequireRead 编辑已有文件前必须在当前会话中 read_file，且文件自读取后未被修改
+	RequireRead bool `json:"requireRead,omitempty" mapstructure:"requireRead"`
+}
+
+// ToolArgRuleConfig 工具参数条件（正则 / 路径前缀 / 域名，negate 取反）
+type ToolArgRuleConfig struct {
+	Pattern      string   `json:"pattern,omitempty" mapstructure:"pattern"`
+	PathPrefixes []string `json:"pathPrefixes,omitempty" mapstructure:"pathPrefixes"`
+	Domains      []string `json:"domains,omitempty" mapstructure:"domains"`
+	Negate       bool     `json:"negate,omitempty" mapstructure:"negate"`
+}
+
+// ToolPolicyRuleConfig 工具策略规则，按顺序匹配，第一条命中的规则生效
+type ToolPolicyRuleConfig struct {
+	Name     string                       `json:"name,omitempty" mapstructure:"name"`
+	Effect   string                       `json:"effect" mapstructure:"effect"`
+	Tools    []string                     `json:"tools,omitempty" mapstructure:"tools"`
+	Agents   []string                     `json:"agents,omitempty" mapstructure:"agents"`
+	Channels []string                     `json:"channels,omitempty" mapstructure:"channels"`
+	Senders  []string                     `json:"senders,omitempty" mapstructure:"senders"`
+	Args     map[string]ToolArgRuleConfig `json:"args,omitempty" mapstructure:"args"`
+	Reason   string                       `json:"reason,omitempty" mapstructure:"reason"`
+}
+
+// ToolPolicyConfig 声明式工具策略
+type ToolPolicyConfig struct {
+	Default string                 `json:"default,omitempty" mapstructure:"default"`
+	Rules   []ToolPolicyRuleConfig `json:"rules,omitempty" mapstructure:"rules"`
+}
+
+// EgressConfig 出站网络防护（web_fetch / browser / MCP HTTP）
+type EgressConfig struct {
+	// AllowPrivate 允许访问内网、回环和链路本地地址（默认拦截）
+	AllowPrivate bool `json:"allowPrivate,omitempty" mapstructure:"allowPrivate"`
+	// AllowCIDRs 例外放行的网段，如 "10.0.5.0/24"
+	AllowCIDRs []string `json:"allowCidrs,omitempty" mapstructure:"allowCidrs"`
+	// AllowDomains 非空时只允许这些域名（含子域名）
+	AllowDomains []string `json:"allowDomains,omitempty" mapstructure:"allowDomains"`
+	DenyDomains  []string `json:"denyDomains,omitempty" mapstructure:"denyDomains"`
+	// Proxy 出站代理，支持 http/https/socks5
+	Proxy string `json:"proxy,omitempty" mapstructure:"proxy"`
+}
+
+// EmbeddingConfig 嵌入模型（OpenAI 兼容 /embeddings 接口）
+type EmbeddingConfig struct {
+	// Provider 复用 providers 中的 apiKey / apiBase，如 "openai"、"vllm" 或自定义 provider
+	Provider string `json:"provider,omitempty" mapstructure:"provider"`
+	// APIBase / APIKey 直接指定接口地址与密钥，优先于 provider
+	APIBase   string `json:"apiBase,omitempty" mapstructure:"apiBase"`
+	APIKey    string `json:"apiKey,omitempty" mapstructure:"apiKey" secret:"true"`
+	Model     string `json:"model,omitempty" mapstructure:"model"`
+	BatchSize int    `json:"batchSize,omitempty" mapstructure:"batchSize"`
+	Timeout   int    `json:"timeout,omitempty" mapstructure:"timeout"`
+}
+
+// RetrievalConfig 工作区检索（search_workspace）
+type RetrievalConfig struct {
+	// Embedding 未配置 model 时只做关键词检索
+	Embedding EmbeddingConfig `json:"embedding,omitempty" mapstructure:"embedding"`
+	// Exclude 额外的 gitignore 风格排除规则
+	Exclude       []string `json:"exclude,omitempty" mapstructure:"exclude"`
+	MaxFileSizeKB int      `json:"maxFileSizeKb,omitempty" mapstructure:"maxFileSizeKb"`
+	MaxFiles      int      `json:"maxFiles,omitempty" mapstructure:"maxFiles"`
+	ChunkChars    int      `json:"chunkChars,omitempty" mapstructure:"chunkChars"`
 }
 
 // ToolsConfig 工具配置
 type ToolsConfig struct {
 	Web                 WebToolsConfig             `json:"web" mapstructure:"web"`
 	Exec                ExecToolConfig             `json:"exec" mapstructure:"exec"`
+	Edit                EditToolConfig             `json:"edit,omitempty" mapstructure:"edit"`
 	RestrictToWorkspace bool                       `json:"restrictToWorkspace" mapstructure:"restrictToWorkspace"`
 	MCPServers          map[string]MCPServerConfig `json:"mcpServers,omitempty" mapstructure:"mcpServers"`
+	Policy              ToolPolicyConfig           `json:"policy,omitempty" mapstructure:"policy"`
+	Egress              EgressConfig               `json:"egress,omitempty" mapstructure:"egress"`
+	Retrieval           RetrievalConfig            `json:"retrieval,omitempty" mapstructure:"retrieval"`
 }
 
 // GatewayConfig 网关配置
@@ -223,6 +365,7 @@ type ProvidersConfig struct {
 	Moonshot   ProviderConfig            `json:"moonshot" mapstructure:"moonshot"`
 	MiniMax    ProviderConfig            `json:"minimax" mapstructure:"minimax"`
 	VLLM       ProviderConfig            `json:"vllm" mapstructure:"vllm"`
+	Ollama     ProviderConfig            `json:"ollama" mapstructure:"ollama"`
 	Custom     map[string]ProviderConfig `json:"-" mapstructure:"custom"`
 }
 
@@ -240,6 +383,7 @@ func (p ProvidersConfig) ToMap() map[string]ProviderConfig {
 		"moonshot":   p.Moonshot,
 		"minimax":    p.MiniMax,
 		"vllm":       p.VLLM,
+		"ollama":     p.Ollama,
 	}
 	for k, v := range p.Custom {
 		out[k] = v
@@ -262,6 +406,7 @@ func ProvidersConfigFromMap(m map[string]ProviderConfig) ProvidersConfig {
 		"moonshot":   true,
 		"minimax":    true,
 		"vllm":       true,
+		"ollama":     true,
 	}
 	for k, v := range m {
 		if !known[k] {
@@ -280,6 +425,7 @@ func ProvidersConfigFromMap(m map[string]ProviderConfig) ProvidersConfig {
 		Moonshot:   m["moonshot"],
 		MiniMax:    m["minimax"],
 		VLLM:       m["vllm"],
+		Ollama:     m["ollama"],
 		Custom:     custom,
 	}
 }
@@ -307,6 +453,141 @@ type Config struct {
 	Providers ProvidersConfig `json:"providers" mapstructure:"providers"`
 	Gateway   GatewayConfig   `json:"gateway" mapstructure:"gateway"`
 	Tools     ToolsConfig     `json:"tools" mapstructure:"tools"`
+	// ModelCatalog 覆盖或补充内置模型目录（上下文窗口、能力、价格）
+	ModelCatalog []providers.ModelInfo `json:"modelCatalog,omitempty" mapstructure:"modelCatalog"`
+	// Routing 多后端负载均衡、熔断与按用途选择模型
+	Routing RoutingConfig `json:"routing" mapstructure:"routing"`
+	// Telemetry 指标与链路追踪
+	Telemetry TelemetryConfig `json:"telemetry" mapstructure:"telemetry"`
+	// Logging 结构化日志级别与轮转策略
+	Logging LoggingConfig `json:"logging" mapstructure:"logging"`
+	// Memory 长期记忆（memory/MEMORY.md）整理策略
+	Memory MemoryConfig `json:"memory" mapstructure:"memory"`
+	// Heartbeat 按 memory/heartbeat.md 定期主动检查并提醒
+	Heartbeat HeartbeatConfig `json:"heartbeat" mapstructure:"heartbeat"`
+
+	// secretRefs / secretErrors 加载时解析的密钥引用及失败项
+	secretRefs   []secretRef
+	secretErrors []ValidationError
+}
+
+// LoggingConfig 日志配置；文件位于 ~/.maxclaw/logs，零值使用默认值
+type LoggingConfig struct {
+	// Level debug / info / warn / error，默认 info
+	Level string `json:"level,omitempty" mapstructure:"level"`
+	// MaxSizeMB 单个文件超过该大小后轮转，默认 20；此外每天也会轮转一次
+	MaxSizeMB int `json:"maxSizeMB,omitempty" mapstructure:"maxSizeMB"`
+	// MaxBackups 每个日志保留的历史文件数，默认 5
+	MaxBackups int `json:"maxBackups,omitempty" mapstructure:"maxBackups"`
+	// MaxAgeDays 历史文件保留天数，默认 14
+	MaxAgeDays int `json:"maxAgeDays,omitempty" mapstructure:"maxAgeDays"`
+}
+
+// MemoryConfig 长期记忆整理配置；零值使用默认值
+type MemoryConfig struct {
+	// MaxTokens MEMORY.md 的 token 预算，默认 4000
+	MaxTokens int `json:"maxTokens,omitempty" mapstructure:"maxTokens"`
+	// KeepDailyDays MEMORY.md 中保留的每日摘要天数，更早的归档到 HISTORY.md，默认 7
+	KeepDailyDays int `json:"keepDailyDays,omitempty" mapstructure:"keepDailyDays"`
+	// AutoCurate gateway 写入每日摘要后自动整理，默认开启
+	AutoCurate *bool `json:"autoCurate,omitempty" mapstructure:"autoCurate"`
+	// UseLLM 整理时用 summary 用途的模型合并相近条目，默认开启；关闭后只使用确定性规则
+	UseLLM *bool `json:"useLLM,omitempty" mapstructure:"useLLM"`
+	// Identities 用户身份 → 各频道账号（channel:senderId），同一身份共享用户作用域的记忆
+	Identities map[string][]string `json:"identities,omitempty" mapstructure:"identities"`
+}
+
+// AutoCurateEnabled 是否在 gateway 中自动整理
+func (m MemoryConfig) AutoCurateEnabled() bool {
+	return m.AutoCurate == nil || *m.AutoCurate
+}
+
+// LLMEnabled 整理时是否使用 LLM
+func (m MemoryConfig) LLMEnabled() bool {
+	return m.UseLLM == nil || *m.UseLLM
+}
+
+// HeartbeatConfig 心跳检查配置；默认关闭，零值字段使用默认值
+type HeartbeatConfig struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// IntervalMinutes 检查间隔，默认 30
+	IntervalMinutes int `json:"intervalMinutes,omitempty" mapstructure:"intervalMinutes"`
+	// ActiveHours 只在该时段内检查，如 "08:00-22:00"；留空时全天检查
+	ActiveHours string `json:"activeHours,omitempty" mapstructure:"activeHours"`
+	// QuietHours 该时段内不投递，提醒推迟到时段结束后，如 "22:30-07:00"
+	QuietHours string `json:"quietHours,omitempty" mapstructure:"quietHours"`
+	// Timezone 时段使用的 IANA 时区，默认本机时区
+	Timezone string `json:"timezone,omitempty" mapstructure:"timezone"`
+	// Channel / To 提醒投递的频道与会话；留空时写入 Web UI 通知
+	Channel string `json:"channel,omitempty" mapstructure:"channel"`
+	To      string `json:"to,omitempty" mapstructure:"to"`
+	// DedupeHours 相同提醒在该时长内只投递一次，默认 24
+	DedupeHours int `json:"dedupeHours,omitempty" mapstructure:"dedupeHours"`
+	// Prompt 覆盖默认的检查提示词
+	Prompt string `json:"prompt,omitempty" mapstructure:"prompt"`
+}
+
+// Interval 检查间隔
+func (h HeartbeatConfig) Interval() time.Duration {
+	if h.IntervalMinutes > 0 {
+		return time.Duration(h.IntervalMinutes) * time.Minute
+	}
+	return 30 * time.Minute
+}
+
+// DedupeWindow 重复提醒的抑制时长
+func (h HeartbeatConfig) DedupeWindow() time.Duration {
+	if h.DedupeHours > 0 {
+		return time.Duration(h.DedupeHours) * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Location 时段使用的时区，未设置或无效时为本机时区
+func (h HeartbeatConfig) Location() *time.Location {
+	if tz := strings.TrimSpace(h.Timezone); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// TelemetryConfig 可观测性配置；Prometheus 指标始终在 gateway 的 /metrics 暴露
+type TelemetryConfig struct {
+	Tracing TracingConfig `json:"tracing" mapstructure:"tracing"`
+}
+
+// TracingConfig OTLP/HTTP 链路导出配置
+type TracingConfig struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Endpoint 如 localhost:4318，或带路径的完整 URL
+	Endpoint    string            `json:"endpoint,omitempty" mapstructure:"endpoint"`
+	Insecure    bool              `json:"insecure,omitempty" mapstructure:"insecure"`
+	Headers     map[string]string `json:"headers,omitempty" mapstructure:"headers" secret:"true"`
+	ServiceName string            `json:"serviceName,omitempty" mapstructure:"serviceName"`
+	// SampleRatio 采样比例（0-1），留空时全部采样
+	SampleRatio float64 `json:"sampleRatio,omitempty" mapstructure:"sampleRatio"`
+}
+
+// RoutingConfig 模型路由配置，全部留空时直接使用 agents.defaults.model
+type RoutingConfig struct {
+	// Backends 主模型的后端列表，按权重轮询；为空时仅使用 agents.defaults.model
+	Backends []RoutingBackend `json:"backends,omitempty" mapstructure:"backends"`
+	// Purposes 按用途指定模型，键为 summary / title / subagent
+	Purposes map[string]string `json:"purposes,omitempty" mapstructure:"purposes"`
+	// FailureThreshold 后端连续失败多少次后熔断，默认 3
+	FailureThreshold int `json:"failureThreshold,omitempty" mapstructure:"failureThreshold"`
+	// CooldownSeconds 熔断后多久再尝试，默认 30；鉴权、欠费错误为 10 倍
+	CooldownSeconds int `json:"cooldownSeconds,omitempty" mapstructure:"cooldownSeconds"`
+}
+
+// RoutingBackend 路由后端，APIBase / APIKeys 为空时使用模型所属 provider 的配置
+type RoutingBackend struct {
+	Model   string   `json:"model" mapstructure:"model"`
+	Weight  int      `json:"weight,omitempty" mapstructure:"weight"`
+	APIBase string   `json:"apiBase,omitempty" mapstructure:"apiBase"`
+	APIKeys []string `json:"apiKeys,omitempty" mapstructure:"apiKeys" secret:"true"`
 }
 
 // DefaultConfig 返回默认配置
@@ -432,6 +713,10 @@ func (c *Config) GetAPIKey(model string) string {
 			if cfg, ok := providerMap[spec.Name]; ok && cfg.APIKey != "" {
 				return cfg.APIKey
 			}
+			// 本地服务（Ollama）无需密钥，返回占位值以免被当作未配置
+			if spec.KeyOptional {
+				return providers.LocalAPIKey
+			}
 		}
 	}
 
@@ -451,6 +736,29 @@ func (c *Config) GetAPIKey(model string) string {
 }
 
 // GetAPIBase 根据模型名称获取 API Base URL
+// GetAPIKeys 返回模型可轮换使用的全部 API Key：GetAPIKey 的结果在前，其后为同一 provider 的 apiKeys
+func (c *Config) GetAPIKeys(model string) []string {
+	primary := c.GetAPIKey(model)
+	if primary == "" {
+		return nil
+	}
+	keys := []string{primary}
+	seen := map[string]bool{primary: true}
+	for _, cfg := range c.providerConfigMap() {
+		if cfg.APIKey != primary {
+			continue
+		}
+		for _, key := range cfg.APIKeys {
+			key = strings.TrimSpace(key)
+			if key != "" && !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
 func (c *Config) GetAPIBase(model string) string {
 	if model == "" {
 		model = c.Agents.Defaults.Model
@@ -664,6 +972,51 @@ func (c *Config) SupportsImageInput(model string) bool {
 	return providers.SupportsImageInput(providers.DetectProviderName(model), model)
 }
 
+// ReasoningFor 返回模型的推理配置，未配置时为零值（不开启）
+func (c *Config) ReasoningFor(model string) providers.ReasoningConfig {
+	if model == "" {
+		model = c.Agents.Defaults.Model
+	}
+	if configured, ok := c.lookupProviderModelConfig(model); ok && configured.Reasoning != nil {
+		return *configured.Reasoning
+	}
+	return providers.ReasoningConfig{}
+}
+
+// CatalogOverrides 汇总用户对模型目录的覆盖：modelCatalog 条目在前，providers.*.models 中的单模型配置在后
+func (c *Config) CatalogOverrides() []providers.ModelInfo {
+	entries := append([]providers.ModelInfo(nil), c.ModelCatalog...)
+	providerCfgs := c.providerConfigMap()
+	names := make([]string, 0, len(providerCfgs))
+	for name := range providerCfgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, model := range providerCfgs[name].Models {
+			if strings.TrimSpace(model.ID) == "" {
+				continue
+			}
+			entry := providers.ModelInfo{
+				ID:            model.ID,
+				ContextWindow: model.ContextWindow,
+				MaxOutput:     model.MaxTokens,
+				Vision:        model.SupportsImageInput,
+				Pricing:       model.Pricing,
+			}
+			if model.Reasoning != nil && model.Reasoning.Enabled() {
+				enabled := true
+				entry.Reasoning = &enabled
+			}
+			if entry.ContextWindow == 0 && entry.MaxOutput == 0 && entry.Vision == nil && entry.Pricing == nil && entry.Reasoning == nil {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
 func (c *Config) lookupProviderModelConfig(model string) (*ProviderModelConfig, bool) {
 	inputAliases := modelAliases(model)
 	for _, providerCfg := range c.providerConfigMap() {
@@ -1,6 +1,10 @@
 package config
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/providers"
+)
 
 func TestGetAPIBaseFallsBackToFirstConfiguredProviderForRawModelID(t *testing.T) {
 	// OpenRouter models like "tencent/hy3-preview:free" don't match any
@@ -144,3 +148,48 @@ func TestSupportsImageInputFallsBackToProviderHeuristic(t *testing.T) {
 		t.Fatal("expected deepseek-vl to allow image input by fallback heuristic")
 	}
 }
+
+func TestCatalogOverridesFromConfig(t *testing.T) {
+	disabled := false
+	cfg := &Config{
+		ModelCatalog: []providers.ModelInfo{{ID: "acme-large", ContextWindow: 64000}},
+		Providers: ProvidersConfig{
+			OpenAI: ProviderConfig{
+				Models: []ProviderModelConfig{
+					{ID: "gpt-5", Enabled: true, ContextWindow: 272000, SupportsImageInput: &disabled, Pricing: &providers.ModelPricing{Input: 1, Output: 8}},
+					{ID: "gpt-5-mini", Enabled: true},
+				},
+			},
+		},
+	}
+
+	overrides := cfg.CatalogOverrides()
+	if len(overrides) != 2 {
+		t.Fatalf("expected modelCatalog entry and one provider model override, got %+v", overrides)
+	}
+
+	providers.SetModelOverrides(overrides)
+	defer providers.SetModelOverrides(nil)
+
+	info, _ := providers.LookupModel("openai", "gpt-5")
+	if info.ContextWindow != 272000 || info.MaxOutput != 128000 || info.Pricing.Input != 1 {
+		t.Fatalf("expected provider model config to override catalog, got %+v", info)
+	}
+	if cfg.SupportsImageInput("gpt-5") {
+		t.Fatal("expected provider model config to disable image input")
+	}
+	if info, ok := providers.LookupModel("unknown", "acme-large"); !ok || info.ContextWindow != 64000 {
+		t.Fatalf("expected modelCatalog entry, got %+v ok=%v", info, ok)
+	}
+}
+
+func TestGetAPIKeysIncludesExtraKeys(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Providers.OpenAI.APIKey = "sk-primary"
+	cfg.Providers.OpenAI.APIKeys = []string{"sk-secondary", "sk-primary", " ", "sk-third"}
+
+	keys := cfg.GetAPIKeys("gpt-4o-mini")
+	if len(keys) != 3 || keys[0] != "sk-primary" || keys[1] != "sk-secondary" || keys[2] != "sk-third" {
+		t.Fatalf("expected primary key first followed by deduplicated extras, got %v", keys)
+	}
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// 密钥引用：schema 中带 secret:"true" 标签的字段可以写成引用而不是明文
+//
+//	${OPENAI_API_KEY}          环境变量（可嵌在字符串中，如 "Bearer ${GITHUB_TOKEN}"）
+//	file:~/.maxclaw/secrets/tg 文件内容（去掉首尾空白）
+//	keyring:maxclaw/openai     系统钥匙串中 service=maxclaw、account=openai 的条目
+const (
+	secretFilePrefix    = "file:"
+	secretKeyringPrefix = "keyring:"
+)
+
+var secretEnvPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// keyringLookup 读取系统钥匙串，测试中可替换
+var keyringLookup = lookupKeyring
+
+// secretRef 一条已解析的引用，SaveConfig 时若值未被修改则写回原始引用
+type secretRef struct {
+	path     string
+	ref      string
+	resolved string
+}
+
+// IsSecretRef 判断 value 是否为密钥引用
+func IsSecretRef(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, secretFilePrefix) ||
+		strings.HasPrefix(value, secretKeyringPrefix) ||
+		secretEnvPattern.MatchString(value)
+}
+
+// ResolveSecret 解析密钥引用，非引用原样返回
+func ResolveSecret(value string) (string, error) {
+	trimmed := strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(trimmed, secretFilePrefix):
+		path := expandPath(strings.TrimSpace(strings.TrimPrefix(trimmed, secretFilePrefix)))
+		if path == "" {
+			return "", fmt.Errorf("empty file reference")
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case strings.HasPrefix(trimmed, secretKeyringPrefix):
+		service, account, ok := strings.Cut(strings.TrimPrefix(trimmed, secretKeyringPrefix), "/")
+		service, account = strings.TrimSpace(service), strings.TrimSpace(account)
+		if !ok || service == "" || account == "" {
+			return "", fmt.Errorf("keyring reference must look like keyring:<service>/<account>")
+		}
+		secret, err := keyringLookup(service, account)
+		if err != nil {
+			return "", fmt.Errorf("keyring %s/%s: %w", service, account, err)
+		}
+		return secret, nil
+	}
+
+	var missing []string
+	resolved := secretEnvPattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := secretEnvPattern.FindStringSubmatch(match)[1]
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return env
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
+func lookupKeyring(service, account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	default:
+		return "", fmt.Errorf("keyring is not supported on %s", runtime.GOOS)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", cmd.Args[0], msg)
+		}
+		return "", fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	secret := strings.TrimRight(string(out), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("entry not found")
+	}
+	return secret, nil
+}
+
+// resolveSecrets 就地解析 cfg 中的全部密钥引用，记录原始引用与解析失败的字段
+func resolveSecrets(cfg *Config) {
+	cfg.secretRefs = nil
+	cfg.secretErrors = nil
+	walkSecretFields(reflect.ValueOf(cfg).Elem(), "", false, func(path, value string) string {
+		if !IsSecretRef(value) {
+			return value
+		}
+		resolved, err := ResolveSecret(value)
+		if err != nil {
+			cfg.secretErrors = append(cfg.secretErrors, ValidationError{Path: path, Message: err.Error()})
+		}
+		cfg.secretRefs = append(cfg.secretRefs, secretRef{path: path, ref: value, resolved: resolved})
+		return resolved
+	})
+}
+
+// withSecretRefs 返回写回原始引用后的副本；已被改成其他值的字段保持新值
+func withSecretRefs(cfg *Config) (*Config, error) {
+	if len(cfg.secretRefs) == 0 {
+		return cfg, nil
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	clone := &Config{}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]secretRef, len(cfg.secretRefs))
+	for _, ref := range cfg.secretRefs {
+		refs[ref.path] = ref
+	}
+	walkSecretFields(reflect.ValueOf(clone).Elem(), "", false, func(path, value string) string {
+		if ref, ok := refs[path]; ok && ref.resolved == value {
+			return ref.ref
+		}
+		return value
+	})
+	return clone, nil
+}
+
+var providersConfigType = reflect.TypeOf(ProvidersConfig{})
+
+// walkSecretFields 遍历带 secret 标签的字符串（含切片与 map 中的字符串），
+// fn 返回替换后的值；path 为 JSON 路径，如 providers.openai.apiKeys[1]
+func walkSecretFields(v reflect.Value, path string, secret bool, fn func(path, value string) string) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			walkSecretFields(v.Elem(), path, secret, fn)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := jsonFieldName(field)
+			fieldPath := joinConfigPath(path, name)
+			if name == "-" {
+				// ProvidersConfig.Custom 在 JSON 中平铺到 providers 下
+				if t != providersConfigType {
+					continue
+				}
+				fieldPath = path
+			}
+			walkSecretFields(v.Field(i), fieldPath, field.Tag.Get("secret") == "true", fn)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkSecretFields(v.Index(i), fmt.Sprintf("%s[%d]", path, i), secret, fn)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			walkSecretFields(elem, joinConfigPath(path, key.String()), secret, fn)
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.String:
+		if secret && v.CanSet() {
+			v.SetString(fn(path, v.String()))
+		}
+	}
+}
+
+// jsonFieldName 返回字段的 JSON 名称，未打标签时与 encoding/json 一致使用字段名
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func joinConfigPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSecretSupportsEnvFileAndKeyring(t *testing.T) {
+	t.Setenv("MAXCLAW_TEST_TOKEN", "tok-123")
+	secretFile := filepath.Join(t.TempDir(), "discord")
+	require.NoError(t, os.WriteFile(secretFile, []byte("  dc-secret\n"), 0600))
+
+	origLookup := keyringLookup
+	defer func() { keyringLookup = origLookup }()
+	keyringLookup = func(service, account string) (string, error) {
+		if service == "maxclaw" && account == "openai" {
+			return "sk-keyring", nil
+		}
+		return "", errors.New("entry not found")
+	}
+
+	for _, tc := range []struct{ in, want string }{
+		{"plain-value", "plain-value"},
+		{"${MAXCLAW_TEST_TOKEN}", "tok-123"},
+		{"Bearer ${MAXCLAW_TEST_TOKEN}", "Bearer tok-123"},
+		{"file:" + secretFile, "dc-secret"},
+		{"keyring:maxclaw/openai", "sk-keyring"},
+	} {
+		got, err := ResolveSecret(tc.in)
+		require.NoError(t, err, tc.in)
+		assert.Equal(t, tc.want, got, tc.in)
+	}
+
+	_, err := ResolveSecret("${MAXCLAW_TEST_MISSING}")
+	assert.ErrorContains(t, err, "MAXCLAW_TEST_MISSING is not set")
+	_, err = ResolveSecret("keyring:maxclaw")
+	assert.Error(t, err)
+	_, err = ResolveSecret("keyring:maxclaw/other")
+	assert.ErrorContains(t, err, "entry not found")
+	assert.False(t, IsSecretRef("$HOME"))
+}
+
+func TestLoadConfigResolvesSecretRefsAndSavePreservesThem(t *testing.T) {
+	t.Setenv("MAXCLAW_HOME", t.TempDir())
+	t.Setenv("MAXCLAW_TEST_OPENAI", "sk-env")
+	t.Setenv("MAXCLAW_TEST_GH", "gh-token")
+	secretFile := filepath.Join(t.TempDir(), "tg")
+	require.NoError(t, os.WriteFile(secretFile, []byte("tg-secret\n"), 0600))
+
+	raw := `{
+  "providers": {
+    "openai": {"apiKey": "${MAXCLAW_TEST_OPENAI}", "apiKeys": ["plain", "${MAXCLAW_TEST_GH}"]},
+    "my-proxy": {"apiKey": "${MAXCLAW_TEST_GH}", "apiBase": "https://proxy.example.com/v1"}
+  },
+  "channels": {"telegram": {"enabled": true, "token": "file:` + secretFile + `"}},
+  "tools": {"mcpServers": {"github": {"url": "https://mcp.example.com", "headers": {"Authorization": "Bearer ${MAXCLAW_TEST_GH}"}}}}
+}`
+	require.NoError(t, os.WriteFile(GetConfigPath(), []byte(raw), 0600))
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "sk-env", cfg.Providers.OpenAI.APIKey)
+	assert.Equal(t, []string{"plain", "gh-token"}, cfg.Providers.OpenAI.APIKeys)
+	assert.Equal(t, "gh-token", cfg.Providers.Custom["my-proxy"].APIKey)
+	assert.Equal(t, "tg-secret", cfg.Channels.Telegram.Token)
+	assert.Equal(t, "Bearer gh-token", cfg.Tools.MCPServers["github"].Headers["Authorization"])
+	assert.Empty(t, cfg.Validate())
+
+	// 未修改的字段写回引用，被改掉的字段保存新值
+	cfg.Channels.Telegram.Token = "new-plain-token"
+	require.NoError(t, SaveConfig(cfg))
+	data, err := os.ReadFile(GetConfigPath())
+	require.NoError(t, err)
+	saved := string(data)
+	assert.Contains(t, saved, `"apiKey": "${MAXCLAW_TEST_OPENAI}"`)
+	assert.Contains(t, saved, `"${MAXCLAW_TEST_GH}"`)
+	assert.Contains(t, saved, `"Authorization": "Bearer ${MAXCLAW_TEST_GH}"`)
+	assert.Contains(t, saved, `"token": "new-plain-token"`)
+	assert.NotContains(t, saved, "sk-env")
+	assert.NotContains(t, saved, "gh-token")
+	assert.Equal(t, "sk-env", cfg.Providers.OpenAI.APIKey, "in-memory config keeps resolved values")
+}
+
+func TestUnresolvedSecretIsReportedByValidate(t *testing.T) {
+	t.Setenv("MAXCLAW_HOME", t.TempDir())
+	raw := `{"channels": {"discord": {"enabled": true, "token": "${MAXCLAW_TEST_UNSET_TOKEN}"}}}`
+	require.NoError(t, os.WriteFile(GetConfigPath(), []byte(raw), 0600))
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Channels.Discord.Token)
+
+	errs := cfg.Validate()
+	require.NotEmpty(t, errs)
+	var paths []string
+	for _, e := range errs {
+		paths = append(paths, e.Path)
+	}
+	assert.Contains(t, strings.Join(paths, ","), "channels.discord.token")
+	assert.Contains(t, errs.Error(), "MAXCLAW_TEST_UNSET_TOKEN is not set")
+}
@@ -0,0 +1,516 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidationError 单条校验错误，Path 为 JSON 路径（如 agents.routes[2].agent）
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors 多条校验错误
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, item := range e {
+		lines[i] = item.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unknownFieldMessage 未知字段的提示，热加载时不视为阻断错误
+const unknownFieldMessage = "unknown field"
+
+// 各枚举字段允许的取值
+var (
+	validExecutionModes   = []string{ExecutionModeSafe, ExecutionModeAsk, ExecutionModeAuto}
+	validExecBackends     = []string{"host", "bwrap", "docker", "podman"}
+	validPolicyEffects    = []string{"allow", "deny"}
+	validFetchModes       = []string{"http", "auto", "browser", "chrome"}
+	validSearchProviders  = []string{"brave", "searxng", "tavily", "bing", "google", "serper", "duckduckgo"}
+	validRoutingPurposes  = []string{"summary", "title", "subagent"}
+	validLogLevels        = []string{"debug", "info", "warn", "warning", "error"}
+	validAPIFormats       = []string{"openai", "anthropic", "gemini", "ollama"}
+	validEgressProxyKinds = []string{"http", "https", "socks5", "socks5h"}
+)
+
+// Validate 检查语义错误（必填项、取值范围、枚举、引用关系）以及加载时未能解析的密钥引用
+func (c *Config) Validate() ValidationErrors {
+	v := &validator{}
+	v.errs = append(v.errs, c.secretErrors...)
+
+	c.validateAgents(v)
+	c.validateChannels(v)
+	c.validateProviders(v)
+	c.validateTools(v)
+
+	if c.Gateway.Port != 0 {
+		v.port("gateway.port", c.Gateway.Port)
+	}
+	v.oneOf("logging.level", c.Logging.Level, validLogLevels)
+	v.nonNegative("logging.maxSizeMB", c.Logging.MaxSizeMB)
+	v.nonNegative("logging.maxBackups", c.Logging.MaxBackups)
+	v.nonNegative("logging.maxAgeDays", c.Logging.MaxAgeDays)
+	c.validateMemory(v)
+	c.validateHeartbeat(v)
+
+	tracing := c.Telemetry.Tracing
+	if tracing.Enabled {
+		v.required("telemetry.tracing.endpoint", tracing.Endpoint)
+	}
+	if tracing.SampleRatio < 0 || tracing.SampleRatio > 1 {
+		v.add("telemetry.tracing.sampleRatio", "must be between 0 and 1")
+	}
+
+	for i, backend := range c.Routing.Backends {
+		path := fmt.Sprintf("routing.backends[%d]", i)
+		v.required(path+".model", backend.Model)
+		v.nonNegative(path+".weight", backend.Weight)
+		if backend.APIBase != "" {
+			v.httpURL(path+".apiBase", backend.APIBase)
+		}
+	}
+	for _, purpose := range sortedKeys(c.Routing.Purposes) {
+		path := "routing.purposes." + purpose
+		v.oneOf(path, purpose, validRoutingPurposes)
+		v.required(path, c.Routing.Purposes[purpose])
+	}
+	v.nonNegative("routing.failureThreshold", c.Routing.FailureThreshold)
+	v.nonNegative("routing.cooldownSeconds", c.Routing.CooldownSeconds)
+
+	for i, model := range c.ModelCatalog {
+		v.required(fmt.Sprintf("modelCatalog[%d].id", i), model.ID)
+	}
+
+	sort.SliceStable(v.errs, func(i, j int) bool { return v.errs[i].Path < v.errs[j].Path })
+	return v.errs
+}
+
+func (c *Config) validateMemory(v *validator) {
+	v.nonNegative("memory.maxTokens", c.Memory.MaxTokens)
+	v.nonNegative("memory.keepDailyDays", c.Memory.KeepDailyDays)
+
+	owners := make(map[string]string)
+	for _, name := range sortedKeys(c.Memory.Identities) {
+		path := "memory.identities." + name
+		if strings.TrimSpace(name) == "" {
+			v.add(path, "identity name must not be empty")
+		}
+		for i, account := range c.Memory.Identities[name] {
+			accountPath := fmt.Sprintf("%s[%d]", path, i)
+			channel, sender, ok := strings.Cut(strings.TrimSpace(account), ":")
+			if !ok || strings.TrimSpace(channel) == "" || strings.TrimSpace(sender) == "" {
+				v.add(accountPath, fmt.Sprintf("%q must look like channel:senderId", account))
+				continue
+			}
+			key := strings.ToLower(strings.TrimSpace(account))
+			if owner, dup := owners[key]; dup && owner != name {
+				v.add(accountPath, fmt.Sprintf("%s is already mapped to identity %q", account, owner))
+				continue
+			}
+			owners[key] = name
+		}
+	}
+}
+
+func (c *Config) validateHeartbeat(v *validator) {
+	hb := c.Heartbeat
+	v.nonNegative("heartbeat.intervalMinutes", hb.IntervalMinutes)
+	v.nonNegative("heartbeat.dedupeHours", hb.DedupeHours)
+	if hb.ActiveHours != "" {
+		if _, err := ParseHoursWindow(hb.ActiveHours); err != nil {
+			v.add("heartbeat.activeHours", err.Error())
+		}
+	}
+	if hb.QuietHours != "" {
+		if _, err := ParseHoursWindow(hb.QuietHours); err != nil {
+			v.add("heartbeat.quietHours", err.Error())
+		}
+	}
+	if tz := strings.TrimSpace(hb.Timezone); tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			v.add("heartbeat.timezone", fmt.Sprintf("unknown time zone %q", tz))
+		}
+	}
+	if hb.Channel != "" && strings.TrimSpace(hb.To) == "" {
+		v.add("heartbeat.to", "is required when heartbeat.channel is set")
+	}
+	if hb.Channel == "" && hb.To != "" {
+		v.add("heartbeat.channel", "is required when heartbeat.to is set")
+	}
+}
+
+func (c *Config) validateAgents(v *validator) {
+	defaults := c.Agents.Defaults
+	v.required("agents.defaults.workspace", defaults.Workspace)
+	v.required("agents.defaults.model", defaults.Model)
+	v.positive("agents.defaults.maxTokens", defaults.MaxTokens)
+	v.positive("agents.defaults.maxToolIterations", defaults.MaxToolIterations)
+	v.temperature("agents.defaults.temperature", defaults.Temperature)
+	v.oneOf("agents.defaults.executionMode", defaults.ExecutionMode, validExecutionModes)
+
+	for _, name := range sortedKeys(c.Agents.Profiles) {
+		profile := c.Agents.Profiles[name]
+		path := "agents.profiles." + name
+		if strings.TrimSpace(name) == "" || strings.EqualFold(strings.TrimSpace(name), DefaultAgentName) {
+			v.add(path, fmt.Sprintf("profile name must not be empty or %q", DefaultAgentName))
+		}
+		v.nonNegative(path+".maxTokens", profile.MaxTokens)
+		v.nonNegative(path+".maxToolIterations", profile.MaxToolIterations)
+		if profile.Temperature != nil {
+			v.temperature(path+".temperature", *profile.Temperature)
+		}
+		v.oneOf(path+".executionMode", profile.ExecutionMode, validExecutionModes)
+		v.oneOf(path+".execBackend", profile.ExecBackend, validExecBackends)
+	}
+
+	for i, route := range c.Agents.Routes {
+		path := fmt.Sprintf("agents.routes[%d].agent", i)
+		agent := strings.TrimSpace(route.Agent)
+		if agent == "" {
+			v.add(path, "is required")
+			continue
+		}
+		if _, ok := c.ResolveAgent(agent); !ok {
+			v.add(path, fmt.Sprintf("unknown agent profile %q", agent))
+		}
+	}
+}
+
+func (c *Config) validateChannels(v *validator) {
+	ch := c.Channels
+	if ch.Telegram.Enabled {
+		v.required("channels.telegram.token", ch.Telegram.Token)
+	}
+	if ch.Telegram.Proxy != "" {
+		v.proxyURL("channels.telegram.proxy", ch.Telegram.Proxy)
+	}
+	if ch.Discord.Enabled {
+		v.required("channels.discord.token", ch.Discord.Token)
+	}
+	if ch.WhatsApp.Enabled {
+		v.required("channels.whatsapp.bridgeUrl", ch.WhatsApp.BridgeURL)
+	}
+	if ch.WebSocket.Port != 0 {
+		v.port("channels.websocket.port", ch.WebSocket.Port)
+	}
+	if ch.Slack.Enabled {
+		v.required("channels.slack.botToken", ch.Slack.BotToken)
+		v.required("channels.slack.appToken", ch.Slack.AppToken)
+	}
+	if ch.Email.Enabled {
+		v.required("channels.email.imapHost", ch.Email.IMAPHost)
+		v.required("channels.email.smtpHost", ch.Email.SMTPHost)
+	}
+	if ch.Email.IMAPPort != 0 {
+		v.port("channels.email.imapPort", ch.Email.IMAPPort)
+	}
+	if ch.Email.SMTPPort != 0 {
+		v.port("channels.email.smtpPort", ch.Email.SMTPPort)
+	}
+	v.nonNegative("channels.email.pollIntervalSeconds", ch.Email.PollIntervalSeconds)
+	if ch.QQ.Enabled && strings.TrimSpace(ch.QQ.AccessToken) == "" {
+		v.required("channels.qq.appId", ch.QQ.AppID)
+		v.required("channels.qq.appSecret", ch.QQ.AppSecret)
+	}
+	if ch.Feishu.Enabled {
+		v.required("channels.feishu.appId", ch.Feishu.AppID)
+		v.required("channels.feishu.appSecret", ch.Feishu.AppSecret)
+	}
+}
+
+func (c *Config) validateProviders(v *validator) {
+	providerMap := c.Providers.ToMap()
+	for _, name := range sortedKeys(providerMap) {
+		provider := providerMap[name]
+		path := "providers." + name
+		if provider.APIBase != "" {
+			v.httpURL(path+".apiBase", provider.APIBase)
+		}
+		v.oneOf(path+".apiFormat", provider.APIFormat, validAPIFormats)
+		for i, model := range provider.Models {
+			v.required(fmt.Sprintf("%s.models[%d].id", path, i), model.ID)
+		}
+	}
+}
+
+func (c *Config) validateTools(v *validator) {
+	tools := c.Tools
+	v.nonNegative("tools.exec.timeout", tools.Exec.Timeout)
+	v.oneOf("tools.exec.backend", tools.Exec.Backend, validExecBackends)
+	for _, mode := range sortedKeys(tools.Exec.ModeBackends) {
+		path := "tools.exec.modeBackends." + mode
+		v.oneOf(path, mode, validExecutionModes)
+		v.oneOf(path, tools.Exec.ModeBackends[mode], validExecBackends)
+	}
+
+	v.nonNegative("tools.web.search.maxResults", tools.Web.Search.MaxResults)
+	for i, provider := range tools.Web.Search.Providers {
+		v.oneOf(fmt.Sprintf("tools.web.search.providers[%d]", i), provider, validSearchProviders)
+	}
+	v.oneOf("tools.web.fetch.mode", tools.Web.Fetch.Mode, validFetchModes)
+
+	for _, name := range sortedKeys(tools.MCPServers) {
+		server := tools.MCPServers[name]
+		path := "tools.mcpServers." + name
+		hasCommand := strings.TrimSpace(server.Command) != ""
+		hasURL := strings.TrimSpace(server.URL) != ""
+		switch {
+		case !hasCommand && !hasURL:
+			v.add(path, "either command or url is required")
+		case hasCommand && hasURL:
+			v.add(path, "command and url are mutually exclusive")
+		case hasURL:
+			v.httpURL(path+".url", server.URL)
+		}
+	}
+
+	v.oneOf("tools.policy.default", tools.Policy.Default, validPolicyEffects)
+	for i, rule := range tools.Policy.Rules {
+		path := fmt.Sprintf("tools.policy.rules[%d]", i)
+		v.oneOf(path+".effect", rule.Effect, validPolicyEffects)
+		for _, arg := range sortedKeys(rule.Args) {
+			if pattern := rule.Args[arg].Pattern; pattern != "" {
+				if _, err := regexp.Compile(pattern); err != nil {
+					v.add(path+".args."+arg+".pattern", "invalid regular expression: "+err.Error())
+				}
+			}
+		}
+	}
+
+	for i, cidr := range tools.Egress.AllowCIDRs {
+		if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+			v.add(fmt.Sprintf("tools.egress.allowCidrs[%d]", i), fmt.Sprintf("invalid CIDR %q", cidr))
+		}
+	}
+	if tools.Egress.Proxy != "" {
+		v.proxyURL("tools.egress.proxy", tools.Egress.Proxy)
+	}
+
+	embedding := tools.Retrieval.Embedding
+	if embedding.APIBase != "" {
+		v.httpURL("tools.retrieval.embedding.apiBase", embedding.APIBase)
+	}
+	if embedding.Provider != "" {
+		if _, ok := c.Providers.ToMap()[embedding.Provider]; !ok {
+			v.add("tools.retrieval.embedding.provider", fmt.Sprintf("unknown provider %q", embedding.Provider))
+		}
+	}
+}
+
+// ValidateFile 校验配置文件：JSON 语法、字段类型、未知字段、语义与密钥引用
+func ValidateFile(path string) (ValidationErrors, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateData(data), nil
+}
+
+// ValidateData 校验配置文件内容，规则同 ValidateFile
+func ValidateData(data []byte) ValidationErrors {
+	_, errs := validateData(data, true)
+	return errs
+}
+
+// validateData 校验并返回解码后的配置（语法或类型错误时为 nil）；
+// resolveRefs 为 false 时不解析密钥引用，适合在没有运行时环境变量的进程中校验
+func validateData(data []byte, resolveRefs bool) (*Config, ValidationErrors) {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, ValidationErrors{jsonSyntaxError(data, err)}
+	}
+	root, ok := raw.(map[string]any)
+	if !ok {
+		return nil, ValidationErrors{{Message: "config must be a JSON object"}}
+	}
+
+	var errs ValidationErrors
+	for _, key := range sortedKeys(root) {
+		// 兼容 Claude Desktop / Cursor 风格的顶层 mcpServers
+		if key == "mcpServers" {
+			errs = append(errs, unknownFields(root[key], reflect.TypeOf(map[string]MCPServerConfig{}), key)...)
+			continue
+		}
+		field, ok := fieldByJSONName(reflect.TypeOf(Config{}), key)
+		if !ok {
+			errs = append(errs, ValidationError{Path: key, Message: unknownFieldMessage})
+			continue
+		}
+		errs = append(errs, unknownFields(root[key], field.Type, key)...)
+	}
+
+	decode := decodeConfig
+	if !resolveRefs {
+		decode = decodeConfigRefs
+	}
+	cfg, err := decode(data)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			path := typeErr.Field
+			if path == "" {
+				path = "(root)"
+			}
+			return nil, append(errs, ValidationError{
+				Path:    path,
+				Message: fmt.Sprintf("expected %s, got JSON %s", typeErr.Type, typeErr.Value),
+			})
+		}
+		return nil, append(errs, ValidationError{Message: err.Error()})
+	}
+	errs = append(errs, cfg.Validate()...)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return cfg, errs
+}
+
+// unknownFields 按结构体 json 标签递归检查多余的字段
+func unknownFields(raw any, t reflect.Type, path string) ValidationErrors {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var errs ValidationErrors
+	switch value := raw.(type) {
+	case map[string]any:
+		switch {
+		case t == providersConfigType:
+			for _, key := range sortedKeys(value) {
+				errs = append(errs, unknownFields(value[key], reflect.TypeOf(ProviderConfig{}), joinConfigPath(path, key))...)
+			}
+		case t.Kind() == reflect.Map:
+			for _, key := range sortedKeys(value) {
+				errs = append(errs, unknownFields(value[key], t.Elem(), joinConfigPath(path, key))...)
+			}
+		case t.Kind() == reflect.Struct:
+			for _, key := range sortedKeys(value) {
+				field, ok := fieldByJSONName(t, key)
+				if !ok {
+					errs = append(errs, ValidationError{Path: joinConfigPath(path, key), Message: unknownFieldMessage})
+					continue
+				}
+				errs = append(errs, unknownFields(value[key], field.Type, joinConfigPath(path, key))...)
+			}
+		}
+	case []any:
+		if t.Kind() == reflect.Slice {
+			for i, item := range value {
+				errs = append(errs, unknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return errs
+}
+
+// jsonSyntaxError 将 JSON 语法错误的偏移量换算为行列号
+func jsonSyntaxError(data []byte, err error) ValidationError {
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return ValidationError{Message: "invalid JSON: " + err.Error()}
+	}
+	offset := int(syntaxErr.Offset)
+	if offset > len(data) {
+		offset = len(data)
+	}
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	col := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return ValidationError{Message: fmt.Sprintf("invalid JSON at line %d, column %d: %s", line, col, syntaxErr.Error())}
+}
+
+// validator 收集校验错误
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) add(path, message string) {
+	v.errs = append(v.errs, ValidationError{Path: path, Message: message})
+}
+
+func (v *validator) required(path, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(path, "is required")
+	}
+}
+
+func (v *validator) positive(path string, value int) {
+	if value <= 0 {
+		v.add(path, "must be greater than 0")
+	}
+}
+
+func (v *validator) nonNegative(path string, value int) {
+	if value < 0 {
+		v.add(path, "must not be negative")
+	}
+}
+
+func (v *validator) port(path string, value int) {
+	if value < 1 || value > 65535 {
+		v.add(path, "must be between 1 and 65535")
+	}
+}
+
+func (v *validator) temperature(path string, value float64) {
+	if value < 0 || value > 2 {
+		v.add(path, "must be between 0 and 2")
+	}
+}
+
+// oneOf 校验枚举值（大小写不敏感），空值视为使用默认值
+func (v *validator) oneOf(path, value string, allowed []string) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return
+	}
+	for _, candidate := range allowed {
+		if value == candidate {
+			return
+		}
+	}
+	v.add(path, fmt.Sprintf("unknown value %q (want one of %s)", value, strings.Join(allowed, ", ")))
+}
+
+func (v *validator) httpURL(path, value string) {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add(path, fmt.Sprintf("invalid URL %q (want http:// or https://)", value))
+	}
+}
+
+func (v *validator) proxyURL(path, value string) {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || u.Host == "" {
+		v.add(path, fmt.Sprintf("invalid proxy URL %q", value))
+		return
+	}
+	v.oneOf(path, u.Scheme, validEgressProxyKinds)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validationPaths(errs ValidationErrors) map[string]string {
+	out := map[string]string{}
+	for _, e := range errs {
+		out[e.Path] = e.Message
+	}
+	return out
+}
+
+func TestDefaultConfigIsValid(t *testing.T) {
+	assert.Empty(t, DefaultConfig().Validate())
+}
+
+func TestValidateReportsPreciseSemanticPaths(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Agents.Defaults.MaxTokens = 0
+	cfg.Agents.Defaults.ExecutionMode = "yolo"
+	cfg.Agents.Profiles = map[string]AgentProfile{"coder": {ExecBackend: "lxc"}}
+	cfg.Agents.Routes = []AgentRoute{{Agent: "coder", Channel: "telegram"}, {Agent: "writer"}}
+	cfg.Channels.Slack.Enabled = true
+	cfg.Channels.Slack.BotToken = "xoxb"
+	cfg.Tools.MCPServers = map[string]MCPServerConfig{"broken": {}}
+	cfg.Tools.Policy.Rules = []ToolPolicyRuleConfig{{Effect: "allow"}, {Effect: "maybe", Args: map[string]ToolArgRuleConfig{"command": {Pattern: "("}}}}
+	cfg.Tools.Egress.AllowCIDRs = []string{"10.0.0.0/8", "nope"}
+	cfg.Routing.Backends = []RoutingBackend{{Model: "gpt-4o"}, {Weight: -1}}
+	cfg.Gateway.Port = 70000
+	cfg.Memory.KeepDailyDays = -1
+	cfg.Memory.Identities = map[string][]string{"alice": {"telegram:1001", "slack"}, "bob": {"Telegram:1001"}}
+	cfg.Heartbeat = HeartbeatConfig{ActiveHours: "08:00-22:00", QuietHours: "7pm-8am", Timezone: "Mars/Olympus", Channel: "telegram"}
+
+	paths := validationPaths(cfg.Validate())
+	assert.Contains(t, paths, "agents.defaults.maxTokens")
+	assert.Contains(t, paths["agents.defaults.executionMode"], `unknown value "yolo"`)
+	assert.Contains(t, paths, "agents.profiles.coder.execBackend")
+	assert.NotContains(t, paths, "agents.routes[0].agent")
+	assert.Contains(t, paths["agents.routes[1].agent"], `unknown agent profile "writer"`)
+	assert.Equal(t, "is required", paths["channels.slack.appToken"])
+	assert.NotContains(t, paths, "channels.slack.botToken")
+	assert.Contains(t, paths, "tools.mcpServers.broken")
+	assert.NotContains(t, paths, "tools.policy.rules[0].effect")
+	assert.Contains(t, paths, "tools.policy.rules[1].effect")
+	assert.Contains(t, paths, "tools.policy.rules[1].args.command.pattern")
+	assert.Contains(t, paths, "tools.egress.allowCidrs[1]")
+	assert.Contains(t, paths, "routing.backends[1].model")
+	assert.Contains(t, paths, "routing.backends[1].weight")
+	assert.Contains(t, paths, "gateway.port")
+	assert.Contains(t, paths, "memory.keepDailyDays")
+	assert.NotContains(t, paths, "memory.identities.alice[0]")
+	assert.Contains(t, paths["memory.identities.alice[1]"], "channel:senderId")
+	assert.Contains(t, paths["memory.identities.bob[0]"], `already mapped to identity "alice"`)
+	assert.NotContains(t, paths, "heartbeat.activeHours")
+	assert.Contains(t, paths["heartbeat.quietHours"], "HH:MM-HH:MM")
+	assert.Contains(t, paths, "heartbeat.timezone")
+	assert.Equal(t, "is required when heartbeat.channel is set", paths["heartbeat.to"])
+}
+
+func TestValidateDataReportsUnknownFieldsTypesAndSyntax(t *testing.T) {
+	errs := ValidateData([]byte(`{
+  "agents": {"defaults": {"modle": "gpt-4o"}, "routes": [{"agent": "default", "chanel": "telegram"}]},
+  "providers": {"openai": {"apiKey": "sk", "apiBaes": "x"}},
+  "mcpServers": {"fs": {"command": "npx", "cmd": "x"}},
+  "unknownTop": true
+}`))
+	paths := validationPaths(errs)
+	for _, path := range []string{
+		"agents.defaults.modle",
+		"agents.routes[0].chanel",
+		"providers.openai.apiBaes",
+		"mcpServers.fs.cmd",
+		"unknownTop",
+	} {
+		assert.Equal(t, unknownFieldMessage, paths[path], path)
+	}
+
+	errs = ValidateData([]byte(`{"agents": {"defaults": {"maxTokens": "lots"}}}`))
+	require.Len(t, errs, 1)
+	assert.Equal(t, "agents.defaults.maxTokens", errs[0].Path)
+	assert.Contains(t, errs[0].Message, "expected int")
+
+	errs = ValidateData([]byte("{\n  \"agents\": {,\n}"))
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Message, "line 2")
+}
+
+func TestGetAndSetValue(t *testing.T) {
+	t.Setenv("MAXCLAW_HOME", t.TempDir())
+	require.NoError(t, os.WriteFile(GetConfigPath(), []byte(`{"providers": {"openai": {"apiKey": "${OPENAI_API_KEY}"}}}`), 0600))
+
+	value, err := GetValue("providers.openai.apiKey")
+	require.NoError(t, err)
+	assert.Equal(t, "${OPENAI_API_KEY}", value, "references are returned unresolved")
+	value, err = GetValue("agents.defaults.maxTokens")
+	require.NoError(t, err)
+	assert.Equal(t, float64(8192), value, "falls back to defaults")
+
+	require.NoError(t, SetValue("agents.defaults.maxTokens", "4096"))
+	require.NoError(t, SetValue("agents.defaults.model", "12345"))
+	require.NoError(t, SetValue("channels.telegram.allowFrom", "alice, bob"))
+	require.NoError(t, SetValue("tools.exec.network", "false"))
+	require.NoError(t, SetValue("agents.routes[0]", `{"agent": "default", "channel": "cli"}`))
+	require.NoError(t, SetValue("agents.routes[0].chatId", "42"))
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 4096, cfg.Agents.Defaults.MaxTokens)
+	assert.Equal(t, "12345", cfg.Agents.Defaults.Model, "string fields are never parsed as JSON")
+	assert.Equal(t, []string{"alice", "bob"}, cfg.Channels.Telegram.AllowFrom)
+	require.NotNil(t, cfg.Tools.Exec.Network)
+	assert.False(t, *cfg.Tools.Exec.Network)
+	require.Len(t, cfg.Agents.Routes, 1)
+	assert.Equal(t, "42", cfg.Agents.Routes[0].ChatID)
+
+	data, err := os.ReadFile(GetConfigPath())
+	require.NoError(t, err)
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.Equal(t, "${OPENAI_API_KEY}", raw["providers"].(map[string]any)["openai"].(map[string]any)["apiKey"])
+
+	assert.ErrorContains(t, SetValue("agents.defaults.modle", "x"), "unknown config key")
+	assert.ErrorContains(t, SetValue("agents.defaults.maxTokens", "many"), "expected an integer")
+	assert.ErrorContains(t, SetValue("agents.routes[5].agent", "default"), "index out of range")
+	err = SetValue("agents.defaults.executionMode", "yolo")
+	assert.ErrorContains(t, err, "agents.defaults.executionMode")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, ExecutionModeAsk, cfg.Agents.Defaults.ExecutionMode, "invalid values are not written")
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WatchInterval 配置文件轮询间隔
+var WatchInterval = 2 * time.Second
+
+var (
+	selfWriteMu sync.Mutex
+	// selfWrites 本进程最近写入的配置内容摘要；这类改动已由写入方自行应用，热加载只更新基线
+	selfWrites = map[string][32]byte{}
+)
+
+// writeConfigFile 写入配置并记录摘要
+func writeConfigFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	selfWriteMu.Lock()
+	selfWrites[path] = sha256.Sum256(data)
+	selfWriteMu.Unlock()
+	return nil
+}
+
+func isSelfWrite(path string, sum [32]byte) bool {
+	selfWriteMu.Lock()
+	defer selfWriteMu.Unlock()
+	return selfWrites[path] == sum
+}
+
+// Changes 两份配置之间变化的叶子路径（JSON 路径，已排序）
+type Changes []string
+
+// Touches 判断是否有变化落在任一前缀（自身或其子路径）下
+func (c Changes) Touches(prefixes ...string) bool {
+	for _, path := range c {
+		for _, prefix := range prefixes {
+			if path == prefix || strings.HasPrefix(path, prefix+".") || strings.HasPrefix(path, prefix+"[") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Diff 比较两份配置，返回发生变化的叶子路径；结果只含路径，不含取值，可安全写日志
+func Diff(old, new *Config) Changes {
+	before, after := flattenConfig(old), flattenConfig(new)
+	var changes Changes
+	for path, value := range after {
+		if before[path] != value {
+			changes = append(changes, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, path)
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// flattenConfig 将配置展开为 JSON 路径 → 叶子值的编码
+func flattenConfig(cfg *Config) map[string]string {
+	out := map[string]string{}
+	if cfg == nil {
+		return out
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return out
+	}
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return out
+	}
+	var walk func(path string, value any)
+	walk = func(path string, value any) {
+		switch v := value.(type) {
+		case map[string]any:
+			for key, child := range v {
+				walk(joinConfigPath(path, key), child)
+			}
+		case []any:
+			for i, child := range v {
+				walk(fmt.Sprintf("%s[%d]", path, i), child)
+			}
+		default:
+			raw, _ := json.Marshal(v)
+			out[path] = string(raw)
+		}
+	}
+	walk("", root)
+	return out
+}
+
+// Watcher 轮询配置文件，内容变化且通过校验后把新配置与差异交给 onChange
+type Watcher struct {
+	path     string
+	onChange func(cfg *Config, changes Changes)
+	onError  func(err error)
+
+	mu      sync.Mutex
+	current *Config
+	sum     [32]byte
+}
+
+// NewWatcher 以 current 为基线监听 path；onError 可为 nil
+func NewWatcher(path string, current *Config, onChange func(cfg *Config, changes Changes), onError func(err error)) *Watcher {
+	w := &Watcher{path: path, current: current, onChange: onChange, onError: onError}
+	if data, err := os.ReadFile(path); err == nil {
+		w.sum = sha256.Sum256(data)
+	}
+	return w
+}
+
+// Run 按 WatchInterval 轮询，直到 ctx 结束
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.Check(); err != nil && w.onError != nil {
+				w.onError(err)
+			}
+		}
+	}
+}
+
+// Check 立即检查一次；文件无变化、仅为本进程写入或无有效差异时返回空
+func (w *Watcher) Check() (Changes, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if sum == w.sum {
+		return nil, nil
+	}
+	// 无论成功与否都记下摘要，同一份无效内容只报告一次
+	w.sum = sum
+
+	cfg, errs := validateData(data, true)
+	if blocking := blockingErrors(errs); len(blocking) > 0 {
+		return nil, fmt.Errorf("config reload rejected:\n%w", blocking)
+	}
+	finishLoad(cfg)
+
+	changes := Diff(w.current, cfg)
+	w.current = cfg
+	if len(changes) == 0 || isSelfWrite(w.path, sum) {
+		return nil, nil
+	}
+	if w.onChange != nil {
+		w.onChange(cfg, changes)
+	}
+	return changes, nil
+}
+
+// Current 返回最近一次生效的配置
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// blockingErrors 未知字段只提示不阻止热加载，其余错误都会拒绝新配置
+func blockingErrors(errs ValidationErrors) ValidationErrors {
+	var out ValidationErrors
+	for _, err := range errs {
+		if err.Message != unknownFieldMessage {
+			out = append(out, err)
+		}
+	}
+	return out
+}
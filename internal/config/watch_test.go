@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffReportsChangedLeafPaths(t *testing.T) {
+	old := DefaultConfig()
+	updated := DefaultConfig()
+	updated.Channels.Telegram.Token = "new"
+	updated.Tools.MCPServers = map[string]MCPServerConfig{"fs": {Command: "npx"}}
+	updated.Agents.Routes = []AgentRoute{{Agent: "default"}}
+
+	changes := Diff(old, updated)
+	assert.Contains(t, changes, "channels.telegram.token")
+	assert.Contains(t, changes, "tools.mcpServers.fs.command")
+	assert.Contains(t, changes, "agents.routes[0].agent")
+	assert.True(t, changes.Touches("channels.telegram"))
+	assert.True(t, changes.Touches("agents.routes"))
+	assert.False(t, changes.Touches("channels.discord", "providers"))
+	assert.False(t, changes.Touches("channels.tele"), "prefixes match whole segments")
+	assert.Empty(t, Diff(old, DefaultConfig()))
+}
+
+func TestWatcherAppliesExternalEditsOnly(t *testing.T) {
+	t.Setenv("MAXCLAW_HOME", t.TempDir())
+	path := GetConfigPath()
+	require.NoError(t, os.WriteFile(path, []byte(`{"agents": {"defaults": {"model": "gpt-4o"}}}`), 0600))
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+
+	var applied []Changes
+	w := NewWatcher(path, cfg, func(_ *Config, changes Changes) { applied = append(applied, changes) }, nil)
+
+	changes, err := w.Check()
+	require.NoError(t, err)
+	assert.Empty(t, changes, "unchanged file")
+
+	// 外部编辑：只报告变化的路径
+	require.NoError(t, os.WriteFile(path, []byte(`{"agents": {"defaults": {"model": "gpt-4o-mini"}}}`), 0600))
+	changes, err = w.Check()
+	require.NoError(t, err)
+	assert.Equal(t, Changes{"agents.defaults.model"}, changes)
+	assert.Equal(t, "gpt-4o-mini", w.Current().Agents.Defaults.Model)
+
+	// 无效配置被拒绝，基线保持不变
+	require.NoError(t, os.WriteFile(path, []byte(`{"agents": {"defaults": {"model": "gpt-4o", "maxTokens": -1}}}`), 0600))
+	_, err = w.Check()
+	assert.ErrorContains(t, err, "agents.defaults.maxTokens")
+	assert.Equal(t, "gpt-4o-mini", w.Current().Agents.Defaults.Model)
+
+	// 本进程 SaveConfig 写入的改动已由调用方应用，只更新基线
+	saved := w.Current()
+	saved.Agents.Defaults.Model = "claude-sonnet-4-5"
+	require.NoError(t, SaveConfig(saved))
+	changes, err = w.Check()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Equal(t, "claude-sonnet-4-5", w.Current().Agents.Defaults.Model)
+
+	assert.Len(t, applied, 1)
+}
@@ -315,9 +315,11 @@ func TestExecuteJobLogsAttemptAndCompletion(t *testing.T) {
 	require.NotNil(t, lg.Cron)
 
 	var buf bytes.Buffer
-	lg.Cron.SetOutput(&buf)
+	prev := lg.Cron
+	lg.Cron = logging.New(&buf, logging.ComponentCron)
+	defer func() { lg.Cron = prev }()
 
-	service := NewService("")
+	service := NewService(filepath.Join(t.TempDir(), "jobs.json"))
 	service.SetJobHandler(func(job *Job) (string, error) {
 		return "ok", nil
 	})
@@ -326,10 +328,10 @@ func TestExecuteJobLogsAttemptAndCompletion(t *testing.T) {
 	service.executeJob(job, "every")
 
 	logText := buf.String()
-	assert.Contains(t, logText, "cron attempt trigger=every")
-	assert.Contains(t, logText, "cron execute trigger=every")
-	assert.Contains(t, logText, "cron completed trigger=every")
-	assert.Contains(t, logText, job.ID)
+	assert.Contains(t, logText, `"msg":"cron attempt","component":"cron","trigger":"every"`)
+	assert.Contains(t, logText, `"msg":"cron execute","component":"cron","trigger":"every"`)
+	assert.Contains(t, logText, `"msg":"cron completed","component":"cron","trigger":"every"`)
+	assert.Contains(t, logText, `"job_id":"`+job.ID+`"`)
 }
 
 func TestExecuteJobLogsSkipReasons(t *testing.T) {
@@ -339,9 +341,11 @@ func TestExecuteJobLogsSkipReasons(t *testing.T) {
 	require.NotNil(t, lg.Cron)
 
 	var buf bytes.Buffer
-	lg.Cron.SetOutput(&buf)
+	prev := lg.Cron
+	lg.Cron = logging.New(&buf, logging.ComponentCron)
+	defer func() { lg.Cron = prev }()
 
-	service := NewService("")
+	service := NewService(filepath.Join(t.TempDir(), "jobs.json"))
 
 	disabledJob := NewJob("disabled", Schedule{Type: ScheduleTypeEvery, EveryMs: 1000}, Payload{})
 	disabledJob.Enabled = false
@@ -351,7 +355,7 @@ func TestExecuteJobLogsSkipReasons(t *testing.T) {
 	service.executeJob(noHandlerJob, "cron")
 
 	logText := buf.String()
-	assert.Contains(t, logText, "cron attempt trigger=cron")
-	assert.True(t, strings.Contains(logText, "reason=disabled"))
-	assert.True(t, strings.Contains(logText, "reason=no_handler"))
+	assert.Contains(t, logText, `"msg":"cron attempt","component":"cron","trigger":"cron"`)
+	assert.True(t, strings.Contains(logText, `"reason":"disabled"`))
+	assert.True(t, strings.Contains(logText, `"reason":"no_handler"`))
 }
@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/Lichas/maxclaw/internal/logging"
+	"github.com/Lichas/maxclaw/internal/telemetry"
 	"github.com/robfig/cron/v3"
 )
 
@@ -255,7 +257,7 @@ func (s *Service) scheduleJob(job *Job) {
 func (s *Service) scheduleEveryJob(job *Job) {
 	duration := time.Duration(job.Schedule.EveryMs) * time.Millisecond
 	if duration <= 0 {
-		s.logCronf("cron schedule skipped type=every job_id=%s reason=invalid_interval interval_ms=%d", job.ID, job.Schedule.EveryMs)
+		s.logCron(slog.LevelWarn, "cron schedule skipped", "type", "every", "job_id", job.ID, "reason", "invalid_interval", "interval_ms", job.Schedule.EveryMs)
 		return
 	}
 
@@ -263,7 +265,7 @@ func (s *Service) scheduleEveryJob(job *Job) {
 	if cancel, ok := s.cancelFuncs[job.ID]; ok {
 		cancel()
 		delete(s.cancelFuncs, job.ID)
-		s.logCronf("cron stopped existing scheduler job_id=%s", job.ID)
+		s.logCron(slog.LevelDebug, "cron stopped existing scheduler", "job_id", job.ID)
 	}
 
 	// 创建可取消的 context
@@ -289,7 +291,7 @@ func (s *Service) scheduleEveryJob(job *Job) {
 				s.executeJob(job, "every")
 			case <-ctx.Done():
 				// 任务被删除或停止
-				s.logCronf("cron job stopped job_id=%s", job.ID)
+				s.logCron(slog.LevelInfo, "cron job stopped", "job_id", job.ID)
 				return
 			case <-s.stopChan:
 				return
@@ -301,14 +303,14 @@ func (s *Service) scheduleEveryJob(job *Job) {
 // scheduleCronJob 调度 Cron 任务
 func (s *Service) scheduleCronJob(job *Job) {
 	if job.Schedule.Expr == "" {
-		s.logCronf("cron schedule skipped type=cron job_id=%s reason=empty_expr", job.ID)
+		s.logCron(slog.LevelWarn, "cron schedule skipped", "type", "cron", "job_id", job.ID, "reason", "empty_expr")
 		return
 	}
 
 	if _, err := s.cron.AddFunc(job.Schedule.Expr, func() {
 		s.executeJob(job, "cron")
 	}); err != nil {
-		s.logCronf("cron schedule failed type=cron job_id=%s expr=%q err=%v", job.ID, job.Schedule.Expr, err)
+		s.logCron(slog.LevelError, "cron schedule failed", "type", "cron", "job_id", job.ID, "expr", job.Schedule.Expr, "err", err)
 	}
 }
 
@@ -316,7 +318,7 @@ func (s *Service) scheduleCronJob(job *Job) {
 func (s *Service) scheduleOnceJob(job *Job) {
 	at := time.UnixMilli(job.Schedule.AtMs)
 	if at.Before(time.Now()) {
-		s.logCronf("cron schedule skipped type=once job_id=%s reason=past_time at=%s", job.ID, at.Format(time.RFC3339))
+		s.logCron(slog.LevelWarn, "cron schedule skipped", "type", "once", "job_id", job.ID, "reason", "past_time", "at", at.Format(time.RFC3339))
 		return
 	}
 
@@ -356,17 +358,17 @@ func (s *Service) RunJob(jobID string) error {
 // executeJob 执行任务
 func (s *Service) executeJob(job *Job, trigger string) {
 	if job == nil {
-		s.logCronf("cron attempt trigger=%s skipped reason=nil_job", trigger)
+		s.logCron(slog.LevelWarn, "cron skip", "trigger", trigger, "reason", "nil_job")
 		return
 	}
 
-	s.logCronf("cron attempt trigger=%s job=%s job_id=%s enabled=%t", trigger, job.Name, job.ID, job.Enabled)
+	s.logCron(slog.LevelInfo, "cron attempt", "trigger", trigger, "job", job.Name, "job_id", job.ID, "enabled", job.Enabled)
 	if !job.Enabled {
-		s.logCronf("cron skip trigger=%s job_id=%s reason=disabled", trigger, job.ID)
+		s.logCron(slog.LevelInfo, "cron skip", "trigger", trigger, "job_id", job.ID, "reason", "disabled")
 		return
 	}
 	if s.onJob == nil {
-		s.logCronf("cron skip trigger=%s job_id=%s reason=no_handler", trigger, job.ID)
+		s.logCron(slog.LevelWarn, "cron skip", "trigger", trigger, "job_id", job.ID, "reason", "no_handler")
 		return
 	}
 
@@ -375,15 +377,18 @@ func (s *Service) executeJob(job *Job, trigger string) {
 		ID:        fmt.Sprintf("exec_%d", time.Now().UnixNano()),
 		JobID:     job.ID,
 		JobTitle:  job.Name,
+		Trigger:   trigger,
 		StartedAt: time.Now(),
 		Status:    "running",
 	}
 	s.historyStore.AddRecord(record)
 
-	s.logCronf("cron execute trigger=%s job=%s job_id=%s", trigger, job.Name, job.ID)
+	s.logCron(slog.LevelInfo, "cron execute", "trigger", trigger, "job", job.Name, "job_id", job.ID)
 	start := time.Now()
 	result, err := s.onJob(job)
-	duration := time.Since(start).Milliseconds()
+	elapsed := time.Since(start)
+	duration := elapsed.Milliseconds()
+	telemetry.RecordCronRun(trigger, elapsed, err)
 
 	// Update record after execution
 	now := time.Now()
@@ -400,7 +405,7 @@ func (s *Service) executeJob(job *Job, trigger string) {
 	})
 
 	if err != nil {
-		s.logCronf("cron failed trigger=%s job=%s job_id=%s err=%v", trigger, job.Name, job.ID, err)
+		s.logCron(slog.LevelError, "cron failed", "trigger", trigger, "job", job.Name, "job_id", job.ID, "duration_ms", duration, "err", err)
 		// Send notification on failure
 		if s.onNotify != nil {
 			s.onNotify(
@@ -415,7 +420,7 @@ func (s *Service) executeJob(job *Job, trigger string) {
 			)
 		}
 	} else {
-		s.logCronf("cron completed trigger=%s job=%s job_id=%s result=%q", trigger, job.Name, job.ID, logging.Truncate(result, 400))
+		s.logCron(slog.LevelInfo, "cron completed", "trigger", trigger, "job", job.Name, "job_id", job.ID, "duration_ms", duration, "result", logging.Truncate(result, 400))
 		// Send notification on success
 		if s.onNotify != nil {
 			s.onNotify(
@@ -437,12 +442,12 @@ func (s *Service) GetHistoryStore() *HistoryStore {
 	return s.historyStore
 }
 
-func (s *Service) logCronf(format string, args ...interface{}) {
+func (s *Service) logCron(level slog.Level, msg string, args ...any) {
 	if lg := logging.Get(); lg != nil && lg.Cron != nil {
-		lg.Cron.Printf(format, args...)
+		lg.Cron.Log(context.Background(), level, msg, args...)
 		return
 	}
-	fmt.Printf("[Cron] "+format+"\n", args...)
+	fmt.Println(append([]any{"[Cron] " + msg}, args...)...)
 }
 
 // save 保存任务到文件
@@ -11,6 +11,7 @@ type ExecutionRecord struct {
 	ID        string     `json:"id"`
 	JobID     string     `json:"jobId"`
 	JobTitle  string     `json:"jobTitle"`
+	Trigger   string     `json:"trigger,omitempty"` // every, cron, once, manual；事件触发器为 webhook, file, imap
 	StartedAt time.Time  `json:"startedAt"`
 	EndedAt   *time.Time `json:"endedAt,omitempty"`
 	Status    string     `json:"status"` // running, success, failed
@@ -0,0 +1,222 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReply(t *testing.T) {
+	for _, reply := range []string{"", "HEARTBEAT_OK", "  **HEARTBEAT_OK**  ", "`HEARTBEAT_OK`.", "All quiet today. HEARTBEAT_OK"} {
+		alert, nothing := ParseReply(reply)
+		assert.True(t, nothing, reply)
+		assert.Empty(t, alert, reply)
+	}
+
+	alert, nothing := ParseReply("The deploy to prod failed twice. Please check CI.")
+	assert.False(t, nothing)
+	assert.Equal(t, "The deploy to prod failed twice. Please check CI.", alert)
+
+	alert, nothing = ParseReply("Disk is at 95% — HEARTBEAT_OK is not appropriate here.")
+	assert.False(t, nothing, "the sentinel in the middle of a message is not an acknowledgement")
+	assert.Equal(t, "Disk is at 95% —  is not appropriate here.", alert)
+
+	long := "HEARTBEAT_OK\n" + strings.Repeat("Invoice #42 is overdue. ", 20)
+	alert, nothing = ParseReply(long)
+	assert.False(t, nothing, "a long message after the sentinel is still delivered")
+	assert.NotContains(t, alert, Sentinel)
+}
+
+func TestFingerprintIgnoresCaseAndWhitespace(t *testing.T) {
+	assert.Equal(t, Fingerprint("Deploy  failed\non prod"), Fingerprint("deploy failed on PROD "))
+	assert.NotEqual(t, Fingerprint("3 unread emails"), Fingerprint("4 unread emails"))
+}
+
+func TestHasChecklist(t *testing.T) {
+	assert.False(t, hasChecklist(""))
+	assert.False(t, hasChecklist("# Heartbeat\n\n## Focus Now\n\n- \n- [ ]\n<!--\nnotes\n-->"))
+	assert.True(t, hasChecklist("# Heartbeat\n\n- Check whether the nightly backup finished"))
+}
+
+type fakeAgent struct {
+	replies []string
+	err     error
+	prompts []string
+}
+
+func (f *fakeAgent) run(ctx context.Context, prompt string) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	if f.err != nil {
+		return "", f.err
+	}
+	if len(f.replies) == 0 {
+		return Sentinel, nil
+	}
+	reply := f.replies[0]
+	f.replies = f.replies[1:]
+	return reply, nil
+}
+
+func newTestService(t *testing.T, cfg config.HeartbeatConfig, agent *fakeAgent) (*Service, *[]string) {
+	t.Helper()
+	workspace := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workspace, "memory"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "memory", "heartbeat.md"), []byte("# Heartbeat\n- Check the nightly backup"), 0644))
+
+	s, err := NewService(workspace, cfg, nil)
+	require.NoError(t, err)
+	s.SetRunner(agent.run)
+	var delivered []string
+	s.SetDeliverer(func(channel, to, content string) error {
+		delivered = append(delivered, channel+"|"+to+"|"+content)
+		return nil
+	})
+	return s, &delivered
+}
+
+func at(clock string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02 15:04", "2026-10-19 "+clock, time.UTC)
+	return t
+}
+
+func TestTickRespectsActiveHoursAndInterval(t *testing.T) {
+	agent := &fakeAgent{}
+	s, delivered := newTestService(t, config.HeartbeatConfig{
+		IntervalMinutes: 30,
+		ActiveHours:     "08:00-22:00",
+		Timezone:        "UTC",
+		Channel:         "telegram",
+		To:              "1001",
+	}, agent)
+	ctx := context.Background()
+
+	assert.Equal(t, StatusSkipped, s.Tick(ctx, at("07:30")).Status)
+	assert.Empty(t, agent.prompts, "no model call outside active hours")
+
+	assert.Equal(t, StatusOK, s.Tick(ctx, at("08:00")).Status)
+	assert.Equal(t, StatusSkipped, s.Tick(ctx, at("08:20")).Status)
+	assert.Equal(t, StatusOK, s.Tick(ctx, at("08:30")).Status)
+	require.Len(t, agent.prompts, 2)
+	assert.Contains(t, agent.prompts[0], "[Heartbeat] Scheduled check-in at 2026-10-19 08:00 UTC")
+	assert.Contains(t, agent.prompts[0], "reply with exactly HEARTBEAT_OK")
+	assert.Empty(t, *delivered, "HEARTBEAT_OK is never delivered")
+
+	// 状态持久化：重启后仍按上次运行时间计算间隔
+	reloaded, err := NewService(s.workspace, s.cfg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, at("08:30"), reloaded.State().LastRun.UTC())
+	assert.Equal(t, StatusOK, reloaded.State().LastStatus)
+
+	records := s.History().GetRecords(JobID, 0)
+	require.Len(t, records, 2)
+	assert.Equal(t, "heartbeat", records[0].Trigger)
+	assert.Equal(t, "success", records[0].Status)
+}
+
+func TestTickDeduplicatesAlerts(t *testing.T) {
+	agent := &fakeAgent{replies: []string{
+		"The nightly backup did not run.",
+		"The  nightly backup did not run.",
+		"The nightly backup did not run. It also failed yesterday.",
+	}}
+	s, delivered := newTestService(t, config.HeartbeatConfig{IntervalMinutes: 30, DedupeHours: 6, Channel: "telegram", To: "1001"}, agent)
+	ctx := context.Background()
+
+	assert.Equal(t, StatusAlert, s.Tick(ctx, at("09:00")).Status)
+	assert.Equal(t, StatusDuplicate, s.Tick(ctx, at("09:30")).Status)
+	assert.Equal(t, StatusAlert, s.Tick(ctx, at("10:00")).Status)
+	assert.Equal(t, []string{
+		"telegram|1001|The nightly backup did not run.",
+		"telegram|1001|The nightly backup did not run. It also failed yesterday.",
+	}, *delivered)
+
+	// 去重窗口过后同样的提醒会再次投递
+	agent.replies = []string{"The nightly backup did not run."}
+	assert.Equal(t, StatusAlert, s.Tick(ctx, at("15:01")).Status)
+	assert.Len(t, *delivered, 3)
+}
+
+func TestQuietHoursHoldLatestAlert(t *testing.T) {
+	agent := &fakeAgent{replies: []string{"Backup failed.", "Backup failed again, disk is full."}}
+	s, _ := newTestService(t, config.HeartbeatConfig{IntervalMinutes: 60, QuietHours: "22:00-07:00", Timezone: "UTC"}, agent)
+	var notified []string
+	s.SetNotificationHandler(func(title, body string, data map[string]interface{}) {
+		assert.Equal(t, "heartbeat", data["type"])
+		notified = append(notified, body)
+	})
+	ctx := context.Background()
+
+	assert.Equal(t, StatusHeld, s.Tick(ctx, at("23:00").AddDate(0, 0, -1)).Status)
+	assert.Equal(t, StatusHeld, s.Tick(ctx, at("06:30")).Status)
+	assert.Empty(t, notified)
+	require.NotNil(t, s.State().Held)
+
+	// 免打扰结束后的第一次 tick 投递最新的提醒，即使未到检查间隔
+	assert.Equal(t, StatusSkipped, s.Tick(ctx, at("07:00")).Status)
+	assert.Equal(t, []string{"Backup failed again, disk is full."}, notified)
+	assert.Nil(t, s.State().Held)
+}
+
+func TestCheckAndFailures(t *testing.T) {
+	agent := &fakeAgent{replies: []string{"Backup failed."}}
+	s, delivered := newTestService(t, config.HeartbeatConfig{Channel: "telegram", To: "1001"}, agent)
+
+	result := s.Check(context.Background())
+	assert.Equal(t, StatusAlert, result.Status)
+	assert.Equal(t, "Backup failed.", result.Alert)
+	assert.Empty(t, *delivered, "manual checks never deliver")
+	assert.True(t, s.State().LastRun.IsZero())
+	assert.Empty(t, s.History().GetRecords(JobID, 0), "manual checks are not recorded")
+
+	agent.err = errors.New("provider unavailable")
+	result = s.Tick(context.Background(), at("09:00"))
+	assert.Equal(t, StatusFailed, result.Status)
+	assert.Equal(t, "provider unavailable", result.Error)
+	assert.Equal(t, "failed", s.History().GetRecords(JobID, 1)[0].Status)
+
+	require.NoError(t, os.WriteFile(filepath.Join(s.workspace, "memory", "heartbeat.md"), []byte("# Heartbeat\n\n## Focus Now\n- \n"), 0644))
+	agent.err = nil
+	calls := len(agent.prompts)
+	assert.Equal(t, StatusEmpty, s.Tick(context.Background(), at("10:00")).Status)
+	assert.Len(t, agent.prompts, calls, "an empty checklist does not call the model")
+
+	_, err := NewService(t.TempDir(), config.HeartbeatConfig{QuietHours: "late"}, nil)
+	assert.Error(t, err)
+}
+
+func TestTickDoesNotHoldLockDuringRun(t *testing.T) {
+	s, _ := newTestService(t, config.HeartbeatConfig{IntervalMinutes: 30}, &fakeAgent{})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.SetRunner(func(ctx context.Context, prompt string) (string, error) {
+		close(started)
+		<-release
+		return Sentinel, nil
+	})
+
+	done := make(chan Result)
+	go func() { done <- s.Tick(context.Background(), at("09:00")) }()
+	<-started
+
+	stateRead := make(chan State)
+	go func() { stateRead <- s.State() }()
+	select {
+	case state := <-stateRead:
+		assert.True(t, state.LastRun.IsZero())
+	case <-time.After(2 * time.Second):
+		t.Fatal("State blocked while the model call was running")
+	}
+	assert.Equal(t, StatusSkipped, s.Tick(context.Background(), at("09:01")).Status, "overlapping ticks are skipped")
+
+	close(release)
+	assert.Equal(t, StatusOK, (<-done).Status)
+	assert.Equal(t, at("09:00"), s.State().LastRun)
+}
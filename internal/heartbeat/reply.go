@@ -0,0 +1,94 @@
+package heartbeat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sentinel 模型没有需要提醒的内容时的回复
+const Sentinel = "HEARTBEAT_OK"
+
+// maxAckLength 与 Sentinel 一起出现的附带文字不超过该长度时仍视为无需提醒
+const maxAckLength = 300
+
+// ParseReply 解析检查结果：回复为空或以 Sentinel 开头/结尾且附带文字很短时返回 nothing=true；
+// 否则返回去掉 Sentinel 后需要投递的提醒
+func ParseReply(reply string) (alert string, nothing bool) {
+	text := strings.TrimSpace(reply)
+	if text == "" {
+		return "", true
+	}
+	const decoration = "*`_ \t\r\n.!:-"
+	clean := strings.Trim(text, decoration)
+	var rest string
+	switch {
+	case strings.HasPrefix(clean, Sentinel):
+		rest = clean[len(Sentinel):]
+	case strings.HasSuffix(clean, Sentinel):
+		rest = clean[:len(clean)-len(Sentinel)]
+	case strings.Contains(text, Sentinel):
+		return strings.TrimSpace(strings.ReplaceAll(text, Sentinel, "")), false
+	default:
+		return text, false
+	}
+	rest = strings.Trim(rest, decoration)
+	if utf8.RuneCountInString(rest) <= maxAckLength {
+		return "", true
+	}
+	return rest, false
+}
+
+// Fingerprint 用于去重的提醒指纹，忽略大小写与空白差异
+func Fingerprint(alert string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(alert), " "))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:8])
+}
+
+// LoadHeartbeat 读取 memory/heartbeat.md，兼容根目录 heartbeat.md（与 ContextBuilder 一致）
+func LoadHeartbeat(workspace string) string {
+	for _, path := range []string{
+		filepath.Join(workspace, "memory", "heartbeat.md"),
+		filepath.Join(workspace, "heartbeat.md"),
+	} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if text := strings.TrimSpace(string(content)); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// hasChecklist 除标题、空列表项与注释外是否还有内容；没有时跳过检查，不调用模型
+func hasChecklist(content string) bool {
+	inComment := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if inComment {
+			if strings.Contains(line, "-->") {
+				inComment = false
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "<!--") {
+			inComment = !strings.Contains(line, "-->")
+			continue
+		}
+		switch strings.TrimSpace(strings.TrimLeft(line, "-*+ ")) {
+		case "", "[ ]", "[x]", "[X]":
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		return true
+	}
+	return false
+}
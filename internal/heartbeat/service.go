@@ -0,0 +1,387 @@
+package heartbeat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/cron"
+	"github.com/Lichas/maxclaw/internal/logging"
+	"github.com/Lichas/maxclaw/internal/telemetry"
+)
+
+// JobID 心跳在执行历史中的任务 ID
+const JobID = "heartbeat"
+
+// SessionKey 心跳使用的独立会话
+const SessionKey = "heartbeat"
+
+const (
+	runTimeout   = 10 * time.Minute
+	tickInterval = time.Minute
+)
+
+// 检查结果状态
+const (
+	StatusSkipped   = "skipped"   // 不在活跃时段或未到检查间隔
+	StatusEmpty     = "empty"     // heartbeat.md 没有检查项，未调用模型
+	StatusOK        = "ok"        // 模型回复 HEARTBEAT_OK，不投递
+	StatusAlert     = "alert"     // 已投递提醒
+	StatusHeld      = "held"      // 免打扰时段，提醒推迟投递
+	StatusDuplicate = "duplicate" // 去重窗口内已投递过相同提醒
+	StatusFailed    = "failed"
+)
+
+// RunFunc 在独立会话中执行一次检查，返回模型回复
+type RunFunc func(ctx context.Context, prompt string) (string, error)
+
+// DeliverFunc 把提醒投递到频道会话
+type DeliverFunc func(channel, to, content string) error
+
+// Result 一次检查的结果
+type Result struct {
+	Status string `json:"status"`
+	Reply  string `json:"reply,omitempty"`
+	Alert  string `json:"alert,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HeldAlert 免打扰时段内推迟的提醒
+type HeldAlert struct {
+	Text        string    `json:"text"`
+	Fingerprint string    `json:"fingerprint"`
+	Created     time.Time `json:"created"`
+}
+
+// State 持久化的运行状态，gateway 重启后继续按间隔检查并保留去重记录
+type State struct {
+	LastRun    time.Time            `json:"lastRun,omitzero"`
+	LastStatus string               `json:"lastStatus,omitempty"`
+	LastAlert  time.Time            `json:"lastAlert,omitzero"`
+	Sent       map[string]time.Time `json:"sent,omitempty"`
+	Held       *HeldAlert           `json:"held,omitempty"`
+}
+
+// Service 按间隔在活跃时段内执行心跳检查，并处理去重、免打扰与投递
+type Service struct {
+	mu        sync.Mutex
+	workspace string
+	statePath string
+	cfg       config.HeartbeatConfig
+	loc       *time.Location
+	active    *config.HoursWindow
+	quiet     *config.HoursWindow
+	history   *cron.HistoryStore
+	state     State
+	running   bool // Tick 的检查进行中，避免重叠执行
+	onRun     RunFunc
+	onDeliver DeliverFunc
+	onNotify  cron.NotificationFunc
+}
+
+// StatePath 心跳状态文件
+func StatePath(workspace string) string {
+	return filepath.Join(workspace, ".cron", "heartbeat_state.json")
+}
+
+// NewService 创建心跳服务；history 为空时打开 workspace 中与定时任务共享的执行历史
+func NewService(workspace string, cfg config.HeartbeatConfig, history *cron.HistoryStore) (*Service, error) {
+	s := &Service{
+		workspace: workspace,
+		statePath: StatePath(workspace),
+		cfg:       cfg,
+		loc:       cfg.Location(),
+		history:   history,
+	}
+	if cfg.ActiveHours != "" {
+		w, err := config.ParseHoursWindow(cfg.ActiveHours)
+		if err != nil {
+			return nil, fmt.Errorf("heartbeat.activeHours: %w", err)
+		}
+		s.active = &w
+	}
+	if cfg.QuietHours != "" {
+		w, err := config.ParseHoursWindow(cfg.QuietHours)
+		if err != nil {
+			return nil, fmt.Errorf("heartbeat.quietHours: %w", err)
+		}
+		s.quiet = &w
+	}
+	if s.history == nil {
+		s.history = cron.NewHistoryStore(filepath.Join(workspace, ".cron", "cron_history.json"))
+	}
+	s.loadState()
+	return s, nil
+}
+
+// SetRunner 设置 agent 执行函数
+func (s *Service) SetRunner(run RunFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRun = run
+}
+
+// SetDeliverer 设置频道投递函数
+func (s *Service) SetDeliverer(deliver DeliverFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onDeliver = deliver
+}
+
+// SetNotificationHandler 设置通知处理器；未配置 heartbeat.channel 时提醒写入通知
+func (s *Service) SetNotificationHandler(handler cron.NotificationFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onNotify = handler
+}
+
+// State 当前运行状态
+func (s *Service) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.state
+	if state.Held != nil {
+		held := *state.Held
+		state.Held = &held
+	}
+	return state
+}
+
+// History 执行历史
+func (s *Service) History() *cron.HistoryStore {
+	return s.history
+}
+
+// Start 每分钟检查一次是否到期，阻塞直到 ctx 结束
+func (s *Service) Start(ctx context.Context) {
+	s.Tick(ctx, time.Now())
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.Tick(ctx, now)
+		}
+	}
+}
+
+// Tick 先投递已过免打扰时段的提醒，再在活跃时段内且到达间隔时执行一次检查；
+// 调用模型期间不持有锁，State 等调用不会被阻塞
+func (s *Service) Tick(ctx context.Context, now time.Time) Result {
+	s.mu.Lock()
+	local := now.In(s.loc)
+	s.releaseHeldLocked(now, local)
+	if s.active != nil && !s.active.Contains(local) {
+		s.mu.Unlock()
+		return Result{Status: StatusSkipped}
+	}
+	if s.running || (!s.state.LastRun.IsZero() && now.Sub(s.state.LastRun) < s.cfg.Interval()) {
+		s.mu.Unlock()
+		return Result{Status: StatusSkipped}
+	}
+	s.running = true
+	run := s.onRun
+	s.mu.Unlock()
+
+	result := s.check(ctx, now, run, true)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.state.LastRun = now
+	if result.Status == StatusAlert {
+		s.dispatchLocked(now, local, &result)
+	}
+	s.state.LastStatus = result.Status
+	s.saveStateLocked()
+	s.log(slog.LevelInfo, "heartbeat checked", "status", result.Status, "alert", logging.Truncate(result.Alert, 200), "err", result.Error)
+	return result
+}
+
+// Check 立即执行一次检查但不投递、不修改状态、不写执行历史，供手动试运行；
+// 去重窗口内已投递过的提醒标记为 duplicate
+func (s *Service) Check(ctx context.Context) Result {
+	s.mu.Lock()
+	run := s.onRun
+	s.mu.Unlock()
+
+	now := time.Now()
+	result := s.check(ctx, now, run, false)
+	if result.Status == StatusAlert {
+		s.mu.Lock()
+		if s.sentRecentlyLocked(Fingerprint(result.Alert), now) {
+			result.Status = StatusDuplicate
+		}
+		s.mu.Unlock()
+	}
+	return result
+}
+
+// Prompt 发给模型的检查提示词
+func (s *Service) Prompt(now time.Time) string {
+	instructions := strings.TrimSpace(s.cfg.Prompt)
+	if instructions == "" {
+		instructions = "This is not a user message. Follow the Heartbeat section of your instructions (memory/heartbeat.md) and check whatever it asks for."
+	}
+	return fmt.Sprintf("[Heartbeat] Scheduled check-in at %s.\n%s\n\nIf nothing needs the user's attention right now, reply with exactly %s and nothing else. Otherwise reply with a short message addressed to the user, without %s.",
+		now.In(s.loc).Format("2006-01-02 15:04 MST"), instructions, Sentinel, Sentinel)
+}
+
+// check 调用模型执行检查，调用方不持有 s.mu；record 为 true 时写入执行历史与遥测
+func (s *Service) check(ctx context.Context, now time.Time, run RunFunc, record bool) Result {
+	if !hasChecklist(LoadHeartbeat(s.workspace)) {
+		return Result{Status: StatusEmpty}
+	}
+	if run == nil {
+		return Result{Status: StatusFailed, Error: "heartbeat runner not configured"}
+	}
+
+	rec := cron.ExecutionRecord{
+		ID:        fmt.Sprintf("exec_%d", time.Now().UnixNano()),
+		JobID:     JobID,
+		JobTitle:  "heartbeat",
+		Trigger:   "heartbeat",
+		StartedAt: time.Now(),
+		Status:    "running",
+	}
+	if record {
+		s.history.AddRecord(rec)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, runTimeout)
+	start := time.Now()
+	reply, err := run(runCtx, s.Prompt(now))
+	cancel()
+	elapsed := time.Since(start)
+
+	if record {
+		telemetry.RecordCronRun("heartbeat", elapsed, err)
+		ended := time.Now()
+		s.history.UpdateRecord(rec.ID, func(r *cron.ExecutionRecord) {
+			r.EndedAt = &ended
+			r.Duration = elapsed.Milliseconds()
+			r.Output = reply
+			if err != nil {
+				r.Status = "failed"
+				r.Error = err.Error()
+			} else {
+				r.Status = "success"
+			}
+		})
+	}
+	if err != nil {
+		return Result{Status: StatusFailed, Reply: reply, Error: err.Error()}
+	}
+
+	alert, nothing := ParseReply(reply)
+	if nothing {
+		return Result{Status: StatusOK, Reply: reply}
+	}
+	return Result{Status: StatusAlert, Reply: reply, Alert: alert}
+}
+
+// dispatchLocked 去重后投递提醒；免打扰时段内只保留最新一条，时段结束后投递
+func (s *Service) dispatchLocked(now, local time.Time, result *Result) {
+	fingerprint := Fingerprint(result.Alert)
+	if s.sentRecentlyLocked(fingerprint, now) {
+		result.Status = StatusDuplicate
+		return
+	}
+	if s.quiet != nil && s.quiet.Contains(local) {
+		s.state.Held = &HeldAlert{Text: result.Alert, Fingerprint: fingerprint, Created: now}
+		result.Status = StatusHeld
+		return
+	}
+	if err := s.deliverLocked(result.Alert); err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		return
+	}
+	s.markSentLocked(fingerprint, now)
+}
+
+// releaseHeldLocked 免打扰时段结束后投递推迟的提醒；投递失败时丢弃，避免每分钟重试
+func (s *Service) releaseHeldLocked(now, local time.Time) {
+	held := s.state.Held
+	if held == nil || (s.quiet != nil && s.quiet.Contains(local)) {
+		return
+	}
+	s.state.Held = nil
+	if !s.sentRecentlyLocked(held.Fingerprint, now) {
+		if err := s.deliverLocked(held.Text); err != nil {
+			s.log(slog.LevelWarn, "heartbeat held alert delivery failed", "err", err)
+		} else {
+			s.markSentLocked(held.Fingerprint, now)
+		}
+	}
+	s.saveStateLocked()
+}
+
+func (s *Service) deliverLocked(alert string) error {
+	if s.cfg.Channel != "" {
+		if s.onDeliver == nil {
+			return fmt.Errorf("heartbeat deliverer not configured")
+		}
+		return s.onDeliver(s.cfg.Channel, s.cfg.To, alert)
+	}
+	if s.onNotify == nil {
+		return fmt.Errorf("heartbeat notification handler not configured")
+	}
+	s.onNotify("Heartbeat", alert, map[string]interface{}{"type": "heartbeat"})
+	return nil
+}
+
+func (s *Service) sentRecentlyLocked(fingerprint string, now time.Time) bool {
+	sent, ok := s.state.Sent[fingerprint]
+	return ok && now.Sub(sent) < s.cfg.DedupeWindow()
+}
+
+func (s *Service) markSentLocked(fingerprint string, now time.Time) {
+	if s.state.Sent == nil {
+		s.state.Sent = make(map[string]time.Time)
+	}
+	for fp, sent := range s.state.Sent {
+		if now.Sub(sent) >= s.cfg.DedupeWindow() {
+			delete(s.state.Sent, fp)
+		}
+	}
+	s.state.Sent[fingerprint] = now
+	s.state.LastAlert = now
+}
+
+func (s *Service) loadState() {
+	data, err := os.ReadFile(s.statePath)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &s.state)
+}
+
+func (s *Service) saveStateLocked() {
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(s.statePath), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(s.statePath, data, 0644)
+	}
+	if err != nil {
+		s.log(slog.LevelWarn, "heartbeat state save failed", "path", s.statePath, "err", err)
+	}
+}
+
+func (s *Service) log(level slog.Level, msg string, args ...any) {
+	if lg := logging.Get(); lg != nil && lg.Cron != nil {
+		lg.Cron.Log(context.Background(), level, msg, args...)
+	}
+}
@@ -0,0 +1,131 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type correlationKey struct{}
+
+// Correlation 随 context 传递的关联 ID，自动写入每一条日志
+type Correlation struct {
+	RequestID string
+	Session   string
+	Channel   string
+	ChatID    string
+	// Turn 本次请求内的 LLM 轮次，从 1 开始；0 表示不在轮次内
+	Turn int
+}
+
+// CorrelationFrom 读取 ctx 中的关联 ID
+func CorrelationFrom(ctx context.Context) Correlation {
+	if ctx == nil {
+		return Correlation{}
+	}
+	c, _ := ctx.Value(correlationKey{}).(Correlation)
+	return c
+}
+
+func withCorrelation(ctx context.Context, update func(*Correlation)) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	c := CorrelationFrom(ctx)
+	update(&c)
+	return context.WithValue(ctx, correlationKey{}, c)
+}
+
+// WithRequestID 标记一次入站请求的处理
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return withCorrelation(ctx, func(c *Correlation) { c.RequestID = requestID })
+}
+
+// WithSession 记录渠道、会话与聊天 ID；空值不会覆盖已有值
+func WithSession(ctx context.Context, channel, chatID, sessionKey string) context.Context {
+	return withCorrelation(ctx, func(c *Correlation) {
+		if channel != "" {
+			c.Channel = channel
+		}
+		if chatID != "" {
+			c.ChatID = chatID
+		}
+		if sessionKey != "" {
+			c.Session = sessionKey
+		}
+	})
+}
+
+// WithTurn 记录当前 LLM 轮次
+func WithTurn(ctx context.Context, turn int) context.Context {
+	return withCorrelation(ctx, func(c *Correlation) { c.Turn = turn })
+}
+
+// NewRequestID 生成 16 位十六进制请求 ID
+func NewRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// contextHandler 把 ctx 中的关联 ID 与 trace_id 追加到记录上；调用方显式传入的同名字段优先
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := correlationAttrs(ctx)
+	if len(attrs) > 0 {
+		present := make(map[string]bool, r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool {
+			present[a.Key] = true
+			return true
+		})
+		for _, a := range attrs {
+			if !present[a.Key] {
+				r.AddAttrs(a)
+			}
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+func correlationAttrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	var attrs []slog.Attr
+	c := CorrelationFrom(ctx)
+	if c.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", c.RequestID))
+	}
+	if c.Session != "" {
+		attrs = append(attrs, slog.String("session", c.Session))
+	}
+	if c.Channel != "" {
+		attrs = append(attrs, slog.String("channel", c.Channel))
+	}
+	if c.ChatID != "" {
+		attrs = append(attrs, slog.String("chat_id", c.ChatID))
+	}
+	if c.Turn > 0 {
+		attrs = append(attrs, slog.Int("turn", c.Turn))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+	}
+	return attrs
+}
@@ -2,32 +2,93 @@ package logging
 
 import (
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
 
+// 各组件对应的日志文件名（不含 .log 后缀）
+const (
+	ComponentGateway  = "gateway"
+	ComponentSession  = "session"
+	ComponentTools    = "tools"
+	ComponentChannels = "channels"
+	ComponentCron     = "cron"
+	ComponentWeb      = "webui"
+	ComponentAudit    = "audit"
+)
+
+// Components 所有组件，顺序即 maxclaw logs 默认读取顺序
+var Components = []string{
+	ComponentGateway,
+	ComponentSession,
+	ComponentTools,
+	ComponentChannels,
+	ComponentCron,
+	ComponentWeb,
+	ComponentAudit,
+}
+
+// IsComponent 判断 name 是否为已知组件
+func IsComponent(name string) bool {
+	for _, component := range Components {
+		if component == name {
+			return true
+		}
+	}
+	return false
+}
+
 type Loggers struct {
-	Gateway  *log.Logger
-	Session  *log.Logger
-	Tools    *log.Logger
-	Channels *log.Logger
-	Cron     *log.Logger
-	Web      *log.Logger
-
-	files []*os.File
+	Gateway  *slog.Logger
+	Session  *slog.Logger
+	Tools    *slog.Logger
+	Channels *slog.Logger
+	Cron     *slog.Logger
+	Web      *slog.Logger
+	Audit    *slog.Logger
+
+	dir     string
+	writers []*RotatingWriter
+}
+
+// Options 日志级别与轮转策略，零值使用默认值
+type Options struct {
+	// Level debug / info / warn / error，默认 info
+	Level string
+	// MaxSizeMB 单个文件超过该大小后轮转，默认 20
+	MaxSizeMB int
+	// MaxBackups 每个组件保留的历史文件数，默认 5
+	MaxBackups int
+	// MaxAgeDays 历史文件保留天数，默认 14
+	MaxAgeDays int
 }
 
+const (
+	defaultMaxSizeMB  = 20
+	defaultMaxBackups = 5
+	defaultMaxAgeDays = 14
+)
+
 var (
 	once    sync.Once
 	loggers *Loggers
 	initErr error
+
+	level = new(slog.LevelVar)
 )
 
-// Init sets up ~/.maxclaw/logs files. Safe to call multiple times.
+// Init sets up ~/.maxclaw/logs files with default options. Safe to call multiple times.
 func Init(baseDir string) (*Loggers, error) {
+	return InitWithOptions(baseDir, Options{})
+}
+
+// InitWithOptions 初始化 JSON 结构化日志，按大小与日期轮转；仅首次调用生效
+func InitWithOptions(baseDir string, opts Options) (*Loggers, error) {
 	once.Do(func() {
 		if baseDir == "" {
 			initErr = fmt.Errorf("log base dir is empty")
@@ -38,67 +99,103 @@ func Init(baseDir string) (*Loggers, error) {
 			initErr = fmt.Errorf("failed to create log dir: %w", err)
 			return
 		}
+		if err := SetLevel(opts.Level); err != nil {
+			initErr = err
+			return
+		}
 
-		open := func(name string) (*log.Logger, *os.File, error) {
-			path := filepath.Join(logDir, name)
-			f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		l := &Loggers{dir: logDir}
+		open := func(component string) (*slog.Logger, error) {
+			w, err := NewRotatingWriter(filepath.Join(logDir, component+".log"), RotateOptions{
+				MaxSize:    int64(positiveOr(opts.MaxSizeMB, defaultMaxSizeMB)) * 1024 * 1024,
+				MaxBackups: positiveOr(opts.MaxBackups, defaultMaxBackups),
+				MaxAge:     time.Duration(positiveOr(opts.MaxAgeDays, defaultMaxAgeDays)) * 24 * time.Hour,
+			})
 			if err != nil {
-				return nil, nil, err
+				return nil, fmt.Errorf("open %s.log: %w", component, err)
 			}
-			l := log.New(f, "", log.LstdFlags|log.Lmicroseconds)
-			return l, f, nil
+			l.writers = append(l.writers, w)
+			return New(w, component), nil
 		}
 
-		l := &Loggers{}
-		var files []*os.File
-
-		var err error
-		l.Gateway, files, err = attach(open, files, "gateway.log")
-		if err != nil {
-			initErr = err
-			return
-		}
-		l.Session, files, err = attach(open, files, "session.log")
-		if err != nil {
-			initErr = err
-			return
-		}
-		l.Tools, files, err = attach(open, files, "tools.log")
-		if err != nil {
-			initErr = err
-			return
+		targets := map[string]**slog.Logger{
+			ComponentGateway:  &l.Gateway,
+			ComponentSession:  &l.Session,
+			ComponentTools:    &l.Tools,
+			ComponentChannels: &l.Channels,
+			ComponentCron:     &l.Cron,
+			ComponentWeb:      &l.Web,
+			ComponentAudit:    &l.Audit,
 		}
-		l.Channels, files, err = attach(open, files, "channels.log")
-		if err != nil {
-			initErr = err
-			return
-		}
-		l.Cron, files, err = attach(open, files, "cron.log")
-		if err != nil {
-			initErr = err
-			return
-		}
-		l.Web, files, err = attach(open, files, "webui.log")
-		if err != nil {
-			initErr = err
-			return
+		for _, component := range Components {
+			logger, err := open(component)
+			if err != nil {
+				l.Close()
+				initErr = err
+				return
+			}
+			*targets[component] = logger
 		}
 
-		l.files = files
 		loggers = l
-
-		l.Gateway.Printf("logging initialized at %s", logDir)
+		l.Gateway.Info("logging initialized", "dir", logDir, "level", level.Level().String())
 	})
 
 	return loggers, initErr
 }
 
-func attach(open func(string) (*log.Logger, *os.File, error), files []*os.File, name string) (*log.Logger, []*os.File, error) {
-	l, f, err := open(name)
+// New 创建写入 w 的 JSON 日志器，自动附带 component 与上下文中的关联 ID
+func New(w io.Writer, component string) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	return slog.New(&contextHandler{Handler: handler}).With("component", component)
+}
+
+// SetLevel 调整全局日志级别，空字符串视为 info
+func SetLevel(name string) error {
+	lvl, err := ParseLevel(name)
 	if err != nil {
-		return nil, files, fmt.Errorf("open %s: %w", name, err)
+		return err
 	}
-	return l, append(files, f), nil
+	level.Set(lvl)
+	return nil
+}
+
+// ParseLevel 解析 debug / info / warn / error（大小写不敏感）
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q", name)
+	}
+}
+
+// Dir 返回日志目录（未初始化时为空）
+func (l *Loggers) Dir() string {
+	if l == nil {
+		return ""
+	}
+	return l.dir
+}
+
+// Close 关闭所有日志文件
+func (l *Loggers) Close() error {
+	if l == nil {
+		return nil
+	}
+	var firstErr error
+	for _, w := range l.writers {
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
 
 // Get returns initialized loggers (may be nil if Init failed or not called).
@@ -118,3 +215,10 @@ func Truncate(s string, max int) string {
 func Timestamp() string {
 	return time.Now().Format(time.RFC3339)
 }
+
+func positiveOr(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
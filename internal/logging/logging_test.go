@@ -0,0 +1,201 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRotatingWriterRotatesBySizeAndPrunesBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tools.log")
+	now := time.Date(2026, 3, 8, 10, 0, 0, 0, time.Local)
+
+	w, err := NewRotatingWriter(path, RotateOptions{MaxSize: 10, MaxBackups: 2})
+	require.NoError(t, err)
+	w.now = func() time.Time { return now }
+	defer w.Close()
+
+	for i := 0; i < 5; i++ {
+		now = now.Add(time.Second)
+		_, err := w.Write([]byte("12345678\n"))
+		require.NoError(t, err)
+	}
+
+	files := RotatedFiles(path)
+	require.Len(t, files, 3, "two backups plus the active file")
+	assert.Equal(t, path, files[2])
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		assert.Equal(t, "12345678\n", string(data), "a record must never be split across files")
+	}
+	assert.Contains(t, filepath.Base(files[1]), "tools-20260308T100005.000")
+}
+
+func TestRotatingWriterRotatesDailyAndDropsExpired(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "session.log")
+	now := time.Date(2026, 3, 8, 23, 59, 0, 0, time.Local)
+
+	w, err := NewRotatingWriter(path, RotateOptions{MaxAge: 48 * time.Hour})
+	require.NoError(t, err)
+	w.now = func() time.Time { return now }
+	w.opened = now
+	defer w.Close()
+
+	_, err = w.Write([]byte("day1\n"))
+	require.NoError(t, err)
+	now = now.Add(2 * time.Minute)
+	_, err = w.Write([]byte("day2\n"))
+	require.NoError(t, err)
+	require.Len(t, RotatedFiles(path), 2)
+
+	// 超过保留期的历史文件在下一次轮转时被清理
+	now = now.Add(72 * time.Hour)
+	_, err = w.Write([]byte("day5\n"))
+	require.NoError(t, err)
+	files := RotatedFiles(path)
+	require.Len(t, files, 2)
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, "day2\n", string(data))
+}
+
+func TestLoggerAddsCorrelationFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, ComponentTools)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled,
+	}))
+	ctx = WithRequestID(ctx, "req-1")
+	ctx = WithSession(ctx, "telegram", "42", "telegram:42")
+	ctx = WithSession(ctx, "", "", "")
+	ctx = WithTurn(ctx, 2)
+
+	logger.InfoContext(ctx, "tool executed", "tool", "exec", "channel", "override")
+	logger.Debug("hidden at info level")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+	var record map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "tools", record["component"])
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, "telegram:42", record["session"])
+	assert.Equal(t, "42", record["chat_id"])
+	assert.Equal(t, float64(2), record["turn"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", record["trace_id"])
+	assert.Equal(t, "override", record["channel"], "explicit attributes win over context")
+	assert.Equal(t, 1, strings.Count(lines[0], `"channel"`))
+}
+
+func TestParseEntrySupportsJSONAndLegacyLines(t *testing.T) {
+	entry, ok := ParseEntry(`{"time":"2026-03-08T10:21:38.999+08:00","level":"WARN","msg":"tool failed","component":"tools","tool":"exec","result_len":12}`, "")
+	require.True(t, ok)
+	assert.Equal(t, "WARN", entry.Level)
+	assert.Equal(t, "tools", entry.Component)
+	assert.Equal(t, "tool failed", entry.Message)
+	assert.Equal(t, "exec", entry.Attr("tool"))
+	assert.Equal(t, "12", entry.Attr("result_len"))
+
+	entry, ok = ParseEntry(`2026/03/08 10:21:38.999215 tool name=exec args="{\"command\": \"ls -la\"}" result_len=10`, ComponentTools)
+	require.True(t, ok)
+	assert.Equal(t, "INFO", entry.Level)
+	assert.Equal(t, "tool", entry.Message)
+	assert.Equal(t, ComponentTools, entry.Component)
+	assert.Equal(t, "exec", entry.Attr("name"))
+	assert.Equal(t, `{"command": "ls -la"}`, entry.Attr("args"))
+	assert.Equal(t, 2026, entry.Time.Year())
+
+	entry, ok = ParseEntry("2026/03/08 10:21:38.999215 gateway shutdown", ComponentGateway)
+	require.True(t, ok)
+	assert.Equal(t, "gateway shutdown", entry.Message)
+
+	_, ok = ParseEntry("not a log line", "")
+	assert.False(t, ok)
+}
+
+func TestFilterMatch(t *testing.T) {
+	entry := Entry{
+		Time:    time.Now(),
+		Level:   "WARN",
+		Message: "tool failed",
+		Attrs:   map[string]any{"tool": "exec", "session": "telegram:42", "channel": "telegram", "err": "exit status 1"},
+	}
+	assert.True(t, Filter{}.Match(entry))
+	assert.True(t, Filter{Level: "warn", Tool: "exec", Session: "telegram:42", Channel: "telegram"}.Match(entry))
+	assert.True(t, Filter{Contains: "EXIT STATUS"}.Match(entry))
+	assert.False(t, Filter{Level: "error"}.Match(entry))
+	assert.False(t, Filter{Tool: "read_file"}.Match(entry))
+	assert.False(t, Filter{Session: "discord:1"}.Match(entry))
+	assert.False(t, Filter{Since: time.Now().Add(time.Hour)}.Match(entry))
+}
+
+func TestReadEntriesMergesComponentsAndRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	line := func(ts, level, msg, session string) string {
+		return `{"time":"` + ts + `","level":"` + level + `","msg":"` + msg + `","session":"` + session + `"}` + "\n"
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "session-20260307T235959.000.log"),
+		[]byte(line("2026-03-07T23:00:00Z", "INFO", "inbound", "a")), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "session.log"),
+		[]byte(line("2026-03-08T10:00:00Z", "INFO", "outbound", "a")+line("2026-03-08T10:00:02Z", "INFO", "inbound", "b")), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tools.log"),
+		[]byte(line("2026-03-08T10:00:01Z", "ERROR", "tool failed", "a")), 0644))
+
+	entries, err := ReadEntries(dir, Filter{Session: "a"}, 0)
+	require.NoError(t, err)
+	var msgs []string
+	for _, e := range entries {
+		msgs = append(msgs, e.Component+":"+e.Message)
+	}
+	assert.Equal(t, []string{"session:inbound", "session:outbound", "tools:tool failed"}, msgs)
+
+	entries, err = ReadEntries(dir, Filter{}, 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "tool failed", entries[0].Message)
+	assert.Equal(t, "b", entries[1].Attr("session"))
+
+	entries, err = ReadEntries(dir, Filter{Components: []string{ComponentTools}, Level: "error"}, 10)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestFollowFileReadsOnlyCompleteNewLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tools.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"time":"2026-03-08T10:00:00Z","level":"INFO","msg":"old"}`+"\n"), 0644))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"time":"2026-03-08T10:00:01Z","level":"INFO","msg":"new"}` + "\n" + `{"time":"2026-03-08T10:00:02Z"`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	var got []string
+	offset, err := followFile(path, ComponentTools, info.Size(), Filter{}, func(e Entry) { got = append(got, e.Message) })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new"}, got)
+
+	// 文件被轮转（变小）后从头读取
+	require.NoError(t, os.WriteFile(path, []byte(`{"time":"2026-03-08T10:00:03Z","level":"INFO","msg":"rotated"}`+"\n"), 0644))
+	got = nil
+	_, err = followFile(path, ComponentTools, offset, Filter{}, func(e Entry) { got = append(got, e.Message) })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rotated"}, got)
+}
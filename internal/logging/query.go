@@ -0,0 +1,320 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// legacyTimeFormat 旧版 log.Logger 文本行的时间前缀
+const legacyTimeFormat = "2006/01/02 15:04:05.000000"
+
+// slog JSON 记录的内置字段
+const (
+	slogTimeKey    = "time"
+	slogLevelKey   = "level"
+	slogMessageKey = "msg"
+)
+
+// Entry 解析后的一条日志
+type Entry struct {
+	Time      time.Time      `json:"time"`
+	Level     string         `json:"level"`
+	Component string         `json:"component,omitempty"`
+	Message   string         `json:"msg"`
+	Attrs     map[string]any `json:"attrs,omitempty"`
+}
+
+// Attr 以字符串形式返回字段值，不存在时为空
+func (e Entry) Attr(key string) string {
+	v, ok := e.Attrs[key]
+	if !ok || v == nil {
+		return ""
+	}
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		raw, _ := json.Marshal(val)
+		return string(raw)
+	}
+}
+
+// ParseEntry 解析一行 JSON 日志；也兼容升级前的文本行（key=value 尽量拆成字段）
+func ParseEntry(line, component string) (Entry, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return Entry{}, false
+	}
+	if strings.HasPrefix(line, "{") {
+		return parseJSONEntry(line, component)
+	}
+	return parseLegacyEntry(line, component)
+}
+
+func parseJSONEntry(line, component string) (Entry, bool) {
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(line), &raw); err != nil {
+		return Entry{}, false
+	}
+	e := Entry{Component: component}
+	if v, ok := raw[slogTimeKey].(string); ok {
+		e.Time, _ = time.Parse(time.RFC3339Nano, v)
+	}
+	e.Level, _ = raw[slogLevelKey].(string)
+	e.Message, _ = raw[slogMessageKey].(string)
+	if v, ok := raw["component"].(string); ok && v != "" {
+		e.Component = v
+	}
+	for _, key := range []string{slogTimeKey, slogLevelKey, slogMessageKey, "component"} {
+		delete(raw, key)
+	}
+	if len(raw) > 0 {
+		e.Attrs = raw
+	}
+	return e, true
+}
+
+func parseLegacyEntry(line, component string) (Entry, bool) {
+	if len(line) <= len(legacyTimeFormat) {
+		return Entry{}, false
+	}
+	at, err := time.ParseInLocation(legacyTimeFormat, line[:len(legacyTimeFormat)], time.Local)
+	if err != nil {
+		return Entry{}, false
+	}
+	rest := strings.TrimSpace(line[len(legacyTimeFormat):])
+
+	e := Entry{Time: at, Level: "INFO", Component: component, Message: rest}
+	attrs := map[string]any{}
+	var words []string
+	for rest != "" {
+		token, remainder := nextLegacyToken(rest)
+		rest = remainder
+		key, value, ok := strings.Cut(token, "=")
+		if !ok || key == "" || strings.ContainsAny(key, `"' `) {
+			if len(attrs) == 0 {
+				words = append(words, token)
+			}
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		attrs[key] = value
+	}
+	if len(attrs) > 0 {
+		e.Message = strings.Join(words, " ")
+		e.Attrs = attrs
+	}
+	return e, true
+}
+
+// nextLegacyToken 切出下一个以空格分隔的片段，key="..." 中的空格不会被拆开
+func nextLegacyToken(s string) (string, string) {
+	s = strings.TrimLeft(s, " ")
+	if eq := strings.Index(s, `="`); eq > 0 && !strings.Contains(s[:eq], " ") {
+		if quoted, err := strconv.QuotedPrefix(s[eq+1:]); err == nil {
+			end := eq + 1 + len(quoted)
+			return s[:end], strings.TrimLeft(s[end:], " ")
+		}
+	}
+	token, rest, _ := strings.Cut(s, " ")
+	return token, strings.TrimLeft(rest, " ")
+}
+
+// Filter 日志筛选条件，空字段表示不限制
+type Filter struct {
+	Components []string
+	// Level 最低级别
+	Level     string
+	Session   string
+	Channel   string
+	Tool      string
+	RequestID string
+	// Contains 在消息与字段值中做子串匹配
+	Contains string
+	Since    time.Time
+}
+
+// Match 判断 e 是否满足筛选条件
+func (f Filter) Match(e Entry) bool {
+	if f.Level != "" {
+		min, err := ParseLevel(f.Level)
+		if err == nil {
+			lvl, err := ParseLevel(strings.SplitN(e.Level, "+", 2)[0])
+			if err == nil && lvl < min {
+				return false
+			}
+		}
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if f.Session != "" && e.Attr("session") != f.Session {
+		return false
+	}
+	if f.Channel != "" && e.Attr("channel") != f.Channel {
+		return false
+	}
+	if f.Tool != "" && e.Attr("tool") != f.Tool {
+		return false
+	}
+	if f.RequestID != "" && e.Attr("request_id") != f.RequestID {
+		return false
+	}
+	if f.Contains != "" && !entryContains(e, f.Contains) {
+		return false
+	}
+	return true
+}
+
+func entryContains(e Entry, needle string) bool {
+	needle = strings.ToLower(needle)
+	if strings.Contains(strings.ToLower(e.Message), needle) {
+		return true
+	}
+	for key := range e.Attrs {
+		if strings.Contains(strings.ToLower(e.Attr(key)), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Filter) components() []string {
+	if len(f.Components) == 0 {
+		return Components
+	}
+	return f.Components
+}
+
+// ReadEntries 从 dir 下各组件日志（含轮转历史）读取最近 limit 条匹配记录，按时间排序
+func ReadEntries(dir string, f Filter, limit int) ([]Entry, error) {
+	var all []Entry
+	for _, component := range f.components() {
+		files := RotatedFiles(filepath.Join(dir, component+".log"))
+		var matched []Entry
+		// 从最新文件往前读，够数即停
+		for i := len(files) - 1; i >= 0; i-- {
+			entries, err := readFileEntries(files[i], component, f)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			matched = append(entries, matched...)
+			if limit > 0 && len(matched) >= limit {
+				break
+			}
+		}
+		if limit > 0 && len(matched) > limit {
+			matched = matched[len(matched)-limit:]
+		}
+		all = append(all, matched...)
+	}
+
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Time.Before(all[j].Time) })
+	if limit > 0 && len(all) > limit {
+		all = all[len(all)-limit:]
+	}
+	return all, nil
+}
+
+func readFileEntries(path, component string, f Filter) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if e, ok := ParseEntry(scanner.Text(), component); ok && f.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// FollowInterval 跟踪日志时的轮询间隔
+var FollowInterval = 500 * time.Millisecond
+
+// Follow 从各组件日志的当前末尾开始，持续把新写入的匹配记录交给 fn，直到 ctx 结束；
+// 文件被轮转后自动从新文件开头继续
+func Follow(ctx context.Context, dir string, f Filter, fn func(Entry)) error {
+	offsets := map[string]int64{}
+	for _, component := range f.components() {
+		if info, err := os.Stat(filepath.Join(dir, component+".log")); err == nil {
+			offsets[component] = info.Size()
+		}
+	}
+
+	ticker := time.NewTicker(FollowInterval)
+	defer ticker.Stop()
+	for {
+		for _, component := range f.components() {
+			path := filepath.Join(dir, component+".log")
+			next, err := followFile(path, component, offsets[component], f, fn)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			offsets[component] = next
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// followFile 读取 offset 之后的完整行，返回新的 offset
+func followFile(path, component string, offset int64, f Filter, fn func(Entry)) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return offset, err
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if info.Size() == offset {
+		return offset, nil
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// 未写完的行留到下一轮
+			return offset, nil
+		}
+		offset += int64(len(line))
+		if e, ok := ParseEntry(line, component); ok && f.Match(e) {
+			fn(e)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat 历史文件名中的时间戳，如 session-20260308T102138.000.log
+const backupTimeFormat = "20060102T150405.000"
+
+// RotateOptions 轮转策略；MaxSize<=0 表示不按大小轮转，MaxBackups/MaxAge<=0 表示不限制
+type RotateOptions struct {
+	MaxSize    int64
+	MaxBackups int
+	MaxAge     time.Duration
+}
+
+// RotatingWriter 追加写入日志文件，超过大小或跨天时轮转，并清理过期历史文件
+type RotatingWriter struct {
+	path string
+	opts RotateOptions
+	now  func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// NewRotatingWriter 打开（或创建）path 并准备轮转
+func NewRotatingWriter(path string, opts RotateOptions) (*RotatingWriter, error) {
+	w := &RotatingWriter{path: path, opts: opts, now: time.Now}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	// 沿用已有文件时以其修改时间判断是否跨天
+	w.opened = w.now()
+	if w.size > 0 {
+		w.opened = info.ModTime()
+	}
+	return nil
+}
+
+// Write 实现 io.Writer；单条记录不会被拆分到两个文件
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *RotatingWriter) shouldRotate(incoming int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.opts.MaxSize > 0 && w.size+incoming > w.opts.MaxSize {
+		return true
+	}
+	now := w.now()
+	return !sameDay(w.opened, now)
+}
+
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	backup := backupName(w.path, w.now())
+	if err := os.Rename(w.path, backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotate %s: %w", filepath.Base(w.path), err)
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.prune()
+	return nil
+}
+
+// prune 按数量与时间清理历史文件，失败时静默跳过
+func (w *RotatingWriter) prune() {
+	backups := listBackups(w.path)
+	now := w.now()
+	keep := 0
+	// 从最新的开始保留
+	for i := len(backups) - 1; i >= 0; i-- {
+		b := backups[i]
+		expired := w.opts.MaxAge > 0 && now.Sub(b.at) > w.opts.MaxAge
+		overflow := w.opts.MaxBackups > 0 && keep >= w.opts.MaxBackups
+		if expired || overflow {
+			_ = os.Remove(b.path)
+			continue
+		}
+		keep++
+	}
+}
+
+// Close 关闭当前文件
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+type backupFile struct {
+	path string
+	at   time.Time
+}
+
+func backupName(path string, at time.Time) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	name := fmt.Sprintf("%s-%s%s", base, at.Format(backupTimeFormat), ext)
+	// 同一毫秒内多次轮转时追加序号，避免覆盖
+	for i := 1; ; i++ {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return name
+		}
+		name = fmt.Sprintf("%s-%s.%d%s", base, at.Format(backupTimeFormat), i, ext)
+	}
+}
+
+// listBackups 返回 path 的历史文件，按时间从旧到新排序
+func listBackups(path string) []backupFile {
+	ext := filepath.Ext(path)
+	prefix := filepath.Base(strings.TrimSuffix(path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+
+	var backups []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if len(stamp) < len(backupTimeFormat) {
+			continue
+		}
+		at, err := time.ParseInLocation(backupTimeFormat, stamp[:len(backupTimeFormat)], time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(filepath.Dir(path), name), at: at})
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		if backups[i].at.Equal(backups[j].at) {
+			// 无序号的先生成
+			if len(backups[i].path) != len(backups[j].path) {
+				return len(backups[i].path) < len(backups[j].path)
+			}
+			return backups[i].path < backups[j].path
+		}
+		return backups[i].at.Before(backups[j].at)
+	})
+	return backups
+}
+
+// RotatedFiles 返回 path 的全部历史文件与当前文件，按时间从旧到新排序
+func RotatedFiles(path string) []string {
+	backups := listBackups(path)
+	files := make([]string, 0, len(backups)+1)
+	for _, b := range backups {
+		files = append(files, b.path)
+	}
+	return append(files, path)
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,304 @@
+package media
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 支持的文档格式
+const (
+	DocumentPDF  = "pdf"
+	DocumentDOCX = "docx"
+	DocumentXLSX = "xlsx"
+	DocumentPPTX = "pptx"
+	DocumentEPUB = "epub"
+)
+
+// DefaultDocumentPageLimit 未指定 limit 时每次读取的页数（PDF 页 / 工作表分段 / 幻灯片 / 章节）
+const DefaultDocumentPageLimit = 20
+
+// maxDocumentFileSize 拒绝解析过大的文档，避免占满内存
+const maxDocumentFileSize = 200 << 20
+
+var documentMimeTypes = map[string]string{
+	"application/pdf":      DocumentPDF,
+	"application/x-pdf":    DocumentPDF,
+	"application/epub+zip": DocumentEPUB,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   DocumentDOCX,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         DocumentXLSX,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": DocumentPPTX,
+}
+
+// DocumentPage 文档中可独立定位的一段内容
+type DocumentPage struct {
+	// Anchor 定位标签，如 "Page 3"、"Sheet: Sales (rows 1-200)"、"Slide 2"、"Chapter 4: ..."
+	Anchor string
+	Text   string
+}
+
+// Document 提取后的文档（Markdown 文本，按页分段）
+type Document struct {
+	Format string
+	Title  string
+	Author string
+	Pages  []DocumentPage
+}
+
+// DetectDocumentFormat 根据 MIME、扩展名和文件头识别文档格式；非文档返回空字符串
+func DetectDocumentFormat(path, mimeType string) string {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+	if format, ok := documentMimeTypes[mimeType]; ok {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		return DocumentPDF
+	case ".docx":
+		return DocumentDOCX
+	case ".xlsx", ".xlsm":
+		return DocumentXLSX
+	case ".pptx":
+		return DocumentPPTX
+	case ".epub":
+		return DocumentEPUB
+	}
+	return sniffDocumentFormat(path)
+}
+
+// sniffDocumentFormat 识别无扩展名或扩展名错误的文档（如上传后改名的附件）
+func sniffDocumentFormat(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head := make([]byte, 8)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return DocumentPDF
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+	default:
+		return ""
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return ""
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return ""
+	}
+	return zipDocumentFormat(zr)
+}
+
+func zipDocumentFormat(zr *zip.Reader) string {
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			return DocumentDOCX
+		case "xl/workbook.xml":
+			return DocumentXLSX
+		case "ppt/presentation.xml":
+			return DocumentPPTX
+		case "META-INF/container.xml":
+			return DocumentEPUB
+		}
+	}
+	return ""
+}
+
+type cachedDocument struct {
+	modTime time.Time
+	size    int64
+	doc     *Document
+	used    time.Time
+}
+
+var (
+	documentCacheMu sync.Mutex
+	documentCache   = make(map[string]*cachedDocument)
+)
+
+const documentCacheSize = 8
+
+// ExtractDocument 解析文档；同一文件未修改时复用上次结果，便于分页连续读取
+func ExtractDocument(path, mimeType string) (*Document, error) {
+	format := DetectDocumentFormat(path, mimeType)
+	if format == "" {
+		return nil, fmt.Errorf("unsupported document type: %s", filepath.Base(path))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxDocumentFileSize {
+		return nil, fmt.Errorf("document too large (%d MB, max %d MB)", info.Size()>>20, maxDocumentFileSize>>20)
+	}
+
+	key, _ := filepath.Abs(path)
+	documentCacheMu.Lock()
+	if cached, ok := documentCache[key]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		cached.used = time.Now()
+		documentCacheMu.Unlock()
+		return cached.doc, nil
+	}
+	documentCacheMu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseDocument(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", strings.ToUpper(format), err)
+	}
+	doc.Format = format
+
+	documentCacheMu.Lock()
+	defer documentCacheMu.Unlock()
+	if len(documentCache) >= documentCacheSize {
+		var oldest string
+		for k, v := range documentCache {
+			if oldest == "" || v.used.Before(documentCache[oldest].used) {
+				oldest = k
+			}
+		}
+		delete(documentCache, oldest)
+	}
+	documentCache[key] = &cachedDocument{modTime: info.ModTime(), size: info.Size(), doc: doc, used: time.Now()}
+	return doc, nil
+}
+
+func parseDocument(format string, data []byte) (*Document, error) {
+	if format == DocumentPDF {
+		return parsePDF(data)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	archive := &zipArchive{Reader: zr}
+	switch format {
+	case DocumentDOCX:
+		return parseDOCX(archive)
+	case DocumentXLSX:
+		return parseXLSX(archive)
+	case DocumentPPTX:
+		return parsePPTX(archive)
+	case DocumentEPUB:
+		return parseEPUB(archive)
+	}
+	return nil, fmt.Errorf("unsupported document format %q", format)
+}
+
+// PageUnit 分页单位名称（用于提示 offset/limit 的含义）
+func (d *Document) PageUnit() string {
+	switch d.Format {
+	case DocumentXLSX:
+		return "sheet sections"
+	case DocumentPPTX:
+		return "slides"
+	case DocumentEPUB:
+		return "chapters"
+	}
+	return "pages"
+}
+
+// Render 输出 [offset, offset+limit) 范围内的页，带锚点标题；limit<=0 使用默认值
+func (d *Document) Render(offset, limit int) (string, error) {
+	total := len(d.Pages)
+	if total == 0 {
+		return "", fmt.Errorf("document contains no extractable text")
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= total {
+		return "", fmt.Errorf("offset %d exceeds document length (%d %s)", offset, total, d.PageUnit())
+	}
+	if limit <= 0 {
+		limit = DefaultDocumentPageLimit
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	var b strings.Builder
+	header := fmt.Sprintf("[%s document", strings.ToUpper(d.Format))
+	if d.Title != "" {
+		header += fmt.Sprintf(": %s", d.Title)
+	}
+	if d.Author != "" {
+		header += fmt.Sprintf(" by %s", d.Author)
+	}
+	header += fmt.Sprintf(", %d %s]", total, d.PageUnit())
+	b.WriteString(header + "\n\n")
+
+	for _, page := range d.Pages[offset:end] {
+		b.WriteString("## " + page.Anchor + "\n\n")
+		text := strings.TrimSpace(page.Text)
+		if text == "" {
+			text = "(no text)"
+		}
+		b.WriteString(text + "\n\n")
+	}
+	if end < total {
+		fmt.Fprintf(&b, "... (showing %s %d-%d of %d; use offset=%d to continue)\n", d.PageUnit(), offset+1, end, total, end)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// DocumentContext 为入站附件生成上下文文本；超过 maxChars 时只包含前几页，并提示用 read_file 继续读取
+func DocumentContext(path, filename, mimeType string, maxChars int) (string, error) {
+	doc, err := ExtractDocument(path, mimeType)
+	if err != nil {
+		return "", err
+	}
+	pages := len(doc.Pages)
+	if maxChars > 0 {
+		size := 0
+		for i, page := range doc.Pages {
+			size += len(page.Anchor) + len(page.Text) + 8
+			if size > maxChars && i > 0 {
+				pages = i
+				break
+			}
+		}
+	}
+	rendered, err := doc.Render(0, pages)
+	if err != nil {
+		return "", err
+	}
+	for maxChars > 0 && len(rendered) > maxChars && pages > 1 {
+		pages--
+		if rendered, err = doc.Render(0, pages); err != nil {
+			return "", err
+		}
+	}
+	if maxChars > 0 && len(rendered) > maxChars {
+		// 单页已超出上限
+		cut := maxChars
+		for cut > 0 && !utf8.RuneStart(rendered[cut]) {
+			cut--
+		}
+		rendered = rendered[:cut] + "\n... (truncated; use read_file for the full text)"
+	}
+
+	name := strings.TrimSpace(filename)
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	return fmt.Sprintf("Attached document %q (path: %s; read more with read_file offset/limit in %s):\n\n%s",
+		name, path, doc.PageUnit(), rendered), nil
+}
@@ -0,0 +1,284 @@
+package media
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// EPUB 文本提取：container.xml -> OPF（元信息、manifest、spine），
+// 按 spine 顺序把每个 XHTML 文件转为 Markdown，一个文件对应一个章节。
+
+func parseEPUB(zr *zipArchive) (*Document, error) {
+	container, err := readZipFile(zr, "META-INF/container.xml")
+	if err != nil {
+		return nil, err
+	}
+	var rootfiles struct {
+		Items []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(container, &rootfiles); err != nil || len(rootfiles.Items) == 0 {
+		return nil, fmt.Errorf("invalid container.xml")
+	}
+	opfPath := rootfiles.Items[0].FullPath
+	opfData, err := readZipFile(zr, opfPath)
+	if err != nil {
+		return nil, err
+	}
+	var opf struct {
+		Titles   []string `xml:"metadata>title"`
+		Creators []string `xml:"metadata>creator"`
+		Items    []struct {
+			ID        string `xml:"id,attr"`
+			Href      string `xml:"href,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	if err := xml.Unmarshal(opfData, &opf); err != nil {
+		return nil, fmt.Errorf("invalid package document: %w", err)
+	}
+
+	doc := &Document{}
+	if len(opf.Titles) > 0 {
+		doc.Title = strings.TrimSpace(opf.Titles[0])
+	}
+	if len(opf.Creators) > 0 {
+		doc.Author = strings.TrimSpace(opf.Creators[0])
+	}
+	hrefs := make(map[string]string)
+	for _, item := range opf.Items {
+		if strings.Contains(item.MediaType, "html") {
+			hrefs[item.ID] = path.Join(path.Dir(opfPath), item.Href)
+		}
+	}
+	for _, ref := range opf.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		data, err := readZipFile(zr, href)
+		if errors.Is(err, errArchiveTooLarge) {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+		heading, text := xhtmlToMarkdown(data)
+		if text == "" {
+			// 封面、纯图片页等
+			continue
+		}
+		anchor := fmt.Sprintf("Chapter %d", len(doc.Pages)+1)
+		if heading != "" {
+			anchor += ": " + heading
+		}
+		doc.Pages = append(doc.Pages, DocumentPage{Anchor: anchor, Text: text})
+	}
+	if len(doc.Pages) == 0 {
+		return nil, fmt.Errorf("EPUB has no readable chapters")
+	}
+	return doc, nil
+}
+
+// xhtmlWriter 将 XHTML 标记流转为 Markdown 块
+type xhtmlWriter struct {
+	blocks  []string
+	inline  bytes.Buffer
+	prefix  string
+	heading string
+	title   string
+
+	skip      int
+	inTitle   bool
+	pre       int
+	listDepth int
+
+	linkHref  string
+	linkStart int
+
+	tableDepth int
+	rows       [][]string
+	row        []string
+}
+
+// xhtmlToMarkdown 返回章节标题（首个标题元素或 <title>）和 Markdown 正文；容忍不规范的标记
+func xhtmlToMarkdown(data []byte) (string, string) {
+	w := &xhtmlWriter{}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			w.start(t)
+		case xml.EndElement:
+			w.end(strings.ToLower(t.Name.Local))
+		case xml.CharData:
+			switch {
+			case w.inTitle:
+				w.title += string(t)
+			case w.skip > 0:
+			case w.pre > 0:
+				w.inline.Write(t)
+			default:
+				// 源码中的换行只是空白，<br> 才是段内换行
+				w.inline.WriteString(strings.Map(func(r rune) rune {
+					if r == '\n' || r == '\r' || r == '\t' {
+						return ' '
+					}
+					return r
+				}, string(t)))
+			}
+		}
+	}
+	w.flush()
+	heading := w.heading
+	if heading == "" {
+		heading = strings.Join(strings.Fields(w.title), " ")
+	}
+	return heading, strings.TrimSpace(strings.Join(w.blocks, "\n\n"))
+}
+
+func (w *xhtmlWriter) start(se xml.StartElement) {
+	tag := strings.ToLower(se.Name.Local)
+	switch tag {
+	case "head", "script", "style":
+		w.skip++
+	case "title":
+		w.inTitle = true
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		w.flush()
+		w.prefix = strings.Repeat("#", int(tag[1]-'0')) + " "
+	case "p", "div", "section", "article", "blockquote", "figure", "figcaption", "dt", "dd", "hr":
+		w.flush()
+	case "ul", "ol":
+		w.flush()
+		w.listDepth++
+	case "li":
+		w.flush()
+		w.prefix = listIndent(w.listDepth-1) + "- "
+	case "pre":
+		w.flush()
+		w.pre++
+	case "br":
+		w.inline.WriteByte('\n')
+	case "a":
+		w.linkHref = ""
+		if href := xmlAttr(se, "href"); strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") {
+			w.linkHref = href
+		}
+		w.linkStart = w.inline.Len()
+	case "table":
+		w.flush()
+		w.tableDepth++
+		if w.tableDepth == 1 {
+			w.rows = nil
+		}
+	case "tr":
+		w.row = nil
+	case "td", "th":
+		w.inline.Reset()
+	}
+}
+
+func (w *xhtmlWriter) end(tag string) {
+	switch tag {
+	case "head", "script", "style":
+		if w.skip > 0 {
+			w.skip--
+		}
+	case "title":
+		w.inTitle = false
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		if w.heading == "" {
+			w.heading = strings.Join(strings.Fields(w.inline.String()), " ")
+		}
+		w.flush()
+	case "p", "div", "section", "article", "blockquote", "figure", "figcaption", "dt", "dd", "li":
+		w.flush()
+	case "ul", "ol":
+		w.flush()
+		if w.listDepth > 0 {
+			w.listDepth--
+		}
+	case "pre":
+		w.flush()
+		if w.pre > 0 {
+			w.pre--
+		}
+	case "a":
+		if w.linkHref != "" && w.linkStart <= w.inline.Len() {
+			text := strings.Join(strings.Fields(w.inline.String()[w.linkStart:]), " ")
+			if text != "" {
+				w.inline.Truncate(w.linkStart)
+				w.inline.WriteString("[" + text + "](" + w.linkHref + ")")
+			}
+		}
+		w.linkHref = ""
+	case "td", "th":
+		if w.tableDepth > 0 {
+			w.row = append(w.row, strings.Join(strings.Fields(w.inline.String()), " "))
+			w.inline.Reset()
+		}
+	case "tr":
+		if w.tableDepth > 0 && len(w.row) > 0 {
+			w.rows = append(w.rows, w.row)
+		}
+	case "table":
+		if w.tableDepth > 0 {
+			w.tableDepth--
+		}
+		if w.tableDepth == 0 {
+			w.inline.Reset()
+			if table := markdownTable(w.rows); table != "" {
+				w.blocks = append(w.blocks, table)
+			}
+			w.rows = nil
+		}
+	}
+}
+
+// flush 结束当前段落；表格单元格内的块级元素不分段
+func (w *xhtmlWriter) flush() {
+	if w.tableDepth > 0 {
+		w.inline.WriteByte(' ')
+		return
+	}
+	raw := w.inline.String()
+	w.inline.Reset()
+	prefix := w.prefix
+	w.prefix = ""
+	if w.pre > 0 {
+		if code := strings.Trim(raw, "\n"); strings.TrimSpace(code) != "" {
+			w.blocks = append(w.blocks, "```\n"+code+"\n```")
+		}
+		return
+	}
+	var lines []string
+	for _, line := range strings.Split(raw, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	if strings.HasPrefix(prefix, "#") {
+		w.blocks = append(w.blocks, prefix+strings.Join(lines, " "))
+		return
+	}
+	w.blocks = append(w.blocks, prefix+strings.Join(lines, "\n"))
+}
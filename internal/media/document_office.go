@@ -0,0 +1,856 @@
+package media
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Office Open XML（DOCX / XLSX / PPTX）文本提取：直接流式解析包内 XML，
+// 只关心正文、标题、列表、表格和超链接，忽略样式与排版。
+
+// maxZipEntrySize 单个包内文件解压后的上限，防止压缩炸弹
+const maxZipEntrySize = 64 << 20
+
+// maxZipTotalSize 整个文档解压总量的上限，与 PDF 的 maxPDFDecodedSize 一致
+const maxZipTotalSize = maxPDFDecodedSize
+
+// xlsxMaxColumns / xlsxMaxRows Excel 的列（XFD）与行上限，超出的单元格引用视为无效
+const (
+	xlsxMaxColumns = 16384
+	xlsxMaxRows    = 1048576
+)
+
+// xlsxMaxCells 单个工作表渲染的单元格上限（行数 × 列宽），超出的行被截断
+const xlsxMaxCells = 4 << 20
+
+// maxListLevel 列表缩进层级上限，Office 文档的列表层级为 0-8
+const maxListLevel = 8
+
+// docxChunkChars 文档没有记录分页信息时，按该字符数近似切分页面
+const docxChunkChars = 4000
+
+// xlsxSectionRows 每个工作表分段包含的行数
+const xlsxSectionRows = 200
+
+type zipRel struct {
+	Type     string
+	Target   string
+	External bool
+}
+
+var errArchiveTooLarge = fmt.Errorf("document content exceeds %d MB", maxZipTotalSize>>20)
+
+// zipArchive 记录已解压的总量，超过 maxZipTotalSize 后拒绝继续读取
+type zipArchive struct {
+	*zip.Reader
+	read int
+}
+
+func findZipFile(zr *zipArchive, name string) *zip.File {
+	name = strings.TrimPrefix(name, "/")
+	names := []string{name}
+	if unescaped, err := url.PathUnescape(name); err == nil && unescaped != name {
+		names = append(names, unescaped)
+	}
+	for _, candidate := range names {
+		for _, f := range zr.File {
+			if f.Name == candidate {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+func readZipFile(zr *zipArchive, name string) ([]byte, error) {
+	if zr.read >= maxZipTotalSize {
+		return nil, errArchiveTooLarge
+	}
+	f := findZipFile(zr, name)
+	if f == nil {
+		return nil, fmt.Errorf("missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxZipEntrySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxZipEntrySize {
+		return nil, fmt.Errorf("%s exceeds %d MB", name, maxZipEntrySize>>20)
+	}
+	zr.read += len(data)
+	if zr.read > maxZipTotalSize {
+		return nil, errArchiveTooLarge
+	}
+	return data, nil
+}
+
+// readZipRels 读取部件的关系文件（_rels/*.rels），Target 解析为包内绝对路径
+func readZipRels(zr *zipArchive, part string) map[string]zipRel {
+	rels := make(map[string]zipRel)
+	dir := path.Dir(part)
+	data, err := readZipFile(zr, path.Join(dir, "_rels", path.Base(part)+".rels"))
+	if err != nil {
+		return rels
+	}
+	var parsed struct {
+		Items []struct {
+			ID         string `xml:"Id,attr"`
+			Type       string `xml:"Type,attr"`
+			Target     string `xml:"Target,attr"`
+			TargetMode string `xml:"TargetMode,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		return rels
+	}
+	for _, item := range parsed.Items {
+		rel := zipRel{Type: item.Type, Target: item.Target, External: strings.EqualFold(item.TargetMode, "External")}
+		if !rel.External {
+			if strings.HasPrefix(item.Target, "/") {
+				rel.Target = strings.TrimPrefix(item.Target, "/")
+			} else {
+				rel.Target = path.Join(dir, item.Target)
+			}
+		}
+		rels[item.ID] = rel
+	}
+	return rels
+}
+
+// readCoreProperties 读取 docProps/core.xml 中的标题和作者
+func readCoreProperties(zr *zipArchive) (string, string) {
+	data, err := readZipFile(zr, "docProps/core.xml")
+	if err != nil {
+		return "", ""
+	}
+	var core struct {
+		Title   string `xml:"title"`
+		Creator string `xml:"creator"`
+	}
+	if err := xml.Unmarshal(data, &core); err != nil {
+		return "", ""
+	}
+	return strings.TrimSpace(core.Title), strings.TrimSpace(core.Creator)
+}
+
+// xmlAttr 按本地名查找属性（忽略命名空间前缀）
+func xmlAttr(se xml.StartElement, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// xmlRelAttr 查找带命名空间的关系属性（r:id / r:embed），与同名的无前缀属性区分
+func xmlRelAttr(se xml.StartElement, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == local && a.Name.Space != "" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// markdownTable 将二维单元格渲染为 Markdown 表格，首行作为表头
+func markdownTable(rows [][]string) string {
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	if cols == 0 {
+		return ""
+	}
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for i := 0; i < cols; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = strings.Join(strings.Fields(cells[i]), " ")
+				cell = strings.ReplaceAll(cell, "|", `\|`)
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+	for i, row := range rows {
+		writeRow(row)
+		if i == 0 {
+			sep := make([]string, cols)
+			for k := range sep {
+				sep[k] = "---"
+			}
+			writeRow(sep)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// ---- DOCX ----
+
+type docxParser struct {
+	rels map[string]zipRel
+
+	pages        [][]string
+	pendingBreak bool
+	explicit     bool // 文档中出现过分页标记
+
+	para      bytes.Buffer
+	style     string
+	listLevel int
+	inText    bool
+	linkURL   string
+	linkStart int
+
+	tableDepth int
+	rows       [][]string
+	row        []string
+	cell       []string
+}
+
+func parseDOCX(zr *zipArchive) (*Document, error) {
+	data, err := readZipFile(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	p := &docxParser{rels: readZipRels(zr, "word/document.xml"), pages: [][]string{nil}, listLevel: -1}
+	if err := p.parse(data); err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	doc.Title, doc.Author = readCoreProperties(zr)
+	pages := p.pages
+	if !p.explicit {
+		pages = chunkBlocks(pages[0], docxChunkChars)
+	}
+	for _, blocks := range pages {
+		if len(blocks) == 0 {
+			continue
+		}
+		doc.Pages = append(doc.Pages, DocumentPage{
+			Anchor: fmt.Sprintf("Page %d", len(doc.Pages)+1),
+			Text:   strings.Join(blocks, "\n\n"),
+		})
+	}
+	if len(doc.Pages) == 0 {
+		return nil, fmt.Errorf("document has no text")
+	}
+	return doc, nil
+}
+
+func (p *docxParser) parse(data []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid document.xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			p.start(t)
+		case xml.EndElement:
+			p.end(t.Name.Local)
+		case xml.CharData:
+			if p.inText {
+				p.para.Write(t)
+			}
+		}
+	}
+}
+
+func (p *docxParser) start(se xml.StartElement) {
+	switch se.Name.Local {
+	case "p":
+		p.para.Reset()
+		p.style = ""
+		p.listLevel = -1
+	case "pStyle":
+		p.style = xmlAttr(se, "val")
+	case "numPr":
+		if p.listLevel < 0 {
+			p.listLevel = 0
+		}
+	case "ilvl":
+		if level, err := strconv.Atoi(xmlAttr(se, "val")); err == nil {
+			p.listLevel = level
+		}
+	case "hyperlink":
+		p.linkURL = ""
+		if rel, ok := p.rels[xmlRelAttr(se, "id")]; ok && rel.External {
+			p.linkURL = rel.Target
+		}
+		p.linkStart = p.para.Len()
+	case "t":
+		p.inText = true
+	case "tab":
+		p.para.WriteByte(' ')
+	case "br", "cr":
+		if xmlAttr(se, "type") == "page" {
+			p.pageBreak()
+		} else {
+			p.para.WriteByte('\n')
+		}
+	case "lastRenderedPageBreak":
+		p.pageBreak()
+	case "tbl":
+		p.tableDepth++
+		if p.tableDepth == 1 {
+			p.rows = nil
+		}
+	case "tr":
+		if p.tableDepth == 1 {
+			p.row = nil
+		}
+	case "tc":
+		if p.tableDepth == 1 {
+			p.cell = nil
+		}
+	}
+}
+
+func (p *docxParser) end(local string) {
+	switch local {
+	case "t":
+		p.inText = false
+	case "hyperlink":
+		if p.linkURL != "" && p.linkStart <= p.para.Len() {
+			text := strings.TrimSpace(p.para.String()[p.linkStart:])
+			if text != "" {
+				p.para.Truncate(p.linkStart)
+				p.para.WriteString("[" + text + "](" + p.linkURL + ")")
+			}
+		}
+		p.linkURL = ""
+	case "p":
+		text := strings.TrimSpace(p.para.String())
+		p.para.Reset()
+		if p.tableDepth > 0 {
+			if text != "" {
+				p.cell = append(p.cell, text)
+			}
+			return
+		}
+		p.addBlock(docxParagraph(text, p.style, p.listLevel))
+	case "tc":
+		if p.tableDepth == 1 {
+			p.row = append(p.row, strings.Join(p.cell, " "))
+		}
+	case "tr":
+		if p.tableDepth == 1 {
+			p.rows = append(p.rows, p.row)
+		}
+	case "tbl":
+		p.tableDepth--
+		if p.tableDepth == 0 {
+			p.addBlock(markdownTable(p.rows))
+		}
+	}
+}
+
+// pageBreak 段落开头的分页符归到下一页，段落中间的在段落结束后分页
+func (p *docxParser) pageBreak() {
+	p.explicit = true
+	if p.tableDepth == 0 && strings.TrimSpace(p.para.String()) == "" {
+		p.newPage()
+		return
+	}
+	p.pendingBreak = true
+}
+
+func (p *docxParser) newPage() {
+	p.pendingBreak = false
+	if len(p.pages[len(p.pages)-1]) > 0 {
+		p.pages = append(p.pages, nil)
+	}
+}
+
+func (p *docxParser) addBlock(block string) {
+	if block != "" {
+		p.pages[len(p.pages)-1] = append(p.pages[len(p.pages)-1], block)
+	}
+	if p.pendingBreak {
+		p.newPage()
+	}
+}
+
+// docxParagraph 按段落样式输出标题或列表项
+// listIndent 列表项缩进，层级限制在 0 到 maxListLevel 之间
+func listIndent(level int) string {
+	return strings.Repeat("  ", min(max(level, 0), maxListLevel))
+}
+
+func docxParagraph(text, style string, listLevel int) string {
+	if text == "" {
+		return ""
+	}
+	style = strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	switch {
+	case style == "title":
+		return "# " + strings.ReplaceAll(text, "\n", " ")
+	case strings.HasPrefix(style, "heading"):
+		if level, err := strconv.Atoi(strings.TrimPrefix(style, "heading")); err == nil && level > 0 {
+			return strings.Repeat("#", min(level, 6)) + " " + strings.ReplaceAll(text, "\n", " ")
+		}
+	}
+	if listLevel >= 0 {
+		return listIndent(listLevel) + "- " + text
+	}
+	return text
+}
+
+// chunkBlocks 按块边界把内容切成约 size 字符的页
+func chunkBlocks(blocks []string, size int) [][]string {
+	var pages [][]string
+	var current []string
+	n := 0
+	for _, block := range blocks {
+		if n > 0 && n+len(block) > size {
+			pages = append(pages, current)
+			current, n = nil, 0
+		}
+		current = append(current, block)
+		n += len(block) + 2
+	}
+	if len(current) > 0 {
+		pages = append(pages, current)
+	}
+	return pages
+}
+
+// ---- XLSX ----
+
+type xlsxRow struct {
+	num   int
+	cells map[int]string
+}
+
+func parseXLSX(zr *zipArchive) (*Document, error) {
+	data, err := readZipFile(zr, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	rels := readZipRels(zr, "xl/workbook.xml")
+	shared := readSharedStrings(zr)
+
+	doc := &Document{}
+	doc.Title, doc.Author = readCoreProperties(zr)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "sheet" {
+			continue
+		}
+		name := xmlAttr(se, "name")
+		rel, ok := rels[xmlRelAttr(se, "id")]
+		if !ok {
+			continue
+		}
+		sheetData, err := readZipFile(zr, rel.Target)
+		if errors.Is(err, errArchiveTooLarge) {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+		rows, err := parseSheetRows(sheetData, shared)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", name, err)
+		}
+		doc.Pages = append(doc.Pages, sheetSections(name, rows)...)
+	}
+	if len(doc.Pages) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets")
+	}
+	return doc, nil
+}
+
+func readSharedStrings(zr *zipArchive) []string {
+	data, err := readZipFile(zr, "xl/sharedStrings.xml")
+	if err != nil {
+		return nil
+	}
+	var out []string
+	var cur strings.Builder
+	inText, phonetic := false, 0
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				cur.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				phonetic++
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				out = append(out, cur.String())
+			case "t":
+				inText = false
+			case "rPh":
+				phonetic--
+			}
+		case xml.CharData:
+			if inText && phonetic == 0 {
+				cur.Write(t)
+			}
+		}
+	}
+	return out
+}
+
+func parseSheetRows(data []byte, shared []string) ([]xlsxRow, error) {
+	var rows []xlsxRow
+	var row *xlsxRow
+	var cellRef, cellType string
+	var value strings.Builder
+	inValue := false
+	col := 0
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				num, err := strconv.Atoi(xmlAttr(t, "r"))
+				if err != nil || num < 1 || num > xlsxMaxRows {
+					num = len(rows) + 1
+					if len(rows) > 0 {
+						num = rows[len(rows)-1].num + 1
+					}
+				}
+				rows = append(rows, xlsxRow{num: num, cells: make(map[int]string)})
+				row = &rows[len(rows)-1]
+				col = 0
+			case "c":
+				cellRef, cellType = xmlAttr(t, "r"), xmlAttr(t, "t")
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				if idx, ok := cellColumn(cellRef); ok {
+					col = idx
+				}
+				if row != nil && col < xlsxMaxColumns {
+					if v := xlsxCellValue(value.String(), cellType, shared); v != "" {
+						row.cells[col] = v
+					}
+				}
+				col++
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// cellColumn 将 "BC12" 之类的单元格引用转为从 0 开始的列号；超过 XFD 的列视为无效
+func cellColumn(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, c := range strings.ToUpper(ref) {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+		if col > xlsxMaxColumns {
+			return 0, false
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+func xlsxCellValue(raw, cellType string, shared []string) string {
+	switch cellType {
+	case "s":
+		idx, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || idx < 0 || idx >= len(shared) {
+			return ""
+		}
+		return shared[idx]
+	case "b":
+		if strings.TrimSpace(raw) == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return raw
+}
+
+// sheetSections 按 xlsxSectionRows 切分工作表；每段都重复表头行，便于单独阅读
+func sheetSections(name string, rows []xlsxRow) []DocumentPage {
+	var nonEmpty []xlsxRow
+	minCol, maxCol := -1, -1
+	for _, row := range rows {
+		if len(row.cells) == 0 {
+			continue
+		}
+		nonEmpty = append(nonEmpty, row)
+		for c := range row.cells {
+			if minCol < 0 || c < minCol {
+				minCol = c
+			}
+			if c > maxCol {
+				maxCol = c
+			}
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return []DocumentPage{{Anchor: "Sheet: " + name + " (empty)"}}
+	}
+	// 列宽不超过 xlsxMaxColumns；行数 × 列宽超过 xlsxMaxCells 时截断后面的行
+	width := maxCol - minCol + 1
+	truncated := false
+	if limit := max(xlsxMaxCells/width, 1); len(nonEmpty) > limit {
+		nonEmpty = nonEmpty[:limit]
+		truncated = true
+	}
+	cells := func(row xlsxRow) []string {
+		out := make([]string, width)
+		for c, v := range row.cells {
+			out[c-minCol] = v
+		}
+		return out
+	}
+
+	header := cells(nonEmpty[0])
+	body := nonEmpty[1:]
+	if len(body) == 0 {
+		return []DocumentPage{{
+			Anchor: fmt.Sprintf("Sheet: %s (rows %d-%d)", name, nonEmpty[0].num, nonEmpty[0].num),
+			Text:   markdownTable([][]string{header}) + truncatedNote(truncated),
+		}}
+	}
+	var pages []DocumentPage
+	for start := 0; start < len(body); start += xlsxSectionRows {
+		end := min(start+xlsxSectionRows, len(body))
+		table := [][]string{header}
+		for _, row := range body[start:end] {
+			table = append(table, cells(row))
+		}
+		first := body[start].num
+		if start == 0 {
+			first = nonEmpty[0].num
+		}
+		pages = append(pages, DocumentPage{
+			Anchor: fmt.Sprintf("Sheet: %s (rows %d-%d)", name, first, body[end-1].num),
+			Text:   markdownTable(table),
+		})
+	}
+	pages[len(pages)-1].Text += truncatedNote(truncated)
+	return pages
+}
+
+func truncatedNote(truncated bool) string {
+	if !truncated {
+		return ""
+	}
+	return fmt.Sprintf("\n\n(sheet truncated: more than %d cells)", xlsxMaxCells)
+}
+
+// ---- PPTX ----
+
+func parsePPTX(zr *zipArchive) (*Document, error) {
+	data, err := readZipFile(zr, "ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+	rels := readZipRels(zr, "ppt/presentation.xml")
+
+	doc := &Document{}
+	doc.Title, doc.Author = readCoreProperties(zr)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "sldId" {
+			continue
+		}
+		rel, ok := rels[xmlRelAttr(se, "id")]
+		if !ok {
+			continue
+		}
+		slideData, err := readZipFile(zr, rel.Target)
+		if errors.Is(err, errArchiveTooLarge) {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+		title, body := parseSlide(slideData, false)
+		anchor := fmt.Sprintf("Slide %d", len(doc.Pages)+1)
+		if title != "" {
+			anchor += ": " + title
+		}
+		for _, slideRel := range readZipRels(zr, rel.Target) {
+			if strings.HasSuffix(slideRel.Type, "/notesSlide") {
+				if notesData, err := readZipFile(zr, slideRel.Target); err == nil {
+					if _, notes := parseSlide(notesData, true); notes != "" {
+						body = strings.TrimSpace(body + "\n\nNotes:\n" + notes)
+					}
+				}
+			}
+		}
+		doc.Pages = append(doc.Pages, DocumentPage{Anchor: anchor, Text: body})
+	}
+	if len(doc.Pages) == 0 {
+		return nil, fmt.Errorf("presentation has no slides")
+	}
+	return doc, nil
+}
+
+// parseSlide 返回标题占位符文本和其余正文；notes 为 true 时只保留备注正文占位符
+func parseSlide(data []byte, notes bool) (string, string) {
+	var title string
+	var blocks []string
+	var shapeLines []string
+	var para strings.Builder
+	placeholder, paraLevel := "", 0
+	isPlaceholder, inText := false, false
+	tableDepth := 0
+	var rows [][]string
+	var row, cell []string
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp":
+				shapeLines, placeholder, isPlaceholder = nil, "", false
+			case "ph":
+				isPlaceholder = true
+				placeholder = xmlAttr(t, "type")
+			case "p":
+				para.Reset()
+				paraLevel = 0
+			case "pPr":
+				if lvl, err := strconv.Atoi(xmlAttr(t, "lvl")); err == nil {
+					paraLevel = lvl
+				}
+			case "t":
+				inText = true
+			case "br":
+				para.WriteByte(' ')
+			case "tbl":
+				tableDepth++
+				rows = nil
+			case "tr":
+				row = nil
+			case "tc":
+				cell = nil
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if text == "" {
+					continue
+				}
+				if tableDepth > 0 {
+					cell = append(cell, text)
+					continue
+				}
+				if isPlaceholder && placeholder != "title" && placeholder != "ctrTitle" && !notes {
+					text = listIndent(paraLevel) + "- " + text
+				}
+				shapeLines = append(shapeLines, text)
+			case "sp":
+				text := strings.Join(shapeLines, "\n")
+				switch {
+				case text == "":
+				case notes:
+					if placeholder == "body" {
+						blocks = append(blocks, text)
+					}
+				case placeholder == "title" || placeholder == "ctrTitle":
+					if title == "" {
+						title = strings.Join(shapeLines, " ")
+					} else {
+						blocks = append(blocks, text)
+					}
+				case placeholder == "sldNum" || placeholder == "dt" || placeholder == "ftr":
+				default:
+					blocks = append(blocks, text)
+				}
+				shapeLines = nil
+			case "tc":
+				row = append(row, strings.Join(cell, " "))
+			case "tr":
+				rows = append(rows, row)
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 && !notes {
+					if table := markdownTable(rows); table != "" {
+						blocks = append(blocks, table)
+					}
+				}
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		}
+	}
+	return title, strings.Join(blocks, "\n\n")
+}
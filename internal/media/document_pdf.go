@@ -0,0 +1,1217 @@
+package media
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// 纯 Go 的 PDF 文本提取：扫描对象（含对象流）、解码 Flate 内容流、
+// 按 ToUnicode CMap / WinAnsi 解码文字，并按文本矩阵位置插入换行。
+// 不支持加密文档和仅含扫描图像的页面。
+
+type pdfName string
+
+type pdfRef struct {
+	num int
+	gen int
+}
+
+type pdfDict map[string]interface{}
+
+type pdfStream struct {
+	dict pdfDict
+	raw  []byte
+}
+
+// pdfKeyword 内容流中的操作符或未识别的裸字
+type pdfKeyword string
+
+type pdfDoc struct {
+	data    []byte
+	offsets map[int]int
+	objects map[int]interface{}
+	inStm   map[int]pdfRef // 对象编号 -> 所在对象流
+	stmData map[int][]byte // 已解码的对象流
+	fonts   map[pdfRef]*pdfFont
+	decoded int   // 已解码的流字节数，受 maxPDFDecodedSize 限制
+	err     error // 嵌套过深或解码超限等需要中止解析的错误
+}
+
+const (
+	// maxPDFNesting 数组 / 字典的最大嵌套层数，防止恶意文档耗尽栈
+	maxPDFNesting = 256
+	// maxPDFStreamSize 单个流解码后的上限，与 Office 文档的 zip 条目一致
+	maxPDFStreamSize = maxZipEntrySize
+	// maxPDFDecodedSize 整个文档解码流的总量上限
+	maxPDFDecodedSize = 256 << 20
+)
+
+var pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+func parsePDF(data []byte) (*Document, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \r\n\t"), []byte("%PDF-")) {
+		return nil, fmt.Errorf("not a PDF file")
+	}
+	d := &pdfDoc{
+		data:    data,
+		offsets: make(map[int]int),
+		objects: make(map[int]interface{}),
+		inStm:   make(map[int]pdfRef),
+		stmData: make(map[int][]byte),
+		fonts:   make(map[pdfRef]*pdfFont),
+	}
+	for _, m := range pdfObjectHeader.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		// 增量更新时后出现的定义覆盖前面的
+		d.offsets[num] = m[1]
+	}
+	if len(d.offsets) == 0 {
+		return nil, fmt.Errorf("no PDF objects found")
+	}
+	d.indexObjectStreams()
+
+	catalog, trailerInfo := d.findCatalog()
+	if d.err != nil {
+		return nil, d.err
+	}
+	if catalog == nil {
+		return nil, fmt.Errorf("PDF catalog not found")
+	}
+	if trailerInfo.encrypted {
+		return nil, fmt.Errorf("encrypted PDFs are not supported")
+	}
+
+	doc := &Document{}
+	if info, ok := d.resolve(trailerInfo.info).(pdfDict); ok {
+		doc.Title = decodePDFTextString(d.resolve(info["Title"]))
+		doc.Author = decodePDFTextString(d.resolve(info["Author"]))
+	}
+
+	var pages []pdfDict
+	d.collectPages(d.resolve(catalog["Pages"]), nil, &pages, 0)
+	for i, page := range pages {
+		text := d.pageText(page)
+		doc.Pages = append(doc.Pages, DocumentPage{Anchor: fmt.Sprintf("Page %d", i+1), Text: text})
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(doc.Pages) == 0 {
+		return nil, fmt.Errorf("PDF has no pages")
+	}
+	empty := true
+	for _, p := range doc.Pages {
+		if strings.TrimSpace(p.Text) != "" {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		return nil, fmt.Errorf("PDF has no extractable text (it may be scanned images)")
+	}
+	return doc, nil
+}
+
+type pdfTrailerInfo struct {
+	info      interface{}
+	encrypted bool
+}
+
+// findCatalog 从 trailer / XRef 流字典中找到 /Root，找不到时回退为扫描 /Type /Catalog
+func (d *pdfDoc) findCatalog() (pdfDict, pdfTrailerInfo) {
+	var info pdfTrailerInfo
+	var root interface{}
+	consider := func(dict pdfDict) {
+		if _, ok := dict["Encrypt"]; ok {
+			info.encrypted = true
+		}
+		if r, ok := dict["Root"]; ok {
+			root = r
+		}
+		if i, ok := dict["Info"]; ok {
+			info.info = i
+		}
+	}
+	for idx := 0; ; {
+		pos := bytes.Index(d.data[idx:], []byte("trailer"))
+		if pos < 0 {
+			break
+		}
+		lx := d.newLexer(d.data[idx+pos+len("trailer"):])
+		if dict, ok := lx.parseValue().(pdfDict); ok {
+			consider(dict)
+		}
+		idx += pos + len("trailer")
+	}
+	nums := d.objectNumbers()
+	for _, num := range nums {
+		if stream, ok := d.object(num).(*pdfStream); ok && stream.dict["Type"] == pdfName("XRef") {
+			consider(stream.dict)
+		}
+	}
+	if catalog, ok := d.resolve(root).(pdfDict); ok {
+		return catalog, info
+	}
+	for _, num := range nums {
+		if dict, ok := d.object(num).(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+			return dict, info
+		}
+	}
+	return nil, info
+}
+
+func (d *pdfDoc) objectNumbers() []int {
+	nums := make([]int, 0, len(d.offsets)+len(d.inStm))
+	for num := range d.offsets {
+		nums = append(nums, num)
+	}
+	for num := range d.inStm {
+		if _, ok := d.offsets[num]; !ok {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// indexObjectStreams 记录压缩在 /ObjStm 中的对象
+func (d *pdfDoc) indexObjectStreams() {
+	for num := range d.offsets {
+		stream, ok := d.object(num).(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		data, err := d.decodeStream(stream)
+		if err != nil {
+			continue
+		}
+		n := pdfInt(d.resolve(stream.dict["N"]))
+		lx := d.newLexer(data)
+		for i := 0; i < n; i++ {
+			objNum, ok1 := lx.parseValue().(float64)
+			_, ok2 := lx.parseValue().(float64)
+			if !ok1 || !ok2 {
+				break
+			}
+			if _, direct := d.offsets[int(objNum)]; !direct {
+				d.inStm[int(objNum)] = pdfRef{num: num}
+			}
+		}
+	}
+}
+
+func (d *pdfDoc) object(num int) interface{} {
+	if obj, ok := d.objects[num]; ok {
+		return obj
+	}
+	d.objects[num] = nil // 防止循环引用
+	var obj interface{}
+	if offset, ok := d.offsets[num]; ok {
+		obj = d.parseIndirect(offset)
+	} else if stm, ok := d.inStm[num]; ok {
+		obj = d.objectFromStream(stm.num, num)
+	}
+	d.objects[num] = obj
+	return obj
+}
+
+func (d *pdfDoc) parseIndirect(offset int) interface{} {
+	lx := d.newLexer(d.data[offset:])
+	value := lx.parseValue()
+	dict, ok := value.(pdfDict)
+	if !ok {
+		return value
+	}
+	save := lx.pos
+	if tok, _ := lx.next(); tok != pdfKeyword("stream") {
+		lx.pos = save
+		return value
+	}
+	start := offset + lx.pos
+	if start < len(d.data) && d.data[start] == '\r' {
+		start++
+	}
+	if start < len(d.data) && d.data[start] == '\n' {
+		start++
+	}
+	length := pdfInt(d.resolveLength(dict["Length"]))
+	end := start + length
+	if length <= 0 || end > len(d.data) || !bytes.Contains(d.data[end:min(end+20, len(d.data))], []byte("endstream")) {
+		idx := bytes.Index(d.data[start:], []byte("endstream"))
+		if idx < 0 {
+			return &pdfStream{dict: dict}
+		}
+		end = start + idx
+		for end > start && (d.data[end-1] == '\n' || d.data[end-1] == '\r') {
+			end--
+		}
+	}
+	return &pdfStream{dict: dict, raw: d.data[start:end]}
+}
+
+// resolveLength 解析 /Length，避免在对象仍在解析时递归
+func (d *pdfDoc) resolveLength(v interface{}) interface{} {
+	ref, ok := v.(pdfRef)
+	if !ok {
+		return v
+	}
+	if offset, ok := d.offsets[ref.num]; ok {
+		return d.newLexer(d.data[offset:]).parseValue()
+	}
+	return nil
+}
+
+func (d *pdfDoc) objectFromStream(stmNum, num int) interface{} {
+	stream, ok := d.object(stmNum).(*pdfStream)
+	if !ok {
+		return nil
+	}
+	// 同一对象流中的对象共用一次解码，避免重复计入解码总量
+	data, ok := d.stmData[stmNum]
+	if !ok {
+		var err error
+		if data, err = d.decodeStream(stream); err != nil {
+			return nil
+		}
+		d.stmData[stmNum] = data
+	}
+	n := pdfInt(d.resolve(stream.dict["N"]))
+	first := pdfInt(d.resolve(stream.dict["First"]))
+	lx := d.newLexer(data)
+	for i := 0; i < n; i++ {
+		objNum, ok1 := lx.parseValue().(float64)
+		offset, ok2 := lx.parseValue().(float64)
+		if !ok1 || !ok2 {
+			return nil
+		}
+		if int(objNum) == num {
+			start := first + int(offset)
+			if start < 0 || start >= len(data) {
+				return nil
+			}
+			return d.newLexer(data[start:]).parseValue()
+		}
+	}
+	return nil
+}
+
+func (d *pdfDoc) resolve(v interface{}) interface{} {
+	for depth := 0; depth < 16; depth++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.object(ref.num)
+	}
+	return nil
+}
+
+// collectPages 按页面树顺序收集页面，并继承父节点的 /Resources
+func (d *pdfDoc) collectPages(node interface{}, inherited interface{}, out *[]pdfDict, depth int) {
+	dict, ok := node.(pdfDict)
+	if !ok || depth > 64 {
+		return
+	}
+	resources := inherited
+	if r, ok := dict["Resources"]; ok {
+		resources = r
+	}
+	if dict["Type"] == pdfName("Page") || (dict["Kids"] == nil && dict["Contents"] != nil) {
+		page := pdfDict{}
+		for k, v := range dict {
+			page[k] = v
+		}
+		page["Resources"] = resources
+		*out = append(*out, page)
+		return
+	}
+	kids, _ := d.resolve(dict["Kids"]).([]interface{})
+	for _, kid := range kids {
+		d.collectPages(d.resolve(kid), resources, out, depth+1)
+	}
+}
+
+func (d *pdfDoc) pageText(page pdfDict) string {
+	var content []byte
+	switch c := d.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		content, _ = d.decodeStream(c)
+	case []interface{}:
+		for _, part := range c {
+			if s, ok := d.resolve(part).(*pdfStream); ok {
+				data, err := d.decodeStream(s)
+				if err == nil {
+					content = append(content, data...)
+					content = append(content, '\n')
+				}
+			}
+		}
+	}
+	resources, _ := d.resolve(page["Resources"]).(pdfDict)
+	w := &pdfTextWriter{}
+	d.runContent(content, resources, w, 0)
+	return w.String()
+}
+
+func (d *pdfDoc) decodeStream(s *pdfStream) ([]byte, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	data := s.raw
+	filters := d.resolve(s.dict["Filter"])
+	parms := d.resolve(s.dict["DecodeParms"])
+	var names []interface{}
+	var parmList []interface{}
+	switch f := filters.(type) {
+	case pdfName:
+		names = []interface{}{f}
+		parmList = []interface{}{parms}
+	case []interface{}:
+		names = f
+		parmList, _ = parms.([]interface{})
+	}
+	for i, raw := range names {
+		name, _ := d.resolve(raw).(pdfName)
+		var parm pdfDict
+		if i < len(parmList) {
+			parm, _ = d.resolve(parmList[i]).(pdfDict)
+		}
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			limit := min(maxPDFStreamSize, maxPDFDecodedSize-d.decoded)
+			data, err = flateDecode(data, limit)
+			if errors.Is(err, errPDFTooLarge) {
+				if limit < maxPDFStreamSize {
+					d.err = fmt.Errorf("PDF decoded content exceeds %d MB", maxPDFDecodedSize>>20)
+				} else {
+					d.err = fmt.Errorf("PDF stream exceeds %d MB", maxPDFStreamSize>>20)
+				}
+				return nil, d.err
+			}
+			if err == nil && parm != nil {
+				data, err = applyPNGPredictor(data, parm)
+			}
+		case "ASCIIHexDecode", "AHx":
+			data, err = asciiHexDecode(data)
+		case "ASCII85Decode", "A85":
+			data, err = ascii85Decode(data)
+		default:
+			return nil, fmt.Errorf("unsupported PDF filter %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(names) > 0 {
+		d.decoded += len(data)
+	}
+	return data, nil
+}
+
+var errPDFTooLarge = errors.New("PDF stream too large")
+
+// flateDecode 解压 Flate 流，输出超过 limit 字节时返回 errPDFTooLarge
+func flateDecode(data []byte, limit int) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if len(out) > limit {
+		return nil, errPDFTooLarge
+	}
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	// 截断的流尽量保留已解出的内容
+	return out, nil
+}
+
+func applyPNGPredictor(data []byte, parm pdfDict) ([]byte, error) {
+	predictor := pdfInt(parm["Predictor"])
+	if predictor < 10 {
+		return data, nil
+	}
+	columns := pdfInt(parm["Columns"])
+	if columns <= 0 {
+		columns = 1
+	}
+	colors := pdfInt(parm["Colors"])
+	if colors <= 0 {
+		colors = 1
+	}
+	bpc := pdfInt(parm["BitsPerComponent"])
+	if bpc <= 0 {
+		bpc = 8
+	}
+	bpp := (colors*bpc + 7) / 8
+	rowLen := (columns*colors*bpc + 7) / 8
+	var out []byte
+	prev := make([]byte, rowLen)
+	for i := 0; i+rowLen < len(data)+1 && i < len(data); i += rowLen + 1 {
+		if i+1+rowLen > len(data) {
+			break
+		}
+		filter := data[i]
+		row := append([]byte(nil), data[i+1:i+1+rowLen]...)
+		for j := range row {
+			var left, up, upLeft byte
+			if j >= bpp {
+				left = row[j-bpp]
+				upLeft = prev[j-bpp]
+			}
+			up = prev[j]
+			switch filter {
+			case 1:
+				row[j] += left
+			case 2:
+				row[j] += up
+			case 3:
+				row[j] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[j] += paethPredictor(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paethPredictor(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := absInt(p-int(a)), absInt(p-int(b)), absInt(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func asciiHexDecode(data []byte) ([]byte, error) {
+	var clean []byte
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if isHexDigit(c) {
+			clean = append(clean, c)
+		}
+	}
+	if len(clean)%2 == 1 {
+		clean = append(clean, '0')
+	}
+	return hex.DecodeString(string(clean))
+}
+
+func ascii85Decode(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	data = bytes.TrimPrefix(data, []byte("<~"))
+	if idx := bytes.Index(data, []byte("~>")); idx >= 0 {
+		data = data[:idx]
+	}
+	out := make([]byte, len(data)*4/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
+
+func pdfInt(v interface{}) int {
+	if f, ok := v.(float64); ok {
+		return int(f)
+	}
+	return 0
+}
+
+func pdfFloat(v interface{}) float64 {
+	f, _ := v.(float64)
+	return f
+}
+
+// decodePDFTextString 解码文档信息中的字符串（UTF-16BE 带 BOM 或 PDFDocEncoding）
+func decodePDFTextString(v interface{}) string {
+	s, ok := v.(string)
+	if !ok {
+		return ""
+	}
+	b := []byte(s)
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		return strings.TrimSpace(decodeUTF16BE(b[2:]))
+	}
+	return strings.TrimSpace(decodeWinAnsi(b))
+}
+
+func decodeUTF16BE(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// winAnsiHigh cp1252 中 0x80-0x9F 的字符
+var winAnsiHigh = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡', 0x88: 'ˆ', 0x89: '‰',
+	0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”',
+	0x95: '•', 0x96: '–', 0x97: '—', 0x98: '˜', 0x99: '™', 0x9A: 'š', 0x9B: '›', 0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+func decodeWinAnsi(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if r, ok := winAnsiHigh[c]; ok {
+			sb.WriteRune(r)
+			continue
+		}
+		sb.WriteRune(rune(c))
+	}
+	return sb.String()
+}
+
+// ---- 字体与 ToUnicode ----
+
+type pdfFont struct {
+	codeBytes int // 1 或 2
+	toUnicode map[uint32]string
+	simple    map[byte]rune // /Differences 覆盖
+}
+
+func (d *pdfDoc) font(resources pdfDict, name pdfName) *pdfFont {
+	fonts, _ := d.resolve(resources["Font"]).(pdfDict)
+	raw := fonts[string(name)]
+	ref, isRef := raw.(pdfRef)
+	if isRef {
+		if f, ok := d.fonts[ref]; ok {
+			return f
+		}
+	}
+	f := &pdfFont{codeBytes: 1}
+	if dict, ok := d.resolve(raw).(pdfDict); ok {
+		if dict["Subtype"] == pdfName("Type0") {
+			f.codeBytes = 2
+		}
+		if s, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+			if data, err := d.decodeStream(s); err == nil {
+				f.toUnicode, f.codeBytes = parseToUnicodeCMap(data, f.codeBytes)
+			}
+		}
+		if enc, ok := d.resolve(dict["Encoding"]).(pdfDict); ok {
+			f.simple = parseDifferences(d.resolve(enc["Differences"]))
+		}
+	}
+	if isRef {
+		d.fonts[ref] = f
+	}
+	return f
+}
+
+func (f *pdfFont) decode(s string) string {
+	b := []byte(s)
+	var sb strings.Builder
+	if f == nil {
+		return decodeWinAnsi(b)
+	}
+	step := f.codeBytes
+	for i := 0; i < len(b); i += step {
+		if i+step > len(b) {
+			break
+		}
+		var code uint32
+		for j := 0; j < step; j++ {
+			code = code<<8 | uint32(b[i+j])
+		}
+		if text, ok := f.toUnicode[code]; ok {
+			sb.WriteString(text)
+			continue
+		}
+		if step == 1 {
+			if r, ok := f.simple[b[i]]; ok {
+				sb.WriteRune(r)
+				continue
+			}
+			sb.WriteString(decodeWinAnsi(b[i : i+1]))
+		}
+		// 无 ToUnicode 的双字节 CID 字体无法可靠解码，跳过
+	}
+	return sb.String()
+}
+
+var (
+	cmapBFChar  = regexp.MustCompile(`(?s)beginbfchar(.*?)endbfchar`)
+	cmapBFRange = regexp.MustCompile(`(?s)beginbfrange(.*?)endbfrange`)
+	cmapRange   = regexp.MustCompile(`(?s)begincodespacerange(.*?)endcodespacerange`)
+	cmapHex     = regexp.MustCompile(`<([0-9A-Fa-f\s]*)>|\[|\]`)
+)
+
+func parseToUnicodeCMap(data []byte, defaultBytes int) (map[uint32]string, int) {
+	out := make(map[uint32]string)
+	codeBytes := defaultBytes
+	if m := cmapRange.FindSubmatch(data); m != nil {
+		if tokens := cmapHex.FindAllSubmatch(m[1], 1); len(tokens) > 0 {
+			if n := len(strings.Join(strings.Fields(string(tokens[0][1])), "")) / 2; n > 0 {
+				codeBytes = n
+			}
+		}
+	}
+	hexValue := func(s []byte) (uint32, []byte) {
+		clean := strings.Join(strings.Fields(string(s)), "")
+		raw, _ := hex.DecodeString(clean)
+		var v uint32
+		for _, c := range raw {
+			v = v<<8 | uint32(c)
+		}
+		return v, raw
+	}
+	for _, block := range cmapBFChar.FindAllSubmatch(data, -1) {
+		tokens := cmapHex.FindAllSubmatch(block[1], -1)
+		for i := 0; i+1 < len(tokens); i += 2 {
+			src, _ := hexValue(tokens[i][1])
+			_, dst := hexValue(tokens[i+1][1])
+			out[src] = decodeUTF16BE(dst)
+		}
+	}
+	for _, block := range cmapBFRange.FindAllSubmatch(data, -1) {
+		tokens := cmapHex.FindAllSubmatch(block[1], -1)
+		for i := 0; i+2 < len(tokens); {
+			lo, _ := hexValue(tokens[i][1])
+			hi, _ := hexValue(tokens[i+1][1])
+			if hi < lo || hi-lo > 0xFFFF {
+				break
+			}
+			if string(tokens[i+2][0]) == "[" {
+				j := i + 3
+				for code := lo; j < len(tokens) && string(tokens[j][0]) != "]"; code, j = code+1, j+1 {
+					_, dst := hexValue(tokens[j][1])
+					out[code] = decodeUTF16BE(dst)
+				}
+				i = j + 1
+				continue
+			}
+			_, dst := hexValue(tokens[i+2][1])
+			units := make([]uint16, 0, len(dst)/2)
+			for k := 0; k+1 < len(dst); k += 2 {
+				units = append(units, uint16(dst[k])<<8|uint16(dst[k+1]))
+			}
+			for code := lo; code <= hi; code++ {
+				shifted := append([]uint16(nil), units...)
+				if len(shifted) > 0 {
+					shifted[len(shifted)-1] += uint16(code - lo)
+				}
+				out[code] = string(utf16.Decode(shifted))
+			}
+			i += 3
+		}
+	}
+	return out, codeBytes
+}
+
+// pdfGlyphNames 常见 Adobe 字形名（/Differences 中非单字母的名称）
+var pdfGlyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "quoteright": '’', "quoteleft": '‘', "parenleft": '(',
+	"parenright": ')', "asterisk": '*', "plus": '+', "comma": ',', "hyphen": '-', "period": '.', "slash": '/',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4', "five": '5', "six": '6', "seven": '7',
+	"eight": '8', "nine": '9', "colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>',
+	"question": '?', "at": '@', "bracketleft": '[', "backslash": '\\', "bracketright": ']', "underscore": '_',
+	"quotedblleft": '“', "quotedblright": '”', "endash": '–', "emdash": '—', "bullet": '•',
+	"fi": 'ﬁ', "fl": 'ﬂ', "ellipsis": '…',
+}
+
+func parseDifferences(v interface{}) map[byte]rune {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make(map[byte]rune)
+	code := 0
+	for _, item := range arr {
+		switch x := item.(type) {
+		case float64:
+			code = int(x)
+		case pdfName:
+			name := string(x)
+			var r rune
+			switch {
+			case len([]rune(name)) == 1:
+				r = []rune(name)[0]
+			case strings.HasPrefix(name, "uni") && len(name) == 7:
+				if v, err := strconv.ParseUint(name[3:], 16, 32); err == nil {
+					r = rune(v)
+				}
+			default:
+				r = pdfGlyphNames[name]
+			}
+			if r != 0 && code >= 0 && code < 256 {
+				out[byte(code)] = r
+			}
+			code++
+		}
+	}
+	return out
+}
+
+// ---- 内容流解释 ----
+
+type pdfTextWriter struct {
+	sb       strings.Builder
+	lastY    float64
+	hasY     bool
+	pendingN bool
+}
+
+func (w *pdfTextWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	if w.pendingN {
+		w.newline()
+		w.pendingN = false
+	}
+	w.sb.WriteString(s)
+}
+
+func (w *pdfTextWriter) space() {
+	str := w.sb.String()
+	if str != "" && !strings.HasSuffix(str, " ") && !strings.HasSuffix(str, "\n") {
+		w.sb.WriteByte(' ')
+	}
+}
+
+func (w *pdfTextWriter) newline() {
+	str := w.sb.String()
+	if str != "" && !strings.HasSuffix(str, "\n") {
+		w.sb.WriteByte('\n')
+	}
+}
+
+func (w *pdfTextWriter) moveTo(y float64) {
+	if w.hasY && absFloat(y-w.lastY) > 1 {
+		w.pendingN = true
+	}
+	w.lastY, w.hasY = y, true
+}
+
+func (w *pdfTextWriter) String() string {
+	lines := strings.Split(w.sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	text := strings.Join(lines, "\n")
+	for strings.Contains(text, "\n\n\n") {
+		text = strings.ReplaceAll(text, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(text)
+}
+
+func absFloat(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func (d *pdfDoc) runContent(content []byte, resources pdfDict, w *pdfTextWriter, depth int) {
+	lx := d.newLexer(content)
+	var operands []interface{}
+	var font *pdfFont
+	var lineY, leading float64
+	for {
+		tok, ok := lx.next()
+		if !ok {
+			return
+		}
+		op, isOp := tok.(pdfKeyword)
+		if !isOp {
+			operands = append(operands, tok)
+			continue
+		}
+		switch op {
+		case "BI":
+			lx.skipInlineImage()
+		case "BT":
+			lineY = 0
+		case "ET":
+			w.space()
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					font = d.font(resources, name)
+				}
+			}
+		case "TL":
+			if len(operands) >= 1 {
+				leading = pdfFloat(operands[len(operands)-1])
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				tx := pdfFloat(operands[len(operands)-2])
+				ty := pdfFloat(operands[len(operands)-1])
+				if op == "TD" {
+					leading = -ty
+				}
+				lineY += ty
+				w.moveTo(lineY)
+				if ty == 0 && tx > 0 {
+					w.space()
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				lineY = pdfFloat(operands[5])
+				w.moveTo(lineY)
+				w.space()
+			}
+		case "T*":
+			lineY -= leading
+			w.newline()
+		case "Tj":
+			if len(operands) >= 1 {
+				if s, ok := operands[len(operands)-1].(string); ok {
+					w.write(font.decode(s))
+				}
+			}
+		case "'", "\"":
+			w.newline()
+			if len(operands) >= 1 {
+				if s, ok := operands[len(operands)-1].(string); ok {
+					w.write(font.decode(s))
+				}
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				if arr, ok := operands[len(operands)-1].([]interface{}); ok {
+					for _, item := range arr {
+						switch x := item.(type) {
+						case string:
+							w.write(font.decode(x))
+						case float64:
+							// 较大的负字距通常表示词间空格
+							if x < -180 {
+								w.space()
+							}
+						}
+					}
+				}
+			}
+		case "Do":
+			if depth < 4 && len(operands) >= 1 {
+				if name, ok := operands[len(operands)-1].(pdfName); ok {
+					d.runXObject(resources, name, w, depth)
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func (d *pdfDoc) runXObject(resources pdfDict, name pdfName, w *pdfTextWriter, depth int) {
+	xobjects, _ := d.resolve(resources["XObject"]).(pdfDict)
+	stream, ok := d.resolve(xobjects[string(name)]).(*pdfStream)
+	if !ok || stream.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	data, err := d.decodeStream(stream)
+	if err != nil {
+		return
+	}
+	inner, ok := d.resolve(stream.dict["Resources"]).(pdfDict)
+	if !ok {
+		inner = resources
+	}
+	w.newline()
+	d.runContent(data, inner, w, depth+1)
+	w.newline()
+}
+
+// ---- 词法 / 语法 ----
+
+type pdfLexer struct {
+	data  []byte
+	pos   int
+	depth int
+	err   *error // 嵌套过深时写入所属 pdfDoc 的错误
+}
+
+func (d *pdfDoc) newLexer(data []byte) *pdfLexer {
+	return &pdfLexer{data: data, err: &d.err}
+}
+
+// enter 进入一层数组或字典；超过 maxPDFNesting 时记录错误并停止读取
+func (lx *pdfLexer) enter() bool {
+	if lx.depth >= maxPDFNesting {
+		if *lx.err == nil {
+			*lx.err = fmt.Errorf("PDF objects nested deeper than %d levels", maxPDFNesting)
+		}
+		lx.pos = len(lx.data)
+		return false
+	}
+	lx.depth++
+	return true
+}
+
+func isPDFWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func (lx *pdfLexer) skipSpace() {
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		if isPDFWhitespace(c) {
+			lx.pos++
+			continue
+		}
+		if c == '%' {
+			for lx.pos < len(lx.data) && lx.data[lx.pos] != '\n' && lx.data[lx.pos] != '\r' {
+				lx.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// next 读取一个完整值或操作符；数组和字典会被完整解析
+func (lx *pdfLexer) next() (interface{}, bool) {
+	lx.skipSpace()
+	if lx.pos >= len(lx.data) {
+		return nil, false
+	}
+	c := lx.data[lx.pos]
+	switch {
+	case c == '/':
+		lx.pos++
+		start := lx.pos
+		for lx.pos < len(lx.data) && !isPDFWhitespace(lx.data[lx.pos]) && !isPDFDelimiter(lx.data[lx.pos]) {
+			lx.pos++
+		}
+		return pdfName(decodeNameEscapes(string(lx.data[start:lx.pos]))), true
+	case c == '(':
+		return lx.literalString(), true
+	case c == '<' && lx.pos+1 < len(lx.data) && lx.data[lx.pos+1] == '<':
+		if !lx.enter() {
+			return nil, false
+		}
+		defer func() { lx.depth-- }()
+		lx.pos += 2
+		dict := pdfDict{}
+		for {
+			lx.skipSpace()
+			if lx.pos >= len(lx.data) {
+				return dict, true
+			}
+			if bytes.HasPrefix(lx.data[lx.pos:], []byte(">>")) {
+				lx.pos += 2
+				return dict, true
+			}
+			key, ok := lx.next()
+			if !ok {
+				return dict, true
+			}
+			name, isName := key.(pdfName)
+			if !isName {
+				continue
+			}
+			dict[string(name)] = lx.parseValue()
+		}
+	case c == '<':
+		lx.pos++
+		start := lx.pos
+		for lx.pos < len(lx.data) && lx.data[lx.pos] != '>' {
+			lx.pos++
+		}
+		raw, _ := asciiHexDecode(lx.data[start:lx.pos])
+		lx.pos++
+		return string(raw), true
+	case c == '[':
+		if !lx.enter() {
+			return nil, false
+		}
+		defer func() { lx.depth-- }()
+		lx.pos++
+		var arr []interface{}
+		for {
+			lx.skipSpace()
+			if lx.pos >= len(lx.data) {
+				return arr, true
+			}
+			if lx.data[lx.pos] == ']' {
+				lx.pos++
+				return arr, true
+			}
+			v := lx.parseValue()
+			if v == nil && lx.pos >= len(lx.data) {
+				return arr, true
+			}
+			arr = append(arr, v)
+		}
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		lx.pos++
+		return pdfKeyword(string(c)), true
+	}
+
+	start := lx.pos
+	for lx.pos < len(lx.data) && !isPDFWhitespace(lx.data[lx.pos]) && !isPDFDelimiter(lx.data[lx.pos]) {
+		lx.pos++
+	}
+	word := string(lx.data[start:lx.pos])
+	if f, err := strconv.ParseFloat(word, 64); err == nil {
+		return f, true
+	}
+	switch word {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	case "null":
+		return nil, true
+	}
+	return pdfKeyword(word), true
+}
+
+// parseValue 读取一个值，识别 "num gen R" 间接引用
+func (lx *pdfLexer) parseValue() interface{} {
+	v, ok := lx.next()
+	if !ok {
+		return nil
+	}
+	num, isNum := v.(float64)
+	if !isNum {
+		return v
+	}
+	save := lx.pos
+	gen, ok1 := lx.next()
+	r, ok2 := lx.next()
+	if g, isNum := gen.(float64); ok1 && ok2 && isNum && r == pdfKeyword("R") {
+		return pdfRef{num: int(num), gen: int(g)}
+	}
+	lx.pos = save
+	return num
+}
+
+func (lx *pdfLexer) literalString() string {
+	lx.pos++ // (
+	var sb []byte
+	depth := 1
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		lx.pos++
+		switch c {
+		case '(':
+			depth++
+			sb = append(sb, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return string(sb)
+			}
+			sb = append(sb, c)
+		case '\\':
+			if lx.pos >= len(lx.data) {
+				return string(sb)
+			}
+			e := lx.data[lx.pos]
+			lx.pos++
+			switch e {
+			case 'n':
+				sb = append(sb, '\n')
+			case 'r':
+				sb = append(sb, '\r')
+			case 't':
+				sb = append(sb, '\t')
+			case 'b':
+				sb = append(sb, '\b')
+			case 'f':
+				sb = append(sb, '\f')
+			case '\r':
+				if lx.pos < len(lx.data) && lx.data[lx.pos] == '\n' {
+					lx.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for k := 0; k < 2 && lx.pos < len(lx.data) && lx.data[lx.pos] >= '0' && lx.data[lx.pos] <= '7'; k++ {
+						v = v*8 + int(lx.data[lx.pos]-'0')
+						lx.pos++
+					}
+					sb = append(sb, byte(v))
+				} else {
+					sb = append(sb, e)
+				}
+			}
+		default:
+			sb = append(sb, c)
+		}
+	}
+	return string(sb)
+}
+
+// skipInlineImage 跳过 BI ... ID <二进制数据> EI
+func (lx *pdfLexer) skipInlineImage() {
+	idx := bytes.Index(lx.data[lx.pos:], []byte("ID"))
+	if idx < 0 {
+		lx.pos = len(lx.data)
+		return
+	}
+	lx.pos += idx + 2
+	for lx.pos < len(lx.data) {
+		end := bytes.Index(lx.data[lx.pos:], []byte("EI"))
+		if end < 0 {
+			lx.pos = len(lx.data)
+			return
+		}
+		at := lx.pos + end
+		lx.pos = at + 2
+		if at > 0 && isPDFWhitespace(lx.data[at-1]) && (lx.pos >= len(lx.data) || isPDFWhitespace(lx.data[lx.pos])) {
+			return
+		}
+	}
+}
+
+func decodeNameEscapes(name string) string {
+	if !strings.Contains(name, "#") {
+		return name
+	}
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '#' && i+2 < len(name) && isHexDigit(name[i+1]) && isHexDigit(name[i+2]) {
+			v, _ := strconv.ParseUint(name[i+1:i+3], 16, 8)
+			sb.WriteByte(byte(v))
+			i += 2
+			continue
+		}
+		sb.WriteByte(name[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,392 @@
+package media
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildTestPDF 构造两页 PDF：第一页为未压缩内容流（标准字体），第二页为 Flate 压缩并使用 ToUnicode 的 CID 字体
+func buildTestPDF(t *testing.T) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write([]byte("BT /F2 12 Tf 72 700 Td <00010002> Tj ET"))
+	require.NoError(t, zw.Close())
+
+	cmap := "/CIDInit /ProcSet findresource begin\nbegincmap\n1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"2 beginbfchar <0001> <4F60> <0002> <597D> endbfchar\nendcmap\nend"
+	page1 := "BT /F1 12 Tf 72 700 Td (Hello \\(PDF\\) World) Tj 0 -14 Td (Second line) Tj 0 -14 Td [(Sp) -250 (aced)] TJ ET"
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R /Resources << /Font << /F2 8 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(page1), page1),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.String()),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Song /Encoding /Identity-H /ToUnicode 9 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(cmap), cmap),
+		"<< /Title (Test Report) /Author (Ann Lee) >>",
+	}
+	for i, obj := range objects {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	b.WriteString("trailer\n<< /Size 11 /Root 1 0 R /Info 10 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+func buildTestZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+const testCoreXML = `<?xml version="1.0"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <dc:title>Quarterly Report</dc:title><dc:creator>Ann Lee</dc:creator>
+</cp:coreProperties>`
+
+func TestExtractPDF(t *testing.T) {
+	doc, err := ExtractDocument(writeTestFile(t, "report.pdf", buildTestPDF(t)), "")
+	require.NoError(t, err)
+
+	assert.Equal(t, DocumentPDF, doc.Format)
+	assert.Equal(t, "Test Report", doc.Title)
+	assert.Equal(t, "Ann Lee", doc.Author)
+	require.Len(t, doc.Pages, 2)
+	assert.Equal(t, "Page 1", doc.Pages[0].Anchor)
+	assert.Equal(t, "Hello (PDF) World\nSecond line\nSp aced", doc.Pages[0].Text)
+	assert.Equal(t, "你好", doc.Pages[1].Text)
+}
+
+func TestExtractPDFRejectsEncrypted(t *testing.T) {
+	data := []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n" +
+		"trailer\n<< /Root 1 0 R /Encrypt 3 0 R >>\n%%EOF\n")
+	_, err := ExtractDocument(writeTestFile(t, "locked.pdf", data), "")
+	assert.ErrorContains(t, err, "encrypted")
+}
+
+func TestExtractPDFRejectsDeepNesting(t *testing.T) {
+	deep := strings.Repeat("[", 1_000_000) + strings.Repeat("<<", 1_000_000)
+	data := []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n" +
+		"2 0 obj\n<< /Type /Pages /Kids " + deep + " >>\nendobj\n" +
+		"trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	_, err := parsePDF(data)
+	assert.ErrorContains(t, err, "nested deeper than 256 levels")
+}
+
+func TestExtractPDFRejectsOversizedStream(t *testing.T) {
+	var compressed bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&compressed, zlib.BestCompression)
+	require.NoError(t, err)
+	zeros := make([]byte, 1<<20)
+	for i := 0; i <= maxPDFStreamSize>>20; i++ {
+		_, err = zw.Write(zeros)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	b.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	b.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	b.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>\nendobj\n")
+	fmt.Fprintf(&b, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream\nendobj\n", compressed.Len(), compressed.String())
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+
+	_, err = parsePDF(b.Bytes())
+	assert.ErrorContains(t, err, "PDF stream exceeds 64 MB")
+}
+
+func TestExtractDOCX(t *testing.T) {
+	data := buildTestZip(t, map[string]string{
+		"docProps/core.xml": testCoreXML,
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId9" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://go.dev/" TargetMode="External"/>
+</Relationships>`,
+		"word/document.xml": `<?xml version="1.0"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<w:body>
+  <w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Report</w:t></w:r></w:p>
+  <w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Intro</w:t></w:r></w:p>
+  <w:p><w:r><w:t xml:space="preserve">Read </w:t></w:r><w:hyperlink r:id="rId9"><w:r><w:t>the Go site</w:t></w:r></w:hyperlink><w:r><w:t>.</w:t></w:r></w:p>
+  <w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>item one</w:t></w:r></w:p>
+  <w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>nested</w:t></w:r></w:p>
+  <w:tbl>
+    <w:tr><w:tc><w:p><w:r><w:t>Name</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Value</w:t></w:r></w:p></w:tc></w:tr>
+    <w:tr><w:tc><w:p><w:r><w:t>alpha</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>1 | 2</w:t></w:r></w:p></w:tc></w:tr>
+  </w:tbl>
+  <w:p><w:r><w:br w:type="page"/><w:t>After the break</w:t></w:r></w:p>
+</w:body>
+</w:document>`,
+	})
+	doc, err := ExtractDocument(writeTestFile(t, "report.docx", data), "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Quarterly Report", doc.Title)
+	assert.Equal(t, "Ann Lee", doc.Author)
+	require.Len(t, doc.Pages, 2)
+	assert.Equal(t, "# Report\n\n## Intro\n\nRead [the Go site](https://go.dev/).\n\n- item one\n\n  - nested\n\n"+
+		"| Name | Value |\n| --- | --- |\n| alpha | 1 \\| 2 |", doc.Pages[0].Text)
+	assert.Equal(t, "Page 2", doc.Pages[1].Anchor)
+	assert.Equal(t, "After the break", doc.Pages[1].Text)
+}
+
+func TestExtractDOCXWithoutPageBreaksIsChunked(t *testing.T) {
+	var body strings.Builder
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&body, "<w:p><w:r><w:t>Paragraph %d %s</w:t></w:r></w:p>", i, strings.Repeat("x", 300))
+	}
+	data := buildTestZip(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			body.String() + `</w:body></w:document>`,
+	})
+	doc, err := ExtractDocument(writeTestFile(t, "long.docx", data), "")
+	require.NoError(t, err)
+	require.Len(t, doc.Pages, 3)
+	assert.True(t, strings.HasPrefix(doc.Pages[1].Text, "Paragraph 12 "))
+}
+
+func TestExtractXLSX(t *testing.T) {
+	data := buildTestZip(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets><sheet name="Sales" sheetId="1" r:id="rId1"/><sheet name="Empty" sheetId="2" r:id="rId2"/></sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+  <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/>
+</Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <si><t>Name</t></si><si><t>Qty</t></si><si><r><t>app</t></r><r><t>le</t></r><rPh><t>ignored</t></rPh></si>
+</sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+  <row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+  <row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><f>1+2</f><v>3</v></c></row>
+  <row r="4"><c r="A4" t="inlineStr"><is><t>pear</t></is></c><c r="C4" t="b"><v>1</v></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`,
+	})
+	doc, err := ExtractDocument(writeTestFile(t, "sales.xlsx", data), "")
+	require.NoError(t, err)
+	require.Len(t, doc.Pages, 2)
+	assert.Equal(t, "Sheet: Sales (rows 1-4)", doc.Pages[0].Anchor)
+	assert.Equal(t, "| Name | Qty |  |\n| --- | --- | --- |\n| apple | 3 |  |\n| pear |  | TRUE |", doc.Pages[0].Text)
+	assert.Equal(t, "Sheet: Empty (empty)", doc.Pages[1].Anchor)
+	assert.Equal(t, "sheet sections", doc.PageUnit())
+}
+
+func TestSheetSectionsRepeatHeader(t *testing.T) {
+	rows := []xlsxRow{{num: 1, cells: map[int]string{0: "id"}}}
+	for i := 2; i <= 451; i++ {
+		rows = append(rows, xlsxRow{num: i, cells: map[int]string{0: fmt.Sprint(i)}})
+	}
+	pages := sheetSections("Data", rows)
+	require.Len(t, pages, 3)
+	assert.Equal(t, "Sheet: Data (rows 1-201)", pages[0].Anchor)
+	assert.Equal(t, "Sheet: Data (rows 202-401)", pages[1].Anchor)
+	assert.Equal(t, "Sheet: Data (rows 402-451)", pages[2].Anchor)
+	assert.True(t, strings.HasPrefix(pages[2].Text, "| id |\n| --- |\n| 402 |"))
+}
+
+func TestExtractXLSXBoundsCellRanges(t *testing.T) {
+	col, ok := cellColumn("XFD1")
+	assert.True(t, ok)
+	assert.Equal(t, xlsxMaxColumns-1, col)
+	_, ok = cellColumn("XFE1")
+	assert.False(t, ok)
+	_, ok = cellColumn("ZZZZZZZZZZZZZZ1")
+	assert.False(t, ok)
+
+	data := buildTestZip(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets><sheet name="Bad" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+  <row r="1"><c r="A1" t="inlineStr"><is><t>name</t></is></c><c r="ZZZZZZZZZZZZZZ1" t="inlineStr"><is><t>far</t></is></c></row>
+</sheetData></worksheet>`,
+	})
+	doc, err := ExtractDocument(writeTestFile(t, "bad.xlsx", data), "")
+	require.NoError(t, err)
+	assert.Equal(t, "| name | far |\n| --- | --- |", doc.Pages[0].Text)
+
+	// 行数 × 列宽超过 xlsxMaxCells 时截断
+	var rows []xlsxRow
+	for i := 1; i <= 300; i++ {
+		rows = append(rows, xlsxRow{num: i, cells: map[int]string{0: fmt.Sprint(i), xlsxMaxColumns - 1: "z"}})
+	}
+	pages := sheetSections("Wide", rows)
+	last := pages[len(pages)-1]
+	assert.Equal(t, "Sheet: Wide (rows 202-256)", last.Anchor)
+	assert.True(t, strings.HasSuffix(last.Text, "(sheet truncated: more than 4194304 cells)"))
+}
+
+func TestExtractDOCXClampsListLevel(t *testing.T) {
+	data := buildTestZip(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+  <w:p><w:pPr><w:numPr><w:ilvl w:val="2000000000"/></w:numPr></w:pPr><w:r><w:t>deep</w:t></w:r></w:p>
+  <w:p><w:pPr><w:numPr><w:ilvl w:val="-3"/></w:numPr></w:pPr><w:r><w:t>negative</w:t></w:r></w:p>
+</w:body></w:document>`,
+	})
+	doc, err := ExtractDocument(writeTestFile(t, "levels.docx", data), "")
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("  ", maxListLevel)+"- deep\n\nnegative", doc.Pages[0].Text)
+}
+
+func TestExtractPPTX(t *testing.T) {
+	slide := func(title, body string) string {
+		return `<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><p:cSld><p:spTree>
+  <p:sp><p:nvSpPr><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>` + title + `</a:t></a:r></a:p></p:txBody></p:sp>
+  <p:sp><p:nvSpPr><p:nvPr><p:ph idx="1"/></p:nvPr></p:nvSpPr><p:txBody>` + body + `</p:txBody></p:sp>
+  <p:sp><p:nvSpPr><p:nvPr><p:ph type="sldNum"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>7</a:t></a:r></a:p></p:txBody></p:sp>
+</p:spTree></p:cSld></p:sld>`
+	}
+	data := buildTestZip(t, map[string]string{
+		"ppt/presentation.xml": `<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <p:sldIdLst><p:sldId id="256" r:id="rId3"/><p:sldId id="257" r:id="rId2"/></p:sldIdLst>
+</p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide2.xml"/>
+  <Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide1.xml"/>
+</Relationships>`,
+		"ppt/slides/slide1.xml": slide("Overview", `<a:p><a:r><a:t>Point A</a:t></a:r></a:p><a:p><a:pPr lvl="1"/><a:r><a:t>Sub point</a:t></a:r></a:p>`),
+		"ppt/slides/slide2.xml": slide("Next", `<a:p><a:r><a:t>Closing</a:t></a:r></a:p>`),
+		"ppt/slides/_rels/slide1.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide" Target="../notesSlides/notesSlide1.xml"/>
+</Relationships>`,
+		"ppt/notesSlides/notesSlide1.xml": `<p:notes xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><p:cSld><p:spTree>
+  <p:sp><p:nvSpPr><p:nvPr><p:ph type="sldImg"/></p:nvPr></p:nvSpPr></p:sp>
+  <p:sp><p:nvSpPr><p:nvPr><p:ph type="body" idx="1"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>Speaker note</a:t></a:r></a:p></p:txBody></p:sp>
+</p:spTree></p:cSld></p:notes>`,
+	})
+	doc, err := ExtractDocument(writeTestFile(t, "deck.pptx", data), "")
+	require.NoError(t, err)
+	require.Len(t, doc.Pages, 2)
+	assert.Equal(t, "Slide 1: Overview", doc.Pages[0].Anchor)
+	assert.Equal(t, "- Point A\n  - Sub point\n\nNotes:\nSpeaker note", doc.Pages[0].Text)
+	assert.Equal(t, "Slide 2: Next", doc.Pages[1].Anchor)
+	assert.Equal(t, "- Closing", doc.Pages[1].Text)
+}
+
+func TestExtractEPUB(t *testing.T) {
+	data := buildTestZip(t, map[string]string{
+		"mimetype": "application/epub+zip",
+		"META-INF/container.xml": `<container xmlns="urn:oasis:names:tc:opendocument:xmlns:container" version="1.0">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`,
+		"OEBPS/content.opf": `<package xmlns="http://www.idpf.org/2007/opf" xmlns:dc="http://purl.org/dc/elements/1.1/" version="3.0">
+  <metadata><dc:title>A Tale</dc:title><dc:creator>B. Writer</dc:creator></metadata>
+  <manifest>
+    <item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c1" href="text/ch%201.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c2" href="text/ch2.xhtml" media-type="application/xhtml+xml"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+  </manifest>
+  <spine><itemref idref="cover"/><itemref idref="c1"/><itemref idref="c2"/></spine>
+</package>`,
+		"OEBPS/cover.xhtml": `<html><body><img src="cover.jpg" alt="Cover"/></body></html>`,
+		"OEBPS/text/ch 1.xhtml": `<?xml version="1.0"?><html xmlns="http://www.w3.org/1999/xhtml"><head><title>One</title><style>p{}</style></head>
+<body><h1>Beginning</h1><p>It was a
+  dark&nbsp;night &amp; <a href="https://x.example/">a link</a>.<br/>New line</p>
+<ul><li>one</li><li>two<ul><li>deep</li></ul></li></ul>
+<table><tr><th>K</th><th>V</th></tr><tr><td>a</td><td><p>1</p></td></tr></table></body></html>`,
+		"OEBPS/text/ch2.xhtml": `<html><head><title>Second Part</title></head><body><p>Unclosed <b>bold<p>Next</body></html>`,
+	})
+	doc, err := ExtractDocument(writeTestFile(t, "tale.epub", data), "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "A Tale", doc.Title)
+	assert.Equal(t, "B. Writer", doc.Author)
+	require.Len(t, doc.Pages, 2)
+	assert.Equal(t, "Chapter 1: Beginning", doc.Pages[0].Anchor)
+	assert.Equal(t, "# Beginning\n\nIt was a dark night & [a link](https://x.example/).\nNew line\n\n- one\n\n- two\n\n  - deep\n\n"+
+		"| K | V |\n| --- | --- |\n| a | 1 |", doc.Pages[0].Text)
+	assert.Equal(t, "Chapter 2: Second Part", doc.Pages[1].Anchor)
+	assert.Contains(t, doc.Pages[1].Text, "Unclosed bold")
+	assert.Contains(t, doc.Pages[1].Text, "Next")
+}
+
+func TestDetectDocumentFormat(t *testing.T) {
+	assert.Equal(t, DocumentPDF, DetectDocumentFormat("x.bin", "application/pdf; charset=binary"))
+	assert.Equal(t, DocumentXLSX, DetectDocumentFormat("Book.XLSX", ""))
+	assert.Equal(t, DocumentPDF, DetectDocumentFormat(writeTestFile(t, "upload", buildTestPDF(t)), ""))
+	docx := buildTestZip(t, map[string]string{"word/document.xml": "<w:document/>"})
+	assert.Equal(t, DocumentDOCX, DetectDocumentFormat(writeTestFile(t, "upload.bin", docx), "application/octet-stream"))
+	assert.Equal(t, "", DetectDocumentFormat(writeTestFile(t, "notes.txt", []byte("plain")), "text/plain"))
+	assert.Equal(t, "", DetectDocumentFormat(writeTestFile(t, "a.zip", buildTestZip(t, map[string]string{"a.txt": "x"})), ""))
+}
+
+func TestDocumentRenderPaging(t *testing.T) {
+	doc := &Document{Format: DocumentPDF, Title: "T"}
+	for i := 1; i <= 5; i++ {
+		doc.Pages = append(doc.Pages, DocumentPage{Anchor: fmt.Sprintf("Page %d", i), Text: fmt.Sprintf("text %d", i)})
+	}
+	out, err := doc.Render(1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "[PDF document: T, 5 pages]\n\n## Page 2\n\ntext 2\n\n## Page 3\n\ntext 3\n\n"+
+		"... (showing pages 2-3 of 5; use offset=3 to continue)", out)
+
+	out, err = doc.Render(3, 0)
+	require.NoError(t, err)
+	assert.NotContains(t, out, "use offset")
+	assert.Contains(t, out, "## Page 5")
+
+	_, err = doc.Render(5, 1)
+	assert.ErrorContains(t, err, "offset 5 exceeds document length (5 pages)")
+}
+
+func TestDocumentContextLimitsSize(t *testing.T) {
+	path := writeTestFile(t, "report.pdf", buildTestPDF(t))
+	full, err := DocumentContext(path, "Report.pdf", "application/pdf", 0)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(full, `Attached document "Report.pdf" (path: `+path+`; read more with read_file offset/limit in pages):`))
+	assert.Contains(t, full, "## Page 2")
+
+	var body strings.Builder
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&body, "<w:p><w:r><w:t>Paragraph %d %s</w:t></w:r></w:p>", i, strings.Repeat("x", 300))
+	}
+	long := writeTestFile(t, "long.docx", buildTestZip(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			body.String() + `</w:body></w:document>`,
+	}))
+	short, err := DocumentContext(long, "", "", 9000)
+	require.NoError(t, err)
+	assert.Contains(t, short, "## Page 2")
+	assert.NotContains(t, short, "## Page 3")
+	assert.Contains(t, short, "use offset=2 to continue")
+
+	tiny, err := DocumentContext(long, "", "", 500)
+	require.NoError(t, err)
+	assert.Contains(t, tiny, "## Page 1")
+	assert.Contains(t, tiny, "(truncated; use read_file for the full text)")
+}
@@ -0,0 +1,597 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Lichas/maxclaw/pkg/textdiff"
+)
+
+const (
+	// DefaultMaxTokens MEMORY.md 的默认 token 预算
+	DefaultMaxTokens = 4000
+	// DefaultKeepDailyDays MEMORY.md 中默认保留的每日摘要天数
+	DefaultKeepDailyDays = 7
+
+	feedbackLessonPrefix = "## User Feedback Lesson"
+	curationBackupDir    = "backups"
+	maxCurationBackups   = 20
+	// 相似度（词集合 Jaccard）达到阈值视为重复
+	lessonSimilarity = 0.8
+	bulletSimilarity = 0.9
+)
+
+var (
+	dailyHeadingRe = regexp.MustCompile(`^### (\d{4}-\d{2}-\d{2})\s*$`)
+	lessonFieldRe  = regexp.MustCompile(`^- \*\*(.+?)\*\*:\s*(.*)$`)
+)
+
+// Condenser 用 LLM 合并整理记忆正文，maxTokens 为目标上限；返回整理后的 Markdown
+type Condenser func(ctx context.Context, content string, maxTokens int) (string, error)
+
+// CurateOptions 记忆整理参数，零值使用默认值
+type CurateOptions struct {
+	MaxTokens     int
+	KeepDailyDays int
+	// Condense 为空时只使用确定性规则
+	Condense Condenser
+	// ForceLLM 即使没有发现重复、也未超出预算，也让 LLM 整理一遍
+	ForceLLM bool
+	// DryRun 只计算结果与 diff，不写文件
+	DryRun bool
+	Now    time.Time
+	// Scope 要整理的作用域，零值为全局 MEMORY.md
+	Scope Scope
+}
+
+// CurationReport 一次整理的结果；Diff 为 MEMORY.md 的 unified diff
+type CurationReport struct {
+	Changed           bool     `json:"changed"`
+	BeforeTokens      int      `json:"beforeTokens"`
+	AfterTokens       int      `json:"afterTokens"`
+	MaxTokens         int      `json:"maxTokens"`
+	OverBudget        bool     `json:"overBudget"`
+	ArchivedDays      []string `json:"archivedDays,omitempty"`
+	ArchivedLessons   int      `json:"archivedLessons"`
+	MergedLessons     int      `json:"mergedLessons"`
+	RemovedDuplicates int      `json:"removedDuplicates"`
+	UsedLLM           bool     `json:"usedLLM"`
+	LLMError          string   `json:"llmError,omitempty"`
+	Diff              string   `json:"diff,omitempty"`
+	BackupPath        string   `json:"backupPath,omitempty"`
+	DiffPath          string   `json:"diffPath,omitempty"`
+}
+
+// memoryDoc MEMORY.md 的结构化视图：前言、结构化条目区块、普通章节、反馈教训与每日摘要
+type memoryDoc struct {
+	preamble []string
+	// entries 由 EntryStore 维护的受管区块，整理时原样保留
+	entries  string
+	sections []*memorySection
+	lessons  []*memoryLesson
+	days     []*dailyEntry
+}
+
+type memorySection struct {
+	heading string
+	lines   []string
+}
+
+type memoryLesson struct {
+	id          string
+	taskType    string
+	issueType   string
+	lesson      string
+	occurrences int
+	learned     string
+}
+
+type dailyEntry struct {
+	day   string
+	lines []string
+}
+
+// Curate 整理 workspace 中 opts.Scope 作用域的 MEMORY.md：
+// 旧的每日摘要归档到 HISTORY.md，合并重复的反馈教训，去除重复条目，并尽量控制在 token 预算内。
+// 有改动时先备份原文件并保存 diff，便于审阅与回滚
+func Curate(ctx context.Context, workspace string, opts CurateOptions) (*CurationReport, error) {
+	store := NewScopedStore(workspace, opts.Scope)
+	original, err := store.ReadLongTerm()
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = DefaultMaxTokens
+	}
+	if opts.KeepDailyDays <= 0 {
+		opts.KeepDailyDays = DefaultKeepDailyDays
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	report := &CurationReport{MaxTokens: opts.MaxTokens, BeforeTokens: EstimateTokens(original)}
+	doc := parseMemoryDoc(original)
+	var archive []string
+
+	// 1. 超出保留期的每日摘要移入 HISTORY.md
+	cutoff := opts.Now.AddDate(0, 0, -opts.KeepDailyDays).Format("2006-01-02")
+	archive = append(archive, doc.archiveDays(func(d *dailyEntry) bool { return d.day < cutoff }, report)...)
+
+	// 2. 合并重复教训、去除重复条目
+	report.MergedLessons = doc.mergeLessons()
+	report.RemovedDuplicates = doc.dedupeSections()
+
+	// 3. 仍超出预算时，继续归档最早的每日摘要
+	if EstimateTokens(doc.render()) > opts.MaxTokens {
+		archive = append(archive, doc.archiveDays(func(*dailyEntry) bool {
+			return EstimateTokens(doc.render()) > opts.MaxTokens
+		}, report)...)
+	}
+
+	// 4. 发现冗余或仍超预算时交给 LLM 合并整理
+	redundant := report.MergedLessons > 0 || report.RemovedDuplicates > 0
+	if opts.Condense != nil && (opts.ForceLLM || redundant || EstimateTokens(doc.render()) > opts.MaxTokens) {
+		if err := doc.condense(ctx, opts.Condense, opts.MaxTokens); err != nil {
+			report.LLMError = err.Error()
+		} else {
+			report.UsedLLM = true
+		}
+	}
+
+	// 5. 最后按时间顺序归档最早、出现次数最少的教训；普通章节不会被自动删除
+	for EstimateTokens(doc.render()) > opts.MaxTokens && len(doc.lessons) > 0 {
+		archive = append(archive, doc.archiveOldestLesson(opts.Now))
+		report.ArchivedLessons++
+	}
+
+	curated := doc.render()
+	report.AfterTokens = EstimateTokens(curated)
+	report.OverBudget = report.AfterTokens > opts.MaxTokens
+	report.Changed = curated != original
+	if !report.Changed {
+		return report, nil
+	}
+	report.Diff = textdiff.Unified("memory/MEMORY.md", original, curated)
+	if opts.DryRun {
+		return report, nil
+	}
+
+	// 整理期间文件被其他写入方修改时放弃，避免覆盖新内容
+	current, err := store.ReadLongTerm()
+	if err != nil {
+		return nil, err
+	}
+	if current != original {
+		return nil, fmt.Errorf("memory file changed during curation, try again")
+	}
+	if err := writeCurationBackup(store, original, report, opts.Now); err != nil {
+		return nil, err
+	}
+	for _, entry := range archive {
+		if err := store.AppendHistory(entry); err != nil {
+			return nil, err
+		}
+	}
+	if err := store.WriteLongTerm(curated); err != nil {
+		return nil, fmt.Errorf("write memory file: %w", err)
+	}
+	return report, nil
+}
+
+// EstimateTokens 粗略估算 token 数：ASCII 约 4 字符一个 token，其他字符（如中文）按一字一个计
+func EstimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < unicode.MaxASCII {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+func writeCurationBackup(store *Store, original string, report *CurationReport, now time.Time) error {
+	dir := filepath.Join(store.memoryDir, curationBackupDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create memory backup dir: %w", err)
+	}
+	stamp := now.Format("20060102-150405")
+	report.BackupPath = filepath.Join(dir, "MEMORY-"+stamp+".md")
+	report.DiffPath = filepath.Join(dir, "MEMORY-"+stamp+".diff")
+	if err := os.WriteFile(report.BackupPath, []byte(original), 0644); err != nil {
+		return fmt.Errorf("write memory backup: %w", err)
+	}
+	if err := os.WriteFile(report.DiffPath, []byte(report.Diff), 0644); err != nil {
+		return fmt.Errorf("write memory diff: %w", err)
+	}
+	pruneCurationBackups(dir)
+	return nil
+}
+
+// pruneCurationBackups 只保留最近的若干份备份
+func pruneCurationBackups(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var stamps []string
+	for _, e := range entries {
+		if name := e.Name(); strings.HasPrefix(name, "MEMORY-") && strings.HasSuffix(name, ".md") {
+			stamps = append(stamps, strings.TrimSuffix(strings.TrimPrefix(name, "MEMORY-"), ".md"))
+		}
+	}
+	sort.Strings(stamps)
+	for len(stamps) > maxCurationBackups {
+		_ = os.Remove(filepath.Join(dir, "MEMORY-"+stamps[0]+".md"))
+		_ = os.Remove(filepath.Join(dir, "MEMORY-"+stamps[0]+".diff"))
+		stamps = stamps[1:]
+	}
+}
+
+// parseMemoryDoc 按 ## 章节与 ### YYYY-MM-DD 每日摘要拆分；代码块内的标题不参与拆分
+func parseMemoryDoc(content string) *memoryDoc {
+	doc := &memoryDoc{}
+	var section *memorySection
+	var day *dailyEntry
+	inFence := false
+
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if start, end, ok := findEntriesBlock(lines); ok {
+		doc.entries = strings.Join(lines[start:end+1], "\n")
+		lines = append(append([]string(nil), lines[:start]...), lines[end+1:]...)
+	}
+
+	flushSection := func() {
+		if section == nil {
+			return
+		}
+		if strings.HasPrefix(section.heading, feedbackLessonPrefix) {
+			if lesson, ok := parseLesson(section); ok {
+				doc.lessons = append(doc.lessons, lesson)
+				section = nil
+				return
+			}
+		}
+		if section.heading != dailySummaryHeader {
+			doc.sections = append(doc.sections, section)
+		}
+		section = nil
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if !inFence {
+			if m := dailyHeadingRe.FindStringSubmatch(line); m != nil {
+				day = &dailyEntry{day: m[1]}
+				doc.days = append(doc.days, day)
+				continue
+			}
+			if strings.HasPrefix(line, "## ") {
+				flushSection()
+				day = nil
+				section = &memorySection{heading: strings.TrimSpace(line)}
+				continue
+			}
+		}
+		switch {
+		case day != nil:
+			day.lines = append(day.lines, line)
+		case section != nil:
+			section.lines = append(section.lines, line)
+		default:
+			doc.preamble = append(doc.preamble, line)
+		}
+	}
+	flushSection()
+	return doc
+}
+
+func parseLesson(section *memorySection) (*memoryLesson, bool) {
+	lesson := &memoryLesson{occurrences: 1}
+	if start, end := strings.Index(section.heading, "["), strings.LastIndex(section.heading, "]"); start >= 0 && end > start {
+		lesson.id = section.heading[start+1 : end]
+	}
+	for _, line := range section.lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		m := lessonFieldRe.FindStringSubmatch(line)
+		if m == nil {
+			return nil, false
+		}
+		switch m[1] {
+		case "Task Type":
+			lesson.taskType = m[2]
+		case "Issue Type":
+			lesson.issueType = m[2]
+		case "Lesson":
+			lesson.lesson = m[2]
+		case "Occurrences":
+			if n, err := strconv.Atoi(m[2]); err == nil && n > 0 {
+				lesson.occurrences = n
+			}
+		case "Learned":
+			lesson.learned = m[2]
+		default:
+			return nil, false
+		}
+	}
+	return lesson, lesson.lesson != ""
+}
+
+// render 以固定顺序输出：前言、结构化条目、普通章节、反馈教训、每日摘要（按日期升序）
+func (d *memoryDoc) render() string {
+	var blocks []string
+	if pre := strings.TrimSpace(strings.Join(d.preamble, "\n")); pre != "" {
+		blocks = append(blocks, pre)
+	}
+	if d.entries != "" {
+		blocks = append(blocks, d.entries)
+	}
+	for _, s := range d.sections {
+		blocks = append(blocks, strings.TrimRight(s.heading+"\n\n"+collapseBlankLines(s.lines), "\n"))
+	}
+	for _, l := range d.lessons {
+		blocks = append(blocks, l.render())
+	}
+	if len(d.days) > 0 {
+		days := append([]*dailyEntry(nil), d.days...)
+		sort.SliceStable(days, func(i, j int) bool { return days[i].day < days[j].day })
+		var parts []string
+		for _, day := range days {
+			parts = append(parts, day.render())
+		}
+		blocks = append(blocks, dailySummaryHeader+"\n\n"+strings.Join(parts, "\n\n"))
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+func (l *memoryLesson) render() string {
+	return fmt.Sprintf("%s [%s]\n\n"+
+		"- **Task Type**: %s\n"+
+		"- **Issue Type**: %s\n"+
+		"- **Lesson**: %s\n"+
+		"- **Occurrences**: %d\n"+
+		"- **Learned**: %s",
+		feedbackLessonPrefix, l.id, l.taskType, l.issueType, l.lesson, l.occurrences, l.learned)
+}
+
+func (e *dailyEntry) render() string {
+	return strings.TrimRight("### "+e.day+"\n"+strings.Trim(strings.Join(e.lines, "\n"), "\n"), "\n")
+}
+
+// archiveDays 按日期从旧到新归档满足条件的每日摘要，返回写入 HISTORY.md 的条目
+func (d *memoryDoc) archiveDays(match func(*dailyEntry) bool, report *CurationReport) []string {
+	sort.SliceStable(d.days, func(i, j int) bool { return d.days[i].day < d.days[j].day })
+	var entries []string
+	for len(d.days) > 0 && match(d.days[0]) {
+		day := d.days[0]
+		d.days = d.days[1:]
+		entries = append(entries, "### ["+day.day+"] daily summary\n"+strings.Trim(strings.Join(day.lines, "\n"), "\n"))
+		report.ArchivedDays = append(report.ArchivedDays, day.day)
+	}
+	return entries
+}
+
+// archiveOldestLesson 归档出现次数最少、学到最早的教训
+func (d *memoryDoc) archiveOldestLesson(now time.Time) string {
+	idx := 0
+	for i, l := range d.lessons {
+		best := d.lessons[idx]
+		if l.occurrences < best.occurrences || (l.occurrences == best.occurrences && l.learned < best.learned) {
+			idx = i
+		}
+	}
+	lesson := d.lessons[idx]
+	d.lessons = append(d.lessons[:idx], d.lessons[idx+1:]...)
+	learned := lesson.learned
+	if learned == "" {
+		learned = now.Format("2006-01-02")
+	}
+	body := strings.SplitN(lesson.render(), "\n\n", 2)[1]
+	return "### [" + learned + "] feedback lesson " + lesson.id + "\n" + body
+}
+
+// mergeLessons 合并任务类型、问题类型相同且内容相近的教训，累加出现次数并保留最近的学习日期
+func (d *memoryDoc) mergeLessons() int {
+	var kept []*memoryLesson
+	merged := 0
+	for _, l := range d.lessons {
+		var target *memoryLesson
+		for _, k := range kept {
+			if strings.EqualFold(k.taskType, l.taskType) && strings.EqualFold(k.issueType, l.issueType) &&
+				similarity(k.lesson, l.lesson) >= lessonSimilarity {
+				target = k
+				break
+			}
+		}
+		if target == nil {
+			kept = append(kept, l)
+			continue
+		}
+		target.occurrences += l.occurrences
+		if l.learned > target.learned {
+			target.learned = l.learned
+		}
+		merged++
+	}
+	d.lessons = kept
+	return merged
+}
+
+// dedupeSections 合并同名章节，并删除与前文重复或近似重复的列表项
+func (d *memoryDoc) dedupeSections() int {
+	removed := 0
+	var kept []*memorySection
+	byHeading := make(map[string]*memorySection)
+	for _, s := range d.sections {
+		key := strings.ToLower(s.heading)
+		if existing, ok := byHeading[key]; ok {
+			existing.lines = append(trimBlankLines(existing.lines), trimBlankLines(s.lines)...)
+			continue
+		}
+		byHeading[key] = s
+		kept = append(kept, s)
+	}
+	d.sections = kept
+
+	var seen []string
+	isDuplicate := func(item string) bool {
+		for _, prev := range seen {
+			if prev == item || (len(strings.Fields(item)) >= 4 && similarity(prev, item) >= bulletSimilarity) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, s := range d.sections {
+		lines := s.lines[:0]
+		inFence := false
+		for _, line := range s.lines {
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				inFence = !inFence
+			}
+			if !inFence && (strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ")) {
+				item := normalizeMemoryText(line[2:])
+				if item != "" && isDuplicate(item) {
+					removed++
+					continue
+				}
+				seen = append(seen, item)
+			}
+			lines = append(lines, line)
+		}
+		s.lines = lines
+	}
+	return removed
+}
+
+// condense 让 LLM 整理普通章节与教训；每日摘要与前言保持不变，结果变长或为空时放弃
+func (d *memoryDoc) condense(ctx context.Context, condense Condenser, maxTokens int) error {
+	body := &memoryDoc{sections: d.sections, lessons: d.lessons}
+	input := strings.TrimSpace(body.render())
+	if input == "" {
+		return nil
+	}
+	budget := maxTokens - EstimateTokens((&memoryDoc{preamble: d.preamble, entries: d.entries, days: d.days}).render())
+	if budget < maxTokens/4 {
+		budget = maxTokens / 4
+	}
+	output, err := condense(ctx, input, budget)
+	if err != nil {
+		return err
+	}
+	output = strings.TrimSpace(stripMarkdownFence(output))
+	if output == "" {
+		return fmt.Errorf("condenser returned empty content")
+	}
+	if EstimateTokens(output) > EstimateTokens(input) {
+		return fmt.Errorf("condenser output is longer than the input")
+	}
+	parsed := parseMemoryDoc(output)
+	if len(parsed.preamble) > 0 && strings.TrimSpace(strings.Join(parsed.preamble, "\n")) != "" {
+		parsed.sections = append([]*memorySection{{heading: "## Notes", lines: parsed.preamble}}, parsed.sections...)
+	}
+	d.sections, d.lessons = parsed.sections, parsed.lessons
+	d.days = append(d.days, parsed.days...)
+	return nil
+}
+
+// trimBlankLines 去掉首尾空行
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func stripMarkdownFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") {
+		return s
+	}
+	s = strings.TrimSuffix(s, "```")
+	if idx := strings.Index(s, "\n"); idx >= 0 {
+		return s[idx+1:]
+	}
+	return ""
+}
+
+func collapseBlankLines(lines []string) string {
+	var out []string
+	blank := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		out = append(out, strings.TrimRight(line, " \t"))
+	}
+	return strings.Trim(strings.Join(out, "\n"), "\n")
+}
+
+// normalizeMemoryText 小写、去掉 Markdown 强调与结尾标点并压缩空白，用于判重
+func normalizeMemoryText(s string) string {
+	s = strings.ToLower(strings.NewReplacer("**", "", "__", "", "`", "").Replace(s))
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.TrimRight(s, ".。!！;；,，")
+}
+
+// similarity 词集合的 Jaccard 相似度；中文等无空格文本按字符计
+func similarity(a, b string) float64 {
+	setA, setB := wordSet(normalizeMemoryText(a)), wordSet(normalizeMemoryText(b))
+	if len(setA) == 0 || len(setB) == 0 {
+		return 0
+	}
+	inter := 0
+	for w := range setA {
+		if setB[w] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(setA)+len(setB)-inter)
+}
+
+func wordSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) }) {
+		if isASCIIWord(field) {
+			set[field] = true
+			continue
+		}
+		for _, r := range field {
+			set[string(r)] = true
+		}
+	}
+	return set
+}
+
+func isASCIIWord(s string) bool {
+	for _, r := range s {
+		if r >= unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
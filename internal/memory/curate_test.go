@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const curateFixture = `# Long-term Memory
+
+This file stores important information that should persist across sessions.
+
+## Preferences
+
+- User prefers concise answers.
+- Deploys happen on Fridays
+
+## User Feedback Lesson [aaaa1111]
+
+- **Task Type**: coding
+- **Issue Type**: style
+- **Lesson**: Use tabs for indentation in Go files
+- **Occurrences**: 1
+- **Learned**: 2026-09-01
+
+## Daily Summaries
+
+### 2026-09-20
+- Sessions active: 1
+- Message count: 4
+
+## User Feedback Lesson [bbbb2222]
+
+- **Task Type**: coding
+- **Issue Type**: style
+- **Lesson**: Use tabs for indentation in Go files.
+- **Occurrences**: 1
+- **Learned**: 2026-10-02
+
+### 2026-10-16
+- Sessions active: 2
+- Message count: 9
+
+## Preferences
+
+- user prefers **concise** answers
+- Timezone is Asia/Shanghai
+`
+
+func writeMemoryFixture(t *testing.T, content string) string {
+	t.Helper()
+	workspace := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workspace, "memory"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "memory", "MEMORY.md"), []byte(content), 0644))
+	return workspace
+}
+
+func readMemoryFile(t *testing.T, workspace, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(workspace, "memory", name))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestCurateAppliesDeterministicRules(t *testing.T) {
+	workspace := writeMemoryFixture(t, curateFixture)
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	report, err := Curate(context.Background(), workspace, CurateOptions{Now: now})
+	require.NoError(t, err)
+	assert.True(t, report.Changed)
+	assert.Equal(t, []string{"2026-09-20"}, report.ArchivedDays)
+	assert.Equal(t, 1, report.MergedLessons)
+	assert.Equal(t, 1, report.RemovedDuplicates)
+	assert.False(t, report.UsedLLM)
+	assert.Less(t, report.AfterTokens, report.BeforeTokens)
+
+	curated := readMemoryFile(t, workspace, "MEMORY.md")
+	assert.Equal(t, `# Long-term Memory
+
+This file stores important information that should persist across sessions.
+
+## Preferences
+
+- User prefers concise answers.
+- Deploys happen on Fridays
+- Timezone is Asia/Shanghai
+
+## User Feedback Lesson [aaaa1111]
+
+- **Task Type**: coding
+- **Issue Type**: style
+- **Lesson**: Use tabs for indentation in Go files
+- **Occurrences**: 2
+- **Learned**: 2026-10-02
+
+## Daily Summaries
+
+### 2026-10-16
+- Sessions active: 2
+- Message count: 9
+`, curated)
+
+	history := readMemoryFile(t, workspace, "HISTORY.md")
+	assert.Contains(t, history, "### [2026-09-20] daily summary\n- Sessions active: 1\n- Message count: 4")
+
+	backup, err := os.ReadFile(report.BackupPath)
+	require.NoError(t, err)
+	assert.Equal(t, curateFixture, string(backup))
+	diff, err := os.ReadFile(report.DiffPath)
+	require.NoError(t, err)
+	assert.Equal(t, report.Diff, string(diff))
+	assert.Contains(t, report.Diff, "--- a/memory/MEMORY.md")
+
+	// 结果稳定：再次整理不会产生改动
+	again, err := Curate(context.Background(), workspace, CurateOptions{Now: now})
+	require.NoError(t, err)
+	assert.False(t, again.Changed)
+	assert.Empty(t, again.BackupPath)
+}
+
+func TestCurateDryRunLeavesFilesUntouched(t *testing.T) {
+	workspace := writeMemoryFixture(t, curateFixture)
+
+	report, err := Curate(context.Background(), workspace, CurateOptions{DryRun: true, Now: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	assert.True(t, report.Changed)
+	assert.NotEmpty(t, report.Diff)
+	assert.Empty(t, report.BackupPath)
+	assert.Equal(t, curateFixture, readMemoryFile(t, workspace, "MEMORY.md"))
+	assert.NoDirExists(t, filepath.Join(workspace, "memory", curationBackupDir))
+}
+
+func TestCurateEnforcesBudgetWithoutDroppingNotes(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("# Long-term Memory\n\n## Project\n\n- The API lives in cmd/server\n")
+	for day := 10; day <= 17; day++ {
+		b.WriteString(fmt.Sprintf("\n### 2026-10-%02d\n", day) + "- Message count: 12\n- User highlights:\n  - " + strings.Repeat("long highlight text ", 10) + "\n")
+	}
+	workspace := writeMemoryFixture(t, b.String())
+
+	report, err := Curate(context.Background(), workspace, CurateOptions{MaxTokens: 120, Now: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	assert.False(t, report.OverBudget)
+	assert.LessOrEqual(t, report.AfterTokens, 120)
+	assert.NotEmpty(t, report.ArchivedDays)
+	assert.Equal(t, "2026-10-10", report.ArchivedDays[0], "oldest days are archived first")
+
+	curated := readMemoryFile(t, workspace, "MEMORY.md")
+	assert.Contains(t, curated, "- The API lives in cmd/server")
+	assert.Contains(t, readMemoryFile(t, workspace, "HISTORY.md"), "### [2026-10-10] daily summary")
+}
+
+func TestCurateUsesCondenserWhenRedundant(t *testing.T) {
+	workspace := writeMemoryFixture(t, curateFixture)
+	var input string
+	condense := func(ctx context.Context, content string, maxTokens int) (string, error) {
+		input = content
+		return "```markdown\n## Preferences\n\n- Concise answers; timezone Asia/Shanghai; deploys on Fridays\n\n## Lessons\n\n- Go files use tabs\n```", nil
+	}
+
+	report, err := Curate(context.Background(), workspace, CurateOptions{Condense: condense, Now: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	assert.True(t, report.UsedLLM)
+	assert.NotContains(t, input, "### 2026-10-16", "daily summaries are not sent to the condenser")
+
+	curated := readMemoryFile(t, workspace, "MEMORY.md")
+	assert.True(t, strings.HasPrefix(curated, "# Long-term Memory\n"))
+	assert.Contains(t, curated, "- Go files use tabs")
+	assert.Contains(t, curated, "### 2026-10-16")
+	assert.NotContains(t, curated, "```")
+}
+
+func TestCurateFallsBackWhenCondenserFails(t *testing.T) {
+	workspace := writeMemoryFixture(t, curateFixture)
+	condense := func(ctx context.Context, content string, maxTokens int) (string, error) {
+		return "", errors.New("provider unavailable")
+	}
+
+	report, err := Curate(context.Background(), workspace, CurateOptions{Condense: condense, Now: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	assert.False(t, report.UsedLLM)
+	assert.Equal(t, "provider unavailable", report.LLMError)
+	assert.Equal(t, 1, report.MergedLessons)
+	assert.Contains(t, readMemoryFile(t, workspace, "MEMORY.md"), "- **Occurrences**: 2")
+}
+
+func TestCurateSkipsCondenserWhenNothingToMerge(t *testing.T) {
+	workspace := writeMemoryFixture(t, "# Long-term Memory\n\n## Project\n\n- Uses Go 1.24\n")
+	called := false
+	condense := func(ctx context.Context, content string, maxTokens int) (string, error) {
+		called = true
+		return content, nil
+	}
+
+	report, err := Curate(context.Background(), workspace, CurateOptions{Condense: condense})
+	require.NoError(t, err)
+	assert.False(t, called)
+	assert.False(t, report.Changed)
+}
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTokens(""))
+	assert.Equal(t, 3, EstimateTokens("hello world"))
+	assert.Equal(t, 4, EstimateTokens("记忆整理"))
+}
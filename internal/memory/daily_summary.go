@@ -22,6 +22,8 @@ const (
 type DailySummaryService struct {
 	workspace string
 	interval  time.Duration
+	// curation 非空时在写入每日摘要或 MEMORY.md 超出预算后自动整理
+	curation *CurateOptions
 }
 
 type summaryData struct {
@@ -41,8 +43,13 @@ func NewDailySummaryService(workspace string, interval time.Duration) *DailySumm
 	}
 }
 
+// EnableCuration 开启自动整理，opts 的 Now 与 DryRun 会被忽略
+func (s *DailySummaryService) EnableCuration(opts CurateOptions) {
+	s.curation = &opts
+}
+
 func (s *DailySummaryService) Start(ctx context.Context) {
-	s.run(time.Now())
+	s.run(ctx, time.Now())
 
 	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
@@ -52,7 +59,7 @@ func (s *DailySummaryService) Start(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case now := <-ticker.C:
-			s.run(now)
+			s.run(ctx, now)
 		}
 	}
 }
@@ -61,19 +68,73 @@ func (s *DailySummaryService) RunOnce(now time.Time) (bool, error) {
 	return SummarizePreviousDay(s.workspace, now)
 }
 
-func (s *DailySummaryService) run(now time.Time) {
+func (s *DailySummaryService) run(ctx context.Context, now time.Time) {
+	// 重新渲染结构化条目，使过期条目及时从 MEMORY.md 中移除
+	if err := NewEntryStore(s.workspace).Sync(); err != nil {
+		if lg := logging.Get(); lg != nil && lg.Cron != nil {
+			lg.Cron.Error("memory entries sync error", "err", err)
+		}
+	}
+
 	updated, err := s.RunOnce(now)
 	if err != nil {
 		if lg := logging.Get(); lg != nil && lg.Cron != nil {
-			lg.Cron.Printf("daily memory summary error: %v", err)
+			lg.Cron.Error("daily memory summary error", "err", err)
 		}
 		return
 	}
 	if updated {
 		if lg := logging.Get(); lg != nil && lg.Cron != nil {
-			lg.Cron.Printf("daily memory summary updated for %s", now.AddDate(0, 0, -1).Format("2006-01-02"))
+			lg.Cron.Info("daily memory summary updated", "date", now.AddDate(0, 0, -1).Format("2006-01-02"))
 		}
 	}
+	if s.curation != nil && (updated || s.overBudget()) {
+		s.curate(ctx, now)
+	}
+}
+
+// overBudget 判断 MEMORY.md 是否超出整理预算（反馈教训与 agent 自身也会随时追加内容）
+func (s *DailySummaryService) overBudget() bool {
+	content, err := os.ReadFile(filepath.Join(s.workspace, "memory", "MEMORY.md"))
+	if err != nil {
+		return false
+	}
+	limit := s.curation.MaxTokens
+	if limit <= 0 {
+		limit = DefaultMaxTokens
+	}
+	return EstimateTokens(string(content)) > limit
+}
+
+func (s *DailySummaryService) curate(ctx context.Context, now time.Time) {
+	opts := *s.curation
+	opts.Now, opts.DryRun = now, false
+	report, err := Curate(ctx, s.workspace, opts)
+	lg := logging.Get()
+	if lg == nil || lg.Cron == nil {
+		return
+	}
+	if err != nil {
+		lg.Cron.Error("memory curation error", "err", err)
+		return
+	}
+	if report.LLMError != "" {
+		lg.Cron.Warn("memory curation LLM pass failed, used rules only", "err", report.LLMError)
+	}
+	if report.Changed {
+		lg.Cron.Info("memory curated",
+			"tokens_before", report.BeforeTokens,
+			"tokens_after", report.AfterTokens,
+			"archived_days", len(report.ArchivedDays),
+			"merged_lessons", report.MergedLessons,
+			"removed_duplicates", report.RemovedDuplicates,
+			"used_llm", report.UsedLLM,
+			"backup", report.BackupPath,
+		)
+	}
+	if report.OverBudget {
+		lg.Cron.Warn("memory still over budget after curation", "tokens", report.AfterTokens, "max_tokens", report.MaxTokens)
+	}
 }
 
 // SummarizePreviousDay appends yesterday's summary to memory/MEMORY.md if not summarized yet.
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -147,3 +148,24 @@ func writeSessionFileAtPath(t *testing.T, path string, sess session.Session) {
 	require.NoError(t, err)
 	require.NoError(t, os.WriteFile(path, data, 0644))
 }
+
+func TestDailySummaryServiceCuratesOverBudgetMemory(t *testing.T) {
+	workspace := writeMemoryFixture(t, curateFixture)
+	now := time.Date(2026, 10, 18, 0, 5, 0, 0, time.UTC)
+
+	// 未开启整理时不改动 MEMORY.md
+	plain := NewDailySummaryService(workspace, time.Hour)
+	plain.run(context.Background(), now)
+	assert.Equal(t, curateFixture, readMemoryFile(t, workspace, "MEMORY.md"))
+
+	// 没有新摘要但超出预算时也会整理
+	service := NewDailySummaryService(workspace, time.Hour)
+	service.EnableCuration(CurateOptions{MaxTokens: 150})
+	service.run(context.Background(), now)
+
+	curated := readMemoryFile(t, workspace, "MEMORY.md")
+	assert.NotContains(t, curated, "### 2026-09-20")
+	assert.Contains(t, curated, "- **Occurrences**: 2")
+	assert.LessOrEqual(t, EstimateTokens(curated), 150)
+	assert.Contains(t, readMemoryFile(t, workspace, "HISTORY.md"), "### [2026-09-20] daily summary")
+}
@@ -0,0 +1,546 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EntryType 结构化记忆条目类型
+type EntryType string
+
+const (
+	EntryFact       EntryType = "fact"
+	EntryPreference EntryType = "preference"
+	EntryLesson     EntryType = "lesson"
+	EntryTodo       EntryType = "todo"
+)
+
+// EntryTypes 按 MEMORY.md 中的渲染顺序列出全部条目类型
+var EntryTypes = []EntryType{EntryFact, EntryPreference, EntryLesson, EntryTodo}
+
+// 条目来源
+const (
+	SourceAgent = "agent"
+	SourceCLI   = "cli"
+	SourceAPI   = "api"
+	SourceFile  = "file"
+)
+
+const entriesFileName = "entries.json"
+
+// ErrEntryNotFound 条目不存在
+var ErrEntryNotFound = errors.New("memory entry not found")
+
+// EntrySource 条目出处：由谁写入，以及对应的会话与消息
+type EntrySource struct {
+	Origin  string `json:"origin"`
+	Channel string `json:"channel,omitempty"`
+	ChatID  string `json:"chatId,omitempty"`
+	Session string `json:"session,omitempty"`
+	Sender  string `json:"sender,omitempty"`
+	// Message 触发写入的用户消息摘录
+	Message string `json:"message,omitempty"`
+}
+
+// Entry 一条结构化记忆
+type Entry struct {
+	ID      string    `json:"id"`
+	Type    EntryType `json:"type"`
+	Content string    `json:"content"`
+	Tags    []string  `json:"tags,omitempty"`
+	// Done 仅对 todo 有意义
+	Done bool `json:"done,omitempty"`
+	// Confidence 0-1，写入时为 0 按 1 处理
+	Confidence float64     `json:"confidence"`
+	Source     EntrySource `json:"source"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+	ExpiresAt  *time.Time  `json:"expiresAt,omitempty"`
+}
+
+// Expired 判断条目是否已过期；过期条目保留在 entries.json 但不再渲染到 MEMORY.md
+func (e Entry) Expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !e.ExpiresAt.After(now)
+}
+
+// ExpiryPtr 把 ParseExpiry 的结果转为 Entry.ExpiresAt，零值表示不过期
+func ExpiryPtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// EntryPatch 条目的部分更新，nil 字段保持不变
+type EntryPatch struct {
+	Type       *EntryType
+	Content    *string
+	Tags       *[]string
+	Done       *bool
+	Confidence *float64
+	// ExpiresAt 为零值时取消过期时间
+	ExpiresAt *time.Time
+}
+
+// EntryFilter 列表过滤条件
+type EntryFilter struct {
+	Type           EntryType
+	Tag            string
+	Query          string
+	IncludeExpired bool
+}
+
+// EntryStore 结构化记忆存储：条目保存在作用域目录的 entries.json，并渲染到同目录 MEMORY.md 的受管区块。
+// 每次读写前先同步 MEMORY.md 中对该区块的手工修改
+type EntryStore struct {
+	scope  Scope
+	path   string
+	memory *Store
+	now    func() time.Time
+}
+
+// entryLocks 同一进程内按文件路径串行化读写
+var entryLocks sync.Map
+
+// NewEntryStore 创建 workspace 全局作用域的结构化记忆存储
+func NewEntryStore(workspace string) *EntryStore {
+	return NewScopedEntryStore(workspace, GlobalScope())
+}
+
+// NewScopedEntryStore 创建指定作用域的结构化记忆存储
+func NewScopedEntryStore(workspace string, scope Scope) *EntryStore {
+	store := NewScopedStore(workspace, scope)
+	return &EntryStore{
+		scope:  scope,
+		path:   filepath.Join(store.memoryDir, entriesFileName),
+		memory: store,
+		now:    time.Now,
+	}
+}
+
+// Scope 存储所属的作用域
+func (s *EntryStore) Scope() Scope {
+	return s.scope
+}
+
+// ParseEntryType 解析条目类型，接受单复数形式与 pref 缩写
+func ParseEntryType(s string) (EntryType, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "pref" || s == "prefs" {
+		return EntryPreference, nil
+	}
+	for _, t := range EntryTypes {
+		if s == string(t) || s == string(t)+"s" {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("invalid memory entry type %q (want fact, preference, lesson or todo)", s)
+}
+
+// ParseExpiry 解析过期时间：Nd / Nw / Go duration（如 36h）/ YYYY-MM-DD / RFC3339；
+// never 返回零值，表示不过期
+func ParseExpiry(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("expiry is empty")
+	}
+	if strings.EqualFold(s, "never") {
+		return time.Time{}, nil
+	}
+	for suffix, days := range map[string]int{"d": 1, "w": 7} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); err == nil && strings.HasSuffix(s, suffix) && n > 0 {
+			return now.AddDate(0, 0, n*days), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry %q (use 7d, 2w, 36h, YYYY-MM-DD or RFC3339)", s)
+}
+
+// List 返回符合条件的条目，按类型与创建时间排序
+func (s *EntryStore) List(filter EntryFilter) ([]Entry, error) {
+	var out []Entry
+	err := s.update(func(entries []Entry, now time.Time) ([]Entry, error) {
+		tag := normalizeTag(filter.Tag)
+		query := strings.ToLower(strings.TrimSpace(filter.Query))
+		for _, e := range entries {
+			if filter.Type != "" && e.Type != filter.Type {
+				continue
+			}
+			if !filter.IncludeExpired && e.Expired(now) {
+				continue
+			}
+			if tag != "" && !containsTag(e.Tags, tag) {
+				continue
+			}
+			if query != "" && !strings.Contains(strings.ToLower(e.Content), query) {
+				continue
+			}
+			out = append(out, e)
+		}
+		return entries, nil
+	})
+	sortEntries(out)
+	return out, err
+}
+
+// Get 按 ID（或唯一前缀）读取条目
+func (s *EntryStore) Get(id string) (Entry, error) {
+	var out Entry
+	err := s.update(func(entries []Entry, now time.Time) ([]Entry, error) {
+		idx, err := findEntry(entries, id)
+		if err != nil {
+			return nil, err
+		}
+		out = entries[idx]
+		return entries, nil
+	})
+	return out, err
+}
+
+// Add 新增条目；同类型且内容相同的条目已存在时合并标签并刷新更新时间，返回值 created 为 false
+func (s *EntryStore) Add(entry Entry) (saved Entry, created bool, err error) {
+	err = s.update(func(entries []Entry, now time.Time) ([]Entry, error) {
+		if err := normalizeEntry(&entry); err != nil {
+			return nil, err
+		}
+		key := normalizeMemoryText(entry.Content)
+		for i := range entries {
+			existing := &entries[i]
+			if existing.Type != entry.Type || normalizeMemoryText(existing.Content) != key {
+				continue
+			}
+			existing.Tags = normalizeTags(append(existing.Tags, entry.Tags...))
+			if entry.Confidence > existing.Confidence {
+				existing.Confidence = entry.Confidence
+			}
+			if entry.ExpiresAt != nil {
+				existing.ExpiresAt = entry.ExpiresAt
+			}
+			existing.UpdatedAt = now
+			saved = *existing
+			return entries, nil
+		}
+
+		entry.ID = newEntryID(entries)
+		entry.CreatedAt, entry.UpdatedAt = now, now
+		saved, created = entry, true
+		return append(entries, entry), nil
+	})
+	return saved, created, err
+}
+
+// Update 按 ID 更新条目
+func (s *EntryStore) Update(id string, patch EntryPatch) (Entry, error) {
+	var out Entry
+	err := s.update(func(entries []Entry, now time.Time) ([]Entry, error) {
+		idx, err := findEntry(entries, id)
+		if err != nil {
+			return nil, err
+		}
+		entry := entries[idx]
+		if patch.Type != nil {
+			entry.Type = *patch.Type
+		}
+		if patch.Content != nil {
+			entry.Content = *patch.Content
+		}
+		if patch.Tags != nil {
+			entry.Tags = *patch.Tags
+		}
+		if patch.Done != nil {
+			entry.Done = *patch.Done
+		}
+		if patch.Confidence != nil {
+			entry.Confidence = *patch.Confidence
+		}
+		if patch.ExpiresAt != nil {
+			if patch.ExpiresAt.IsZero() {
+				entry.ExpiresAt = nil
+			} else {
+				expires := *patch.ExpiresAt
+				entry.ExpiresAt = &expires
+			}
+		}
+		if err := normalizeEntry(&entry); err != nil {
+			return nil, err
+		}
+		entry.UpdatedAt = now
+		entries[idx] = entry
+		out = entry
+		return entries, nil
+	})
+	return out, err
+}
+
+// Remove 按 ID 删除条目
+func (s *EntryStore) Remove(id string) (Entry, error) {
+	var out Entry
+	err := s.update(func(entries []Entry, now time.Time) ([]Entry, error) {
+		idx, err := findEntry(entries, id)
+		if err != nil {
+			return nil, err
+		}
+		out = entries[idx]
+		return append(entries[:idx], entries[idx+1:]...), nil
+	})
+	return out, err
+}
+
+// Sync 同步 MEMORY.md 中的手工修改并重新渲染受管区块（例如移除已过期条目）
+func (s *EntryStore) Sync() error {
+	return s.update(func(entries []Entry, now time.Time) ([]Entry, error) {
+		return entries, nil
+	})
+}
+
+// update 在锁内完成：读取条目 → 同步 MEMORY.md 的手工修改 → 执行 fn → 保存并重新渲染
+func (s *EntryStore) update(fn func(entries []Entry, now time.Time) ([]Entry, error)) error {
+	lock, _ := entryLocks.LoadOrStore(s.path, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	now := s.now()
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	content, err := s.memory.peekLongTerm()
+	if err != nil {
+		return err
+	}
+	entries, synced := syncEntriesFromMarkdown(entries, content, now)
+	base := append([]Entry(nil), entries...)
+
+	before, _ := json.Marshal(entries)
+	entries, err = fn(entries, now)
+	if err != nil {
+		// 操作失败时手工修改仍需落盘，否则下次渲染会覆盖它们
+		if synced {
+			if perr := s.persist(base, content, now, true); perr != nil {
+				return errors.Join(err, perr)
+			}
+		}
+		return err
+	}
+	after, _ := json.Marshal(entries)
+	return s.persist(entries, content, now, synced || string(before) != string(after))
+}
+
+// persist 条目有变化时写入 entries.json；受管区块与渲染结果不一致时重写 MEMORY.md
+func (s *EntryStore) persist(entries []Entry, content string, now time.Time, changed bool) error {
+	if changed {
+		if err := s.save(entries); err != nil {
+			return err
+		}
+	}
+	updated := replaceEntriesBlock(content, renderEntriesBlock(entries, now))
+	if updated == content {
+		return nil
+	}
+	return s.memory.WriteLongTerm(updated)
+}
+
+func (s *EntryStore) load() ([]Entry, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read memory entries: %w", err)
+	}
+	var file struct {
+		Entries []Entry `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	return file.Entries, nil
+}
+
+func (s *EntryStore) save(entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{"version": 1, "entries": entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("create memory dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".entries-*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// FormatEntry 单行展示条目：id、类型、内容、标签、置信度、过期时间与来源
+func FormatEntry(e Entry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %-10s  ", e.ID, e.Type)
+	if e.Type == EntryTodo {
+		if e.Done {
+			b.WriteString("[x] ")
+		} else {
+			b.WriteString("[ ] ")
+		}
+	}
+	b.WriteString(strings.ReplaceAll(e.Content, "\n", " / "))
+	for _, tag := range e.Tags {
+		b.WriteString(" #" + tag)
+	}
+	var meta []string
+	if e.Confidence > 0 && e.Confidence < 1 {
+		meta = append(meta, fmt.Sprintf("confidence %.2g", e.Confidence))
+	}
+	if e.ExpiresAt != nil {
+		meta = append(meta, "expires "+e.ExpiresAt.Format("2006-01-02"))
+	}
+	source := e.Source.Origin
+	if e.Source.Session != "" {
+		source += " " + e.Source.Session
+	}
+	meta = append(meta, "from "+source)
+	b.WriteString("  (" + strings.Join(meta, ", ") + ")")
+	return b.String()
+}
+
+func normalizeEntry(e *Entry) error {
+	if _, err := ParseEntryType(string(e.Type)); err != nil {
+		return err
+	}
+	e.Content = normalizeEntryContent(e.Content)
+	if e.Content == "" {
+		return fmt.Errorf("memory entry content is empty")
+	}
+	if e.Confidence < 0 || e.Confidence > 1 {
+		return fmt.Errorf("confidence must be between 0 and 1")
+	}
+	if e.Confidence == 0 {
+		e.Confidence = 1
+	}
+	if e.Type != EntryTodo {
+		e.Done = false
+	}
+	e.Tags = normalizeTags(e.Tags)
+	if e.Source.Origin == "" {
+		e.Source.Origin = SourceAgent
+	}
+	return nil
+}
+
+// normalizeEntryContent 去掉首尾空白与每行行尾空白，折叠空行，保证渲染后可逐行解析回来
+func normalizeEntryContent(s string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, tag := range tags {
+		if tag = normalizeTag(tag); tag != "" && !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// findEntry 按完整 ID 或唯一前缀查找
+func findEntry(entries []Entry, id string) (int, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id == "" {
+		return -1, fmt.Errorf("memory entry id is required")
+	}
+	match := -1
+	for i, e := range entries {
+		if e.ID == id {
+			return i, nil
+		}
+		if strings.HasPrefix(e.ID, id) {
+			if match >= 0 {
+				return -1, fmt.Errorf("memory entry id %q is ambiguous", id)
+			}
+			match = i
+		}
+	}
+	if match < 0 {
+		return -1, fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	}
+	return match, nil
+}
+
+func newEntryID(entries []Entry) string {
+	for {
+		id := uuid.New().String()[:8]
+		if _, err := findEntry(entries, id); errors.Is(err, ErrEntryNotFound) {
+			return id
+		}
+	}
+}
+
+func sortEntries(entries []Entry) {
+	order := make(map[EntryType]int, len(EntryTypes))
+	for i, t := range EntryTypes {
+		order[t] = i
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Type != entries[j].Type {
+			return order[entries[i].Type] < order[entries[j].Type]
+		}
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+}
@@ -0,0 +1,249 @@
+package memory
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MEMORY.md 中结构化条目的受管区块，区块内可以直接编辑、增删条目
+const (
+	entriesBeginMarker = "<!-- maxclaw:entries:begin (edit freely; ids link lines to memory/entries.json) -->"
+	entriesEndMarker   = "<!-- maxclaw:entries:end -->"
+	entriesBeginPrefix = "<!-- maxclaw:entries:begin"
+)
+
+var entryHeadings = map[EntryType]string{
+	EntryFact:       "## Facts",
+	EntryPreference: "## Preferences",
+	EntryLesson:     "## Lessons",
+	EntryTodo:       "## Todos",
+}
+
+var (
+	entryIDSuffixRe = regexp.MustCompile(`\s*<!--\s*id:([0-9a-zA-Z-]+)\s*-->\s*$`)
+	entryTodoRe     = regexp.MustCompile(`^\[([ xX])\]\s*`)
+)
+
+// parsedEntry 从受管区块解析出的一条记录
+type parsedEntry struct {
+	id      string
+	typ     EntryType
+	content string
+	done    bool
+}
+
+// renderEntriesBlock 按类型分组渲染未过期条目；没有条目时返回空串
+func renderEntriesBlock(entries []Entry, now time.Time) string {
+	visible := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if !e.Expired(now) {
+			visible = append(visible, e)
+		}
+	}
+	if len(visible) == 0 {
+		return ""
+	}
+	sortEntries(visible)
+
+	var b strings.Builder
+	b.WriteString(entriesBeginMarker + "\n")
+	current := EntryType("")
+	for _, e := range visible {
+		if e.Type != current {
+			current = e.Type
+			b.WriteString("\n" + entryHeadings[e.Type] + "\n\n")
+		}
+		lines := strings.Split(e.Content, "\n")
+		b.WriteString("- ")
+		if e.Type == EntryTodo {
+			if e.Done {
+				b.WriteString("[x] ")
+			} else {
+				b.WriteString("[ ] ")
+			}
+		}
+		b.WriteString(lines[0] + " <!-- id:" + e.ID + " -->\n")
+		for _, line := range lines[1:] {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	b.WriteString("\n" + entriesEndMarker)
+	return b.String()
+}
+
+// findEntriesBlock 返回受管区块的起止行号（含标记行）；代码块内的标记不算
+func findEntriesBlock(lines []string) (start, end int, ok bool) {
+	start = -1
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if start < 0 && strings.HasPrefix(trimmed, entriesBeginPrefix) {
+			start = i
+		} else if start >= 0 && trimmed == entriesEndMarker {
+			return start, i, true
+		}
+	}
+	if start >= 0 {
+		// 结束标记被删掉时区块延续到文件末尾
+		return start, len(lines) - 1, true
+	}
+	return -1, -1, false
+}
+
+// extractEntriesBlock 取出受管区块正文（含标记行）
+func extractEntriesBlock(content string) (string, bool) {
+	lines := strings.Split(content, "\n")
+	start, end, ok := findEntriesBlock(lines)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimRight(strings.Join(lines[start:end+1], "\n"), "\n"), true
+}
+
+// replaceEntriesBlock 用新区块替换 MEMORY.md 中的旧区块；原来没有区块时插入到第一个 ## 章节之前，
+// block 为空时移除区块
+func replaceEntriesBlock(content, block string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	start, end, ok := findEntriesBlock(lines)
+	if !ok {
+		if block == "" {
+			return content
+		}
+		start = len(lines)
+		inFence := false
+		for i, line := range lines {
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				inFence = !inFence
+			}
+			if !inFence && strings.HasPrefix(line, "## ") {
+				start = i
+				break
+			}
+		}
+		end = start - 1
+	}
+
+	head := trimBlankLines(append([]string(nil), lines[:start]...))
+	tail := trimBlankLines(append([]string(nil), lines[end+1:]...))
+	var parts []string
+	if len(head) > 0 {
+		parts = append(parts, strings.Join(head, "\n"))
+	}
+	if block != "" {
+		parts = append(parts, block)
+	}
+	if len(tail) > 0 {
+		parts = append(parts, strings.Join(tail, "\n"))
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+// parseEntriesBlock 解析受管区块：## 标题决定类型，"- " 开头为一条记录，缩进行是上一条的续行
+func parseEntriesBlock(block string) []parsedEntry {
+	var out []parsedEntry
+	typ := EntryFact
+	var current *parsedEntry
+	for _, line := range strings.Split(block, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, entriesBeginPrefix), trimmed == entriesEndMarker:
+			current = nil
+		case strings.HasPrefix(line, "## "):
+			current = nil
+			typ = EntryFact
+			if t, err := ParseEntryType(strings.TrimPrefix(line, "## ")); err == nil {
+				typ = t
+			}
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* "):
+			text := strings.TrimSpace(trimmed[2:])
+			entry := parsedEntry{typ: typ}
+			if m := entryIDSuffixRe.FindStringSubmatch(text); m != nil {
+				entry.id = strings.ToLower(m[1])
+				text = strings.TrimSpace(text[:len(text)-len(m[0])])
+			}
+			if m := entryTodoRe.FindStringSubmatch(text); m != nil {
+				entry.done = m[1] != " "
+				text = text[len(m[0]):]
+				if typ == EntryFact {
+					// 未放在 ## Todos 下的复选框条目仍视为待办
+					entry.typ = EntryTodo
+				}
+			}
+			entry.content = text
+			out = append(out, entry)
+			current = &out[len(out)-1]
+		case trimmed == "":
+			current = nil
+		case current != nil && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")):
+			current.content += "\n" + trimmed
+		}
+	}
+
+	kept := out[:0]
+	for _, e := range out {
+		if e.content = normalizeEntryContent(e.content); e.content != "" {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// syncEntriesFromMarkdown 把受管区块中的手工修改合并回条目：改动的行更新对应条目，
+// 没有 id 的新行成为新条目，从区块中删除的未过期条目被移除。返回是否有改动
+func syncEntriesFromMarkdown(entries []Entry, content string, now time.Time) ([]Entry, bool) {
+	block, ok := extractEntriesBlock(content)
+	if !ok || block == renderEntriesBlock(entries, now) {
+		return entries, false
+	}
+
+	byID := make(map[string]int, len(entries))
+	for i, e := range entries {
+		byID[e.ID] = i
+	}
+	seen := make(map[string]bool)
+	changed := false
+	var added []Entry
+	for _, p := range parseEntriesBlock(block) {
+		if idx, ok := byID[p.id]; ok && !seen[p.id] {
+			seen[p.id] = true
+			e := &entries[idx]
+			done := p.done && p.typ == EntryTodo
+			if e.Type != p.typ || e.Content != p.content || e.Done != done {
+				e.Type, e.Content, e.Done = p.typ, p.content, done
+				e.UpdatedAt = now
+				changed = true
+			}
+			continue
+		}
+		entry := Entry{
+			Type:       p.typ,
+			Content:    p.content,
+			Done:       p.done && p.typ == EntryTodo,
+			Confidence: 1,
+			Source:     EntrySource{Origin: SourceFile},
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		entry.ID = newEntryID(append(entries, added...))
+		added = append(added, entry)
+		changed = true
+	}
+
+	kept := make([]Entry, 0, len(entries)+len(added))
+	for _, e := range entries {
+		if !seen[e.ID] && !e.Expired(now) {
+			changed = true
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return append(kept, added...), changed
+}
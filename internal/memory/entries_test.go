@@ -0,0 +1,224 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEntryStore(t *testing.T, memoryContent string) (*EntryStore, string, *time.Time) {
+	t.Helper()
+	workspace := writeMemoryFixture(t, memoryContent)
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	store := NewEntryStore(workspace)
+	store.now = func() time.Time { return now }
+	return store, workspace, &now
+}
+
+func TestEntryStoreRendersEntriesIntoMemory(t *testing.T) {
+	store, workspace, now := newTestEntryStore(t, "# Long-term Memory\n\nIntro.\n\n## Project\n\n- Uses Go 1.24\n")
+
+	deploy, created, err := store.Add(Entry{
+		Type:    EntryFact,
+		Content: "Deploys happen on Fridays",
+		Tags:    []string{"#Ops", "deploy"},
+		Source:  EntrySource{Origin: SourceAgent, Session: "telegram:42", Message: "we deploy on fridays"},
+	})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Len(t, deploy.ID, 8)
+	assert.Equal(t, []string{"deploy", "ops"}, deploy.Tags)
+	assert.Equal(t, 1.0, deploy.Confidence)
+	assert.Equal(t, "telegram:42", deploy.Source.Session)
+
+	*now = now.Add(time.Minute)
+	todo, _, err := store.Add(Entry{Type: EntryTodo, Content: "Renew the TLS certificate", Source: EntrySource{Origin: SourceCLI}})
+	require.NoError(t, err)
+	pref, _, err := store.Add(Entry{Type: EntryPreference, Content: "Prefers concise answers\nwith code first", Confidence: 0.7})
+	require.NoError(t, err)
+
+	// 同类型同内容的条目合并而不是重复添加
+	again, created, err := store.Add(Entry{Type: EntryFact, Content: "deploys happen on fridays.", Tags: []string{"release"}})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, deploy.ID, again.ID)
+	assert.Equal(t, []string{"deploy", "ops", "release"}, again.Tags)
+
+	assert.Equal(t, "# Long-term Memory\n\nIntro.\n\n"+entriesBeginMarker+`
+
+## Facts
+
+- Deploys happen on Fridays <!-- id:`+deploy.ID+` -->
+
+## Preferences
+
+- Prefers concise answers <!-- id:`+pref.ID+` -->
+  with code first
+
+## Todos
+
+- [ ] Renew the TLS certificate <!-- id:`+todo.ID+` -->
+
+`+entriesEndMarker+"\n\n## Project\n\n- Uses Go 1.24\n", readMemoryFile(t, workspace, "MEMORY.md"))
+
+	done := true
+	updated, err := store.Update(todo.ID[:5], EntryPatch{Done: &done})
+	require.NoError(t, err)
+	assert.True(t, updated.Done)
+	assert.Contains(t, readMemoryFile(t, workspace, "MEMORY.md"), "- [x] Renew the TLS certificate")
+
+	removed, err := store.Remove(pref.ID)
+	require.NoError(t, err)
+	assert.Equal(t, pref.Content, removed.Content)
+	assert.NotContains(t, readMemoryFile(t, workspace, "MEMORY.md"), "Prefers concise answers")
+
+	_, err = store.Remove(pref.ID)
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+
+	list, err := store.List(EntryFilter{Tag: "ops"})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, deploy.ID, list[0].ID)
+
+	// 删掉所有条目后区块也随之移除
+	_, err = store.Remove(deploy.ID)
+	require.NoError(t, err)
+	_, err = store.Remove(todo.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "# Long-term Memory\n\nIntro.\n\n## Project\n\n- Uses Go 1.24\n", readMemoryFile(t, workspace, "MEMORY.md"))
+}
+
+func TestEntryStoreSyncsHandEditsFromMemory(t *testing.T) {
+	store, workspace, now := newTestEntryStore(t, "# Long-term Memory\n")
+	keep, _, err := store.Add(Entry{Type: EntryFact, Content: "The API lives in cmd/server", Source: EntrySource{Origin: SourceAgent, Session: "cli:direct"}})
+	require.NoError(t, err)
+	drop, _, err := store.Add(Entry{Type: EntryFact, Content: "Staging is on port 8080"})
+	require.NoError(t, err)
+	todo, _, err := store.Add(Entry{Type: EntryTodo, Content: "Write the release notes"})
+	require.NoError(t, err)
+
+	path := filepath.Join(workspace, "memory", "MEMORY.md")
+	content := readMemoryFile(t, workspace, "MEMORY.md")
+	content = strings.Replace(content, "The API lives in cmd/server", "The API lives in cmd/api", 1)
+	content = strings.Replace(content, "- Staging is on port 8080 <!-- id:"+drop.ID+" -->\n", "", 1)
+	content = strings.Replace(content, "- [ ] Write the release notes", "- [x] Write the release notes", 1)
+	content = strings.Replace(content, "## Todos", "## Preferences\n\n- Answers in Chinese\n\n## Todos", 1)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	*now = now.Add(time.Hour)
+	entries, err := store.List(EntryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, keep.ID, entries[0].ID)
+	assert.Equal(t, "The API lives in cmd/api", entries[0].Content)
+	assert.Equal(t, "cli:direct", entries[0].Source.Session, "provenance survives hand edits")
+	assert.Equal(t, *now, entries[0].UpdatedAt)
+
+	assert.Equal(t, EntryPreference, entries[1].Type)
+	assert.Equal(t, "Answers in Chinese", entries[1].Content)
+	assert.Equal(t, SourceFile, entries[1].Source.Origin)
+
+	assert.Equal(t, todo.ID, entries[2].ID)
+	assert.True(t, entries[2].Done)
+
+	// 新行被分配 id 并写回 MEMORY.md
+	assert.Contains(t, readMemoryFile(t, workspace, "MEMORY.md"), "- Answers in Chinese <!-- id:"+entries[1].ID+" -->")
+
+	var file struct {
+		Entries []Entry `json:"entries"`
+	}
+	data, err := os.ReadFile(filepath.Join(workspace, "memory", entriesFileName))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &file))
+	assert.Len(t, file.Entries, 3)
+}
+
+func TestEntryStoreHidesExpiredEntries(t *testing.T) {
+	store, workspace, now := newTestEntryStore(t, "# Long-term Memory\n")
+	expires, err := ParseExpiry("2d", *now)
+	require.NoError(t, err)
+	short, _, err := store.Add(Entry{Type: EntryFact, Content: "On call this week", ExpiresAt: &expires})
+	require.NoError(t, err)
+	_, _, err = store.Add(Entry{Type: EntryFact, Content: "Team uses Linear"})
+	require.NoError(t, err)
+	assert.Contains(t, readMemoryFile(t, workspace, "MEMORY.md"), "On call this week")
+
+	*now = now.AddDate(0, 0, 3)
+	require.NoError(t, store.Sync())
+	assert.NotContains(t, readMemoryFile(t, workspace, "MEMORY.md"), "On call this week")
+
+	active, err := store.List(EntryFilter{})
+	require.NoError(t, err)
+	assert.Len(t, active, 1)
+	all, err := store.List(EntryFilter{IncludeExpired: true})
+	require.NoError(t, err)
+	assert.Len(t, all, 2, "expired entries stay in entries.json")
+
+	// 清除过期时间后重新渲染
+	_, err = store.Update(short.ID, EntryPatch{ExpiresAt: &time.Time{}})
+	require.NoError(t, err)
+	assert.Contains(t, readMemoryFile(t, workspace, "MEMORY.md"), "On call this week")
+}
+
+func TestEntryStoreValidatesEntries(t *testing.T) {
+	store, _, _ := newTestEntryStore(t, "# Long-term Memory\n")
+	_, _, err := store.Add(Entry{Type: "note", Content: "x"})
+	assert.Error(t, err)
+	_, _, err = store.Add(Entry{Type: EntryFact, Content: "  "})
+	assert.EqualError(t, err, "memory entry content is empty")
+	_, _, err = store.Add(Entry{Type: EntryFact, Content: "x", Confidence: 1.5})
+	assert.EqualError(t, err, "confidence must be between 0 and 1")
+	_, err = store.Update("", EntryPatch{})
+	assert.EqualError(t, err, "memory entry id is required")
+}
+
+func TestCurateKeepsEntriesBlock(t *testing.T) {
+	store, workspace, _ := newTestEntryStore(t, curateFixture)
+	_, _, err := store.Add(Entry{Type: EntryPreference, Content: "User prefers concise answers."})
+	require.NoError(t, err)
+	block, ok := extractEntriesBlock(readMemoryFile(t, workspace, "MEMORY.md"))
+	require.True(t, ok)
+
+	_, err = Curate(context.Background(), workspace, CurateOptions{Now: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	curated := readMemoryFile(t, workspace, "MEMORY.md")
+	assert.Contains(t, curated, "sessions.\n\n"+block+"\n\n## Preferences\n")
+	assert.Contains(t, curated, "- Timezone is Asia/Shanghai")
+
+	require.NoError(t, store.Sync())
+	assert.Equal(t, curated, readMemoryFile(t, workspace, "MEMORY.md"))
+}
+
+func TestParseExpiryAndEntryType(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	for input, want := range map[string]time.Time{
+		"7d":                   now.AddDate(0, 0, 7),
+		"2w":                   now.AddDate(0, 0, 14),
+		"36h":                  now.Add(36 * time.Hour),
+		"2026-11-01":           time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		"2026-11-01T08:00:00Z": time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC),
+	} {
+		got, err := ParseExpiry(input, now)
+		require.NoError(t, err, input)
+		assert.True(t, want.Equal(got), input)
+	}
+	never, err := ParseExpiry("never", now)
+	require.NoError(t, err)
+	assert.True(t, never.IsZero())
+	_, err = ParseExpiry("soon", now)
+	assert.Error(t, err)
+
+	typ, err := ParseEntryType("Preferences")
+	require.NoError(t, err)
+	assert.Equal(t, EntryPreference, typ)
+	_, err = ParseEntryType("note")
+	assert.Error(t, err)
+}
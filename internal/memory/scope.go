@@ -0,0 +1,359 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ScopeKind 记忆作用域类型
+type ScopeKind string
+
+const (
+	ScopeGlobal ScopeKind = "global"
+	ScopeAgent  ScopeKind = "agent"
+	ScopeUser   ScopeKind = "user"
+	ScopeChat   ScopeKind = "chat"
+)
+
+// ScopeKinds 按从宽到窄的顺序列出全部作用域类型
+var ScopeKinds = []ScopeKind{ScopeGlobal, ScopeAgent, ScopeUser, ScopeChat}
+
+// scopeDirs 非全局作用域在 memory/ 下的目录
+var scopeDirs = map[ScopeKind]string{
+	ScopeAgent: "agents",
+	ScopeUser:  "users",
+	ScopeChat:  "chats",
+}
+
+// systemSenders 不代表真实用户的发送者，没有用户作用域
+var systemSenders = map[string]bool{
+	"":          true,
+	"cron":      true,
+	"heartbeat": true,
+	"subagent":  true,
+	"system":    true,
+	"trigger":   true,
+}
+
+// Scope 记忆作用域。全局作用域对应 memory/MEMORY.md，
+// 其余作用域各自拥有 memory/<agents|users|chats>/<key>/ 下的 MEMORY.md 与 entries.json
+type Scope struct {
+	Kind ScopeKind
+	Key  string
+}
+
+// GlobalScope 所有对话共享的作用域
+func GlobalScope() Scope { return Scope{Kind: ScopeGlobal} }
+
+// AgentScope 某个 agent profile 的作用域
+func AgentScope(name string) Scope {
+	return Scope{Kind: ScopeAgent, Key: strings.TrimSpace(name)}
+}
+
+// UserScope 某个用户身份的作用域，身份见 Identities.Resolve
+func UserScope(identity string) Scope {
+	return Scope{Kind: ScopeUser, Key: strings.TrimSpace(identity)}
+}
+
+// ChatScope 某个频道会话的作用域
+func ChatScope(channel, chatID string) Scope {
+	return Scope{Kind: ScopeChat, Key: strings.TrimSpace(channel) + ":" + strings.TrimSpace(chatID)}
+}
+
+// ParseScope 解析 global、agent:<name>、user:<identity>、chat:<channel>:<chatID>
+func ParseScope(raw string) (Scope, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.EqualFold(raw, string(ScopeGlobal)) {
+		return GlobalScope(), nil
+	}
+	kind, key, _ := strings.Cut(raw, ":")
+	scope := Scope{Kind: ScopeKind(strings.ToLower(strings.TrimSpace(kind))), Key: strings.TrimSpace(key)}
+	if err := scope.Validate(); err != nil {
+		return Scope{}, err
+	}
+	return scope, nil
+}
+
+// Validate 检查作用域类型与 key
+func (s Scope) Validate() error {
+	switch s.Kind {
+	case ScopeGlobal:
+		return nil
+	case ScopeAgent, ScopeUser:
+		if s.Key == "" {
+			return fmt.Errorf("memory scope %s needs a name, e.g. %s:<name>", s.Kind, s.Kind)
+		}
+		if s.Key == "." || s.Key == ".." {
+			return fmt.Errorf("invalid memory scope name %q", s.Key)
+		}
+		return nil
+	case ScopeChat:
+		channel, chatID, ok := strings.Cut(s.Key, ":")
+		if !ok || strings.TrimSpace(channel) == "" || strings.TrimSpace(chatID) == "" {
+			return fmt.Errorf("chat memory scope must look like chat:<channel>:<chatID>")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid memory scope %q (want global, agent:<name>, user:<identity> or chat:<channel>:<chatID>)", s.String())
+	}
+}
+
+// IsGlobal 是否为全局作用域
+func (s Scope) IsGlobal() bool {
+	return s.Kind == ScopeGlobal || s.Kind == ""
+}
+
+func (s Scope) String() string {
+	if s.IsGlobal() {
+		return string(ScopeGlobal)
+	}
+	return string(s.Kind) + ":" + s.Key
+}
+
+// MarshalText 以 String 形式序列化，便于 JSON 输出
+func (s Scope) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText 使用 ParseScope 解析
+func (s *Scope) UnmarshalText(data []byte) error {
+	parsed, err := ParseScope(string(data))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// Dir 作用域在 workspace 中的记忆目录；key 经过转义，可安全用作目录名
+func (s Scope) Dir(workspace string) string {
+	base := filepath.Join(workspace, "memory")
+	if s.IsGlobal() {
+		return base
+	}
+	return filepath.Join(base, scopeDirs[s.Kind], escapeScopeKey(s.Key))
+}
+
+// escapeScopeKey 把 key 转成单级目录名；QueryEscape 不转义点号，"." 与 ".." 需单独转义，避免指向上级目录
+func escapeScopeKey(key string) string {
+	escaped := url.QueryEscape(key)
+	if escaped == "." || escaped == ".." {
+		return strings.ReplaceAll(escaped, ".", "%2E")
+	}
+	return escaped
+}
+
+// MemoryPath 作用域的 MEMORY.md 路径
+func (s Scope) MemoryPath(workspace string) string {
+	return filepath.Join(s.Dir(workspace), "MEMORY.md")
+}
+
+// Description 用于 system prompt 与新建文件模板的说明
+func (s Scope) Description() string {
+	switch s.Kind {
+	case ScopeAgent:
+		return fmt.Sprintf("loaded only by agent profile %s", s.Key)
+	case ScopeUser:
+		return fmt.Sprintf("loaded only in conversations with user %s", s.Key)
+	case ScopeChat:
+		return fmt.Sprintf("loaded only in chat %s", s.Key)
+	default:
+		return "loaded in every conversation"
+	}
+}
+
+// ListScopes 列出 workspace 中已有记忆文件的作用域，全局作用域总在首位
+func ListScopes(workspace string) ([]Scope, error) {
+	scopes := []Scope{GlobalScope()}
+	for _, kind := range ScopeKinds[1:] {
+		dir := filepath.Join(workspace, "memory", scopeDirs[kind])
+		items, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", dir, err)
+		}
+		var found []Scope
+		for _, item := range items {
+			if !item.IsDir() {
+				continue
+			}
+			key, err := url.QueryUnescape(item.Name())
+			if err != nil {
+				continue
+			}
+			found = append(found, Scope{Kind: kind, Key: key})
+		}
+		sort.Slice(found, func(i, j int) bool { return found[i].Key < found[j].Key })
+		scopes = append(scopes, found...)
+	}
+	return scopes, nil
+}
+
+// Identities 把不同频道的发送者账号映射到同一用户身份，
+// 如 {"alice": ["telegram:123456", "slack:U024BE7LH"]}
+type Identities map[string][]string
+
+// Resolve 返回 channel/sender 对应的用户身份：已映射时为身份名，否则为 channel:sender；
+// cron、subagent 等系统发送者返回空
+func (ids Identities) Resolve(channel, sender string) string {
+	channel = strings.TrimSpace(channel)
+	sender = strings.TrimSpace(sender)
+	if systemSenders[strings.ToLower(sender)] {
+		return ""
+	}
+	account := channel + ":" + sender
+	for _, name := range sortedIdentityNames(ids) {
+		for _, candidate := range ids[name] {
+			if strings.EqualFold(strings.TrimSpace(candidate), account) {
+				return strings.TrimSpace(name)
+			}
+		}
+	}
+	return account
+}
+
+func sortedIdentityNames(ids Identities) []string {
+	names := make([]string, 0, len(ids))
+	for name := range ids {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ScopeContext 当前对话的信息，决定哪些作用域适用
+type ScopeContext struct {
+	// Agent agent profile 名称；默认 profile 为空
+	Agent   string
+	Channel string
+	ChatID  string
+	// User 解析后的用户身份，见 Identities.Resolve
+	User string
+}
+
+// NewScopeContext 由频道消息构造作用域上下文
+func NewScopeContext(agent, channel, chatID, sender string, ids Identities) ScopeContext {
+	agent = strings.TrimSpace(agent)
+	if strings.EqualFold(agent, "default") {
+		agent = ""
+	}
+	return ScopeContext{
+		Agent:   agent,
+		Channel: strings.TrimSpace(channel),
+		ChatID:  strings.TrimSpace(chatID),
+		User:    ids.Resolve(channel, sender),
+	}
+}
+
+// Scopes 返回适用于当前对话的作用域，按 全局 → agent → 用户 → 会话 从宽到窄排列
+func (c ScopeContext) Scopes() []Scope {
+	scopes := []Scope{GlobalScope()}
+	for _, kind := range ScopeKinds[1:] {
+		if scope, err := c.Resolve(kind); err == nil {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// Resolve 把相对作用域解析为当前对话的具体作用域
+func (c ScopeContext) Resolve(kind ScopeKind) (Scope, error) {
+	switch kind {
+	case ScopeGlobal, "":
+		return GlobalScope(), nil
+	case ScopeAgent:
+		if c.Agent == "" {
+			return Scope{}, fmt.Errorf("agent memory scope needs a named agent profile")
+		}
+		return AgentScope(c.Agent), nil
+	case ScopeUser:
+		if c.User == "" {
+			return Scope{}, fmt.Errorf("user memory scope is not available: the sender is not a user")
+		}
+		return UserScope(c.User), nil
+	case ScopeChat:
+		if c.Channel == "" || c.ChatID == "" {
+			return Scope{}, fmt.Errorf("chat memory scope is not available outside a chat")
+		}
+		return ChatScope(c.Channel, c.ChatID), nil
+	default:
+		return Scope{}, fmt.Errorf("invalid memory scope %q (want global, agent, user or chat)", kind)
+	}
+}
+
+// Allows 判断作用域是否适用于当前对话
+func (c ScopeContext) Allows(scope Scope) bool {
+	for _, s := range c.Scopes() {
+		if s == scope || (s.IsGlobal() && scope.IsGlobal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// PersonalScope 个人信息（偏好、反馈教训、待办）默认写入的作用域：
+// 优先当前用户，其次当前会话，最后全局
+func (c ScopeContext) PersonalScope() Scope {
+	for _, kind := range []ScopeKind{ScopeUser, ScopeChat} {
+		if scope, err := c.Resolve(kind); err == nil {
+			return scope
+		}
+	}
+	return GlobalScope()
+}
+
+// DefaultEntryScope 未指定作用域时条目写入的位置：事实写入全局，其余写入 PersonalScope
+func (c ScopeContext) DefaultEntryScope(t EntryType) Scope {
+	if t == EntryFact {
+		return GlobalScope()
+	}
+	return c.PersonalScope()
+}
+
+// ScopedEntry 带作用域的条目，用于跨作用域列表
+type ScopedEntry struct {
+	Entry
+	Scope Scope `json:"scope"`
+}
+
+// ListScopedEntries 按顺序列出多个作用域中符合条件的条目
+func ListScopedEntries(workspace string, scopes []Scope, filter EntryFilter) ([]ScopedEntry, error) {
+	var out []ScopedEntry
+	for _, scope := range scopes {
+		entries, err := NewScopedEntryStore(workspace, scope).List(filter)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", scope, err)
+		}
+		for _, e := range entries {
+			out = append(out, ScopedEntry{Entry: e, Scope: scope})
+		}
+	}
+	return out, nil
+}
+
+// FindEntryStore 在多个作用域中查找 id（或唯一前缀）所在的存储，先找到的作用域优先
+func FindEntryStore(workspace string, scopes []Scope, id string) (*EntryStore, error) {
+	var notFound error
+	for _, scope := range scopes {
+		store := NewScopedEntryStore(workspace, scope)
+		_, err := store.Get(id)
+		if err == nil {
+			return store, nil
+		}
+		if !errors.Is(err, ErrEntryNotFound) {
+			return nil, err
+		}
+		notFound = err
+	}
+	if notFound == nil {
+		notFound = fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	}
+	return nil, notFound
+}
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScope(t *testing.T) {
+	for raw, want := range map[string]Scope{
+		"":                     GlobalScope(),
+		"Global":               GlobalScope(),
+		"agent:coder":          AgentScope("coder"),
+		"user:alice":           UserScope("alice"),
+		"user:telegram:1001":   UserScope("telegram:1001"),
+		"chat:telegram:-10042": ChatScope("telegram", "-10042"),
+	} {
+		got, err := ParseScope(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, got, raw)
+	}
+	for _, raw := range []string{"user", "user:", "chat:telegram", "team:x", "user:..", "agent:."} {
+		_, err := ParseScope(raw)
+		assert.Error(t, err, raw)
+	}
+	assert.Equal(t, "chat:telegram:-10042", ChatScope("telegram", "-10042").String())
+}
+
+func TestScopeDirsRoundTripThroughListScopes(t *testing.T) {
+	workspace := t.TempDir()
+	scopes := []Scope{ChatScope("slack", "C42/general"), UserScope("telegram:1001"), AgentScope("coder")}
+	for _, scope := range scopes {
+		_, _, err := NewScopedEntryStore(workspace, scope).Add(Entry{Type: EntryFact, Content: "note for " + scope.String()})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, filepath.Join(workspace, "memory", "chats", "slack%3AC42%2Fgeneral"), scopes[0].Dir(workspace))
+
+	listed, err := ListScopes(workspace)
+	require.NoError(t, err)
+	assert.Equal(t, []Scope{GlobalScope(), AgentScope("coder"), UserScope("telegram:1001"), ChatScope("slack", "C42/general")}, listed)
+
+	content := readScopeMemory(t, workspace, UserScope("telegram:1001"))
+	assert.Contains(t, content, "# Long-term Memory (user:telegram:1001)\n\nThis file is loaded only in conversations with user telegram:1001.\n")
+	assert.Contains(t, content, "- note for user:telegram:1001")
+	_, err = os.Stat(filepath.Join(workspace, "memory", "MEMORY.md"))
+	assert.True(t, os.IsNotExist(err), "scoped writes do not touch the global memory")
+}
+
+func TestScopeDirStaysInsideKindDirectory(t *testing.T) {
+	workspace := t.TempDir()
+	for _, scope := range []Scope{UserScope(".."), AgentScope("."), UserScope("../../etc"), ChatScope("..", "..")} {
+		dir := scope.Dir(workspace)
+		parent := filepath.Join(workspace, "memory", scopeDirs[scope.Kind])
+		assert.Equal(t, parent, filepath.Dir(dir), scope.String())
+		assert.NotContains(t, []string{".", ".."}, filepath.Base(dir), scope.String())
+	}
+	assert.Equal(t, filepath.Join(workspace, "memory", "users", "%2E%2E"), UserScope("..").Dir(workspace))
+	assert.Equal(t, filepath.Join(workspace, "memory", "users", "alice.smith"), UserScope("alice.smith").Dir(workspace))
+}
+
+func TestScopeContextResolvesApplicableScopes(t *testing.T) {
+	ids := Identities{"alice": {"telegram:1001", "Slack:U1"}}
+	assert.Equal(t, "alice", ids.Resolve("slack", "U1"))
+	assert.Equal(t, "slack:U2", ids.Resolve("slack", "U2"))
+	assert.Empty(t, ids.Resolve("telegram", "cron"))
+
+	sc := NewScopeContext("default", "slack", "C42", "U1", ids)
+	assert.Equal(t, []Scope{GlobalScope(), UserScope("alice"), ChatScope("slack", "C42")}, sc.Scopes())
+	assert.True(t, sc.Allows(UserScope("alice")))
+	assert.False(t, sc.Allows(UserScope("bob")))
+	assert.Equal(t, GlobalScope(), sc.DefaultEntryScope(EntryFact))
+	assert.Equal(t, UserScope("alice"), sc.DefaultEntryScope(EntryPreference))
+	_, err := sc.Resolve(ScopeAgent)
+	assert.Error(t, err)
+
+	sc = NewScopeContext("coder", "telegram", "1001", "subagent", ids)
+	assert.Equal(t, []Scope{GlobalScope(), AgentScope("coder"), ChatScope("telegram", "1001")}, sc.Scopes())
+	assert.Equal(t, ChatScope("telegram", "1001"), sc.PersonalScope())
+	assert.Equal(t, GlobalScope(), ScopeContext{}.PersonalScope())
+}
+
+func TestFindEntryStoreSearchesScopesInOrder(t *testing.T) {
+	workspace := t.TempDir()
+	user := UserScope("alice")
+	entry, _, err := NewScopedEntryStore(workspace, user).Add(Entry{Type: EntryPreference, Content: "Answers in Chinese"})
+	require.NoError(t, err)
+
+	store, err := FindEntryStore(workspace, []Scope{GlobalScope(), user}, entry.ID[:4])
+	require.NoError(t, err)
+	assert.Equal(t, user, store.Scope())
+	_, err = FindEntryStore(workspace, []Scope{GlobalScope(), UserScope("bob")}, entry.ID)
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+
+	listed, err := ListScopedEntries(workspace, []Scope{GlobalScope(), user}, EntryFilter{})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, user, listed[0].Scope)
+	assert.Equal(t, entry.ID, listed[0].ID)
+}
+
+func TestCurateScopedMemory(t *testing.T) {
+	workspace := t.TempDir()
+	scope := UserScope("alice")
+	require.NoError(t, os.MkdirAll(scope.Dir(workspace), 0755))
+	require.NoError(t, os.WriteFile(scope.MemoryPath(workspace), []byte(curateFixture), 0644))
+
+	report, err := Curate(context.Background(), workspace, CurateOptions{Scope: scope, Now: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	assert.True(t, report.Changed)
+	assert.FileExists(t, filepath.Join(scope.Dir(workspace), "HISTORY.md"))
+	assert.DirExists(t, filepath.Join(scope.Dir(workspace), curationBackupDir))
+	_, err = os.Stat(filepath.Join(workspace, "memory", "HISTORY.md"))
+	assert.True(t, os.IsNotExist(err), "archived scoped entries stay in the scope")
+}
+
+func readScopeMemory(t *testing.T, workspace string, scope Scope) string {
+	t.Helper()
+	data, err := os.ReadFile(scope.MemoryPath(workspace))
+	require.NoError(t, err)
+	return string(data)
+}
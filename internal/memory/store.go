@@ -20,15 +20,26 @@ type Store struct {
 	memoryDir   string
 	memoryPath  string
 	historyPath string
+	template    string
 }
 
 func NewStore(workspace string) *Store {
-	memoryDir := filepath.Join(workspace, "memory")
+	return NewScopedStore(workspace, GlobalScope())
+}
+
+// NewScopedStore 创建指定作用域的存储，MEMORY.md 与 HISTORY.md 都位于作用域目录
+func NewScopedStore(workspace string, scope Scope) *Store {
+	memoryDir := scope.Dir(workspace)
+	template := defaultMemoryTemplate
+	if !scope.IsGlobal() {
+		template = fmt.Sprintf("# Long-term Memory (%s)\n\nThis file is %s.\n", scope, scope.Description())
+	}
 	return &Store{
 		workspace:   workspace,
 		memoryDir:   memoryDir,
 		memoryPath:  filepath.Join(memoryDir, "MEMORY.md"),
 		historyPath: filepath.Join(memoryDir, "HISTORY.md"),
+		template:    template,
 	}
 }
 
@@ -39,7 +50,7 @@ func (s *Store) EnsureFiles() error {
 	if err := os.MkdirAll(s.memoryDir, 0755); err != nil {
 		return fmt.Errorf("create memory dir: %w", err)
 	}
-	if err := ensureFileWithDefault(s.memoryPath, defaultMemoryTemplate); err != nil {
+	if err := ensureFileWithDefault(s.memoryPath, s.template); err != nil {
 		return err
 	}
 	if err := ensureFileWithDefault(s.historyPath, defaultHistoryTemplate); err != nil {
@@ -59,6 +70,18 @@ func (s *Store) ReadLongTerm() (string, error) {
 	return string(body), nil
 }
 
+// peekLongTerm 读取 MEMORY.md；文件不存在时返回模板内容且不创建任何文件
+func (s *Store) peekLongTerm() (string, error) {
+	body, err := os.ReadFile(s.memoryPath)
+	if os.IsNotExist(err) {
+		return s.template, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read memory file: %w", err)
+	}
+	return string(body), nil
+}
+
 func (s *Store) WriteLongTerm(content string) error {
 	if err := s.EnsureFiles(); err != nil {
 		return err
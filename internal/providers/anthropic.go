@@ -20,6 +20,7 @@ type AnthropicProvider struct {
 	maxTokens          int
 	temperature        float64
 	supportsImageInput func(model string) bool
+	reasoning          func(model string) ReasoningConfig
 }
 
 // NewAnthropicProvider creates an Anthropic provider backed by anthropic-sdk-go.
@@ -72,6 +73,11 @@ func (p *AnthropicProvider) Chat(ctx context.Context, messages []Message, tools
 		switch block.Type {
 		case "text":
 			result.Content += block.AsText().Text
+		case "thinking":
+			thinking := block.AsThinking()
+			result.Reasoning = append(result.Reasoning, ReasoningBlock{Text: thinking.Thinking, Signature: thinking.Signature})
+		case "redacted_thinking":
+			result.Reasoning = append(result.Reasoning, ReasoningBlock{RedactedData: block.AsRedactedThinking().Data})
 		case "tool_use":
 			toolUse := block.AsToolUse()
 			arguments := ""
@@ -99,10 +105,21 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, messages []Message,
 	defer stream.Close()
 
 	buildersByIndex := make(map[int64]*toolCallBuilder)
+	// 思考块的签名随 signature_delta 到达，块结束时一并交给处理器
+	signaturesByIndex := make(map[int64]*strings.Builder)
+	reasoning := newReasoningStream(handler)
 	for stream.Next() {
 		event := stream.Current()
 		switch current := event.AsAny().(type) {
 		case anthropic.ContentBlockStartEvent:
+			switch current.ContentBlock.Type {
+			case "thinking":
+				signaturesByIndex[current.Index] = &strings.Builder{}
+				continue
+			case "redacted_thinking":
+				reasoning.redacted(current.ContentBlock.AsRedactedThinking().Data)
+				continue
+			}
 			if current.ContentBlock.Type != "tool_use" {
 				continue
 			}
@@ -126,6 +143,12 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, messages []Message,
 				if current.Delta.Text != "" {
 					handler.OnContent(current.Delta.Text)
 				}
+			case "thinking_delta":
+				reasoning.delta(current.Delta.Thinking)
+			case "signature_delta":
+				if signature, ok := signaturesByIndex[current.Index]; ok {
+					signature.WriteString(current.Delta.Signature)
+				}
 			case "input_json_delta":
 				builder, ok := buildersByIndex[current.Index]
 				if !ok || builder == nil || !builder.Started || current.Delta.PartialJSON == "" {
@@ -135,6 +158,11 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, messages []Message,
 				handler.OnToolCallDelta(builder.ID, current.Delta.PartialJSON)
 			}
 		case anthropic.ContentBlockStopEvent:
+			if signature, ok := signaturesByIndex[current.Index]; ok {
+				reasoning.flush(signature.String())
+				delete(signaturesByIndex, current.Index)
+				continue
+			}
 			builder, ok := buildersByIndex[current.Index]
 			if !ok || builder == nil || !builder.Started || builder.ID == "" {
 				continue
@@ -172,13 +200,23 @@ func (p *AnthropicProvider) buildMessageParams(messages []Message, tools []map[s
 		model = p.defaultModel
 	}
 
-	system, anthropicMessages := convertToAnthropicMessages(messages, p.SupportsImageInput(model))
+	budget := lookupReasoning(p.reasoning, model).Budget()
+	system, anthropicMessages := convertToAnthropicMessages(messages, p.SupportsImageInput(model), budget > 0)
 	params := anthropic.MessageNewParams{
-		MaxTokens:   int64(p.maxTokens),
-		Messages:    anthropicMessages,
-		Model:       anthropic.Model(normalizeModelForProvider("anthropic", model)),
-		Temperature: anthropic.Float(p.temperature),
-		System:      system,
+		MaxTokens: int64(p.maxTokens),
+		Messages:  anthropicMessages,
+		Model:     anthropic.Model(normalizeModelForProvider("anthropic", model)),
+		System:    system,
+	}
+	if budget > 0 {
+		// 扩展思考要求 max_tokens 大于预算且不能设置 temperature
+		budget = max(budget, minThinkingBudget)
+		if p.maxTokens <= budget {
+			params.MaxTokens = int64(budget + p.maxTokens)
+		}
+		params.Thinking = anthropic.ThinkingConfigParamOfEnabled(int64(budget))
+	} else {
+		params.Temperature = anthropic.Float(p.temperature)
 	}
 	if len(tools) > 0 {
 		params.Tools = convertToAnthropicTools(tools)
@@ -187,7 +225,8 @@ func (p *AnthropicProvider) buildMessageParams(messages []Message, tools []map[s
 	return params
 }
 
-func convertToAnthropicMessages(messages []Message, allowImageInput bool) ([]anthropic.TextBlockParam, []anthropic.MessageParam) {
+// convertToAnthropicMessages 开启扩展思考时，助手消息前置带签名的思考块以保持工具调用连续性
+func convertToAnthropicMessages(messages []Message, allowImageInput, includeThinking bool) ([]anthropic.TextBlockParam, []anthropic.MessageParam) {
 	system := make([]anthropic.TextBlockParam, 0)
 	result := make([]anthropic.MessageParam, 0, len(messages))
 
@@ -199,7 +238,7 @@ func convertToAnthropicMessages(messages []Message, allowImageInput bool) ([]ant
 				system = append(system, anthropic.TextBlockParam{Text: text})
 			}
 		case "assistant":
-			blocks := anthropicBlocksForAssistant(msg)
+			blocks := anthropicBlocksForAssistant(msg, includeThinking)
 			if len(blocks) == 0 {
 				blocks = append(blocks, anthropic.NewTextBlock(""))
 			}
@@ -243,8 +282,19 @@ func anthropicBlocksForUser(msg Message, allowImageInput bool) []anthropic.Conte
 	return blocks
 }
 
-func anthropicBlocksForAssistant(msg Message) []anthropic.ContentBlockParamUnion {
-	blocks := make([]anthropic.ContentBlockParamUnion, 0, len(msg.ToolCalls)+1)
+func anthropicBlocksForAssistant(msg Message, includeThinking bool) []anthropic.ContentBlockParamUnion {
+	blocks := make([]anthropic.ContentBlockParamUnion, 0, len(msg.Reasoning)+len(msg.ToolCalls)+1)
+	if includeThinking {
+		for _, block := range msg.Reasoning {
+			// 未签名的思考内容（来自其他提供商）会被 API 拒绝，不回传
+			switch {
+			case block.RedactedData != "":
+				blocks = append(blocks, anthropic.NewRedactedThinkingBlock(block.RedactedData))
+			case block.Signature != "":
+				blocks = append(blocks, anthropic.NewThinkingBlock(block.Signature, block.Text))
+			}
+		}
+	}
 	if text := strings.TrimSpace(flattenContentParts(msg)); text != "" {
 		blocks = append(blocks, anthropic.NewTextBlock(text))
 	}
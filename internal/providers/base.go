@@ -2,6 +2,7 @@ package providers
 
 import (
 	"context"
+	"strings"
 )
 
 type ContentPart struct {
@@ -19,6 +20,8 @@ type Message struct {
 	Parts      []ContentPart
 	ToolCallID string     `json:"tool_call_id,omitempty"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	// Reasoning 助手消息的推理内容，仅在同一轮工具调用内回传给需要的提供商
+	Reasoning []ReasoningBlock `json:"reasoning,omitempty"`
 }
 
 // ToolCall 工具调用
@@ -39,6 +42,36 @@ type Response struct {
 	Content      string     `json:"content"`
 	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
 	HasToolCalls bool       `json:"has_tool_calls"`
+	Usage        *Usage     `json:"usage,omitempty"`
+	// Reasoning 模型返回的推理内容（思考过程），不支持时为空
+	Reasoning []ReasoningBlock `json:"reasoning,omitempty"`
+}
+
+// ReasoningBlock 一段推理内容
+// Signature/RedactedData 为 Anthropic 扩展思考的签名与加密内容，工具调用轮次间需原样回传
+type ReasoningBlock struct {
+	Text         string `json:"text,omitempty"`
+	Signature    string `json:"signature,omitempty"`
+	RedactedData string `json:"redacted_data,omitempty"`
+}
+
+// ReasoningText 拼接推理块中的可读文本
+func ReasoningText(blocks []ReasoningBlock) string {
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if text := strings.TrimSpace(block.Text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Usage token 用量，提供商未返回时为 nil
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	CachedTokens     int `json:"cached_tokens,omitempty"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // StreamHandler 流式响应处理器
@@ -51,6 +84,17 @@ type StreamHandler interface {
 	OnError(err error)                // 错误处理
 }
 
+// UsageHandler 可选接口，流式处理器实现后在流结束前收到 token 用量
+type UsageHandler interface {
+	OnUsage(usage Usage)
+}
+
+// ReasoningHandler 可选接口，流式处理器实现后单独收到推理内容
+type ReasoningHandler interface {
+	OnReasoningDelta(delta string)         // 推理文本片段
+	OnReasoningBlock(block ReasoningBlock) // 一段推理结束，含需回传的签名
+}
+
 // LLMProvider LLM 提供商接口
 type LLMProvider interface {
 	Chat(ctx context.Context, messages []Message, tools []map[string]interface{}, model string) (*Response, error)
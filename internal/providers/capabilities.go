@@ -1,6 +1,48 @@
 package providers
 
-import "strings"
+import (
+	"strings"
+	"sync"
+)
+
+// LocalAPIKey 本地提供商（如 Ollama）无需密钥时使用的占位值
+const LocalAPIKey = "local"
+
+// ModelCapabilities 运行时从提供商探测到的模型能力（如 Ollama /api/show）
+type ModelCapabilities struct {
+	ContextLength int
+	Vision        bool
+	Tools         bool
+	Thinking      bool
+}
+
+var (
+	discoveredMu           sync.RWMutex
+	discoveredCapabilities = map[string]ModelCapabilities{}
+)
+
+func capabilityKey(providerName, model string) string {
+	providerName = strings.ToLower(strings.TrimSpace(providerName))
+	if providerName == "ollama" {
+		return "ollama/" + strings.ToLower(ollamaModelName(model))
+	}
+	return providerName + "/" + strings.ToLower(normalizeModelForProvider(providerName, model))
+}
+
+// RecordModelCapabilities 记录探测到的模型能力
+func RecordModelCapabilities(providerName, model string, caps ModelCapabilities) {
+	discoveredMu.Lock()
+	defer discoveredMu.Unlock()
+	discoveredCapabilities[capabilityKey(providerName, model)] = caps
+}
+
+// LookupModelCapabilities 查询已探测的模型能力
+func LookupModelCapabilities(providerName, model string) (ModelCapabilities, bool) {
+	discoveredMu.RLock()
+	defer discoveredMu.RUnlock()
+	caps, ok := discoveredCapabilities[capabilityKey(providerName, model)]
+	return caps, ok
+}
 
 // DetectProviderName infers a provider name from the configured model id.
 func DetectProviderName(model string) string {
@@ -13,38 +55,11 @@ func DetectProviderName(model string) string {
 }
 
 // SupportsImageInput reports whether the target model should receive image parts.
+// 以模型目录（含运行时探测结果）为准，目录未收录的模型按名称兜底
 func SupportsImageInput(providerName, model string) bool {
-	providerName = strings.ToLower(strings.TrimSpace(providerName))
-	modelName := strings.ToLower(strings.TrimSpace(model))
-
-	switch providerName {
-	case "deepseek":
-		return strings.Contains(modelName, "vl")
-	case "openai":
-		return strings.Contains(modelName, "gpt-4o") ||
-			strings.Contains(modelName, "gpt-4.1") ||
-			strings.HasPrefix(modelName, "o1") ||
-			strings.HasPrefix(modelName, "o3")
-	case "anthropic":
-		return strings.Contains(modelName, "claude-3") ||
-			strings.Contains(modelName, "claude-sonnet-4") ||
-			strings.Contains(modelName, "claude-opus-4")
-	case "zhipu":
-		return strings.Contains(modelName, "glm-4.6v") ||
-			strings.Contains(modelName, "glm-ocr") ||
-			strings.Contains(modelName, "vision") ||
-			strings.Contains(modelName, "vl")
-	case "gemini":
-		return true
-	case "openrouter":
-		return strings.Contains(modelName, "gpt-4o") ||
-			strings.Contains(modelName, "gpt-4.1") ||
-			strings.Contains(modelName, "claude-3") ||
-			strings.Contains(modelName, "claude-sonnet-4") ||
-			strings.Contains(modelName, "gemini") ||
-			strings.Contains(modelName, "vl") ||
-			strings.Contains(modelName, "vision")
-	default:
-		return strings.Contains(modelName, "vision") || strings.Contains(modelName, "vl")
+	if info, ok := LookupModel(providerName, model); ok && info.Vision != nil {
+		return *info.Vision
 	}
+	modelName := strings.ToLower(strings.TrimSpace(model))
+	return strings.Contains(modelName, "vision") || strings.Contains(modelName, "vl")
 }
@@ -0,0 +1,255 @@
+package providers
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 模型目录：内置 catalog.json + 用户覆盖（config.modelCatalog 与 providers.*.models），
+// 条目 ID 为完整模型名或模型族前缀，查询时按前缀长度由粗到细叠加，越具体的条目优先级越高
+
+//go:embed catalog.json
+var builtinCatalogJSON []byte
+
+// ModelPricing 模型价格，单位为美元 / 百万 token；缓存读写价格为空时按输入价格换算
+type ModelPricing struct {
+	Input      float64 `json:"input" mapstructure:"input"`
+	Output     float64 `json:"output" mapstructure:"output"`
+	CacheRead  float64 `json:"cacheRead,omitempty" mapstructure:"cacheRead"`
+	CacheWrite float64 `json:"cacheWrite,omitempty" mapstructure:"cacheWrite"`
+}
+
+// ModelInfo 模型目录条目，未填写的字段表示未知，由更粗的条目或兜底逻辑决定
+type ModelInfo struct {
+	// ID 模型名或模型族前缀（如 claude-sonnet-4-5 同时匹配 claude-sonnet-4-5-20250929），"*" 匹配该 Provider 的所有模型
+	ID string `json:"id" mapstructure:"id"`
+	// Aliases 其他写法（如 OpenRouter 的 claude-sonnet-4.5）
+	Aliases []string `json:"aliases,omitempty" mapstructure:"aliases"`
+	// Provider 仅对该提供商生效，为空时对所有提供商生效
+	Provider      string        `json:"provider,omitempty" mapstructure:"provider"`
+	ContextWindow int           `json:"contextWindow,omitempty" mapstructure:"contextWindow"`
+	MaxOutput     int           `json:"maxOutput,omitempty" mapstructure:"maxOutput"`
+	Vision        *bool         `json:"vision,omitempty" mapstructure:"vision"`
+	Audio         *bool         `json:"audio,omitempty" mapstructure:"audio"`
+	Tools         *bool         `json:"tools,omitempty" mapstructure:"tools"`
+	Reasoning     *bool         `json:"reasoning,omitempty" mapstructure:"reasoning"`
+	Pricing       *ModelPricing `json:"pricing,omitempty" mapstructure:"pricing"`
+}
+
+// Capabilities 返回已知支持的能力名称
+func (m ModelInfo) Capabilities() []string {
+	var caps []string
+	for _, c := range []struct {
+		name string
+		flag *bool
+	}{{"vision", m.Vision}, {"audio", m.Audio}, {"tools", m.Tools}, {"reasoning", m.Reasoning}} {
+		if c.flag != nil && *c.flag {
+			caps = append(caps, c.name)
+		}
+	}
+	return caps
+}
+
+// Cost 按目录价格估算费用（美元），缓存 token 与输入 token 分开计数；无价格数据时 ok 为 false
+func (m ModelInfo) Cost(inputTokens, outputTokens, cacheReadTokens, cacheWriteTokens int) (float64, bool) {
+	if m.Pricing == nil {
+		return 0, false
+	}
+	p := *m.Pricing
+	if p.CacheRead == 0 {
+		p.CacheRead = p.Input * 0.1
+	}
+	if p.CacheWrite == 0 {
+		p.CacheWrite = p.Input * 1.25
+	}
+	cost := float64(inputTokens)*p.Input +
+		float64(outputTokens)*p.Output +
+		float64(cacheReadTokens)*p.CacheRead +
+		float64(cacheWriteTokens)*p.CacheWrite
+	return cost / 1_000_000, true
+}
+
+// merge 用 other 中已填写的字段覆盖当前条目
+func (m ModelInfo) merge(other ModelInfo) ModelInfo {
+	if other.ContextWindow > 0 {
+		m.ContextWindow = other.ContextWindow
+	}
+	if other.MaxOutput > 0 {
+		m.MaxOutput = other.MaxOutput
+	}
+	if other.Vision != nil {
+		m.Vision = other.Vision
+	}
+	if other.Audio != nil {
+		m.Audio = other.Audio
+	}
+	if other.Tools != nil {
+		m.Tools = other.Tools
+	}
+	if other.Reasoning != nil {
+		m.Reasoning = other.Reasoning
+	}
+	if other.Pricing != nil {
+		m.Pricing = other.Pricing
+	}
+	return m
+}
+
+// matchLen 返回条目命中模型名的前缀长度，未命中返回 -1
+func (m ModelInfo) matchLen(name string) int {
+	best := -1
+	for _, pattern := range append([]string{m.ID}, m.Aliases...) {
+		if strings.TrimSpace(pattern) == "*" {
+			best = max(best, 0)
+			continue
+		}
+		pattern = catalogModelName(pattern)
+		switch {
+		case pattern == "":
+			continue
+		case name == pattern:
+			best = max(best, len(pattern))
+		case strings.HasPrefix(name, pattern) && strings.ContainsRune("-:@", rune(name[len(pattern)])):
+			// 仅在分隔符处截断，避免 gpt-4 命中 gpt-4o
+			best = max(best, len(pattern))
+		}
+	}
+	return best
+}
+
+var (
+	builtinCatalogOnce sync.Once
+	builtinCatalog     []ModelInfo
+
+	catalogMu      sync.RWMutex
+	modelOverrides []ModelInfo
+)
+
+func builtinModels() []ModelInfo {
+	builtinCatalogOnce.Do(func() {
+		var file struct {
+			Models []ModelInfo `json:"models"`
+		}
+		if err := json.Unmarshal(builtinCatalogJSON, &file); err != nil {
+			panic(fmt.Sprintf("invalid built-in model catalog: %v", err))
+		}
+		builtinCatalog = file.Models
+	})
+	return builtinCatalog
+}
+
+// SetModelOverrides 设置用户覆盖的目录条目，同等具体程度下覆盖内置条目
+func SetModelOverrides(entries []ModelInfo) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	modelOverrides = append([]ModelInfo(nil), entries...)
+}
+
+func catalogLayers() [][]ModelInfo {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	return [][]ModelInfo{builtinModels(), modelOverrides}
+}
+
+// catalogModelName 统一模型名：小写，去掉 provider 前缀（openrouter/anthropic/claude-x → claude-x）
+func catalogModelName(model string) string {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// LookupModel 查询模型目录，叠加运行时探测到的能力；ok 为 false 表示目录未收录
+func LookupModel(providerName, model string) (ModelInfo, bool) {
+	info, ok := lookupCatalog(catalogLayers(), providerName, model)
+	if caps, found := LookupModelCapabilities(providerName, model); found {
+		// 探测结果来自提供商本身，优先级最高
+		if caps.ContextLength > 0 {
+			info.ContextWindow = caps.ContextLength
+		}
+		info.Vision, info.Tools, info.Reasoning = &caps.Vision, &caps.Tools, &caps.Thinking
+		if info.ID == "" {
+			info.ID = catalogModelName(model)
+		}
+		ok = true
+	}
+	return info, ok
+}
+
+func lookupCatalog(layers [][]ModelInfo, providerName, model string) (ModelInfo, bool) {
+	name := catalogModelName(model)
+	providerName = strings.ToLower(strings.TrimSpace(providerName))
+	if name == "" {
+		return ModelInfo{}, false
+	}
+
+	type match struct {
+		entry    ModelInfo
+		length   int
+		specific bool
+		layer    int
+	}
+	var matches []match
+	for layer, entries := range layers {
+		for _, entry := range entries {
+			if entry.Provider != "" && !strings.EqualFold(entry.Provider, providerName) {
+				continue
+			}
+			if n := entry.matchLen(name); n >= 0 {
+				matches = append(matches, match{entry: entry, length: n, specific: entry.Provider != "", layer: layer})
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return ModelInfo{}, false
+	}
+
+	// 由粗到细叠加：前缀更长、限定 provider、用户覆盖的条目后应用
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.length != b.length {
+			return a.length < b.length
+		}
+		if a.specific != b.specific {
+			return !a.specific
+		}
+		return a.layer < b.layer
+	})
+	var info ModelInfo
+	for _, m := range matches {
+		info = info.merge(m.entry)
+		if m.entry.ID != "*" {
+			info.ID, info.Aliases, info.Provider = m.entry.ID, m.entry.Aliases, m.entry.Provider
+		}
+	}
+	if info.ID == "" {
+		info.ID = name
+	}
+	return info, true
+}
+
+// CatalogModels 列出目录中的模型（内置与用户覆盖合并后），不含 "*" 通配条目
+func CatalogModels() []ModelInfo {
+	layers := catalogLayers()
+	seen := make(map[string]bool)
+	var result []ModelInfo
+	for _, entries := range layers {
+		for _, entry := range entries {
+			id := catalogModelName(entry.ID)
+			key := strings.ToLower(entry.Provider) + "/" + id
+			if id == "" || id == "*" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if info, ok := lookupCatalog(layers, entry.Provider, entry.ID); ok {
+				result = append(result, info)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,130 @@
+package providers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLookupModelMatchesFamilyPrefixAndAliases(t *testing.T) {
+	info, ok := LookupModel("anthropic", "anthropic/claude-sonnet-4-5-20250929")
+	if !ok || info.ID != "claude-sonnet-4-5" || info.ContextWindow != 200000 {
+		t.Fatalf("expected dated id to match claude-sonnet-4-5, got %+v ok=%v", info, ok)
+	}
+
+	info, ok = LookupModel("openrouter", "openrouter/anthropic/claude-sonnet-4.5")
+	if !ok || info.ID != "claude-sonnet-4-5" {
+		t.Fatalf("expected OpenRouter alias to match, got %+v ok=%v", info, ok)
+	}
+
+	info, _ = LookupModel("openai", "gpt-4o-mini-2024-07-18")
+	if info.ID != "gpt-4o-mini" || info.Pricing == nil || info.Pricing.Input != 0.15 {
+		t.Fatalf("expected the longest prefix to win, got %+v", info)
+	}
+
+	if info, _ := LookupModel("openai", "gpt-4o"); info.ContextWindow != 128000 {
+		t.Fatalf("gpt-4 must not match gpt-4o, got %+v", info)
+	}
+
+	if _, ok := LookupModel("unknown", "totally-new-model"); ok {
+		t.Fatal("expected unknown model to miss the catalog")
+	}
+}
+
+func TestLookupModelInheritsFromFamilyEntry(t *testing.T) {
+	info, ok := LookupModel("moonshot", "kimi-k2-thinking")
+	if !ok {
+		t.Fatal("expected kimi-k2-thinking in catalog")
+	}
+	if info.Reasoning == nil || !*info.Reasoning {
+		t.Fatalf("expected specific entry to enable reasoning, got %+v", info)
+	}
+	if info.ContextWindow != 262144 || info.Pricing == nil {
+		t.Fatalf("expected context and pricing inherited from kimi-k2, got %+v", info)
+	}
+}
+
+func TestLookupModelProviderScopedEntries(t *testing.T) {
+	info, ok := LookupModel("ollama", "ollama/gemma3:4b")
+	if !ok || info.Vision == nil || !*info.Vision {
+		t.Fatalf("expected ollama gemma3 entry, got %+v ok=%v", info, ok)
+	}
+	if cost, ok := info.Cost(1000, 1000, 0, 0); !ok || cost != 0 {
+		t.Fatalf("expected local models to be free, got %v ok=%v", cost, ok)
+	}
+
+	if _, ok := LookupModel("openai", "gemma3"); ok {
+		t.Fatal("ollama-scoped entries must not apply to other providers")
+	}
+}
+
+func TestSetModelOverridesTakePrecedence(t *testing.T) {
+	enabled := true
+	SetModelOverrides([]ModelInfo{
+		{ID: "claude-sonnet-4-5", ContextWindow: 1000000},
+		{ID: "acme-large", Vision: &enabled, Pricing: &ModelPricing{Input: 1, Output: 2}},
+	})
+	defer SetModelOverrides(nil)
+
+	info, _ := LookupModel("anthropic", "claude-sonnet-4-5")
+	if info.ContextWindow != 1000000 || info.MaxOutput != 64000 {
+		t.Fatalf("expected override merged onto built-in entry, got %+v", info)
+	}
+	if !SupportsImageInput("unknown", "custom/acme-large") {
+		t.Fatal("expected override to enable image input for a new model")
+	}
+
+	found := false
+	for _, m := range CatalogModels() {
+		if m.ID == "acme-large" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected override entry in CatalogModels")
+	}
+}
+
+func TestLookupModelPrefersProbedCapabilities(t *testing.T) {
+	RecordModelCapabilities("ollama", "ollama/llava:probe", ModelCapabilities{ContextLength: 4096, Vision: false, Tools: true})
+
+	info, ok := LookupModel("ollama", "ollama/llava:probe")
+	if !ok || info.ContextWindow != 4096 || *info.Vision || !*info.Tools {
+		t.Fatalf("expected probed capabilities to win, got %+v ok=%v", info, ok)
+	}
+}
+
+func TestSupportsImageInputUsesCatalogForNewModels(t *testing.T) {
+	for _, tc := range []struct {
+		provider, model string
+		want            bool
+	}{
+		{"openai", "gpt-5", true},
+		{"openai", "o3-mini", false},
+		{"openrouter", "openrouter/anthropic/claude-sonnet-4.5", true},
+		{"anthropic", "claude-3-5-haiku-latest", false},
+		{"zhipu", "glm-4.6v", true},
+		{"dashscope", "qwen-vl-max", true},
+		{"gemini", "gemini-2.5-flash", true},
+		{"unknown", "mystery-vl-7b", true},
+	} {
+		if got := SupportsImageInput(tc.provider, tc.model); got != tc.want {
+			t.Fatalf("SupportsImageInput(%q, %q) = %v, want %v", tc.provider, tc.model, got, tc.want)
+		}
+	}
+}
+
+func TestModelInfoCost(t *testing.T) {
+	info, _ := LookupModel("anthropic", "claude-sonnet-4-5")
+	cost, ok := info.Cost(1_000_000, 100_000, 1_000_000, 0)
+	if !ok {
+		t.Fatal("expected pricing for claude-sonnet-4-5")
+	}
+	// 3 (input) + 1.5 (output) + 0.3 (cache read)
+	if math.Abs(cost-4.8) > 1e-9 {
+		t.Fatalf("unexpected cost %v", cost)
+	}
+
+	if _, ok := (ModelInfo{}).Cost(1, 1, 0, 0); ok {
+		t.Fatal("expected no cost without pricing")
+	}
+}
@@ -1,23 +1,78 @@
 package providers
 
-import "strings"
+import (
+	"context"
+	"strings"
+)
 
 const (
 	providerKindCompatOpenAI = "compat-openai"
 	providerKindOpenAI       = "openai"
 	providerKindAnthropic    = "anthropic"
+	providerKindGemini       = "gemini"
+	providerKindOllama       = "ollama"
 )
 
+// ProviderOption 提供商的可选参数
+type ProviderOption func(*providerOptions)
+
+type providerOptions struct {
+	safetySettings map[string]string
+	reasoning      func(model string) ReasoningConfig
+}
+
+// WithSafetySettings 设置 Gemini 安全阈值（类别 → 阈值），其他提供商忽略
+func WithSafetySettings(settings map[string]string) ProviderOption {
+	return func(o *providerOptions) {
+		o.safetySettings = settings
+	}
+}
+
 // NewProvider creates the appropriate runtime provider implementation for the
 // configured model/provider pair.
-func NewProvider(apiKey, apiBase, apiFormat, defaultModel string, maxTokens int, temperature float64, supportsImageInput func(model string) bool) (LLMProvider, error) {
+func NewProvider(apiKey, apiBase, apiFormat, defaultModel string, maxTokens int, temperature float64, supportsImageInput func(model string) bool, opts ...ProviderOption) (LLMProvider, error) {
+	var options providerOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
 	switch ResolveProviderKind(defaultModel, apiBase, apiFormat) {
+	case providerKindGemini:
+		provider, err := NewGeminiProvider(apiKey, apiBase, defaultModel, maxTokens, temperature, options.safetySettings, supportsImageInput)
+		if err != nil {
+			return nil, err
+		}
+		provider.reasoning = options.reasoning
+		return provider, nil
+	case providerKindOllama:
+		provider, err := NewOllamaProvider(apiKey, apiBase, defaultModel, maxTokens, temperature, supportsImageInput)
+		if err != nil {
+			return nil, err
+		}
+		provider.reasoning = options.reasoning
+		// 启动时探测上下文长度和视觉能力，Ollama 未运行时跳过
+		provider.DetectCapabilities(context.Background(), defaultModel)
+		return provider, nil
 	case providerKindAnthropic:
-		return NewAnthropicProvider(apiKey, apiBase, defaultModel, maxTokens, temperature, supportsImageInput)
+		provider, err := NewAnthropicProvider(apiKey, apiBase, defaultModel, maxTokens, temperature, supportsImageInput)
+		if err != nil {
+			return nil, err
+		}
+		provider.reasoning = options.reasoning
+		return provider, nil
 	case providerKindOpenAI:
-		return NewOpenAIOfficialProvider(apiKey, apiBase, defaultModel, maxTokens, temperature, supportsImageInput)
+		provider, err := NewOpenAIOfficialProvider(apiKey, apiBase, defaultModel, maxTokens, temperature, supportsImageInput)
+		if err != nil {
+			return nil, err
+		}
+		provider.reasoning = options.reasoning
+		return provider, nil
 	default:
-		return NewOpenAIProvider(apiKey, apiBase, defaultModel, maxTokens, temperature, supportsImageInput)
+		provider, err := NewOpenAIProvider(apiKey, apiBase, defaultModel, maxTokens, temperature, supportsImageInput)
+		if err != nil {
+			return nil, err
+		}
+		provider.reasoning = options.reasoning
+		return provider, nil
 	}
 }
 
@@ -44,6 +99,18 @@ func ResolveProviderKind(model, apiBase, apiFormat string) string {
 		if strings.EqualFold(strings.TrimSpace(apiFormat), "openai") || apiFormat == "" {
 			return providerKindOpenAI
 		}
+	case "ollama":
+		// apiFormat 为 openai 时走 /v1 兼容接口
+		format := strings.ToLower(strings.TrimSpace(apiFormat))
+		if format == "" || format == "ollama" {
+			return providerKindOllama
+		}
+	case "gemini":
+		// 显式配置 OpenAI 兼容端点（.../v1beta/openai）时仍走兼容实现
+		format := strings.ToLower(strings.TrimSpace(apiFormat))
+		if (format == "" || format == "gemini") && !strings.Contains(strings.ToLower(apiBase), "/openai") {
+			return providerKindGemini
+		}
 	}
 
 	if strings.EqualFold(strings.TrimSpace(apiFormat), "anthropic") && DetectProviderNameFromAPIBase(apiBase) == "anthropic" {
@@ -73,6 +140,8 @@ func DetectProviderNameFromAPIBase(apiBase string) string {
 		return "zhipu"
 	case strings.Contains(base, "groq.com"):
 		return "groq"
+	case strings.Contains(base, ":11434") || strings.Contains(base, "ollama"):
+		return "ollama"
 	case strings.Contains(base, "generativelanguage.googleapis.com"):
 		return "gemini"
 	case strings.Contains(base, "dashscope.aliyuncs.com"):
@@ -16,6 +16,10 @@ func TestResolveProviderKind(t *testing.T) {
 		{name: "anthropic api base can recover official provider", model: "custom", apiBase: "https://api.anthropic.com", apiFormat: "anthropic", expected: providerKindAnthropic},
 		{name: "kimi coding api uses anthropic provider", model: "kimi-2.5", apiBase: "https://api.kimi.com/coding/v1", apiFormat: "openai", expected: providerKindAnthropic},
 		{name: "kimi coding api without v1 uses anthropic provider", model: "kimi-for-coding", apiBase: "https://api.kimi.com/coding", apiFormat: "", expected: providerKindAnthropic},
+		{name: "gemini model uses native provider", model: "gemini/gemini-2.5-pro", apiBase: "https://generativelanguage.googleapis.com/v1beta", expected: providerKindGemini},
+		{name: "ollama model uses native provider", model: "ollama/deepseek-r1:8b", apiBase: "http://localhost:11434", expected: providerKindOllama},
+		{name: "ollama api base with openai format stays compat", model: "llama3.2", apiBase: "http://localhost:11434/v1", apiFormat: "openai", expected: providerKindCompatOpenAI},
+		{name: "gemini openai endpoint stays compat", model: "gemini-2.5-flash", apiBase: "https://generativelanguage.googleapis.com/v1beta/openai/", apiFormat: "openai", expected: providerKindCompatOpenAI},
 	}
 
 	for _, tt := range tests {
@@ -71,4 +75,12 @@ func TestNewProviderUsesOfficialImplementations(t *testing.T) {
 	if _, ok := compatProvider.(*OpenAIProvider); !ok {
 		t.Fatalf("expected OpenAIProvider compatibility implementation, got %T", compatProvider)
 	}
+
+	geminiProvider, err := NewProvider("g-key", "", "", "gemini/gemini-2.5-flash", 32, 0, nil, WithSafetySettings(map[string]string{"harassment": "BLOCK_NONE"}))
+	if err != nil {
+		t.Fatalf("NewProvider gemini failed: %v", err)
+	}
+	if _, ok := geminiProvider.(*GeminiProvider); !ok {
+		t.Fatalf("expected GeminiProvider, got %T", geminiProvider)
+	}
 }
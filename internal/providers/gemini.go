@@ -0,0 +1,850 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultGeminiAPIBase = "https://generativelanguage.googleapis.com/v1beta"
+	// maxGeminiSignatures 缓存的 functionCall thoughtSignature 数量上限
+	maxGeminiSignatures = 1024
+)
+
+// geminiSafetyCategories 配置中可省略 HARM_CATEGORY_ 前缀
+var geminiSafetyCategories = map[string]string{
+	"harassment":        "HARM_CATEGORY_HARASSMENT",
+	"hate_speech":       "HARM_CATEGORY_HATE_SPEECH",
+	"sexually_explicit": "HARM_CATEGORY_SEXUALLY_EXPLICIT",
+	"dangerous_content": "HARM_CATEGORY_DANGEROUS_CONTENT",
+	"civic_integrity":   "HARM_CATEGORY_CIVIC_INTEGRITY",
+}
+
+// GeminiProvider 使用 Gemini 原生 generateContent / streamGenerateContent REST API
+type GeminiProvider struct {
+	apiKey             string
+	apiBase            string
+	defaultModel       string
+	maxTokens          int
+	temperature        float64
+	safetySettings     []geminiSafetySetting
+	httpClient         *http.Client
+	streamClient       *http.Client
+	supportsImageInput func(model string) bool
+	reasoning          func(model string) ReasoningConfig
+
+	// Gemini 的 functionCall 不一定带 id，回传时需要 name 和 thoughtSignature，按生成的调用 ID 记录
+	mu         sync.Mutex
+	callSeq    int
+	signatures map[string]string
+	sigOrder   []string
+}
+
+// NewGeminiProvider 创建 Gemini 原生提供商；safetySettings 为类别 → 阈值
+func NewGeminiProvider(apiKey, apiBase, defaultModel string, maxTokens int, temperature float64, safetySettings map[string]string, supportsImageInput func(model string) bool) (*GeminiProvider, error) {
+	return newGeminiProvider(apiKey, apiBase, defaultModel, maxTokens, temperature, safetySettings, supportsImageInput, nil)
+}
+
+func newGeminiProvider(apiKey, apiBase, defaultModel string, maxTokens int, temperature float64, safetySettings map[string]string, supportsImageInput func(model string) bool, httpClient *http.Client) (*GeminiProvider, error) {
+	if strings.TrimSpace(apiKey) == "" {
+		return nil, fmt.Errorf("API key is required")
+	}
+	if strings.TrimSpace(defaultModel) == "" {
+		defaultModel = "gemini-2.5-flash"
+	}
+	if maxTokens <= 0 {
+		maxTokens = 1
+	}
+	settings, err := buildGeminiSafetySettings(safetySettings)
+	if err != nil {
+		return nil, err
+	}
+
+	client := httpClient
+	streamClient := httpClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+		streamClient = &http.Client{}
+	}
+
+	return &GeminiProvider{
+		apiKey:             strings.TrimSpace(apiKey),
+		apiBase:            normalizeGeminiBaseURL(apiBase),
+		defaultModel:       defaultModel,
+		maxTokens:          maxTokens,
+		temperature:        temperature,
+		safetySettings:     settings,
+		httpClient:         client,
+		streamClient:       streamClient,
+		supportsImageInput: supportsImageInput,
+		signatures:         make(map[string]string),
+	}, nil
+}
+
+// normalizeGeminiBaseURL 去掉末尾的 / 和 /models，空值使用官方地址
+func normalizeGeminiBaseURL(apiBase string) string {
+	trimmed := strings.TrimRight(strings.TrimSpace(apiBase), "/")
+	trimmed = strings.TrimSuffix(trimmed, "/models")
+	if trimmed == "" {
+		return defaultGeminiAPIBase
+	}
+	return trimmed
+}
+
+func buildGeminiSafetySettings(raw map[string]string) ([]geminiSafetySetting, error) {
+	settings := make([]geminiSafetySetting, 0, len(raw))
+	for category, threshold := range raw {
+		name := strings.ToUpper(strings.TrimSpace(category))
+		if mapped, ok := geminiSafetyCategories[strings.ToLower(strings.TrimSpace(category))]; ok {
+			name = mapped
+		}
+		if !strings.HasPrefix(name, "HARM_CATEGORY_") {
+			return nil, fmt.Errorf("unknown gemini safety category %q", category)
+		}
+		threshold = strings.ToUpper(strings.TrimSpace(threshold))
+		if threshold == "" {
+			return nil, fmt.Errorf("gemini safety category %q needs a threshold", category)
+		}
+		settings = append(settings, geminiSafetySetting{Category: name, Threshold: threshold})
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Category < settings[j].Category })
+	return settings, nil
+}
+
+// Chat 发送 generateContent 请求
+func (p *GeminiProvider) Chat(ctx context.Context, messages []Message, tools []map[string]interface{}, model string) (*Response, error) {
+	model = p.resolveModel(model)
+	payload, err := json.Marshal(p.buildRequest(messages, tools, model))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	body, err := p.doRequest(ctx, p.endpoint(model, false), payload)
+	if err != nil {
+		return nil, p.wrapModelRequestError("chat request failed", model, err)
+	}
+
+	var resp geminiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, p.wrapModelRequestError("chat request failed", model, fmt.Errorf("failed to decode response: %w", err))
+	}
+	if err := resp.blockedError(); err != nil {
+		return nil, p.wrapModelRequestError("chat request failed", model, err)
+	}
+
+	result := &Response{Usage: resp.usage()}
+	for _, part := range resp.parts() {
+		switch {
+		case part.FunctionCall != nil:
+			result.ToolCalls = append(result.ToolCalls, p.toolCallFromPart(part))
+		case part.Thought:
+			// 思考摘要不作为回复内容
+			if part.Text != "" {
+				result.Reasoning = append(result.Reasoning, ReasoningBlock{Text: part.Text})
+			}
+		default:
+			result.Content += part.Text
+		}
+	}
+	result.HasToolCalls = len(result.ToolCalls) > 0
+	return result, nil
+}
+
+// ChatStream 发送 streamGenerateContent?alt=sse 请求
+func (p *GeminiProvider) ChatStream(ctx context.Context, messages []Message, tools []map[string]interface{}, model string, handler StreamHandler) error {
+	model = p.resolveModel(model)
+	payload, err := json.Marshal(p.buildRequest(messages, tools, model))
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	stream, err := p.doStreamRequest(ctx, p.endpoint(model, true), payload)
+	if err != nil {
+		wrappedErr := p.wrapModelRequestError("stream request failed", model, err)
+		handler.OnError(wrappedErr)
+		return wrappedErr
+	}
+	defer stream.Close()
+
+	fail := func(err error) error {
+		wrappedErr := p.wrapModelRequestError("stream read failed", model, err)
+		handler.OnError(wrappedErr)
+		return wrappedErr
+	}
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+	var usage *Usage
+	reasoning := newReasoningStream(handler)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" {
+			continue
+		}
+
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fail(fmt.Errorf("stream decode error: %w", err))
+		}
+		if chunk.Error != nil {
+			return fail(fmt.Errorf("gemini API error (status code %d): %s", chunk.Error.Code, data))
+		}
+		if err := chunk.blockedError(); err != nil {
+			return fail(err)
+		}
+		if u := chunk.usage(); u != nil {
+			usage = u
+		}
+		for _, part := range chunk.parts() {
+			switch {
+			case part.FunctionCall != nil:
+				// Gemini 每个 functionCall 在单个分片内完整给出
+				call := p.toolCallFromPart(part)
+				handler.OnToolCallStart(call.ID, call.Function.Name)
+				handler.OnToolCallDelta(call.ID, call.Function.Arguments)
+				handler.OnToolCallEnd(call.ID)
+			case part.Thought:
+				reasoning.delta(part.Text)
+			case part.Text != "":
+				reasoning.flush("")
+				handler.OnContent(part.Text)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fail(fmt.Errorf("stream error: %w", err))
+	}
+
+	reasoning.flush("")
+	if usageHandler, ok := handler.(UsageHandler); ok && usage != nil {
+		usageHandler.OnUsage(*usage)
+	}
+	handler.OnComplete()
+	return nil
+}
+
+// GetDefaultModel 默认模型
+func (p *GeminiProvider) GetDefaultModel() string {
+	return p.defaultModel
+}
+
+// SupportsImageInput Gemini 模型均支持图片输入
+func (p *GeminiProvider) SupportsImageInput(model string) bool {
+	if strings.TrimSpace(model) == "" {
+		model = p.defaultModel
+	}
+	if p.supportsImageInput != nil {
+		return p.supportsImageInput(model)
+	}
+	return SupportsImageInput("gemini", model)
+}
+
+func (p *GeminiProvider) resolveModel(model string) string {
+	if strings.TrimSpace(model) == "" {
+		model = p.defaultModel
+	}
+	return model
+}
+
+func (p *GeminiProvider) endpoint(model string, stream bool) string {
+	name := normalizeModelForProvider("gemini", model)
+	name = strings.TrimPrefix(name, "models/")
+	if stream {
+		return p.apiBase + "/models/" + name + ":streamGenerateContent?alt=sse"
+	}
+	return p.apiBase + "/models/" + name + ":generateContent"
+}
+
+func (p *GeminiProvider) wrapModelRequestError(prefix, model string, err error) error {
+	return fmt.Errorf("%s provider=gemini model=%s api_base=%s: %w", prefix, model, p.apiBase, err)
+}
+
+// toolCallFromPart 为 functionCall 生成调用 ID，并记录 thoughtSignature 以便下一轮回传
+func (p *GeminiProvider) toolCallFromPart(part geminiPart) ToolCall {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := strings.TrimSpace(part.FunctionCall.ID)
+	if id == "" {
+		p.callSeq++
+		id = fmt.Sprintf("gemini_call_%d_%d", time.Now().UnixNano(), p.callSeq)
+	}
+	if part.ThoughtSignature != "" {
+		if _, exists := p.signatures[id]; !exists {
+			p.sigOrder = append(p.sigOrder, id)
+		}
+		p.signatures[id] = part.ThoughtSignature
+		for len(p.sigOrder) > maxGeminiSignatures {
+			delete(p.signatures, p.sigOrder[0])
+			p.sigOrder = p.sigOrder[1:]
+		}
+	}
+
+	args := "{}"
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, part.FunctionCall.Args); err == nil && compact.String() != "null" {
+		args = compact.String()
+	}
+	return ToolCall{
+		ID:       id,
+		Type:     "function",
+		Function: ToolCallFunction{Name: part.FunctionCall.Name, Arguments: args},
+	}
+}
+
+func (p *GeminiProvider) signature(callID string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.signatures[callID]
+}
+
+func (p *GeminiProvider) buildRequest(messages []Message, tools []map[string]interface{}, model string) geminiRequest {
+	system, contents := p.convertMessages(messages, p.SupportsImageInput(model))
+	req := geminiRequest{
+		SystemInstruction: system,
+		Contents:          contents,
+		SafetySettings:    p.safetySettings,
+		GenerationConfig: &geminiGenerationConfig{
+			MaxOutputTokens: p.maxTokens,
+			Temperature:     &p.temperature,
+		},
+	}
+	if budget := lookupReasoning(p.reasoning, model).Budget(); budget > 0 {
+		req.GenerationConfig.ThinkingConfig = &geminiThinkingConfig{ThinkingBudget: budget, IncludeThoughts: true}
+	}
+	if declarations := convertToGeminiFunctions(tools); len(declarations) > 0 {
+		req.Tools = []geminiTool{{FunctionDeclarations: declarations}}
+		req.ToolConfig = &geminiToolConfig{FunctionCallingConfig: geminiFunctionCallingConfig{Mode: "AUTO"}}
+	}
+	return req
+}
+
+// convertMessages 转换为 Gemini contents：system 合并为 systemInstruction，
+// 连续的同角色消息（如多个工具结果）合并为一个 content
+func (p *GeminiProvider) convertMessages(messages []Message, allowMedia bool) (*geminiContent, []geminiContent) {
+	var systemParts []geminiPart
+	var contents []geminiContent
+	toolNames := make(map[string]string)
+
+	appendContent := func(role string, parts []geminiPart) {
+		if len(parts) == 0 {
+			parts = []geminiPart{{Text: " "}}
+		}
+		if n := len(contents); n > 0 && contents[n-1].Role == role {
+			contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+			return
+		}
+		contents = append(contents, geminiContent{Role: role, Parts: parts})
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			if text := strings.TrimSpace(flattenContentParts(msg)); text != "" {
+				systemParts = append(systemParts, geminiPart{Text: text})
+			}
+		case "assistant":
+			var parts []geminiPart
+			if text := strings.TrimSpace(flattenContentParts(msg)); text != "" {
+				parts = append(parts, geminiPart{Text: text})
+			}
+			for _, call := range msg.ToolCalls {
+				toolNames[call.ID] = call.Function.Name
+				parts = append(parts, geminiPart{
+					FunctionCall:     &geminiFunctionCall{Name: call.Function.Name, Args: toolArgumentsJSON(call.Function.Arguments)},
+					ThoughtSignature: p.signature(call.ID),
+				})
+			}
+			appendContent("model", parts)
+		case "tool":
+			name := toolNames[msg.ToolCallID]
+			if name == "" {
+				name = "tool"
+			}
+			appendContent("user", []geminiPart{{
+				FunctionResponse: &geminiFunctionResponse{
+					Name:     name,
+					Response: map[string]interface{}{"content": flattenContentParts(msg)},
+				},
+			}})
+		default:
+			appendContent("user", geminiUserParts(msg, allowMedia))
+		}
+	}
+
+	var system *geminiContent
+	if len(systemParts) > 0 {
+		system = &geminiContent{Parts: systemParts}
+	}
+	return system, contents
+}
+
+// geminiUserParts 文本与内联媒体（图片、PDF 等）；模型不支持时退化为纯文本
+func geminiUserParts(msg Message, allowMedia bool) []geminiPart {
+	if !allowMedia || len(msg.Parts) == 0 {
+		return []geminiPart{{Text: flattenContentParts(msg)}}
+	}
+	var parts []geminiPart
+	for _, part := range msg.Parts {
+		if part.Type == "text" || (part.ImagePath == "" && part.ImageURL == "") {
+			if strings.TrimSpace(part.Text) != "" {
+				parts = append(parts, geminiPart{Text: part.Text})
+			}
+			continue
+		}
+		if media, ok := geminiMediaPart(part); ok {
+			parts = append(parts, media)
+		}
+	}
+	if len(parts) == 0 {
+		return []geminiPart{{Text: flattenContentParts(msg)}}
+	}
+	return parts
+}
+
+func geminiMediaPart(part ContentPart) (geminiPart, bool) {
+	if dataURL := buildInlineDataURL(part); dataURL != "" {
+		if mimeType, data, ok := parseImageDataURL(dataURL); ok {
+			return geminiPart{InlineData: &geminiBlob{MimeType: mimeType, Data: data}}, true
+		}
+	}
+	url := strings.TrimSpace(part.ImageURL)
+	if mimeType, data, ok := parseImageDataURL(url); ok {
+		return geminiPart{InlineData: &geminiBlob{MimeType: mimeType, Data: data}}, true
+	}
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "gs://") {
+		mimeType := strings.TrimSpace(part.MimeType)
+		if mimeType == "" {
+			mimeType = mime.TypeByExtension(filepath.Ext(strings.SplitN(url, "?", 2)[0]))
+		}
+		if mimeType == "" {
+			mimeType = "image/jpeg"
+		}
+		return geminiPart{FileData: &geminiFileData{MimeType: mimeType, FileURI: url}}, true
+	}
+	return geminiPart{}, false
+}
+
+// toolArgumentsJSON 将工具参数字符串转为 JSON 对象（原生接口要求对象而非字符串）
+func toolArgumentsJSON(arguments string) json.RawMessage {
+	decoded := decodeToolArguments(arguments)
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		return json.RawMessage("{}")
+	}
+	return data
+}
+
+// convertToGeminiFunctions 将 OpenAI 风格的工具定义转为 functionDeclarations
+func convertToGeminiFunctions(tools []map[string]interface{}) []geminiFunctionDeclaration {
+	result := make([]geminiFunctionDeclaration, 0, len(tools))
+	for _, tool := range tools {
+		function, _ := tool["function"].(map[string]interface{})
+		name, _ := function["name"].(string)
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		description, _ := function["description"].(string)
+		decl := geminiFunctionDeclaration{Name: name, Description: description}
+		if parameters, ok := function["parameters"].(map[string]interface{}); ok {
+			if props, _ := parameters["properties"].(map[string]interface{}); len(props) > 0 {
+				decl.Parameters = sanitizeGeminiSchema(parameters)
+			}
+		}
+		result = append(result, decl)
+	}
+	return result
+}
+
+// geminiSchemaKeys Gemini Schema（OpenAPI 子集）支持的字段
+var geminiSchemaKeys = map[string]bool{
+	"type": true, "format": true, "description": true, "nullable": true, "enum": true,
+	"items": true, "properties": true, "required": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true, "minimum": true, "maximum": true,
+	"anyOf": true, "default": true, "title": true, "minProperties": true, "maxProperties": true,
+}
+
+// sanitizeGeminiSchema 去掉 Gemini 不接受的 JSON Schema 字段（additionalProperties、$schema 等），
+// 并把 ["string","null"] 形式的类型改写为 nullable
+func sanitizeGeminiSchema(schema map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		if !geminiSchemaKeys[key] {
+			continue
+		}
+		switch key {
+		case "type":
+			if types, ok := value.([]interface{}); ok {
+				for _, t := range types {
+					if s, _ := t.(string); s == "null" {
+						out["nullable"] = true
+					} else if s != "" {
+						out["type"] = s
+					}
+				}
+				continue
+			}
+			out[key] = value
+		case "properties":
+			props, _ := value.(map[string]interface{})
+			cleaned := make(map[string]interface{}, len(props))
+			for name, prop := range props {
+				if m, ok := prop.(map[string]interface{}); ok {
+					cleaned[name] = sanitizeGeminiSchema(m)
+				}
+			}
+			out[key] = cleaned
+		case "items":
+			if m, ok := value.(map[string]interface{}); ok {
+				out[key] = sanitizeGeminiSchema(m)
+			}
+		case "anyOf":
+			if list, ok := value.([]interface{}); ok {
+				cleaned := make([]interface{}, 0, len(list))
+				for _, item := range list {
+					if m, ok := item.(map[string]interface{}); ok {
+						cleaned = append(cleaned, sanitizeGeminiSchema(m))
+					}
+				}
+				out[key] = cleaned
+			}
+		case "enum":
+			// Gemini 只接受字符串枚举
+			if list, ok := value.([]interface{}); ok {
+				values := make([]string, 0, len(list))
+				for _, item := range list {
+					values = append(values, fmt.Sprint(item))
+				}
+				out[key] = values
+			} else {
+				out[key] = value
+			}
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// doRequest 执行非流式请求，5xx / 429 时重试
+func (p *GeminiProvider) doRequest(ctx context.Context, endpoint string, payload []byte) ([]byte, error) {
+	const maxRetries = 3
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+		req, err := p.newRequest(ctx, endpoint, payload)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := p.httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			if isRetryableError(err) && ctx.Err() == nil {
+				continue
+			}
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			lastErr = formatGeminiError(body, resp.StatusCode)
+			if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+				continue
+			}
+			return nil, lastErr
+		}
+		return body, nil
+	}
+	return nil, fmt.Errorf("failed after %d attempts: %w", maxRetries, lastErr)
+}
+
+func (p *GeminiProvider) doStreamRequest(ctx context.Context, endpoint string, payload []byte) (io.ReadCloser, error) {
+	const maxRetries = 3
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+		req, err := p.newRequest(ctx, endpoint, payload)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := p.streamClient.Do(req)
+		if err != nil {
+			lastErr = err
+			if isRetryableError(err) && ctx.Err() == nil {
+				continue
+			}
+			return nil, err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			lastErr = formatGeminiError(body, resp.StatusCode)
+			if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+				continue
+			}
+			return nil, lastErr
+		}
+		return resp.Body, nil
+	}
+	return nil, fmt.Errorf("failed after %d attempts: %w", maxRetries, lastErr)
+}
+
+func (p *GeminiProvider) newRequest(ctx context.Context, endpoint string, payload []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", p.apiKey)
+	return req, nil
+}
+
+// ListGeminiModels 列出支持 generateContent 的模型（不含 models/ 前缀）
+func ListGeminiModels(ctx context.Context, apiKey, apiBase string) ([]string, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	base := normalizeGeminiBaseURL(apiBase)
+	var models []string
+	pageToken := ""
+	for {
+		endpoint := base + "/models?pageSize=1000"
+		if pageToken != "" {
+			endpoint += "&pageToken=" + pageToken
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("x-goog-api-key", strings.TrimSpace(apiKey))
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, formatGeminiError(body, resp.StatusCode)
+		}
+		var page struct {
+			Models []struct {
+				Name                       string   `json:"name"`
+				SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+			} `json:"models"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode models: %w", err)
+		}
+		for _, m := range page.Models {
+			for _, method := range m.SupportedGenerationMethods {
+				if method == "generateContent" {
+					models = append(models, strings.TrimPrefix(m.Name, "models/"))
+					break
+				}
+			}
+		}
+		if page.NextPageToken == "" {
+			return models, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// formatGeminiError 保留状态码和 JSON 错误体（含 google.rpc status 与 details.reason），供 ErrorClassifier 解析
+func formatGeminiError(body []byte, status int) error {
+	trimmed := bytes.TrimSpace(body)
+	// 流式接口的错误体可能是数组
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var list []json.RawMessage
+		if err := json.Unmarshal(trimmed, &list); err == nil && len(list) > 0 {
+			trimmed = bytes.TrimSpace(list[0])
+		}
+	}
+	var compact bytes.Buffer
+	if json.Valid(trimmed) && json.Compact(&compact, trimmed) == nil {
+		return fmt.Errorf("gemini API error (status code %d): %s", status, compact.String())
+	}
+	text := strings.TrimSpace(string(trimmed))
+	if len(text) > 500 {
+		text = text[:500] + "..."
+	}
+	return fmt.Errorf("gemini API error (status code %d): %s", status, text)
+}
+
+// ---- Gemini request/response structs ----
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent         `json:"contents"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig       `json:"toolConfig,omitempty"`
+	SafetySettings    []geminiSafetySetting   `json:"safetySettings,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	ThoughtSignature string                  `json:"thoughtSignature,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations,omitempty"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig geminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type geminiFunctionCallingConfig struct {
+	Mode string `json:"mode"`
+}
+
+type geminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens int                   `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64              `json:"temperature,omitempty"`
+	ThinkingConfig  *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget  int  `json:"thinkingBudget,omitempty"`
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason,omitempty"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason,omitempty"`
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata *struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+		TotalTokenCount         int `json:"totalTokenCount"`
+	} `json:"usageMetadata,omitempty"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error,omitempty"`
+}
+
+func (r geminiResponse) parts() []geminiPart {
+	if len(r.Candidates) == 0 {
+		return nil
+	}
+	return r.Candidates[0].Content.Parts
+}
+
+func (r geminiResponse) usage() *Usage {
+	if r.UsageMetadata == nil {
+		return nil
+	}
+	return &Usage{
+		PromptTokens:     r.UsageMetadata.PromptTokenCount,
+		CompletionTokens: r.UsageMetadata.CandidatesTokenCount + r.UsageMetadata.ThoughtsTokenCount,
+		CachedTokens:     r.UsageMetadata.CachedContentTokenCount,
+		TotalTokens:      r.UsageMetadata.TotalTokenCount,
+	}
+}
+
+// blockedError 提示词或回复被安全策略拦截时返回错误（无可用内容）
+func (r geminiResponse) blockedError() error {
+	if r.PromptFeedback != nil && r.PromptFeedback.BlockReason != "" {
+		return fmt.Errorf("gemini prompt blocked by safety filters: blockReason=%s", r.PromptFeedback.BlockReason)
+	}
+	if len(r.Candidates) == 0 {
+		return nil
+	}
+	switch reason := r.Candidates[0].FinishReason; reason {
+	case "SAFETY", "PROHIBITED_CONTENT", "BLOCKLIST", "SPII", "RECITATION":
+		if len(r.Candidates[0].Content.Parts) == 0 {
+			return fmt.Errorf("gemini response blocked by safety filters: finishReason=%s", reason)
+		}
+	}
+	return nil
+}
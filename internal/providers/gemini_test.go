@@ -0,0 +1,310 @@
+package providers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type geminiRecordedRequest struct {
+	Path  string
+	Query string
+	Key   string
+	Body  map[string]interface{}
+}
+
+func newGeminiTestServer(t *testing.T, handler func(w http.ResponseWriter, req geminiRecordedRequest)) (*httptest.Server, *[]geminiRecordedRequest) {
+	t.Helper()
+	var recorded []geminiRecordedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Fatalf("invalid request body: %v", err)
+		}
+		req := geminiRecordedRequest{Path: r.URL.Path, Query: r.URL.RawQuery, Key: r.Header.Get("x-goog-api-key"), Body: body}
+		recorded = append(recorded, req)
+		handler(w, req)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &recorded
+}
+
+func TestGeminiChatRoundTripsFunctionCalls(t *testing.T) {
+	srv, recorded := newGeminiTestServer(t, func(w http.ResponseWriter, req geminiRecordedRequest) {
+		_, _ = io.WriteString(w, `{
+			"candidates": [{"content": {"role": "model", "parts": [
+				{"text": "thinking...", "thought": true},
+				{"text": "Checking the weather."},
+				{"functionCall": {"name": "get_weather", "args": {"city": "Paris"}}, "thoughtSignature": "sig-1"}
+			]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 5, "thoughtsTokenCount": 3, "totalTokenCount": 20}
+		}`)
+	})
+
+	p, err := newGeminiProvider("g-key", srv.URL+"/v1beta/", "gemini/gemini-2.5-pro", 256, 0.2,
+		map[string]string{"dangerous_content": "block_only_high"}, nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newGeminiProvider failed: %v", err)
+	}
+
+	tools := []map[string]interface{}{{
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":        "get_weather",
+			"description": "Weather lookup",
+			"parameters": map[string]interface{}{
+				"type":                 "object",
+				"$schema":              "http://json-schema.org/draft-07/schema#",
+				"additionalProperties": false,
+				"properties": map[string]interface{}{
+					"city": map[string]interface{}{"type": []interface{}{"string", "null"}},
+					"days": map[string]interface{}{"type": "integer", "enum": []interface{}{1, 3}},
+				},
+				"required": []interface{}{"city"},
+			},
+		},
+	}}
+	messages := []Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "Weather in Paris?"},
+	}
+	resp, err := p.Chat(context.Background(), messages, tools, "")
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Content != "Checking the weather." {
+		t.Fatalf("unexpected content %q", resp.Content)
+	}
+	if !resp.HasToolCalls || len(resp.ToolCalls) != 1 {
+		t.Fatalf("expected one tool call, got %+v", resp.ToolCalls)
+	}
+	call := resp.ToolCalls[0]
+	if call.Function.Name != "get_weather" || call.Function.Arguments != `{"city":"Paris"}` || call.ID == "" {
+		t.Fatalf("unexpected tool call %+v", call)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 12 || resp.Usage.CompletionTokens != 8 || resp.Usage.TotalTokens != 20 {
+		t.Fatalf("unexpected usage %+v", resp.Usage)
+	}
+
+	first := (*recorded)[0]
+	if first.Path != "/v1beta/models/gemini-2.5-pro:generateContent" || first.Key != "g-key" {
+		t.Fatalf("unexpected endpoint %s key=%q", first.Path, first.Key)
+	}
+	system := first.Body["systemInstruction"].(map[string]interface{})
+	if text := system["parts"].([]interface{})[0].(map[string]interface{})["text"]; text != "You are helpful." {
+		t.Fatalf("unexpected system instruction %v", system)
+	}
+	safety := first.Body["safetySettings"].([]interface{})[0].(map[string]interface{})
+	if safety["category"] != "HARM_CATEGORY_DANGEROUS_CONTENT" || safety["threshold"] != "BLOCK_ONLY_HIGH" {
+		t.Fatalf("unexpected safety settings %v", safety)
+	}
+	generation := first.Body["generationConfig"].(map[string]interface{})
+	if generation["maxOutputTokens"] != float64(256) {
+		t.Fatalf("unexpected generation config %v", generation)
+	}
+	decl := first.Body["tools"].([]interface{})[0].(map[string]interface{})["functionDeclarations"].([]interface{})[0].(map[string]interface{})
+	params := decl["parameters"].(map[string]interface{})
+	if _, ok := params["additionalProperties"]; ok {
+		t.Fatalf("additionalProperties should be stripped: %v", params)
+	}
+	if _, ok := params["$schema"]; ok {
+		t.Fatalf("$schema should be stripped: %v", params)
+	}
+	props := params["properties"].(map[string]interface{})
+	city := props["city"].(map[string]interface{})
+	if city["type"] != "string" || city["nullable"] != true {
+		t.Fatalf("expected nullable string, got %v", city)
+	}
+	days := props["days"].(map[string]interface{})
+	if enum := days["enum"].([]interface{}); enum[0] != "1" {
+		t.Fatalf("expected string enum values, got %v", enum)
+	}
+
+	// 第二轮：工具结果以 functionResponse 回传，functionCall 带回 thoughtSignature
+	messages = append(messages,
+		Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls},
+		Message{Role: "tool", ToolCallID: call.ID, Content: "sunny, 21C"},
+	)
+	if _, err := p.Chat(context.Background(), messages, tools, ""); err != nil {
+		t.Fatalf("second Chat failed: %v", err)
+	}
+	contents := (*recorded)[1].Body["contents"].([]interface{})
+	if len(contents) != 3 {
+		t.Fatalf("expected user/model/user contents, got %d", len(contents))
+	}
+	model := contents[1].(map[string]interface{})
+	if model["role"] != "model" {
+		t.Fatalf("expected model role, got %v", model["role"])
+	}
+	fcPart := model["parts"].([]interface{})[1].(map[string]interface{})
+	if fcPart["thoughtSignature"] != "sig-1" {
+		t.Fatalf("expected thought signature to be replayed, got %v", fcPart)
+	}
+	if args := fcPart["functionCall"].(map[string]interface{})["args"].(map[string]interface{}); args["city"] != "Paris" {
+		t.Fatalf("unexpected function call args %v", args)
+	}
+	fr := contents[2].(map[string]interface{})["parts"].([]interface{})[0].(map[string]interface{})["functionResponse"].(map[string]interface{})
+	if fr["name"] != "get_weather" || fr["response"].(map[string]interface{})["content"] != "sunny, 21C" {
+		t.Fatalf("unexpected function response %v", fr)
+	}
+}
+
+func TestGeminiChatSendsInlineImagesAndPDFs(t *testing.T) {
+	srv, recorded := newGeminiTestServer(t, func(w http.ResponseWriter, req geminiRecordedRequest) {
+		_, _ = io.WriteString(w, `{"candidates":[{"content":{"parts":[{"text":"ok"}]}}]}`)
+	})
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(pdfPath, []byte("%PDF-1.4 test"), 0o644); err != nil {
+		t.Fatalf("write pdf: %v", err)
+	}
+
+	p, err := newGeminiProvider("g-key", srv.URL, "gemini-2.5-flash", 64, 0, nil, nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newGeminiProvider failed: %v", err)
+	}
+	pngData := base64.StdEncoding.EncodeToString([]byte("png-bytes"))
+	msg := Message{Role: "user", Parts: []ContentPart{
+		{Type: "text", Text: "Compare these"},
+		{Type: "image", ImageURL: "data:image/png;base64," + pngData},
+		{Type: "file", ImagePath: pdfPath, MimeType: "application/pdf"},
+		{Type: "image", ImageURL: "https://example.com/cat.webp"},
+	}}
+	if _, err := p.Chat(context.Background(), []Message{msg}, nil, ""); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	body := (*recorded)[0].Body
+	if _, ok := body["tools"]; ok {
+		t.Fatalf("tools should be omitted when none are given")
+	}
+	parts := body["contents"].([]interface{})[0].(map[string]interface{})["parts"].([]interface{})
+	if len(parts) != 4 {
+		t.Fatalf("expected 4 parts, got %v", parts)
+	}
+	image := parts[1].(map[string]interface{})["inlineData"].(map[string]interface{})
+	if image["mimeType"] != "image/png" || image["data"] != pngData {
+		t.Fatalf("unexpected image part %v", image)
+	}
+	pdf := parts[2].(map[string]interface{})["inlineData"].(map[string]interface{})
+	if pdf["mimeType"] != "application/pdf" || pdf["data"] != base64.StdEncoding.EncodeToString([]byte("%PDF-1.4 test")) {
+		t.Fatalf("unexpected pdf part %v", pdf)
+	}
+	remote := parts[3].(map[string]interface{})["fileData"].(map[string]interface{})
+	if remote["fileUri"] != "https://example.com/cat.webp" || remote["mimeType"] != "image/webp" {
+		t.Fatalf("unexpected file part %v", remote)
+	}
+}
+
+type geminiStreamRecorder struct {
+	content   strings.Builder
+	calls     []string
+	args      []string
+	completed bool
+	err       error
+	usage     *Usage
+}
+
+func (r *geminiStreamRecorder) OnContent(token string)           { r.content.WriteString(token) }
+func (r *geminiStreamRecorder) OnToolCallStart(id, name string)  { r.calls = append(r.calls, name) }
+func (r *geminiStreamRecorder) OnToolCallDelta(id, delta string) { r.args = append(r.args, delta) }
+func (r *geminiStreamRecorder) OnToolCallEnd(id string)          {}
+func (r *geminiStreamRecorder) OnComplete()                      { r.completed = true }
+func (r *geminiStreamRecorder) OnError(err error)                { r.err = err }
+func (r *geminiStreamRecorder) OnUsage(usage Usage)              { r.usage = &usage }
+
+func TestGeminiChatStreamParsesSSE(t *testing.T) {
+	srv, recorded := newGeminiTestServer(t, func(w http.ResponseWriter, req geminiRecordedRequest) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Hel\"}]}}]}\n\n")
+		_, _ = io.WriteString(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"lo\"},{\"functionCall\":{\"name\":\"read_file\",\"args\":{\"path\":\"a.txt\"}}}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":4,\"candidatesTokenCount\":2,\"totalTokenCount\":6}}\n\n")
+	})
+
+	p, err := newGeminiProvider("g-key", srv.URL, "gemini-2.5-flash", 64, 0, nil, nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newGeminiProvider failed: %v", err)
+	}
+	recorder := &geminiStreamRecorder{}
+	if err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "", recorder); err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if got := (*recorded)[0]; got.Path != "/models/gemini-2.5-flash:streamGenerateContent" || got.Query != "alt=sse" {
+		t.Fatalf("unexpected stream endpoint %s?%s", got.Path, got.Query)
+	}
+	if recorder.content.String() != "Hello" || !recorder.completed || recorder.err != nil {
+		t.Fatalf("unexpected stream result content=%q completed=%v err=%v", recorder.content.String(), recorder.completed, recorder.err)
+	}
+	if len(recorder.calls) != 1 || recorder.calls[0] != "read_file" || recorder.args[0] != `{"path":"a.txt"}` {
+		t.Fatalf("unexpected tool calls %v %v", recorder.calls, recorder.args)
+	}
+	if recorder.usage == nil || recorder.usage.TotalTokens != 6 {
+		t.Fatalf("unexpected usage %+v", recorder.usage)
+	}
+}
+
+func TestGeminiErrorsKeepStatusAndBody(t *testing.T) {
+	srv, _ := newGeminiTestServer(t, func(w http.ResponseWriter, req geminiRecordedRequest) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `[{"error": {"code": 400, "message": "API key not valid.", "status": "INVALID_ARGUMENT",
+			"details": [{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "API_KEY_INVALID"}]}}]`)
+	})
+	p, err := newGeminiProvider("bad", srv.URL, "gemini-2.5-flash", 64, 0, nil, nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newGeminiProvider failed: %v", err)
+	}
+	_, err = p.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "status code 400") || !strings.Contains(msg, `"reason":"API_KEY_INVALID"`) || !strings.Contains(msg, "provider=gemini") {
+		t.Fatalf("unexpected error %q", msg)
+	}
+
+	blocked, _ := newGeminiTestServer(t, func(w http.ResponseWriter, req geminiRecordedRequest) {
+		_, _ = io.WriteString(w, `{"promptFeedback": {"blockReason": "SAFETY"}}`)
+	})
+	p, _ = newGeminiProvider("g-key", blocked.URL, "gemini-2.5-flash", 64, 0, nil, nil, blocked.Client())
+	_, err = p.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "")
+	if err == nil || !strings.Contains(err.Error(), "blocked by safety") {
+		t.Fatalf("expected safety block error, got %v", err)
+	}
+
+	if _, err := NewGeminiProvider("g-key", "", "gemini-2.5-flash", 64, 0, map[string]string{"violence": "BLOCK_NONE"}, nil); err == nil {
+		t.Fatalf("expected unknown safety category to be rejected")
+	}
+}
+
+func TestListGeminiModelsFiltersGenerateContent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-goog-api-key") != "g-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("pageToken") == "" {
+			_, _ = io.WriteString(w, `{"models":[{"name":"models/gemini-2.5-pro","supportedGenerationMethods":["generateContent","countTokens"]},{"name":"models/text-embedding-004","supportedGenerationMethods":["embedContent"]}],"nextPageToken":"p2"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"models":[{"name":"models/gemini-2.5-flash","supportedGenerationMethods":["generateContent"]}]}`)
+	}))
+	defer srv.Close()
+
+	models, err := ListGeminiModels(context.Background(), "g-key", srv.URL)
+	if err != nil {
+		t.Fatalf("ListGeminiModels failed: %v", err)
+	}
+	if strings.Join(models, ",") != "gemini-2.5-pro,gemini-2.5-flash" {
+		t.Fatalf("unexpected models %v", models)
+	}
+	if _, err := ListGeminiModels(context.Background(), "bad", srv.URL); err == nil || !strings.Contains(err.Error(), "status code 401") {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+}
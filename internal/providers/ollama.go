@@ -0,0 +1,712 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultOllamaAPIBase = "http://localhost:11434"
+	// maxOllamaNumCtx 请求的上下文窗口上限；Ollama 按 num_ctx 分配显存，模型声明的 128K 往往跑不起来
+	maxOllamaNumCtx = 32768
+	// ollamaProbeTimeout 探测 /api/show 的超时，本地服务不可用时不阻塞启动
+	ollamaProbeTimeout = 3 * time.Second
+	// ollamaProbeBackoff 探测失败后的首次重试间隔，之后逐次加倍，最长 ollamaProbeMaxBackoff
+	ollamaProbeBackoff    = 5 * time.Second
+	ollamaProbeMaxBackoff = 5 * time.Minute
+)
+
+// OllamaClient Ollama 原生 API 客户端（模型列表、详情、拉取）
+type OllamaClient struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// NewOllamaClient 创建客户端，apiBase 为空时使用 http://localhost:11434
+func NewOllamaClient(apiBase, apiKey string) *OllamaClient {
+	return &OllamaClient{
+		BaseURL:    normalizeOllamaBaseURL(apiBase),
+		APIKey:     strings.TrimSpace(apiKey),
+		HTTPClient: &http.Client{},
+	}
+}
+
+// normalizeOllamaBaseURL 去掉 OpenAI 兼容路径 /v1 和 /api 后缀
+func normalizeOllamaBaseURL(apiBase string) string {
+	trimmed := strings.TrimRight(strings.TrimSpace(apiBase), "/")
+	trimmed = strings.TrimSuffix(trimmed, "/v1")
+	trimmed = strings.TrimSuffix(trimmed, "/api")
+	if trimmed == "" {
+		return defaultOllamaAPIBase
+	}
+	return trimmed
+}
+
+// OllamaModel /api/tags 中的本地模型
+type OllamaModel struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+	Details    struct {
+		Family            string `json:"family"`
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+}
+
+// OllamaModelInfo /api/show 解析出的模型能力
+type OllamaModelInfo struct {
+	Name          string
+	Architecture  string
+	ContextLength int
+	Capabilities  []string
+}
+
+// Has 是否具备某项能力（completion、vision、tools、thinking 等）
+func (i OllamaModelInfo) Has(capability string) bool {
+	for _, c := range i.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// ListModels 列出本地已安装的模型
+func (c *OllamaClient) ListModels(ctx context.Context) ([]OllamaModel, error) {
+	var out struct {
+		Models []OllamaModel `json:"models"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/tags", nil, &out); err != nil {
+		return nil, err
+	}
+	sort.Slice(out.Models, func(i, j int) bool { return out.Models[i].Name < out.Models[j].Name })
+	return out.Models, nil
+}
+
+// ShowModel 读取模型详情，并记录到能力表供 SupportsImageInput 与上下文长度查询使用
+func (c *OllamaClient) ShowModel(ctx context.Context, model string) (*OllamaModelInfo, error) {
+	name := ollamaModelName(model)
+	var out struct {
+		Capabilities []string               `json:"capabilities"`
+		ModelInfo    map[string]interface{} `json:"model_info"`
+		Template     string                 `json:"template"`
+		Details      struct {
+			Family   string   `json:"family"`
+			Families []string `json:"families"`
+		} `json:"details"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/show", map[string]string{"model": name}, &out); err != nil {
+		return nil, err
+	}
+
+	info := &OllamaModelInfo{Name: name, Capabilities: out.Capabilities}
+	info.Architecture, _ = out.ModelInfo["general.architecture"].(string)
+	if v, ok := out.ModelInfo[info.Architecture+".context_length"].(float64); ok {
+		info.ContextLength = int(v)
+	} else {
+		for key, value := range out.ModelInfo {
+			if v, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") {
+				info.ContextLength = int(v)
+				break
+			}
+		}
+	}
+
+	// 旧版 Ollama 不返回 capabilities，按模型结构推断
+	if len(info.Capabilities) == 0 {
+		info.Capabilities = []string{"completion"}
+		vision := false
+		for key := range out.ModelInfo {
+			if strings.Contains(key, ".vision.") {
+				vision = true
+				break
+			}
+		}
+		for _, family := range out.Details.Families {
+			if family == "clip" || family == "mllama" {
+				vision = true
+			}
+		}
+		if vision {
+			info.Capabilities = append(info.Capabilities, "vision")
+		}
+		if strings.Contains(out.Template, ".Tools") {
+			info.Capabilities = append(info.Capabilities, "tools")
+		}
+	}
+
+	RecordModelCapabilities("ollama", name, ModelCapabilities{
+		ContextLength: effectiveOllamaContext(info.ContextLength),
+		Vision:        info.Has("vision"),
+		Tools:         info.Has("tools"),
+		Thinking:      info.Has("thinking"),
+	})
+	return info, nil
+}
+
+// OllamaPullProgress 拉取进度
+type OllamaPullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+}
+
+// PullModel 下载模型，progress 在每条进度消息时回调
+func (c *OllamaClient) PullModel(ctx context.Context, model string, progress func(OllamaPullProgress)) error {
+	payload, err := json.Marshal(map[string]interface{}{"model": ollamaModelName(model), "stream": true})
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/pull", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapTransportError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return formatOllamaError(body, resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var event struct {
+			OllamaPullProgress
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(line, &event); err != nil {
+			return fmt.Errorf("pull decode error: %w", err)
+		}
+		if event.Error != "" {
+			return fmt.Errorf("pull failed: %s", event.Error)
+		}
+		if progress != nil {
+			progress(event.OllamaPullProgress)
+		}
+	}
+	return scanner.Err()
+}
+
+func (c *OllamaClient) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" && c.APIKey != LocalAPIKey {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	return req, nil
+}
+
+func (c *OllamaClient) do(ctx context.Context, method, path string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapTransportError(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return formatOllamaError(data, resp.StatusCode)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// wrapTransportError 连接被拒绝时提示启动 Ollama
+func (c *OllamaClient) wrapTransportError(err error) error {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("cannot reach Ollama at %s (is `ollama serve` running?): %w", c.BaseURL, err)
+	}
+	return err
+}
+
+// OllamaProvider 使用 Ollama 原生 /api/chat
+type OllamaProvider struct {
+	client             *OllamaClient
+	streamClient       *http.Client
+	defaultModel       string
+	maxTokens          int
+	temperature        float64
+	supportsImageInput func(model string) bool
+	reasoning          func(model string) ReasoningConfig
+
+	mu      sync.Mutex
+	callSeq int
+	probes  map[string]*ollamaProbe
+}
+
+// ollamaProbe 单个模型的 /api/show 探测状态
+type ollamaProbe struct {
+	done     bool
+	inFlight bool
+	failures int
+	retryAt  time.Time
+}
+
+// NewOllamaProvider 创建 Ollama 提供商；apiKey 可为空（经反向代理或 ollama.com 时使用）
+func NewOllamaProvider(apiKey, apiBase, defaultModel string, maxTokens int, temperature float64, supportsImageInput func(model string) bool) (*OllamaProvider, error) {
+	return newOllamaProvider(apiKey, apiBase, defaultModel, maxTokens, temperature, supportsImageInput, nil)
+}
+
+func newOllamaProvider(apiKey, apiBase, defaultModel string, maxTokens int, temperature float64, supportsImageInput func(model string) bool, httpClient *http.Client) (*OllamaProvider, error) {
+	if strings.TrimSpace(defaultModel) == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	if maxTokens <= 0 {
+		maxTokens = 1
+	}
+	client := NewOllamaClient(apiBase, apiKey)
+	streamClient := httpClient
+	if httpClient != nil {
+		client.HTTPClient = httpClient
+	} else {
+		// 首次请求需要加载模型，本地大模型可能耗时数分钟
+		client.HTTPClient = &http.Client{Timeout: 10 * time.Minute}
+		streamClient = &http.Client{}
+	}
+	return &OllamaProvider{
+		client:             client,
+		streamClient:       streamClient,
+		defaultModel:       defaultModel,
+		maxTokens:          maxTokens,
+		temperature:        temperature,
+		supportsImageInput: supportsImageInput,
+		probes:             make(map[string]*ollamaProbe),
+	}, nil
+}
+
+// Chat 发送非流式 /api/chat 请求
+func (p *OllamaProvider) Chat(ctx context.Context, messages []Message, tools []map[string]interface{}, model string) (*Response, error) {
+	model = p.resolveModel(model)
+	payload, err := json.Marshal(p.buildRequest(messages, tools, model, false))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	body, err := p.doRequest(ctx, p.client.HTTPClient, payload)
+	if err != nil {
+		return nil, p.wrapModelRequestError("chat request failed", model, err)
+	}
+	defer body.Close()
+
+	var resp ollamaChatResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, p.wrapModelRequestError("chat request failed", model, fmt.Errorf("failed to decode response: %w", err))
+	}
+	if resp.Error != "" {
+		return nil, p.wrapModelRequestError("chat request failed", model, fmt.Errorf("ollama API error: %s", resp.Error))
+	}
+
+	result := &Response{Content: resp.Message.Content, Usage: resp.usage()}
+	if resp.Message.Thinking != "" {
+		result.Reasoning = []ReasoningBlock{{Text: resp.Message.Thinking}}
+	}
+	for _, call := range resp.Message.ToolCalls {
+		result.ToolCalls = append(result.ToolCalls, p.toolCall(call))
+	}
+	result.HasToolCalls = len(result.ToolCalls) > 0
+	return result, nil
+}
+
+// ChatStream 发送流式 /api/chat 请求（NDJSON）
+func (p *OllamaProvider) ChatStream(ctx context.Context, messages []Message, tools []map[string]interface{}, model string, handler StreamHandler) error {
+	model = p.resolveModel(model)
+	payload, err := json.Marshal(p.buildRequest(messages, tools, model, true))
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	stream, err := p.doRequest(ctx, p.streamClient, payload)
+	if err != nil {
+		wrappedErr := p.wrapModelRequestError("stream request failed", model, err)
+		handler.OnError(wrappedErr)
+		return wrappedErr
+	}
+	defer stream.Close()
+
+	fail := func(err error) error {
+		wrappedErr := p.wrapModelRequestError("stream read failed", model, err)
+		handler.OnError(wrappedErr)
+		return wrappedErr
+	}
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+	var usage *Usage
+	reasoning := newReasoningStream(handler)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fail(fmt.Errorf("stream decode error: %w", err))
+		}
+		if chunk.Error != "" {
+			return fail(fmt.Errorf("ollama API error: %s", chunk.Error))
+		}
+		reasoning.delta(chunk.Message.Thinking)
+		if chunk.Message.Content != "" {
+			reasoning.flush("")
+			handler.OnContent(chunk.Message.Content)
+		}
+		// Ollama 的工具调用在单个分片内完整给出
+		for _, raw := range chunk.Message.ToolCalls {
+			call := p.toolCall(raw)
+			handler.OnToolCallStart(call.ID, call.Function.Name)
+			handler.OnToolCallDelta(call.ID, call.Function.Arguments)
+			handler.OnToolCallEnd(call.ID)
+		}
+		if chunk.Done {
+			usage = chunk.usage()
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fail(fmt.Errorf("stream error: %w", err))
+	}
+
+	reasoning.flush("")
+	if usageHandler, ok := handler.(UsageHandler); ok && usage != nil {
+		usageHandler.OnUsage(*usage)
+	}
+	handler.OnComplete()
+	return nil
+}
+
+// GetDefaultModel 默认模型
+func (p *OllamaProvider) GetDefaultModel() string {
+	return p.defaultModel
+}
+
+// SupportsImageInput 先探测 /api/show 的 vision 能力，再交给配置判断（显式配置优先）
+func (p *OllamaProvider) SupportsImageInput(model string) bool {
+	model = p.resolveModel(model)
+	p.DetectCapabilities(context.Background(), model)
+	if p.supportsImageInput != nil {
+		return p.supportsImageInput(model)
+	}
+	return SupportsImageInput("ollama", model)
+}
+
+// DetectCapabilities 每个模型探测成功一次即可；失败时退回名称推断，并按退避间隔在之后的调用中重试
+func (p *OllamaProvider) DetectCapabilities(ctx context.Context, model string) {
+	name := ollamaModelName(model)
+	p.mu.Lock()
+	probe := p.probes[name]
+	if probe == nil {
+		probe = &ollamaProbe{}
+		p.probes[name] = probe
+	}
+	if probe.done || probe.inFlight || time.Now().Before(probe.retryAt) {
+		p.mu.Unlock()
+		return
+	}
+	probe.inFlight = true
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, ollamaProbeTimeout)
+	defer cancel()
+	_, err := p.client.ShowModel(ctx, name)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	probe.inFlight = false
+	if err == nil {
+		probe.done = true
+		return
+	}
+	backoff := ollamaProbeBackoff << probe.failures
+	if backoff <= 0 || backoff > ollamaProbeMaxBackoff {
+		backoff = ollamaProbeMaxBackoff
+	}
+	probe.failures++
+	probe.retryAt = time.Now().Add(backoff)
+}
+
+func (p *OllamaProvider) resolveModel(model string) string {
+	if strings.TrimSpace(model) == "" {
+		model = p.defaultModel
+	}
+	return model
+}
+
+func (p *OllamaProvider) wrapModelRequestError(prefix, model string, err error) error {
+	return fmt.Errorf("%s provider=ollama model=%s api_base=%s: %w", prefix, model, p.client.BaseURL, err)
+}
+
+func (p *OllamaProvider) toolCall(call ollamaToolCall) ToolCall {
+	p.mu.Lock()
+	p.callSeq++
+	id := fmt.Sprintf("ollama_call_%d_%d", time.Now().UnixNano(), p.callSeq)
+	p.mu.Unlock()
+
+	args := "{}"
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, call.Function.Arguments); err == nil && compact.String() != "null" {
+		args = compact.String()
+	}
+	return ToolCall{
+		ID:       id,
+		Type:     "function",
+		Function: ToolCallFunction{Name: call.Function.Name, Arguments: args},
+	}
+}
+
+func (p *OllamaProvider) buildRequest(messages []Message, tools []map[string]interface{}, model string, stream bool) ollamaChatRequest {
+	p.DetectCapabilities(context.Background(), model)
+	caps, known := LookupModelCapabilities("ollama", model)
+
+	req := ollamaChatRequest{
+		Model:    ollamaModelName(model),
+		Messages: convertToOllamaMessages(messages, p.SupportsImageInput(model)),
+		Stream:   stream,
+		Options: ollamaOptions{
+			NumPredict:  p.maxTokens,
+			Temperature: &p.temperature,
+		},
+	}
+	if known && caps.ContextLength > 0 {
+		req.Options.NumCtx = caps.ContextLength
+	}
+	// 不支持工具的模型带上 tools 会直接报错
+	if len(tools) > 0 && (!known || caps.Tools) {
+		req.Tools = tools
+	}
+	// think 同理，仅对具备 thinking 能力的模型开启
+	if cfg := lookupReasoning(p.reasoning, model); cfg.Enabled() && (!known || caps.Thinking) {
+		req.Think = ollamaThink(model, cfg)
+	}
+	return req
+}
+
+// ollamaThink gpt-oss 只接受 low/medium/high 强度，其余模型为布尔开关
+func ollamaThink(model string, cfg ReasoningConfig) interface{} {
+	if !strings.Contains(strings.ToLower(model), "gpt-oss") {
+		return true
+	}
+	if effort := cfg.EffortLevel(); effort != "minimal" {
+		return effort
+	}
+	return "low"
+}
+
+// convertToOllamaMessages 工具参数以对象回传，工具结果带 tool_name，图片以 base64 放入 images
+func convertToOllamaMessages(messages []Message, allowImages bool) []ollamaMessage {
+	out := make([]ollamaMessage, 0, len(messages))
+	toolNames := make(map[string]string)
+	for _, msg := range messages {
+		om := ollamaMessage{Role: msg.Role, Content: flattenContentParts(msg)}
+		switch msg.Role {
+		case "assistant":
+			for _, call := range msg.ToolCalls {
+				toolNames[call.ID] = call.Function.Name
+				om.ToolCalls = append(om.ToolCalls, ollamaToolCall{Function: ollamaFunctionCall{
+					Name:      call.Function.Name,
+					Arguments: toolArgumentsJSON(call.Function.Arguments),
+				}})
+			}
+		case "tool":
+			om.ToolName = toolNames[msg.ToolCallID]
+		case "user":
+			if allowImages {
+				om.Content, om.Images = ollamaUserContent(msg)
+			}
+		}
+		out = append(out, om)
+	}
+	return out
+}
+
+func ollamaUserContent(msg Message) (string, []string) {
+	if len(msg.Parts) == 0 {
+		return msg.Content, nil
+	}
+	var texts []string
+	var images []string
+	for _, part := range msg.Parts {
+		if part.Type == "text" || (part.ImagePath == "" && part.ImageURL == "") {
+			if strings.TrimSpace(part.Text) != "" {
+				texts = append(texts, part.Text)
+			}
+			continue
+		}
+		dataURL := buildInlineDataURL(part)
+		if dataURL == "" {
+			dataURL = strings.TrimSpace(part.ImageURL)
+		}
+		// Ollama 只接受 base64 图片，不会下载远程链接
+		if mimeType, data, ok := parseImageDataURL(dataURL); ok && strings.HasPrefix(mimeType, "image/") {
+			images = append(images, data)
+		}
+	}
+	if len(texts) == 0 {
+		return msg.Content, images
+	}
+	return strings.Join(texts, "\n"), images
+}
+
+func (p *OllamaProvider) doRequest(ctx context.Context, client *http.Client, payload []byte) (io.ReadCloser, error) {
+	const maxRetries = 3
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+		req, err := p.client.newRequest(ctx, http.MethodPost, "/api/chat", bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, p.client.wrapTransportError(err)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			lastErr = formatOllamaError(body, resp.StatusCode)
+			if resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusTooManyRequests {
+				continue
+			}
+			return nil, lastErr
+		}
+		return resp.Body, nil
+	}
+	return nil, fmt.Errorf("failed after %d attempts: %w", maxRetries, lastErr)
+}
+
+// formatOllamaError Ollama 的错误体为 {"error": "..."}
+func formatOllamaError(body []byte, status int) error {
+	var payload struct {
+		Error string `json:"error"`
+	}
+	message := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error != "" {
+		message = payload.Error
+	}
+	if len(message) > 500 {
+		message = message[:500] + "..."
+	}
+	return fmt.Errorf("ollama API error (status code %d): %s", status, message)
+}
+
+// ollamaModelName 去掉 ollama/ 前缀；未写标签时补 :latest
+func ollamaModelName(model string) string {
+	name := normalizeModelForProvider("ollama", model)
+	if !strings.Contains(name, ":") {
+		name += ":latest"
+	}
+	return name
+}
+
+func effectiveOllamaContext(contextLength int) int {
+	if contextLength <= 0 {
+		return 0
+	}
+	return min(contextLength, maxOllamaNumCtx)
+}
+
+// ---- Ollama request/response structs ----
+
+type ollamaChatRequest struct {
+	Model    string                   `json:"model"`
+	Messages []ollamaMessage          `json:"messages"`
+	Tools    []map[string]interface{} `json:"tools,omitempty"`
+	Stream   bool                     `json:"stream"`
+	Options  ollamaOptions            `json:"options"`
+	Think    interface{}              `json:"think,omitempty"`
+}
+
+type ollamaOptions struct {
+	NumPredict  int      `json:"num_predict,omitempty"`
+	NumCtx      int      `json:"num_ctx,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function ollamaFunctionCall `json:"function"`
+}
+
+type ollamaFunctionCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+func (r ollamaChatResponse) usage() *Usage {
+	if !r.Done || (r.PromptEvalCount == 0 && r.EvalCount == 0) {
+		return nil
+	}
+	return &Usage{
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+		TotalTokens:      r.PromptEvalCount + r.EvalCount,
+	}
+}
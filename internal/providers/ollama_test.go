@@ -0,0 +1,282 @@
+package providers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOllama 模拟本地 Ollama：/api/show 按模型返回能力，/api/chat 记录请求
+type fakeOllama struct {
+	t     *testing.T
+	chats []map[string]interface{}
+	reply func(w http.ResponseWriter, body map[string]interface{})
+}
+
+func (f *fakeOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := io.ReadAll(r.Body)
+	var body map[string]interface{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &body); err != nil {
+			f.t.Fatalf("invalid request body: %v", err)
+		}
+	}
+	switch r.URL.Path {
+	case "/api/show":
+		switch body["model"] {
+		case "llava:latest":
+			_, _ = io.WriteString(w, `{"capabilities":["completion","vision"],"model_info":{"general.architecture":"llama","llama.context_length":4096}}`)
+		case "qwen3:8b":
+			_, _ = io.WriteString(w, `{"capabilities":["completion","tools","thinking"],"model_info":{"general.architecture":"qwen3","qwen3.context_length":40960}}`)
+		case "legacy:latest":
+			_, _ = io.WriteString(w, `{"template":"{{ if .Tools }}...{{ end }}","model_info":{"general.architecture":"mllama","mllama.context_length":8192,"mllama.vision.image_size":560}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error":"model not found"}`)
+		}
+	case "/api/tags":
+		_, _ = io.WriteString(w, `{"models":[{"name":"qwen3:8b","size":5200000000,"details":{"parameter_size":"8.2B","quantization_level":"Q4_K_M"}},{"name":"llava:latest","size":4700000000}]}`)
+	case "/api/chat":
+		f.chats = append(f.chats, body)
+		f.reply(w, body)
+	case "/api/pull":
+		_, _ = io.WriteString(w, "{\"status\":\"pulling manifest\"}\n{\"status\":\"pulling abc\",\"total\":100,\"completed\":50}\n{\"status\":\"success\"}\n")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestOllamaChatUsesCapabilitiesAndToolCalls(t *testing.T) {
+	fake := &fakeOllama{t: t, reply: func(w http.ResponseWriter, body map[string]interface{}) {
+		_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"read_file","arguments":{"path":"notes.md"}}}]},"done":true,"prompt_eval_count":30,"eval_count":7}`)
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	p, err := newOllamaProvider("", srv.URL+"/v1", "ollama/qwen3:8b", 512, 0.1, nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newOllamaProvider failed: %v", err)
+	}
+	tools := []map[string]interface{}{{"type": "function", "function": map[string]interface{}{"name": "read_file"}}}
+	resp, err := p.Chat(context.Background(), []Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "open notes"},
+	}, tools, "")
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Function.Arguments != `{"path":"notes.md"}` || resp.ToolCalls[0].ID == "" {
+		t.Fatalf("unexpected tool calls %+v", resp.ToolCalls)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 37 {
+		t.Fatalf("unexpected usage %+v", resp.Usage)
+	}
+
+	req := fake.chats[0]
+	if req["model"] != "qwen3:8b" || req["stream"] != false {
+		t.Fatalf("unexpected request %v", req)
+	}
+	options := req["options"].(map[string]interface{})
+	// 40960 超过上限，按 32768 请求
+	if options["num_ctx"] != float64(maxOllamaNumCtx) || options["num_predict"] != float64(512) {
+		t.Fatalf("unexpected options %v", options)
+	}
+	if _, ok := req["tools"]; !ok {
+		t.Fatalf("tools should be sent to a tool-capable model")
+	}
+	caps, ok := LookupModelCapabilities("ollama", "ollama/qwen3:8b")
+	if !ok || caps.ContextLength != maxOllamaNumCtx || !caps.Tools || caps.Vision {
+		t.Fatalf("unexpected capabilities %+v ok=%v", caps, ok)
+	}
+
+	// 工具结果回传：参数为对象，结果带 tool_name
+	_, err = p.Chat(context.Background(), []Message{
+		{Role: "user", Content: "open notes"},
+		{Role: "assistant", ToolCalls: resp.ToolCalls},
+		{Role: "tool", ToolCallID: resp.ToolCalls[0].ID, Content: "# Notes"},
+	}, tools, "")
+	if err != nil {
+		t.Fatalf("second Chat failed: %v", err)
+	}
+	messages := fake.chats[1]["messages"].([]interface{})
+	assistant := messages[1].(map[string]interface{})
+	args := assistant["tool_calls"].([]interface{})[0].(map[string]interface{})["function"].(map[string]interface{})["arguments"].(map[string]interface{})
+	if args["path"] != "notes.md" {
+		t.Fatalf("unexpected replayed arguments %v", args)
+	}
+	if tool := messages[2].(map[string]interface{}); tool["tool_name"] != "read_file" || tool["content"] != "# Notes" {
+		t.Fatalf("unexpected tool message %v", tool)
+	}
+}
+
+func TestOllamaVisionModelGetsImagesButNoTools(t *testing.T) {
+	fake := &fakeOllama{t: t, reply: func(w http.ResponseWriter, body map[string]interface{}) {
+		_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":"a cat"},"done":true}`)
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	p, err := newOllamaProvider("", srv.URL, "ollama/llava", 64, 0, nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newOllamaProvider failed: %v", err)
+	}
+	if !p.SupportsImageInput("") {
+		t.Fatalf("llava should support images after probing")
+	}
+	image := base64.StdEncoding.EncodeToString([]byte("jpeg"))
+	tools := []map[string]interface{}{{"type": "function", "function": map[string]interface{}{"name": "exec"}}}
+	resp, err := p.Chat(context.Background(), []Message{{Role: "user", Parts: []ContentPart{
+		{Type: "text", Text: "what is this?"},
+		{Type: "image", ImageURL: "data:image/jpeg;base64," + image},
+	}}}, tools, "")
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Content != "a cat" {
+		t.Fatalf("unexpected content %q", resp.Content)
+	}
+	req := fake.chats[0]
+	if _, ok := req["tools"]; ok {
+		t.Fatalf("tools must be omitted for a model without tool support")
+	}
+	if req["options"].(map[string]interface{})["num_ctx"] != float64(4096) {
+		t.Fatalf("unexpected num_ctx %v", req["options"])
+	}
+	user := req["messages"].([]interface{})[0].(map[string]interface{})
+	if user["content"] != "what is this?" || user["images"].([]interface{})[0] != image {
+		t.Fatalf("unexpected user message %v", user)
+	}
+
+	// 旧版 Ollama 没有 capabilities 字段时按模型结构推断
+	info, err := NewOllamaClient(srv.URL, "").ShowModel(context.Background(), "legacy")
+	if err != nil {
+		t.Fatalf("ShowModel failed: %v", err)
+	}
+	if !info.Has("vision") || !info.Has("tools") || info.ContextLength != 8192 {
+		t.Fatalf("unexpected legacy info %+v", info)
+	}
+}
+
+func TestOllamaChatStreamParsesNDJSON(t *testing.T) {
+	fake := &fakeOllama{t: t, reply: func(w http.ResponseWriter, body map[string]interface{}) {
+		_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":"Hel"},"done":false}`+"\n")
+		_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":"lo","tool_calls":[{"function":{"name":"exec","arguments":{"command":"ls"}}}]},"done":false}`+"\n")
+		_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":5,"eval_count":3}`+"\n")
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	p, err := newOllamaProvider("", srv.URL, "ollama/qwen3:8b", 64, 0, nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newOllamaProvider failed: %v", err)
+	}
+	recorder := &geminiStreamRecorder{}
+	if err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "", recorder); err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if fake.chats[0]["stream"] != true {
+		t.Fatalf("expected stream=true")
+	}
+	if recorder.content.String() != "Hello" || !recorder.completed {
+		t.Fatalf("unexpected stream content %q completed=%v", recorder.content.String(), recorder.completed)
+	}
+	if len(recorder.calls) != 1 || recorder.args[0] != `{"command":"ls"}` {
+		t.Fatalf("unexpected tool calls %v %v", recorder.calls, recorder.args)
+	}
+	if recorder.usage == nil || recorder.usage.TotalTokens != 8 {
+		t.Fatalf("unexpected usage %+v", recorder.usage)
+	}
+}
+
+func TestOllamaClientListPullAndErrors(t *testing.T) {
+	fake := &fakeOllama{t: t, reply: func(w http.ResponseWriter, body map[string]interface{}) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":"model 'missing:latest' not found, try pulling it first"}`)
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client := NewOllamaClient(srv.URL+"/", "")
+	models, err := client.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 2 || models[0].Name != "llava:latest" || models[1].Details.ParameterSize != "8.2B" {
+		t.Fatalf("unexpected models %+v", models)
+	}
+
+	var statuses []string
+	if err := client.PullModel(context.Background(), "ollama/qwen3:8b", func(p OllamaPullProgress) {
+		statuses = append(statuses, p.Status)
+	}); err != nil {
+		t.Fatalf("PullModel failed: %v", err)
+	}
+	if strings.Join(statuses, "|") != "pulling manifest|pulling abc|success" {
+		t.Fatalf("unexpected pull progress %v", statuses)
+	}
+
+	p, _ := newOllamaProvider("", srv.URL, "ollama/missing", 64, 0, nil, srv.Client())
+	_, err = p.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "")
+	if err == nil || !strings.Contains(err.Error(), "status code 404") || !strings.Contains(err.Error(), "try pulling it first") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// 服务未启动时给出明确提示
+	closed := httptest.NewServer(http.NotFoundHandler())
+	base := closed.URL
+	closed.Close()
+	if _, err := NewOllamaClient(base, "").ListModels(context.Background()); err == nil || !strings.Contains(err.Error(), "ollama serve") {
+		t.Fatalf("expected connection hint, got %v", err)
+	}
+}
+
+func TestOllamaDetectCapabilitiesRetriesAfterFailure(t *testing.T) {
+	var shows int
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		shows++
+		n := shows
+		mu.Unlock()
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, `{"capabilities":["completion","tools"],"model_info":{"general.architecture":"llama","llama.context_length":8192}}`)
+	}))
+	defer srv.Close()
+
+	p, err := newOllamaProvider("", srv.URL, "retry-probe:latest", 512, 0.1, nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newOllamaProvider failed: %v", err)
+	}
+	p.DetectCapabilities(context.Background(), "retry-probe:latest")
+	p.DetectCapabilities(context.Background(), "retry-probe:latest")
+	if shows != 1 {
+		t.Fatalf("expected no retry within the backoff, got %d probes", shows)
+	}
+	if _, ok := LookupModelCapabilities("ollama", "retry-probe:latest"); ok {
+		t.Fatalf("failed probe should not record capabilities")
+	}
+
+	// 退避到期后重试，成功后不再探测
+	p.mu.Lock()
+	p.probes["retry-probe:latest"].retryAt = time.Time{}
+	p.mu.Unlock()
+	p.DetectCapabilities(context.Background(), "retry-probe:latest")
+	p.DetectCapabilities(context.Background(), "retry-probe:latest")
+	if shows != 2 {
+		t.Fatalf("expected exactly one retry, got %d probes", shows)
+	}
+	caps, ok := LookupModelCapabilities("ollama", "retry-probe:latest")
+	if !ok || !caps.Tools || caps.ContextLength != 8192 {
+		t.Fatalf("unexpected capabilities %+v ok=%v", caps, ok)
+	}
+}
@@ -33,6 +33,7 @@ type OpenAIProvider struct {
 	httpClient         *http.Client
 	streamClient       *http.Client
 	supportsImageInput func(model string) bool
+	reasoning          func(model string) ReasoningConfig
 }
 
 // NewOpenAIProvider 创建 OpenAI 提供商
@@ -79,6 +80,7 @@ func (p *OpenAIProvider) Chat(ctx context.Context, messages []Message, tools []m
 	normalizedModel := normalizeModelForProvider(providerName, model)
 
 	reqBody := buildChatRequest(messages, tools, normalizedModel, p.SupportsImageInput(model), false, p.maxTokens, p.temperature)
+	applyChatReasoning(&reqBody, providerName, lookupReasoning(p.reasoning, model))
 	payload, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode request: %w", err)
@@ -107,6 +109,9 @@ func (p *OpenAIProvider) Chat(ctx context.Context, messages []Message, tools []m
 	result := &Response{
 		Content: choice.Message.Content,
 	}
+	if reasoning := choice.Message.reasoningText(); reasoning != "" {
+		result.Reasoning = []ReasoningBlock{{Text: reasoning}}
+	}
 
 	if len(choice.Message.ToolCalls) > 0 {
 		result.HasToolCalls = true
@@ -152,6 +157,7 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []Message, too
 	normalizedModel := normalizeModelForProvider(providerName, model)
 
 	reqBody := buildChatRequest(messages, tools, normalizedModel, p.SupportsImageInput(model), true, p.maxTokens, p.temperature)
+	applyChatReasoning(&reqBody, providerName, lookupReasoning(p.reasoning, model))
 	payload, err := json.Marshal(reqBody)
 	if err != nil {
 		return fmt.Errorf("failed to encode request: %w", err)
@@ -170,6 +176,7 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []Message, too
 	defer stream.Close()
 
 	buildersByIndex := make(map[int]*toolCallBuilder)
+	reasoning := newReasoningStream(handler)
 
 	// Use a goroutine to read from stream so we can respond to context cancellation
 	lines := make(chan string, 100)
@@ -236,6 +243,7 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []Message, too
 				fmt.Printf("[DEBUG] FinishReason: %s\n", choice.FinishReason)
 			}
 
+			reasoning.delta(delta.reasoningText())
 			if delta.Content != "" {
 				handler.OnContent(delta.Content)
 			}
@@ -272,6 +280,7 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []Message, too
 
 complete:
 
+	reasoning.flush("")
 	for _, builder := range buildersByIndex {
 		if builder != nil && builder.Arguments != "" && builder.ID != "" {
 			handler.OnToolCallEnd(builder.ID)
@@ -291,7 +300,7 @@ func (p *OpenAIProvider) detectProvider(model string) string {
 	if normalizedModel != "" && strings.Contains(normalizedModel, "/") {
 		prefix := strings.SplitN(normalizedModel, "/", 2)[0]
 		switch prefix {
-		case "openrouter", "anthropic", "openai", "deepseek", "zhipu", "groq", "gemini", "dashscope", "moonshot", "minimax", "vllm":
+		case "openrouter", "anthropic", "openai", "deepseek", "zhipu", "groq", "gemini", "dashscope", "moonshot", "minimax", "vllm", "ollama":
 			return prefix
 		}
 	}
@@ -376,7 +385,21 @@ func buildChatRequest(messages []Message, tools []map[string]interface{}, model
 	return reqBody
 }
 
+// applyChatReasoning 设置推理强度：OpenRouter 使用 reasoning 对象，其余兼容接口使用 reasoning_effort
+func applyChatReasoning(reqBody *chatRequest, providerName string, cfg ReasoningConfig) {
+	effort := cfg.EffortLevel()
+	if effort == "" {
+		return
+	}
+	if providerName == "openrouter" {
+		reqBody.Reasoning = &chatReasoning{Effort: effort}
+		return
+	}
+	reqBody.ReasoningEffort = effort
+}
+
 // convertToChatMessages 转换消息格式为 OpenAI 兼容格式
+// 推理内容（reasoning_content）不回传：DeepSeek 等接口收到后会直接报错
 func convertToChatMessages(messages []Message, allowContentParts bool) []chatMessage {
 	result := make([]chatMessage, len(messages))
 	for i, msg := range messages {
@@ -676,6 +699,13 @@ type chatRequest struct {
 	Stream      bool                     `json:"stream,omitempty"`
 	MaxTokens   int                      `json:"max_tokens"`
 	Temperature float64                  `json:"temperature"`
+
+	ReasoningEffort string         `json:"reasoning_effort,omitempty"`
+	Reasoning       *chatReasoning `json:"reasoning,omitempty"`
+}
+
+type chatReasoning struct {
+	Effort string `json:"effort,omitempty"`
 }
 
 type chatMessage struct {
@@ -711,6 +741,7 @@ type chatResponse struct {
 		Message struct {
 			Content   string         `json:"content"`
 			ToolCalls []chatToolCall `json:"tool_calls,omitempty"`
+			chatReasoningFields
 		} `json:"message"`
 	} `json:"choices"`
 }
@@ -720,11 +751,25 @@ type chatStreamChunk struct {
 		Delta struct {
 			Content   string              `json:"content,omitempty"`
 			ToolCalls []chatToolCallDelta `json:"tool_calls,omitempty"`
+			chatReasoningFields
 		} `json:"delta"`
 		FinishReason string `json:"finish_reason,omitempty"`
 	} `json:"choices"`
 }
 
+// chatReasoningFields 兼容接口的推理字段：DeepSeek/Qwen/Kimi 使用 reasoning_content，OpenRouter/Ollama 使用 reasoning
+type chatReasoningFields struct {
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	Reasoning        string `json:"reasoning,omitempty"`
+}
+
+func (f chatReasoningFields) reasoningText() string {
+	if f.ReasoningContent != "" {
+		return f.ReasoningContent
+	}
+	return f.Reasoning
+}
+
 type chatToolCallDelta struct {
 	Index    int                  `json:"index"`
 	ID       string               `json:"id,omitempty"`
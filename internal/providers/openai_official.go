@@ -20,6 +20,7 @@ type OpenAIOfficialProvider struct {
 	maxTokens          int
 	temperature        float64
 	supportsImageInput func(model string) bool
+	reasoning          func(model string) ReasoningConfig
 }
 
 // NewOpenAIOfficialProvider creates an OpenAI provider backed by openai-go.
@@ -181,10 +182,16 @@ func (p *OpenAIOfficialProvider) buildChatParams(messages []Message, tools []map
 	normalizedModel := normalizeModelForProvider("openai", model)
 
 	params := openai.ChatCompletionNewParams{
-		Messages:    convertToOfficialOpenAIMessages(messages, p.SupportsImageInput(model)),
-		Model:       shared.ChatModel(normalizedModel),
-		MaxTokens:   openai.Int(int64(p.maxTokens)),
-		Temperature: openai.Float(p.temperature),
+		Messages: convertToOfficialOpenAIMessages(messages, p.SupportsImageInput(model)),
+		Model:    shared.ChatModel(normalizedModel),
+	}
+	if effort := lookupReasoning(p.reasoning, model).EffortLevel(); effort != "" {
+		// 推理模型（o 系列、gpt-5）只接受 max_completion_tokens，且不支持自定义 temperature
+		params.ReasoningEffort = shared.ReasoningEffort(effort)
+		params.MaxCompletionTokens = openai.Int(int64(p.maxTokens))
+	} else {
+		params.MaxTokens = openai.Int(int64(p.maxTokens))
+		params.Temperature = openai.Float(p.temperature)
 	}
 	if len(tools) > 0 {
 		params.Tools = convertToOfficialOpenAITools(tools)
@@ -0,0 +1,114 @@
+package providers
+
+import "strings"
+
+// minThinkingBudget Anthropic 扩展思考允许的最小预算
+const minThinkingBudget = 1024
+
+// ReasoningConfig 推理模型配置（按模型），Effort 与 BudgetTokens 均为空时不开启
+type ReasoningConfig struct {
+	// Effort 推理强度：minimal/low/medium/high，OpenAI 系与 Ollama 使用
+	Effort string `json:"effort,omitempty" mapstructure:"effort"`
+	// BudgetTokens 思考 token 预算，Anthropic/Gemini 使用；未设置时按 Effort 换算
+	BudgetTokens int `json:"budgetTokens,omitempty" mapstructure:"budgetTokens"`
+}
+
+// Enabled 是否开启推理
+func (c ReasoningConfig) Enabled() bool {
+	return c.EffortLevel() != "" || c.BudgetTokens > 0
+}
+
+// EffortLevel 返回推理强度，仅配置预算时按预算换算
+func (c ReasoningConfig) EffortLevel() string {
+	switch effort := strings.ToLower(strings.TrimSpace(c.Effort)); effort {
+	case "minimal", "low", "medium", "high":
+		return effort
+	}
+	switch {
+	case c.BudgetTokens <= 0:
+		return ""
+	case c.BudgetTokens <= 2048:
+		return "low"
+	case c.BudgetTokens <= 8192:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// Budget 返回思考 token 预算，仅配置强度时按强度换算
+func (c ReasoningConfig) Budget() int {
+	if c.BudgetTokens > 0 {
+		return c.BudgetTokens
+	}
+	switch c.EffortLevel() {
+	case "minimal":
+		return minThinkingBudget
+	case "low":
+		return 2048
+	case "medium":
+		return 8192
+	case "high":
+		return 24576
+	}
+	return 0
+}
+
+// WithReasoning 按模型设置推理配置，未开启的模型返回零值
+func WithReasoning(lookup func(model string) ReasoningConfig) ProviderOption {
+	return func(o *providerOptions) {
+		o.reasoning = lookup
+	}
+}
+
+func lookupReasoning(lookup func(model string) ReasoningConfig, model string) ReasoningConfig {
+	if lookup == nil {
+		return ReasoningConfig{}
+	}
+	return lookup(model)
+}
+
+// reasoningStream 汇总流式推理片段：片段转给可选的 ReasoningHandler，段落结束时生成 ReasoningBlock
+type reasoningStream struct {
+	handler ReasoningHandler
+	text    strings.Builder
+	blocks  []ReasoningBlock
+}
+
+func newReasoningStream(handler StreamHandler) *reasoningStream {
+	rh, _ := handler.(ReasoningHandler)
+	return &reasoningStream{handler: rh}
+}
+
+func (r *reasoningStream) delta(text string) {
+	if text == "" {
+		return
+	}
+	r.text.WriteString(text)
+	if r.handler != nil {
+		r.handler.OnReasoningDelta(text)
+	}
+}
+
+// flush 结束当前推理段落，signature 为空表示无需回传校验
+func (r *reasoningStream) flush(signature string) {
+	if r.text.Len() == 0 && signature == "" {
+		return
+	}
+	r.emit(ReasoningBlock{Text: r.text.String(), Signature: signature})
+	r.text.Reset()
+}
+
+func (r *reasoningStream) redacted(data string) {
+	if data == "" {
+		return
+	}
+	r.emit(ReasoningBlock{RedactedData: data})
+}
+
+func (r *reasoningStream) emit(block ReasoningBlock) {
+	r.blocks = append(r.blocks, block)
+	if r.handler != nil {
+		r.handler.OnReasoningBlock(block)
+	}
+}
@@ -0,0 +1,294 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// reasoningRecorder 在 geminiStreamRecorder 基础上记录推理片段与推理块
+type reasoningRecorder struct {
+	geminiStreamRecorder
+	reasoning strings.Builder
+	blocks    []ReasoningBlock
+}
+
+func (r *reasoningRecorder) OnReasoningDelta(delta string) { r.reasoning.WriteString(delta) }
+func (r *reasoningRecorder) OnReasoningBlock(block ReasoningBlock) {
+	r.blocks = append(r.blocks, block)
+}
+
+func fixedReasoning(cfg ReasoningConfig) func(string) ReasoningConfig {
+	return func(string) ReasoningConfig { return cfg }
+}
+
+// recordingServer 记录每次请求的 JSON 请求体，并按序号返回响应
+func recordingServer(t *testing.T, reply func(w http.ResponseWriter, n int)) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		bodies = append(bodies, body)
+		reply(w, len(bodies)-1)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies
+}
+
+func TestReasoningConfigConvertsEffortAndBudget(t *testing.T) {
+	cases := []struct {
+		cfg    ReasoningConfig
+		effort string
+		budget int
+	}{
+		{ReasoningConfig{}, "", 0},
+		{ReasoningConfig{Effort: "High"}, "high", 24576},
+		{ReasoningConfig{Effort: "bogus"}, "", 0},
+		{ReasoningConfig{BudgetTokens: 4000}, "medium", 4000},
+		{ReasoningConfig{Effort: "low", BudgetTokens: 16000}, "low", 16000},
+	}
+	for _, tc := range cases {
+		if got := tc.cfg.EffortLevel(); got != tc.effort {
+			t.Fatalf("%+v: effort=%q want %q", tc.cfg, got, tc.effort)
+		}
+		if got := tc.cfg.Budget(); got != tc.budget {
+			t.Fatalf("%+v: budget=%d want %d", tc.cfg, got, tc.budget)
+		}
+		if tc.cfg.Enabled() != (tc.budget > 0) {
+			t.Fatalf("%+v: unexpected Enabled()", tc.cfg)
+		}
+	}
+}
+
+func TestAnthropicThinkingStreamsAndReplaysSignedBlocks(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":3,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Need the "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"file first."}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-abc"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"redacted_thinking","data":"opaque"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_1","name":"read_file","input":{}}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"path\":\"a.txt\"}"}}`,
+		`{"type":"content_block_stop","index":2}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":9}}`,
+		`{"type":"message_stop"}`,
+	}
+	srv, bodies := recordingServer(t, func(w http.ResponseWriter, n int) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		if n > 0 {
+			_, _ = io.WriteString(w, "event: message_start\ndata: "+events[0]+"\n\nevent: message_stop\ndata: "+events[len(events)-1]+"\n\n")
+			return
+		}
+		for _, event := range events {
+			var head struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal([]byte(event), &head)
+			_, _ = io.WriteString(w, "event: "+head.Type+"\ndata: "+event+"\n\n")
+		}
+	})
+
+	p, err := newAnthropicProvider("sk-anthropic", srv.URL, "claude-sonnet-4-5", 1024, 0.2, nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newAnthropicProvider failed: %v", err)
+	}
+	p.reasoning = fixedReasoning(ReasoningConfig{BudgetTokens: 2000})
+
+	recorder := &reasoningRecorder{}
+	history := []Message{{Role: "user", Content: "read a.txt"}}
+	if err := p.ChatStream(context.Background(), history, nil, "", recorder); err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if recorder.reasoning.String() != "Need the file first." || recorder.content.String() != "" {
+		t.Fatalf("unexpected reasoning=%q content=%q", recorder.reasoning.String(), recorder.content.String())
+	}
+	if len(recorder.blocks) != 2 || recorder.blocks[0].Signature != "sig-abc" || recorder.blocks[1].RedactedData != "opaque" {
+		t.Fatalf("unexpected reasoning blocks %+v", recorder.blocks)
+	}
+	if len(recorder.calls) != 1 || recorder.calls[0] != "read_file" {
+		t.Fatalf("unexpected tool calls %v", recorder.calls)
+	}
+
+	first := (*bodies)[0]
+	thinking, _ := first["thinking"].(map[string]interface{})
+	if thinking["type"] != "enabled" || thinking["budget_tokens"] != float64(2000) {
+		t.Fatalf("unexpected thinking param %v", first["thinking"])
+	}
+	// max_tokens 必须大于思考预算，且开启思考时不发送 temperature
+	if first["max_tokens"] != float64(3024) {
+		t.Fatalf("unexpected max_tokens %v", first["max_tokens"])
+	}
+	if _, ok := first["temperature"]; ok {
+		t.Fatalf("temperature must be omitted with thinking enabled")
+	}
+
+	// 工具结果回传时，思考块（含签名）须位于 tool_use 之前
+	history = append(history,
+		Message{Role: "assistant", ToolCalls: []ToolCall{{ID: "toolu_1", Type: "function", Function: ToolCallFunction{Name: "read_file", Arguments: `{"path":"a.txt"}`}}}, Reasoning: recorder.blocks},
+		Message{Role: "tool", ToolCallID: "toolu_1", Content: "hello"},
+	)
+	if err := p.ChatStream(context.Background(), history, nil, "", &reasoningRecorder{}); err != nil {
+		t.Fatalf("second ChatStream failed: %v", err)
+	}
+	assistant := (*bodies)[1]["messages"].([]interface{})[1].(map[string]interface{})
+	content := assistant["content"].([]interface{})
+	if len(content) != 3 {
+		t.Fatalf("unexpected assistant content %v", content)
+	}
+	block := content[0].(map[string]interface{})
+	if block["type"] != "thinking" || block["thinking"] != "Need the file first." || block["signature"] != "sig-abc" {
+		t.Fatalf("unexpected thinking block %v", block)
+	}
+	if content[1].(map[string]interface{})["type"] != "redacted_thinking" || content[2].(map[string]interface{})["type"] != "tool_use" {
+		t.Fatalf("unexpected block order %v", content)
+	}
+}
+
+func TestCompatReasoningContentIsStreamedButNotResent(t *testing.T) {
+	srv, bodies := recordingServer(t, func(w http.ResponseWriter, n int) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"reasoning_content\":\"Think\"}}]}\n\n")
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"reasoning_content\":\"ing...\"}}]}\n\n")
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Answer\"}}]}\n\n")
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	})
+
+	p, err := NewOpenAIProvider("sk-deepseek", srv.URL, "deepseek-reasoner", 256, 0.3, nil)
+	if err != nil {
+		t.Fatalf("NewOpenAIProvider failed: %v", err)
+	}
+	p.reasoning = fixedReasoning(ReasoningConfig{Effort: "high"})
+
+	recorder := &reasoningRecorder{}
+	history := []Message{
+		{Role: "user", Content: "q1"},
+		{Role: "assistant", Content: "a1", Reasoning: []ReasoningBlock{{Text: "old thoughts"}}},
+		{Role: "user", Content: "q2"},
+	}
+	if err := p.ChatStream(context.Background(), history, nil, "", recorder); err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if recorder.reasoning.String() != "Thinking..." || recorder.content.String() != "Answer" {
+		t.Fatalf("unexpected reasoning=%q content=%q", recorder.reasoning.String(), recorder.content.String())
+	}
+	if len(recorder.blocks) != 1 || recorder.blocks[0].Text != "Thinking..." {
+		t.Fatalf("unexpected reasoning blocks %+v", recorder.blocks)
+	}
+
+	req := (*bodies)[0]
+	if req["reasoning_effort"] != "high" {
+		t.Fatalf("expected reasoning_effort=high, got %v", req["reasoning_effort"])
+	}
+	raw, _ := json.Marshal(req["messages"])
+	if strings.Contains(string(raw), "old thoughts") || strings.Contains(string(raw), "reasoning") {
+		t.Fatalf("reasoning must not be re-sent: %s", raw)
+	}
+}
+
+func TestCompatOpenRouterUsesReasoningObject(t *testing.T) {
+	req := buildChatRequest([]Message{{Role: "user", Content: "hi"}}, nil, "openai/o3", false, false, 64, 0)
+	applyChatReasoning(&req, "openrouter", ReasoningConfig{BudgetTokens: 20000})
+	if req.Reasoning == nil || req.Reasoning.Effort != "high" || req.ReasoningEffort != "" {
+		t.Fatalf("unexpected reasoning fields %+v %q", req.Reasoning, req.ReasoningEffort)
+	}
+}
+
+func TestOpenAIOfficialReasoningEffortUsesMaxCompletionTokens(t *testing.T) {
+	srv, bodies := recordingServer(t, func(w http.ResponseWriter, n int) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"chatcmpl_1","object":"chat.completion","created":1,"model":"o4-mini","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+	})
+	p, err := newOpenAIOfficialProvider("sk-openai", srv.URL, "o4-mini", 512, 0.7, nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newOpenAIOfficialProvider failed: %v", err)
+	}
+	p.reasoning = fixedReasoning(ReasoningConfig{Effort: "medium"})
+	if _, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, ""); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	req := (*bodies)[0]
+	if req["reasoning_effort"] != "medium" || req["max_completion_tokens"] != float64(512) {
+		t.Fatalf("unexpected reasoning params %v", req)
+	}
+	if _, ok := req["max_tokens"]; ok {
+		t.Fatalf("max_tokens must not be sent to reasoning models")
+	}
+	if _, ok := req["temperature"]; ok {
+		t.Fatalf("temperature must not be sent to reasoning models")
+	}
+}
+
+func TestGeminiThoughtPartsBecomeReasoning(t *testing.T) {
+	srv, bodies := recordingServer(t, func(w http.ResponseWriter, n int) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Plan: \",\"thought\":true}]}}]}\n\n")
+		_, _ = io.WriteString(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"answer briefly\",\"thought\":true},{\"text\":\"Hi!\"}]}}]}\n\n")
+	})
+	p, err := newGeminiProvider("g-key", srv.URL, "gemini-2.5-pro", 64, 0, nil, nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newGeminiProvider failed: %v", err)
+	}
+	p.reasoning = fixedReasoning(ReasoningConfig{Effort: "low"})
+
+	recorder := &reasoningRecorder{}
+	if err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "", recorder); err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if recorder.reasoning.String() != "Plan: answer briefly" || recorder.content.String() != "Hi!" {
+		t.Fatalf("unexpected reasoning=%q content=%q", recorder.reasoning.String(), recorder.content.String())
+	}
+	if len(recorder.blocks) != 1 {
+		t.Fatalf("unexpected reasoning blocks %+v", recorder.blocks)
+	}
+	config := (*bodies)[0]["generationConfig"].(map[string]interface{})
+	thinking, _ := config["thinkingConfig"].(map[string]interface{})
+	if thinking["thinkingBudget"] != float64(2048) || thinking["includeThoughts"] != true {
+		t.Fatalf("unexpected thinkingConfig %v", config)
+	}
+}
+
+func TestOllamaThinkOnlyForThinkingModels(t *testing.T) {
+	fake := &fakeOllama{t: t, reply: func(w http.ResponseWriter, body map[string]interface{}) {
+		_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":"","thinking":"hmm"},"done":false}`+"\n")
+		_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":"42"},"done":true}`+"\n")
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	p, err := newOllamaProvider("", srv.URL, "ollama/qwen3:8b", 64, 0, nil, srv.Client())
+	if err != nil {
+		t.Fatalf("newOllamaProvider failed: %v", err)
+	}
+	p.reasoning = fixedReasoning(ReasoningConfig{Effort: "medium"})
+	recorder := &reasoningRecorder{}
+	if err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "answer?"}}, nil, "", recorder); err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if fake.chats[0]["think"] != true {
+		t.Fatalf("expected think=true for a thinking model, got %v", fake.chats[0]["think"])
+	}
+	if recorder.reasoning.String() != "hmm" || recorder.content.String() != "42" {
+		t.Fatalf("unexpected reasoning=%q content=%q", recorder.reasoning.String(), recorder.content.String())
+	}
+
+	// llava 不具备 thinking 能力，不发送 think
+	if err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "ollama/llava", &reasoningRecorder{}); err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if _, ok := fake.chats[1]["think"]; ok {
+		t.Fatalf("think must be omitted for models without thinking capability")
+	}
+	if got := ollamaThink("gpt-oss:20b", ReasoningConfig{Effort: "high"}); got != "high" {
+		t.Fatalf("gpt-oss should receive an effort level, got %v", got)
+	}
+}
@@ -6,6 +6,8 @@ type ProviderSpec struct {
 	Name           string
 	Keywords       []string
 	DefaultAPIBase string
+	// KeyOptional 本地服务无需 API Key
+	KeyOptional bool
 }
 
 func (s ProviderSpec) MatchesModel(model string) bool {
@@ -24,11 +26,13 @@ func (s ProviderSpec) MatchesModel(model string) bool {
 // 2) append one ProviderSpec here
 var ProviderSpecs = []ProviderSpec{
 	{Name: "openrouter", Keywords: []string{"openrouter"}, DefaultAPIBase: "https://openrouter.ai/api/v1"},
+	// ollama 需排在前面：ollama/deepseek-r1、ollama/qwen3 等会命中其他 provider 的关键词
+	{Name: "ollama", Keywords: []string{"ollama"}, DefaultAPIBase: "http://localhost:11434", KeyOptional: true},
 	{Name: "deepseek", Keywords: []string{"deepseek"}, DefaultAPIBase: "https://api.deepseek.com/v1"},
 	{Name: "zhipu", Keywords: []string{"zhipu", "glm", "zai"}, DefaultAPIBase: "https://open.bigmodel.cn/api/coding/paas/v4"},
 	{Name: "anthropic", Keywords: []string{"anthropic", "claude"}, DefaultAPIBase: "https://api.anthropic.com"},
 	{Name: "openai", Keywords: []string{"openai", "gpt"}, DefaultAPIBase: "https://api.openai.com/v1"},
-	{Name: "gemini", Keywords: []string{"gemini"}},
+	{Name: "gemini", Keywords: []string{"gemini"}, DefaultAPIBase: "https://generativelanguage.googleapis.com/v1beta"},
 	{Name: "dashscope", Keywords: []string{"dashscope", "qwen"}, DefaultAPIBase: "https://dashscope.aliyuncs.com/compatible-mode/v1"},
 	{Name: "groq", Keywords: []string{"groq"}},
 	{Name: "moonshot", Keywords: []string{"moonshot", "kimi"}, DefaultAPIBase: "https://api.moonshot.ai/v1"},
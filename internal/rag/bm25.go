@@ -0,0 +1,120 @@
+package rag
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// tokenize 小写化并按非字母数字切分；中日韩文字输出单字和相邻双字，便于无空格文本匹配
+func tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		for i, r := range cjk {
+			tokens = append(tokens, string(r))
+			if i+1 < len(cjk) {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// bm25Doc 单个片段的词频
+type bm25Doc struct {
+	tf     map[string]int
+	length int
+}
+
+// bm25Corpus 内存中的 BM25 统计，索引变化后重建
+type bm25Corpus struct {
+	docs   []bm25Doc
+	df     map[string]int
+	avgLen float64
+}
+
+func newBM25Corpus(texts []string) *bm25Corpus {
+	c := &bm25Corpus{docs: make([]bm25Doc, len(texts)), df: make(map[string]int)}
+	total := 0
+	for i, text := range texts {
+		tokens := tokenize(text)
+		tf := make(map[string]int, len(tokens))
+		for _, tok := range tokens {
+			tf[tok]++
+		}
+		for tok := range tf {
+			c.df[tok]++
+		}
+		c.docs[i] = bm25Doc{tf: tf, length: len(tokens)}
+		total += len(tokens)
+	}
+	if len(texts) > 0 {
+		c.avgLen = float64(total) / float64(len(texts))
+	}
+	return c
+}
+
+// scores 返回每个片段对查询的 BM25 得分
+func (c *bm25Corpus) scores(query string) []float64 {
+	out := make([]float64, len(c.docs))
+	seen := make(map[string]bool)
+	n := float64(len(c.docs))
+	for _, term := range tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		df := float64(c.df[term])
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for i, doc := range c.docs {
+			tf := float64(doc.tf[term])
+			if tf == 0 {
+				continue
+			}
+			norm := 1 - bm25B + bm25B*float64(doc.length)/math.Max(c.avgLen, 1)
+			out[i] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+	}
+	return out
+}
+
+// pathTerms 路径中的词（文件名通常很能说明内容）
+func pathTerms(rel string) string {
+	return strings.NewReplacer("/", " ", "_", " ", "-", " ", ".", " ").Replace(rel)
+}
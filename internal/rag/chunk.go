@@ -0,0 +1,191 @@
+package rag
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Lichas/maxclaw/internal/media"
+)
+
+// maxIndexedDocumentSize PDF / Office / EPUB 文档的大小上限（普通文本文件由 Options.MaxFileSize 控制）
+const maxIndexedDocumentSize = 20 << 20
+
+// sourceFile 待索引的工作区文件
+type sourceFile struct {
+	rel     string // 相对工作区的 slash 路径
+	abs     string
+	size    int64
+	modTime time.Time
+}
+
+// chunkSpan 文件中的一段内容；Start/End 为 1 起始的行号（文档为页内行号）
+type chunkSpan struct {
+	Start  int
+	End    int
+	Anchor string
+	Text   string
+}
+
+// walkWorkspace 遍历工作区，应用默认忽略规则、额外规则和各级 .gitignore；不跟随符号链接
+func walkWorkspace(root string, extra []string, maxFiles int) ([]sourceFile, bool, error) {
+	var files []sourceFile
+	truncated := false
+	var walk func(dir, rel string, matcher *ignoreMatcher) error
+	walk = func(dir, rel string, matcher *ignoreMatcher) error {
+		if data, err := os.ReadFile(filepath.Join(dir, ".gitignore")); err == nil {
+			matcher = matcher.with(parseIgnoreRules(rel, data))
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if truncated {
+				return nil
+			}
+			if entry.Type()&os.ModeSymlink != 0 {
+				continue
+			}
+			childRel := entry.Name()
+			if rel != "" {
+				childRel = rel + "/" + entry.Name()
+			}
+			if matcher.ignored(childRel, entry.IsDir()) {
+				continue
+			}
+			childAbs := filepath.Join(dir, entry.Name())
+			if entry.IsDir() {
+				// 子目录不可读时跳过，不中断整个索引
+				_ = walk(childAbs, childRel, matcher)
+				continue
+			}
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			if maxFiles > 0 && len(files) >= maxFiles {
+				truncated = true
+				return nil
+			}
+			files = append(files, sourceFile{rel: childRel, abs: childAbs, size: info.Size(), modTime: info.ModTime()})
+		}
+		return nil
+	}
+	if err := walk(root, "", newIgnoreMatcher(extra)); err != nil {
+		return nil, false, err
+	}
+	return files, truncated, nil
+}
+
+// loadChunks 读取并切分文件；二进制或过大的文件返回空结果。返回内容哈希用于判断是否需要重新嵌入
+func loadChunks(file sourceFile, maxChars int, maxFileSize int64) ([]chunkSpan, string, error) {
+	format := media.DetectDocumentFormat(file.abs, "")
+	limit := maxFileSize
+	if format != "" {
+		limit = maxIndexedDocumentSize
+	}
+	if file.size > limit {
+		return nil, "", nil
+	}
+	data, err := os.ReadFile(file.abs)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if format != "" {
+		doc, err := media.ExtractDocument(file.abs, "")
+		if err != nil {
+			// 加密或无文字层的文档不参与检索
+			return nil, hash, nil
+		}
+		var chunks []chunkSpan
+		for _, page := range doc.Pages {
+			for _, c := range chunkText(page.Text, maxChars) {
+				c.Anchor = page.Anchor
+				chunks = append(chunks, c)
+			}
+		}
+		return chunks, hash, nil
+	}
+	if !looksLikeText(data) {
+		return nil, hash, nil
+	}
+	return chunkText(string(data), maxChars), hash, nil
+}
+
+func looksLikeText(data []byte) bool {
+	head := data
+	if len(head) > 8192 {
+		head = head[:8192]
+		// 截断处可能落在多字节字符中间
+		for i := 0; i < utf8.UTFMax && len(head) > 0 && !utf8.Valid(head); i++ {
+			head = head[:len(head)-1]
+		}
+	}
+	return bytes.IndexByte(head, 0) < 0 && utf8.Valid(head)
+}
+
+// chunkText 按行切分为不超过约 maxChars 的片段，尽量在空行或 Markdown 标题前断开
+func chunkText(text string, maxChars int) []chunkSpan {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	var chunks []chunkSpan
+	emit := func(start, end int, body string) {
+		if strings.TrimSpace(body) != "" {
+			chunks = append(chunks, chunkSpan{Start: start, End: end, Text: body})
+		}
+	}
+
+	i := 0
+	for i < len(lines) {
+		start := i
+		size := 0
+		lastBreak := -1
+		for i < len(lines) {
+			n := len(lines[i]) + 1
+			if size > 0 && size+n > maxChars {
+				break
+			}
+			size += n
+			i++
+			if size >= maxChars/2 && i < len(lines) &&
+				(strings.TrimSpace(lines[i-1]) == "" || strings.HasPrefix(lines[i], "#")) {
+				lastBreak = i
+			}
+		}
+		if i < len(lines) && lastBreak > start {
+			i = lastBreak
+		}
+		if i == start+1 && len(lines[start]) > maxChars {
+			// 超长单行（如压缩后的 JSON）按字符切分
+			line := lines[start]
+			for len(line) > 0 {
+				cut := min(maxChars, len(line))
+				for cut < len(line) && !utf8.RuneStart(line[cut]) {
+					cut++
+				}
+				emit(start+1, start+1, line[:cut])
+				line = line[cut:]
+			}
+			continue
+		}
+		// 片段末尾的空行不计入行号范围
+		end := i
+		for end > start+1 && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		emit(start+1, end, strings.Join(lines[start:end], "\n"))
+	}
+	return chunks
+}
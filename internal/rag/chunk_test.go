@@ -0,0 +1,142 @@
+package rag
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeWorkspaceFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestIgnoreMatcherFollowsGitignoreSemantics(t *testing.T) {
+	m := newIgnoreMatcher([]string{"secrets/"})
+	m = m.with(parseIgnoreRules("", []byte("# comment\n*.log\n!keep.log\n/build\ndocs/**/draft-*.md\n")))
+	m = m.with(parseIgnoreRules("sub", []byte("local.txt\n")))
+
+	cases := []struct {
+		rel     string
+		isDir   bool
+		ignored bool
+	}{
+		{"node_modules", true, true},
+		{".git", true, true},
+		{"app.log", false, true},
+		{"nested/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"src/build", true, false},
+		{"docs/a/b/draft-1.md", false, true},
+		{"docs/final.md", false, false},
+		{"secrets", true, true},
+		{"secrets", false, false},
+		{"sub/local.txt", false, true},
+		{"local.txt", false, false},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.ignored, m.ignored(tc.rel, tc.isDir), tc.rel)
+	}
+}
+
+func TestWalkWorkspaceAppliesNestedGitignore(t *testing.T) {
+	root := t.TempDir()
+	writeWorkspaceFile(t, root, "notes.md", "hello")
+	writeWorkspaceFile(t, root, ".gitignore", "tmp/\n")
+	writeWorkspaceFile(t, root, "tmp/scratch.md", "skip")
+	writeWorkspaceFile(t, root, "node_modules/pkg/index.js", "skip")
+	writeWorkspaceFile(t, root, "app/.gitignore", "*.gen.go\n")
+	writeWorkspaceFile(t, root, "app/main.go", "package main")
+	writeWorkspaceFile(t, root, "app/types.gen.go", "package main")
+	writeWorkspaceFile(t, root, "vendor.gen.go", "package main")
+
+	files, truncated, err := walkWorkspace(root, nil, 0)
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	var rels []string
+	for _, f := range files {
+		rels = append(rels, f.rel)
+	}
+	assert.ElementsMatch(t, []string{".gitignore", "notes.md", "app/.gitignore", "app/main.go", "vendor.gen.go"}, rels)
+
+	files, truncated, err = walkWorkspace(root, nil, 2)
+	require.NoError(t, err)
+	assert.True(t, truncated)
+	assert.Len(t, files, 2)
+}
+
+func TestChunkTextSplitsAtParagraphsAndHeadings(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("# Title\n\n")
+	for i := 0; i < 20; i++ {
+		sb.WriteString("A fairly ordinary line of prose that fills the chunk.\n")
+		if i%5 == 4 {
+			sb.WriteString("\n")
+		}
+	}
+	sb.WriteString("## Second section\n" + strings.Repeat("body text\n", 30))
+
+	chunks := chunkText(sb.String(), 400)
+	require.Greater(t, len(chunks), 2)
+	prevEnd := 0
+	for _, c := range chunks {
+		assert.LessOrEqual(t, len(c.Text), 400)
+		assert.Greater(t, c.Start, prevEnd)
+		assert.GreaterOrEqual(t, c.End, c.Start)
+		prevEnd = c.End
+	}
+	assert.Equal(t, 1, chunks[0].Start)
+	headingChunk := false
+	for _, c := range chunks {
+		headingChunk = headingChunk || strings.HasPrefix(c.Text, "## Second section")
+	}
+	assert.True(t, headingChunk)
+
+	long := chunkText(strings.Repeat("界", 300), 200)
+	require.Len(t, long, 5)
+	for _, c := range long {
+		assert.Equal(t, 1, c.Start)
+		assert.True(t, strings.HasPrefix(c.Text, "界"))
+	}
+}
+
+func TestLoadChunksSkipsBinaryAndOversizedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeWorkspaceFile(t, root, "bin.dat", "abc\x00def")
+	writeWorkspaceFile(t, root, "big.txt", strings.Repeat("x", 2048))
+	writeWorkspaceFile(t, root, "ok.txt", "line one\nline two\n")
+
+	files, _, err := walkWorkspace(root, nil, 0)
+	require.NoError(t, err)
+	byName := map[string]sourceFile{}
+	for _, f := range files {
+		byName[f.rel] = f
+	}
+
+	chunks, hash, err := loadChunks(byName["bin.dat"], 100, 1024)
+	require.NoError(t, err)
+	assert.Empty(t, chunks)
+	assert.NotEmpty(t, hash)
+
+	chunks, _, err = loadChunks(byName["big.txt"], 100, 1024)
+	require.NoError(t, err)
+	assert.Empty(t, chunks)
+
+	chunks, _, err = loadChunks(byName["ok.txt"], 100, 1024)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, 1, chunks[0].Start)
+	assert.Equal(t, 2, chunks[0].End)
+}
+
+func TestTokenizeHandlesCJK(t *testing.T) {
+	assert.Equal(t, []string{"hello", "world2"}, tokenize("Hello, World2!"))
+	assert.Equal(t, []string{"go", "向", "向量", "量", "量检", "检", "检索", "索"}, tokenize("Go向量检索"))
+}
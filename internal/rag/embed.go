@@ -0,0 +1,160 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Embedder 将文本转为向量
+type Embedder interface {
+	// Name 模型标识；变化时索引中的向量全部重新计算
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// maxEmbedInputChars 单条输入的字符上限，避免超出嵌入模型的上下文
+const maxEmbedInputChars = 8000
+
+// OpenAIEmbedder 调用 OpenAI 兼容的 /embeddings 接口（OpenAI、vLLM、Ollama、LM Studio 等）
+type OpenAIEmbedder struct {
+	BaseURL   string
+	APIKey    string
+	Model     string
+	BatchSize int
+	Client    *http.Client
+}
+
+// NewOpenAIEmbedder 创建嵌入客户端；timeout<=0 时使用 60 秒
+func NewOpenAIEmbedder(baseURL, apiKey, model string, batchSize int, timeout time.Duration) *OpenAIEmbedder {
+	if batchSize <= 0 {
+		batchSize = 64
+	}
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	return &OpenAIEmbedder{
+		BaseURL:   strings.TrimRight(strings.TrimSpace(baseURL), "/"),
+		APIKey:    strings.TrimSpace(apiKey),
+		Model:     strings.TrimSpace(model),
+		BatchSize: batchSize,
+		Client:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Name 返回模型名
+func (e *OpenAIEmbedder) Name() string {
+	return e.Model
+}
+
+// Embed 分批请求嵌入，返回单位长度的向量
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += e.BatchSize {
+		end := min(start+e.BatchSize, len(texts))
+		vectors, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vectors...)
+	}
+	return out, nil
+}
+
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	inputs := make([]string, len(texts))
+	for i, text := range texts {
+		if len(text) > maxEmbedInputChars {
+			text = strings.ToValidUTF8(text[:maxEmbedInputChars], "")
+		}
+		if strings.TrimSpace(text) == "" {
+			text = " "
+		}
+		inputs[i] = text
+	}
+	body, err := json.Marshal(map[string]interface{}{"model": e.Model, "input": inputs})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.BaseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 256<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > 300 {
+			msg = msg[:300] + "..."
+		}
+		return nil, fmt.Errorf("embeddings request failed (%d): %s", resp.StatusCode, msg)
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid embeddings response: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings response has %d vectors for %d inputs", len(parsed.Data), len(texts))
+	}
+	sort.SliceStable(parsed.Data, func(i, j int) bool { return parsed.Data[i].Index < parsed.Data[j].Index })
+	vectors := make([][]float32, len(parsed.Data))
+	for i, item := range parsed.Data {
+		if len(item.Embedding) == 0 {
+			return nil, fmt.Errorf("embeddings response has an empty vector")
+		}
+		vectors[i] = normalizeVector(item.Embedding)
+	}
+	return vectors, nil
+}
+
+func normalizeVector(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(1 / math.Sqrt(sum))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x * norm
+	}
+	return out
+}
+
+// dot 单位向量的点积即余弦相似度
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,146 @@
+package rag
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// defaultIgnorePatterns 即使没有 .gitignore 也跳过的目录和文件
+var defaultIgnorePatterns = []string{
+	".git/", ".hg/", ".svn/", "node_modules/", ".venv/", "venv/", "__pycache__/",
+	".sessions/", ".maxclaw/", ".DS_Store", "*.lock", "package-lock.json",
+}
+
+// ignoreRule 一条 gitignore 规则；base 为规则所在 .gitignore 的目录（相对工作区，根目录为空）
+type ignoreRule struct {
+	base    string
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+	// anchored 含 "/" 的模式匹配相对 base 的完整路径，否则只匹配文件名
+	anchored bool
+}
+
+// parseIgnoreRules 解析 gitignore 语法（#注释、!取反、/锚定、尾部 / 仅目录、* ? [] 与 **）
+func parseIgnoreRules(base string, data []byte) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		re, err := regexp.Compile("^" + globToRegexp(line) + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" 匹配零或多级目录
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// ignoreMatcher 按顺序应用规则，最后一条命中的规则决定结果
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+func newIgnoreMatcher(extra []string) *ignoreMatcher {
+	patterns := append(append([]string(nil), defaultIgnorePatterns...), extra...)
+	return &ignoreMatcher{rules: parseIgnoreRules("", []byte(strings.Join(patterns, "\n")))}
+}
+
+// with 返回追加了子目录 .gitignore 规则的新匹配器
+func (m *ignoreMatcher) with(rules []ignoreRule) *ignoreMatcher {
+	if len(rules) == 0 {
+		return m
+	}
+	combined := make([]ignoreRule, 0, len(m.rules)+len(rules))
+	combined = append(combined, m.rules...)
+	combined = append(combined, rules...)
+	return &ignoreMatcher{rules: combined}
+}
+
+// ignored rel 为相对工作区的 slash 路径
+func (m *ignoreMatcher) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		target := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			target = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		if !rule.anchored {
+			target = path.Base(target)
+		}
+		if rule.re.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
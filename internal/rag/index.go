@@ -0,0 +1,541 @@
+// Package rag 为工作区文件建立本地检索索引：按 .gitignore 遍历、切分片段、
+// 通过 OpenAI 兼容接口计算嵌入，并以 BM25 + 向量的混合排序检索。
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	indexVersion = 1
+
+	defaultChunkChars  = 1500
+	defaultMaxFileSize = 1 << 20
+	defaultMaxFiles    = 5000
+	// rrfK Reciprocal Rank Fusion 的平滑常数
+	rrfK = 60
+)
+
+// ErrEmbedding 嵌入接口调用失败；文本索引已保存，关键词检索仍可用
+var ErrEmbedding = errors.New("embedding failed")
+
+// 检索模式
+const (
+	ModeHybrid   = "hybrid"
+	ModeKeyword  = "keyword"
+	ModeSemantic = "semantic"
+)
+
+// Options 索引配置
+type Options struct {
+	// IndexDir 索引文件目录，每个工作区一个文件
+	IndexDir string
+	// Embedder 为空时只做关键词检索
+	Embedder    Embedder
+	ChunkChars  int
+	MaxFileSize int64
+	MaxFiles    int
+	// Exclude 额外的 gitignore 风格排除规则
+	Exclude []string
+}
+
+// Index 单个工作区的检索索引
+type Index struct {
+	workspace string
+	opts      Options
+	path      string
+
+	mu     sync.Mutex
+	data   *indexData
+	corpus *bm25Corpus
+	refs   []chunkRef
+}
+
+// indexData 持久化结构
+type indexData struct {
+	Version   int
+	Workspace string
+	Model     string
+	UpdatedAt time.Time
+	Files     map[string]*fileEntry
+}
+
+type fileEntry struct {
+	Size    int64
+	ModTime time.Time
+	Hash    string
+	Chunks  []chunkRecord
+}
+
+type chunkRecord struct {
+	Start  int
+	End    int
+	Anchor string
+	Text   string
+	Vector []float32
+}
+
+type chunkRef struct {
+	path  string
+	chunk *chunkRecord
+}
+
+// UpdateStats 一次增量更新的统计
+type UpdateStats struct {
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+	Embedded  int
+	// Truncated 文件数超过 MaxFiles，部分文件未被索引
+	Truncated bool
+}
+
+// Changed 是否有文件变化
+func (s UpdateStats) Changed() bool {
+	return s.Added+s.Updated+s.Removed > 0
+}
+
+// Status 索引概况
+type Status struct {
+	Workspace string
+	Path      string
+	Model     string
+	Files     int
+	Chunks    int
+	Embedded  int
+	UpdatedAt time.Time
+}
+
+// SearchOptions 检索参数
+type SearchOptions struct {
+	Limit int
+	// Path 只返回该相对路径前缀下的结果
+	Path string
+	Mode string
+}
+
+// Result 一条检索结果
+type Result struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Anchor    string
+	Text      string
+	Score     float64
+}
+
+// Open 创建工作区索引；索引文件在首次 Update 或 Search 时读取
+func Open(workspace string, opts Options) (*Index, error) {
+	abs, err := filepath.Abs(workspace)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(opts.IndexDir) == "" {
+		return nil, errors.New("index directory is required")
+	}
+	if opts.ChunkChars <= 0 {
+		opts.ChunkChars = defaultChunkChars
+	}
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = defaultMaxFileSize
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = defaultMaxFiles
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return &Index{
+		workspace: abs,
+		opts:      opts,
+		path:      filepath.Join(opts.IndexDir, hex.EncodeToString(sum[:8])+".gob"),
+	}, nil
+}
+
+// Path 索引文件路径
+func (idx *Index) Path() string {
+	return idx.path
+}
+
+func (idx *Index) modelName() string {
+	if idx.opts.Embedder == nil {
+		return ""
+	}
+	return idx.opts.Embedder.Name()
+}
+
+// load 读取磁盘索引；不存在、版本或工作区不符时返回空索引
+func (idx *Index) load() {
+	if idx.data != nil {
+		return
+	}
+	idx.data = &indexData{Version: indexVersion, Workspace: idx.workspace, Files: map[string]*fileEntry{}}
+	f, err := os.Open(idx.path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	var data indexData
+	if err := gob.NewDecoder(f).Decode(&data); err != nil {
+		return
+	}
+	if data.Version != indexVersion || data.Workspace != idx.workspace || data.Files == nil {
+		return
+	}
+	idx.data = &data
+}
+
+func (idx *Index) save() error {
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(idx.path), ".index-*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(tmp).Encode(idx.data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), idx.path)
+}
+
+// Update 增量更新：只重新切分大小、修改时间和内容有变化的文件，只为缺少向量的片段计算嵌入。
+// 嵌入失败时仍保存文本索引（关键词检索可用）并返回错误。
+func (idx *Index) Update(ctx context.Context) (UpdateStats, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.update(ctx, false)
+}
+
+// Rebuild 丢弃已有索引并完整重建
+func (idx *Index) Rebuild(ctx context.Context) (UpdateStats, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.update(ctx, true)
+}
+
+func (idx *Index) update(ctx context.Context, full bool) (UpdateStats, error) {
+	var stats UpdateStats
+	idx.load()
+	model := idx.modelName()
+	dirty := full
+	if full {
+		idx.data.Files = map[string]*fileEntry{}
+	}
+	if model != "" && idx.data.Model != model {
+		// 换了嵌入模型，旧向量维度和语义都不再可比
+		for _, entry := range idx.data.Files {
+			for i := range entry.Chunks {
+				entry.Chunks[i].Vector = nil
+			}
+		}
+		idx.data.Model = model
+		idx.corpus = nil
+		dirty = true
+	}
+
+	files, truncated, err := walkWorkspace(idx.workspace, idx.opts.Exclude, idx.opts.MaxFiles)
+	if err != nil {
+		return stats, err
+	}
+	stats.Truncated = truncated
+
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		seen[file.rel] = true
+		old := idx.data.Files[file.rel]
+		if old != nil && old.Size == file.size && old.ModTime.Equal(file.modTime) {
+			stats.Unchanged++
+			continue
+		}
+		chunks, hash, err := loadChunks(file, idx.opts.ChunkChars, idx.opts.MaxFileSize)
+		if err != nil {
+			continue
+		}
+		if old != nil && hash != "" && old.Hash == hash {
+			// 仅修改时间变化（如 touch、git checkout）
+			old.Size, old.ModTime = file.size, file.modTime
+			stats.Unchanged++
+			dirty = true
+			continue
+		}
+		entry := &fileEntry{Size: file.size, ModTime: file.modTime, Hash: hash}
+		for _, c := range chunks {
+			entry.Chunks = append(entry.Chunks, chunkRecord{Start: c.Start, End: c.End, Anchor: c.Anchor, Text: c.Text})
+		}
+		if old == nil {
+			stats.Added++
+		} else {
+			stats.Updated++
+		}
+		idx.data.Files[file.rel] = entry
+		dirty = true
+	}
+	for rel := range idx.data.Files {
+		if !seen[rel] {
+			delete(idx.data.Files, rel)
+			stats.Removed++
+			dirty = true
+		}
+	}
+
+	embedErr := idx.embedPending(ctx, &stats)
+	if stats.Embedded > 0 {
+		dirty = true
+	}
+	if dirty {
+		idx.data.UpdatedAt = time.Now()
+		idx.corpus = nil
+		if err := idx.save(); err != nil {
+			return stats, err
+		}
+	} else if _, err := os.Stat(idx.path); err != nil {
+		idx.data.UpdatedAt = time.Now()
+		if err := idx.save(); err != nil {
+			return stats, err
+		}
+	}
+	return stats, embedErr
+}
+
+// embedPending 为缺少向量的片段计算嵌入，每批结果立即写回以便失败后续传
+func (idx *Index) embedPending(ctx context.Context, stats *UpdateStats) error {
+	if idx.opts.Embedder == nil {
+		return nil
+	}
+	var pending []*chunkRecord
+	for _, rel := range idx.sortedPaths() {
+		entry := idx.data.Files[rel]
+		for i := range entry.Chunks {
+			if entry.Chunks[i].Vector == nil {
+				pending = append(pending, &entry.Chunks[i])
+			}
+		}
+	}
+	const batch = 256
+	for start := 0; start < len(pending); start += batch {
+		end := min(start+batch, len(pending))
+		texts := make([]string, 0, end-start)
+		for _, c := range pending[start:end] {
+			texts = append(texts, c.Text)
+		}
+		vectors, err := idx.opts.Embedder.Embed(ctx, texts)
+		if err == nil && len(vectors) != len(texts) {
+			err = fmt.Errorf("embedder returned %d vectors for %d inputs", len(vectors), len(texts))
+		}
+		if err != nil {
+			return fmt.Errorf("%w after %d of %d chunks: %v", ErrEmbedding, stats.Embedded, len(pending), err)
+		}
+		for i, c := range pending[start:end] {
+			c.Vector = normalizeVector(vectors[i])
+		}
+		stats.Embedded += len(texts)
+	}
+	return nil
+}
+
+func (idx *Index) sortedPaths() []string {
+	paths := make([]string, 0, len(idx.data.Files))
+	for rel := range idx.data.Files {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// buildCorpus 展开片段并建立 BM25 统计（路径词与正文一起索引）
+func (idx *Index) buildCorpus() {
+	if idx.corpus != nil {
+		return
+	}
+	idx.refs = idx.refs[:0]
+	var texts []string
+	for _, rel := range idx.sortedPaths() {
+		entry := idx.data.Files[rel]
+		terms := pathTerms(rel)
+		for i := range entry.Chunks {
+			c := &entry.Chunks[i]
+			idx.refs = append(idx.refs, chunkRef{path: rel, chunk: c})
+			texts = append(texts, terms+" "+c.Anchor+"\n"+c.Text)
+		}
+	}
+	idx.corpus = newBM25Corpus(texts)
+}
+
+// Search 在当前索引中检索（不触发更新）。向量不可用时 hybrid 退化为关键词检索，note 说明原因
+func (idx *Index) Search(ctx context.Context, query string, opts SearchOptions) ([]Result, string, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, "", errors.New("query is required")
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+	mode := strings.ToLower(strings.TrimSpace(opts.Mode))
+	if mode == "" {
+		mode = ModeHybrid
+	}
+	if mode != ModeHybrid && mode != ModeKeyword && mode != ModeSemantic {
+		return nil, "", fmt.Errorf("unknown search mode %q (use hybrid, keyword or semantic)", opts.Mode)
+	}
+	prefix := strings.Trim(filepath.ToSlash(strings.TrimSpace(opts.Path)), "/")
+	if prefix == "." {
+		prefix = ""
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.load()
+	idx.buildCorpus()
+
+	inScope := func(i int) bool {
+		p := idx.refs[i].path
+		return prefix == "" || p == prefix || strings.HasPrefix(p, prefix+"/")
+	}
+
+	note := ""
+	var vectorRank []int
+	if mode != ModeKeyword {
+		ranked, reason := idx.vectorRanking(ctx, query, inScope)
+		if reason != "" {
+			if mode == ModeSemantic {
+				return nil, "", errors.New(reason)
+			}
+			note = reason + "; showing keyword matches only"
+		}
+		vectorRank = ranked
+	}
+
+	var keywordRank []int
+	if mode != ModeSemantic {
+		scores := idx.corpus.scores(query)
+		for i, s := range scores {
+			if s > 0 && inScope(i) {
+				keywordRank = append(keywordRank, i)
+			}
+		}
+		sort.SliceStable(keywordRank, func(a, b int) bool { return scores[keywordRank[a]] > scores[keywordRank[b]] })
+	}
+
+	fused := map[int]float64{}
+	for rank, i := range keywordRank {
+		fused[i] += 1.0 / float64(rrfK+rank+1)
+	}
+	for rank, i := range vectorRank {
+		fused[i] += 1.0 / float64(rrfK+rank+1)
+	}
+	order := make([]int, 0, len(fused))
+	for i := range fused {
+		order = append(order, i)
+	}
+	sort.Slice(order, func(a, b int) bool {
+		if fused[order[a]] != fused[order[b]] {
+			return fused[order[a]] > fused[order[b]]
+		}
+		return order[a] < order[b]
+	})
+	if len(order) > limit {
+		order = order[:limit]
+	}
+
+	results := make([]Result, 0, len(order))
+	for _, i := range order {
+		ref := idx.refs[i]
+		results = append(results, Result{
+			Path:      ref.path,
+			StartLine: ref.chunk.Start,
+			EndLine:   ref.chunk.End,
+			Anchor:    ref.chunk.Anchor,
+			Text:      ref.chunk.Text,
+			Score:     fused[i],
+		})
+	}
+	return results, note, nil
+}
+
+// maxVectorCandidates 向量排序参与融合的候选数
+const maxVectorCandidates = 50
+
+// vectorRanking 返回按余弦相似度排序的片段下标；不可用时返回原因
+func (idx *Index) vectorRanking(ctx context.Context, query string, inScope func(int) bool) ([]int, string) {
+	if idx.opts.Embedder == nil {
+		return nil, "semantic search is not configured (set tools.retrieval.embedding)"
+	}
+	if idx.data.Model != idx.modelName() {
+		return nil, "index was built with a different embedding model; rebuild it"
+	}
+	vectors, err := idx.opts.Embedder.Embed(ctx, []string{query})
+	if err != nil || len(vectors) != 1 {
+		if err == nil {
+			err = errors.New("no vector returned")
+		}
+		return nil, "query embedding failed: " + err.Error()
+	}
+	qv := normalizeVector(vectors[0])
+	type scored struct {
+		i     int
+		score float64
+	}
+	var candidates []scored
+	for i, ref := range idx.refs {
+		if ref.chunk.Vector == nil || !inScope(i) {
+			continue
+		}
+		candidates = append(candidates, scored{i, dot(qv, ref.chunk.Vector)})
+	}
+	if len(candidates) == 0 {
+		return nil, "no embedded chunks in the index yet"
+	}
+	sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].score > candidates[b].score })
+	if len(candidates) > maxVectorCandidates {
+		candidates = candidates[:maxVectorCandidates]
+	}
+	ranked := make([]int, len(candidates))
+	for i, c := range candidates {
+		ranked[i] = c.i
+	}
+	return ranked, ""
+}
+
+// Status 读取索引概况
+func (idx *Index) Status() Status {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.load()
+	st := Status{
+		Workspace: idx.workspace,
+		Path:      idx.path,
+		Model:     idx.data.Model,
+		Files:     len(idx.data.Files),
+		UpdatedAt: idx.data.UpdatedAt,
+	}
+	for _, entry := range idx.data.Files {
+		st.Chunks += len(entry.Chunks)
+		for _, c := range entry.Chunks {
+			if c.Vector != nil {
+				st.Embedded++
+			}
+		}
+	}
+	return st
+}
@@ -0,0 +1,255 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// topicEmbedder 按主题词表生成向量，同义词落在同一维度，用来模拟语义匹配
+type topicEmbedder struct {
+	model string
+	fail  bool
+
+	mu    sync.Mutex
+	calls int
+	texts int
+}
+
+var embedTopics = [][]string{
+	{"cat", "cats", "kitten", "feline"},
+	{"invoice", "billing", "payment", "receipt"},
+	{"deploy", "release", "rollout", "kubernetes"},
+}
+
+func (e *topicEmbedder) Name() string { return e.model }
+
+func (e *topicEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.fail {
+		return nil, errors.New("endpoint down")
+	}
+	e.calls++
+	e.texts += len(texts)
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(embedTopics)+1)
+		v[len(embedTopics)] = 0.01
+		for _, tok := range tokenize(text) {
+			for d, words := range embedTopics {
+				for _, w := range words {
+					if tok == w {
+						v[d]++
+					}
+				}
+			}
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func newTestIndex(t *testing.T, root string, embedder Embedder) *Index {
+	t.Helper()
+	opts := Options{IndexDir: filepath.Join(t.TempDir(), "index"), ChunkChars: 200}
+	if embedder != nil {
+		opts.Embedder = embedder
+	}
+	idx, err := Open(root, opts)
+	require.NoError(t, err)
+	return idx
+}
+
+func TestIndexHybridSearchFindsSemanticMatches(t *testing.T) {
+	root := t.TempDir()
+	writeWorkspaceFile(t, root, "pets.md", "# Pets\nOur kitten sleeps all day on the sofa.\n")
+	writeWorkspaceFile(t, root, "finance/q3.md", "Billing notes: the receipt for October is missing.\n")
+	writeWorkspaceFile(t, root, "ops/runbook.md", "Rollout steps for kubernetes clusters.\n")
+
+	embedder := &topicEmbedder{model: "topic-v1"}
+	idx := newTestIndex(t, root, embedder)
+	stats, err := idx.Update(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Added)
+	assert.Equal(t, 3, stats.Embedded)
+
+	// 查询与文档没有共同词，只能靠向量命中
+	results, note, err := idx.Search(context.Background(), "feline", SearchOptions{Limit: 1})
+	require.NoError(t, err)
+	assert.Empty(t, note)
+	require.Len(t, results, 1)
+	assert.Equal(t, "pets.md", results[0].Path)
+	assert.Equal(t, 1, results[0].StartLine)
+
+	results, _, err = idx.Search(context.Background(), "payment", SearchOptions{Limit: 1, Mode: ModeSemantic})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "finance/q3.md", results[0].Path)
+
+	// 关键词模式只匹配字面
+	results, _, err = idx.Search(context.Background(), "feline", SearchOptions{Mode: ModeKeyword})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// 路径前缀限定范围，文件名参与关键词匹配
+	results, _, err = idx.Search(context.Background(), "runbook", SearchOptions{Path: "ops"})
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "ops/runbook.md", results[0].Path)
+	results, _, err = idx.Search(context.Background(), "kitten", SearchOptions{Path: "ops/"})
+	require.NoError(t, err)
+	for _, r := range results {
+		assert.True(t, strings.HasPrefix(r.Path, "ops/"))
+	}
+
+	_, _, err = idx.Search(context.Background(), "x", SearchOptions{Mode: "fuzzy"})
+	assert.Error(t, err)
+}
+
+func TestIndexUpdateIsIncremental(t *testing.T) {
+	root := t.TempDir()
+	writeWorkspaceFile(t, root, "a.md", "kitten one")
+	writeWorkspaceFile(t, root, "b.md", "invoice two")
+	writeWorkspaceFile(t, root, "c.md", "release three")
+
+	embedder := &topicEmbedder{model: "topic-v1"}
+	idx := newTestIndex(t, root, embedder)
+	_, err := idx.Update(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, embedder.texts)
+
+	stats, err := idx.Update(context.Background())
+	require.NoError(t, err)
+	assert.False(t, stats.Changed())
+	assert.Equal(t, 3, stats.Unchanged)
+	assert.Equal(t, 3, embedder.texts)
+
+	writeWorkspaceFile(t, root, "a.md", "kitten one, edited")
+	later := time.Now().Add(2 * time.Second)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "a.md"), later, later))
+	// 仅修改时间变化的文件不重新嵌入
+	require.NoError(t, os.Chtimes(filepath.Join(root, "b.md"), later, later))
+	require.NoError(t, os.Remove(filepath.Join(root, "c.md")))
+	writeWorkspaceFile(t, root, "d.md", "feline four")
+
+	stats, err = idx.Update(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Added)
+	assert.Equal(t, 1, stats.Updated)
+	assert.Equal(t, 1, stats.Removed)
+	assert.Equal(t, 1, stats.Unchanged)
+	assert.Equal(t, 2, stats.Embedded)
+	assert.Equal(t, 5, embedder.texts)
+
+	// 重新打开时从磁盘读取，无需再次嵌入
+	reopened, err := Open(root, idx.opts)
+	require.NoError(t, err)
+	st := reopened.Status()
+	assert.Equal(t, 3, st.Files)
+	assert.Equal(t, 3, st.Embedded)
+	assert.Equal(t, "topic-v1", st.Model)
+	stats, err = reopened.Update(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Embedded)
+
+	// 换模型后全部重新嵌入
+	other := &topicEmbedder{model: "topic-v2"}
+	opts := idx.opts
+	opts.Embedder = other
+	switched, err := Open(root, opts)
+	require.NoError(t, err)
+	stats, err = switched.Update(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Embedded)
+}
+
+func TestIndexFallsBackToKeywordsWhenEmbeddingFails(t *testing.T) {
+	root := t.TempDir()
+	writeWorkspaceFile(t, root, "pets.md", "Our kitten sleeps all day.")
+
+	embedder := &topicEmbedder{model: "topic-v1", fail: true}
+	idx := newTestIndex(t, root, embedder)
+	stats, err := idx.Update(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, 1, stats.Added)
+
+	results, note, err := idx.Search(context.Background(), "kitten", SearchOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, note, "keyword matches only")
+	require.Len(t, results, 1)
+	assert.Equal(t, "pets.md", results[0].Path)
+
+	_, _, err = idx.Search(context.Background(), "kitten", SearchOptions{Mode: ModeSemantic})
+	assert.Error(t, err)
+
+	// 接口恢复后补齐缺失的向量
+	embedder.fail = false
+	stats, err = idx.Update(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Added)
+	assert.Equal(t, 1, stats.Embedded)
+
+	keywordOnly := newTestIndex(t, root, nil)
+	_, err = keywordOnly.Update(context.Background())
+	require.NoError(t, err)
+	_, note, err = keywordOnly.Search(context.Background(), "kitten", SearchOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, note, "not configured")
+}
+
+func TestOpenAIEmbedderBatchesAndOrdersByIndex(t *testing.T) {
+	var batches [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "nomic-embed-text", req.Model)
+		batches = append(batches, req.Input)
+
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		var data []item
+		// 倒序返回，客户端需按 index 排序
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, item{Index: i, Embedding: []float32{float32(len(req.Input[i])), 0, 0}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer srv.Close()
+
+	e := NewOpenAIEmbedder(srv.URL+"/v1/", "sk-test", "nomic-embed-text", 2, 0)
+	vectors, err := e.Embed(context.Background(), []string{"a", "bb", "ccc"})
+	require.NoError(t, err)
+	require.Len(t, vectors, 3)
+	assert.Len(t, batches, 2)
+	for _, v := range vectors {
+		assert.InDelta(t, 1.0, float64(v[0]), 1e-6)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not found", http.StatusNotFound)
+	}))
+	defer failing.Close()
+	_, err = NewOpenAIEmbedder(failing.URL, "", "missing", 0, 0).Embed(context.Background(), []string{"x"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+	assert.Contains(t, err.Error(), "model not found")
+}
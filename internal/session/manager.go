@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -124,6 +126,71 @@ func (s *Session) Clear() {
 	s.LastConsolidated = 0
 }
 
+// RemoveLastTurn 删除最后一轮对话（最后一条用户消息及其后的所有回复），返回被删除的消息
+func (s *Session) RemoveLastTurn() []Message {
+	start := -1
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if s.Messages[i].Role == "user" {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+	removed := append([]Message(nil), s.Messages[start:]...)
+	s.Messages = s.Messages[:start]
+	if s.LastConsolidated > len(s.Messages) {
+		s.LastConsolidated = len(s.Messages)
+	}
+	return removed
+}
+
+// Summary 会话概要，用于列表展示
+type Summary struct {
+	Key          string
+	Title        string
+	MessageCount int
+	UpdatedAt    time.Time
+}
+
+// List 列出已保存的会话，按最后一条消息时间倒序
+func (m *Manager) List() ([]Summary, error) {
+	dir := filepath.Join(m.workspace, ".sessions")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var results []Summary
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var sess Session
+		if err := json.Unmarshal(data, &sess); err != nil || sess.Key == "" {
+			continue
+		}
+		summary := Summary{Key: sess.Key, Title: sess.Title, MessageCount: len(sess.Messages)}
+		if len(sess.Messages) > 0 {
+			summary.UpdatedAt = sess.Messages[len(sess.Messages)-1].Timestamp
+		}
+		results = append(results, summary)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].UpdatedAt.After(results[j].UpdatedAt)
+	})
+	return results, nil
+}
+
 // getSessionFilePath 获取会话文件路径
 func (m *Manager) getSessionFilePath(key string) string {
 	// 将 key 中的特殊字符替换为安全字符
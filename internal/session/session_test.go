@@ -211,3 +211,45 @@ func TestSessionFileCreation(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, files, 1)
 }
+
+func TestRemoveLastTurn(t *testing.T) {
+	session := &Session{Key: "test"}
+	assert.Nil(t, session.RemoveLastTurn())
+
+	session.AddMessage("user", "first")
+	session.AddMessage("assistant", "one")
+	session.AddMessage("user", "second")
+	session.AddMessage("assistant", "two")
+	session.LastConsolidated = 4
+
+	removed := session.RemoveLastTurn()
+	require.Len(t, removed, 2)
+	assert.Equal(t, "second", removed[0].Content)
+	require.Len(t, session.Messages, 2)
+	assert.Equal(t, "one", session.Messages[1].Content)
+	assert.Equal(t, 2, session.LastConsolidated)
+}
+
+func TestListSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(tmpDir)
+
+	sessions, err := manager.List()
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	older := manager.GetOrCreate("cli:older")
+	older.AddMessage("user", "Hello")
+	require.NoError(t, manager.Save(older))
+	newer := manager.GetOrCreate("cli:newer")
+	newer.AddMessage("user", "Plan the release")
+	newer.AddMessage("assistant", "Sure")
+	require.NoError(t, manager.Save(newer))
+
+	sessions, err = manager.List()
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "cli:newer", sessions[0].Key)
+	assert.Equal(t, 2, sessions[0].MessageCount)
+	assert.Equal(t, "cli:older", sessions[1].Key)
+}
@@ -0,0 +1,177 @@
+package telemetry
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// 指标统一注册到独立的 Registry，避免与依赖库注册到默认 Registry 的指标冲突
+var registry = prometheus.NewRegistry()
+
+var (
+	messagesInbound = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "maxclaw_messages_inbound_total",
+		Help: "Inbound messages published to the bus, by channel.",
+	}, []string{"channel"})
+
+	messagesOutbound = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "maxclaw_messages_outbound_total",
+		Help: "Outbound messages delivered to channels, by channel and status.",
+	}, []string{"channel", "status"})
+
+	llmDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "maxclaw_llm_request_duration_seconds",
+		Help:    "LLM request latency, by provider, model and status.",
+		Buckets: []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"provider", "model", "status"})
+
+	llmTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "maxclaw_llm_tokens_total",
+		Help: "Tokens reported by providers, by provider, model and type (prompt, completion).",
+	}, []string{"provider", "model", "type"})
+
+	llmErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "maxclaw_llm_errors_total",
+		Help: "Failed LLM requests, by provider, model and classified error reason.",
+	}, []string{"provider", "model", "reason"})
+
+	toolDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "maxclaw_tool_duration_seconds",
+		Help:    "Tool execution time, by tool and status.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 300},
+	}, []string{"tool", "status"})
+
+	toolFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "maxclaw_tool_failures_total",
+		Help: "Failed tool executions, by tool.",
+	}, []string{"tool"})
+
+	cronRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "maxclaw_cron_runs_total",
+		Help: "Cron job runs, by trigger and status.",
+	}, []string{"trigger", "status"})
+
+	cronDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "maxclaw_cron_run_duration_seconds",
+		Help:    "Cron job run time.",
+		Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800},
+	})
+
+	mcpServerUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "maxclaw_mcp_server_up",
+		Help: "Whether an MCP server is connected (1) or failed to connect (0).",
+	}, []string{"server"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		messagesInbound,
+		messagesOutbound,
+		llmDuration,
+		llmTokens,
+		llmErrors,
+		toolDuration,
+		toolFailures,
+		cronRuns,
+		cronDuration,
+		mcpServerUp,
+	)
+}
+
+// Handler 返回 Prometheus 文本格式的 /metrics 处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterQueueDepth 注册消息总线队列深度，direction 为 inbound / outbound；重复注册时忽略
+func RegisterQueueDepth(direction string, depth func() int) {
+	gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "maxclaw_bus_queue_depth",
+		Help:        "Messages waiting in the bus queue, by direction.",
+		ConstLabels: prometheus.Labels{"direction": direction},
+	}, func() float64 { return float64(depth()) })
+	_ = registry.Register(gauge)
+}
+
+// RecordInbound 记录一条入站消息
+func RecordInbound(channel string) {
+	messagesInbound.WithLabelValues(label(channel)).Inc()
+}
+
+// RecordOutbound 记录一条出站消息的投递结果
+func RecordOutbound(channel string, err error) {
+	messagesOutbound.WithLabelValues(label(channel), status(err)).Inc()
+}
+
+// RecordLLMCall 记录一次 LLM 请求；失败时 reason 为 ErrorClassifier 的分类结果
+func RecordLLMCall(provider, model string, duration time.Duration, reason string) {
+	provider, model = label(provider), label(model)
+	st := "ok"
+	if reason != "" {
+		st = "error"
+		llmErrors.WithLabelValues(provider, model, reason).Inc()
+	}
+	llmDuration.WithLabelValues(provider, model, st).Observe(duration.Seconds())
+}
+
+// RecordLLMTokens 记录提供商返回的 token 用量
+func RecordLLMTokens(provider, model string, promptTokens, completionTokens int) {
+	provider, model = label(provider), label(model)
+	if promptTokens > 0 {
+		llmTokens.WithLabelValues(provider, model, "prompt").Add(float64(promptTokens))
+	}
+	if completionTokens > 0 {
+		llmTokens.WithLabelValues(provider, model, "completion").Add(float64(completionTokens))
+	}
+}
+
+// RecordTool 记录一次工具执行
+func RecordTool(tool string, duration time.Duration, err error) {
+	tool = label(tool)
+	toolDuration.WithLabelValues(tool, status(err)).Observe(duration.Seconds())
+	if err != nil {
+		toolFailures.WithLabelValues(tool).Inc()
+	}
+}
+
+// RecordCronRun 记录一次定时任务执行
+func RecordCronRun(trigger string, duration time.Duration, err error) {
+	cronRuns.WithLabelValues(label(trigger), status(err)).Inc()
+	cronDuration.Observe(duration.Seconds())
+}
+
+// SetMCPServerUp 更新 MCP 服务器连接状态
+func SetMCPServerUp(server string, up bool) {
+	v := 0.0
+	if up {
+		v = 1
+	}
+	mcpServerUp.WithLabelValues(label(server)).Set(v)
+}
+
+// ResetMCPServers 清空 MCP 服务器状态（配置变更后重新连接前调用）
+func ResetMCPServers() {
+	mcpServerUp.Reset()
+}
+
+func status(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+func label(v string) string {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "unknown"
+	}
+	return v
+}
@@ -0,0 +1,87 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetricsHandlerExposesRecordedSeries(t *testing.T) {
+	RecordInbound("telegram")
+	RecordOutbound("telegram", errors.New("send failed"))
+	RecordLLMCall("anthropic", "claude-sonnet-4-5", 2*time.Second, "")
+	RecordLLMCall("anthropic", "claude-sonnet-4-5", time.Second, "rate_limit")
+	RecordLLMTokens("anthropic", "claude-sonnet-4-5", 1200, 300)
+	RecordTool("read_file", 20*time.Millisecond, nil)
+	RecordTool("exec", time.Second, errors.New("exit status 1"))
+	RecordCronRun("every", 3*time.Second, nil)
+	SetMCPServerUp("github", true)
+	RegisterQueueDepth("inbound", func() int { return 7 })
+	RegisterQueueDepth("inbound", func() int { return 9 })
+
+	body := scrapeMetrics(t)
+	for _, want := range []string{
+		`maxclaw_messages_inbound_total{channel="telegram"} 1`,
+		`maxclaw_messages_outbound_total{channel="telegram",status="error"} 1`,
+		`maxclaw_llm_request_duration_seconds_count{model="claude-sonnet-4-5",provider="anthropic",status="ok"} 1`,
+		`maxclaw_llm_errors_total{model="claude-sonnet-4-5",provider="anthropic",reason="rate_limit"} 1`,
+		`maxclaw_llm_tokens_total{model="claude-sonnet-4-5",provider="anthropic",type="prompt"} 1200`,
+		`maxclaw_llm_tokens_total{model="claude-sonnet-4-5",provider="anthropic",type="completion"} 300`,
+		`maxclaw_tool_duration_seconds_count{status="ok",tool="read_file"} 1`,
+		`maxclaw_tool_failures_total{tool="exec"} 1`,
+		`maxclaw_cron_runs_total{status="ok",trigger="every"} 1`,
+		`maxclaw_mcp_server_up{server="github"} 1`,
+		`maxclaw_bus_queue_depth{direction="inbound"} 7`,
+		`go_goroutines`,
+	} {
+		assert.Contains(t, body, want)
+	}
+}
+
+func TestTraceParentLinksSpansAcrossMessages(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(prev)
+
+	assert.Empty(t, InjectTraceParent(context.Background()))
+
+	ctx, root := StartSpan(context.Background(), "agent.message")
+	traceParent := InjectTraceParent(ctx)
+	require.NotEmpty(t, traceParent)
+	EndSpan(root, nil)
+
+	// 出站投递在另一个 goroutine 中，仅凭 traceparent 还原父 span
+	_, send := StartSpan(ExtractTraceParent(context.Background(), traceParent), "channel.send")
+	EndSpan(send, errors.New("boom"))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, spans[0].SpanContext.TraceID(), spans[1].SpanContext.TraceID())
+	assert.Equal(t, spans[0].SpanContext.SpanID(), spans[1].Parent.SpanID())
+	assert.Equal(t, "Error", spans[1].Status.Code.String())
+}
+
+func TestInitTracingRequiresEndpoint(t *testing.T) {
+	_, err := InitTracing(context.Background(), TracingOptions{})
+	assert.Error(t, err)
+}
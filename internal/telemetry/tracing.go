@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/Lichas/maxclaw"
+
+// TracingOptions OTLP 链路导出配置
+type TracingOptions struct {
+	// Endpoint OTLP/HTTP 地址，如 localhost:4318 或 https://otel.example.com
+	Endpoint    string
+	Insecure    bool
+	Headers     map[string]string
+	ServiceName string
+	// SampleRatio 采样比例，<=0 或 >=1 时全部采样
+	SampleRatio float64
+}
+
+var propagator = propagation.TraceContext{}
+
+// InitTracing 创建 OTLP 导出器并设置为全局 TracerProvider，返回的函数用于退出前刷新并关闭
+func InitTracing(ctx context.Context, opts TracingOptions) (func(context.Context) error, error) {
+	endpoint := strings.TrimSpace(opts.Endpoint)
+	if endpoint == "" {
+		return nil, fmt.Errorf("tracing endpoint is empty")
+	}
+
+	var clientOpts []otlptracehttp.Option
+	if strings.Contains(endpoint, "://") {
+		clientOpts = append(clientOpts, otlptracehttp.WithEndpointURL(endpoint))
+	} else {
+		clientOpts = append(clientOpts, otlptracehttp.WithEndpoint(endpoint))
+	}
+	if opts.Insecure {
+		clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+	}
+	if len(opts.Headers) > 0 {
+		clientOpts = append(clientOpts, otlptracehttp.WithHeaders(opts.Headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	serviceName := strings.TrimSpace(opts.ServiceName)
+	if serviceName == "" {
+		serviceName = "maxclaw"
+	}
+	sampler := sdktrace.AlwaysSample()
+	if opts.SampleRatio > 0 && opts.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(opts.SampleRatio)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// StartSpan 使用全局 TracerProvider 创建 span；未启用链路导出时为无操作 span
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan 结束 span，err 不为空时标记为错误
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// InjectTraceParent 返回 ctx 中 span 的 W3C traceparent，用于随消息跨 goroutine 传递；无有效 span 时为空
+func InjectTraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// ExtractTraceParent 将 traceparent 还原为父 span 上下文
+func ExtractTraceParent(ctx context.Context, traceParent string) context.Context {
+	if strings.TrimSpace(traceParent) == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+}
@@ -0,0 +1,199 @@
+package triggers
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxWatchedFiles 单个文件触发器最多跟踪的文件数
+const maxWatchedFiles = 20000
+
+// maxListedChanges 提示词中最多列出的变化
+const maxListedChanges = 50
+
+// FileChange 一个文件变化
+type FileChange struct {
+	Event string `json:"event"`
+	Path  string `json:"path"`
+}
+
+type fileState struct {
+	size int64
+	mod  time.Time
+}
+
+// scanFiles 扫描 spec 中的路径，返回相对 workspace 的文件状态；
+// 隐藏目录（如 .git、.cron）只有被直接指定时才扫描
+func scanFiles(workspace string, spec *FileSpec) map[string]fileState {
+	files := make(map[string]fileState)
+	for _, p := range spec.Paths {
+		root := filepath.Join(workspace, filepath.Clean(p))
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if len(files) >= maxWatchedFiles {
+				return filepath.SkipAll
+			}
+			rel, err := filepath.Rel(workspace, path)
+			if err != nil || !matchesPatterns(spec.Patterns, rel) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			files[filepath.ToSlash(rel)] = fileState{size: info.Size(), mod: info.ModTime()}
+			return nil
+		})
+	}
+	return files
+}
+
+// matchesPatterns 通配匹配文件名；带 / 的通配匹配相对路径
+func matchesPatterns(patterns []string, rel string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range patterns {
+		target := filepath.Base(rel)
+		if strings.Contains(pattern, "/") {
+			target = rel
+		}
+		if ok, _ := filepath.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// diffFiles 比较两次扫描结果
+func diffFiles(before, after map[string]fileState) map[string]string {
+	changes := make(map[string]string)
+	for path, state := range after {
+		old, ok := before[path]
+		switch {
+		case !ok:
+			changes[path] = FileCreate
+		case old != state:
+			changes[path] = FileWrite
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes[path] = FileRemove
+		}
+	}
+	return changes
+}
+
+// mergeFileChange 合并同一文件在防抖窗口内的多次变化
+func mergeFileChange(pending map[string]string, path, event string) {
+	prev, ok := pending[path]
+	switch {
+	case !ok:
+		pending[path] = event
+	case prev == FileCreate && event == FileRemove:
+		delete(pending, path)
+	case prev == FileCreate:
+		// 新建后又修改仍视为新建
+	case prev == FileRemove && event == FileCreate:
+		pending[path] = FileWrite
+	default:
+		pending[path] = event
+	}
+}
+
+// fileEvent 按事件过滤并生成触发事件；没有匹配的变化时返回 false
+func fileEvent(spec *FileSpec, pending map[string]string) (Event, bool) {
+	var changes []FileChange
+	for path, event := range pending {
+		if len(spec.Events) == 0 || containsString(spec.Events, event) {
+			changes = append(changes, FileChange{Event: event, Path: path})
+		}
+	}
+	if len(changes) == 0 {
+		return Event{}, false
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	var lines strings.Builder
+	for i, c := range changes {
+		if i == maxListedChanges {
+			fmt.Fprintf(&lines, "- ...and %d more\n", len(changes)-maxListedChanges)
+			break
+		}
+		fmt.Fprintf(&lines, "- %s: %s\n", c.Event, c.Path)
+	}
+	return Event{
+		Kind: string(TypeFile),
+		Vars: map[string]string{
+			"files": strings.TrimRight(lines.String(), "\n"),
+			"path":  changes[0].Path,
+			"event": changes[0].Event,
+			"count": strconv.Itoa(len(changes)),
+		},
+	}, true
+}
+
+// watchFiles 轮询扫描文件；一个间隔内没有新变化后触发一次运行。
+// 运行期间的变化（包括 agent 自己写入的文件）不会再次触发
+func (s *Service) watchFiles(ctx context.Context, t *Trigger) {
+	if info, err := os.Stat(s.workspace); err != nil || !info.IsDir() {
+		s.log(slog.LevelWarn, "trigger watch skipped", "trigger", t.Name, "reason", "workspace_missing")
+		return
+	}
+	ticker := time.NewTicker(t.interval())
+	defer ticker.Stop()
+
+	baseline := scanFiles(s.workspace, t.File)
+	pending := make(map[string]string)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := scanFiles(s.workspace, t.File)
+		changes := diffFiles(baseline, current)
+		baseline = current
+		if len(changes) > 0 {
+			for path, event := range changes {
+				mergeFileChange(pending, path, event)
+			}
+			continue
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		if event, ok := fileEvent(t.File, pending); ok {
+			s.run(ctx, t, event)
+			baseline = scanFiles(s.workspace, t.File)
+		}
+		pending = make(map[string]string)
+	}
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,331 @@
+package triggers
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	imapclient "github.com/emersion/go-imap/client"
+)
+
+const (
+	// imapTimeout 连接与单条 IMAP 命令的超时
+	imapTimeout = 2 * time.Minute
+	// maxMailBodySize 每封邮件最多读取的字节数（服务端按 partial fetch 截断）
+	maxMailBodySize = 1 << 20
+	// maxMIMEDepth multipart 嵌套的最大层数
+	maxMIMEDepth = 8
+)
+
+// Mail 一封新邮件
+type Mail struct {
+	UID  uint32
+	From string
+	// Address 发件人地址（mailbox@host，小写），用于 from 规则匹配
+	Address string
+	Subject string
+	Body    string
+	Date    time.Time
+}
+
+// MailCursor 记录邮箱中已处理到的位置；UIDValidity 变化时重新建立基线
+type MailCursor struct {
+	UIDValidity uint32 `json:"uidValidity"`
+	LastUID     uint32 `json:"lastUid"`
+}
+
+// MailSource 读取邮箱中的新邮件
+type MailSource interface {
+	// Poll 返回 cursor 之后的新邮件与新的 cursor；cursor 为空或失效时只建立基线，不返回邮件
+	Poll(ctx context.Context, mailbox string, cursor MailCursor) ([]Mail, MailCursor, error)
+}
+
+// IMAPAccount IMAP 账号，取自 channels.email
+type IMAPAccount struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	UseSSL   bool
+}
+
+type imapSource struct {
+	account IMAPAccount
+}
+
+// NewIMAPSource 创建基于 IMAP 的邮件源；只读取邮件（BODY.PEEK），不改变已读状态
+func NewIMAPSource(account IMAPAccount) MailSource {
+	if account.Port == 0 {
+		account.Port = 993
+	}
+	return &imapSource{account: account}
+}
+
+func (s *imapSource) Poll(ctx context.Context, mailbox string, cursor MailCursor) ([]Mail, MailCursor, error) {
+	c, err := s.dial(ctx)
+	if err != nil {
+		return nil, cursor, err
+	}
+	// ctx 取消时关闭连接，正在执行的命令随之返回
+	stop := context.AfterFunc(ctx, func() { _ = c.Terminate() })
+	defer stop()
+	defer c.Logout()
+	if err := c.Login(s.account.Username, s.account.Password); err != nil {
+		return nil, cursor, pollErr(ctx, err)
+	}
+
+	status, err := c.Select(mailbox, true)
+	if err != nil {
+		return nil, cursor, pollErr(ctx, err)
+	}
+	if cursor.LastUID == 0 || cursor.UIDValidity != status.UidValidity {
+		last := uint32(0)
+		if status.UidNext > 0 {
+			last = status.UidNext - 1
+		}
+		return nil, MailCursor{UIDValidity: status.UidValidity, LastUID: last}, nil
+	}
+
+	seqset := new(imap.SeqSet)
+	seqset.AddRange(cursor.LastUID+1, 0)
+	section := &imap.BodySectionName{Peek: true, Partial: []int{0, maxMailBodySize}}
+	items := []imap.FetchItem{imap.FetchUid, imap.FetchEnvelope, section.FetchItem()}
+	msgCh := make(chan *imap.Message, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.UidFetch(seqset, items, msgCh)
+	}()
+
+	next := cursor
+	var mails []Mail
+	for msg := range msgCh {
+		// n:* 在没有新邮件时也会返回最后一封，需要按 UID 过滤
+		if msg == nil || msg.Uid <= cursor.LastUID {
+			continue
+		}
+		m := Mail{UID: msg.Uid}
+		if env := msg.Envelope; env != nil {
+			m.Subject = env.Subject
+			m.Date = env.Date
+			if len(env.From) > 0 {
+				from := env.From[0]
+				m.From = strings.TrimSpace(from.PersonalName + " <" + from.MailboxName + "@" + from.HostName + ">")
+				if from.MailboxName != "" && from.HostName != "" {
+					m.Address = strings.ToLower(strings.TrimSpace(from.MailboxName) + "@" + strings.TrimSpace(from.HostName))
+				}
+			}
+		}
+		if r := msg.GetBody(section); r != nil {
+			raw, _ := io.ReadAll(io.LimitReader(r, maxMailBodySize))
+			m.Body = mailBody(raw)
+		}
+		mails = append(mails, m)
+		if msg.Uid > next.LastUID {
+			next.LastUID = msg.Uid
+		}
+	}
+	if err := <-done; err != nil {
+		return nil, cursor, pollErr(ctx, err)
+	}
+	return mails, next, nil
+}
+
+// dial 连接 IMAP 服务器；连接、TLS 握手和问候都受 imapTimeout 与 ctx 限制
+func (s *imapSource) dial(ctx context.Context) (*imapclient.Client, error) {
+	addr := net.JoinHostPort(s.account.Host, fmt.Sprint(s.account.Port))
+	dialer := &net.Dialer{Timeout: imapTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(imapTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if s.account.UseSSL {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: s.account.Host, MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	c, err := imapclient.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, pollErr(ctx, err)
+	}
+	// 之后每条命令各自设置 imapTimeout 的期限
+	c.Timeout = imapTimeout
+	return c, nil
+}
+
+// pollErr ctx 已结束时返回 ctx 的错误，而不是关闭连接导致的读写错误
+func pollErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// mailBody 取邮件的纯文本正文：multipart 中只取第一个 text/plain 部分，并按 Content-Transfer-Encoding 解码
+func mailBody(raw []byte) string {
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(string(raw))))
+	if err != nil {
+		return ""
+	}
+	body, _ := textPart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body, 0)
+	return strings.TrimSpace(body)
+}
+
+func textPart(contentType, encoding string, r io.Reader, depth int) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// 没有或无法解析 Content-Type 时按 text/plain 处理
+		mediaType = "text/plain"
+	}
+	switch {
+	case mediaType == "text/plain":
+		data, _ := io.ReadAll(transferDecoder(encoding, r))
+		return string(data), true
+	case strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" && depth < maxMIMEDepth:
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				return "", false
+			}
+			// multipart.Reader 已透明解码 quoted-printable 并移除该头
+			if body, ok := textPart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part, depth+1); ok {
+				return body, true
+			}
+		}
+	}
+	return "", false
+}
+
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// base64Cleaner 去掉 base64 正文中的换行与空白
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	for {
+		n, err := c.r.Read(p)
+		j := 0
+		for _, b := range p[:n] {
+			if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+				p[j] = b
+				j++
+			}
+		}
+		if j > 0 || err != nil {
+			return j, err
+		}
+	}
+}
+
+// matchMail 判断邮件是否符合规则
+func matchMail(spec *IMAPSpec, m Mail) bool {
+	if spec.From != "" && !matchSender(spec.From, m.Address) {
+		return false
+	}
+	if spec.Subject != "" && !strings.Contains(strings.ToLower(m.Subject), strings.ToLower(strings.TrimSpace(spec.Subject))) {
+		return false
+	}
+	return true
+}
+
+// matchSender 发件人规则只比较解析后的地址：含 @ 时要求完整地址相同，否则视为域名，匹配该域名及其子域名
+func matchSender(rule, address string) bool {
+	rule = strings.ToLower(strings.TrimSpace(rule))
+	address = strings.ToLower(strings.TrimSpace(address))
+	at := strings.LastIndex(address, "@")
+	if at <= 0 {
+		return false
+	}
+	if strings.HasPrefix(rule, "@") {
+		rule = rule[1:]
+	} else if strings.Contains(rule, "@") {
+		return address == rule
+	}
+	host := address[at+1:]
+	return rule != "" && (host == rule || strings.HasSuffix(host, "."+rule))
+}
+
+func mailEvent(m Mail) Event {
+	return Event{
+		Kind: string(TypeIMAP),
+		Vars: map[string]string{
+			"from":    m.From,
+			"subject": m.Subject,
+			"body":    m.Body,
+			"date":    m.Date.Format(time.RFC3339),
+		},
+	}
+}
+
+// watchMail 定期轮询邮箱，每封匹配的新邮件触发一次运行；处理位置持久化，gateway 重启后继续
+func (s *Service) watchMail(ctx context.Context, t *Trigger) {
+	s.mu.RLock()
+	source := s.mail
+	s.mu.RUnlock()
+	if source == nil {
+		s.log(slog.LevelWarn, "trigger watch skipped", "trigger", t.Name, "reason", "imap_not_configured")
+		return
+	}
+	ticker := time.NewTicker(t.interval())
+	defer ticker.Stop()
+
+	for {
+		cursor := s.mailCursor(t.ID)
+		mails, next, err := source.Poll(ctx, t.IMAP.Mailbox, cursor)
+		if err != nil {
+			s.log(slog.LevelWarn, "trigger imap poll failed", "trigger", t.Name, "err", err)
+		} else {
+			for _, m := range mails {
+				if ctx.Err() != nil {
+					return
+				}
+				if matchMail(t.IMAP, m) {
+					s.run(ctx, t, mailEvent(m))
+				}
+			}
+			if next != cursor {
+				s.setMailCursor(t.ID, next)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
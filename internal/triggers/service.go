@@ -0,0 +1,671 @@
+package triggers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/cron"
+	"github.com/Lichas/maxclaw/internal/logging"
+	"github.com/Lichas/maxclaw/internal/telemetry"
+)
+
+const (
+	// runTimeout 单次运行的超时，与定时任务一致
+	runTimeout = 10 * time.Minute
+	// reloadInterval 检查 triggers.json 外部修改（如 CLI）的间隔
+	reloadInterval = 5 * time.Second
+	// maxDeliveredOutput 投递到频道的输出上限
+	maxDeliveredOutput = 4000
+)
+
+// RunFunc 以渲染好的提示词运行 agent，返回最终回复
+type RunFunc func(ctx context.Context, t *Trigger, prompt string) (string, error)
+
+// DeliverFunc 把结果发送到频道会话
+type DeliverFunc func(channel, to, content string) error
+
+// CallbackPayload 回调请求体
+type CallbackPayload struct {
+	Trigger   string `json:"trigger"`
+	TriggerID string `json:"triggerId"`
+	RecordID  string `json:"recordId"`
+	Event     string `json:"event"`
+	Status    string `json:"status"`
+	Output    string `json:"output,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// StorePath 触发器定义文件，与定时任务放在同一目录
+func StorePath(workspace string) string {
+	return filepath.Join(workspace, ".cron", "triggers.json")
+}
+
+// HistoryPath 与定时任务共享的执行历史
+func HistoryPath(workspace string) string {
+	return filepath.Join(workspace, ".cron", "cron_history.json")
+}
+
+type watcher struct {
+	cancel      context.CancelFunc
+	fingerprint string
+}
+
+// Service 管理触发器定义，监听文件与邮件事件，执行并投递结果
+type Service struct {
+	mu         sync.RWMutex
+	triggers   map[string]*Trigger
+	workspace  string
+	storePath  string
+	statePath  string
+	modTime    time.Time
+	cursors    map[string]MailCursor
+	history    *cron.HistoryStore
+	onRun      RunFunc
+	onDeliver  DeliverFunc
+	onNotify   cron.NotificationFunc
+	mail       MailSource
+	httpClient *http.Client
+	ctx        context.Context
+	cancel     context.CancelFunc
+	watchers   map[string]*watcher
+	runLocks   map[string]*sync.Mutex
+	wg         sync.WaitGroup
+}
+
+// NewService 创建触发器服务；history 为空时打开 workspace 中与定时任务共享的执行历史
+func NewService(workspace string, history *cron.HistoryStore) *Service {
+	if history == nil {
+		history = cron.NewHistoryStore(HistoryPath(workspace))
+	}
+	s := &Service{
+		triggers:   make(map[string]*Trigger),
+		workspace:  workspace,
+		storePath:  StorePath(workspace),
+		statePath:  filepath.Join(workspace, ".cron", "trigger_state.json"),
+		cursors:    make(map[string]MailCursor),
+		history:    history,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		watchers:   make(map[string]*watcher),
+		runLocks:   make(map[string]*sync.Mutex),
+	}
+	if err := s.load(); err != nil {
+		s.log(slog.LevelWarn, "trigger store load failed", "path", s.storePath, "err", err)
+	}
+	s.loadState()
+	return s
+}
+
+// SetRunner 设置 agent 执行函数
+func (s *Service) SetRunner(run RunFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRun = run
+}
+
+// SetDeliverer 设置频道投递函数
+func (s *Service) SetDeliverer(deliver DeliverFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onDeliver = deliver
+}
+
+// SetNotificationHandler 设置通知处理器
+func (s *Service) SetNotificationHandler(handler cron.NotificationFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onNotify = handler
+}
+
+// SetMailSource 设置 IMAP 触发器使用的邮件源
+func (s *Service) SetMailSource(source MailSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mail = source
+}
+
+// History 执行历史
+func (s *Service) History() *cron.HistoryStore {
+	return s.history
+}
+
+// Add 添加并启用触发器；webhook 未设置密钥时自动生成
+func (s *Service) Add(t *Trigger) (*Trigger, error) {
+	t = t.clone()
+	t.Normalize()
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	if t.Type == TypeWebhook && strings.TrimSpace(t.Webhook.Secret) == "" {
+		t.Webhook.Secret = GenerateSecret()
+	}
+	t.ID = generateTriggerID()
+	t.Enabled = true
+	t.Created = time.Now().UnixMilli()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing := s.findLocked(t.Name); existing != nil {
+		return nil, fmt.Errorf("trigger %q already exists", t.Name)
+	}
+	s.triggers[t.ID] = t
+	if err := s.saveLocked(); err != nil {
+		delete(s.triggers, t.ID)
+		return nil, fmt.Errorf("failed to save trigger: %w", err)
+	}
+	s.reconcileLocked()
+	return t.clone(), nil
+}
+
+// Update 替换触发器定义，保留 ID、启用状态与创建时间，未提供 webhook 密钥时保留原密钥
+func (s *Service) Update(idOrName string, t *Trigger) (*Trigger, error) {
+	t = t.clone()
+	t.Normalize()
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current := s.findLocked(idOrName)
+	if current == nil {
+		return nil, ErrNotFound
+	}
+	if other := s.findLocked(t.Name); other != nil && other.ID != current.ID {
+		return nil, fmt.Errorf("trigger %q already exists", t.Name)
+	}
+	if t.Type == TypeWebhook && strings.TrimSpace(t.Webhook.Secret) == "" {
+		if current.Webhook != nil && current.Webhook.Secret != "" {
+			t.Webhook.Secret = current.Webhook.Secret
+		} else {
+			t.Webhook.Secret = GenerateSecret()
+		}
+	}
+	t.ID = current.ID
+	t.Enabled = current.Enabled
+	t.Created = current.Created
+	s.triggers[t.ID] = t
+	if err := s.saveLocked(); err != nil {
+		s.triggers[current.ID] = current
+		return nil, fmt.Errorf("failed to save trigger: %w", err)
+	}
+	s.reconcileLocked()
+	return t.clone(), nil
+}
+
+// Get 按 ID 或名称获取触发器
+func (s *Service) Get(idOrName string) (*Trigger, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if t := s.findLocked(idOrName); t != nil {
+		return t.clone(), true
+	}
+	return nil, false
+}
+
+// List 按名称排序列出全部触发器
+func (s *Service) List() []*Trigger {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*Trigger, 0, len(s.triggers))
+	for _, t := range s.triggers {
+		list = append(list, t.clone())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Remove 删除触发器
+func (s *Service) Remove(idOrName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.findLocked(idOrName)
+	if t == nil {
+		return ErrNotFound
+	}
+	delete(s.triggers, t.ID)
+	delete(s.cursors, t.ID)
+	if err := s.saveLocked(); err != nil {
+		return err
+	}
+	s.reconcileLocked()
+	return nil
+}
+
+// Enable 启用或禁用触发器
+func (s *Service) Enable(idOrName string, enabled bool) (*Trigger, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.findLocked(idOrName)
+	if t == nil {
+		return nil, ErrNotFound
+	}
+	t.Enabled = enabled
+	if err := s.saveLocked(); err != nil {
+		return nil, err
+	}
+	s.reconcileLocked()
+	return t.clone(), nil
+}
+
+// HandleWebhook 校验签名后异步运行 webhook 触发器，返回执行记录 ID
+func (s *Service) HandleWebhook(name string, body []byte, header http.Header) (string, error) {
+	s.reloadIfChanged()
+	t, ok := s.Get(name)
+	if !ok || t.Type != TypeWebhook || t.Name != strings.ToLower(name) {
+		return "", ErrNotFound
+	}
+	if !t.Enabled {
+		return "", ErrDisabled
+	}
+	secret, err := config.ResolveSecret(t.Webhook.Secret)
+	if err != nil {
+		return "", fmt.Errorf("resolve webhook secret: %w", err)
+	}
+	if err := VerifySignature(secret, body, header); err != nil {
+		return "", err
+	}
+	return s.start(t, webhookEvent(t.Webhook, body, header)), nil
+}
+
+// Fire 手动触发一次运行（用于测试触发器），返回执行记录 ID；
+// webhook 触发器会把 payload 当作请求体
+func (s *Service) Fire(idOrName string, payload []byte) (string, error) {
+	t, ok := s.Get(idOrName)
+	if !ok {
+		return "", ErrNotFound
+	}
+	event := Event{Kind: "manual", Vars: map[string]string{}}
+	if t.Type == TypeWebhook {
+		event = webhookEvent(t.Webhook, payload, http.Header{})
+		event.Kind = "manual"
+	} else if len(payload) > 0 {
+		event.Vars["body"] = string(payload)
+	}
+	return s.start(t, event), nil
+}
+
+// Start 启动文件与邮件监听，并定期加载外部对 triggers.json 的修改
+func (s *Service) Start(ctx context.Context) {
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.reconcileLocked()
+	runCtx := s.ctx
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(reloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				s.reloadIfChanged()
+			}
+		}
+	}()
+}
+
+// Stop 停止监听并等待进行中的运行结束
+func (s *Service) Stop() {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.ctx, s.cancel = nil, nil
+	s.watchers = make(map[string]*watcher)
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// start 创建执行记录并异步运行
+func (s *Service) start(t *Trigger, event Event) string {
+	s.mu.RLock()
+	ctx := s.ctx
+	s.mu.RUnlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	recordID := s.addRecord(t, event)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.execute(ctx, t, event, recordID)
+	}()
+	return recordID
+}
+
+// run 同步运行，供文件与邮件监听使用
+func (s *Service) run(ctx context.Context, t *Trigger, event Event) {
+	s.execute(ctx, t, event, s.addRecord(t, event))
+}
+
+func (s *Service) addRecord(t *Trigger, event Event) string {
+	record := cron.ExecutionRecord{
+		ID:        fmt.Sprintf("exec_%d", time.Now().UnixNano()),
+		JobID:     t.ID,
+		JobTitle:  t.Name,
+		Trigger:   event.Kind,
+		StartedAt: time.Now(),
+		Status:    "running",
+	}
+	s.history.AddRecord(record)
+	return record.ID
+}
+
+func (s *Service) execute(ctx context.Context, t *Trigger, event Event, recordID string) {
+	// 同一触发器的运行串行执行，避免并发写同一会话
+	lock := s.runLock(t.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	s.mu.RLock()
+	run := s.onRun
+	s.mu.RUnlock()
+
+	s.log(slog.LevelInfo, "trigger execute", "trigger", t.Name, "trigger_id", t.ID, "event", event.Kind)
+	start := time.Now()
+	var result string
+	var err error
+	if run == nil {
+		err = fmt.Errorf("trigger runner not configured")
+	} else {
+		runCtx, cancel := context.WithTimeout(ctx, runTimeout)
+		result, err = run(runCtx, t, RenderPrompt(t, event))
+		cancel()
+	}
+	elapsed := time.Since(start)
+	telemetry.RecordCronRun(event.Kind, elapsed, err)
+
+	now := time.Now()
+	s.history.UpdateRecord(recordID, func(r *cron.ExecutionRecord) {
+		r.EndedAt = &now
+		r.Duration = elapsed.Milliseconds()
+		r.Output = result
+		if err != nil {
+			r.Status = "failed"
+			r.Error = err.Error()
+		} else {
+			r.Status = "success"
+		}
+	})
+	if err != nil {
+		s.log(slog.LevelError, "trigger failed", "trigger", t.Name, "trigger_id", t.ID, "duration_ms", elapsed.Milliseconds(), "err", err)
+	} else {
+		s.log(slog.LevelInfo, "trigger completed", "trigger", t.Name, "trigger_id", t.ID, "duration_ms", elapsed.Milliseconds(), "result", logging.Truncate(result, 400))
+	}
+	s.deliver(t, event, recordID, result, err)
+}
+
+// deliver 按 Route 投递结果
+func (s *Service) deliver(t *Trigger, event Event, recordID, result string, runErr error) {
+	s.mu.RLock()
+	deliver, notify := s.onDeliver, s.onNotify
+	s.mu.RUnlock()
+
+	status := "success"
+	if runErr != nil {
+		status = "failed"
+	}
+	if t.Route.Channel != "" && t.Route.To != "" && deliver != nil {
+		if err := deliver(t.Route.Channel, t.Route.To, formatDelivery(t, result, runErr)); err != nil {
+			s.log(slog.LevelError, "trigger deliver failed", "trigger", t.Name, "channel", t.Route.Channel, "err", err)
+		}
+	}
+	if t.Route.CallbackURL != "" {
+		payload := CallbackPayload{
+			Trigger:   t.Name,
+			TriggerID: t.ID,
+			RecordID:  recordID,
+			Event:     event.Kind,
+			Status:    status,
+			Output:    result,
+		}
+		if runErr != nil {
+			payload.Error = runErr.Error()
+		}
+		if err := s.postCallback(t, payload); err != nil {
+			s.log(slog.LevelError, "trigger callback failed", "trigger", t.Name, "url", t.Route.CallbackURL, "err", err)
+		}
+	}
+	if t.Route.Notify && notify != nil {
+		title, body := "触发器完成", fmt.Sprintf("触发器 \"%s\" 执行完成", t.Name)
+		if runErr != nil {
+			title, body = "触发器执行失败", fmt.Sprintf("触发器 \"%s\" 执行失败: %v", t.Name, runErr)
+		}
+		notify(title, body, map[string]interface{}{
+			"type":        "trigger",
+			"triggerId":   t.ID,
+			"triggerName": t.Name,
+			"recordId":    recordID,
+			"status":      status,
+		})
+	}
+}
+
+// postCallback 以 JSON POST 结果；webhook 触发器用同一密钥签名
+func (s *Service) postCallback(t *Trigger, payload CallbackPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.Route.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.Webhook != nil {
+		if secret, err := config.ResolveSecret(t.Webhook.Secret); err == nil && secret != "" {
+			req.Header.Set(SignatureHeader, Sign(secret, body))
+		}
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned %s", resp.Status)
+	}
+	return nil
+}
+
+func formatDelivery(t *Trigger, result string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("❌ **触发器执行失败**\n\n**触发器**: %s\n**错误**: %v", t.Name, err)
+	}
+	if strings.TrimSpace(result) == "" {
+		return fmt.Sprintf("✅ **触发器完成**\n\n**触发器**: %s\n\n无输出内容", t.Name)
+	}
+	if len(result) > maxDeliveredOutput {
+		result = result[:maxDeliveredOutput] + "\n\n...(内容已截断)"
+	}
+	return fmt.Sprintf("✅ **触发器完成**\n\n**触发器**: %s\n\n%s", t.Name, result)
+}
+
+func (s *Service) runLock(id string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.runLocks[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.runLocks[id] = lock
+	}
+	return lock
+}
+
+// findLocked 按 ID 或名称查找，调用方持有锁
+func (s *Service) findLocked(idOrName string) *Trigger {
+	if t, ok := s.triggers[idOrName]; ok {
+		return t
+	}
+	name := strings.ToLower(strings.TrimSpace(idOrName))
+	for _, t := range s.triggers {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// reconcileLocked 让文件与邮件监听与当前定义一致：新增或修改的重新启动，删除或禁用的停止
+func (s *Service) reconcileLocked() {
+	if s.ctx == nil {
+		return
+	}
+	wanted := make(map[string]*Trigger)
+	for id, t := range s.triggers {
+		if t.Enabled && (t.Type == TypeFile || t.Type == TypeIMAP) {
+			wanted[id] = t
+		}
+	}
+	for id, w := range s.watchers {
+		t, ok := wanted[id]
+		if !ok || fingerprint(t) != w.fingerprint {
+			w.cancel()
+			delete(s.watchers, id)
+		}
+	}
+	for id, t := range wanted {
+		if _, ok := s.watchers[id]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(s.ctx)
+		s.watchers[id] = &watcher{cancel: cancel, fingerprint: fingerprint(t)}
+		snapshot := t.clone()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if snapshot.Type == TypeFile {
+				s.watchFiles(ctx, snapshot)
+			} else {
+				s.watchMail(ctx, snapshot)
+			}
+		}()
+	}
+}
+
+func fingerprint(t *Trigger) string {
+	data, _ := json.Marshal(t)
+	return string(data)
+}
+
+// reloadIfChanged 在 triggers.json 被外部修改（如 maxclaw trigger add）后重新加载
+func (s *Service) reloadIfChanged() {
+	info, err := os.Stat(s.storePath)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if info.ModTime().Equal(s.modTime) {
+		return
+	}
+	if err := s.loadLocked(); err != nil {
+		s.log(slog.LevelWarn, "trigger store reload failed", "path", s.storePath, "err", err)
+		return
+	}
+	s.reconcileLocked()
+}
+
+func (s *Service) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadLocked()
+}
+
+func (s *Service) loadLocked() error {
+	data, err := os.ReadFile(s.storePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var triggers map[string]*Trigger
+	if err := json.Unmarshal(data, &triggers); err != nil {
+		return err
+	}
+	for _, t := range triggers {
+		t.Normalize()
+	}
+	if triggers == nil {
+		triggers = make(map[string]*Trigger)
+	}
+	s.triggers = triggers
+	if info, err := os.Stat(s.storePath); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// saveLocked 写入 triggers.json；文件包含 webhook 密钥，只对当前用户可读
+func (s *Service) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(s.storePath), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.triggers, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.storePath, data, 0600); err != nil {
+		return err
+	}
+	if info, err := os.Stat(s.storePath); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+func (s *Service) mailCursor(id string) MailCursor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cursors[id]
+}
+
+func (s *Service) setMailCursor(id string, cursor MailCursor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursors[id] = cursor
+	data, err := json.MarshalIndent(s.cursors, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(s.statePath), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(s.statePath, data, 0644)
+	}
+	if err != nil {
+		s.log(slog.LevelWarn, "trigger state save failed", "path", s.statePath, "err", err)
+	}
+}
+
+func (s *Service) loadState() {
+	data, err := os.ReadFile(s.statePath)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &s.cursors)
+}
+
+func (s *Service) log(level slog.Level, msg string, args ...any) {
+	if lg := logging.Get(); lg != nil && lg.Cron != nil {
+		lg.Cron.Log(context.Background(), level, msg, args...)
+	}
+}
@@ -0,0 +1,130 @@
+package triggers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxVarLength 单个模板变量写入提示词的最大长度
+const maxVarLength = 8000
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// defaultPrompts 未设置 Prompt 时使用的模板
+var defaultPrompts = map[Type]string{
+	TypeWebhook: "Webhook \"{{trigger}}\" received this payload:\n\n{{body}}",
+	TypeFile:    "Files changed in the workspace (trigger \"{{trigger}}\"):\n{{files}}",
+	TypeIMAP:    "New email matched trigger \"{{trigger}}\".\nFrom: {{from}}\nSubject: {{subject}}\n\n{{body}}",
+}
+
+// RenderPrompt 渲染触发器提示词：{{name}} 先取事件变量（请求头为 {{header.X-GitHub-Event}}），
+// 否则作为 JSON 路径在请求体中查找（如 {{pull_request.title}}），找不到时为空
+func RenderPrompt(t *Trigger, event Event) string {
+	tmpl := strings.TrimSpace(t.Prompt)
+	if tmpl == "" {
+		tmpl = defaultPrompts[t.Type]
+	}
+	vars := map[string]string{"trigger": t.Name}
+	for k, v := range event.Vars {
+		vars[k] = v
+	}
+	return placeholderPattern.ReplaceAllStringFunc(tmpl, func(m string) string {
+		name := strings.TrimSpace(placeholderPattern.FindStringSubmatch(m)[1])
+		if rest, ok := strings.CutPrefix(name, "header."); ok {
+			name = "header." + http.CanonicalHeaderKey(rest)
+		}
+		if v, ok := vars[name]; ok {
+			return truncateVar(v)
+		}
+		if v, ok := LookupJSONPath(event.Doc, name); ok {
+			return truncateVar(FormatJSONValue(v))
+		}
+		return ""
+	})
+}
+
+// LookupJSONPath 在已解析的 JSON 中按路径取值，路径形如 a.b[0].c、a.b.0.c，可带前缀 $.
+func LookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if doc == nil {
+		return nil, false
+	}
+	if path == "" {
+		return doc, true
+	}
+	cur := doc
+	for _, seg := range splitJSONPath(path) {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[seg]
+			if !ok {
+				return nil, false
+			}
+			cur = v
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			cur = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func splitJSONPath(path string) []string {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	var segs []string
+	for _, seg := range strings.Split(path, ".") {
+		if seg = strings.TrimSpace(seg); seg != "" {
+			segs = append(segs, seg)
+		}
+	}
+	return segs
+}
+
+// FormatJSONValue 字符串原样返回，其余值编码为 JSON
+func FormatJSONValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// extractVars 按 WebhookSpec.Extract 从请求体中提取变量
+func extractVars(doc interface{}, extract map[string]string) map[string]string {
+	vars := make(map[string]string, len(extract))
+	for name, path := range extract {
+		if v, ok := LookupJSONPath(doc, path); ok {
+			vars[strings.TrimSpace(name)] = FormatJSONValue(v)
+		} else {
+			vars[strings.TrimSpace(name)] = ""
+		}
+	}
+	return vars
+}
+
+func truncateVar(s string) string {
+	if r := []rune(s); len(r) > maxVarLength {
+		return string(r[:maxVarLength]) + "\n...(truncated)"
+	}
+	return s
+}
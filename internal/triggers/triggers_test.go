@@ -0,0 +1,356 @@
+package triggers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/cron"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderPromptUsesVarsAndJSONPaths(t *testing.T) {
+	var doc interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"pull_request":{"title":"Fix login","number":42,"labels":[{"name":"bug"}]}}`), &doc))
+
+	trigger := &Trigger{Name: "github", Type: TypeWebhook, Prompt: "[{{trigger}}] {{event}} #{{pull_request.number}} {{ title }} ({{$.pull_request.labels[0].name}}){{missing}}"}
+	event := Event{Doc: doc, Vars: extractVars(doc, map[string]string{"title": "pull_request.title"})}
+	event.Vars["header.X-Github-Event"] = "pull_request"
+	trigger.Prompt += " via {{header.X-GitHub-Event}}"
+	event.Vars["event"] = "opened"
+	assert.Equal(t, "[github] opened #42 Fix login (bug) via pull_request", RenderPrompt(trigger, event))
+
+	labels, ok := LookupJSONPath(doc, "pull_request.labels")
+	require.True(t, ok)
+	assert.Equal(t, `[{"name":"bug"}]`, FormatJSONValue(labels))
+	_, ok = LookupJSONPath(doc, "pull_request.labels.3.name")
+	assert.False(t, ok)
+
+	// 未设置模板时使用默认模板
+	assert.Equal(t, "Files changed in the workspace (trigger \"docs\"):\n- write: docs/a.md",
+		RenderPrompt(&Trigger{Name: "docs", Type: TypeFile}, Event{Vars: map[string]string{"files": "- write: docs/a.md"}}))
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"ok":true}`)
+	header := http.Header{}
+	assert.ErrorIs(t, VerifySignature("s3cret", body, header), ErrBadSignature)
+
+	header.Set("X-Hub-Signature-256", Sign("s3cret", body))
+	assert.NoError(t, VerifySignature("s3cret", body, header))
+	assert.ErrorIs(t, VerifySignature("other", body, header), ErrBadSignature)
+	assert.ErrorIs(t, VerifySignature("", body, header), ErrBadSignature)
+}
+
+func TestTriggerValidation(t *testing.T) {
+	for _, bad := range []*Trigger{
+		{Name: "Bad Name", Type: TypeWebhook},
+		{Name: "x", Type: "cron"},
+		{Name: "x", Type: TypeFile},
+		{Name: "x", Type: TypeFile, File: &FileSpec{Paths: []string{"../outside"}}},
+		{Name: "x", Type: TypeFile, File: &FileSpec{Paths: []string{"docs"}, Events: []string{"chmod"}}},
+		{Name: "x", Type: TypeIMAP},
+		{Name: "x", Type: TypeWebhook, Route: Route{Channel: "telegram"}},
+		{Name: "x", Type: TypeWebhook, Route: Route{CallbackURL: "ftp://example.com"}},
+	} {
+		bad.Normalize()
+		assert.Error(t, bad.Validate(), "%+v", bad)
+	}
+}
+
+func TestWebhookTriggerRunsAndRoutesResult(t *testing.T) {
+	workspace := t.TempDir()
+	svc := NewService(workspace, nil)
+
+	var mu sync.Mutex
+	var prompts, delivered []string
+	var notified []map[string]interface{}
+	svc.SetRunner(func(ctx context.Context, tr *Trigger, prompt string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		prompts = append(prompts, prompt)
+		return "triaged", nil
+	})
+	svc.SetDeliverer(func(channel, to, content string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered = append(delivered, channel+"|"+to+"|"+content)
+		return nil
+	})
+	svc.SetNotificationHandler(func(title, body string, data map[string]interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		notified = append(notified, data)
+	})
+	callbacks := make(chan CallbackPayload, 1)
+	callbackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload CallbackPayload
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		assert.NotEmpty(t, r.Header.Get(SignatureHeader))
+		callbacks <- payload
+	}))
+	defer callbackServer.Close()
+
+	trigger, err := svc.Add(&Trigger{
+		Name:    "GitHub",
+		Type:    TypeWebhook,
+		Prompt:  "Triage issue: {{title}}",
+		Webhook: &WebhookSpec{Extract: map[string]string{"title": "issue.title"}},
+		Route:   Route{Channel: "telegram", To: "1001", CallbackURL: callbackServer.URL, Notify: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "github", trigger.Name)
+	assert.True(t, trigger.Enabled)
+	require.NotEmpty(t, trigger.Webhook.Secret)
+	info, err := os.Stat(StorePath(workspace))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	body := []byte(`{"issue":{"title":"Login broken"}}`)
+	_, err = svc.HandleWebhook("github", body, http.Header{})
+	assert.ErrorIs(t, err, ErrBadSignature)
+	_, err = svc.HandleWebhook("unknown", body, http.Header{})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	header := http.Header{}
+	header.Set(SignatureHeader, Sign(trigger.Webhook.Secret, body))
+	recordID, err := svc.HandleWebhook("github", body, header)
+	require.NoError(t, err)
+
+	payload := <-callbacks
+	assert.Equal(t, "success", payload.Status)
+	assert.Equal(t, "triaged", payload.Output)
+	assert.Equal(t, recordID, payload.RecordID)
+	svc.Stop()
+
+	record, ok := svc.History().GetRecord(recordID)
+	require.True(t, ok)
+	assert.Equal(t, trigger.ID, record.JobID)
+	assert.Equal(t, "webhook", record.Trigger)
+	assert.Equal(t, "success", record.Status)
+	// 执行历史与定时任务共享同一文件
+	assert.Len(t, cron.NewHistoryStore(HistoryPath(workspace)).GetRecords(trigger.ID, 0), 1)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"Triage issue: Login broken"}, prompts)
+	require.Len(t, delivered, 1)
+	assert.Contains(t, delivered[0], "telegram|1001|")
+	assert.Contains(t, delivered[0], "triaged")
+	require.Len(t, notified, 1)
+	assert.Equal(t, "trigger", notified[0]["type"])
+
+	_, err = svc.Enable("github", false)
+	require.NoError(t, err)
+	_, err = svc.HandleWebhook("github", body, header)
+	assert.ErrorIs(t, err, ErrDisabled)
+}
+
+func TestServicePicksUpTriggersAddedElsewhere(t *testing.T) {
+	workspace := t.TempDir()
+	gateway := NewService(workspace, nil)
+	cli := NewService(workspace, nil)
+
+	trigger, err := cli.Add(&Trigger{Name: "deploy", Type: TypeWebhook, Webhook: &WebhookSpec{Secret: "s3cret"}})
+	require.NoError(t, err)
+
+	body := []byte(`{}`)
+	header := http.Header{}
+	header.Set(SignatureHeader, Sign("s3cret", body))
+	_, err = gateway.HandleWebhook("deploy", body, header)
+	require.NoError(t, err)
+	gateway.Stop()
+
+	// 没有配置执行函数时记录失败
+	records := gateway.History().GetRecords(trigger.ID, 0)
+	require.Len(t, records, 1)
+	assert.Equal(t, "failed", records[0].Status)
+}
+
+func TestFileChangesAreMergedAndFiltered(t *testing.T) {
+	workspace := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workspace, "docs", ".drafts"), 0755))
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(workspace, name), []byte(content), 0644))
+	}
+	write("docs/a.md", "a")
+	write("docs/b.txt", "b")
+	write("docs/.drafts/c.md", "c")
+
+	spec := &FileSpec{Paths: []string{"docs"}, Patterns: []string{"*.md"}, Events: []string{FileCreate, FileWrite}}
+	before := scanFiles(workspace, spec)
+	assert.Len(t, before, 1, "hidden directories and non-matching files are skipped")
+
+	write("docs/a.md", "changed")
+	write("docs/new.md", "new")
+	after := scanFiles(workspace, spec)
+	pending := map[string]string{}
+	for path, event := range diffFiles(before, after) {
+		mergeFileChange(pending, path, event)
+	}
+	mergeFileChange(pending, "docs/new.md", FileWrite)
+	mergeFileChange(pending, "docs/tmp.md", FileCreate)
+	mergeFileChange(pending, "docs/tmp.md", FileRemove)
+	mergeFileChange(pending, "docs/old.md", FileRemove)
+
+	event, ok := fileEvent(spec, pending)
+	require.True(t, ok)
+	assert.Equal(t, "- write: docs/a.md\n- create: docs/new.md", event.Vars["files"])
+	assert.Equal(t, "2", event.Vars["count"])
+
+	_, ok = fileEvent(spec, map[string]string{"docs/old.md": FileRemove})
+	assert.False(t, ok, "remove events are filtered out")
+}
+
+type fakeMailSource struct {
+	mu    sync.Mutex
+	polls []MailCursor
+	mails []Mail
+}
+
+func (f *fakeMailSource) Poll(ctx context.Context, mailbox string, cursor MailCursor) ([]Mail, MailCursor, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.polls = append(f.polls, cursor)
+	if cursor.LastUID == 0 {
+		return nil, MailCursor{UIDValidity: 1, LastUID: 10}, nil
+	}
+	mails := f.mails
+	f.mails = nil
+	next := cursor
+	for _, m := range mails {
+		next.LastUID = m.UID
+	}
+	return mails, next, nil
+}
+
+func TestIMAPTriggerRunsForMatchingMail(t *testing.T) {
+	workspace := t.TempDir()
+	svc := NewService(workspace, nil)
+	source := &fakeMailSource{mails: []Mail{
+		{UID: 11, From: "Alerts <alerts@example.com>", Address: "alerts@example.com", Subject: "Disk almost full", Body: "95% used"},
+		{UID: 12, From: "alerts@example.com <friend@example.com>", Address: "friend@example.com", Subject: "Lunch?"},
+	}}
+	svc.SetMailSource(source)
+	runs := make(chan string, 2)
+	svc.SetRunner(func(ctx context.Context, tr *Trigger, prompt string) (string, error) {
+		runs <- prompt
+		return "", nil
+	})
+
+	trigger, err := svc.Add(&Trigger{Name: "alerts", Type: TypeIMAP, IMAP: &IMAPSpec{From: "Alerts@Example.com"}})
+	require.NoError(t, err)
+	assert.Equal(t, "INBOX", trigger.IMAP.Mailbox)
+	// 已有基线时直接读取新邮件
+	svc.setMailCursor(trigger.ID, MailCursor{UIDValidity: 1, LastUID: 10})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc.Start(ctx)
+	select {
+	case prompt := <-runs:
+		assert.Contains(t, prompt, "Subject: Disk almost full")
+		assert.Contains(t, prompt, "95% used")
+	case <-time.After(5 * time.Second):
+		t.Fatal("imap trigger did not run")
+	}
+	svc.Stop()
+	assert.Empty(t, runs, "non-matching mail does not trigger a run")
+
+	reloaded := NewService(workspace, nil)
+	assert.Equal(t, MailCursor{UIDValidity: 1, LastUID: 12}, reloaded.mailCursor(trigger.ID))
+}
+
+func TestMatchMailComparesParsedSenderAddress(t *testing.T) {
+	cases := []struct {
+		rule, address string
+		want          bool
+	}{
+		{"alerts@example.com", "alerts@example.com", true},
+		{"ALERTS@example.com", "alerts@EXAMPLE.com", true},
+		{"alerts@example.com", "alerts@example.com.evil.test", false},
+		{"alerts@example.com", "x-alerts@example.com", false},
+		{"example.com", "ops@example.com", true},
+		{"@example.com", "ops@mail.example.com", true},
+		{"example.com", "ops@notexample.com", false},
+		{"example.com", "", false},
+	}
+	for _, c := range cases {
+		spec := &IMAPSpec{From: c.rule}
+		assert.Equal(t, c.want, matchMail(spec, Mail{Address: c.address}), "%s vs %s", c.rule, c.address)
+	}
+	// 显示名中的地址不参与匹配
+	spec := &IMAPSpec{From: "alerts@example.com"}
+	assert.False(t, matchMail(spec, Mail{From: "alerts@example.com <attacker@evil.test>", Address: "attacker@evil.test"}))
+}
+
+func TestMailBodyDecodesTextPlainPart(t *testing.T) {
+	raw := strings.Join([]string{
+		"From: a@example.com",
+		"Subject: hi",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/mixed; boundary="outer"`,
+		"",
+		"--outer",
+		`Content-Type: multipart/alternative; boundary="inner"`,
+		"",
+		"--inner",
+		"Content-Type: text/html; charset=UTF-8",
+		"",
+		"<p>html version</p>",
+		"--inner",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: base64",
+		"",
+		"5L2g5aW977yM",
+		"5LiW55WM",
+		"--inner--",
+		"--outer",
+		"Content-Type: application/octet-stream",
+		"",
+		"binary",
+		"--outer--",
+		"",
+	}, "\r\n")
+	assert.Equal(t, "你好，世界", mailBody([]byte(raw)))
+
+	qp := "Subject: qp\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nline =\r\ncontinued =3D ok\r\n"
+	assert.Equal(t, "line continued = ok", mailBody([]byte(qp)))
+
+	htmlOnly := "Subject: html\r\nContent-Type: text/html\r\n\r\n<p>x</p>\r\n"
+	assert.Empty(t, mailBody([]byte(htmlOnly)))
+}
+
+func TestIMAPSourcePollHonorsContext(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	// 服务器接受连接但从不发送问候
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+	source := NewIMAPSource(IMAPAccount{Host: "127.0.0.1", Port: addr.Port})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err = source.Poll(ctx, "INBOX", MailCursor{})
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
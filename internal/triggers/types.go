@@ -0,0 +1,267 @@
+package triggers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Type 触发器类型
+type Type string
+
+const (
+	// TypeWebhook 入站 webhook：POST /hooks/{name}
+	TypeWebhook Type = "webhook"
+	// TypeFile workspace 目录中的文件变化
+	TypeFile Type = "file"
+	// TypeIMAP 收件箱中匹配规则的新邮件
+	TypeIMAP Type = "imap"
+)
+
+// Types 全部触发器类型
+var Types = []Type{TypeWebhook, TypeFile, TypeIMAP}
+
+// 文件事件
+const (
+	FileCreate = "create"
+	FileWrite  = "write"
+	FileRemove = "remove"
+)
+
+var (
+	// ErrNotFound 触发器不存在
+	ErrNotFound = errors.New("trigger not found")
+	// ErrDisabled 触发器已禁用
+	ErrDisabled = errors.New("trigger is disabled")
+	// ErrBadSignature webhook 签名缺失或不匹配
+	ErrBadSignature = errors.New("invalid webhook signature")
+)
+
+// namePattern 名称同时用作 webhook 路径，限制为 URL 安全字符
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// WebhookSpec webhook 触发器配置
+type WebhookSpec struct {
+	// Secret HMAC-SHA256 密钥，可以是密钥引用；为空时创建触发器会自动生成
+	Secret string `json:"secret,omitempty"`
+	// Extract 从 JSON 请求体中提取变量：变量名 → JSON 路径（如 pull_request.title、commits[0].id）
+	Extract map[string]string `json:"extract,omitempty"`
+}
+
+// FileSpec 文件变化触发器配置
+type FileSpec struct {
+	// Paths 相对 workspace 的目录或文件
+	Paths []string `json:"paths"`
+	// Patterns 文件名通配（如 *.md），为空时匹配全部文件
+	Patterns []string `json:"patterns,omitempty"`
+	// Events create、write、remove，为空时匹配全部
+	Events []string `json:"events,omitempty"`
+	// IntervalSeconds 扫描间隔，默认 5 秒；变化在一个间隔内没有新变化后才触发
+	IntervalSeconds int `json:"intervalSeconds,omitempty"`
+}
+
+// IMAPSpec 邮件规则，使用 channels.email 的 IMAP 账号
+type IMAPSpec struct {
+	Mailbox string `json:"mailbox,omitempty"`
+	// From 发件人地址（完整地址）或域名（如 example.com，包含子域名），不区分大小写
+	From string `json:"from,omitempty"`
+	// Subject 主题包含的文本（不区分大小写）
+	Subject string `json:"subject,omitempty"`
+	// IntervalSeconds 轮询间隔，默认 60 秒
+	IntervalSeconds int `json:"intervalSeconds,omitempty"`
+}
+
+// Route 结果的去向，可同时设置多个；都未设置时结果只记录在执行历史中
+type Route struct {
+	Channel string `json:"channel,omitempty"`
+	To      string `json:"to,omitempty"`
+	// CallbackURL 以 JSON POST 执行结果
+	CallbackURL string `json:"callbackUrl,omitempty"`
+	// Notify 写入桌面通知
+	Notify bool `json:"notify,omitempty"`
+}
+
+// Trigger 事件触发器：事件发生时按 Prompt 模板启动一次 agent 运行
+type Trigger struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Type    Type   `json:"type"`
+	Enabled bool   `json:"enabled"`
+	// Prompt 提示词模板，{{var}} 替换为事件变量，见 RenderPrompt
+	Prompt  string       `json:"prompt,omitempty"`
+	Webhook *WebhookSpec `json:"webhook,omitempty"`
+	File    *FileSpec    `json:"file,omitempty"`
+	IMAP    *IMAPSpec    `json:"imap,omitempty"`
+	Route   Route        `json:"route"`
+	Created int64        `json:"created"`
+}
+
+// Event 一次触发事件
+type Event struct {
+	// Kind webhook、file、imap 或 manual
+	Kind string
+	// Vars 模板变量
+	Vars map[string]string
+	// Doc 已解析的 JSON 请求体，模板中的 JSON 路径在其中查找
+	Doc interface{}
+}
+
+// Normalize 整理字段并填充默认值
+func (t *Trigger) Normalize() {
+	t.Name = strings.ToLower(strings.TrimSpace(t.Name))
+	t.Type = Type(strings.ToLower(strings.TrimSpace(string(t.Type))))
+	t.Route.Channel = strings.TrimSpace(t.Route.Channel)
+	t.Route.To = strings.TrimSpace(t.Route.To)
+	t.Route.CallbackURL = strings.TrimSpace(t.Route.CallbackURL)
+	switch t.Type {
+	case TypeWebhook:
+		if t.Webhook == nil {
+			t.Webhook = &WebhookSpec{}
+		}
+	case TypeFile:
+		if t.File == nil {
+			t.File = &FileSpec{}
+		}
+		for i, event := range t.File.Events {
+			t.File.Events[i] = strings.ToLower(strings.TrimSpace(event))
+		}
+	case TypeIMAP:
+		if t.IMAP == nil {
+			t.IMAP = &IMAPSpec{}
+		}
+		if strings.TrimSpace(t.IMAP.Mailbox) == "" {
+			t.IMAP.Mailbox = "INBOX"
+		}
+	}
+}
+
+// Validate 检查触发器定义
+func (t *Trigger) Validate() error {
+	if !namePattern.MatchString(t.Name) {
+		return fmt.Errorf("invalid trigger name %q: use lowercase letters, digits, - and _", t.Name)
+	}
+	if (t.Route.Channel == "") != (t.Route.To == "") {
+		return fmt.Errorf("route needs both channel and to")
+	}
+	if u := t.Route.CallbackURL; u != "" && !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return fmt.Errorf("callback URL must start with http:// or https://")
+	}
+	switch t.Type {
+	case TypeWebhook:
+		for name, path := range t.Webhook.Extract {
+			if strings.TrimSpace(name) == "" || strings.TrimSpace(path) == "" {
+				return fmt.Errorf("webhook extract entries must look like name=json.path")
+			}
+		}
+	case TypeFile:
+		if len(t.File.Paths) == 0 {
+			return fmt.Errorf("file trigger needs at least one path")
+		}
+		for _, p := range t.File.Paths {
+			if filepath.IsAbs(p) || strings.HasPrefix(filepath.Clean(p), "..") {
+				return fmt.Errorf("file trigger path %q must be inside the workspace", p)
+			}
+		}
+		for _, pattern := range t.File.Patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+			}
+		}
+		for _, event := range t.File.Events {
+			if event != FileCreate && event != FileWrite && event != FileRemove {
+				return fmt.Errorf("invalid file event %q (want create, write or remove)", event)
+			}
+		}
+	case TypeIMAP:
+		if strings.TrimSpace(t.IMAP.From) == "" && strings.TrimSpace(t.IMAP.Subject) == "" {
+			return fmt.Errorf("imap trigger needs a from or subject rule")
+		}
+	default:
+		return fmt.Errorf("invalid trigger type %q (want webhook, file or imap)", t.Type)
+	}
+	return nil
+}
+
+// Summary 一行描述触发条件，用于列表显示
+func (t *Trigger) Summary() string {
+	switch t.Type {
+	case TypeWebhook:
+		return "POST /hooks/" + t.Name
+	case TypeFile:
+		s := strings.Join(t.File.Paths, ", ")
+		if len(t.File.Patterns) > 0 {
+			s += " (" + strings.Join(t.File.Patterns, ", ") + ")"
+		}
+		return s
+	case TypeIMAP:
+		var rules []string
+		if t.IMAP.From != "" {
+			rules = append(rules, "from="+t.IMAP.From)
+		}
+		if t.IMAP.Subject != "" {
+			rules = append(rules, "subject~"+t.IMAP.Subject)
+		}
+		return t.IMAP.Mailbox + " " + strings.Join(rules, " ")
+	}
+	return ""
+}
+
+// interval 文件扫描或邮件轮询间隔
+func (t *Trigger) interval() time.Duration {
+	switch t.Type {
+	case TypeFile:
+		if t.File.IntervalSeconds > 0 {
+			return time.Duration(t.File.IntervalSeconds) * time.Second
+		}
+		return 5 * time.Second
+	case TypeIMAP:
+		if t.IMAP.IntervalSeconds > 0 {
+			return time.Duration(t.IMAP.IntervalSeconds) * time.Second
+		}
+		return time.Minute
+	}
+	return 0
+}
+
+// clone 深拷贝，避免调用方修改正在运行的定义
+func (t *Trigger) clone() *Trigger {
+	c := *t
+	if t.Webhook != nil {
+		w := *t.Webhook
+		w.Extract = make(map[string]string, len(t.Webhook.Extract))
+		for k, v := range t.Webhook.Extract {
+			w.Extract[k] = v
+		}
+		c.Webhook = &w
+	}
+	if t.File != nil {
+		f := *t.File
+		f.Paths = append([]string(nil), t.File.Paths...)
+		f.Patterns = append([]string(nil), t.File.Patterns...)
+		f.Events = append([]string(nil), t.File.Events...)
+		c.File = &f
+	}
+	if t.IMAP != nil {
+		m := *t.IMAP
+		c.IMAP = &m
+	}
+	return &c
+}
+
+// GenerateSecret 生成 webhook 密钥
+func GenerateSecret() string {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+func generateTriggerID() string {
+	return fmt.Sprintf("trg_%d", time.Now().UnixNano())
+}
@@ -0,0 +1,76 @@
+package triggers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// SignatureHeader maxclaw 使用的签名头，值为 sha256=<hex HMAC-SHA256(secret, body)>
+const SignatureHeader = "X-Maxclaw-Signature-256"
+
+// signatureHeaders 接受的签名头；X-Hub-Signature-256 兼容 GitHub webhook
+var signatureHeaders = []string{SignatureHeader, "X-Hub-Signature-256"}
+
+// Sign 计算请求体签名
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature 校验请求头中的签名
+func VerifySignature(secret string, body []byte, header http.Header) error {
+	if secret == "" {
+		return ErrBadSignature
+	}
+	expected := []byte(Sign(secret, body))
+	for _, name := range signatureHeaders {
+		got := strings.TrimSpace(header.Get(name))
+		if got != "" && hmac.Equal([]byte(strings.ToLower(got)), expected) {
+			return nil
+		}
+	}
+	return ErrBadSignature
+}
+
+// webhookEvent 把请求转为触发事件：body 为格式化后的请求体，
+// header.<Name> 为请求头，另加 Extract 中定义的变量
+func webhookEvent(spec *WebhookSpec, body []byte, header http.Header) Event {
+	event := Event{Kind: string(TypeWebhook), Vars: map[string]string{}}
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err == nil {
+		event.Doc = doc
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") == nil {
+			event.Vars["body"] = pretty.String()
+		}
+	}
+	if _, ok := event.Vars["body"]; !ok {
+		event.Vars["body"] = string(body)
+	}
+	for name, values := range header {
+		if len(values) > 0 && !isSignatureHeader(name) {
+			event.Vars["header."+http.CanonicalHeaderKey(name)] = values[0]
+		}
+	}
+	if spec != nil {
+		for k, v := range extractVars(event.Doc, spec.Extract) {
+			event.Vars[k] = v
+		}
+	}
+	return event
+}
+
+func isSignatureHeader(name string) bool {
+	for _, h := range signatureHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
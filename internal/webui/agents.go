@@ -0,0 +1,98 @@
+package webui
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/config"
+)
+
+type agentProfileResponse struct {
+	Name          string   `json:"name"`
+	Description   string   `json:"description,omitempty"`
+	Model         string   `json:"model"`
+	Workspace     string   `json:"workspace"`
+	ExecutionMode string   `json:"executionMode"`
+	Tools         []string `json:"tools,omitempty"`
+	Skills        []string `json:"skills,omitempty"`
+	Default       bool     `json:"default"`
+	Active        bool     `json:"active"`
+}
+
+// SetAgentRouter enables named agent profile selection for Web UI requests.
+func (s *Server) SetAgentRouter(router *agent.AgentRouter) {
+	s.agentRouter = router
+}
+
+func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	names := append([]string{config.DefaultAgentName}, s.cfg.AgentProfileNames()...)
+	out := make([]agentProfileResponse, 0, len(names))
+	for _, name := range names {
+		resolved, ok := s.cfg.ResolveAgent(name)
+		if !ok {
+			continue
+		}
+		_, active := s.agentLoopByName(name)
+		out = append(out, agentProfileResponse{
+			Name:          resolved.Name,
+			Description:   resolved.Description,
+			Model:         resolved.Model,
+			Workspace:     resolved.Workspace,
+			ExecutionMode: resolved.ExecutionMode,
+			Tools:         resolved.Tools,
+			Skills:        resolved.Skills,
+			Default:       resolved.Name == config.DefaultAgentName,
+			Active:        active,
+		})
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"agents": out,
+		"routes": s.cfg.Agents.Routes,
+	})
+}
+
+// resolveAgentLoop picks the loop for a Web UI message: an explicit profile wins,
+// otherwise agents.routes are evaluated against the payload channel/chat.
+func (s *Server) resolveAgentLoop(payload messagePayload) (*agent.AgentLoop, error) {
+	name := strings.TrimSpace(payload.Agent)
+	if name == "" {
+		name = s.cfg.MatchAgentRoute(payload.Channel, payload.ChatID, "")
+	}
+	if loop, ok := s.agentLoopByName(name); ok {
+		return loop, nil
+	}
+	if strings.TrimSpace(payload.Agent) != "" {
+		return nil, fmt.Errorf("agent profile %q is not available", payload.Agent)
+	}
+	if s.agentLoop == nil {
+		return nil, fmt.Errorf("agent loop is not available")
+	}
+	return s.agentLoop, nil
+}
+
+func (s *Server) agentLoopByName(name string) (*agent.AgentLoop, bool) {
+	if s.agentRouter != nil {
+		return s.agentRouter.Get(name)
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, config.DefaultAgentName) {
+		return s.agentLoop, s.agentLoop != nil
+	}
+	return nil, false
+}
+
+// workspaceForAgent returns the workspace of the named profile, falling back to defaults.
+func (s *Server) workspaceForAgent(name string) string {
+	if resolved, ok := s.cfg.ResolveAgent(name); ok && strings.TrimSpace(resolved.Workspace) != "" {
+		return resolved.Workspace
+	}
+	return s.cfg.Agents.Defaults.Workspace
+}
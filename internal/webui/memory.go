@@ -0,0 +1,237 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/memory"
+)
+
+type memoryEntryRequest struct {
+	Type       string              `json:"type"`
+	Content    string              `json:"content"`
+	Tags       []string            `json:"tags,omitempty"`
+	Confidence float64             `json:"confidence,omitempty"`
+	Expires    string              `json:"expires,omitempty"`
+	Source     *memory.EntrySource `json:"source,omitempty"`
+}
+
+// memoryEntryPatchRequest 省略的字段保持不变；expires 为空串或 never 时取消过期时间
+type memoryEntryPatchRequest struct {
+	Type       *string   `json:"type,omitempty"`
+	Content    *string   `json:"content,omitempty"`
+	Tags       *[]string `json:"tags,omitempty"`
+	Confidence *float64  `json:"confidence,omitempty"`
+	Expires    *string   `json:"expires,omitempty"`
+	Done       *bool     `json:"done,omitempty"`
+}
+
+// memoryScopesFor 按 ?agent= 选择对应 profile 的 workspace；
+// 指定 ?scope= 时只操作该作用域，否则为 workspace 中已有的全部作用域（全局在首位）
+func (s *Server) memoryScopesFor(r *http.Request) (string, []memory.Scope, error) {
+	workspace := s.workspaceForAgent(r.URL.Query().Get("agent"))
+	if raw := r.URL.Query().Get("scope"); raw != "" {
+		scope, err := memory.ParseScope(raw)
+		if err != nil {
+			return "", nil, err
+		}
+		return workspace, []memory.Scope{scope}, nil
+	}
+	scopes, err := memory.ListScopes(workspace)
+	if err != nil {
+		return "", nil, err
+	}
+	return workspace, scopes, nil
+}
+
+func (s *Server) handleMemory(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleMemoryList(w, r)
+	case http.MethodPost:
+		s.handleMemoryCreate(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleMemoryList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := memory.EntryFilter{
+		Tag:            query.Get("tag"),
+		Query:          query.Get("q"),
+		IncludeExpired: query.Get("all") == "true" || query.Get("all") == "1",
+	}
+	if raw := query.Get("type"); raw != "" {
+		typ, err := memory.ParseEntryType(raw)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		filter.Type = typ
+	}
+
+	workspace, scopes, err := s.memoryScopesFor(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	entries, err := memory.ListScopedEntries(workspace, scopes, filter)
+	if err != nil {
+		writeMemoryError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []memory.ScopedEntry{}
+	}
+	writeJSON(w, map[string]interface{}{"entries": entries})
+}
+
+func (s *Server) handleMemoryCreate(w http.ResponseWriter, r *http.Request) {
+	workspace, scopes, err := s.memoryScopesFor(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var req memoryEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if strings.TrimSpace(req.Type) == "" {
+		req.Type = string(memory.EntryFact)
+	}
+	typ, err := memory.ParseEntryType(req.Type)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	entry := memory.Entry{
+		Type:       typ,
+		Content:    req.Content,
+		Tags:       req.Tags,
+		Confidence: req.Confidence,
+	}
+	if req.Source != nil {
+		// 客户端可以带上会话与消息作为出处，但来源固定为 api
+		entry.Source = *req.Source
+	}
+	entry.Source.Origin = memory.SourceAPI
+	if strings.TrimSpace(req.Expires) != "" {
+		expires, err := memory.ParseExpiry(req.Expires, time.Now())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		entry.ExpiresAt = memory.ExpiryPtr(expires)
+	}
+
+	// 未指定 ?scope= 时写入全局作用域
+	store := memory.NewScopedEntryStore(workspace, scopes[0])
+	saved, created, err := store.Add(entry)
+	if err != nil {
+		writeMemoryError(w, err)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"entry":   memory.ScopedEntry{Entry: saved, Scope: store.Scope()},
+		"created": created,
+	})
+}
+
+func (s *Server) handleMemoryByID(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/memory/"), "/")
+	if id == "" || strings.Contains(id, "/") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	workspace, scopes, err := s.memoryScopesFor(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	store, err := memory.FindEntryStore(workspace, scopes, id)
+	if err != nil {
+		writeMemoryError(w, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		entry, err := store.Get(id)
+		if err != nil {
+			writeMemoryError(w, err)
+			return
+		}
+		writeJSON(w, memory.ScopedEntry{Entry: entry, Scope: store.Scope()})
+	case http.MethodPut, http.MethodPatch:
+		var req memoryEntryPatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, err)
+			return
+		}
+		patch, err := req.toPatch(time.Now())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		entry, err := store.Update(id, patch)
+		if err != nil {
+			writeMemoryError(w, err)
+			return
+		}
+		writeJSON(w, memory.ScopedEntry{Entry: entry, Scope: store.Scope()})
+	case http.MethodDelete:
+		entry, err := store.Remove(id)
+		if err != nil {
+			writeMemoryError(w, err)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"ok": true, "entry": memory.ScopedEntry{Entry: entry, Scope: store.Scope()}})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (req memoryEntryPatchRequest) toPatch(now time.Time) (memory.EntryPatch, error) {
+	patch := memory.EntryPatch{
+		Content:    req.Content,
+		Tags:       req.Tags,
+		Confidence: req.Confidence,
+		Done:       req.Done,
+	}
+	if req.Type != nil {
+		typ, err := memory.ParseEntryType(*req.Type)
+		if err != nil {
+			return patch, err
+		}
+		patch.Type = &typ
+	}
+	if req.Expires != nil {
+		var expires time.Time
+		if strings.TrimSpace(*req.Expires) != "" {
+			parsed, err := memory.ParseExpiry(*req.Expires, now)
+			if err != nil {
+				return patch, err
+			}
+			expires = parsed
+		}
+		patch.ExpiresAt = &expires
+	}
+	return patch, nil
+}
+
+// writeMemoryError 条目不存在时返回 404，其余按 400 处理
+func writeMemoryError(w http.ResponseWriter, err error) {
+	if !errors.Is(err, memory.ErrEntryNotFound) {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,121 @@
+package webui
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Lichas/maxclaw/internal/agent"
+	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/pkg/tools"
+)
+
+type processResponse struct {
+	tools.ProcessInfo
+	Agent string `json:"agent"`
+}
+
+func (s *Server) handleProcesses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	agentName := strings.TrimSpace(r.URL.Query().Get("agent"))
+	sessionKey := strings.TrimSpace(r.URL.Query().Get("sessionKey"))
+	out := make([]processResponse, 0)
+	for _, named := range s.namedAgentLoops() {
+		if agentName != "" && !strings.EqualFold(agentName, named.name) {
+			continue
+		}
+		for _, info := range named.loop.Processes(sessionKey) {
+			out = append(out, processResponse{ProcessInfo: info, Agent: named.name})
+		}
+	}
+	writeJSON(w, map[string]interface{}{"processes": out})
+}
+
+// handleProcessByID serves /api/processes/{id}/output and /api/processes/{id}/kill.
+func (s *Server) handleProcessByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/processes/"), "/"), "/")
+	if len(parts) == 0 || parts[0] == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	id := parts[0]
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	loop, ok := s.processOwner(id)
+	if !ok {
+		writeError(w, fmt.Errorf("process %s not found", id))
+		return
+	}
+
+	switch {
+	case action == "output" && r.Method == http.MethodGet:
+		offset, _ := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+		maxBytes, _ := strconv.Atoi(r.URL.Query().Get("maxBytes"))
+		if maxBytes <= 0 {
+			maxBytes = 64 * 1024
+		}
+		out, err := loop.ProcessOutput(id, offset, maxBytes)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, out)
+	case (action == "kill" && r.Method == http.MethodPost) || (action == "" && r.Method == http.MethodDelete):
+		info, err := loop.KillProcess(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"ok": true, "process": info})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+type namedAgentLoop struct {
+	name string
+	loop *agent.AgentLoop
+}
+
+func (s *Server) namedAgentLoops() []namedAgentLoop {
+	var out []namedAgentLoop
+	if s.agentRouter != nil {
+		if loop := s.agentRouter.Default(); loop != nil {
+			out = append(out, namedAgentLoop{name: config.DefaultAgentName, loop: loop})
+		}
+		for _, name := range s.agentRouter.Names() {
+			if loop, ok := s.agentRouter.Get(name); ok {
+				out = append(out, namedAgentLoop{name: name, loop: loop})
+			}
+		}
+		return out
+	}
+	if s.agentLoop != nil {
+		out = append(out, namedAgentLoop{name: config.DefaultAgentName, loop: s.agentLoop})
+	}
+	return out
+}
+
+func (s *Server) processOwner(id string) (*agent.AgentLoop, bool) {
+	for _, named := range s.namedAgentLoops() {
+		if named.loop.HasProcess(id) {
+			return named.loop, true
+		}
+	}
+	return nil, false
+}
+
+// killSessionProcesses stops processes left behind by a deleted session in every agent.
+func (s *Server) killSessionProcesses(sessionKey string) {
+	for _, named := range s.namedAgentLoops() {
+		named.loop.KillSessionProcesses(sessionKey)
+	}
+}
@@ -22,9 +22,12 @@ import (
 	"github.com/Lichas/maxclaw/internal/config"
 	"github.com/Lichas/maxclaw/internal/cron"
 	"github.com/Lichas/maxclaw/internal/logging"
+	"github.com/Lichas/maxclaw/internal/media"
 	"github.com/Lichas/maxclaw/internal/providers"
 	"github.com/Lichas/maxclaw/internal/session"
 	workspaceSkills "github.com/Lichas/maxclaw/internal/skills"
+	"github.com/Lichas/maxclaw/internal/telemetry"
+	"github.com/Lichas/maxclaw/internal/triggers"
 	"github.com/Lichas/maxclaw/pkg/tools"
 	"github.com/anthropics/anthropic-sdk-go"
 	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
@@ -36,12 +39,14 @@ import (
 type Server struct {
 	cfg               *config.Config
 	agentLoop         *agent.AgentLoop
+	agentRouter       *agent.AgentRouter
 	cronService       *cron.Service
 	channelRegistry   *channels.Registry
 	server            *http.Server
 	uiDir             string
 	skillsStateMgr    *workspaceSkills.StateManager
 	notificationStore *NotificationStore
+	triggerService    *triggers.Service
 	wsHub             *WebSocketHub
 	outboundUnsub     func()
 }
@@ -61,6 +66,7 @@ type messagePayload struct {
 	Channel        string              `json:"channel"`
 	ChatID         string              `json:"chatId"`
 	SelectedSkills []string            `json:"selectedSkills,omitempty"`
+	Agent          string              `json:"agent,omitempty"`
 	Attachments    []messageAttachment `json:"attachments,omitempty"`
 	Stream         bool                `json:"stream,omitempty"`
 }
@@ -129,6 +135,7 @@ func (s *Server) Start(ctx context.Context, host string, port int) error {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/api/status", s.handleStatus)
+	mux.Handle("/metrics", telemetry.Handler())
 	mux.HandleFunc("/api/sessions", s.handleSessions)
 	mux.HandleFunc("/api/sessions/", s.handleSessionByKey)
 	mux.HandleFunc("/api/skills", s.handleSkills)
@@ -136,14 +143,22 @@ func (s *Server) Start(ctx context.Context, host string, port int) error {
 	mux.HandleFunc("/api/skills/", s.handleSkillsPath)
 	mux.HandleFunc("/api/skills/install", s.handleSkillsInstall)
 	mux.HandleFunc("/api/message", s.handleMessage)
+	mux.HandleFunc("/api/agents", s.handleAgents)
+	mux.HandleFunc("/api/processes", s.handleProcesses)
+	mux.HandleFunc("/api/processes/", s.handleProcessByID)
 	mux.HandleFunc("/api/browser/action", s.handleBrowserAction)
 	mux.HandleFunc("/api/config", s.handleConfig)
 	mux.HandleFunc("/api/workspace-file/", s.handleWorkspaceFile)
+	mux.HandleFunc("/api/memory", s.handleMemory)
+	mux.HandleFunc("/api/memory/", s.handleMemoryByID)
 	mux.HandleFunc("/api/gateway/restart", s.handleGatewayRestart)
 	mux.HandleFunc("/api/cron", s.handleCron)
 	mux.HandleFunc("/api/cron/", s.handleCronByID)
 	mux.HandleFunc("/api/cron/history", s.handleGetCronHistory)
 	mux.HandleFunc("/api/cron/history/", s.handleGetCronHistoryDetail)
+	mux.HandleFunc("/api/triggers", s.handleTriggers)
+	mux.HandleFunc("/api/triggers/", s.handleTriggerByID)
+	mux.HandleFunc("/hooks/", s.handleHook)
 	mux.HandleFunc("/api/upload", s.handleUpload)
 	mux.HandleFunc("/api/uploads/", s.handleGetUpload)
 	mux.HandleFunc("/api/notifications/pending", s.handleGetPendingNotifications)
@@ -151,6 +166,7 @@ func (s *Server) Start(ctx context.Context, host string, port int) error {
 	mux.HandleFunc("/api/providers/test", s.handleTestProvider)
 	mux.HandleFunc("/api/providers/models", s.handleFetchProviderModels)
 	mux.HandleFunc("/api/channels/senders", s.handleChannelSenders)
+	mux.HandleFunc("/api/logs", s.handleLogs)
 	mux.HandleFunc("/api/channels/", s.handleTestChannel)
 	mux.HandleFunc("/api/channels/whatsapp/status", s.handleWhatsAppStatus)
 	mux.HandleFunc("/api/mcp", s.handleMCP)
@@ -233,6 +249,72 @@ func (s *Server) handleChannelSenders(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, map[string]interface{}{"users": users})
 }
 
+// handleLogs 返回最近的结构化日志，支持 component/session/channel/tool/level/request/q/since/limit 筛选
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 200
+	if rawLimit := strings.TrimSpace(query.Get("limit")); rawLimit != "" {
+		if parsed, err := strconv.Atoi(rawLimit); err == nil && parsed > 0 {
+			if parsed > 1000 {
+				parsed = 1000
+			}
+			limit = parsed
+		}
+	}
+
+	filter := logging.Filter{
+		Level:     strings.TrimSpace(query.Get("level")),
+		Session:   strings.TrimSpace(query.Get("session")),
+		Channel:   strings.TrimSpace(query.Get("channel")),
+		Tool:      strings.TrimSpace(query.Get("tool")),
+		RequestID: strings.TrimSpace(query.Get("request")),
+		Contains:  strings.TrimSpace(query.Get("q")),
+	}
+	if filter.Level != "" {
+		if _, err := logging.ParseLevel(filter.Level); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	for _, component := range strings.Split(query.Get("component"), ",") {
+		if component = strings.TrimSpace(component); component != "" {
+			filter.Components = append(filter.Components, component)
+		}
+	}
+	for _, component := range filter.Components {
+		if !logging.IsComponent(component) {
+			writeError(w, fmt.Errorf("unknown log component %q", component))
+			return
+		}
+	}
+	if rawSince := strings.TrimSpace(query.Get("since")); rawSince != "" {
+		since, err := time.ParseDuration(rawSince)
+		if err != nil {
+			writeError(w, fmt.Errorf("invalid since: %w", err))
+			return
+		}
+		filter.Since = time.Now().Add(-since)
+	}
+
+	entries, err := logging.ReadEntries(config.GetLogsDir(), filter, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []logging.Entry{}
+	}
+	writeJSON(w, map[string]interface{}{
+		"entries":    entries,
+		"components": logging.Components,
+	})
+}
+
 func (s *Server) Stop(ctx context.Context) error {
 	if s.outboundUnsub != nil {
 		s.outboundUnsub()
@@ -286,6 +368,12 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		status["cron"] = s.cronService.Status()
 	}
 
+	if s.agentLoop != nil {
+		if health, ok := s.agentLoop.ProviderHealth(); ok {
+			status["providers"] = health
+		}
+	}
+
 	writeJSON(w, status)
 }
 
@@ -295,7 +383,7 @@ func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	list, err := listSessions(s.cfg.Agents.Defaults.Workspace)
+	list, err := listSessions(s.workspaceForAgent(r.URL.Query().Get("agent")))
 	if err != nil {
 		writeError(w, err)
 		return
@@ -324,7 +412,7 @@ func (s *Server) handleSessionGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mgr := session.NewManager(s.cfg.Agents.Defaults.Workspace)
+	mgr := session.NewManager(s.workspaceForAgent(r.URL.Query().Get("agent")))
 	sess := mgr.GetOrCreate(key)
 	writeJSON(w, sess)
 }
@@ -389,6 +477,7 @@ func (s *Server) handleSessionDelete(w http.ResponseWriter, r *http.Request) {
 		writeError(w, err)
 		return
 	}
+	s.killSessionProcesses(key)
 
 	writeJSON(w, map[string]interface{}{
 		"ok":  true,
@@ -424,36 +513,43 @@ func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
 		payload.ChatID = payload.SessionKey
 	}
 
+	loop, err := s.resolveAgentLoop(payload)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
 	if wantsStreamResponse(r, payload) {
-		s.handleMessageStream(w, r, payload)
+		s.handleMessageStream(w, r, loop, payload)
 		return
 	}
 
 	enrichedContent := s.enrichContentWithAttachments(payload.Content, payload.Attachments)
-	resp, err := s.agentLoop.ProcessDirectWithMediaAndSkills(
+	resp, err := loop.ProcessDirectWithMediaAndSkills(
 		r.Context(),
 		enrichedContent,
 		payload.SessionKey,
 		payload.Channel,
 		payload.ChatID,
 		payload.SelectedSkills,
-		s.extractImageAttachment(payload.Attachments),
+		s.extractInboundAttachment(payload.Attachments),
 	)
 	if err != nil {
 		writeError(w, err)
 		if lg := logging.Get(); lg != nil && lg.Web != nil {
-			lg.Web.Printf("message error session=%s channel=%s err=%v", payload.SessionKey, payload.Channel, err)
+			lg.Web.Error("message error", "session", payload.SessionKey, "channel", payload.Channel, "err", err)
 		}
 		return
 	}
 
 	if lg := logging.Get(); lg != nil && lg.Web != nil {
-		lg.Web.Printf("message session=%s channel=%s content=%q", payload.SessionKey, payload.Channel, logging.Truncate(payload.Content, 300))
+		lg.Web.Info("message", "session", payload.SessionKey, "channel", payload.Channel, "content", logging.Truncate(payload.Content, 300))
 	}
 
 	writeJSON(w, map[string]interface{}{
 		"response":   resp,
 		"sessionKey": payload.SessionKey,
+		"agent":      loop.ProfileName,
 	})
 }
 
@@ -506,13 +602,13 @@ func (s *Server) handleBrowserAction(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		writeError(w, err)
 		if lg := logging.Get(); lg != nil && lg.Web != nil {
-			lg.Web.Printf("browser action error session=%s action=%s err=%v", payload.SessionKey, action, err)
+			lg.Web.Error("browser action error", "session", payload.SessionKey, "action", action, "err", err)
 		}
 		return
 	}
 
 	if lg := logging.Get(); lg != nil && lg.Web != nil {
-		lg.Web.Printf("browser action session=%s action=%s", payload.SessionKey, action)
+		lg.Web.Info("browser action", "session", payload.SessionKey, "action", action)
 	}
 
 	writeJSON(w, map[string]interface{}{
@@ -522,7 +618,7 @@ func (s *Server) handleBrowserAction(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (s *Server) handleMessageStream(w http.ResponseWriter, r *http.Request, payload messagePayload) {
+func (s *Server) handleMessageStream(w http.ResponseWriter, r *http.Request, loop *agent.AgentLoop, payload messagePayload) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		writeError(w, fmt.Errorf("streaming is not supported by this server"))
@@ -552,14 +648,14 @@ func (s *Server) handleMessageStream(w http.ResponseWriter, r *http.Request, pay
 	defer cancel()
 
 	var streamWriteErr error
-	resp, err := s.agentLoop.ProcessDirectEventStreamWithMediaAndSkills(
+	resp, err := loop.ProcessDirectEventStreamWithMediaAndSkills(
 		ctx,
 		s.enrichContentWithAttachments(payload.Content, payload.Attachments),
 		payload.SessionKey,
 		payload.Channel,
 		payload.ChatID,
 		payload.SelectedSkills,
-		s.extractImageAttachment(payload.Attachments),
+		s.extractInboundAttachment(payload.Attachments),
 		func(event agent.StreamEvent) {
 			if streamWriteErr != nil {
 				return
@@ -578,7 +674,7 @@ func (s *Server) handleMessageStream(w http.ResponseWriter, r *http.Request, pay
 
 	if streamWriteErr != nil {
 		if lg := logging.Get(); lg != nil && lg.Web != nil {
-			lg.Web.Printf("stream write aborted session=%s channel=%s err=%v", payload.SessionKey, payload.Channel, streamWriteErr)
+			lg.Web.Warn("stream write aborted", "session", payload.SessionKey, "channel", payload.Channel, "err", streamWriteErr)
 		}
 		return
 	}
@@ -592,7 +688,7 @@ func (s *Server) handleMessageStream(w http.ResponseWriter, r *http.Request, pay
 		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
 		flusher.Flush()
 		if lg := logging.Get(); lg != nil && lg.Web != nil {
-			lg.Web.Printf("message stream error session=%s channel=%s err=%v", payload.SessionKey, payload.Channel, err)
+			lg.Web.Error("message stream error", "session", payload.SessionKey, "channel", payload.Channel, "err", err)
 		}
 		return
 	}
@@ -607,7 +703,7 @@ func (s *Server) handleMessageStream(w http.ResponseWriter, r *http.Request, pay
 	flusher.Flush()
 
 	if lg := logging.Get(); lg != nil && lg.Web != nil {
-		lg.Web.Printf("message stream session=%s channel=%s content=%q", payload.SessionKey, payload.Channel, logging.Truncate(payload.Content, 300))
+		lg.Web.Info("message stream", "session", payload.SessionKey, "channel", payload.Channel, "content", logging.Truncate(payload.Content, 300))
 	}
 }
 
@@ -669,16 +765,51 @@ func (s *Server) enrichContentWithAttachments(content string, attachments []mess
 	return b.String()
 }
 
+// extractInboundAttachment 优先传递图片附件，其次是可提取文本的文档附件
+func (s *Server) extractInboundAttachment(attachments []messageAttachment) *bus.MediaAttachment {
+	if image := s.extractImageAttachment(attachments); image != nil {
+		return image
+	}
+	return s.extractDocumentAttachment(attachments)
+}
+
+func (s *Server) extractDocumentAttachment(attachments []messageAttachment) *bus.MediaAttachment {
+	for _, att := range attachments {
+		path := s.resolveAttachmentPath(att.Path)
+		if path == "" || media.DetectDocumentFormat(path, "") == "" {
+			continue
+		}
+		filename := strings.TrimSpace(att.Filename)
+		if filename == "" {
+			filename = filepath.Base(path)
+		}
+		return &bus.MediaAttachment{
+			Type:      "document",
+			Filename:  filename,
+			LocalPath: path,
+			MimeType:  mime.TypeByExtension(strings.ToLower(filepath.Ext(path))),
+		}
+	}
+	return nil
+}
+
+func (s *Server) resolveAttachmentPath(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.cfg.Agents.Defaults.Workspace, path)
+	}
+	return filepath.Clean(path)
+}
+
 func (s *Server) extractImageAttachment(attachments []messageAttachment) *bus.MediaAttachment {
 	for _, att := range attachments {
-		path := strings.TrimSpace(att.Path)
+		path := s.resolveAttachmentPath(att.Path)
 		if path == "" {
 			continue
 		}
-		if !filepath.IsAbs(path) {
-			path = filepath.Join(s.cfg.Agents.Defaults.Workspace, path)
-		}
-		path = filepath.Clean(path)
 
 		ext := strings.ToLower(filepath.Ext(path))
 		if ext == "" {
@@ -1097,12 +1228,32 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 		s.cfg = updated
 		if err := s.applyRuntimeModelConfig(updated); err != nil {
 			if lg := logging.Get(); lg != nil && lg.Web != nil {
-				lg.Web.Printf("apply runtime model config failed: %v", err)
+				lg.Web.Error("apply runtime model config failed", "err", err)
 			}
 		}
 		if err := s.applyRuntimeMCPConfig(updated); err != nil {
 			if lg := logging.Get(); lg != nil && lg.Web != nil {
-				lg.Web.Printf("apply runtime MCP config failed: %v", err)
+				lg.Web.Error("apply runtime MCP config failed", "err", err)
+			}
+		}
+		if err := s.applyRuntimeToolPolicy(updated); err != nil {
+			if lg := logging.Get(); lg != nil && lg.Web != nil {
+				lg.Web.Error("apply runtime tool policy failed", "err", err)
+			}
+		}
+		if err := s.applyRuntimeWebSearchConfig(updated); err != nil {
+			if lg := logging.Get(); lg != nil && lg.Web != nil {
+				lg.Web.Error("apply runtime web search config failed", "err", err)
+			}
+		}
+		if err := s.applyRuntimeEgressConfig(updated); err != nil {
+			if lg := logging.Get(); lg != nil && lg.Web != nil {
+				lg.Web.Error("apply runtime egress config failed", "err", err)
+			}
+		}
+		if err := s.applyRuntimeRetrievalConfig(updated); err != nil {
+			if lg := logging.Get(); lg != nil && lg.Web != nil {
+				lg.Web.Error("apply runtime retrieval config failed", "err", err)
 			}
 		}
 		writeJSON(w, updated)
@@ -1220,21 +1371,7 @@ func (s *Server) applyRuntimeModelConfig(cfg *config.Config) error {
 		return nil
 	}
 
-	apiKey := cfg.GetAPIKey(model)
-	if apiKey == "" {
-		return fmt.Errorf("no API key configured for model %s", model)
-	}
-	apiBase := cfg.GetAPIBase(model)
-
-	provider, err := providers.NewProvider(
-		apiKey,
-		apiBase,
-		cfg.GetAPIFormat(model),
-		model,
-		cfg.Agents.Defaults.MaxTokens,
-		cfg.Agents.Defaults.Temperature,
-		cfg.SupportsImageInput,
-	)
+	provider, err := agent.NewProviderFromConfig(cfg, model, cfg.Agents.Defaults.MaxTokens, cfg.Agents.Defaults.Temperature)
 	if err != nil {
 		return err
 	}
@@ -1250,6 +1387,125 @@ func (s *Server) applyRuntimeMCPConfig(cfg *config.Config) error {
 	return s.agentLoop.UpdateRuntimeMCPServers(cfg.Tools.MCPServers)
 }
 
+func (s *Server) applyRuntimeToolPolicy(cfg *config.Config) error {
+	if s.agentLoop == nil || cfg == nil {
+		return nil
+	}
+	policy, err := agent.BuildToolPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	s.agentLoop.SetToolPolicy(policy)
+	s.agentLoop.SetRequireReadBeforeEdit(cfg.Tools.Edit.RequireRead)
+	if s.agentRouter != nil {
+		for _, name := range s.agentRouter.Names() {
+			if loop, ok := s.agentRouter.Get(name); ok {
+				loop.SetToolPolicy(policy)
+				loop.SetRequireReadBeforeEdit(cfg.Tools.Edit.RequireRead)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) applyRuntimeWebSearchConfig(cfg *config.Config) error {
+	if s.agentLoop == nil || cfg == nil {
+		return nil
+	}
+	if err := agent.ValidateWebSearchConfig(cfg); err != nil {
+		return err
+	}
+	opts := agent.BuildWebSearchOptions(cfg)
+	s.agentLoop.SetWebSearchOptions(opts)
+	if s.agentRouter != nil {
+		for _, name := range s.agentRouter.Names() {
+			if loop, ok := s.agentRouter.Get(name); ok {
+				loop.SetWebSearchOptions(opts)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) applyRuntimeRetrievalConfig(cfg *config.Config) error {
+	if s.agentLoop == nil || cfg == nil {
+		return nil
+	}
+	opts, err := agent.BuildRetrievalOptions(cfg)
+	if err != nil {
+		return err
+	}
+	s.agentLoop.SetRetrievalOptions(opts)
+	if s.agentRouter != nil {
+		for _, name := range s.agentRouter.Names() {
+			if loop, ok := s.agentRouter.Get(name); ok {
+				loop.SetRetrievalOptions(opts)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) applyRuntimeEgressConfig(cfg *config.Config) error {
+	if s.agentLoop == nil || cfg == nil {
+		return nil
+	}
+	guard, err := agent.BuildEgressGuard(cfg)
+	if err != nil {
+		return err
+	}
+	s.agentLoop.SetEgressGuard(guard)
+	if s.agentRouter != nil {
+		for _, name := range s.agentRouter.Names() {
+			loop, ok := s.agentRouter.Get(name)
+			if !ok {
+				continue
+			}
+			profile, _ := cfg.ResolveAgent(name)
+			loop.SetEgressGuard(guard.ForAgent(name, profile.AllowDomains, profile.DenyDomains))
+		}
+	}
+	return nil
+}
+
+// ApplyConfig 应用热加载的配置：替换 s.cfg，只重建 changes 涉及的运行时组件，返回成功重建的组件
+func (s *Server) ApplyConfig(cfg *config.Config, changes config.Changes) []string {
+	s.cfg = cfg
+	var applied []string
+	apply := func(name string, fn func(*config.Config) error, prefixes ...string) {
+		if !changes.Touches(prefixes...) {
+			return
+		}
+		if err := fn(cfg); err != nil {
+			if lg := logging.Get(); lg != nil && lg.Web != nil {
+				lg.Web.Error("apply reloaded config failed", "component", name, "err", err)
+			}
+			return
+		}
+		applied = append(applied, name)
+	}
+
+	apply("model", s.applyRuntimeModelConfig,
+		"agents.defaults.model", "agents.defaults.maxTokens", "agents.defaults.temperature",
+		"agents.defaults.maxToolIterations", "agents.defaults.executionMode",
+		"providers", "routing", "modelCatalog")
+	apply("mcp", s.applyRuntimeMCPConfig, "tools.mcpServers")
+	apply("toolPolicy", s.applyRuntimeToolPolicy, "tools.policy", "tools.edit")
+	apply("webSearch", s.applyRuntimeWebSearchConfig, "tools.web.search")
+	apply("egress", s.applyRuntimeEgressConfig, "tools.egress", "agents.profiles")
+	apply("retrieval", s.applyRuntimeRetrievalConfig, "tools.retrieval", "providers")
+	apply("routes", s.applyRuntimeRoutes, "agents.routes")
+	return applied
+}
+
+func (s *Server) applyRuntimeRoutes(cfg *config.Config) error {
+	if s.agentRouter == nil || cfg == nil {
+		return nil
+	}
+	s.agentRouter.SetRoutes(cfg.Agents.Routes)
+	return nil
+}
+
 func (s *Server) handleGatewayRestart(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -1270,7 +1526,7 @@ func (s *Server) handleGatewayRestart(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if lg := logging.Get(); lg != nil && lg.Web != nil {
-		lg.Web.Printf("gateway restart triggered script=%s pid=%d", script, cmd.Process.Pid)
+		lg.Web.Info("gateway restart triggered", "script", script, "pid", cmd.Process.Pid)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1662,7 +1918,8 @@ func (s *Server) handleTestProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.APIKey == "" {
+	kind := providers.ResolveProviderKind(req.Name, req.BaseURL, req.APIFormat)
+	if req.APIKey == "" && kind != "ollama" {
 		http.Error(w, "API key is required", http.StatusBadRequest)
 		return
 	}
@@ -1670,7 +1927,7 @@ func (s *Server) handleTestProvider(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	switch providers.ResolveProviderKind(req.Name, req.BaseURL, req.APIFormat) {
+	switch kind {
 	case "anthropic":
 		baseURL := req.BaseURL
 		baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
@@ -1703,6 +1960,16 @@ func (s *Server) handleTestProvider(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+	case "gemini":
+		if _, err := providers.ListGeminiModels(ctx, req.APIKey, req.BaseURL); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case "ollama":
+		if _, err := providers.NewOllamaClient(req.BaseURL, req.APIKey).ListModels(ctx); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	default:
 		if err := s.testCompatibleProvider(ctx, req); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
@@ -1725,7 +1992,8 @@ func (s *Server) handleFetchProviderModels(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if req.APIKey == "" {
+	kind := providers.ResolveProviderKind(req.Name, req.BaseURL, req.APIFormat)
+	if req.APIKey == "" && kind != "ollama" {
 		http.Error(w, "API key is required", http.StatusBadRequest)
 		return
 	}
@@ -1735,7 +2003,7 @@ func (s *Server) handleFetchProviderModels(w http.ResponseWriter, r *http.Reques
 
 	models := make([]map[string]string, 0)
 
-	switch providers.ResolveProviderKind(req.Name, req.BaseURL, req.APIFormat) {
+	switch kind {
 	case "anthropic":
 		baseURL := strings.TrimRight(strings.TrimSpace(req.BaseURL), "/")
 		if baseURL == "" {
@@ -1775,6 +2043,24 @@ func (s *Server) handleFetchProviderModels(w http.ResponseWriter, r *http.Reques
 		for _, m := range list.Data {
 			models = append(models, map[string]string{"id": m.ID, "name": m.ID})
 		}
+	case "gemini":
+		list, err := providers.ListGeminiModels(ctx, req.APIKey, req.BaseURL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, id := range list {
+			models = append(models, map[string]string{"id": id, "name": id})
+		}
+	case "ollama":
+		list, err := providers.NewOllamaClient(req.BaseURL, req.APIKey).ListModels(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, m := range list {
+			models = append(models, map[string]string{"id": "ollama/" + m.Name, "name": m.Name})
+		}
 	default:
 		fetched, err := s.fetchCompatibleModels(ctx, req)
 		if err != nil {
@@ -1784,7 +2070,42 @@ func (s *Server) handleFetchProviderModels(w http.ResponseWriter, r *http.Reques
 		models = fetched
 	}
 
-	writeJSON(w, map[string]interface{}{"models": models})
+	writeJSON(w, map[string]interface{}{"models": catalogModelEntries(req, models)})
+}
+
+// providerModelEntry 拉取到的模型，附带模型目录中的上下文窗口、能力与价格
+type providerModelEntry struct {
+	ID            string                  `json:"id"`
+	Name          string                  `json:"name"`
+	ContextWindow int                     `json:"contextWindow,omitempty"`
+	MaxOutput     int                     `json:"maxOutput,omitempty"`
+	Vision        *bool                   `json:"vision,omitempty"`
+	Audio         *bool                   `json:"audio,omitempty"`
+	Tools         *bool                   `json:"tools,omitempty"`
+	Reasoning     *bool                   `json:"reasoning,omitempty"`
+	Pricing       *providers.ModelPricing `json:"pricing,omitempty"`
+}
+
+func catalogModelEntries(req ProviderTestRequest, models []map[string]string) []providerModelEntry {
+	providerName := providers.DetectProviderName(req.Name)
+	if providerName == "unknown" {
+		providerName = providers.DetectProviderNameFromAPIBase(req.BaseURL)
+	}
+	entries := make([]providerModelEntry, 0, len(models))
+	for _, m := range models {
+		entry := providerModelEntry{ID: m["id"], Name: m["name"]}
+		modelProvider := providers.DetectProviderName(entry.ID)
+		if modelProvider == "unknown" {
+			modelProvider = providerName
+		}
+		if info, ok := providers.LookupModel(modelProvider, entry.ID); ok {
+			entry.ContextWindow, entry.MaxOutput = info.ContextWindow, info.MaxOutput
+			entry.Vision, entry.Audio, entry.Tools, entry.Reasoning = info.Vision, info.Audio, info.Tools, info.Reasoning
+			entry.Pricing = info.Pricing
+		}
+		entries = append(entries, entry)
+	}
+	return entries
 }
 
 func (s *Server) fetchCompatibleModels(ctx context.Context, req ProviderTestRequest) ([]map[string]string, error) {
@@ -2416,7 +2737,7 @@ func (s *Server) handleMCPAdd(w http.ResponseWriter, r *http.Request) {
 	s.cfg = updated
 	if err := s.applyRuntimeMCPConfig(updated); err != nil {
 		if lg := logging.Get(); lg != nil && lg.Web != nil {
-			lg.Web.Printf("apply runtime MCP config failed after add: %v", err)
+			lg.Web.Error("apply runtime MCP config failed after add", "err", err)
 		}
 	}
 	writeJSON(w, map[string]interface{}{
@@ -2476,7 +2797,7 @@ func (s *Server) handleMCPUpdate(w http.ResponseWriter, r *http.Request, name st
 	s.cfg = updated
 	if err := s.applyRuntimeMCPConfig(updated); err != nil {
 		if lg := logging.Get(); lg != nil && lg.Web != nil {
-			lg.Web.Printf("apply runtime MCP config failed after update: %v", err)
+			lg.Web.Error("apply runtime MCP config failed after update", "err", err)
 		}
 	}
 	writeJSON(w, map[string]interface{}{
@@ -2513,7 +2834,7 @@ func (s *Server) handleMCPDelete(w http.ResponseWriter, r *http.Request, name st
 	s.cfg = updated
 	if err := s.applyRuntimeMCPConfig(updated); err != nil {
 		if lg := logging.Get(); lg != nil && lg.Web != nil {
-			lg.Web.Printf("apply runtime MCP config failed after delete: %v", err)
+			lg.Web.Error("apply runtime MCP config failed after delete", "err", err)
 		}
 	}
 	writeJSON(w, map[string]interface{}{
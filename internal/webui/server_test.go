@@ -2,6 +2,7 @@ package webui
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -14,7 +15,9 @@ import (
 	"github.com/Lichas/maxclaw/internal/agent"
 	"github.com/Lichas/maxclaw/internal/bus"
 	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/memory"
 	"github.com/Lichas/maxclaw/internal/session"
+	"github.com/Lichas/maxclaw/internal/triggers"
 	"github.com/Lichas/maxclaw/pkg/tools"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -127,6 +130,34 @@ func TestExtractImageAttachmentIgnoresNonImageFiles(t *testing.T) {
 	assert.Nil(t, got)
 }
 
+func TestExtractInboundAttachmentFallsBackToDocuments(t *testing.T) {
+	workspace := filepath.Join(string(filepath.Separator), "tmp", "ws")
+	s := &Server{
+		cfg: &config.Config{
+			Agents: config.AgentsConfig{
+				Defaults: config.AgentDefaults{
+					Workspace: workspace,
+				},
+			},
+		},
+	}
+
+	report := messageAttachment{Filename: "report.pdf", Path: ".uploads/20260222_report.pdf"}
+	got := s.extractInboundAttachment([]messageAttachment{report})
+	assert.Equal(t, &bus.MediaAttachment{
+		Type:      "document",
+		Filename:  "report.pdf",
+		LocalPath: filepath.Join(workspace, ".uploads", "20260222_report.pdf"),
+		MimeType:  "application/pdf",
+	}, got)
+
+	got = s.extractInboundAttachment([]messageAttachment{report, {Path: filepath.Join(workspace, "a.png")}})
+	require.NotNil(t, got)
+	assert.Equal(t, "image", got.Type)
+
+	assert.Nil(t, s.extractInboundAttachment([]messageAttachment{{Path: filepath.Join(workspace, "notes.txt")}}))
+}
+
 func TestNormalizeMiniMaxBaseURLRewritesLegacyChinaDomain(t *testing.T) {
 	got := normalizeMiniMaxBaseURL("https://api.minimax.com/v1")
 	assert.Equal(t, "https://api.minimaxi.com/v1", got)
@@ -279,6 +310,63 @@ func TestReadChannelSenderStatsFiltersByChannel(t *testing.T) {
 	}
 }
 
+func TestReadChannelSenderStatsParsesStructuredAndRotatedLogs(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "session.log")
+	backup := `2026/03/08 10:21:38.999215 inbound channel=qq chat=qq-openid-1 sender=qq-openid-1 content="legacy"` + "\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "session-20260308T235959.000.log"), []byte(backup), 0644))
+	content := strings.Join([]string{
+		`{"time":"2026-03-09T10:22:15.668501+08:00","level":"INFO","msg":"inbound","component":"session","request_id":"r1","session":"qq:qq-openid-1","channel":"qq","chat_id":"qq-openid-1","sender":"qq-openid-1","content":"structured"}`,
+		`{"time":"2026-03-09T10:24:24.413482+08:00","level":"INFO","msg":"outbound","component":"session","channel":"qq","chat_id":"qq-openid-1","content":"ignored"}`,
+		"",
+	}, "\n")
+	assert.NoError(t, os.WriteFile(logPath, []byte(content), 0644))
+
+	stats, err := readChannelSenderStats(logPath, "", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, stats, 1) {
+		assert.Equal(t, "qq", stats[0].Channel)
+		assert.Equal(t, "qq-openid-1", stats[0].ChatID)
+		assert.Equal(t, 2, stats[0].MessageCount)
+		assert.Equal(t, "structured", stats[0].LatestMessage)
+	}
+}
+
+func TestHandleLogsFiltersEntries(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("MAXCLAW_HOME", home)
+	logsDir := filepath.Join(home, "logs")
+	require.NoError(t, os.MkdirAll(logsDir, 0755))
+	tools := strings.Join([]string{
+		`{"time":"2026-03-09T10:00:00Z","level":"INFO","msg":"tool executed","component":"tools","session":"telegram:1","tool":"read_file"}`,
+		`{"time":"2026-03-09T10:00:01Z","level":"WARN","msg":"tool failed","component":"tools","session":"telegram:1","tool":"exec"}`,
+		`{"time":"2026-03-09T10:00:02Z","level":"WARN","msg":"tool failed","component":"tools","session":"discord:2","tool":"exec"}`,
+		"",
+	}, "\n")
+	require.NoError(t, os.WriteFile(filepath.Join(logsDir, "tools.log"), []byte(tools), 0644))
+
+	s := &Server{}
+	req := httptest.NewRequest(http.MethodGet, "/api/logs?component=tools&session=telegram:1&level=warn", nil)
+	rec := httptest.NewRecorder()
+	s.handleLogs(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var payload struct {
+		Entries []struct {
+			Message string         `json:"msg"`
+			Attrs   map[string]any `json:"attrs"`
+		} `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &payload))
+	require.Len(t, payload.Entries, 1)
+	assert.Equal(t, "tool failed", payload.Entries[0].Message)
+	assert.Equal(t, "exec", payload.Entries[0].Attrs["tool"])
+
+	rec = httptest.NewRecorder()
+	s.handleLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs?component=nope", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
 func TestListSessionsBackfillsLegacyTitle(t *testing.T) {
 	workspace := t.TempDir()
 	require.NoError(t, os.MkdirAll(filepath.Join(workspace, ".sessions"), 0755))
@@ -434,3 +522,220 @@ func TestHandleMCPAddAndDeleteRefreshRuntimeTools(t *testing.T) {
 	require.Equal(t, http.StatusOK, deleteRec.Code)
 	assert.NotContains(t, loop.ListToolNames(), "mcp_docs_ping")
 }
+
+func TestHandleProcessesListsAndKillsAgentProcesses(t *testing.T) {
+	workspace := t.TempDir()
+	loop := agent.NewAgentLoop(
+		bus.NewMessageBus(10),
+		nil,
+		workspace,
+		"test-model",
+		3,
+		"",
+		tools.WebFetchOptions{},
+		config.ExecToolConfig{Timeout: 5},
+		false,
+		nil,
+		nil,
+		false,
+	)
+	defer loop.Close()
+
+	_, err := loop.ExecuteToolWithSession(context.Background(), "process", map[string]interface{}{
+		"action":  "start",
+		"command": "echo booted; sleep 30",
+	}, "webui:dev", "webui", "dev")
+	require.NoError(t, err)
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = workspace
+	s := &Server{cfg: cfg, agentLoop: loop}
+
+	rec := httptest.NewRecorder()
+	s.handleProcesses(rec, httptest.NewRequest(http.MethodGet, "/api/processes?sessionKey=webui:dev", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listed struct {
+		Processes []struct {
+			ID      string `json:"id"`
+			Agent   string `json:"agent"`
+			Running bool   `json:"running"`
+		} `json:"processes"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	require.Len(t, listed.Processes, 1)
+	assert.Equal(t, "default", listed.Processes[0].Agent)
+	assert.True(t, listed.Processes[0].Running)
+	id := listed.Processes[0].ID
+
+	rec = httptest.NewRecorder()
+	s.handleProcessByID(rec, httptest.NewRequest(http.MethodGet, "/api/processes/"+id+"/output", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "booted")
+
+	rec = httptest.NewRecorder()
+	s.handleProcessByID(rec, httptest.NewRequest(http.MethodPost, "/api/processes/"+id+"/kill", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, loop.Processes(""))
+}
+
+func TestHandleMemoryEntriesCRUD(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = workspace
+	s := &Server{cfg: cfg}
+
+	rec := httptest.NewRecorder()
+	s.handleMemory(rec, httptest.NewRequest(http.MethodPost, "/api/memory", strings.NewReader(
+		`{"type":"preference","content":"Answers in Chinese","tags":["lang"],"confidence":0.9,"source":{"origin":"agent","session":"desktop:1","message":"请用中文回答"}}`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var created struct {
+		Entry   memory.Entry `json:"entry"`
+		Created bool         `json:"created"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.True(t, created.Created)
+	assert.Equal(t, memory.SourceAPI, created.Entry.Source.Origin)
+	assert.Equal(t, "desktop:1", created.Entry.Source.Session)
+	id := created.Entry.ID
+
+	rec = httptest.NewRecorder()
+	s.handleMemoryByID(rec, httptest.NewRequest(http.MethodPatch, "/api/memory/"+id, strings.NewReader(`{"content":"Answers in Simplified Chinese","expires":"7d"}`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var updated memory.Entry
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updated))
+	assert.Equal(t, "Answers in Simplified Chinese", updated.Content)
+	assert.NotNil(t, updated.ExpiresAt)
+	assert.Equal(t, []string{"lang"}, updated.Tags)
+
+	memoryFile, err := os.ReadFile(filepath.Join(workspace, "memory", "MEMORY.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(memoryFile), "- Answers in Simplified Chinese <!-- id:"+id+" -->")
+
+	rec = httptest.NewRecorder()
+	s.handleMemory(rec, httptest.NewRequest(http.MethodGet, "/api/memory?type=preference&tag=lang", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listed struct {
+		Entries []memory.Entry `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	require.Len(t, listed.Entries, 1)
+	assert.Equal(t, id, listed.Entries[0].ID)
+
+	rec = httptest.NewRecorder()
+	s.handleMemoryByID(rec, httptest.NewRequest(http.MethodDelete, "/api/memory/"+id, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.handleMemoryByID(rec, httptest.NewRequest(http.MethodGet, "/api/memory/"+id, nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.handleMemory(rec, httptest.NewRequest(http.MethodPost, "/api/memory", strings.NewReader(`{"type":"note","content":"x"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleMemoryEntriesScopes(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = workspace
+	s := &Server{cfg: cfg}
+
+	rec := httptest.NewRecorder()
+	s.handleMemory(rec, httptest.NewRequest(http.MethodPost, "/api/memory?scope=user:alice", strings.NewReader(`{"type":"preference","content":"Answers in Chinese"}`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var created struct {
+		Entry memory.ScopedEntry `json:"entry"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, memory.UserScope("alice"), created.Entry.Scope)
+	assert.FileExists(t, memory.UserScope("alice").MemoryPath(workspace))
+
+	rec = httptest.NewRecorder()
+	s.handleMemory(rec, httptest.NewRequest(http.MethodPost, "/api/memory", strings.NewReader(`{"content":"Deploys happen on Fridays"}`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// 未指定 scope 时列出全部作用域
+	rec = httptest.NewRecorder()
+	s.handleMemory(rec, httptest.NewRequest(http.MethodGet, "/api/memory", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listed struct {
+		Entries []memory.ScopedEntry `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	require.Len(t, listed.Entries, 2)
+	assert.Equal(t, memory.GlobalScope(), listed.Entries[0].Scope)
+	assert.Equal(t, memory.UserScope("alice"), listed.Entries[1].Scope)
+
+	rec = httptest.NewRecorder()
+	s.handleMemory(rec, httptest.NewRequest(http.MethodGet, "/api/memory?scope=global", nil))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	require.Len(t, listed.Entries, 1)
+
+	rec = httptest.NewRecorder()
+	s.handleMemoryByID(rec, httptest.NewRequest(http.MethodGet, "/api/memory/"+created.Entry.ID, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"scope":"user:alice"`)
+
+	rec = httptest.NewRecorder()
+	s.handleMemoryByID(rec, httptest.NewRequest(http.MethodDelete, "/api/memory/"+created.Entry.ID+"?scope=global", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.handleMemory(rec, httptest.NewRequest(http.MethodGet, "/api/memory?scope=team:x", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleHookAndTriggerAPI(t *testing.T) {
+	service := triggers.NewService(t.TempDir(), nil)
+	ran := make(chan string, 1)
+	service.SetRunner(func(ctx context.Context, tr *triggers.Trigger, prompt string) (string, error) {
+		ran <- prompt
+		return "done", nil
+	})
+	s := &Server{cfg: config.DefaultConfig()}
+	s.SetTriggerService(service)
+
+	rec := httptest.NewRecorder()
+	s.handleTriggers(rec, httptest.NewRequest(http.MethodPost, "/api/triggers", strings.NewReader(`{"name":"deploy","type":"webhook","prompt":"Deployed {{env}}","webhook":{"secret":"s3cret","extract":{"env":"deployment.environment"}}}`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var created struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, "/hooks/deploy", created.URL)
+
+	body := `{"deployment":{"environment":"prod"}}`
+	rec = httptest.NewRecorder()
+	s.handleHook(rec, httptest.NewRequest(http.MethodPost, "/hooks/deploy", strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.handleHook(rec, httptest.NewRequest(http.MethodPost, "/hooks/unknown", strings.NewReader(body)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/hooks/deploy", strings.NewReader(body))
+	req.Header.Set(triggers.SignatureHeader, triggers.Sign("s3cret", []byte(body)))
+	rec = httptest.NewRecorder()
+	s.handleHook(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	assert.Equal(t, "Deployed prod", <-ran)
+	service.Stop()
+
+	rec = httptest.NewRecorder()
+	s.handleTriggerByID(rec, httptest.NewRequest(http.MethodGet, "/api/triggers/deploy/history", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"trigger":"webhook"`)
+
+	rec = httptest.NewRecorder()
+	s.handleTriggerByID(rec, httptest.NewRequest(http.MethodPost, "/api/triggers/"+created.ID+"/disable", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"enabled":false`)
+
+	rec = httptest.NewRecorder()
+	s.handleTriggerByID(rec, httptest.NewRequest(http.MethodDelete, "/api/triggers/deploy", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = httptest.NewRecorder()
+	s.handleTriggerByID(rec, httptest.NewRequest(http.MethodGet, "/api/triggers/deploy", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
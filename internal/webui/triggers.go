@@ -0,0 +1,207 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Lichas/maxclaw/internal/triggers"
+)
+
+// maxHookBody webhook 请求体上限
+const maxHookBody = 1 << 20
+
+type triggerResponse struct {
+	*triggers.Trigger
+	Summary string `json:"summary"`
+	// URL webhook 触发器的相对地址
+	URL string `json:"url,omitempty"`
+}
+
+// SetTriggerService 设置事件触发器服务
+func (s *Server) SetTriggerService(service *triggers.Service) {
+	s.triggerService = service
+}
+
+func toTriggerResponse(t *triggers.Trigger) triggerResponse {
+	resp := triggerResponse{Trigger: t, Summary: t.Summary()}
+	if t.Type == triggers.TypeWebhook {
+		resp.URL = "/hooks/" + t.Name
+	}
+	return resp
+}
+
+// handleHook 入站 webhook：POST /hooks/{name}，签名校验通过后异步运行并返回 202
+func (s *Server) handleHook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/hooks/"), "/")
+	if s.triggerService == nil || name == "" || strings.Contains(name, "/") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxHookBody+1))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(body) > maxHookBody {
+		writeTriggerError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("payload larger than %d bytes", maxHookBody))
+		return
+	}
+
+	recordID, err := s.triggerService.HandleWebhook(name, body, r.Header)
+	if err != nil {
+		writeTriggerError(w, triggerErrorStatus(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "recordId": recordID})
+}
+
+func (s *Server) handleTriggers(w http.ResponseWriter, r *http.Request) {
+	if s.triggerService == nil {
+		if r.Method == http.MethodGet {
+			writeJSON(w, map[string]interface{}{"triggers": []triggerResponse{}})
+			return
+		}
+		writeError(w, fmt.Errorf("trigger service not available"))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		list := s.triggerService.List()
+		resp := make([]triggerResponse, 0, len(list))
+		for _, t := range list {
+			resp = append(resp, toTriggerResponse(t))
+		}
+		writeJSON(w, map[string]interface{}{"triggers": resp})
+	case http.MethodPost:
+		var req triggers.Trigger
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, err)
+			return
+		}
+		t, err := s.triggerService.Add(&req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, toTriggerResponse(t))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTriggerByID 路径格式: /api/triggers/{id}，/api/triggers/{id}/enable|disable|run|history
+func (s *Server) handleTriggerByID(w http.ResponseWriter, r *http.Request) {
+	if s.triggerService == nil {
+		writeError(w, fmt.Errorf("trigger service not available"))
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/triggers/"), "/"), "/")
+	id := parts[0]
+	if id == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if len(parts) >= 2 {
+		switch parts[1] {
+		case "enable", "disable":
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			t, err := s.triggerService.Enable(id, parts[1] == "enable")
+			if err != nil {
+				writeTriggerError(w, triggerErrorStatus(err), err)
+				return
+			}
+			writeJSON(w, toTriggerResponse(t))
+		case "run":
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			payload, err := io.ReadAll(io.LimitReader(r.Body, maxHookBody))
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			recordID, err := s.triggerService.Fire(id, payload)
+			if err != nil {
+				writeTriggerError(w, triggerErrorStatus(err), err)
+				return
+			}
+			writeJSON(w, map[string]interface{}{"ok": true, "recordId": recordID})
+		case "history":
+			t, ok := s.triggerService.Get(id)
+			if !ok {
+				writeTriggerError(w, http.StatusNotFound, triggers.ErrNotFound)
+				return
+			}
+			limit := 50
+			if l := r.URL.Query().Get("limit"); l != "" {
+				fmt.Sscanf(l, "%d", &limit)
+			}
+			writeJSON(w, map[string]interface{}{"records": s.triggerService.History().GetRecords(t.ID, limit)})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		t, ok := s.triggerService.Get(id)
+		if !ok {
+			writeTriggerError(w, http.StatusNotFound, triggers.ErrNotFound)
+			return
+		}
+		writeJSON(w, toTriggerResponse(t))
+	case http.MethodPut:
+		var req triggers.Trigger
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, err)
+			return
+		}
+		t, err := s.triggerService.Update(id, &req)
+		if err != nil {
+			writeTriggerError(w, triggerErrorStatus(err), err)
+			return
+		}
+		writeJSON(w, toTriggerResponse(t))
+	case http.MethodDelete:
+		if err := s.triggerService.Remove(id); err != nil {
+			writeTriggerError(w, triggerErrorStatus(err), err)
+			return
+		}
+		writeJSON(w, map[string]bool{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func triggerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, triggers.ErrNotFound), errors.Is(err, triggers.ErrDisabled):
+		return http.StatusNotFound
+	case errors.Is(err, triggers.ErrBadSignature):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func writeTriggerError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
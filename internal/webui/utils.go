@@ -8,12 +8,12 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
 
 	"github.com/Lichas/maxclaw/internal/config"
+	"github.com/Lichas/maxclaw/internal/logging"
 	"github.com/Lichas/maxclaw/internal/session"
 )
 
@@ -269,16 +269,37 @@ type providerProbePayload struct {
 }
 
 func readChannelSenderStats(logPath, filterChannel string, limit int) ([]channelSenderStat, error) {
+	seen := map[string]channelSenderStat{}
+	// 轮转后的历史文件从旧到新依次读取
+	for _, path := range logging.RotatedFiles(logPath) {
+		if err := collectChannelSenderStats(path, filterChannel, seen); err != nil {
+			return nil, err
+		}
+	}
+
+	users := make([]channelSenderStat, 0, len(seen))
+	for _, entry := range seen {
+		users = append(users, entry)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].LastSeen > users[j].LastSeen
+	})
+	if limit > 0 && len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func collectChannelSenderStats(logPath, filterChannel string, seen map[string]channelSenderStat) error {
 	file, err := os.Open(logPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return []channelSenderStat{}, nil
+			return nil
 		}
-		return nil, err
+		return err
 	}
 	defer file.Close()
 
-	seen := map[string]channelSenderStat{}
 	scanner := bufio.NewScanner(file)
 	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 
@@ -301,62 +322,31 @@ func readChannelSenderStats(logPath, filterChannel string, limit int) ([]channel
 		prev.MessageCount++
 		seen[key] = prev
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	users := make([]channelSenderStat, 0, len(seen))
-	for _, entry := range seen {
-		users = append(users, entry)
-	}
-	sort.Slice(users, func(i, j int) bool {
-		return users[i].LastSeen > users[j].LastSeen
-	})
-	if limit > 0 && len(users) > limit {
-		users = users[:limit]
-	}
-	return users, nil
+	return scanner.Err()
 }
 
+// parseInboundSenderLogLine 解析 session.log 中的 inbound 记录，兼容 JSON 与旧版文本行
 func parseInboundSenderLogLine(line string) (channelSenderStat, bool) {
-	const marker = " inbound channel="
-	idx := strings.Index(line, marker)
-	if idx <= 0 {
-		return channelSenderStat{}, false
-	}
-
-	timestampRaw := strings.TrimSpace(line[:idx])
-	lastSeen, err := time.Parse("2006/01/02 15:04:05.000000", timestampRaw)
-	if err != nil {
+	entry, ok := logging.ParseEntry(line, logging.ComponentSession)
+	if !ok || entry.Message != "inbound" || entry.Time.IsZero() {
 		return channelSenderStat{}, false
 	}
-
-	rest := line[idx+len(marker):]
-	channelPart, rest, ok := strings.Cut(rest, " ")
-	if !ok || strings.TrimSpace(channelPart) == "" {
+	channel := strings.ToLower(strings.TrimSpace(entry.Attr("channel")))
+	if channel == "" {
 		return channelSenderStat{}, false
 	}
-	chatPart, rest, ok := strings.Cut(rest, " ")
-	if !ok || !strings.HasPrefix(chatPart, "chat=") {
-		return channelSenderStat{}, false
-	}
-	senderPart, contentPart, ok := strings.Cut(rest, " ")
-	if !ok || !strings.HasPrefix(senderPart, "sender=") || !strings.HasPrefix(contentPart, "content=") {
-		return channelSenderStat{}, false
-	}
-
-	latestMessage := strings.TrimPrefix(contentPart, "content=")
-	if unquoted, err := strconv.Unquote(latestMessage); err == nil {
-		latestMessage = unquoted
+	chatID := entry.Attr("chat_id")
+	if chatID == "" {
+		chatID = entry.Attr("chat")
 	}
 
 	return channelSenderStat{
-		Channel:       strings.ToLower(strings.TrimSpace(channelPart)),
-		Sender:        strings.TrimPrefix(senderPart, "sender="),
-		ChatID:        strings.TrimPrefix(chatPart, "chat="),
-		LastSeen:      lastSeen.Format(time.RFC3339Nano),
+		Channel:       channel,
+		Sender:        entry.Attr("sender"),
+		ChatID:        chatID,
+		LastSeen:      entry.Time.Format(time.RFC3339Nano),
 		MessageCount:  1,
-		LatestMessage: latestMessage,
+		LatestMessage: entry.Attr("content"),
 	}, true
 }
 
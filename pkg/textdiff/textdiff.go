@@ -0,0 +1,231 @@
+// Package textdiff 生成按行比较的 unified diff
+package textdiff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	diffContextLines = 3
+	// 超过该规模的中间差异不再做 LCS，直接整体替换，避免超大文件占用过多内存
+	maxDiffMatrixCells = 4_000_000
+)
+
+type diffOp struct {
+	kind byte // ' ', '-', '+'
+	text string
+}
+
+// Unified 生成 old → new 的 unified diff（3 行上下文）；内容相同时返回空串
+func Unified(path, oldContent, newContent string) string {
+	if oldContent == newContent {
+		return ""
+	}
+	oldLines := SplitLines(oldContent)
+	newLines := SplitLines(newContent)
+	ops := diffLines(oldLines, newLines)
+
+	oldLabel, newLabel := "a/"+path, "b/"+path
+	if oldContent == "" {
+		oldLabel = "/dev/null"
+	}
+	if newContent == "" {
+		newLabel = "/dev/null"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldLabel, newLabel)
+	for _, h := range groupDiffHunks(ops) {
+		b.WriteString(h)
+	}
+	return b.String()
+}
+
+// Stats 统计 unified diff 中新增与删除的行数
+func Stats(diff string) (added, removed int) {
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// SplitLines 按行拆分并保留换行符，使缺少结尾换行的文件也能精确还原
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func diffMiddle(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	ops := make([]diffOp, 0, n+m)
+	if n == 0 || m == 0 || n*m > maxDiffMatrixCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] = a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+func groupDiffHunks(ops []diffOp) []string {
+	var hunks []string
+	oldLine, newLine := 1, 1
+	i := 0
+	for i < len(ops) {
+		if ops[i].kind == ' ' {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// 向前带上最多 3 行上下文
+		start := i
+		ctx := 0
+		for start > 0 && ops[start-1].kind == ' ' && ctx < diffContextLines {
+			start--
+			ctx++
+		}
+		hunkOld, hunkNew := oldLine-ctx, newLine-ctx
+
+		// 向后扩展，直到连续上下文超过 2*3 行
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := 0
+			for end+run < len(ops) && ops[end+run].kind == ' ' {
+				run++
+			}
+			if end+run >= len(ops) || run > 2*diffContextLines {
+				if run > diffContextLines {
+					run = diffContextLines
+				}
+				end += run
+				break
+			}
+			end += run
+		}
+
+		var body strings.Builder
+		oldCount, newCount := 0, 0
+		for _, op := range ops[start:end] {
+			switch op.kind {
+			case ' ':
+				oldCount++
+				newCount++
+			case '-':
+				oldCount++
+			case '+':
+				newCount++
+			}
+			body.WriteByte(op.kind)
+			body.WriteString(op.text)
+			if !strings.HasSuffix(op.text, "\n") {
+				body.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		hunks = append(hunks, fmt.Sprintf("@@ -%s +%s @@\n%s",
+			hunkRange(hunkOld, oldCount), hunkRange(hunkNew, newCount), body.String()))
+
+		for _, op := range ops[i:end] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return hunks
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,37 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedGroupsDistantChanges(t *testing.T) {
+	oldContent := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	newContent := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nL\nm\n"
+
+	diff := Unified("notes.txt", oldContent, newContent)
+	require.NotEmpty(t, diff)
+	assert.True(t, strings.HasPrefix(diff, "--- a/notes.txt\n+++ b/notes.txt\n"))
+	assert.Equal(t, 2, strings.Count(diff, "@@ -"), "distant changes should produce two hunks")
+
+	added, removed := Stats(diff)
+	assert.Equal(t, 3, added)
+	assert.Equal(t, 2, removed)
+}
+
+func TestUnifiedNoChange(t *testing.T) {
+	assert.Empty(t, Unified("x", "same\n", "same\n"))
+}
+
+func TestUnifiedMissingTrailingNewline(t *testing.T) {
+	assert.Contains(t, Unified("x", "one\ntwo", "one\nthree"), "\\ No newline at end of file")
+	assert.Equal(t, []string{"one\n", "two"}, SplitLines("one\ntwo"))
+}
+
+func TestUnifiedCreateAndDelete(t *testing.T) {
+	assert.True(t, strings.HasPrefix(Unified("new.txt", "", "x\n"), "--- /dev/null\n+++ b/new.txt\n"))
+	assert.True(t, strings.HasPrefix(Unified("old.txt", "x\n", ""), "--- a/old.txt\n+++ /dev/null\n"))
+}
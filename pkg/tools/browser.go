@@ -135,6 +135,7 @@ type browserToolRequest struct {
 	MaxChars  int                   `json:"maxChars,omitempty"`
 	TimeoutMs int                   `json:"timeoutMs,omitempty"`
 	SessionID string                `json:"sessionId,omitempty"`
+	Proxy     string                `json:"proxy,omitempty"`
 	Chrome    *browserChromeRequest `json:"chrome,omitempty"`
 }
 
@@ -207,6 +208,26 @@ func (t *BrowserTool) Execute(ctx context.Context, params map[string]interface{}
 		req.FullPage = v
 	}
 
+	guard := EgressGuardFrom(ctx)
+	if guard != nil {
+		// open 打开的窗口在调用结束后仍继续运行，无法经本次调用的守卫代理出站
+		if action == "open" {
+			return "", fmt.Errorf("browser open is not available while the egress guard is enabled; use navigate instead")
+		}
+		req.Chrome.withoutCDP()
+		if req.URL != "" && req.URL != "about:blank" {
+			if err := guard.CheckURL(ctx, t.name, req.URL); err != nil {
+				return "", err
+			}
+		}
+		proxy, err := guard.StartBrowserProxy(t.name)
+		if err != nil {
+			return "", err
+		}
+		defer proxy.Close()
+		req.Proxy = proxy.URL()
+	}
+
 	channel, chatID := RuntimeContextFrom(ctx)
 	req.SessionID = browserSessionID(channel, chatID)
 	if action == "screenshot" {
@@ -247,6 +268,12 @@ func (t *BrowserTool) Execute(ctx context.Context, params map[string]interface{}
 		}
 		return "", fmt.Errorf("browser error: %s", result.Error)
 	}
+	// 点击或脚本跳转后落在受限地址时不返回页面内容
+	if finalURL, _ := result.Data["url"].(string); guard != nil && strings.HasPrefix(finalURL, "http") {
+		if err := guard.CheckURL(ctx, t.name, finalURL); err != nil {
+			return "", err
+		}
+	}
 
 	if strings.TrimSpace(result.Summary) != "" {
 		return strings.TrimSpace(result.Summary), nil
@@ -0,0 +1,213 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Lichas/maxclaw/pkg/textdiff"
+)
+
+// filePatch 单个文件的 unified diff
+type filePatch struct {
+	oldPath string
+	newPath string
+	hunks   []patchHunk
+}
+
+type patchHunk struct {
+	oldStart int
+	lines    []patchLine
+}
+
+// patchLine hunk 中的一行：kind 为 ' '、'-' 或 '+'，text 保留换行符
+type patchLine struct {
+	kind byte
+	text string
+}
+
+func (p filePatch) isCreate() bool { return p.oldPath == "/dev/null" }
+func (p filePatch) isDelete() bool { return p.newPath == "/dev/null" }
+
+func (p filePatch) targetPath() string {
+	if p.isDelete() {
+		return p.oldPath
+	}
+	return p.newPath
+}
+
+// parseUnifiedDiff 解析（可能包含多个文件的）unified diff
+func parseUnifiedDiff(patch string) ([]filePatch, error) {
+	lines := strings.SplitAfter(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var files []filePatch
+	var current *filePatch
+	var hunk *patchHunk
+
+	flushHunk := func() {
+		if current != nil && hunk != nil {
+			current.hunks = append(current.hunks, *hunk)
+		}
+		hunk = nil
+	}
+
+	for idx := 0; idx < len(lines); idx++ {
+		raw := lines[idx]
+		line := strings.TrimRight(raw, "\n")
+		switch {
+		case strings.HasPrefix(line, "--- ") && idx+1 < len(lines) && strings.HasPrefix(lines[idx+1], "+++ "):
+			flushHunk()
+			files = append(files, filePatch{
+				oldPath: diffHeaderPath(line[4:]),
+				newPath: diffHeaderPath(strings.TrimRight(lines[idx+1], "\n")[4:]),
+			})
+			current = &files[len(files)-1]
+			idx++
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				return nil, fmt.Errorf("hunk header before file header: %q", line)
+			}
+			flushHunk()
+			oldStart, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			hunk = &patchHunk{oldStart: oldStart}
+		case hunk != nil && strings.HasPrefix(line, "\\"):
+			// "\ No newline at end of file" 作用于上一行
+			if n := len(hunk.lines); n > 0 {
+				hunk.lines[n-1].text = strings.TrimSuffix(hunk.lines[n-1].text, "\n")
+			}
+		case hunk != nil && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "-") || strings.HasPrefix(line, "+")):
+			text := raw[1:]
+			if !strings.HasSuffix(text, "\n") {
+				text += "\n"
+			}
+			hunk.lines = append(hunk.lines, patchLine{kind: line[0], text: text})
+		case hunk != nil && line == "" && idx < len(lines)-1:
+			// 部分模型会把空的上下文行输出为真正的空行
+			hunk.lines = append(hunk.lines, patchLine{kind: ' ', text: "\n"})
+		default:
+			// diff --git / index / 说明文字等，忽略
+			flushHunk()
+		}
+	}
+	flushHunk()
+
+	for i := range files {
+		if files[i].targetPath() == "" || files[i].targetPath() == "/dev/null" {
+			return nil, fmt.Errorf("patch contains a file header without a path")
+		}
+		if len(files[i].hunks) == 0 {
+			return nil, fmt.Errorf("patch for %s has no hunks", files[i].targetPath())
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file headers (---/+++) found in patch")
+	}
+	return files, nil
+}
+
+func diffHeaderPath(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "\t"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "/dev/null" {
+		return s
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		return s[2:]
+	}
+	return s
+}
+
+func parseHunkHeader(line string) (int, error) {
+	// @@ -l[,c] +l[,c] @@
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") {
+		return 0, fmt.Errorf("invalid hunk header: %q", line)
+	}
+	old := strings.TrimPrefix(fields[1], "-")
+	if i := strings.Index(old, ","); i >= 0 {
+		old = old[:i]
+	}
+	start, err := strconv.Atoi(old)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hunk header: %q", line)
+	}
+	return start, nil
+}
+
+// applyFilePatch 将 hunks 应用到内容上；hunk 位置允许偏移，找不到上下文时报错
+func applyFilePatch(content string, p filePatch) (string, error) {
+	lines := textdiff.SplitLines(content)
+	out := make([]string, 0, len(lines))
+	cursor := 0
+	delta := 0
+
+	for n, h := range p.hunks {
+		var oldSeg, newSeg []string
+		for _, op := range h.lines {
+			if op.kind != '+' {
+				oldSeg = append(oldSeg, op.text)
+			}
+			if op.kind != '-' {
+				newSeg = append(newSeg, op.text)
+			}
+		}
+
+		want := h.oldStart - 1 + delta
+		if len(oldSeg) == 0 {
+			// 纯新增：oldStart 指向插入点之前的行
+			want = h.oldStart + delta
+		}
+		pos := findHunkPosition(lines, oldSeg, want, cursor)
+		if pos < 0 {
+			return "", fmt.Errorf("hunk %d (@@ -%d) does not apply: context not found", n+1, h.oldStart)
+		}
+
+		out = append(out, lines[cursor:pos]...)
+		out = append(out, newSeg...)
+		cursor = pos + len(oldSeg)
+		delta = pos - (h.oldStart - 1)
+		if len(oldSeg) == 0 {
+			delta = pos - h.oldStart
+		}
+	}
+	out = append(out, lines[cursor:]...)
+	return strings.Join(out, ""), nil
+}
+
+func findHunkPosition(lines, seg []string, want, min int) int {
+	if want < min {
+		want = min
+	}
+	if want > len(lines) {
+		want = len(lines)
+	}
+	for _, equal := range []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		func(a, b string) bool { return strings.TrimRight(a, " \t\r\n") == strings.TrimRight(b, " \t\r\n") },
+	} {
+		matches := func(pos int) bool {
+			if pos < min || pos+len(seg) > len(lines) {
+				return false
+			}
+			for i, s := range seg {
+				if !equal(lines[pos+i], s) {
+					return false
+				}
+			}
+			return true
+		}
+		for dist := 0; dist <= len(lines); dist++ {
+			if matches(want - dist) {
+				return want - dist
+			}
+			if dist > 0 && matches(want+dist) {
+				return want + dist
+			}
+		}
+	}
+	return -1
+}
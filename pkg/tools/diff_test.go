@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Lichas/maxclaw/pkg/textdiff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedDiffRoundTrip(t *testing.T) {
+	oldContent := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	newContent := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nL\nm\n"
+
+	diff := textdiff.Unified("notes.txt", oldContent, newContent)
+	require.NotEmpty(t, diff)
+
+	patches, err := parseUnifiedDiff(diff)
+	require.NoError(t, err)
+	require.Len(t, patches, 1)
+	got, err := applyFilePatch(oldContent, patches[0])
+	require.NoError(t, err)
+	assert.Equal(t, newContent, got)
+}
+
+func TestUnifiedDiffMissingTrailingNewline(t *testing.T) {
+	diff := textdiff.Unified("x", "one\ntwo", "one\nthree")
+
+	patches, err := parseUnifiedDiff(diff)
+	require.NoError(t, err)
+	got, err := applyFilePatch("one\ntwo", patches[0])
+	require.NoError(t, err)
+	assert.Equal(t, "one\nthree", got)
+}
+
+func TestApplyFilePatchToleratesLineOffset(t *testing.T) {
+	patch := "--- a/f.go\n+++ b/f.go\n@@ -2,3 +2,3 @@\n x := 1\n-y := 2\n+y := 3\n z := 4\n"
+	patches, err := parseUnifiedDiff(patch)
+	require.NoError(t, err)
+
+	// 文件开头多了几行，hunk 仍应定位成功
+	content := "// header\n// more\npackage f\nx := 1\ny := 2\nz := 4\n"
+	got, err := applyFilePatch(content, patches[0])
+	require.NoError(t, err)
+	assert.Equal(t, "// header\n// more\npackage f\nx := 1\ny := 3\nz := 4\n", got)
+}
+
+func TestApplyFilePatchContextMismatch(t *testing.T) {
+	patch := "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n alpha\n-beta\n+gamma\n"
+	patches, err := parseUnifiedDiff(patch)
+	require.NoError(t, err)
+
+	_, err = applyFilePatch("alpha\ndelta\n", patches[0])
+	require.Error(t, err)
+}
+
+func TestParseUnifiedDiffMultiFileCreateDelete(t *testing.T) {
+	patch := strings.Join([]string{
+		"--- /dev/null",
+		"+++ b/new.txt",
+		"@@ -0,0 +1,2 @@",
+		"+hello",
+		"+world",
+		"--- a/old.txt",
+		"+++ /dev/null",
+		"@@ -1 +0,0 @@",
+		"-bye",
+		"",
+	}, "\n")
+
+	patches, err := parseUnifiedDiff(patch)
+	require.NoError(t, err)
+	require.Len(t, patches, 2)
+
+	assert.True(t, patches[0].isCreate())
+	assert.Equal(t, "new.txt", patches[0].targetPath())
+	created, err := applyFilePatch("", patches[0])
+	require.NoError(t, err)
+	assert.Equal(t, "hello\nworld\n", created)
+
+	assert.True(t, patches[1].isDelete())
+	assert.Equal(t, "old.txt", patches[1].targetPath())
+	deleted, err := applyFilePatch("bye\n", patches[1])
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+}
+
+func TestParseUnifiedDiffRejectsGarbage(t *testing.T) {
+	_, err := parseUnifiedDiff("just some text\n")
+	assert.Error(t, err)
+}
@@ -0,0 +1,352 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type egressContextKey struct{}
+
+// EgressOptions 出站网络访问策略（web_fetch / browser / MCP HTTP）
+type EgressOptions struct {
+	// AllowPrivate 允许访问回环、内网、链路本地等地址（默认禁止）
+	AllowPrivate bool
+	// AllowCIDRs 即使 AllowPrivate 关闭也允许的网段，例如局域网内的服务
+	AllowCIDRs []string
+	// AllowDomains 非空时仅允许这些域名及其子域名
+	AllowDomains []string
+	DenyDomains  []string
+	// Proxy 出站代理（http / https / socks5）
+	Proxy string
+	// Agent 写入审计记录的代理名称
+	Agent string
+	// Audit 请求被拦截时调用
+	Audit func(ctx context.Context, block EgressBlock)
+}
+
+// EgressBlock 一次被拦截的出站请求
+type EgressBlock struct {
+	Agent  string `json:"agent,omitempty"`
+	Source string `json:"source"`
+	URL    string `json:"url,omitempty"`
+	Host   string `json:"host"`
+	IP     string `json:"ip,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// EgressBlockedError 出站请求被策略拦截
+type EgressBlockedError struct {
+	Block EgressBlock
+}
+
+func (e *EgressBlockedError) Error() string {
+	if e.Block.IP != "" && e.Block.IP != e.Block.Host {
+		return fmt.Sprintf("egress blocked: %s (%s -> %s)", e.Block.Reason, e.Block.Host, e.Block.IP)
+	}
+	return fmt.Sprintf("egress blocked: %s (%s)", e.Block.Reason, e.Block.Host)
+}
+
+// IsEgressBlocked 判断错误是否来自出站策略拦截
+func IsEgressBlocked(err error) bool {
+	var blocked *EgressBlockedError
+	return errors.As(err, &blocked)
+}
+
+// EgressGuard 解析 DNS 后校验目标地址，并为 HTTP 客户端提供逐跳检查
+type EgressGuard struct {
+	opts      EgressOptions
+	allowNets []*net.IPNet
+	allow     []string
+	deny      []string
+	proxy     *url.URL
+	trusted   map[string]bool
+	lookup    func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NewEgressGuard 创建出站访问守卫
+func NewEgressGuard(opts EgressOptions) (*EgressGuard, error) {
+	g := &EgressGuard{
+		opts:   opts,
+		allow:  normalizeDomains(opts.AllowDomains),
+		deny:   normalizeDomains(opts.DenyDomains),
+		lookup: net.DefaultResolver.LookupIPAddr,
+	}
+	for _, cidr := range opts.AllowCIDRs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowCidrs entry %q: %w", cidr, err)
+		}
+		g.allowNets = append(g.allowNets, network)
+	}
+	if proxy := strings.TrimSpace(opts.Proxy); proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q", proxy)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+		}
+		g.proxy = u
+	}
+	return g, nil
+}
+
+// Options 返回守卫的配置
+func (g *EgressGuard) Options() EgressOptions {
+	return g.opts
+}
+
+// ProxyURL 返回配置的上游代理地址
+func (g *EgressGuard) ProxyURL() string {
+	if g.proxy == nil {
+		return ""
+	}
+	return g.proxy.String()
+}
+
+// ForAgent 派生代理级守卫：allow 非空时替换允许列表，deny 追加到拒绝列表
+func (g *EgressGuard) ForAgent(name string, allow, deny []string) *EgressGuard {
+	opts := g.opts
+	opts.Agent = name
+	if len(trimPatterns(allow)) > 0 {
+		opts.AllowDomains = append([]string(nil), allow...)
+	}
+	opts.DenyDomains = append(append([]string(nil), opts.DenyDomains...), deny...)
+	derived := g.clone()
+	derived.opts = opts
+	derived.allow = normalizeDomains(opts.AllowDomains)
+	derived.deny = normalizeDomains(opts.DenyDomains)
+	return derived
+}
+
+// Trusting 返回信任指定主机的副本（用于运维显式配置的 MCP 地址，重定向到其他主机仍会检查）
+func (g *EgressGuard) Trusting(hosts ...string) *EgressGuard {
+	derived := g.clone()
+	derived.trusted = make(map[string]bool, len(g.trusted)+len(hosts))
+	for host := range g.trusted {
+		derived.trusted[host] = true
+	}
+	for _, host := range hosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			derived.trusted[host] = true
+		}
+	}
+	return derived
+}
+
+func (g *EgressGuard) clone() *EgressGuard {
+	c := *g
+	return &c
+}
+
+// CheckURL 校验 URL 的协议、域名列表与解析后的地址
+func (g *EgressGuard) CheckURL(ctx context.Context, source, rawURL string) error {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return g.block(ctx, EgressBlock{Source: source, URL: rawURL, Host: u.Host, Reason: fmt.Sprintf("scheme %q is not allowed", u.Scheme)})
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("url has no host: %s", rawURL)
+	}
+	// 经代理访问时由代理解析 DNS，这里仍在本地预先解析并检查
+	_, err = g.checkHost(ctx, source, rawURL, u.Hostname())
+	return err
+}
+
+// checkHost 检查域名列表并返回允许连接的 IP；可信主机返回 nil
+func (g *EgressGuard) checkHost(ctx context.Context, source, rawURL, host string) ([]net.IP, error) {
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if g.trusted[host] {
+		return nil, nil
+	}
+	if hostInDomains(host, g.deny) {
+		return nil, g.block(ctx, EgressBlock{Source: source, URL: rawURL, Host: host, Reason: "domain is denied"})
+	}
+	if len(g.allow) > 0 && !hostInDomains(host, g.allow) {
+		return nil, g.block(ctx, EgressBlock{Source: source, URL: rawURL, Host: host, Reason: "domain is not in the allow list"})
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := g.lookup(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s: %w", host, err)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("resolve %s: no addresses", host)
+		}
+	}
+
+	// 任一解析结果落在受限网段即拒绝，避免多记录轮询绕过
+	for _, ip := range ips {
+		if reason := g.ipBlockReason(ip); reason != "" {
+			return nil, g.block(ctx, EgressBlock{Source: source, URL: rawURL, Host: host, IP: ip.String(), Reason: reason})
+		}
+	}
+	return ips, nil
+}
+
+func (g *EgressGuard) ipBlockReason(ip net.IP) string {
+	for _, network := range g.allowNets {
+		if network.Contains(ip) {
+			return ""
+		}
+	}
+	if g.opts.AllowPrivate {
+		return ""
+	}
+	if kind := restrictedIPKind(ip); kind != "" {
+		return "address is " + kind
+	}
+	return ""
+}
+
+func (g *EgressGuard) block(ctx context.Context, block EgressBlock) error {
+	block.Agent = g.opts.Agent
+	if g.opts.Audit != nil {
+		g.opts.Audit(ctx, block)
+	}
+	return &EgressBlockedError{Block: block}
+}
+
+type restrictedNetwork struct {
+	kind    string
+	network *net.IPNet
+}
+
+// restrictedNetworks 标准库分类之外仍需拦截的网段
+var restrictedNetworks = func() []restrictedNetwork {
+	entries := [][2]string{
+		{"unspecified", "0.0.0.0/8"},
+		{"carrier-grade NAT", "100.64.0.0/10"},
+		{"benchmarking", "198.18.0.0/15"},
+		{"reserved", "240.0.0.0/4"},
+	}
+	out := make([]restrictedNetwork, 0, len(entries))
+	for _, e := range entries {
+		_, network, _ := net.ParseCIDR(e[1])
+		out = append(out, restrictedNetwork{kind: e[0], network: network})
+	}
+	return out
+}()
+
+// restrictedIPKind 返回受限地址类型；公网地址返回空字符串
+func restrictedIPKind(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	switch {
+	case ip.IsLoopback():
+		return "loopback"
+	case ip.IsPrivate():
+		return "private"
+	case ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast():
+		return "link-local"
+	case ip.IsUnspecified():
+		return "unspecified"
+	case ip.IsMulticast(), ip.IsInterfaceLocalMulticast():
+		return "multicast"
+	}
+	for _, r := range restrictedNetworks {
+		if r.network.Contains(ip) {
+			return r.kind
+		}
+	}
+	return ""
+}
+
+func hostInDomains(host string, domains []string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPClient 返回受守卫保护的客户端：每次请求（含重定向）检查 URL，
+// 直连时在拨号阶段再次校验解析结果并连接已校验的 IP，防止 DNS rebinding。
+func (g *EgressGuard) HTTPClient(source string, timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       60 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	if g.proxy != nil {
+		transport.Proxy = http.ProxyURL(g.proxy)
+		transport.DialContext = dialer.DialContext
+	} else {
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return g.dialChecked(ctx, source, network, addr)
+		}
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &egressRoundTripper{guard: g, source: source, base: transport},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects")
+			}
+			return nil
+		},
+	}
+}
+
+type egressRoundTripper struct {
+	guard  *EgressGuard
+	source string
+	base   http.RoundTripper
+}
+
+func (t *egressRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.guard.CheckURL(req.Context(), t.source, req.URL.String()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// WithEgressGuard 注入请求级出站守卫
+func WithEgressGuard(ctx context.Context, guard *EgressGuard) context.Context {
+	return context.WithValue(ctx, egressContextKey{}, guard)
+}
+
+// EgressGuardFrom 读取请求级出站守卫；未注入时返回 nil（不做限制）
+func EgressGuardFrom(ctx context.Context) *EgressGuard {
+	if ctx == nil {
+		return nil
+	}
+	guard, _ := ctx.Value(egressContextKey{}).(*EgressGuard)
+	return guard
+}
@@ -0,0 +1,256 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// BrowserProxy 浏览器使用的本地转发代理：Playwright 的全部请求（含重定向与页面子资源）都经此出站，
+// 每个连接按守卫检查目标主机，直连时连接已校验的 IP，配置了 tools.egress.proxy 时经上游代理转发
+type BrowserProxy struct {
+	guard    *EgressGuard
+	source   string
+	listener net.Listener
+	server   *http.Server
+	forward  *httputil.ReverseProxy
+
+	mu      sync.Mutex
+	tunnels map[net.Conn]struct{}
+	closed  bool
+}
+
+// StartBrowserProxy 在 127.0.0.1 的随机端口启动转发代理，用完后调用 Close
+func (g *EgressGuard) StartBrowserProxy(source string) (*BrowserProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("start egress proxy: %w", err)
+	}
+	p := &BrowserProxy{
+		guard:    g,
+		source:   source,
+		listener: listener,
+		tunnels:  make(map[net.Conn]struct{}),
+	}
+	p.forward = &httputil.ReverseProxy{
+		// 代理请求的 URL 已是绝对地址，原样转发；不添加 X-Forwarded-* 头
+		Rewrite:      func(*httputil.ProxyRequest) {},
+		Transport:    g.HTTPClient(source, 0).Transport,
+		ErrorHandler: p.fail,
+	}
+	p.server = &http.Server{Handler: p, ReadHeaderTimeout: 30 * time.Second}
+	go func() { _ = p.server.Serve(listener) }()
+	return p, nil
+}
+
+// URL 传给 Playwright 的代理地址
+func (p *BrowserProxy) URL() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+// Close 停止代理并断开仍在进行的隧道
+func (p *BrowserProxy) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	for conn := range p.tunnels {
+		_ = conn.Close()
+	}
+	p.tunnels = nil
+	p.mu.Unlock()
+	return p.server.Close()
+}
+
+func (p *BrowserProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "egress proxy only accepts proxy requests", http.StatusBadRequest)
+		return
+	}
+	p.forward.ServeHTTP(w, r)
+}
+
+// tunnel 处理 HTTPS / WebSocket 的 CONNECT 请求
+func (p *BrowserProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := p.guard.dialChecked(r.Context(), p.source, "tcp", r.Host)
+	if err != nil {
+		p.fail(w, r, err)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if !p.track(client, upstream) {
+		client.Close()
+		upstream.Close()
+		return
+	}
+	defer p.untrack(client, upstream)
+
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		return
+	}
+	// 客户端可能已在 CONNECT 之后发出数据
+	if n := buffered.Reader.Buffered(); n > 0 {
+		data, _ := buffered.Reader.Peek(n)
+		if _, err := upstream.Write(data); err != nil {
+			return
+		}
+	}
+
+	done := make(chan struct{}, 2)
+	pipe := func(dst, src net.Conn) {
+		_, _ = io.Copy(dst, src)
+		if tcp, ok := dst.(*net.TCPConn); ok {
+			_ = tcp.CloseWrite()
+		}
+		done <- struct{}{}
+	}
+	go pipe(upstream, client)
+	go pipe(client, upstream)
+	<-done
+	<-done
+}
+
+func (p *BrowserProxy) track(conns ...net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	for _, conn := range conns {
+		p.tunnels[conn] = struct{}{}
+	}
+	return true
+}
+
+func (p *BrowserProxy) untrack(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range conns {
+		_ = conn.Close()
+		delete(p.tunnels, conn)
+	}
+}
+
+// fail 被拦截的请求返回 403，浏览器看到的是加载失败而不是目标内容
+func (p *BrowserProxy) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if IsEgressBlocked(err) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadGateway)
+}
+
+// dialChecked 检查目标主机后建立连接：直连时只连接已校验的 IP，配置了代理时经上游代理建立隧道
+func (g *EgressGuard) dialChecked(ctx context.Context, source, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := g.checkHost(ctx, source, "", host)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	if g.proxy != nil {
+		return g.dialUpstream(ctx, dialer, addr)
+	}
+	if ips == nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// dialUpstream 经配置的上游代理连接 addr：socks5 直接拨号，http / https 代理发送 CONNECT
+func (g *EgressGuard) dialUpstream(ctx context.Context, dialer *net.Dialer, addr string) (net.Conn, error) {
+	var username, password string
+	if g.proxy.User != nil {
+		username = g.proxy.User.Username()
+		password, _ = g.proxy.User.Password()
+	}
+
+	if g.proxy.Scheme == "socks5" || g.proxy.Scheme == "socks5h" {
+		var auth *proxy.Auth
+		if username != "" {
+			auth = &proxy.Auth{User: username, Password: password}
+		}
+		socks, err := proxy.SOCKS5("tcp", g.proxy.Host, auth, dialer)
+		if err != nil {
+			return nil, err
+		}
+		return socks.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", g.proxy.Host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(dialer.Timeout))
+	}
+	if g.proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: g.proxy.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if username != "" {
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT %s: %s", addr, resp.Status)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
@@ -0,0 +1,281 @@
+package tools
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestrictedIPKind(t *testing.T) {
+	cases := map[string]string{
+		"127.0.0.1":       "loopback",
+		"::1":             "loopback",
+		"10.1.2.3":        "private",
+		"192.168.1.10":    "private",
+		"fd00::1":         "private",
+		"169.254.169.254": "link-local",
+		"fe80::1":         "link-local",
+		"0.0.0.0":         "unspecified",
+		"100.64.0.1":      "carrier-grade NAT",
+		"::ffff:10.0.0.1": "private",
+		"8.8.8.8":         "",
+		"2606:4700::1111": "",
+	}
+	for raw, want := range cases {
+		assert.Equal(t, want, restrictedIPKind(net.ParseIP(raw)), raw)
+	}
+}
+
+func TestEgressGuardBlocksLoopbackFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	var blocks []EgressBlock
+	guard, err := NewEgressGuard(EgressOptions{
+		Agent: "research",
+		Audit: func(ctx context.Context, block EgressBlock) { blocks = append(blocks, block) },
+	})
+	require.NoError(t, err)
+
+	tool := NewWebFetchTool(WebFetchOptions{Mode: "http"})
+	_, err = tool.Execute(WithEgressGuard(context.Background(), guard), map[string]interface{}{"url": server.URL})
+	require.Error(t, err)
+	assert.True(t, IsEgressBlocked(err))
+	require.Len(t, blocks, 1)
+	assert.Equal(t, "research", blocks[0].Agent)
+	assert.Equal(t, "web_fetch", blocks[0].Source)
+	assert.Equal(t, "127.0.0.1", blocks[0].IP)
+	assert.Equal(t, "address is loopback", blocks[0].Reason)
+
+	// 未注入守卫时保持原有行为
+	out, err := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	require.NoError(t, err)
+	assert.Contains(t, out, "secret")
+}
+
+func TestEgressGuardRechecksRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	guard, err := NewEgressGuard(EgressOptions{AllowCIDRs: []string{"127.0.0.1"}})
+	require.NoError(t, err)
+
+	tool := NewWebFetchTool(WebFetchOptions{Mode: "http"})
+	_, err = tool.Execute(WithEgressGuard(context.Background(), guard), map[string]interface{}{"url": server.URL})
+	require.Error(t, err)
+	assert.True(t, IsEgressBlocked(err))
+	assert.Contains(t, err.Error(), "link-local")
+}
+
+func TestEgressGuardDomainLists(t *testing.T) {
+	guard, err := NewEgressGuard(EgressOptions{
+		AllowDomains: []string{"example.com"},
+		DenyDomains:  []string{"private.example.com"},
+	})
+	require.NoError(t, err)
+	guard.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, guard.CheckURL(ctx, "web_fetch", "https://docs.example.com/page"))
+	assert.ErrorContains(t, guard.CheckURL(ctx, "web_fetch", "https://api.private.example.com/"), "domain is denied")
+	assert.ErrorContains(t, guard.CheckURL(ctx, "web_fetch", "https://other.org/"), "not in the allow list")
+	assert.ErrorContains(t, guard.CheckURL(ctx, "web_fetch", "file:///etc/passwd"), "scheme")
+
+	agentGuard := guard.ForAgent("coder", []string{"github.com"}, []string{"gist.github.com"})
+	assert.NoError(t, agentGuard.CheckURL(ctx, "web_fetch", "https://github.com/Lichas/maxclaw"))
+	assert.Error(t, agentGuard.CheckURL(ctx, "web_fetch", "https://docs.example.com/"))
+	assert.Error(t, agentGuard.CheckURL(ctx, "web_fetch", "https://gist.github.com/x"))
+	assert.Error(t, agentGuard.CheckURL(ctx, "web_fetch", "https://api.private.example.com/"))
+	assert.Equal(t, "coder", agentGuard.Options().Agent)
+	// 派生守卫不影响原守卫
+	assert.NoError(t, guard.CheckURL(ctx, "web_fetch", "https://docs.example.com/page"))
+}
+
+func TestEgressGuardBlocksAnyPrivateDNSAnswer(t *testing.T) {
+	guard, err := NewEgressGuard(EgressOptions{})
+	require.NoError(t, err)
+	guard.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}, {IP: net.ParseIP("10.0.0.5")}}, nil
+	}
+
+	err = guard.CheckURL(context.Background(), "browser", "http://rebind.example.net/")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rebind.example.net -> 10.0.0.5")
+}
+
+func TestEgressGuardTrustedHost(t *testing.T) {
+	guard, err := NewEgressGuard(EgressOptions{})
+	require.NoError(t, err)
+
+	trusted := guard.Trusting("127.0.0.1")
+	assert.NoError(t, trusted.CheckURL(context.Background(), "mcp:local", "http://127.0.0.1:8080/mcp"))
+	assert.Error(t, trusted.CheckURL(context.Background(), "mcp:local", "http://169.254.169.254/"))
+	assert.Error(t, guard.CheckURL(context.Background(), "mcp:local", "http://127.0.0.1:8080/mcp"))
+}
+
+func TestNewEgressGuardValidation(t *testing.T) {
+	_, err := NewEgressGuard(EgressOptions{AllowCIDRs: []string{"not-a-cidr"}})
+	assert.Error(t, err)
+
+	_, err = NewEgressGuard(EgressOptions{Proxy: "ftp://proxy:21"})
+	assert.Error(t, err)
+
+	guard, err := NewEgressGuard(EgressOptions{Proxy: "socks5://127.0.0.1:1080"})
+	require.NoError(t, err)
+	assert.Equal(t, "socks5://127.0.0.1:1080", guard.ProxyURL())
+}
+
+func TestBrowserProxyChecksEveryRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("page"))
+	}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("secure page"))
+	}))
+	defer tlsServer.Close()
+
+	var blocks []EgressBlock
+	guard, err := NewEgressGuard(EgressOptions{Audit: func(ctx context.Context, block EgressBlock) { blocks = append(blocks, block) }})
+	require.NoError(t, err)
+
+	get := func(guard *EgressGuard, target string) (int, string) {
+		proxy, err := guard.StartBrowserProxy("browser")
+		require.NoError(t, err)
+		defer proxy.Close()
+		proxyURL, err := url.Parse(proxy.URL())
+		require.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyURL(proxyURL),
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
+		resp, err := client.Get(target)
+		if err != nil {
+			return 0, err.Error()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	// 子资源或脚本请求回环地址：HTTP 与 CONNECT 都被拦截
+	status, body := get(guard, server.URL)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Contains(t, body, "address is loopback")
+	_, msg := get(guard, tlsServer.URL)
+	assert.Contains(t, msg, "Forbidden")
+	require.Len(t, blocks, 2)
+	assert.Equal(t, "browser", blocks[1].Source)
+
+	// 允许的地址正常转发；重定向到元数据地址的下一跳被拦截
+	local := guard.Trusting("127.0.0.1")
+	status, body = get(local, server.URL)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "page", body)
+	status, body = get(local, tlsServer.URL)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "secure page", body)
+	status, body = get(local, server.URL+"/redirect")
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Contains(t, body, "link-local")
+
+	// 配置了上游代理时经上游转发（HTTP 直接转发，HTTPS 发送 CONNECT）
+	upstream, err := local.StartBrowserProxy("upstream")
+	require.NoError(t, err)
+	defer upstream.Close()
+	chained, err := NewEgressGuard(EgressOptions{Proxy: upstream.URL()})
+	require.NoError(t, err)
+	chained = chained.Trusting("127.0.0.1")
+	status, body = get(chained, server.URL)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "page", body)
+	status, body = get(chained, tlsServer.URL)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "secure page", body)
+}
+
+// fakeNodeRunner 代替 node 运行脚本：记录收到的请求并返回成功结果
+func fakeNodeRunner(t *testing.T) (nodePath, scriptPath, requestPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	requestPath = filepath.Join(dir, "request.json")
+	scriptPath = filepath.Join(dir, "browser.mjs")
+	require.NoError(t, os.WriteFile(scriptPath, []byte("// unused"), 0644))
+	nodePath = filepath.Join(dir, "node")
+	script := "#!/bin/sh\ncat > '" + requestPath + "'\n" +
+		`echo '{"ok":true,"summary":"done","title":"T","text":"hello","html":"<p>hello</p>"}'` + "\n"
+	require.NoError(t, os.WriteFile(nodePath, []byte(script), 0755))
+	return nodePath, scriptPath, requestPath
+}
+
+func TestEgressGuardCoversCDPAndOpenBrowserModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake node runner is a shell script")
+	}
+	guard, err := NewEgressGuard(EgressOptions{})
+	require.NoError(t, err)
+	ctx := WithEgressGuard(context.Background(), guard)
+	chrome := WebFetchChromeOptions{CDPEndpoint: "http://127.0.0.1:9222", AutoStartCDP: true, TakeoverExisting: true}
+
+	readRequest := func(path string) map[string]interface{} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var req map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &req))
+		return req
+	}
+
+	nodePath, scriptPath, requestPath := fakeNodeRunner(t)
+	browser := NewBrowserTool(BrowserToolOptions{NodePath: nodePath, ScriptPath: scriptPath, Chrome: chrome})
+	_, err = browser.Execute(ctx, map[string]interface{}{"action": "open", "url": "https://93.184.215.14/"})
+	assert.ErrorContains(t, err, "not available while the egress guard is enabled")
+	assert.NoFileExists(t, requestPath, "open never reaches the browser script")
+
+	_, err = browser.Execute(ctx, map[string]interface{}{"action": "snapshot"})
+	require.NoError(t, err)
+	req := readRequest(requestPath)
+	assert.NotEmpty(t, req["proxy"])
+	cdp := req["chrome"].(map[string]interface{})
+	assert.Empty(t, cdp["cdpEndpoint"], "CDP takeover is replaced by a managed profile behind the proxy")
+	assert.Equal(t, false, cdp["autoStartCDP"])
+
+	nodePath, scriptPath, requestPath = fakeNodeRunner(t)
+	fetch := NewWebFetchTool(WebFetchOptions{Mode: "chrome", NodePath: nodePath, ScriptPath: scriptPath, Chrome: chrome})
+	out, err := fetch.Execute(ctx, map[string]interface{}{"url": "https://93.184.215.14/"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "hello")
+	req = readRequest(requestPath)
+	assert.NotEmpty(t, req["proxy"])
+	assert.Empty(t, req["chrome"].(map[string]interface{})["cdpEndpoint"])
+
+	// 未启用守卫时保持 CDP 配置
+	nodePath, scriptPath, requestPath = fakeNodeRunner(t)
+	browser = NewBrowserTool(BrowserToolOptions{NodePath: nodePath, ScriptPath: scriptPath, Chrome: chrome})
+	_, err = browser.Execute(context.Background(), map[string]interface{}{"action": "snapshot"})
+	require.NoError(t, err)
+	req = readRequest(requestPath)
+	assert.Empty(t, req["proxy"])
+	assert.Equal(t, "http://127.0.0.1:9222", req["chrome"].(map[string]interface{})["cdpEndpoint"])
+}
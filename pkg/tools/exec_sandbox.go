@@ -0,0 +1,311 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ExecBackendHost   = "host"
+	ExecBackendBwrap  = "bwrap"
+	ExecBackendDocker = "docker"
+	ExecBackendPodman = "podman"
+
+	defaultExecMaxOutputBytes = 10 * 1024
+	defaultExecContainerImage = "debian:stable-slim"
+)
+
+// ExecSandboxOptions exec 工具的执行后端配置
+type ExecSandboxOptions struct {
+	// Backend 默认后端：host / bwrap / docker / podman
+	Backend string
+	// ModeBackends 按执行模式（safe/ask/auto）覆盖后端
+	ModeBackends map[string]string
+	// Network 是否允许联网；nil 时 host 允许，沙箱后端默认禁用
+	Network *bool
+	// CPUs 可用 CPU 核数上限（0 表示不限制）
+	CPUs float64
+	// MemoryMB 内存上限（MB，0 表示不限制）
+	MemoryMB int
+	// MaxOutputBytes 输出截断上限（0 使用默认 10KB）
+	MaxOutputBytes int
+	// Image 容器后端使用的镜像
+	Image string
+}
+
+// ExecRequest 一次沙箱执行请求
+type ExecRequest struct {
+	Command  string
+	WorkDir  string
+	Network  bool
+	CPUs     float64
+	MemoryMB int
+	Image    string
+}
+
+// ExecBackend 将 shell 命令包装为具体的执行进程
+type ExecBackend interface {
+	Name() string
+	// Sandboxed 为 true 时工作区隔离由后端挂载保证，不再依赖命令路径启发式检查
+	Sandboxed() bool
+	Available() error
+	// Prepare 返回待执行的进程与超时后的清理函数（可为 nil）
+	Prepare(ctx context.Context, req ExecRequest) (*exec.Cmd, func(), error)
+}
+
+// NewExecBackend 按名称创建执行后端
+func NewExecBackend(name string) (ExecBackend, error) {
+	switch normalizeExecBackend(name) {
+	case ExecBackendHost:
+		return hostExecBackend{}, nil
+	case ExecBackendBwrap:
+		return bwrapExecBackend{}, nil
+	case ExecBackendDocker:
+		return containerExecBackend{runtime: ExecBackendDocker}, nil
+	case ExecBackendPodman:
+		return containerExecBackend{runtime: ExecBackendPodman}, nil
+	default:
+		return nil, fmt.Errorf("unknown exec backend %q (want host, bwrap, docker or podman)", name)
+	}
+}
+
+// ValidateExecSandboxOptions 校验后端名称与资源限制
+func ValidateExecSandboxOptions(opts ExecSandboxOptions) error {
+	if _, err := NewExecBackend(opts.Backend); err != nil {
+		return err
+	}
+	for mode, backend := range opts.ModeBackends {
+		if _, err := NewExecBackend(backend); err != nil {
+			return fmt.Errorf("modeBackends.%s: %w", mode, err)
+		}
+	}
+	if opts.CPUs < 0 || opts.MemoryMB < 0 || opts.MaxOutputBytes < 0 {
+		return fmt.Errorf("exec limits must not be negative")
+	}
+	return nil
+}
+
+// ResolveExecBackendName 选择后端：执行模式映射优先，其次默认后端，最后 host
+func ResolveExecBackendName(opts ExecSandboxOptions, executionMode string) string {
+	if mode := strings.ToLower(strings.TrimSpace(executionMode)); mode != "" {
+		if backend := strings.TrimSpace(opts.ModeBackends[mode]); backend != "" {
+			return normalizeExecBackend(backend)
+		}
+	}
+	return normalizeExecBackend(opts.Backend)
+}
+
+func normalizeExecBackend(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "", "none", "local":
+		return ExecBackendHost
+	case "bubblewrap", "namespace":
+		return ExecBackendBwrap
+	default:
+		return name
+	}
+}
+
+func (o ExecSandboxOptions) networkFor(backend ExecBackend) bool {
+	if o.Network != nil {
+		return *o.Network
+	}
+	return !backend.Sandboxed()
+}
+
+func (o ExecSandboxOptions) maxOutput() int {
+	if o.MaxOutputBytes > 0 {
+		return o.MaxOutputBytes
+	}
+	return defaultExecMaxOutputBytes
+}
+
+// hostExecBackend 直接在宿主机执行（原有行为）
+type hostExecBackend struct{}
+
+func (hostExecBackend) Name() string     { return ExecBackendHost }
+func (hostExecBackend) Sandboxed() bool  { return false }
+func (hostExecBackend) Available() error { return nil }
+
+func (hostExecBackend) Prepare(ctx context.Context, req ExecRequest) (*exec.Cmd, func(), error) {
+	if !req.Network {
+		return nil, nil, fmt.Errorf("host exec backend cannot disable network; use bwrap, docker or podman")
+	}
+	name, args := wrapWithTaskset(req.CPUs, "sh", "-c", withMemoryUlimit(req.Command, req.MemoryMB))
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = req.WorkDir
+	return cmd, nil, nil
+}
+
+// bwrapExecBackend 使用 bubblewrap 建立只读根文件系统 + 可写工作区的命名空间沙箱
+type bwrapExecBackend struct{}
+
+func (bwrapExecBackend) Name() string    { return ExecBackendBwrap }
+func (bwrapExecBackend) Sandboxed() bool { return true }
+
+func (bwrapExecBackend) Available() error {
+	if _, err := exec.LookPath("bwrap"); err != nil {
+		return fmt.Errorf("bwrap not found in PATH (install bubblewrap)")
+	}
+	return nil
+}
+
+func (b bwrapExecBackend) Prepare(ctx context.Context, req ExecRequest) (*exec.Cmd, func(), error) {
+	if req.WorkDir == "" {
+		return nil, nil, fmt.Errorf("bwrap exec backend requires a workspace")
+	}
+	name, args := wrapWithTaskset(req.CPUs, "bwrap", buildBwrapArgs(req)...)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = req.WorkDir
+	return cmd, nil, nil
+}
+
+func buildBwrapArgs(req ExecRequest) []string {
+	args := []string{
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+		"--bind", req.WorkDir, req.WorkDir,
+		"--chdir", req.WorkDir,
+		"--unshare-all",
+	}
+	if req.Network {
+		args = append(args, "--share-net")
+	}
+	args = append(args, "--die-with-parent", "--new-session", "--", "sh", "-c", withMemoryUlimit(req.Command, req.MemoryMB))
+	return args
+}
+
+// containerExecBackend 在一次性 Docker/Podman 容器中执行，工作区以读写方式挂载
+type containerExecBackend struct {
+	runtime string
+}
+
+func (c containerExecBackend) Name() string  { return c.runtime }
+func (containerExecBackend) Sandboxed() bool { return true }
+
+func (c containerExecBackend) Available() error {
+	if _, err := exec.LookPath(c.runtime); err != nil {
+		return fmt.Errorf("%s not found in PATH", c.runtime)
+	}
+	return nil
+}
+
+func (c containerExecBackend) Prepare(ctx context.Context, req ExecRequest) (*exec.Cmd, func(), error) {
+	if req.WorkDir == "" {
+		return nil, nil, fmt.Errorf("%s exec backend requires a workspace", c.runtime)
+	}
+	name := "maxclaw-exec-" + randomSuffix()
+	cmd := exec.CommandContext(ctx, c.runtime, buildContainerArgs(name, req)...)
+	cleanup := func() {
+		// CommandContext 只会杀掉客户端进程，容器需要显式删除
+		rmCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = exec.CommandContext(rmCtx, c.runtime, "rm", "-f", name).Run()
+	}
+	return cmd, cleanup, nil
+}
+
+func buildContainerArgs(name string, req ExecRequest) []string {
+	image := strings.TrimSpace(req.Image)
+	if image == "" {
+		image = defaultExecContainerImage
+	}
+	args := []string{
+		"run", "--rm", "-i",
+		"--name", name,
+		"--read-only",
+		"--tmpfs", "/tmp",
+		"-v", req.WorkDir + ":" + req.WorkDir,
+		"-w", req.WorkDir,
+	}
+	if req.Network {
+		args = append(args, "--network", "bridge")
+	} else {
+		args = append(args, "--network", "none")
+	}
+	if req.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(req.CPUs, 'f', -1, 64))
+	}
+	if req.MemoryMB > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%dm", req.MemoryMB))
+	}
+	if uid, gid := os.Getuid(), os.Getgid(); uid >= 0 && gid >= 0 {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", uid, gid))
+	}
+	return append(args, image, "sh", "-c", req.Command)
+}
+
+// withMemoryUlimit 通过 ulimit -v 为非容器后端施加虚拟内存上限
+func withMemoryUlimit(command string, memoryMB int) string {
+	if memoryMB <= 0 {
+		return command
+	}
+	return fmt.Sprintf("ulimit -v %d && %s", memoryMB*1024, command)
+}
+
+// wrapWithTaskset 在可用时通过 taskset 将进程绑定到前 N 个 CPU
+func wrapWithTaskset(cpus float64, name string, args ...string) (string, []string) {
+	if cpus <= 0 {
+		return name, args
+	}
+	taskset, err := exec.LookPath("taskset")
+	if err != nil {
+		return name, args
+	}
+	n := int(math.Ceil(cpus))
+	return taskset, append([]string{"-c", fmt.Sprintf("0-%d", n-1), name}, args...)
+}
+
+func randomSuffix() string {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// cappedBuffer 只保留前 limit 字节输出，避免失控命令占满内存
+type cappedBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if remaining := b.limit - b.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.buf.Write(p[:remaining])
+			b.truncated = true
+		} else {
+			b.buf.Write(p)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.truncated {
+		return b.buf.String() + "\n... (output truncated)"
+	}
+	return b.buf.String()
+}
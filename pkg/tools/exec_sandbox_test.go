@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveExecBackendName(t *testing.T) {
+	opts := ExecSandboxOptions{
+		Backend:      "bubblewrap",
+		ModeBackends: map[string]string{"auto": "docker"},
+	}
+	assert.Equal(t, ExecBackendDocker, ResolveExecBackendName(opts, "auto"))
+	assert.Equal(t, ExecBackendBwrap, ResolveExecBackendName(opts, "ask"))
+	assert.Equal(t, ExecBackendHost, ResolveExecBackendName(ExecSandboxOptions{}, "auto"))
+}
+
+func TestValidateExecSandboxOptions(t *testing.T) {
+	assert.NoError(t, ValidateExecSandboxOptions(ExecSandboxOptions{Backend: "podman", ModeBackends: map[string]string{"safe": "bwrap"}}))
+	assert.Error(t, ValidateExecSandboxOptions(ExecSandboxOptions{Backend: "firecracker"}))
+	assert.Error(t, ValidateExecSandboxOptions(ExecSandboxOptions{ModeBackends: map[string]string{"auto": "vm"}}))
+	assert.Error(t, ValidateExecSandboxOptions(ExecSandboxOptions{MemoryMB: -1}))
+}
+
+func TestBuildBwrapArgs(t *testing.T) {
+	args := buildBwrapArgs(ExecRequest{Command: "ls", WorkDir: "/ws", MemoryMB: 256})
+	joined := strings.Join(args, " ")
+	assert.Contains(t, joined, "--ro-bind / /")
+	assert.Contains(t, joined, "--bind /ws /ws")
+	assert.Contains(t, joined, "--unshare-all")
+	assert.NotContains(t, joined, "--share-net")
+	assert.Equal(t, "ulimit -v 262144 && ls", args[len(args)-1])
+
+	withNet := buildBwrapArgs(ExecRequest{Command: "ls", WorkDir: "/ws", Network: true})
+	assert.Contains(t, withNet, "--share-net")
+}
+
+func TestBuildContainerArgs(t *testing.T) {
+	args := buildContainerArgs("maxclaw-exec-test", ExecRequest{
+		Command:  "make test",
+		WorkDir:  "/ws",
+		CPUs:     1.5,
+		MemoryMB: 512,
+		Image:    "golang:1.24",
+	})
+	joined := strings.Join(args, " ")
+	assert.Contains(t, joined, "--network none")
+	assert.Contains(t, joined, "--cpus 1.5")
+	assert.Contains(t, joined, "--memory 512m")
+	assert.Contains(t, joined, "-v /ws:/ws")
+	assert.Contains(t, joined, "--read-only")
+	assert.Equal(t, []string{"golang:1.24", "sh", "-c", "make test"}, args[len(args)-4:])
+
+	withNet := buildContainerArgs("n", ExecRequest{Command: "ls", WorkDir: "/ws", Network: true})
+	assert.Contains(t, strings.Join(withNet, " "), "--network bridge")
+	assert.Contains(t, withNet, defaultExecContainerImage)
+}
+
+func TestCappedBuffer(t *testing.T) {
+	buf := &cappedBuffer{limit: 5}
+	n, err := buf.Write([]byte("abc"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	_, _ = buf.Write([]byte("defgh"))
+	assert.Equal(t, "abcde\n... (output truncated)", buf.String())
+}
+
+func TestExecToolSandboxOptions(t *testing.T) {
+	workspace := t.TempDir()
+	ctx := context.Background()
+
+	t.Run("output cap", func(t *testing.T) {
+		tool := NewExecToolWithSandbox(workspace, 5, false, ExecSandboxOptions{MaxOutputBytes: 8})
+		result, err := tool.Execute(ctx, map[string]interface{}{"command": "printf '0123456789abcdef'"})
+		require.NoError(t, err)
+		assert.Equal(t, "01234567\n... (output truncated)", result)
+	})
+
+	t.Run("host cannot disable network", func(t *testing.T) {
+		off := false
+		tool := NewExecToolWithSandbox(workspace, 5, false, ExecSandboxOptions{Network: &off})
+		_, err := tool.Execute(ctx, map[string]interface{}{"command": "echo hi"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot disable network")
+	})
+
+	t.Run("unknown backend", func(t *testing.T) {
+		tool := NewExecToolWithSandbox(workspace, 5, false, ExecSandboxOptions{Backend: "vm"})
+		_, err := tool.Execute(ctx, map[string]interface{}{"command": "echo hi"})
+		assert.Error(t, err)
+	})
+
+	t.Run("mode selects backend", func(t *testing.T) {
+		if _, err := exec.LookPath("podman"); err == nil {
+			t.Skip("podman installed; unavailable-backend path not exercised")
+		}
+		tool := NewExecToolWithSandbox(workspace, 5, false, ExecSandboxOptions{ModeBackends: map[string]string{"auto": "podman"}})
+		_, err := tool.Execute(WithRuntimeExecutionMode(ctx, "auto"), map[string]interface{}{"command": "echo hi"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exec backend podman unavailable")
+
+		result, err := tool.Execute(WithRuntimeExecutionMode(ctx, "ask"), map[string]interface{}{"command": "echo hi"})
+		require.NoError(t, err)
+		assert.Contains(t, result, "hi")
+	})
+
+	t.Run("bwrap sandbox", func(t *testing.T) {
+		if _, err := exec.LookPath("bwrap"); err != nil {
+			t.Skip("bwrap not installed")
+		}
+		tool := NewExecToolWithSandbox(workspace, 5, true, ExecSandboxOptions{Backend: "bwrap"})
+		result, err := tool.Execute(ctx, map[string]interface{}{"command": "echo ok > inside.txt && cat inside.txt && touch /etc/maxclaw-probe"})
+		require.NoError(t, err)
+		assert.Contains(t, result, "ok")
+		assert.Contains(t, result, "Command failed")
+	})
+}
@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Lichas/maxclaw/pkg/textdiff"
+)
+
+type fileTrackingContextKey string
+
+const (
+	fileReadTrackerKey    fileTrackingContextKey = "file_read_tracker"
+	fileChangeRecorderKey fileTrackingContextKey = "file_change_recorder"
+)
+
+// FileChange 一次文件修改记录（供时间线 / Web UI 展示逐轮 diff）
+type FileChange struct {
+	Path    string `json:"path"`
+	Tool    string `json:"tool"`
+	Diff    string `json:"diff"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	Created bool   `json:"created,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// WithFileChangeRecorder 注入文件修改回调，编辑类工具成功写入后调用
+func WithFileChangeRecorder(ctx context.Context, record func(FileChange)) context.Context {
+	return context.WithValue(ctx, fileChangeRecorderKey, record)
+}
+
+func recordFileChange(ctx context.Context, tool, path, oldContent, newContent string) string {
+	diff := textdiff.Unified(path, oldContent, newContent)
+	if diff == "" || ctx == nil {
+		return diff
+	}
+	if record, ok := ctx.Value(fileChangeRecorderKey).(func(FileChange)); ok && record != nil {
+		added, removed := textdiff.Stats(diff)
+		record(FileChange{
+			Path:    path,
+			Tool:    tool,
+			Diff:    diff,
+			Added:   added,
+			Removed: removed,
+			Created: oldContent == "" && newContent != "",
+			Deleted: newContent == "",
+		})
+	}
+	return diff
+}
+
+// FileReadTracker 记录每个会话读取过的文件版本；开启 requireRead 时编辑前必须先 read_file
+type FileReadTracker struct {
+	mu          sync.Mutex
+	requireRead bool
+	reads       map[string]map[string]fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewFileReadTracker 创建读取跟踪器
+func NewFileReadTracker(requireRead bool) *FileReadTracker {
+	return &FileReadTracker{
+		requireRead: requireRead,
+		reads:       make(map[string]map[string]fileStamp),
+	}
+}
+
+// SetRequireRead 切换编辑前必须先读取的检查
+func (t *FileReadTracker) SetRequireRead(require bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requireRead = require
+}
+
+// RequireRead 是否要求编辑前先读取
+func (t *FileReadTracker) RequireRead() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.requireRead
+}
+
+// WithFileReadTracker 注入读取跟踪器
+func WithFileReadTracker(ctx context.Context, tracker *FileReadTracker) context.Context {
+	return context.WithValue(ctx, fileReadTrackerKey, tracker)
+}
+
+func fileReadTrackerFrom(ctx context.Context) *FileReadTracker {
+	if ctx == nil {
+		return nil
+	}
+	tracker, _ := ctx.Value(fileReadTrackerKey).(*FileReadTracker)
+	return tracker
+}
+
+// markFileRead 记录文件当前版本已被会话读取（编辑工具写入后同样调用）
+func markFileRead(ctx context.Context, path string) {
+	tracker := fileReadTrackerFrom(ctx)
+	if tracker == nil {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	session := RuntimeSessionKeyFrom(ctx)
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if tracker.reads[session] == nil {
+		tracker.reads[session] = make(map[string]fileStamp)
+	}
+	tracker.reads[session][path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// ensureFileRead 在 RequireRead 开启时检查文件自上次读取后未被修改
+func ensureFileRead(ctx context.Context, path string) error {
+	tracker := fileReadTrackerFrom(ctx)
+	if tracker == nil || !tracker.RequireRead() {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		// 新文件无需先读取
+		return nil
+	}
+
+	session := RuntimeSessionKeyFrom(ctx)
+	tracker.mu.Lock()
+	stamp, ok := tracker.reads[session][path]
+	tracker.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("file %s has not been read in this session; call read_file before editing it", path)
+	}
+	if !stamp.modTime.Equal(info.ModTime()) || stamp.size != info.Size() {
+		return fmt.Errorf("file %s changed since it was last read; call read_file again before editing it", path)
+	}
+	return nil
+}
+
+// Forget 清除会话的读取记录
+func (t *FileReadTracker) Forget(sessionKey string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.reads, sessionKey)
+}
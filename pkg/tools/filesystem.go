@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/Lichas/maxclaw/internal/media"
 )
 
 // allowedDir 全局允许的目录（用于沙箱）
@@ -33,7 +35,8 @@ func GetWorkspaceDir() string {
 }
 
 // isPathAllowed 检查路径是否允许访问
-func isPathAllowed(path string) error {
+func isPathAllowed(ctx context.Context, path string) error {
+	allowedDir := allowedDirFromContext(ctx)
 	if allowedDir == "" {
 		return nil
 	}
@@ -80,7 +83,7 @@ func resolvePath(ctx context.Context, path string) (string, error) {
 			if err != nil {
 				return "", fmt.Errorf("invalid session base path: %w", err)
 			}
-			if err := isPathAllowed(absBase); err != nil {
+			if err := isPathAllowed(ctx, absBase); err != nil {
 				return "", err
 			}
 
@@ -97,7 +100,7 @@ func resolvePath(ctx context.Context, path string) (string, error) {
 				return "", fmt.Errorf("path %q escapes current session directory", path)
 			}
 
-			if err := isPathAllowed(absPath); err != nil {
+			if err := isPathAllowed(ctx, absPath); err != nil {
 				return "", err
 			}
 			return absPath, nil
@@ -109,15 +112,29 @@ func resolvePath(ctx context.Context, path string) (string, error) {
 		return "", err
 	}
 
-	if err := isPathAllowed(absPath); err != nil {
+	if err := isPathAllowed(ctx, absPath); err != nil {
 		return "", err
 	}
 
 	return absPath, nil
 }
 
+// allowedDirFromContext 优先使用请求级工作区限制（多代理），否则回退到全局设置
+func allowedDirFromContext(ctx context.Context) string {
+	if dir, restrict := RuntimeWorkspaceFrom(ctx); dir != "" {
+		if restrict {
+			return dir
+		}
+		return ""
+	}
+	return allowedDir
+}
+
 func sessionBaseDirFromContext(ctx context.Context) (string, bool) {
 	root := strings.TrimSpace(workspaceDir)
+	if dir, _ := RuntimeWorkspaceFrom(ctx); dir != "" {
+		root = dir
+	}
 	if root == "" {
 		return "", false
 	}
@@ -191,7 +208,7 @@ func NewReadFileTool() *ReadFileTool {
 	return &ReadFileTool{
 		BaseTool: BaseTool{
 			name:        "read_file",
-			description: "Read the contents of a file. Use for viewing code, logs, or any text file.",
+			description: "Read the contents of a file. Use for viewing code, logs, or any text file. PDF, DOCX, XLSX, PPTX and EPUB files are converted to Markdown with page/sheet/slide/chapter anchors.",
 			parameters: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -201,13 +218,13 @@ func NewReadFileTool() *ReadFileTool {
 					},
 					"limit": map[string]interface{}{
 						"type":        "integer",
-						"description": "Maximum number of lines to read (optional)",
+						"description": "Maximum number of lines to read (optional). For documents (PDF/DOCX/XLSX/PPTX/EPUB) this counts pages, sheet sections, slides or chapters (default 20).",
 						"minimum":     1,
 						"maximum":     1000,
 					},
 					"offset": map[string]interface{}{
 						"type":        "integer",
-						"description": "Line offset to start reading from (0-indexed, optional)",
+						"description": "Line offset to start reading from (0-indexed, optional). For documents this is the page offset.",
 						"minimum":     0,
 					},
 				},
@@ -229,11 +246,6 @@ func (t *ReadFileTool) Execute(ctx context.Context, params map[string]interface{
 		return "", err
 	}
 
-	content, err := os.ReadFile(resolvedPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
-	}
-
 	// 处理 offset 和 limit (支持 float64 和 int)
 	offset := 0
 	if v, ok := params["offset"].(float64); ok {
@@ -249,6 +261,22 @@ func (t *ReadFileTool) Execute(ctx context.Context, params map[string]interface{
 		limit = v
 	}
 
+	// 文档按页提取为 Markdown，offset/limit 以页为单位
+	if info, statErr := os.Stat(resolvedPath); statErr == nil && !info.IsDir() && media.DetectDocumentFormat(resolvedPath, "") != "" {
+		doc, err := media.ExtractDocument(resolvedPath, "")
+		if err != nil {
+			return "", err
+		}
+		markFileRead(ctx, resolvedPath)
+		return doc.Render(offset, limit)
+	}
+
+	content, err := os.ReadFile(resolvedPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	markFileRead(ctx, resolvedPath)
+
 	lines := strings.Split(string(content), "\n")
 
 	// 应用 offset
@@ -316,9 +344,16 @@ func (t *WriteFileTool) Execute(ctx context.Context, params map[string]interface
 		return "", fmt.Errorf("failed to create directory: %w", err)
 	}
 
+	var oldContent string
+	if existing, err := os.ReadFile(resolvedPath); err == nil {
+		oldContent = string(existing)
+	}
+
 	if err := os.WriteFile(resolvedPath, []byte(content), 0644); err != nil {
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
+	recordFileChange(ctx, t.name, path, oldContent, content)
+	markFileRead(ctx, resolvedPath)
 
 	return fmt.Sprintf("File written successfully: %s", resolvedPath), nil
 }
@@ -333,7 +368,7 @@ func NewEditFileTool() *EditFileTool {
 	return &EditFileTool{
 		BaseTool: BaseTool{
 			name:        "edit_file",
-			description: "Edit a file by replacing specific text. Use for making targeted changes to existing files.",
+			description: "Edit a file by replacing specific text. old_string must match exactly once unless replace_all is set; include surrounding lines to disambiguate. Returns a unified diff of the change. Use apply_patch for multi-file or multi-hunk edits.",
 			parameters: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -349,6 +384,10 @@ func NewEditFileTool() *EditFileTool {
 						"type":        "string",
 						"description": "New text to insert",
 					},
+					"replace_all": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Replace every occurrence of old_string (default: false)",
+					},
 				},
 				"required": []string{"path", "old_string", "new_string"},
 			},
@@ -361,6 +400,7 @@ func (t *EditFileTool) Execute(ctx context.Context, params map[string]interface{
 	path, _ := params["path"].(string)
 	oldString, _ := params["old_string"].(string)
 	newString, _ := params["new_string"].(string)
+	replaceAll, _ := params["replace_all"].(bool)
 
 	if path == "" {
 		return "", fmt.Errorf("path is required")
@@ -376,17 +416,27 @@ func (t *EditFileTool) Execute(ctx context.Context, params map[string]interface{
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
 
+	if err := ensureFileRead(ctx, resolvedPath); err != nil {
+		return "", err
+	}
+
 	oldContent := string(content)
-	if !strings.Contains(oldContent, oldString) {
-		return "", fmt.Errorf("old_string not found in file")
+	newContent, err := replaceExact(oldContent, oldString, newString, replaceAll)
+	if err != nil {
+		return "", err
 	}
 
-	newContent := strings.Replace(oldContent, oldString, newString, 1)
-	if err := os.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
+	if err := writeFileAtomic(resolvedPath, []byte(newContent)); err != nil {
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
+	diff := recordFileChange(ctx, t.name, path, oldContent, newContent)
+	markFileRead(ctx, resolvedPath)
 
-	return fmt.Sprintf("File edited successfully: %s", resolvedPath), nil
+	result := fmt.Sprintf("File edited successfully: %s", resolvedPath)
+	if diff != "" {
+		result += "\n\n" + capOutput(diff, maxPatchResultDiffBytes)
+	}
+	return result, nil
 }
 
 // ListDirTool 列出目录工具
@@ -0,0 +1,420 @@
+package tools
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var htmlBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "center": true, "dd": true,
+	"details": true, "dialog": true, "div": true, "dl": true, "dt": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "summary": true, "table": true, "tbody": true,
+	"td": true, "tfoot": true, "th": true, "thead": true, "tr": true, "ul": true,
+}
+
+var codeLanguagePattern = regexp.MustCompile(`(?:^|\s)(?:language|lang|highlight-source|brush:?)-?([A-Za-z0-9_+#.-]+)`)
+
+// markdownRenderer 将节点树渲染为 Markdown
+type markdownRenderer struct {
+	base           *url.URL
+	referenceLinks bool
+	refs           []string
+	refIndex       map[string]int
+	links          []PageLink
+	seenLinks      map[string]bool
+}
+
+func (r *markdownRenderer) render(n *htmlNode) string {
+	return strings.TrimSpace(strings.Join(r.blocks(n), "\n\n"))
+}
+
+// blocks 渲染子节点：连续的行内内容合并为一个段落
+func (r *markdownRenderer) blocks(n *htmlNode) []string {
+	var out []string
+	var inline strings.Builder
+	flush := func() {
+		if text := cleanInline(inline.String()); text != "" {
+			out = append(out, text)
+		}
+		inline.Reset()
+	}
+	for _, c := range n.children {
+		if c.isText() || !htmlBlockTags[c.tag] {
+			inline.WriteString(r.inline(c))
+			continue
+		}
+		flush()
+		out = append(out, r.block(c)...)
+	}
+	flush()
+	return out
+}
+
+func (r *markdownRenderer) block(n *htmlNode) []string {
+	switch n.tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := strings.ReplaceAll(cleanInline(r.inlineChildren(n)), "\n", " ")
+		if text == "" {
+			return nil
+		}
+		level := int(n.tag[1] - '0')
+		return []string{strings.Repeat("#", level) + " " + text}
+	case "pre":
+		return []string{r.codeBlock(n)}
+	case "ul", "ol":
+		if list := r.list(n); list != "" {
+			return []string{list}
+		}
+		return nil
+	case "blockquote":
+		inner := strings.Join(r.blocks(n), "\n\n")
+		if inner == "" {
+			return nil
+		}
+		lines := strings.Split(inner, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return []string{strings.Join(lines, "\n")}
+	case "table":
+		return r.table(n)
+	case "hr":
+		return []string{"---"}
+	case "dt":
+		if text := cleanInline(r.inlineChildren(n)); text != "" {
+			return []string{"**" + text + "**"}
+		}
+		return nil
+	case "dd":
+		inner := strings.Join(r.blocks(n), "\n")
+		if inner == "" {
+			return nil
+		}
+		lines := strings.Split(inner, "\n")
+		lines[0] = ": " + lines[0]
+		return []string{strings.Join(lines, "\n  ")}
+	}
+	return r.blocks(n)
+}
+
+func (r *markdownRenderer) inlineChildren(n *htmlNode) string {
+	var b strings.Builder
+	for _, c := range n.children {
+		b.WriteString(r.inline(c))
+	}
+	return b.String()
+}
+
+func (r *markdownRenderer) inline(n *htmlNode) string {
+	if n.isText() {
+		return collapseSpaces(n.text)
+	}
+	switch n.tag {
+	case "br":
+		return "\n"
+	case "a":
+		return r.link(n)
+	case "strong", "b":
+		return wrapInline(r.inlineChildren(n), "**")
+	case "em", "i", "cite":
+		return wrapInline(r.inlineChildren(n), "*")
+	case "del", "s", "strike":
+		return wrapInline(r.inlineChildren(n), "~~")
+	case "code", "kbd", "samp", "tt":
+		code := strings.TrimSpace(collapseSpaces(rawText(n)))
+		if code == "" {
+			return ""
+		}
+		fence := "`"
+		if strings.Contains(code, "`") {
+			fence = "``"
+		}
+		return fence + code + fence
+	case "img":
+		alt := collapseSpaces(strings.TrimSpace(n.attr("alt")))
+		src := resolveLink(r.base, n.attr("src"))
+		if alt == "" || src == "" {
+			return ""
+		}
+		return "![" + escapeMarkdownLinkText(alt) + "](" + src + ")"
+	}
+	if htmlBlockTags[n.tag] {
+		// 行内元素中嵌套的块级内容，按换行分隔
+		return "\n" + strings.Join(r.block(n), "\n") + "\n"
+	}
+	return r.inlineChildren(n)
+}
+
+func (r *markdownRenderer) link(n *htmlNode) string {
+	text := strings.TrimSpace(strings.ReplaceAll(cleanInline(r.inlineChildren(n)), "\n", " "))
+	rawHref := strings.TrimSpace(n.attr("href"))
+	href := resolveLink(r.base, rawHref)
+	if href == "" || strings.HasPrefix(rawHref, "#") {
+		return text
+	}
+	if text == "" {
+		text = firstNonEmpty(n.attr("title"), n.attr("aria-label"))
+		if text == "" {
+			return ""
+		}
+	}
+	if r.seenLinks == nil {
+		r.seenLinks = make(map[string]bool)
+	}
+	if !r.seenLinks[href] {
+		r.seenLinks[href] = true
+		r.links = append(r.links, PageLink{Text: text, URL: href})
+	}
+	if !strings.HasPrefix(text, "![") {
+		text = escapeMarkdownLinkText(text)
+	}
+	if r.referenceLinks {
+		if r.refIndex == nil {
+			r.refIndex = make(map[string]int)
+		}
+		idx, ok := r.refIndex[href]
+		if !ok {
+			r.refs = append(r.refs, href)
+			idx = len(r.refs)
+			r.refIndex[href] = idx
+		}
+		return fmt.Sprintf("[%s][%d]", text, idx)
+	}
+	return "[" + text + "](" + href + ")"
+}
+
+func (r *markdownRenderer) codeBlock(n *htmlNode) string {
+	lang := codeLanguage(n)
+	if lang == "" {
+		if code := n.find("code"); code != nil {
+			lang = codeLanguage(code)
+		}
+	}
+	code := strings.TrimRight(strings.TrimPrefix(rawText(n), "\n"), " \t\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+func codeLanguage(n *htmlNode) string {
+	if lang := strings.TrimSpace(n.attr("data-lang")); lang != "" {
+		return lang
+	}
+	if m := codeLanguagePattern.FindStringSubmatch(n.attr("class")); m != nil {
+		return strings.ToLower(m[1])
+	}
+	return ""
+}
+
+func (r *markdownRenderer) list(n *htmlNode) string {
+	ordered := n.tag == "ol"
+	index := 1
+	if start, err := strconv.Atoi(strings.TrimSpace(n.attr("start"))); err == nil && ordered {
+		index = start
+	}
+	var items []string
+	for _, c := range n.children {
+		if c.isText() {
+			if text := cleanInline(c.text); text != "" {
+				items = append(items, "- "+text)
+			}
+			continue
+		}
+		if c.tag == "ul" || c.tag == "ol" {
+			// 不规范地直接嵌套在列表中的子列表
+			if nested := r.list(c); nested != "" {
+				items = append(items, indentLines(nested, "  "))
+			}
+			continue
+		}
+		body := strings.Join(r.blocks(c), "\n")
+		if c.tag != "li" {
+			body = strings.Join(r.block(c), "\n")
+		}
+		if strings.TrimSpace(body) == "" {
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(index) + ". "
+			index++
+		}
+		lines := strings.Split(body, "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = strings.Repeat(" ", len(marker)) + lines[i]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (r *markdownRenderer) table(n *htmlNode) []string {
+	var rows [][]*htmlNode
+	var collect func(*htmlNode)
+	collect = func(node *htmlNode) {
+		for _, c := range node.children {
+			switch c.tag {
+			case "tr":
+				var cells []*htmlNode
+				for _, cell := range c.children {
+					if cell.tag == "td" || cell.tag == "th" {
+						cells = append(cells, cell)
+					}
+				}
+				if len(cells) > 0 {
+					rows = append(rows, cells)
+				}
+			case "thead", "tbody", "tfoot":
+				collect(c)
+			}
+		}
+	}
+	collect(n)
+
+	cols := 0
+	layout := false
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+		for _, cell := range row {
+			if cell.find("table") != nil || cell.find("p") != nil || cell.find("ul") != nil || cell.find("pre") != nil {
+				layout = true
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	// 排版用表格（单列或单元格内含块级内容）按普通块渲染
+	if layout || cols < 2 {
+		var out []string
+		for _, row := range rows {
+			for _, cell := range row {
+				out = append(out, r.blocks(cell)...)
+			}
+		}
+		return out
+	}
+
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for i := 0; i < cols; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+	var caption string
+	if c := n.find("caption"); c != nil {
+		caption = cleanInline(r.inlineChildren(c))
+	}
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			text := cleanInline(r.inlineChildren(cell))
+			text = strings.ReplaceAll(text, "\n", " ")
+			cells[j] = strings.ReplaceAll(text, "|", `\|`)
+		}
+		writeRow(cells)
+		if i == 0 {
+			sep := make([]string, cols)
+			for k := range sep {
+				sep[k] = "---"
+			}
+			writeRow(sep)
+		}
+	}
+	table := strings.TrimRight(b.String(), "\n")
+	if caption != "" {
+		return []string{"**" + caption + "**", table}
+	}
+	return []string{table}
+}
+
+// rawText 保留空白的文本内容（<pre> / <code>）
+func rawText(n *htmlNode) string {
+	var b strings.Builder
+	n.walk(func(c *htmlNode) bool {
+		if c.isText() {
+			b.WriteString(c.text)
+		} else if c.tag == "br" {
+			b.WriteString("\n")
+		}
+		return true
+	})
+	return b.String()
+}
+
+func collapseSpaces(s string) string {
+	if s == "" {
+		return ""
+	}
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		switch r {
+		case ' ', '\t', '\n', '\r', '\f', '\u00a0':
+			if !space {
+				b.WriteByte(' ')
+				space = true
+			}
+		default:
+			b.WriteRune(r)
+			space = false
+		}
+	}
+	return b.String()
+}
+
+// cleanInline 折叠行内空白并去掉首尾空行
+func cleanInline(s string) string {
+	lines := strings.Split(s, "\n")
+	out := lines[:0]
+	for _, line := range lines {
+		line = strings.TrimSpace(collapseSpaces(line))
+		if line == "" && (len(out) == 0 || out[len(out)-1] == "") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// wrapInline 用标记包裹文本，标记紧贴内容、空白留在外侧
+func wrapInline(s, mark string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	lead := s[:len(s)-len(strings.TrimLeft(s, " \n"))]
+	trail := s[len(strings.TrimRight(s, " \n")):]
+	return lead + mark + trimmed + mark + trail
+}
+
+func escapeMarkdownLinkText(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(s)
+}
+
+func indentLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,363 @@
+package tools
+
+import (
+	"html"
+	"strings"
+)
+
+// htmlNode 轻量 DOM 节点；仅覆盖正文提取需要的部分 HTML 语法
+type htmlNode struct {
+	tag      string // 文本节点为空
+	text     string
+	attrs    map[string]string
+	parent   *htmlNode
+	children []*htmlNode
+}
+
+func (n *htmlNode) isText() bool {
+	return n.tag == ""
+}
+
+func (n *htmlNode) attr(name string) string {
+	if n.attrs == nil {
+		return ""
+	}
+	return n.attrs[name]
+}
+
+func (n *htmlNode) appendChild(child *htmlNode) {
+	child.parent = n
+	n.children = append(n.children, child)
+}
+
+// walk 深度优先遍历；fn 返回 false 时跳过子节点
+func (n *htmlNode) walk(fn func(*htmlNode) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range n.children {
+		c.walk(fn)
+	}
+}
+
+func (n *htmlNode) find(tag string) *htmlNode {
+	var found *htmlNode
+	n.walk(func(c *htmlNode) bool {
+		if found != nil {
+			return false
+		}
+		if c.tag == tag {
+			found = c
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func (n *htmlNode) findAll(tag string) []*htmlNode {
+	var out []*htmlNode
+	n.walk(func(c *htmlNode) bool {
+		if c.tag == tag {
+			out = append(out, c)
+		}
+		return true
+	})
+	return out
+}
+
+// textContent 返回节点内全部文本（空白已折叠）
+func (n *htmlNode) textContent() string {
+	var b strings.Builder
+	n.walk(func(c *htmlNode) bool {
+		if c.isText() {
+			b.WriteString(c.text)
+			b.WriteByte(' ')
+		}
+		return true
+	})
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlRawTextElements 内容不解析为标签的元素
+var htmlRawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true, "noscript": true,
+}
+
+// htmlImpliedEnd 打开这些标签时隐式关闭同名（或同组）的未闭合元素，直到遇到边界元素
+var htmlImpliedEnd = map[string]struct {
+	closes   []string
+	boundary []string
+}{
+	"li":     {closes: []string{"li"}, boundary: []string{"ul", "ol", "menu"}},
+	"dt":     {closes: []string{"dt", "dd"}, boundary: []string{"dl"}},
+	"dd":     {closes: []string{"dt", "dd"}, boundary: []string{"dl"}},
+	"tr":     {closes: []string{"tr", "td", "th"}, boundary: []string{"table", "thead", "tbody", "tfoot"}},
+	"td":     {closes: []string{"td", "th"}, boundary: []string{"tr", "table"}},
+	"th":     {closes: []string{"td", "th"}, boundary: []string{"tr", "table"}},
+	"option": {closes: []string{"option"}, boundary: []string{"select", "datalist"}},
+	"thead":  {closes: []string{"thead", "tbody", "tfoot"}, boundary: []string{"table"}},
+	"tbody":  {closes: []string{"thead", "tbody", "tfoot"}, boundary: []string{"table"}},
+	"tfoot":  {closes: []string{"thead", "tbody", "tfoot"}, boundary: []string{"table"}},
+}
+
+// htmlClosesParagraph 打开这些块级元素时隐式关闭 <p>
+var htmlClosesParagraph = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true, "div": true,
+	"dl": true, "fieldset": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "table": true, "ul": true,
+}
+
+// maxHTMLDepth 节点树的最大嵌套层数；更深的元素不再入栈，其内容并入当前层，
+// 避免遍历与渲染时递归耗尽栈
+const maxHTMLDepth = 256
+
+// parseHTML 将 HTML 解析为节点树；容忍未闭合和错误嵌套的标签
+func parseHTML(src string) *htmlNode {
+	root := &htmlNode{tag: "#document"}
+	stack := []*htmlNode{root}
+	current := func() *htmlNode { return stack[len(stack)-1] }
+
+	closeTo := func(index int) {
+		if index > 0 {
+			stack = stack[:index]
+		}
+	}
+	openIndex := func(tag string, boundary []string) int {
+		for i := len(stack) - 1; i > 0; i-- {
+			if stack[i].tag == tag {
+				return i
+			}
+			for _, b := range boundary {
+				if stack[i].tag == b {
+					return -1
+				}
+			}
+		}
+		return -1
+	}
+
+	var text strings.Builder
+	flushText := func() {
+		if text.Len() == 0 {
+			return
+		}
+		current().appendChild(&htmlNode{text: html.UnescapeString(text.String())})
+		text.Reset()
+	}
+
+	i := 0
+	for i < len(src) {
+		if src[i] != '<' {
+			next := strings.IndexByte(src[i:], '<')
+			if next < 0 {
+				text.WriteString(src[i:])
+				break
+			}
+			text.WriteString(src[i : i+next])
+			i += next
+			continue
+		}
+
+		rest := src[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			flushText()
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += 4 + end + 3
+			}
+			continue
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			flushText()
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end + 1
+			}
+			continue
+		case strings.HasPrefix(rest, "</"):
+			name, end := scanTagName(rest, 2)
+			if name == "" {
+				text.WriteString("</")
+				i += 2
+				continue
+			}
+			flushText()
+			closeAt := strings.IndexByte(rest[end:], '>')
+			if closeAt < 0 {
+				i = len(src)
+			} else {
+				i += end + closeAt + 1
+			}
+			for j := len(stack) - 1; j > 0; j-- {
+				if stack[j].tag == name {
+					closeTo(j)
+					break
+				}
+			}
+			continue
+		}
+
+		name, end := scanTagName(rest, 1)
+		if name == "" {
+			text.WriteByte('<')
+			i++
+			continue
+		}
+		flushText()
+		attrs, consumed, selfClosing := scanAttributes(rest[end:])
+		i += end + consumed
+
+		if htmlClosesParagraph[name] {
+			if idx := openIndex("p", []string{"button", "table", "li", "td", "th", "blockquote", "div", "section", "article"}); idx > 0 {
+				closeTo(idx)
+			}
+		}
+		if rule, ok := htmlImpliedEnd[name]; ok {
+			for _, closes := range rule.closes {
+				if idx := openIndex(closes, rule.boundary); idx > 0 {
+					closeTo(idx)
+					break
+				}
+			}
+		}
+
+		node := &htmlNode{tag: name, attrs: attrs}
+		current().appendChild(node)
+		if htmlVoidElements[name] || selfClosing {
+			continue
+		}
+		if htmlRawTextElements[name] {
+			closing := indexClosingTag(src[i:], name)
+			raw := src[i:]
+			if closing >= 0 {
+				raw = src[i : i+closing]
+				i += closing
+				if gt := strings.IndexByte(src[i:], '>'); gt >= 0 {
+					i += gt + 1
+				} else {
+					i = len(src)
+				}
+			} else {
+				i = len(src)
+			}
+			if raw != "" {
+				if name == "title" || name == "textarea" {
+					raw = html.UnescapeString(raw)
+				}
+				node.appendChild(&htmlNode{text: raw})
+			}
+			continue
+		}
+		if len(stack) > maxHTMLDepth {
+			continue
+		}
+		stack = append(stack, node)
+	}
+	flushText()
+	return root
+}
+
+// indexClosingTag 查找 "</name"（忽略大小写）的位置，不复制剩余文档
+func indexClosingTag(s, name string) int {
+	for offset := 0; ; {
+		j := strings.Index(s[offset:], "</")
+		if j < 0 {
+			return -1
+		}
+		start := offset + j
+		end := start + 2 + len(name)
+		if end <= len(s) && strings.EqualFold(s[start+2:end], name) {
+			return start
+		}
+		offset = start + 2
+	}
+}
+
+// scanTagName 从 s[start:] 读取小写标签名，返回名称和结束位置
+func scanTagName(s string, start int) (string, int) {
+	end := start
+	for end < len(s) {
+		c := s[end]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (end > start && ((c >= '0' && c <= '9') || c == '-' || c == ':')) {
+			end++
+			continue
+		}
+		break
+	}
+	if end == start {
+		return "", start
+	}
+	return strings.ToLower(s[start:end]), end
+}
+
+// scanAttributes 解析属性直到 '>'；返回属性、消耗的字节数和是否自闭合
+func scanAttributes(s string) (map[string]string, int, bool) {
+	attrs := make(map[string]string)
+	i := 0
+	selfClosing := false
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '>':
+			return attrs, i + 1, selfClosing
+		case c == '/':
+			selfClosing = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+			continue
+		}
+		selfClosing = false
+
+		start := i
+		for i < len(s) && !strings.ContainsRune(" \t\n\r\f/>=", rune(s[i])) {
+			i++
+		}
+		name := strings.ToLower(s[start:i])
+		for i < len(s) && strings.ContainsRune(" \t\n\r\f", rune(s[i])) {
+			i++
+		}
+		value := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && strings.ContainsRune(" \t\n\r\f", rune(s[i])) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				quote := s[i]
+				end := strings.IndexByte(s[i+1:], quote)
+				if end < 0 {
+					value = s[i+1:]
+					i = len(s)
+				} else {
+					value = s[i+1 : i+1+end]
+					i += end + 2
+				}
+			} else {
+				start := i
+				for i < len(s) && !strings.ContainsRune(" \t\n\r\f>", rune(s[i])) {
+					i++
+				}
+				value = s[start:i]
+			}
+		}
+		if name != "" {
+			if _, exists := attrs[name]; !exists {
+				attrs[name] = html.UnescapeString(value)
+			}
+		}
+	}
+	return attrs, len(s), selfClosing
+}
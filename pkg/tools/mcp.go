@@ -10,6 +10,7 @@ import (
 	"hash/fnv"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"sort"
@@ -58,6 +59,8 @@ type MCPServerOptions struct {
 	Env     map[string]string
 	URL     string
 	Headers map[string]string
+	// Egress HTTP 传输的出站守卫；配置的服务地址本身受信任，重定向到其他主机仍会检查
+	Egress *EgressGuard
 }
 
 type mcpRemoteTool struct {
@@ -140,8 +143,10 @@ type MCPConnector struct {
 	factory        mcpClientFactory
 	clients        map[string]mcpClient
 	registered     []string
+	serverUp       map[string]bool
 	connected      bool
 	lastConnectErr error
+	egress         *EgressGuard
 }
 
 // NewMCPConnector 创建 MCP 连接器。
@@ -169,6 +174,13 @@ func newMCPConnectorWithFactory(servers map[string]MCPServerOptions, factory mcp
 	}
 }
 
+// SetEgressGuard 为 HTTP 类型的 MCP 服务器设置出站守卫，下次 Connect 时生效
+func (c *MCPConnector) SetEgressGuard(guard *EgressGuard) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.egress = guard
+}
+
 // Connect 连接所有 MCP 服务器，并注册它们暴露的工具。
 // 即使部分服务器失败，其他服务器仍会继续连接。
 func (c *MCPConnector) Connect(ctx context.Context, registry *Registry) error {
@@ -183,6 +195,7 @@ func (c *MCPConnector) Connect(ctx context.Context, registry *Registry) error {
 		return err
 	}
 	c.connected = true
+	egress := c.egress
 	c.mu.Unlock()
 
 	names := make([]string, 0, len(c.servers))
@@ -197,6 +210,9 @@ func (c *MCPConnector) Connect(ctx context.Context, registry *Registry) error {
 
 	for _, name := range names {
 		server := c.servers[name]
+		if server.Egress == nil {
+			server.Egress = egress
+		}
 		client, err := c.factory.New(server)
 		if err != nil {
 			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
@@ -233,9 +249,15 @@ func (c *MCPConnector) Connect(ctx context.Context, registry *Registry) error {
 		nextClients[name] = client
 	}
 
+	serverUp := make(map[string]bool, len(names))
+	for _, name := range names {
+		serverUp[name] = nextClients[name] != nil
+	}
+
 	c.mu.Lock()
 		c.clients = nextClients
 		c.registered = registered
+		c.serverUp = serverUp
 		if len(errs) > 0 {
 			c.lastConnectErr = errors.New(strings.Join(errs, "; "))
 		} else {
@@ -252,6 +274,7 @@ func (c *MCPConnector) Close() error {
 	clients := c.clients
 	c.clients = map[string]mcpClient{}
 	c.registered = nil
+	c.serverUp = nil
 	c.connected = false
 	c.lastConnectErr = nil
 	c.mu.Unlock()
@@ -285,6 +308,17 @@ func (c *MCPConnector) RegisteredTools() []string {
 	return out
 }
 
+// ServerStatus 返回最近一次 Connect 后各 MCP 服务器是否连接成功。
+func (c *MCPConnector) ServerStatus() map[string]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]bool, len(c.serverUp))
+	for name, up := range c.serverUp {
+		out[name] = up
+	}
+	return out
+}
+
 type mcpToolWrapper struct {
 	BaseTool
 	serverName   string
@@ -927,12 +961,21 @@ type httpMCPTransport struct {
 }
 
 func newHTTPMCPTransport(opts MCPServerOptions) *httpMCPTransport {
+	endpoint := strings.TrimSpace(opts.URL)
+	client := &http.Client{
+		Timeout: 60 * time.Second,
+	}
+	if opts.Egress != nil {
+		host := ""
+		if u, err := url.Parse(endpoint); err == nil {
+			host = u.Hostname()
+		}
+		client = opts.Egress.Trusting(host).HTTPClient("mcp:"+opts.Name, 60*time.Second)
+	}
 	return &httpMCPTransport{
-		endpoint: strings.TrimSpace(opts.URL),
-		client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
-		headers: opts.Headers,
+		endpoint: endpoint,
+		client:   client,
+		headers:  opts.Headers,
 	}
 }
 
@@ -0,0 +1,317 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Lichas/maxclaw/internal/memory"
+)
+
+// memorySourceMessageLimit 出处中保存的用户消息摘录长度
+const memorySourceMessageLimit = 200
+
+// memoryScopeKey 当前对话的记忆作用域
+const memoryScopeKey runtimeContextKey = "memory_scope"
+
+// WithMemoryScope 注入当前对话适用的记忆作用域
+func WithMemoryScope(ctx context.Context, scope memory.ScopeContext) context.Context {
+	return context.WithValue(ctx, memoryScopeKey, scope)
+}
+
+// MemoryScopeFrom 读取当前对话的记忆作用域；未注入时按频道、会话与发送者推断（不含 agent 与身份映射）
+func MemoryScopeFrom(ctx context.Context) memory.ScopeContext {
+	if ctx != nil {
+		if scope, ok := ctx.Value(memoryScopeKey).(memory.ScopeContext); ok {
+			return scope
+		}
+	}
+	channel, chatID := RuntimeContextFrom(ctx)
+	return memory.NewScopeContext("", channel, chatID, RuntimeSenderFrom(ctx), nil)
+}
+
+// MemoryTool 结构化长期记忆工具：按条目增删改查，结果渲染到对应作用域的 MEMORY.md
+type MemoryTool struct {
+	BaseTool
+	workspace string
+}
+
+// NewMemoryTool 创建记忆工具；请求上下文带有 agent workspace 时优先使用
+func NewMemoryTool(workspace string) *MemoryTool {
+	return &MemoryTool{
+		BaseTool: BaseTool{
+			name: "memory",
+			description: "Manage long-term memory entries (facts, preferences, lessons, todos). Entries are rendered into the MEMORY.md of their scope; each line there carries its id as <!-- id:xxxx -->. " +
+				"Scopes: global is loaded in every conversation, user only when talking to the current user (on any channel), chat only in the current chat, agent only for this agent profile. " +
+				"Use add for durable information worth remembering, update/remove to correct or delete a single entry by id, list to look entries up. Prefer this over editing MEMORY.md by hand.",
+			parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"action": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"add", "update", "remove", "list"},
+						"description": "Action to perform",
+					},
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "Entry id (required for update and remove)",
+					},
+					"type": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"fact", "preference", "lesson", "todo"},
+						"description": "Entry type (required for add; filter for list)",
+					},
+					"scope": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"global", "agent", "user", "chat"},
+						"description": "Memory scope. add defaults to global for facts and user for everything else; update, remove and list default to every scope of this conversation",
+					},
+					"content": map[string]interface{}{
+						"type":        "string",
+						"description": "One self-contained statement (required for add)",
+					},
+					"tags": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Optional tags; for list, the first tag filters entries",
+					},
+					"confidence": map[string]interface{}{
+						"type":        "number",
+						"minimum":     0,
+						"maximum":     1,
+						"description": "How sure you are, 0-1 (default 1). Use lower values for inferred information",
+					},
+					"expires": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional expiry: 7d, 2w, 36h, YYYY-MM-DD, RFC3339 or never",
+					},
+					"done": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Mark a todo as done (update)",
+					},
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Substring filter for list",
+					},
+				},
+				"required": []string{"action"},
+			},
+		},
+		workspace: workspace,
+	}
+}
+
+// Execute 执行记忆操作
+func (t *MemoryTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	workspace := t.workspaceFor(ctx)
+	scopes, err := memoryToolScopes(MemoryScopeFrom(ctx), params)
+	if err != nil {
+		return "", err
+	}
+	id, _ := params["id"].(string)
+	action, _ := params["action"].(string)
+	switch action {
+	case "add":
+		return t.add(ctx, workspace, params)
+	case "update":
+		store, err := memory.FindEntryStore(workspace, scopes, id)
+		if err != nil {
+			return "", err
+		}
+		return t.update(store, params)
+	case "remove":
+		store, err := memory.FindEntryStore(workspace, scopes, id)
+		if err != nil {
+			return "", err
+		}
+		entry, err := store.Remove(id)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Removed %s %s from %s: %s", entry.Type, entry.ID, store.Scope(), entry.Content), nil
+	case "list":
+		return t.list(workspace, scopes, params)
+	case "":
+		return "", fmt.Errorf("action is required")
+	default:
+		return "", fmt.Errorf("unknown action: %s", action)
+	}
+}
+
+func (t *MemoryTool) workspaceFor(ctx context.Context) string {
+	if workspace, _ := RuntimeWorkspaceFrom(ctx); workspace != "" {
+		return workspace
+	}
+	return t.workspace
+}
+
+// memoryToolScopes 解析 scope 参数；未指定时返回当前对话的全部作用域
+func memoryToolScopes(sc memory.ScopeContext, params map[string]interface{}) ([]memory.Scope, error) {
+	kind := memoryScopeParam(params)
+	if kind == "" {
+		return sc.Scopes(), nil
+	}
+	scope, err := sc.Resolve(kind)
+	if err != nil {
+		return nil, err
+	}
+	return []memory.Scope{scope}, nil
+}
+
+func memoryScopeParam(params map[string]interface{}) memory.ScopeKind {
+	raw, _ := params["scope"].(string)
+	return memory.ScopeKind(strings.ToLower(strings.TrimSpace(raw)))
+}
+
+func (t *MemoryTool) add(ctx context.Context, workspace string, params map[string]interface{}) (string, error) {
+	rawType, _ := params["type"].(string)
+	if rawType == "" {
+		return "", fmt.Errorf("type is required for add")
+	}
+	typ, err := memory.ParseEntryType(rawType)
+	if err != nil {
+		return "", err
+	}
+	sc := MemoryScopeFrom(ctx)
+	scope := sc.DefaultEntryScope(typ)
+	if kind := memoryScopeParam(params); kind != "" {
+		if scope, err = sc.Resolve(kind); err != nil {
+			return "", err
+		}
+	}
+	store := memory.NewScopedEntryStore(workspace, scope)
+	content, _ := params["content"].(string)
+	channel, chatID := RuntimeContextFrom(ctx)
+	entry := memory.Entry{
+		Type:    typ,
+		Content: content,
+		Tags:    stringSliceParam(params["tags"]),
+		Source: memory.EntrySource{
+			Origin:  memory.SourceAgent,
+			Channel: channel,
+			ChatID:  chatID,
+			Session: RuntimeSessionKeyFrom(ctx),
+			Sender:  RuntimeSenderFrom(ctx),
+			Message: truncateMemorySource(RuntimeMessageFrom(ctx)),
+		},
+	}
+	if v, ok := params["confidence"].(float64); ok {
+		entry.Confidence = v
+	}
+	if raw, _ := params["expires"].(string); strings.TrimSpace(raw) != "" {
+		expires, err := memory.ParseExpiry(raw, time.Now())
+		if err != nil {
+			return "", err
+		}
+		entry.ExpiresAt = memory.ExpiryPtr(expires)
+	}
+
+	saved, created, err := store.Add(entry)
+	if err != nil {
+		return "", err
+	}
+	if !created {
+		return fmt.Sprintf("Already remembered as %s %s in %s; refreshed it.", saved.Type, saved.ID, scope), nil
+	}
+	return fmt.Sprintf("Remembered %s %s in %s: %s", saved.Type, saved.ID, scope, saved.Content), nil
+}
+
+func (t *MemoryTool) update(store *memory.EntryStore, params map[string]interface{}) (string, error) {
+	id, _ := params["id"].(string)
+	var patch memory.EntryPatch
+	if raw, _ := params["type"].(string); raw != "" {
+		typ, err := memory.ParseEntryType(raw)
+		if err != nil {
+			return "", err
+		}
+		patch.Type = &typ
+	}
+	if content, ok := params["content"].(string); ok && content != "" {
+		patch.Content = &content
+	}
+	if _, ok := params["tags"]; ok {
+		tags := stringSliceParam(params["tags"])
+		patch.Tags = &tags
+	}
+	if v, ok := params["confidence"].(float64); ok {
+		patch.Confidence = &v
+	}
+	if v, ok := params["done"].(bool); ok {
+		patch.Done = &v
+	}
+	if raw, _ := params["expires"].(string); strings.TrimSpace(raw) != "" {
+		expires, err := memory.ParseExpiry(raw, time.Now())
+		if err != nil {
+			return "", err
+		}
+		patch.ExpiresAt = &expires
+	}
+
+	entry, err := store.Update(id, patch)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Updated %s %s in %s: %s", entry.Type, entry.ID, store.Scope(), entry.Content), nil
+}
+
+func (t *MemoryTool) list(workspace string, scopes []memory.Scope, params map[string]interface{}) (string, error) {
+	filter := memory.EntryFilter{}
+	if raw, _ := params["type"].(string); raw != "" {
+		typ, err := memory.ParseEntryType(raw)
+		if err != nil {
+			return "", err
+		}
+		filter.Type = typ
+	}
+	if tags := stringSliceParam(params["tags"]); len(tags) > 0 {
+		filter.Tag = tags[0]
+	}
+	filter.Query, _ = params["query"].(string)
+
+	entries, err := memory.ListScopedEntries(workspace, scopes, filter)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "No memory entries.", nil
+	}
+	var b strings.Builder
+	for i, e := range entries {
+		if i == 0 || entries[i-1].Scope != e.Scope {
+			fmt.Fprintf(&b, "[%s]\n", e.Scope)
+		}
+		b.WriteString(memory.FormatEntry(e.Entry))
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func truncateMemorySource(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > memorySourceMessageLimit {
+		return string(r[:memorySourceMessageLimit]) + "..."
+	}
+	return s
+}
+
+func stringSliceParam(v interface{}) []string {
+	switch val := v.(type) {
+	case []string:
+		return val
+	case []interface{}:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	case string:
+		if strings.TrimSpace(val) == "" {
+			return nil
+		}
+		return strings.Split(val, ",")
+	}
+	return nil
+}
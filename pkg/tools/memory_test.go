@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryToolManagesEntriesWithProvenance(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewMemoryTool(t.TempDir())
+
+	ctx := WithRuntimeContextWithSession(context.Background(), "telegram", "42", "telegram:42")
+	ctx = WithRuntimeSender(ctx, "alice")
+	ctx = WithRuntimeMessage(ctx, "remember that   we deploy on Fridays")
+	ctx = WithRuntimeWorkspace(ctx, workspace, false)
+
+	result, err := tool.Execute(ctx, map[string]interface{}{
+		"action":     "add",
+		"type":       "fact",
+		"content":    "Deploys happen on Fridays",
+		"tags":       []interface{}{"ops"},
+		"confidence": 0.8,
+		"expires":    "30d",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result, "Remembered fact ")
+
+	entries, err := memory.NewEntryStore(workspace).List(memory.EntryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	entry := entries[0]
+	assert.Equal(t, memory.EntrySource{
+		Origin:  memory.SourceAgent,
+		Channel: "telegram",
+		ChatID:  "42",
+		Session: "telegram:42",
+		Sender:  "alice",
+		Message: "remember that we deploy on Fridays",
+	}, entry.Source)
+	assert.Equal(t, 0.8, entry.Confidence)
+	require.NotNil(t, entry.ExpiresAt)
+
+	memoryFile, err := os.ReadFile(filepath.Join(workspace, "memory", "MEMORY.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(memoryFile), "- Deploys happen on Fridays <!-- id:"+entry.ID+" -->")
+
+	result, err = tool.Execute(ctx, map[string]interface{}{"action": "update", "id": entry.ID, "content": "Deploys happen on Thursdays", "expires": "never"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "Deploys happen on Thursdays")
+
+	result, err = tool.Execute(ctx, map[string]interface{}{"action": "list", "tags": []interface{}{"ops"}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(result, "[global]\n"+entry.ID+"  fact"), result)
+	assert.Contains(t, result, "#ops")
+	assert.NotContains(t, result, "expires")
+	assert.Contains(t, result, "from agent telegram:42")
+
+	_, err = tool.Execute(ctx, map[string]interface{}{"action": "remove", "id": entry.ID})
+	require.NoError(t, err)
+	result, err = tool.Execute(ctx, map[string]interface{}{"action": "list"})
+	require.NoError(t, err)
+	assert.Equal(t, "No memory entries.", result)
+}
+
+func TestMemoryToolRejectsInvalidInput(t *testing.T) {
+	tool := NewMemoryTool(t.TempDir())
+	ctx := context.Background()
+
+	_, err := tool.Execute(ctx, map[string]interface{}{"action": "add", "content": "x"})
+	assert.EqualError(t, err, "type is required for add")
+	_, err = tool.Execute(ctx, map[string]interface{}{"action": "add", "type": "fact", "content": "x", "expires": "soon"})
+	assert.Error(t, err)
+	_, err = tool.Execute(ctx, map[string]interface{}{"action": "remove", "id": "missing"})
+	assert.ErrorIs(t, err, memory.ErrEntryNotFound)
+	_, err = tool.Execute(ctx, map[string]interface{}{"action": "forget"})
+	assert.EqualError(t, err, "unknown action: forget")
+}
+
+func TestMemoryToolKeepsPersonalEntriesInTheirScope(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewMemoryTool(workspace)
+	ids := memory.Identities{"alice": {"telegram:1001", "slack:U1"}}
+
+	dm := WithMemoryScope(context.Background(), memory.NewScopeContext("", "telegram", "1001", "1001", ids))
+	result, err := tool.Execute(dm, map[string]interface{}{"action": "add", "type": "preference", "content": "Answers in Chinese"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "in user:alice")
+	_, err = tool.Execute(dm, map[string]interface{}{"action": "add", "type": "fact", "content": "Chat is about the Q4 launch", "scope": "chat"})
+	require.NoError(t, err)
+	_, err = tool.Execute(dm, map[string]interface{}{"action": "add", "type": "fact", "content": "x", "scope": "agent"})
+	assert.EqualError(t, err, "agent memory scope needs a named agent profile")
+
+	userMemory, err := os.ReadFile(filepath.Join(workspace, "memory", "users", "alice", "MEMORY.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(userMemory), "- Answers in Chinese")
+	_, err = os.Stat(filepath.Join(workspace, "memory", "MEMORY.md"))
+	assert.True(t, os.IsNotExist(err), "personal entries stay out of the global memory")
+
+	// 同一用户在其他频道仍能看到自己的偏好，但看不到 DM 的会话记忆
+	group := WithMemoryScope(context.Background(), memory.NewScopeContext("", "slack", "C42", "U1", ids))
+	result, err = tool.Execute(group, map[string]interface{}{"action": "list"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "[user:alice]")
+	assert.Contains(t, result, "Answers in Chinese")
+	assert.NotContains(t, result, "Q4 launch")
+
+	colleague := WithMemoryScope(context.Background(), memory.NewScopeContext("", "slack", "C42", "U2", ids))
+	result, err = tool.Execute(colleague, map[string]interface{}{"action": "list"})
+	require.NoError(t, err)
+	assert.Equal(t, "No memory entries.", result)
+}
@@ -0,0 +1,385 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const maxPatchResultDiffBytes = 20 * 1024
+
+// ApplyPatchTool 原子地应用 unified diff 或多处查找替换
+type ApplyPatchTool struct {
+	BaseTool
+}
+
+// NewApplyPatchTool 创建补丁工具
+func NewApplyPatchTool() *ApplyPatchTool {
+	return &ApplyPatchTool{
+		BaseTool: BaseTool{
+			name: "apply_patch",
+			description: "Apply several edits atomically and return a unified diff of the result. " +
+				"Provide either `patch` (a unified diff, may touch multiple files, supports creating files from /dev/null and deleting to /dev/null) " +
+				"or `edits` (search/replace blocks). Every old_string must match exactly once unless replace_all is true; " +
+				"if any edit fails nothing is written.",
+			parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"patch": map[string]interface{}{
+						"type":        "string",
+						"description": "Unified diff with ---/+++ headers and @@ hunks. Paths are relative to the session directory.",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Default file for edits that omit path",
+					},
+					"edits": map[string]interface{}{
+						"type":        "array",
+						"description": "Search/replace blocks applied in order",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"path":        map[string]interface{}{"type": "string"},
+								"old_string":  map[string]interface{}{"type": "string"},
+								"new_string":  map[string]interface{}{"type": "string"},
+								"replace_all": map[string]interface{}{"type": "boolean"},
+							},
+							"required": []string{"old_string", "new_string"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// pendingFile 尚未落盘的文件修改；existed 为磁盘上的原始状态，exists 为本次调用暂存后的状态
+type pendingFile struct {
+	display  string
+	resolved string
+	original string
+	content  string
+	existed  bool
+	exists   bool
+	deleted  bool
+}
+
+// patchTargets 返回 apply_patch 调用将写入或删除的文件路径，供工具策略逐个检查 path 条件
+func patchTargets(args map[string]interface{}) []string {
+	var targets []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		path = strings.TrimSpace(path)
+		if path != "" && !seen[path] {
+			seen[path] = true
+			targets = append(targets, path)
+		}
+	}
+
+	if patch, _ := args["patch"].(string); strings.TrimSpace(patch) != "" {
+		filePatches, err := parseUnifiedDiff(patch)
+		if err != nil {
+			return nil
+		}
+		for _, fp := range filePatches {
+			add(fp.targetPath())
+		}
+		return targets
+	}
+	defaultPath, _ := args["path"].(string)
+	rawEdits, _ := args["edits"].([]interface{})
+	for _, raw := range rawEdits {
+		edit, _ := raw.(map[string]interface{})
+		path, _ := edit["path"].(string)
+		if strings.TrimSpace(path) == "" {
+			path = defaultPath
+		}
+		add(path)
+	}
+	return targets
+}
+
+// Execute 应用补丁
+func (t *ApplyPatchTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	patch, _ := params["patch"].(string)
+	rawEdits, _ := params["edits"].([]interface{})
+	if strings.TrimSpace(patch) == "" && len(rawEdits) == 0 {
+		return "", fmt.Errorf("either patch or edits is required")
+	}
+	if strings.TrimSpace(patch) != "" && len(rawEdits) > 0 {
+		return "", fmt.Errorf("provide patch or edits, not both")
+	}
+
+	files := newPendingFiles()
+	var err error
+	if strings.TrimSpace(patch) != "" {
+		err = t.stagePatch(ctx, files, patch)
+	} else {
+		defaultPath, _ := params["path"].(string)
+		err = t.stageEdits(ctx, files, defaultPath, rawEdits)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := files.commit(); err != nil {
+		return "", err
+	}
+
+	var diffs strings.Builder
+	changed := 0
+	for _, f := range files.ordered {
+		newContent := f.content
+		if f.deleted {
+			newContent = ""
+		}
+		diff := recordFileChange(ctx, t.name, f.display, f.original, newContent)
+		if diff == "" {
+			continue
+		}
+		changed++
+		if !f.deleted {
+			markFileRead(ctx, f.resolved)
+		}
+		diffs.WriteString(diff)
+	}
+
+	result := fmt.Sprintf("Patch applied successfully: %d file(s) changed", changed)
+	if diffs.Len() > 0 {
+		result += "\n\n" + capOutput(diffs.String(), maxPatchResultDiffBytes)
+	}
+	return result, nil
+}
+
+func (t *ApplyPatchTool) stagePatch(ctx context.Context, files *pendingFiles, patch string) error {
+	filePatches, err := parseUnifiedDiff(patch)
+	if err != nil {
+		return err
+	}
+	for _, fp := range filePatches {
+		f, err := files.load(ctx, fp.targetPath())
+		if err != nil {
+			return err
+		}
+		switch {
+		case fp.isCreate() && f.exists:
+			return fmt.Errorf("%s already exists; patch expects to create it", fp.targetPath())
+		case !fp.isCreate() && !f.exists:
+			return fmt.Errorf("%s does not exist", fp.targetPath())
+		}
+		if !fp.isCreate() && f.existed {
+			if err := ensureFileRead(ctx, f.resolved); err != nil {
+				return err
+			}
+		}
+
+		base := f.content
+		if fp.isCreate() {
+			base = ""
+		}
+		updated, err := applyFilePatch(base, fp)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fp.targetPath(), err)
+		}
+		f.content = updated
+		f.deleted = fp.isDelete()
+		f.exists = !f.deleted
+		if f.deleted && strings.TrimSpace(updated) != "" {
+			return fmt.Errorf("%s: delete patch leaves content behind", fp.targetPath())
+		}
+	}
+	return nil
+}
+
+func (t *ApplyPatchTool) stageEdits(ctx context.Context, files *pendingFiles, defaultPath string, rawEdits []interface{}) error {
+	for i, raw := range rawEdits {
+		edit, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("edits[%d] must be an object", i)
+		}
+		path, _ := edit["path"].(string)
+		if strings.TrimSpace(path) == "" {
+			path = defaultPath
+		}
+		if strings.TrimSpace(path) == "" {
+			return fmt.Errorf("edits[%d]: path is required", i)
+		}
+		oldString, _ := edit["old_string"].(string)
+		newString, _ := edit["new_string"].(string)
+		replaceAll, _ := edit["replace_all"].(bool)
+
+		f, err := files.load(ctx, path)
+		if err != nil {
+			return err
+		}
+		if !f.exists {
+			if oldString != "" {
+				return fmt.Errorf("edits[%d]: %s does not exist", i, path)
+			}
+			// 空 old_string 作用于不存在的文件表示新建
+			f.content = newString
+			f.exists = true
+			continue
+		}
+		if f.existed {
+			if err := ensureFileRead(ctx, f.resolved); err != nil {
+				return err
+			}
+		}
+		updated, err := replaceExact(f.content, oldString, newString, replaceAll)
+		if err != nil {
+			return fmt.Errorf("edits[%d] (%s): %w", i, path, err)
+		}
+		f.content = updated
+	}
+	return nil
+}
+
+// replaceExact 替换 old；出现多次且未指定 replaceAll 时报告歧义及所在行号
+func replaceExact(content, old, replacement string, replaceAll bool) (string, error) {
+	if old == "" {
+		return "", fmt.Errorf("old_string must not be empty")
+	}
+	count := strings.Count(content, old)
+	switch {
+	case count == 0:
+		hint := ""
+		if strings.Contains(normalizeWhitespace(content), normalizeWhitespace(old)) {
+			hint = " (a match exists with different whitespace/indentation)"
+		}
+		return "", fmt.Errorf("old_string not found in file%s", hint)
+	case count > 1 && !replaceAll:
+		return "", fmt.Errorf("old_string is ambiguous: %d matches at lines %s; add surrounding context or set replace_all",
+			count, strings.Join(matchLines(content, old, 10), ", "))
+	}
+	if replaceAll {
+		return strings.ReplaceAll(content, old, replacement), nil
+	}
+	return strings.Replace(content, old, replacement, 1), nil
+}
+
+func matchLines(content, needle string, limit int) []string {
+	var lines []string
+	offset := 0
+	for len(lines) < limit {
+		idx := strings.Index(content[offset:], needle)
+		if idx < 0 {
+			break
+		}
+		pos := offset + idx
+		lines = append(lines, fmt.Sprintf("%d", strings.Count(content[:pos], "\n")+1))
+		offset = pos + len(needle)
+	}
+	return lines
+}
+
+func normalizeWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+type pendingFiles struct {
+	byPath  map[string]*pendingFile
+	ordered []*pendingFile
+}
+
+func newPendingFiles() *pendingFiles {
+	return &pendingFiles{byPath: make(map[string]*pendingFile)}
+}
+
+func (p *pendingFiles) load(ctx context.Context, path string) (*pendingFile, error) {
+	resolved, err := resolvePath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := p.byPath[resolved]; ok {
+		return f, nil
+	}
+
+	f := &pendingFile{display: filepath.ToSlash(strings.TrimSpace(path)), resolved: resolved}
+	data, err := os.ReadFile(resolved)
+	switch {
+	case err == nil:
+		f.existed = true
+		f.exists = true
+		f.original = string(data)
+		f.content = f.original
+	case os.IsNotExist(err):
+	default:
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	p.byPath[resolved] = f
+	p.ordered = append(p.ordered, f)
+	return f, nil
+}
+
+// commit 写入所有修改；任一文件失败时回滚已写入的文件
+func (p *pendingFiles) commit() error {
+	var written []*pendingFile
+	rollback := func() {
+		for _, f := range written {
+			if f.existed {
+				_ = os.WriteFile(f.resolved, []byte(f.original), 0644)
+			} else {
+				_ = os.Remove(f.resolved)
+			}
+		}
+	}
+
+	for _, f := range p.ordered {
+		if !f.deleted && f.existed && f.content == f.original {
+			continue
+		}
+		if f.deleted && !f.existed {
+			// 本次调用中新建又删除，磁盘上无需改动
+			continue
+		}
+		var err error
+		if f.deleted {
+			err = os.Remove(f.resolved)
+		} else {
+			err = writeFileAtomic(f.resolved, []byte(f.content))
+		}
+		if err != nil {
+			rollback()
+			return fmt.Errorf("failed to write %s: %w", f.display, err)
+		}
+		written = append(written, f)
+	}
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, mode); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPatchToolUnifiedDiff(t *testing.T) {
+	dir := t.TempDir()
+	SetAllowedDir(dir)
+	t.Cleanup(func() { SetAllowedDir("") })
+
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	require.NoError(t, os.WriteFile(a, []byte("one\ntwo\nthree\n"), 0644))
+	require.NoError(t, os.WriteFile(b, []byte("remove me\n"), 0644))
+
+	patch := "--- a/" + a + "\n+++ b/" + a + "\n@@ -1,3 +1,3 @@\n one\n-two\n+TWO\n three\n" +
+		"--- " + b + "\n+++ /dev/null\n@@ -1 +0,0 @@\n-remove me\n" +
+		"--- /dev/null\n+++ " + filepath.Join(dir, "c.txt") + "\n@@ -0,0 +1 @@\n+created\n"
+
+	var changes []FileChange
+	ctx := WithFileChangeRecorder(context.Background(), func(c FileChange) { changes = append(changes, c) })
+
+	result, err := NewApplyPatchTool().Execute(ctx, map[string]interface{}{"patch": patch})
+	require.NoError(t, err)
+	assert.Contains(t, result, "3 file(s) changed")
+	assert.Contains(t, result, "+TWO")
+
+	body, err := os.ReadFile(a)
+	require.NoError(t, err)
+	assert.Equal(t, "one\nTWO\nthree\n", string(body))
+	_, err = os.Stat(b)
+	assert.True(t, os.IsNotExist(err))
+	body, err = os.ReadFile(filepath.Join(dir, "c.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "created\n", string(body))
+
+	require.Len(t, changes, 3)
+	assert.Equal(t, 1, changes[0].Added)
+	assert.True(t, changes[1].Deleted)
+	assert.True(t, changes[2].Created)
+}
+
+func TestApplyPatchToolIsAtomic(t *testing.T) {
+	dir := t.TempDir()
+	SetAllowedDir(dir)
+	t.Cleanup(func() { SetAllowedDir("") })
+
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	require.NoError(t, os.WriteFile(a, []byte("alpha\n"), 0644))
+	require.NoError(t, os.WriteFile(b, []byte("beta\n"), 0644))
+
+	_, err := NewApplyPatchTool().Execute(context.Background(), map[string]interface{}{
+		"edits": []interface{}{
+			map[string]interface{}{"path": a, "old_string": "alpha", "new_string": "ALPHA"},
+			map[string]interface{}{"path": b, "old_string": "missing", "new_string": "x"},
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "edits[1]")
+
+	body, _ := os.ReadFile(a)
+	assert.Equal(t, "alpha\n", string(body), "first edit must not be written when a later one fails")
+}
+
+func TestApplyPatchToolEditsAmbiguity(t *testing.T) {
+	dir := t.TempDir()
+	SetAllowedDir(dir)
+	t.Cleanup(func() { SetAllowedDir("") })
+
+	path := filepath.Join(dir, "dup.txt")
+	require.NoError(t, os.WriteFile(path, []byte("x = 1\ny = 2\nx = 1\n"), 0644))
+	tool := NewApplyPatchTool()
+
+	_, err := tool.Execute(context.Background(), map[string]interface{}{
+		"path":  path,
+		"edits": []interface{}{map[string]interface{}{"old_string": "x = 1", "new_string": "x = 9"}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ambiguous")
+	assert.Contains(t, err.Error(), "lines 1, 3")
+
+	_, err = tool.Execute(context.Background(), map[string]interface{}{
+		"path":  path,
+		"edits": []interface{}{map[string]interface{}{"old_string": "x = 1", "new_string": "x = 9", "replace_all": true}},
+	})
+	require.NoError(t, err)
+	body, _ := os.ReadFile(path)
+	assert.Equal(t, "x = 9\ny = 2\nx = 9\n", string(body))
+}
+
+func TestApplyPatchToolEditsFileCreatedInSameCall(t *testing.T) {
+	dir := t.TempDir()
+	SetAllowedDir(dir)
+	t.Cleanup(func() { SetAllowedDir("") })
+	tool := NewApplyPatchTool()
+
+	notes := filepath.Join(dir, "notes.txt")
+	_, err := tool.Execute(context.Background(), map[string]interface{}{
+		"edits": []interface{}{
+			map[string]interface{}{"path": notes, "old_string": "", "new_string": "draft\n"},
+			map[string]interface{}{"path": notes, "old_string": "draft", "new_string": "final"},
+		},
+	})
+	require.NoError(t, err)
+	body, _ := os.ReadFile(notes)
+	assert.Equal(t, "final\n", string(body))
+
+	todo := filepath.Join(dir, "todo.txt")
+	patch := "--- /dev/null\n+++ " + todo + "\n@@ -0,0 +1 @@\n+one\n" +
+		"--- " + todo + "\n+++ " + todo + "\n@@ -1 +1,2 @@\n one\n+two\n"
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"patch": patch})
+	require.NoError(t, err)
+	body, _ = os.ReadFile(todo)
+	assert.Equal(t, "one\ntwo\n", string(body))
+
+	// 新建后又删除的文件不落盘
+	tmp := filepath.Join(dir, "tmp.txt")
+	patch = "--- /dev/null\n+++ " + tmp + "\n@@ -0,0 +1 @@\n+x\n" +
+		"--- " + tmp + "\n+++ /dev/null\n@@ -1 +0,0 @@\n-x\n"
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"patch": patch})
+	require.NoError(t, err)
+	_, err = os.Stat(tmp)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestApplyPatchToolRejectsBadInput(t *testing.T) {
+	tool := NewApplyPatchTool()
+	_, err := tool.Execute(context.Background(), map[string]interface{}{})
+	assert.Error(t, err)
+
+	_, err = tool.Execute(context.Background(), map[string]interface{}{
+		"patch": "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b\n",
+		"edits": []interface{}{map[string]interface{}{"old_string": "a", "new_string": "b"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestFileReadTrackerRequiresFreshRead(t *testing.T) {
+	dir := t.TempDir()
+	SetAllowedDir(dir)
+	t.Cleanup(func() { SetAllowedDir("") })
+
+	path := filepath.Join(dir, "tracked.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello world\n"), 0644))
+
+	tracker := NewFileReadTracker(true)
+	ctx := WithFileReadTracker(WithRuntimeContextWithSession(context.Background(), "cli", "direct", "cli:direct"), tracker)
+	edit := NewEditFileTool()
+	params := map[string]interface{}{"path": path, "old_string": "world", "new_string": "there"}
+
+	_, err := edit.Execute(ctx, params)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has not been read")
+
+	_, err = NewReadFileTool().Execute(ctx, map[string]interface{}{"path": path})
+	require.NoError(t, err)
+	result, err := edit.Execute(ctx, params)
+	require.NoError(t, err)
+	assert.Contains(t, result, "+hello there")
+
+	// 外部修改后需要重新读取
+	require.NoError(t, os.WriteFile(path, []byte("hello there, changed outside\n"), 0644))
+	_, err = edit.Execute(ctx, map[string]interface{}{"path": path, "old_string": "there", "new_string": "again"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "changed since it was last read")
+
+	// Forget 清除会话记录后需要重新读取
+	tracker.Forget("cli:direct")
+	_, err = edit.Execute(ctx, map[string]interface{}{"path": path, "old_string": "there", "new_string": "again"})
+	assert.Contains(t, err.Error(), "has not been read")
+}
@@ -0,0 +1,374 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	PolicyEffectAllow = "allow"
+	PolicyEffectDeny  = "deny"
+)
+
+// ToolPolicyOptions 声明式工具策略（规则按顺序匹配，第一条命中的规则生效）
+type ToolPolicyOptions struct {
+	Default string
+	Rules   []ToolPolicyRuleOptions
+}
+
+// ToolPolicyRuleOptions 单条策略规则，空的匹配字段表示任意
+type ToolPolicyRuleOptions struct {
+	Name     string
+	Effect   string
+	Tools    []string
+	Agents   []string
+	Channels []string
+	Senders  []string
+	Args     map[string]ToolArgCondition
+	Reason   string
+}
+
+// ToolArgCondition 参数级条件；同一条件内的多个约束须同时满足，Negate 对结果取反
+type ToolArgCondition struct {
+	Pattern      string
+	PathPrefixes []string
+	Domains      []string
+	Negate       bool
+}
+
+// PolicyRequest 一次待评估的工具调用
+type PolicyRequest struct {
+	Tool      string
+	Agent     string
+	Channel   string
+	ChatID    string
+	Sender    string
+	Workspace string
+	Args      map[string]interface{}
+}
+
+// PolicyDecision 策略评估结果
+type PolicyDecision struct {
+	Allowed bool   `json:"allowed"`
+	Rule    string `json:"rule,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// PolicyDeniedError 返回给模型的结构化拒绝错误
+type PolicyDeniedError struct {
+	Tool     string
+	Decision PolicyDecision
+}
+
+func (e *PolicyDeniedError) Error() string {
+	body, _ := json.Marshal(map[string]interface{}{
+		"error":  "tool_policy_denied",
+		"tool":   e.Tool,
+		"rule":   e.Decision.Rule,
+		"reason": e.Decision.Reason,
+	})
+	return string(body)
+}
+
+// ToolPolicy 编译后的工具策略
+type ToolPolicy struct {
+	defaultAllow bool
+	rules        []compiledPolicyRule
+}
+
+type compiledPolicyRule struct {
+	name     string
+	allow    bool
+	tools    []string
+	agents   []string
+	channels []string
+	senders  []string
+	args     map[string]compiledArgCondition
+	reason   string
+}
+
+type compiledArgCondition struct {
+	pattern      *regexp.Regexp
+	pathPrefixes []string
+	domains      []string
+	negate       bool
+}
+
+// NewToolPolicy 编译策略，正则或 effect 无效时返回错误
+func NewToolPolicy(opts ToolPolicyOptions) (*ToolPolicy, error) {
+	p := &ToolPolicy{defaultAllow: true}
+	switch strings.ToLower(strings.TrimSpace(opts.Default)) {
+	case "", PolicyEffectAllow:
+	case PolicyEffectDeny:
+		p.defaultAllow = false
+	default:
+		return nil, fmt.Errorf("invalid policy default %q (want allow or deny)", opts.Default)
+	}
+
+	for i, rule := range opts.Rules {
+		name := strings.TrimSpace(rule.Name)
+		if name == "" {
+			name = fmt.Sprintf("rule#%d", i+1)
+		}
+		compiled := compiledPolicyRule{
+			name:     name,
+			tools:    trimPatterns(rule.Tools),
+			agents:   trimPatterns(rule.Agents),
+			channels: trimPatterns(rule.Channels),
+			senders:  trimPatterns(rule.Senders),
+			reason:   strings.TrimSpace(rule.Reason),
+		}
+		switch strings.ToLower(strings.TrimSpace(rule.Effect)) {
+		case PolicyEffectAllow:
+			compiled.allow = true
+		case PolicyEffectDeny, "":
+			compiled.allow = false
+		default:
+			return nil, fmt.Errorf("policy %s: invalid effect %q", name, rule.Effect)
+		}
+
+		if len(rule.Args) > 0 {
+			compiled.args = make(map[string]compiledArgCondition, len(rule.Args))
+			for arg, cond := range rule.Args {
+				cc := compiledArgCondition{
+					pathPrefixes: trimPatterns(cond.PathPrefixes),
+					domains:      normalizeDomains(cond.Domains),
+					negate:       cond.Negate,
+				}
+				if strings.TrimSpace(cond.Pattern) != "" {
+					re, err := regexp.Compile(cond.Pattern)
+					if err != nil {
+						return nil, fmt.Errorf("policy %s: invalid pattern for %s: %w", name, arg, err)
+					}
+					cc.pattern = re
+				}
+				compiled.args[arg] = cc
+			}
+		}
+		p.rules = append(p.rules, compiled)
+	}
+	return p, nil
+}
+
+// Evaluate 返回工具调用的策略决定。nil 策略总是允许。
+// 参数中携带命令或路径的工具（process、apply_patch）按其等效调用逐一评估，任一被拒绝即拒绝。
+func (p *ToolPolicy) Evaluate(req PolicyRequest) PolicyDecision {
+	if p == nil {
+		return PolicyDecision{Allowed: true}
+	}
+	var decision PolicyDecision
+	for i, sub := range policySubRequests(req) {
+		d := p.evaluate(sub)
+		if !d.Allowed {
+			return d
+		}
+		if i == 0 {
+			decision = d
+		}
+	}
+	return decision
+}
+
+// policySubRequests 展开需要评估的请求，全部允许时返回第一个请求的决定
+func policySubRequests(req PolicyRequest) []PolicyRequest {
+	requests := []PolicyRequest{req}
+	switch req.Tool {
+	case "process":
+		// process 的 start/shell/write 实际执行命令，须同样满足 exec 规则
+		if command := processPolicyCommand(req.Args); command != "" {
+			exec := req
+			exec.Tool = "exec"
+			exec.Args = map[string]interface{}{"command": command}
+			requests = append(requests, exec)
+		}
+	case "apply_patch":
+		// 目标路径在 patch / edits 中，按每个目标文件分别以 path 参数评估
+		if targets := patchTargets(req.Args); len(targets) > 0 {
+			requests = requests[:0]
+			for _, target := range targets {
+				sub := req
+				sub.Args = make(map[string]interface{}, len(req.Args)+1)
+				for k, v := range req.Args {
+					sub.Args[k] = v
+				}
+				sub.Args["path"] = target
+				requests = append(requests, sub)
+			}
+		}
+	}
+	return requests
+}
+
+func (p *ToolPolicy) evaluate(req PolicyRequest) PolicyDecision {
+	for _, rule := range p.rules {
+		if !rule.matches(req) {
+			continue
+		}
+		decision := PolicyDecision{Allowed: rule.allow, Rule: rule.name, Reason: rule.reason}
+		if decision.Reason == "" && !rule.allow {
+			decision.Reason = fmt.Sprintf("tool %s denied by policy rule %s", req.Tool, rule.name)
+		}
+		return decision
+	}
+	if p.defaultAllow {
+		return PolicyDecision{Allowed: true, Rule: "default"}
+	}
+	return PolicyDecision{
+		Allowed: false,
+		Rule:    "default",
+		Reason:  fmt.Sprintf("tool %s is not allowed by any policy rule", req.Tool),
+	}
+}
+
+// RuleCount 返回规则数量
+func (p *ToolPolicy) RuleCount() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.rules)
+}
+
+func (r compiledPolicyRule) matches(req PolicyRequest) bool {
+	if !matchAnyPattern(r.tools, req.Tool) ||
+		!matchAnyPattern(r.agents, req.Agent) ||
+		!matchAnyPattern(r.channels, req.Channel) ||
+		!matchAnyPattern(r.senders, req.Sender) {
+		return false
+	}
+	for arg, cond := range r.args {
+		value := policyArgString(req.Args[arg])
+		if !cond.matches(value, req.Workspace) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c compiledArgCondition) matches(value, workspace string) bool {
+	ok := true
+	if c.pattern != nil && !c.pattern.MatchString(value) {
+		ok = false
+	}
+	if ok && len(c.pathPrefixes) > 0 && !pathUnderAny(value, workspace, c.pathPrefixes) {
+		ok = false
+	}
+	if ok && len(c.domains) > 0 && !hostMatchesAny(value, c.domains) {
+		ok = false
+	}
+	if c.negate {
+		return !ok
+	}
+	return ok
+}
+
+// MatchesToolPatterns reports whether name matches one of the tool name patterns
+// (exact, case-insensitive, or path-style globs such as "mcp_*"). An empty list matches everything.
+func MatchesToolPatterns(patterns []string, name string) bool {
+	return matchAnyPattern(patterns, name)
+}
+
+func matchAnyPattern(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if p == "*" || strings.EqualFold(p, value) {
+			return true
+		}
+		if ok, err := path.Match(p, value); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func pathUnderAny(value, workspace string, prefixes []string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false
+	}
+	target := value
+	if !filepath.IsAbs(target) && workspace != "" {
+		target = filepath.Join(workspace, target)
+	}
+	target = resolveSymlinks(filepath.Clean(target))
+	for _, prefix := range prefixes {
+		base := prefix
+		if !filepath.IsAbs(base) && workspace != "" {
+			base = filepath.Join(workspace, base)
+		}
+		base = resolveSymlinks(filepath.Clean(base))
+		if isWithin(base, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSymlinks 解析路径中的符号链接；路径尚不存在时解析最近的已存在上级目录，再接上其余部分
+func resolveSymlinks(path string) string {
+	rest := ""
+	dir := path
+	for {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+		dir = parent
+	}
+}
+
+func hostMatchesAny(raw string, domains []string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	return hostInDomains(u.Hostname(), domains)
+}
+
+func policyArgString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		body, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(body)
+	}
+}
+
+func trimPatterns(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func normalizeDomains(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		v = strings.TrimPrefix(v, "*.")
+		v = strings.TrimPrefix(v, ".")
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
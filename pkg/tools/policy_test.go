@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolPolicyFirstMatchWins(t *testing.T) {
+	policy, err := NewToolPolicy(ToolPolicyOptions{
+		Rules: []ToolPolicyRuleOptions{
+			{Name: "git-only", Effect: "allow", Tools: []string{"exec"}, Args: map[string]ToolArgCondition{
+				"command": {Pattern: `^git\s`},
+			}},
+			{Name: "no-exec", Effect: "deny", Tools: []string{"exec"}, Reason: "only git is allowed"},
+		},
+	})
+	require.NoError(t, err)
+
+	allowed := policy.Evaluate(PolicyRequest{Tool: "exec", Args: map[string]interface{}{"command": "git status"}})
+	assert.True(t, allowed.Allowed)
+	assert.Equal(t, "git-only", allowed.Rule)
+
+	denied := policy.Evaluate(PolicyRequest{Tool: "exec", Args: map[string]interface{}{"command": "curl http://x"}})
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, "no-exec", denied.Rule)
+	assert.Equal(t, "only git is allowed", denied.Reason)
+
+	other := policy.Evaluate(PolicyRequest{Tool: "read_file"})
+	assert.True(t, other.Allowed)
+	assert.Equal(t, "default", other.Rule)
+}
+
+func TestToolPolicyExecRulesCoverProcessCommands(t *testing.T) {
+	policy, err := NewToolPolicy(ToolPolicyOptions{
+		Rules: []ToolPolicyRuleOptions{
+			{Name: "no-curl", Effect: "deny", Tools: []string{"exec"}, Args: map[string]ToolArgCondition{
+				"command": {Pattern: `\bcurl\b`},
+			}},
+		},
+	})
+	require.NoError(t, err)
+
+	shell := policy.Evaluate(PolicyRequest{Tool: "process", Args: map[string]interface{}{"action": "shell", "command": "curl http://169.254.169.254/"}})
+	assert.False(t, shell.Allowed)
+	assert.Equal(t, "no-curl", shell.Rule)
+
+	assert.False(t, policy.Evaluate(PolicyRequest{Tool: "process", Args: map[string]interface{}{"action": "start", "command": "curl -O http://x"}}).Allowed)
+	assert.False(t, policy.Evaluate(PolicyRequest{Tool: "process", Args: map[string]interface{}{"action": "write", "id": "sh_1", "input": "curl http://x\n"}}).Allowed)
+	assert.True(t, policy.Evaluate(PolicyRequest{Tool: "process", Args: map[string]interface{}{"action": "shell", "command": "git status"}}).Allowed)
+	assert.True(t, policy.Evaluate(PolicyRequest{Tool: "process", Args: map[string]interface{}{"action": "list"}}).Allowed)
+}
+
+func TestToolPolicyPathPrefixNegate(t *testing.T) {
+	workspace := t.TempDir()
+	policy, err := NewToolPolicy(ToolPolicyOptions{
+		Rules: []ToolPolicyRuleOptions{
+			{Name: "src-only", Effect: "deny", Tools: []string{"write_file", "edit_file"}, Args: map[string]ToolArgCondition{
+				"path": {PathPrefixes: []string{"src"}, Negate: true},
+			}},
+		},
+	})
+	require.NoError(t, err)
+
+	inside := policy.Evaluate(PolicyRequest{Tool: "write_file", Workspace: workspace, Args: map[string]interface{}{"path": "src/main.go"}})
+	assert.True(t, inside.Allowed)
+
+	outside := policy.Evaluate(PolicyRequest{Tool: "write_file", Workspace: workspace, Args: map[string]interface{}{"path": "src/../etc/passwd"}})
+	assert.False(t, outside.Allowed)
+	assert.Equal(t, "src-only", outside.Rule)
+}
+
+func TestToolPolicyPathPrefixResolvesSymlinks(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workspace, "src"), 0755))
+	require.NoError(t, os.Symlink(outside, filepath.Join(workspace, "src", "escape")))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "existing.txt"), []byte("x"), 0644))
+	policy, err := NewToolPolicy(ToolPolicyOptions{
+		Rules: []ToolPolicyRuleOptions{
+			{Name: "src-only", Effect: "deny", Tools: []string{"write_file"}, Args: map[string]ToolArgCondition{
+				"path": {PathPrefixes: []string{"src"}, Negate: true},
+			}},
+		},
+	})
+	require.NoError(t, err)
+	evaluate := func(path string) PolicyDecision {
+		return policy.Evaluate(PolicyRequest{Tool: "write_file", Workspace: workspace, Args: map[string]interface{}{"path": path}})
+	}
+
+	assert.True(t, evaluate("src/new/main.go").Allowed, "new files under src are allowed")
+	assert.False(t, evaluate("src/escape/existing.txt").Allowed, "existing file behind a symlink")
+	assert.False(t, evaluate("src/escape/new.txt").Allowed, "new file behind a symlink")
+	assert.False(t, evaluate("src/escape/deeper/new.txt").Allowed, "new nested file behind a symlink")
+}
+
+func TestToolPolicyPathRulesCoverApplyPatchTargets(t *testing.T) {
+	workspace := t.TempDir()
+	policy, err := NewToolPolicy(ToolPolicyOptions{
+		Rules: []ToolPolicyRuleOptions{
+			{Name: "src-only", Effect: "deny", Tools: []string{"write_file", "apply_patch"}, Args: map[string]ToolArgCondition{
+				"path": {PathPrefixes: []string{"src"}, Negate: true},
+			}},
+		},
+	})
+	require.NoError(t, err)
+	evaluate := func(args map[string]interface{}) PolicyDecision {
+		return policy.Evaluate(PolicyRequest{Tool: "apply_patch", Workspace: workspace, Args: args})
+	}
+
+	inside := "--- a/src/a.go\n+++ b/src/a.go\n@@ -1 +1 @@\n-a\n+b\n"
+	assert.True(t, evaluate(map[string]interface{}{"patch": inside}).Allowed)
+
+	outside := inside + "--- /dev/null\n+++ b/.github/workflows/ci.yml\n@@ -0,0 +1 @@\n+on: push\n"
+	denied := evaluate(map[string]interface{}{"patch": outside})
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, "src-only", denied.Rule)
+
+	assert.True(t, evaluate(map[string]interface{}{
+		"path":  "src/a.go",
+		"edits": []interface{}{map[string]interface{}{"old_string": "a", "new_string": "b"}},
+	}).Allowed)
+	assert.False(t, evaluate(map[string]interface{}{
+		"path": "src/a.go",
+		"edits": []interface{}{
+			map[string]interface{}{"old_string": "a", "new_string": "b"},
+			map[string]interface{}{"path": "Makefile", "old_string": "a", "new_string": "b"},
+		},
+	}).Allowed)
+}
+
+func TestToolPolicyDomainsAndScopes(t *testing.T) {
+	policy, err := NewToolPolicy(ToolPolicyOptions{
+		Default: "deny",
+		Rules: []ToolPolicyRuleOptions{
+			{Name: "no-internal", Effect: "deny", Tools: []string{"web_*"}, Args: map[string]ToolArgCondition{
+				"url": {Domains: []string{"*.internal.example.com"}},
+			}},
+			{Name: "web", Effect: "allow", Tools: []string{"web_*"}, Channels: []string{"telegram"}, Agents: []string{"research"}},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.False(t, policy.Evaluate(PolicyRequest{
+		Tool: "web_fetch", Agent: "research", Channel: "telegram",
+		Args: map[string]interface{}{"url": "https://wiki.internal.example.com/page"},
+	}).Allowed)
+	assert.True(t, policy.Evaluate(PolicyRequest{
+		Tool: "web_fetch", Agent: "research", Channel: "telegram",
+		Args: map[string]interface{}{"url": "https://example.com"},
+	}).Allowed)
+
+	fallback := policy.Evaluate(PolicyRequest{Tool: "web_fetch", Agent: "coder", Channel: "telegram"})
+	assert.False(t, fallback.Allowed)
+	assert.Equal(t, "default", fallback.Rule)
+	assert.NotEmpty(t, fallback.Reason)
+}
+
+func TestToolPolicyInvalidOptions(t *testing.T) {
+	_, err := NewToolPolicy(ToolPolicyOptions{Default: "maybe"})
+	assert.Error(t, err)
+
+	_, err = NewToolPolicy(ToolPolicyOptions{Rules: []ToolPolicyRuleOptions{{Effect: "block"}}})
+	assert.Error(t, err)
+
+	_, err = NewToolPolicy(ToolPolicyOptions{Rules: []ToolPolicyRuleOptions{{
+		Effect: "deny", Args: map[string]ToolArgCondition{"command": {Pattern: "("}},
+	}}})
+	assert.Error(t, err)
+}
+
+func TestNilToolPolicyAllows(t *testing.T) {
+	var policy *ToolPolicy
+	assert.True(t, policy.Evaluate(PolicyRequest{Tool: "exec"}).Allowed)
+	assert.Equal(t, 0, policy.RuleCount())
+}
+
+func TestPolicyDeniedErrorIsStructured(t *testing.T) {
+	err := &PolicyDeniedError{Tool: "exec", Decision: PolicyDecision{Rule: "no-exec", Reason: "blocked"}}
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(err.Error()), &body))
+	assert.Equal(t, "tool_policy_denied", body["error"])
+	assert.Equal(t, "exec", body["tool"])
+	assert.Equal(t, "no-exec", body["rule"])
+	assert.Equal(t, "blocked", body["reason"])
+}
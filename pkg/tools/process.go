@@ -0,0 +1,691 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ProcessKindBackground = "process"
+	ProcessKindShell      = "shell"
+
+	defaultProcessBufferBytes     = 256 * 1024
+	defaultMaxProcessesPerSession = 16
+	defaultProcessWaitSeconds     = 30
+	maxProcessWaitSeconds         = 300
+	processKillGrace              = 2 * time.Second
+)
+
+// ProcessInfo 后台进程 / 持久 shell 的快照（供工具输出与 Web UI 使用）
+type ProcessInfo struct {
+	ID          string     `json:"id"`
+	Kind        string     `json:"kind"`
+	Name        string     `json:"name,omitempty"`
+	SessionKey  string     `json:"sessionKey"`
+	Command     string     `json:"command"`
+	WorkDir     string     `json:"workDir"`
+	Backend     string     `json:"backend"`
+	PID         int        `json:"pid"`
+	Running     bool       `json:"running"`
+	ExitCode    *int       `json:"exitCode,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	OutputBytes int64      `json:"outputBytes"`
+}
+
+// ProcessOutput 一段增量输出
+type ProcessOutput struct {
+	Output     string `json:"output"`
+	Offset     int64  `json:"offset"`
+	NextOffset int64  `json:"nextOffset"`
+	// Dropped 为 true 表示请求的起点已被环形缓冲区覆盖
+	Dropped bool `json:"dropped,omitempty"`
+}
+
+// ProcessManager 管理某个代理工作区内的后台进程与持久 shell，按会话归属
+type ProcessManager struct {
+	WorkDir             string
+	RestrictToWorkspace bool
+	Sandbox             ExecSandboxOptions
+	MaxPerSession       int
+	BufferBytes         int
+
+	mu    sync.Mutex
+	procs map[string]*managedProcess
+	// pending 各会话已占用名额、尚未登记到 procs 的启动数
+	pending map[string]int
+}
+
+type managedProcess struct {
+	id         string
+	kind       string
+	name       string
+	sessionKey string
+	command    string
+	workDir    string
+	backend    string
+	startedAt  time.Time
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	output  *processBuffer
+	cleanup func()
+	done    chan struct{}
+
+	// cursor 记录工具上一次 read 的位置，用于无 offset 的增量读取
+	cursor  int64
+	runMu   sync.Mutex
+	stateMu sync.Mutex
+	exit    *int
+	exitErr string
+	endedAt *time.Time
+}
+
+// NewProcessManager 创建进程管理器
+func NewProcessManager(workDir string, restrictToWorkspace bool, sandbox ExecSandboxOptions) *ProcessManager {
+	return &ProcessManager{
+		WorkDir:             workDir,
+		RestrictToWorkspace: restrictToWorkspace,
+		Sandbox:             sandbox,
+		MaxPerSession:       defaultMaxProcessesPerSession,
+		BufferBytes:         defaultProcessBufferBytes,
+		procs:               make(map[string]*managedProcess),
+		pending:             make(map[string]int),
+	}
+}
+
+// ProcessStartRequest 启动参数
+type ProcessStartRequest struct {
+	SessionKey    string
+	Kind          string
+	Name          string
+	Command       string
+	WorkDir       string
+	ExecutionMode string
+}
+
+// Start 启动后台进程（Kind=process）或持久 shell（Kind=shell）
+func (m *ProcessManager) Start(req ProcessStartRequest) (ProcessInfo, error) {
+	kind := req.Kind
+	if kind == "" {
+		kind = ProcessKindBackground
+	}
+	command := strings.TrimSpace(req.Command)
+	if kind == ProcessKindShell {
+		command = "exec sh"
+	} else if command == "" {
+		return ProcessInfo{}, fmt.Errorf("command is required")
+	}
+
+	backend, err := NewExecBackend(ResolveExecBackendName(m.Sandbox, req.ExecutionMode))
+	if err != nil {
+		return ProcessInfo{}, err
+	}
+	if err := backend.Available(); err != nil {
+		return ProcessInfo{}, fmt.Errorf("exec backend %s unavailable: %w", backend.Name(), err)
+	}
+	if kind == ProcessKindBackground {
+		if err := m.checkCommand(command, backend); err != nil {
+			return ProcessInfo{}, err
+		}
+	}
+	workDir, err := m.resolveWorkDir(req.WorkDir, backend)
+	if err != nil {
+		return ProcessInfo{}, err
+	}
+
+	// 在锁内占用名额，直到进程登记或启动失败，避免并发启动越过上限
+	m.mu.Lock()
+	if m.countSession(req.SessionKey)+m.pending[req.SessionKey] >= m.maxPerSession() {
+		m.mu.Unlock()
+		return ProcessInfo{}, fmt.Errorf("too many processes for this session (max %d); kill finished or idle ones first", m.maxPerSession())
+	}
+	m.pending[req.SessionKey]++
+	m.mu.Unlock()
+
+	proc, err := m.launch(req, kind, command, workDir, backend)
+	m.mu.Lock()
+	m.pending[req.SessionKey]--
+	if m.pending[req.SessionKey] <= 0 {
+		delete(m.pending, req.SessionKey)
+	}
+	if err == nil {
+		m.procs[proc.id] = proc
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return ProcessInfo{}, err
+	}
+	return proc.info(), nil
+}
+
+// launch 通过后端启动进程；失败时调用后端返回的清理函数
+func (m *ProcessManager) launch(req ProcessStartRequest, kind, command, workDir string, backend ExecBackend) (*managedProcess, error) {
+	cmd, cleanup, err := backend.Prepare(context.Background(), ExecRequest{
+		Command:  command,
+		WorkDir:  workDir,
+		Network:  m.Sandbox.networkFor(backend),
+		CPUs:     m.Sandbox.CPUs,
+		MemoryMB: m.Sandbox.MemoryMB,
+		Image:    m.Sandbox.Image,
+	})
+	if err != nil {
+		return nil, err
+	}
+	fail := func(err error) (*managedProcess, error) {
+		if cleanup != nil {
+			cleanup()
+		}
+		return nil, err
+	}
+	setProcessGroup(cmd)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fail(err)
+	}
+	output := newProcessBuffer(m.bufferBytes())
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Start(); err != nil {
+		return fail(fmt.Errorf("failed to start process: %w", err))
+	}
+
+	proc := &managedProcess{
+		id:         newProcessID(kind),
+		kind:       kind,
+		name:       strings.TrimSpace(req.Name),
+		sessionKey: req.SessionKey,
+		command:    command,
+		workDir:    workDir,
+		backend:    backend.Name(),
+		startedAt:  time.Now(),
+		cmd:        cmd,
+		stdin:      stdin,
+		output:     output,
+		cleanup:    cleanup,
+		done:       make(chan struct{}),
+	}
+	go proc.wait()
+	return proc, nil
+}
+
+// Shell 在会话的持久 shell 中执行命令，保留 cwd 与环境变量；超时后命令继续在 shell 中运行
+func (m *ProcessManager) Shell(sessionKey, shellID, command, executionMode string, timeout time.Duration) (string, ProcessInfo, error) {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return "", ProcessInfo{}, fmt.Errorf("command is required")
+	}
+
+	proc, err := m.shellFor(sessionKey, shellID, executionMode)
+	if err != nil {
+		return "", ProcessInfo{}, err
+	}
+	backend, err := NewExecBackend(proc.backend)
+	if err != nil {
+		return "", ProcessInfo{}, err
+	}
+	if err := m.checkCommand(command, backend); err != nil {
+		return "", ProcessInfo{}, err
+	}
+
+	proc.runMu.Lock()
+	defer proc.runMu.Unlock()
+
+	marker := "__MAXCLAW_DONE_" + randomSuffix()
+	start := proc.output.End()
+	script := fmt.Sprintf("%s\nprintf '\\n%s:%%s\\n' \"$?\"\n", command, marker)
+	if _, err := io.WriteString(proc.stdin, script); err != nil {
+		return "", proc.info(), fmt.Errorf("shell %s is not accepting input: %w", proc.id, err)
+	}
+
+	markerRe := regexp.MustCompile(`\n?` + marker + `:(\d+)\n`)
+	deadline := time.Now().Add(timeout)
+	for {
+		chunk := proc.output.ReadFrom(start, 0)
+		if loc := markerRe.FindStringSubmatchIndex(chunk.Output); loc != nil {
+			code := chunk.Output[loc[2]:loc[3]]
+			out := chunk.Output[:loc[0]]
+			proc.advanceCursor(start + int64(loc[1]))
+			result := capOutput(out, m.Sandbox.maxOutput())
+			if code != "0" {
+				result = fmt.Sprintf("%s\n[exit code %s]", result, code)
+			}
+			return result, proc.info(), nil
+		}
+		if !proc.running() {
+			proc.advanceCursor(chunk.NextOffset)
+			return capOutput(chunk.Output, m.Sandbox.maxOutput()), proc.info(), fmt.Errorf("shell %s exited", proc.id)
+		}
+		if time.Now().After(deadline) {
+			proc.advanceCursor(chunk.NextOffset)
+			return fmt.Sprintf("%s\n... (still running after %v; use action=read with id=%s to follow, or action=kill)",
+				capOutput(chunk.Output, m.Sandbox.maxOutput()), timeout, proc.id), proc.info(), nil
+		}
+		select {
+		case <-proc.done:
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// Read 返回增量输出；offset < 0 时从上次读取的位置继续
+func (m *ProcessManager) Read(sessionKey, id string, offset int64, maxBytes int) (ProcessOutput, ProcessInfo, error) {
+	proc, err := m.lookup(sessionKey, id)
+	if err != nil {
+		return ProcessOutput{}, ProcessInfo{}, err
+	}
+	if offset < 0 {
+		offset = proc.currentCursor()
+	}
+	chunk := proc.output.ReadFrom(offset, maxBytes)
+	proc.advanceCursor(chunk.NextOffset)
+	return chunk, proc.info(), nil
+}
+
+// Write 向进程 stdin 写入数据
+func (m *ProcessManager) Write(sessionKey, id, input string) error {
+	proc, err := m.lookup(sessionKey, id)
+	if err != nil {
+		return err
+	}
+	if !proc.running() {
+		return fmt.Errorf("process %s has exited", id)
+	}
+	if proc.kind == ProcessKindShell {
+		backend, err := NewExecBackend(proc.backend)
+		if err != nil {
+			return err
+		}
+		if err := m.checkCommand(input, backend); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(proc.stdin, input)
+	return err
+}
+
+// Wait 等待进程退出或超时，返回最新状态
+func (m *ProcessManager) Wait(sessionKey, id string, timeout time.Duration) (ProcessInfo, bool, error) {
+	proc, err := m.lookup(sessionKey, id)
+	if err != nil {
+		return ProcessInfo{}, false, err
+	}
+	select {
+	case <-proc.done:
+		return proc.info(), true, nil
+	case <-time.After(timeout):
+		return proc.info(), false, nil
+	}
+}
+
+// List 返回进程列表；sessionKey 为空时返回全部
+func (m *ProcessManager) List(sessionKey string) []ProcessInfo {
+	m.mu.Lock()
+	procs := make([]*managedProcess, 0, len(m.procs))
+	for _, proc := range m.procs {
+		if sessionKey == "" || proc.sessionKey == sessionKey {
+			procs = append(procs, proc)
+		}
+	}
+	m.mu.Unlock()
+
+	out := make([]ProcessInfo, 0, len(procs))
+	for _, proc := range procs {
+		out = append(out, proc.info())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out
+}
+
+// Get 返回单个进程信息；sessionKey 为空时不校验归属
+func (m *ProcessManager) Get(sessionKey, id string) (ProcessInfo, error) {
+	proc, err := m.lookup(sessionKey, id)
+	if err != nil {
+		return ProcessInfo{}, err
+	}
+	return proc.info(), nil
+}
+
+// Output 返回从 offset 开始的输出但不移动工具的读取游标（供 Web UI 使用）
+func (m *ProcessManager) Output(id string, offset int64, maxBytes int) (ProcessOutput, error) {
+	proc, err := m.lookup("", id)
+	if err != nil {
+		return ProcessOutput{}, err
+	}
+	return proc.output.ReadFrom(offset, maxBytes), nil
+}
+
+// Kill 终止进程并从列表中移除
+func (m *ProcessManager) Kill(sessionKey, id string) (ProcessInfo, error) {
+	proc, err := m.lookup(sessionKey, id)
+	if err != nil {
+		return ProcessInfo{}, err
+	}
+	proc.terminate()
+
+	m.mu.Lock()
+	delete(m.procs, proc.id)
+	m.mu.Unlock()
+	return proc.info(), nil
+}
+
+// KillSession 终止会话下的所有进程（会话删除时调用）
+func (m *ProcessManager) KillSession(sessionKey string) int {
+	return m.killWhere(func(p *managedProcess) bool { return p.sessionKey == sessionKey })
+}
+
+// Close 终止全部进程（网关停止时调用）
+func (m *ProcessManager) Close() error {
+	m.killWhere(func(*managedProcess) bool { return true })
+	return nil
+}
+
+func (m *ProcessManager) killWhere(match func(*managedProcess) bool) int {
+	m.mu.Lock()
+	var targets []*managedProcess
+	for id, proc := range m.procs {
+		if match(proc) {
+			targets = append(targets, proc)
+			delete(m.procs, id)
+		}
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, proc := range targets {
+		wg.Add(1)
+		go func(p *managedProcess) {
+			defer wg.Done()
+			p.terminate()
+		}(proc)
+	}
+	wg.Wait()
+	return len(targets)
+}
+
+func (m *ProcessManager) shellFor(sessionKey, shellID, executionMode string) (*managedProcess, error) {
+	if shellID = strings.TrimSpace(shellID); shellID != "" {
+		proc, err := m.lookup(sessionKey, shellID)
+		if err != nil {
+			return nil, err
+		}
+		if proc.kind != ProcessKindShell {
+			return nil, fmt.Errorf("process %s is not a shell session", shellID)
+		}
+		if !proc.running() {
+			return nil, fmt.Errorf("shell %s has exited", shellID)
+		}
+		return proc, nil
+	}
+
+	m.mu.Lock()
+	var existing *managedProcess
+	for _, proc := range m.procs {
+		if proc.sessionKey == sessionKey && proc.kind == ProcessKindShell && proc.name == "" && proc.running() {
+			if existing == nil || proc.startedAt.After(existing.startedAt) {
+				existing = proc
+			}
+		}
+	}
+	m.mu.Unlock()
+	if existing != nil {
+		return existing, nil
+	}
+
+	info, err := m.Start(ProcessStartRequest{SessionKey: sessionKey, Kind: ProcessKindShell, ExecutionMode: executionMode})
+	if err != nil {
+		return nil, err
+	}
+	return m.lookup(sessionKey, info.ID)
+}
+
+func (m *ProcessManager) lookup(sessionKey, id string) (*managedProcess, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	proc, ok := m.procs[strings.TrimSpace(id)]
+	if !ok || (sessionKey != "" && proc.sessionKey != sessionKey) {
+		return nil, fmt.Errorf("process %s not found", id)
+	}
+	return proc, nil
+}
+
+func (m *ProcessManager) countSession(sessionKey string) int {
+	n := 0
+	for _, proc := range m.procs {
+		if proc.sessionKey == sessionKey {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *ProcessManager) checkCommand(command string, backend ExecBackend) error {
+	if err := isDangerousCommand(command); err != nil {
+		return err
+	}
+	if !m.RestrictToWorkspace || backend.Sandboxed() {
+		return nil
+	}
+	if m.WorkDir == "" {
+		return fmt.Errorf("restrictToWorkspace enabled but working directory is empty")
+	}
+	workspace, err := cleanAbsPath(m.WorkDir)
+	if err != nil {
+		return fmt.Errorf("invalid workspace: %w", err)
+	}
+	return validateCommandInWorkspace(command, workspace)
+}
+
+func (m *ProcessManager) resolveWorkDir(sub string, backend ExecBackend) (string, error) {
+	base := m.WorkDir
+	if base == "" {
+		if backend.Sandboxed() || m.RestrictToWorkspace {
+			return "", fmt.Errorf("process tool requires a workspace")
+		}
+		return "", nil
+	}
+	base, err := cleanAbsPath(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid workspace: %w", err)
+	}
+	sub = strings.TrimSpace(sub)
+	if sub == "" {
+		return base, nil
+	}
+	target := sub
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(base, target)
+	}
+	target, err = cleanAbsPath(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid cwd: %w", err)
+	}
+	if (m.RestrictToWorkspace || backend.Sandboxed()) && !isWithin(base, target) {
+		return "", fmt.Errorf("cwd %s is outside workspace", sub)
+	}
+	return target, nil
+}
+
+func (m *ProcessManager) maxPerSession() int {
+	if m.MaxPerSession > 0 {
+		return m.MaxPerSession
+	}
+	return defaultMaxProcessesPerSession
+}
+
+func (m *ProcessManager) bufferBytes() int {
+	if m.BufferBytes > 0 {
+		return m.BufferBytes
+	}
+	return defaultProcessBufferBytes
+}
+
+func (p *managedProcess) wait() {
+	err := p.cmd.Wait()
+	now := time.Now()
+	code := 0
+	if p.cmd.ProcessState != nil {
+		code = p.cmd.ProcessState.ExitCode()
+	}
+
+	p.stateMu.Lock()
+	p.exit = &code
+	p.endedAt = &now
+	if err != nil {
+		p.exitErr = err.Error()
+	}
+	p.stateMu.Unlock()
+	close(p.done)
+}
+
+func (p *managedProcess) running() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (p *managedProcess) terminate() {
+	if !p.running() {
+		return
+	}
+	_ = p.stdin.Close()
+	signalProcessGroup(p.cmd, false)
+	select {
+	case <-p.done:
+	case <-time.After(processKillGrace):
+		signalProcessGroup(p.cmd, true)
+		if p.cleanup != nil {
+			p.cleanup()
+		}
+		select {
+		case <-p.done:
+		case <-time.After(processKillGrace):
+		}
+	}
+}
+
+func (p *managedProcess) currentCursor() int64 {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	return p.cursor
+}
+
+func (p *managedProcess) advanceCursor(offset int64) {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	if offset > p.cursor {
+		p.cursor = offset
+	}
+}
+
+func (p *managedProcess) info() ProcessInfo {
+	info := ProcessInfo{
+		ID:          p.id,
+		Kind:        p.kind,
+		Name:        p.name,
+		SessionKey:  p.sessionKey,
+		Command:     p.command,
+		WorkDir:     p.workDir,
+		Backend:     p.backend,
+		Running:     p.running(),
+		StartedAt:   p.startedAt,
+		OutputBytes: p.output.End(),
+	}
+	if p.cmd.Process != nil {
+		info.PID = p.cmd.Process.Pid
+	}
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	if p.exit != nil {
+		code := *p.exit
+		info.ExitCode = &code
+	}
+	if p.endedAt != nil {
+		ended := *p.endedAt
+		info.FinishedAt = &ended
+	}
+	info.Error = p.exitErr
+	return info
+}
+
+func newProcessID(kind string) string {
+	prefix := "proc"
+	if kind == ProcessKindShell {
+		prefix = "sh"
+	}
+	return prefix + "_" + randomSuffix()[:8]
+}
+
+func capOutput(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	return s[:limit] + "\n... (output truncated)"
+}
+
+// processBuffer 保留最近 limit 字节的输出，并以全局偏移量支持增量读取
+type processBuffer struct {
+	mu    sync.Mutex
+	data  []byte
+	base  int64
+	limit int
+}
+
+func newProcessBuffer(limit int) *processBuffer {
+	return &processBuffer{limit: limit}
+}
+
+func (b *processBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if over := len(b.data) - b.limit; over > 0 {
+		b.data = append([]byte(nil), b.data[over:]...)
+		b.base += int64(over)
+	}
+	return len(p), nil
+}
+
+// End 返回已写入的总字节数（下一次写入的偏移量）
+func (b *processBuffer) End() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.base + int64(len(b.data))
+}
+
+// ReadFrom 读取 offset 起的最多 maxBytes 字节（maxBytes <= 0 表示不限）
+func (b *processBuffer) ReadFrom(offset int64, maxBytes int) ProcessOutput {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := ProcessOutput{Offset: offset}
+	if offset < b.base {
+		out.Dropped = offset >= 0
+		offset = b.base
+		out.Offset = offset
+	}
+	end := b.base + int64(len(b.data))
+	if offset > end {
+		offset = end
+	}
+	start := int(offset - b.base)
+	chunk := b.data[start:]
+	if maxBytes > 0 && len(chunk) > maxBytes {
+		chunk = chunk[:maxBytes]
+	}
+	out.Output = string(chunk)
+	out.NextOffset = offset + int64(len(chunk))
+	return out
+}
@@ -0,0 +1,212 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessManagerBackgroundLifecycle(t *testing.T) {
+	m := NewProcessManager(t.TempDir(), false, ExecSandboxOptions{})
+	defer m.Close()
+
+	info, err := m.Start(ProcessStartRequest{SessionKey: "s1", Command: "cat"})
+	require.NoError(t, err)
+	assert.True(t, info.Running)
+	assert.Equal(t, ExecBackendHost, info.Backend)
+
+	require.NoError(t, m.Write("s1", info.ID, "hello\n"))
+	require.Eventually(t, func() bool {
+		out, _, err := m.Read("s1", info.ID, 0, 0)
+		return err == nil && strings.Contains(out.Output, "hello")
+	}, 2*time.Second, 20*time.Millisecond)
+
+	// 增量读取：游标之后没有新输出
+	out, _, err := m.Read("s1", info.ID, -1, 0)
+	require.NoError(t, err)
+	assert.Empty(t, out.Output)
+
+	require.NoError(t, m.Write("s1", info.ID, "again\n"))
+	require.Eventually(t, func() bool {
+		out, _, _ := m.Read("s1", info.ID, -1, 0)
+		return out.Output == "again\n"
+	}, 2*time.Second, 20*time.Millisecond)
+
+	_, _, err = m.Read("other-session", info.ID, -1, 0)
+	assert.Error(t, err)
+
+	assert.Len(t, m.List("s1"), 1)
+	assert.Empty(t, m.List("other-session"))
+
+	killed, err := m.Kill("s1", info.ID)
+	require.NoError(t, err)
+	assert.False(t, killed.Running)
+	assert.Empty(t, m.List(""))
+}
+
+func TestProcessManagerWaitReportsExitCode(t *testing.T) {
+	m := NewProcessManager(t.TempDir(), false, ExecSandboxOptions{})
+	defer m.Close()
+
+	info, err := m.Start(ProcessStartRequest{SessionKey: "s", Command: "echo done; exit 3"})
+	require.NoError(t, err)
+
+	final, exited, err := m.Wait("s", info.ID, 5*time.Second)
+	require.NoError(t, err)
+	assert.True(t, exited)
+	require.NotNil(t, final.ExitCode)
+	assert.Equal(t, 3, *final.ExitCode)
+
+	out, _, err := m.Read("s", info.ID, -1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "done\n", out.Output)
+}
+
+func TestProcessManagerShellKeepsState(t *testing.T) {
+	workspace := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(workspace, "sub"), 0755))
+	m := NewProcessManager(workspace, false, ExecSandboxOptions{})
+	defer m.Close()
+
+	_, first, err := m.Shell("s", "", "cd sub && export GREETING=hi", "", 5*time.Second)
+	require.NoError(t, err)
+
+	out, second, err := m.Shell("s", "", "pwd; echo $GREETING", "", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Contains(t, out, filepath.Join(workspace, "sub"))
+	assert.Contains(t, out, "hi")
+
+	out, _, err = m.Shell("s", "", "false", "", 5*time.Second)
+	require.NoError(t, err)
+	assert.Contains(t, out, "[exit code 1]")
+
+	out, _, err = m.Shell("s", "", "sleep 5", "", 200*time.Millisecond)
+	require.NoError(t, err)
+	assert.Contains(t, out, "still running")
+
+	// 其他会话拥有独立 shell
+	_, other, err := m.Shell("s2", "", "true", "", 5*time.Second)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, other.ID)
+
+	assert.Equal(t, 1, m.KillSession("s"))
+	assert.Len(t, m.List(""), 1)
+}
+
+func TestProcessManagerGuards(t *testing.T) {
+	workspace := t.TempDir()
+	m := NewProcessManager(workspace, true, ExecSandboxOptions{})
+	m.MaxPerSession = 1
+	defer m.Close()
+
+	_, err := m.Start(ProcessStartRequest{SessionKey: "s", Command: "cat /etc/passwd"})
+	assert.ErrorContains(t, err, "outside workspace")
+
+	_, err = m.Start(ProcessStartRequest{SessionKey: "s", Command: "sleep 5", WorkDir: "../"})
+	assert.ErrorContains(t, err, "outside workspace")
+
+	_, err = m.Start(ProcessStartRequest{SessionKey: "s", Command: "sleep 5"})
+	require.NoError(t, err)
+	_, err = m.Start(ProcessStartRequest{SessionKey: "s", Command: "sleep 5"})
+	assert.ErrorContains(t, err, "too many processes")
+}
+
+func TestProcessManagerLimitHoldsUnderConcurrentStarts(t *testing.T) {
+	workspace := t.TempDir()
+	m := NewProcessManager(workspace, true, ExecSandboxOptions{})
+	m.MaxPerSession = 2
+	defer m.Close()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	started := 0
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Start(ProcessStartRequest{SessionKey: "s", Command: "sleep 5"}); err == nil {
+				mu.Lock()
+				started++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 2, started)
+	assert.Len(t, m.List("s"), 2)
+	assert.Empty(t, m.pending)
+}
+
+type failingStartBackend struct {
+	cleaned *int
+}
+
+func (failingStartBackend) Name() string     { return "fake" }
+func (failingStartBackend) Sandboxed() bool  { return true }
+func (failingStartBackend) Available() error { return nil }
+func (b failingStartBackend) Prepare(ctx context.Context, req ExecRequest) (*exec.Cmd, func(), error) {
+	return exec.Command(filepath.Join(req.WorkDir, "missing-binary")), func() { *b.cleaned++ }, nil
+}
+
+func TestProcessManagerLaunchCleansUpOnStartFailure(t *testing.T) {
+	workspace := t.TempDir()
+	m := NewProcessManager(workspace, true, ExecSandboxOptions{})
+	defer m.Close()
+
+	cleaned := 0
+	_, err := m.launch(ProcessStartRequest{SessionKey: "s"}, ProcessKindBackground, "run", workspace, failingStartBackend{cleaned: &cleaned})
+	assert.ErrorContains(t, err, "failed to start process")
+	assert.Equal(t, 1, cleaned)
+}
+
+func TestProcessBufferKeepsTail(t *testing.T) {
+	buf := newProcessBuffer(4)
+	_, _ = buf.Write([]byte("abcdef"))
+	assert.Equal(t, int64(6), buf.End())
+
+	out := buf.ReadFrom(0, 0)
+	assert.True(t, out.Dropped)
+	assert.Equal(t, "cdef", out.Output)
+	assert.Equal(t, int64(6), out.NextOffset)
+
+	out = buf.ReadFrom(4, 1)
+	assert.Equal(t, "e", out.Output)
+	assert.Equal(t, int64(5), out.NextOffset)
+}
+
+func TestProcessToolActions(t *testing.T) {
+	m := NewProcessManager(t.TempDir(), false, ExecSandboxOptions{})
+	defer m.Close()
+	tool := NewProcessTool(m)
+	ctx := WithRuntimeContextWithSession(context.Background(), "cli", "direct", "cli:direct")
+
+	result, err := tool.Execute(ctx, map[string]interface{}{"action": "start", "command": "echo ready; sleep 5", "name": "server"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "running")
+	assert.Contains(t, result, "ready")
+
+	list, err := tool.Execute(ctx, map[string]interface{}{"action": "list"})
+	require.NoError(t, err)
+	assert.Contains(t, list, `"name": "server"`)
+	assert.Contains(t, list, `"sessionKey": "cli:direct"`)
+
+	result, err = tool.Execute(ctx, map[string]interface{}{"action": "shell", "command": "echo from-shell"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "from-shell")
+
+	empty, err := tool.Execute(context.Background(), map[string]interface{}{"action": "list"})
+	require.NoError(t, err)
+	assert.Equal(t, "No processes in this session.", empty)
+
+	_, err = tool.Execute(ctx, map[string]interface{}{"action": "bogus"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,231 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProcessTool 后台进程与持久 shell 工具
+type ProcessTool struct {
+	BaseTool
+	manager *ProcessManager
+}
+
+// NewProcessTool 创建进程管理工具
+func NewProcessTool(manager *ProcessManager) *ProcessTool {
+	return &ProcessTool{
+		BaseTool: BaseTool{
+			name: "process",
+			description: "Manage long-running processes and persistent shells. " +
+				"Actions: start (background process, e.g. dev server or long build; returns an id), " +
+				"shell (run a command in this session's persistent shell that keeps cwd and env across calls), " +
+				"read (incremental stdout/stderr since the last read), write (send stdin), wait, list, kill. " +
+				"Use exec for short one-off commands.",
+			parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"action": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"start", "shell", "read", "write", "wait", "list", "kill"},
+						"description": "Action to perform",
+					},
+					"command": map[string]interface{}{
+						"type":        "string",
+						"description": "Command line (required for start and shell)",
+					},
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "Process or shell id (read/write/wait/kill; optional for shell to target a specific shell)",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional label for start",
+					},
+					"cwd": map[string]interface{}{
+						"type":        "string",
+						"description": "Working directory for start, relative to the workspace",
+					},
+					"input": map[string]interface{}{
+						"type":        "string",
+						"description": "Data to write to stdin (write). Include a trailing newline to submit a line.",
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "Output offset for read (default: continue after the previous read)",
+						"minimum":     0,
+					},
+					"timeout": map[string]interface{}{
+						"type":        "integer",
+						"description": "Seconds to wait for shell/wait (default 30, max 300)",
+						"minimum":     1,
+						"maximum":     maxProcessWaitSeconds,
+					},
+				},
+				"required": []string{"action"},
+			},
+		},
+		manager: manager,
+	}
+}
+
+// processPolicyCommand 返回 process 调用将要执行的命令（start/shell 的 command，write 的 input），
+// 供工具策略按 exec 规则评估
+func processPolicyCommand(args map[string]interface{}) string {
+	action, _ := args["action"].(string)
+	switch action {
+	case "start", "shell":
+		command, _ := args["command"].(string)
+		return strings.TrimSpace(command)
+	case "write":
+		input, _ := args["input"].(string)
+		return strings.TrimSpace(input)
+	}
+	return ""
+}
+
+// Execute 执行进程操作
+func (t *ProcessTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	if t.manager == nil {
+		return "", fmt.Errorf("process manager is not configured")
+	}
+	action, _ := params["action"].(string)
+	if action == "" {
+		return "", fmt.Errorf("action is required")
+	}
+
+	sessionKey := processSessionKey(ctx)
+	id, _ := params["id"].(string)
+	command, _ := params["command"].(string)
+
+	switch action {
+	case "start":
+		name, _ := params["name"].(string)
+		cwd, _ := params["cwd"].(string)
+		info, err := t.manager.Start(ProcessStartRequest{
+			SessionKey:    sessionKey,
+			Kind:          ProcessKindBackground,
+			Name:          name,
+			Command:       command,
+			WorkDir:       cwd,
+			ExecutionMode: RuntimeExecutionModeFrom(ctx),
+		})
+		if err != nil {
+			return "", err
+		}
+		// 给进程一点时间输出启动信息
+		waitInfo, _, _ := t.manager.Wait(sessionKey, info.ID, 300*time.Millisecond)
+		out, _, _ := t.manager.Read(sessionKey, info.ID, -1, t.manager.Sandbox.maxOutput())
+		return formatProcessResult(waitInfo, out.Output), nil
+
+	case "shell":
+		result, info, err := t.manager.Shell(sessionKey, id, command, RuntimeExecutionModeFrom(ctx), processTimeout(params))
+		if err != nil {
+			if info.ID == "" {
+				return "", err
+			}
+			return fmt.Sprintf("%s\n[%v]", result, err), nil
+		}
+		return fmt.Sprintf("[shell %s]\n%s", info.ID, result), nil
+
+	case "read":
+		offset := int64(-1)
+		if v, ok := params["offset"].(float64); ok && v >= 0 {
+			offset = int64(v)
+		}
+		out, info, err := t.manager.Read(sessionKey, id, offset, t.manager.Sandbox.maxOutput())
+		if err != nil {
+			return "", err
+		}
+		text := out.Output
+		if out.Dropped {
+			text = "... (earlier output discarded)\n" + text
+		}
+		if more := info.OutputBytes - out.NextOffset; more > 0 {
+			text += fmt.Sprintf("\n... (%d more bytes; read again to continue)", more)
+		}
+		return formatProcessResult(info, text), nil
+
+	case "write":
+		input, _ := params["input"].(string)
+		if input == "" {
+			return "", fmt.Errorf("input is required")
+		}
+		if err := t.manager.Write(sessionKey, id, input); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("wrote %d bytes to %s", len(input), id), nil
+
+	case "wait":
+		info, exited, err := t.manager.Wait(sessionKey, id, processTimeout(params))
+		if err != nil {
+			return "", err
+		}
+		out, info, _ := t.manager.Read(sessionKey, info.ID, -1, t.manager.Sandbox.maxOutput())
+		text := out.Output
+		if !exited {
+			text += "\n... (still running)"
+		}
+		return formatProcessResult(info, text), nil
+
+	case "list":
+		list := t.manager.List(sessionKey)
+		if len(list) == 0 {
+			return "No processes in this session.", nil
+		}
+		body, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(body), nil
+
+	case "kill":
+		info, err := t.manager.Kill(sessionKey, id)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("killed %s (%s)", info.ID, info.Command), nil
+
+	default:
+		return "", fmt.Errorf("unknown action: %s", action)
+	}
+}
+
+func processSessionKey(ctx context.Context) string {
+	if key := strings.TrimSpace(RuntimeSessionKeyFrom(ctx)); key != "" {
+		return key
+	}
+	channel, chatID := RuntimeContextFrom(ctx)
+	if channel != "" || chatID != "" {
+		return channel + ":" + chatID
+	}
+	return "default"
+}
+
+func processTimeout(params map[string]interface{}) time.Duration {
+	seconds := defaultProcessWaitSeconds
+	if v, ok := params["timeout"].(float64); ok && v > 0 {
+		seconds = int(v)
+	}
+	if seconds > maxProcessWaitSeconds {
+		seconds = maxProcessWaitSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func formatProcessResult(info ProcessInfo, output string) string {
+	status := "running"
+	if !info.Running {
+		status = "exited"
+		if info.ExitCode != nil {
+			status = fmt.Sprintf("exited (code %d)", *info.ExitCode)
+		}
+	}
+	header := fmt.Sprintf("[%s %s] %s pid=%d", info.Kind, info.ID, status, info.PID)
+	if strings.TrimSpace(output) == "" {
+		return header
+	}
+	return header + "\n" + output
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package tools
+
+import "os/exec"
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func signalProcessGroup(cmd *exec.Cmd, force bool) {
+	if cmd.Process != nil {
+		_ = cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,449 @@
+package tools
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// 正文提取模式（web_fetch 的 extract 参数）
+const (
+	ExtractArticle = "article"
+	ExtractFull    = "full"
+	ExtractLinks   = "links"
+)
+
+// PageMetadata 页面元信息
+type PageMetadata struct {
+	Title       string `json:"title,omitempty"`
+	Byline      string `json:"byline,omitempty"`
+	Published   string `json:"published,omitempty"`
+	Canonical   string `json:"canonical,omitempty"`
+	SiteName    string `json:"siteName,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// PageLink 页面中的链接
+type PageLink struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// HTMLExtractOptions HTML 提取选项
+type HTMLExtractOptions struct {
+	// Mode article（默认，仅正文）/ full（整页）/ links（仅链接列表）
+	Mode string
+	// BaseURL 用于补全相对链接，页面中的 <base href> 优先
+	BaseURL string
+	// ReferenceLinks 链接输出为 [text][n]，文末附编号列表
+	ReferenceLinks bool
+}
+
+// ExtractedPage HTML 提取结果
+type ExtractedPage struct {
+	Metadata PageMetadata
+	Markdown string
+	Links    []PageLink
+}
+
+// Format 输出带元信息头的 Markdown
+func (p ExtractedPage) Format() string {
+	var b strings.Builder
+	meta := p.Metadata
+	if meta.Title != "" {
+		b.WriteString("Title: " + meta.Title + "\n")
+	}
+	if meta.Byline != "" {
+		b.WriteString("Byline: " + meta.Byline + "\n")
+	}
+	if meta.Published != "" {
+		b.WriteString("Published: " + meta.Published + "\n")
+	}
+	if meta.Canonical != "" {
+		b.WriteString("URL: " + meta.Canonical + "\n")
+	}
+	if b.Len() > 0 && p.Markdown != "" {
+		b.WriteString("\n")
+	}
+	b.WriteString(p.Markdown)
+	return strings.TrimSpace(b.String())
+}
+
+// NormalizeExtractMode 规范化 extract 参数，未知值返回错误
+func NormalizeExtractMode(mode string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", ExtractArticle:
+		return ExtractArticle, nil
+	case ExtractFull:
+		return ExtractFull, nil
+	case ExtractLinks:
+		return ExtractLinks, nil
+	}
+	return "", fmt.Errorf("unsupported extract mode %q (use article, full or links)", mode)
+}
+
+// ExtractHTML 将 HTML 转为 Markdown，并提取标题、作者、发布时间和规范链接
+func ExtractHTML(rawHTML string, opts HTMLExtractOptions) ExtractedPage {
+	mode, err := NormalizeExtractMode(opts.Mode)
+	if err != nil {
+		mode = ExtractArticle
+	}
+	doc := parseHTML(rawHTML)
+	base := resolveDocumentBase(doc, opts.BaseURL)
+	meta := extractPageMetadata(doc, base)
+
+	body := doc.find("body")
+	if body == nil {
+		body = doc
+	}
+	removeNodes(body, isNeverContent)
+
+	page := ExtractedPage{Metadata: meta}
+	if mode == ExtractLinks {
+		page.Links = collectPageLinks(body, base)
+		var b strings.Builder
+		for i, link := range page.Links {
+			fmt.Fprintf(&b, "%d. [%s](%s)\n", i+1, escapeMarkdownLinkText(link.Text), link.URL)
+		}
+		page.Markdown = strings.TrimSpace(b.String())
+		return page
+	}
+
+	content := body
+	if mode == ExtractArticle {
+		content = selectArticleContent(body)
+	}
+	r := &markdownRenderer{base: base, referenceLinks: opts.ReferenceLinks}
+	markdown := r.render(content)
+	if meta.Title != "" {
+		markdown = dropLeadingTitle(markdown, meta.Title)
+	}
+	if len(r.refs) > 0 {
+		var refs strings.Builder
+		for i, ref := range r.refs {
+			fmt.Fprintf(&refs, "[%d]: %s\n", i+1, ref)
+		}
+		markdown = strings.TrimSpace(markdown) + "\n\n" + strings.TrimSpace(refs.String())
+	}
+	page.Markdown = markdown
+	page.Links = r.links
+	return page
+}
+
+func resolveDocumentBase(doc *htmlNode, fallback string) *url.URL {
+	base, _ := url.Parse(strings.TrimSpace(fallback))
+	if b := doc.find("base"); b != nil {
+		if href := strings.TrimSpace(b.attr("href")); href != "" {
+			if u, err := url.Parse(href); err == nil {
+				if base != nil {
+					u = base.ResolveReference(u)
+				}
+				if u.IsAbs() {
+					base = u
+				}
+			}
+		}
+	}
+	return base
+}
+
+func resolveLink(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return ""
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if base != nil && base.IsAbs() {
+		u = base.ResolveReference(u)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "javascript", "data", "vbscript":
+		return ""
+	}
+	return u.String()
+}
+
+func extractPageMetadata(doc *htmlNode, base *url.URL) PageMetadata {
+	metas := make(map[string]string)
+	for _, m := range doc.findAll("meta") {
+		key := strings.ToLower(strings.TrimSpace(firstNonEmpty(m.attr("property"), m.attr("name"), m.attr("itemprop"))))
+		content := strings.TrimSpace(m.attr("content"))
+		if key == "" || content == "" {
+			continue
+		}
+		if _, exists := metas[key]; !exists {
+			metas[key] = content
+		}
+	}
+
+	var meta PageMetadata
+	titleTag := ""
+	if t := doc.find("title"); t != nil {
+		titleTag = t.textContent()
+	}
+	meta.Title = firstNonEmpty(metas["og:title"], metas["twitter:title"], titleTag)
+	if meta.Title == "" {
+		if h1 := doc.find("h1"); h1 != nil {
+			meta.Title = h1.textContent()
+		}
+	}
+	meta.SiteName = metas["og:site_name"]
+	meta.Description = firstNonEmpty(metas["description"], metas["og:description"])
+	meta.Byline = firstNonEmpty(metas["author"], metas["article:author"], metas["parsely-author"], metas["dc.creator"])
+	if meta.Byline == "" {
+		meta.Byline = findByline(doc)
+	}
+	if strings.HasPrefix(meta.Byline, "http") {
+		// article:author 常为作者主页链接
+		meta.Byline = ""
+	}
+	meta.Published = firstNonEmpty(metas["article:published_time"], metas["datepublished"], metas["date"],
+		metas["pubdate"], metas["publishdate"], metas["dc.date"], metas["og:published_time"])
+	if meta.Published == "" {
+		if tm := doc.find("time"); tm != nil {
+			meta.Published = firstNonEmpty(strings.TrimSpace(tm.attr("datetime")), tm.textContent())
+		}
+	}
+	for _, l := range doc.findAll("link") {
+		if strings.EqualFold(strings.TrimSpace(l.attr("rel")), "canonical") {
+			meta.Canonical = resolveLink(base, l.attr("href"))
+			break
+		}
+	}
+	if meta.Canonical == "" && metas["og:url"] != "" {
+		meta.Canonical = resolveLink(base, metas["og:url"])
+	}
+	return meta
+}
+
+var bylinePattern = regexp.MustCompile(`(?i)\b(byline|author)\b`)
+
+func findByline(doc *htmlNode) string {
+	var byline string
+	doc.walk(func(n *htmlNode) bool {
+		if byline != "" || n.isText() {
+			return false
+		}
+		if strings.EqualFold(n.attr("rel"), "author") || strings.EqualFold(n.attr("itemprop"), "author") ||
+			bylinePattern.MatchString(n.attr("class")) {
+			text := n.textContent()
+			if text != "" && len(text) < 100 {
+				byline = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(text, "By "), "by "))
+			}
+			return false
+		}
+		return true
+	})
+	return byline
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// isNeverContent 任何模式下都丢弃的元素
+func isNeverContent(n *htmlNode) bool {
+	switch n.tag {
+	case "script", "style", "noscript", "template", "iframe", "svg", "canvas", "object", "embed",
+		"head", "button", "select", "input", "textarea", "dialog":
+		return true
+	}
+	if _, hidden := n.attrs["hidden"]; hidden && !n.isText() {
+		return true
+	}
+	return strings.EqualFold(n.attr("aria-hidden"), "true")
+}
+
+var (
+	unlikelyContentPattern = regexp.MustCompile(`(?i)(^|[\s_-])(banner|breadcrumbs?|comments?|cookie|disqus|footer|masthead|menu|modal|nav|navbar|newsletter|pager|pagination|popup|promo|related|share|sharing|sidebar|social|sponsor|subscribe|toolbar|ad|ads|advert|advertisement)([\s_-]|$)`)
+	likelyContentPattern   = regexp.MustCompile(`(?i)(article|body|content|entry|main|page|post|story|text|blog)`)
+)
+
+// isBoilerplate article 模式下丢弃的导航、页脚、侧栏等元素
+func isBoilerplate(n *htmlNode) bool {
+	switch n.tag {
+	case "nav", "footer", "aside":
+		return true
+	case "header":
+		// 文章内的 header 通常包含标题和作者，保留
+		for p := n.parent; p != nil; p = p.parent {
+			if p.tag == "article" || p.tag == "main" {
+				return false
+			}
+		}
+		return true
+	case "body", "article", "main", "#document":
+		return false
+	}
+	switch strings.ToLower(n.attr("role")) {
+	case "navigation", "banner", "contentinfo", "complementary", "dialog", "alert":
+		return true
+	}
+	signature := n.attr("class") + " " + n.attr("id")
+	if strings.TrimSpace(signature) == "" {
+		return false
+	}
+	return unlikelyContentPattern.MatchString(signature) && !likelyContentPattern.MatchString(signature)
+}
+
+func removeNodes(root *htmlNode, match func(*htmlNode) bool) {
+	kept := root.children[:0]
+	for _, c := range root.children {
+		if !c.isText() && match(c) {
+			c.parent = nil
+			continue
+		}
+		kept = append(kept, c)
+	}
+	root.children = kept
+	for _, c := range root.children {
+		if !c.isText() {
+			removeNodes(c, match)
+		}
+	}
+}
+
+// selectArticleContent 按 readability 思路给段落打分，选出正文容器
+func selectArticleContent(body *htmlNode) *htmlNode {
+	removeNodes(body, isBoilerplate)
+
+	// 唯一且足够长的 <article> / <main> 直接作为正文
+	for _, tag := range []string{"article", "main"} {
+		nodes := body.findAll(tag)
+		if len(nodes) == 1 && len(nodes[0].textContent()) >= 200 {
+			return nodes[0]
+		}
+	}
+	for _, n := range body.findAll("div") {
+		if strings.EqualFold(n.attr("role"), "main") && len(n.textContent()) >= 200 {
+			return n
+		}
+	}
+
+	scores := make(map[*htmlNode]float64)
+	var candidates []*htmlNode
+	addScore := func(n *htmlNode, score float64) {
+		if n == nil || n.tag == "#document" {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = classWeight(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+	body.walk(func(n *htmlNode) bool {
+		switch n.tag {
+		case "p", "pre", "td", "blockquote", "li":
+		default:
+			return true
+		}
+		text := n.textContent()
+		if len(text) < 25 {
+			return false
+		}
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，"))
+		score += minFloat(float64(len(text))/100, 3)
+		addScore(n.parent, score)
+		if n.parent != nil {
+			addScore(n.parent.parent, score/2)
+		}
+		return false
+	})
+
+	var best *htmlNode
+	bestScore := 0.0
+	for _, n := range candidates {
+		score := scores[n] * (1 - linkDensity(n))
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	if best == nil || len(best.textContent()) < 200 {
+		return body
+	}
+	// 正文被拆分在多个兄弟容器中时，提升到父节点
+	if parent := best.parent; parent != nil && parent != body.parent {
+		strong := 0
+		for _, sibling := range parent.children {
+			if sibling != best && scores[sibling] >= bestScore*0.5 {
+				strong++
+			}
+		}
+		if strong >= 2 {
+			return parent
+		}
+	}
+	return best
+}
+
+func classWeight(n *htmlNode) float64 {
+	signature := n.attr("class") + " " + n.attr("id")
+	weight := 0.0
+	if likelyContentPattern.MatchString(signature) {
+		weight += 25
+	}
+	if unlikelyContentPattern.MatchString(signature) {
+		weight -= 25
+	}
+	return weight
+}
+
+func linkDensity(n *htmlNode) float64 {
+	total := len(n.textContent())
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	for _, a := range n.findAll("a") {
+		linked += len(a.textContent())
+	}
+	return float64(linked) / float64(total)
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func collectPageLinks(root *htmlNode, base *url.URL) []PageLink {
+	seen := make(map[string]bool)
+	var links []PageLink
+	for _, a := range root.findAll("a") {
+		href := resolveLink(base, a.attr("href"))
+		if href == "" || strings.HasPrefix(strings.TrimSpace(a.attr("href")), "#") || seen[href] {
+			continue
+		}
+		seen[href] = true
+		text := a.textContent()
+		if text == "" {
+			text = firstNonEmpty(a.attr("title"), a.attr("aria-label"), href)
+		}
+		links = append(links, PageLink{Text: text, URL: href})
+	}
+	return links
+}
+
+// dropLeadingTitle 正文首行重复标题时去掉，避免与元信息头重复
+func dropLeadingTitle(markdown, title string) string {
+	trimmed := strings.TrimLeft(markdown, "\n")
+	line := trimmed
+	if idx := strings.IndexByte(trimmed, '\n'); idx >= 0 {
+		line = trimmed[:idx]
+	}
+	if !strings.HasPrefix(line, "# ") || strings.TrimSpace(strings.TrimPrefix(line, "# ")) != strings.TrimSpace(title) {
+		return markdown
+	}
+	return strings.TrimLeft(strings.TrimPrefix(trimmed, line), "\n")
+}
@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleArticleHTML = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Ignored &amp; Title | Site</title>
+  <meta property="og:title" content="Parsing HTML in Go">
+  <meta name="author" content="Jane Doe">
+  <meta property="article:published_time" content="2026-03-01T10:00:00Z">
+  <link rel="canonical" href="/posts/parsing-html">
+  <script>var tracking = "should not appear";</script>
+  <style>.nav { color: red }</style>
+</head>
+<body>
+  <header class="site-header"><a href="/">Home</a> <a href="/about">About</a></header>
+  <nav><ul><li><a href="/a">Menu A</a><li><a href="/b">Menu B</a></ul></nav>
+  <div class="sidebar"><p>Subscribe to our newsletter for more great content, deals and news.</p></div>
+  <div id="content" class="post-body">
+    <h1>Parsing HTML in Go</h1>
+    <p>Extracting the <strong>main content</strong> of a page, with links such as
+      <a href="https://go.dev/doc/">the Go docs</a> and <a href="/relative">a relative page</a>, matters for agents.</p>
+    <p>Readability-style scoring looks at paragraphs, commas, and link density to find the article body.</p>
+    <h2>Example</h2>
+    <pre><code class="language-go">func main() {
+	fmt.Println("hi")
+}</code></pre>
+    <ul>
+      <li>First point
+        <ul><li>Nested point</li></ul>
+      </li>
+      <li>Second point with <code>inline code</code></li>
+    </ul>
+    <table>
+      <thead><tr><th>Name</th><th>Value</th></tr></thead>
+      <tbody><tr><td>alpha</td><td>1 | 2</td></tr><tr><td>beta<td>3</tr></tbody>
+    </table>
+    <blockquote><p>Quoted text.</p></blockquote>
+  </div>
+  <footer class="footer"><p>Copyright 2026. All rights reserved by the example company.</p></footer>
+</body>
+</html>`
+
+func TestExtractHTMLArticle(t *testing.T) {
+	page := ExtractHTML(sampleArticleHTML, HTMLExtractOptions{BaseURL: "https://blog.example.com/posts/x?utm=1"})
+
+	assert.Equal(t, "Parsing HTML in Go", page.Metadata.Title)
+	assert.Equal(t, "Jane Doe", page.Metadata.Byline)
+	assert.Equal(t, "2026-03-01T10:00:00Z", page.Metadata.Published)
+	assert.Equal(t, "https://blog.example.com/posts/parsing-html", page.Metadata.Canonical)
+
+	md := page.Markdown
+	assert.NotContains(t, md, "# Parsing HTML in Go", "title moves to the metadata header")
+	assert.Contains(t, md, "**main content**")
+	assert.Contains(t, md, "[the Go docs](https://go.dev/doc/)")
+	assert.Contains(t, md, "[a relative page](https://blog.example.com/relative)")
+	assert.Contains(t, md, "## Example")
+	assert.Contains(t, md, "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```")
+	assert.Contains(t, md, "- First point\n  - Nested point\n- Second point with `inline code`")
+	assert.Contains(t, md, "| Name | Value |\n| --- | --- |\n| alpha | 1 \\| 2 |\n| beta | 3 |")
+	assert.Contains(t, md, "> Quoted text.")
+
+	for _, noise := range []string{"tracking", "Menu A", "newsletter", "Copyright", "color: red"} {
+		assert.NotContains(t, md, noise)
+	}
+
+	formatted := page.Format()
+	assert.True(t, strings.HasPrefix(formatted, "Title: Parsing HTML in Go\nByline: Jane Doe\nPublished: 2026-03-01T10:00:00Z\nURL: https://blog.example.com/posts/parsing-html\n\n"))
+}
+
+func TestExtractHTMLFullKeepsNavigation(t *testing.T) {
+	page := ExtractHTML(sampleArticleHTML, HTMLExtractOptions{Mode: ExtractFull, BaseURL: "https://blog.example.com/"})
+	assert.Contains(t, page.Markdown, "[Menu A](https://blog.example.com/a)")
+	assert.Contains(t, page.Markdown, "Copyright 2026")
+	assert.NotContains(t, page.Markdown, "tracking")
+}
+
+func TestExtractHTMLLinksAndReferences(t *testing.T) {
+	page := ExtractHTML(sampleArticleHTML, HTMLExtractOptions{Mode: ExtractLinks, BaseURL: "https://blog.example.com/"})
+	require.NotEmpty(t, page.Links)
+	assert.Equal(t, PageLink{Text: "Home", URL: "https://blog.example.com/"}, page.Links[0])
+	assert.Contains(t, page.Markdown, "1. [Home](https://blog.example.com/)")
+	assert.Contains(t, page.Markdown, "[the Go docs](https://go.dev/doc/)")
+
+	refs := ExtractHTML(`<body><p>See <a href="https://a.example/x">one</a>, <a href="https://b.example/">two</a>
+		and <a href="https://a.example/x">one again</a>. <a href="javascript:void(0)">js</a> <a href="#top">top</a></p></body>`,
+		HTMLExtractOptions{Mode: ExtractFull, ReferenceLinks: true})
+	assert.Equal(t, "See [one][1], [two][2] and [one again][1]. js top\n\n[1]: https://a.example/x\n[2]: https://b.example/", refs.Markdown)
+}
+
+func TestParseHTMLToleratesMalformedMarkup(t *testing.T) {
+	doc := parseHTML(`<div><p>one<p>two <b>bold <i>both</b> tail</i><br>x</div><p>after<!-- c --> <img src=/a.png alt="A > B">`)
+	r := &markdownRenderer{}
+	assert.Equal(t, "one\n\ntwo **bold *both*** tail\nx\n\nafter ![A > B](/a.png)", r.render(doc))
+}
+
+func TestParseHTMLBoundsNestingDepth(t *testing.T) {
+	doc := parseHTML(strings.Repeat("<div>", 2_000_000) + "<p>deep text</p>")
+	depth := 0
+	for n := doc; len(n.children) > 0; n = n.children[0] {
+		depth++
+	}
+	assert.LessOrEqual(t, depth, maxHTMLDepth+2)
+
+	page := ExtractHTML(strings.Repeat("<div><span>", 1_000_000)+"<p>deep text</p>", HTMLExtractOptions{Mode: ExtractFull})
+	assert.Equal(t, "deep text", page.Markdown)
+}
+
+func TestParseHTMLRawTextIsLinear(t *testing.T) {
+	src := strings.Repeat("<SCRIPT>var x = 1;</Script><p>keep</p>", 50_000)
+	start := time.Now()
+	doc := parseHTML(src)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Len(t, doc.findAll("script"), 50_000)
+	assert.Equal(t, "var x = 1;", doc.find("script").textContent())
+}
+
+func TestWebFetchToolExtractParam(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(sampleArticleHTML))
+	}))
+	defer server.Close()
+
+	tool := NewWebFetchTool(WebFetchOptions{Mode: "http"})
+	out, err := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL + "/posts/x"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "Title: Parsing HTML in Go")
+	assert.Contains(t, out, "[a relative page]("+server.URL+"/relative)")
+	assert.NotContains(t, out, "Menu A")
+
+	out, err = tool.Execute(context.Background(), map[string]interface{}{"url": server.URL, "extract": "links"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "[Menu A]("+server.URL+"/a)")
+
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"url": server.URL, "extract": "summary"})
+	assert.ErrorContains(t, err, "unsupported extract mode")
+}
@@ -82,6 +82,27 @@ func (r *Registry) GetDefinitions() []map[string]interface{} {
 	return definitions
 }
 
+// GetDefinitionsFiltered 获取通过 allow 过滤的工具定义（allow 为 nil 时返回全部）
+func (r *Registry) GetDefinitionsFiltered(allow func(name string) bool) []map[string]interface{} {
+	if allow == nil {
+		return r.GetDefinitions()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	definitions := make([]map[string]interface{}, 0, len(r.tools))
+	for name, tool := range r.tools {
+		if !allow(name) {
+			continue
+		}
+		if st, ok := tool.(schemaTool); ok {
+			definitions = append(definitions, st.ToOpenAISchema())
+		}
+	}
+	return definitions
+}
+
 // List 列出所有工具名称
 func (r *Registry) List() []string {
 	r.mu.RLock()
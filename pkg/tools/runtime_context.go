@@ -1,6 +1,11 @@
 package tools
 
-import "context"
+import (
+	"context"
+	"strings"
+
+	"github.com/Lichas/maxclaw/internal/logging"
+)
 
 type runtimeContextKey string
 
@@ -8,6 +13,11 @@ const (
 	runtimeChannelKey runtimeContextKey = "channel"
 	runtimeChatIDKey  runtimeContextKey = "chat_id"
 	runtimeSessionKey runtimeContextKey = "session_key"
+	runtimeWorkspace  runtimeContextKey = "workspace"
+	runtimeRestrict   runtimeContextKey = "restrict_to_workspace"
+	runtimeSender     runtimeContextKey = "sender"
+	runtimeExecMode   runtimeContextKey = "execution_mode"
+	runtimeMessage    runtimeContextKey = "message"
 )
 
 // WithRuntimeContext injects channel/chat metadata for tools in the current request.
@@ -16,7 +26,9 @@ func WithRuntimeContext(ctx context.Context, channel, chatID string) context.Con
 }
 
 // WithRuntimeContextWithSession injects channel/chat/session metadata for tools in the current request.
+// The same IDs are attached to every structured log line written with this context.
 func WithRuntimeContextWithSession(ctx context.Context, channel, chatID, sessionKey string) context.Context {
+	ctx = logging.WithSession(ctx, channel, chatID, sessionKey)
 	ctx = context.WithValue(ctx, runtimeChannelKey, channel)
 	ctx = context.WithValue(ctx, runtimeChatIDKey, chatID)
 	ctx = context.WithValue(ctx, runtimeSessionKey, sessionKey)
@@ -49,3 +61,77 @@ func RuntimeSessionKeyFrom(ctx context.Context) string {
 	}
 	return ""
 }
+
+// WithRuntimeWorkspace injects the per-agent workspace, overriding the global
+// workspace/allowed directory for tools executed with the returned context.
+func WithRuntimeWorkspace(ctx context.Context, workspace string, restrictToWorkspace bool) context.Context {
+	ctx = context.WithValue(ctx, runtimeWorkspace, strings.TrimSpace(workspace))
+	ctx = context.WithValue(ctx, runtimeRestrict, restrictToWorkspace)
+	return ctx
+}
+
+// RuntimeWorkspaceFrom extracts the per-agent workspace metadata from context.
+func RuntimeWorkspaceFrom(ctx context.Context) (workspace string, restrictToWorkspace bool) {
+	if ctx == nil {
+		return "", false
+	}
+
+	if v, ok := ctx.Value(runtimeWorkspace).(string); ok {
+		workspace = v
+	}
+	if v, ok := ctx.Value(runtimeRestrict).(bool); ok {
+		restrictToWorkspace = v
+	}
+	return workspace, restrictToWorkspace
+}
+
+// WithRuntimeSender injects the inbound sender ID for policy decisions.
+func WithRuntimeSender(ctx context.Context, sender string) context.Context {
+	return context.WithValue(ctx, runtimeSender, strings.TrimSpace(sender))
+}
+
+// RuntimeSenderFrom extracts the inbound sender ID from context.
+func RuntimeSenderFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	if v, ok := ctx.Value(runtimeSender).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// WithRuntimeMessage injects the inbound user message that triggered the current tool calls.
+func WithRuntimeMessage(ctx context.Context, content string) context.Context {
+	return context.WithValue(ctx, runtimeMessage, content)
+}
+
+// RuntimeMessageFrom extracts the inbound user message from context.
+func RuntimeMessageFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	if v, ok := ctx.Value(runtimeMessage).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// WithRuntimeExecutionMode injects the agent execution mode (safe/ask/auto) for the current request.
+func WithRuntimeExecutionMode(ctx context.Context, mode string) context.Context {
+	return context.WithValue(ctx, runtimeExecMode, strings.ToLower(strings.TrimSpace(mode)))
+}
+
+// RuntimeExecutionModeFrom extracts the execution mode from context.
+func RuntimeExecutionModeFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	if v, ok := ctx.Value(runtimeExecMode).(string); ok {
+		return v
+	}
+	return ""
+}
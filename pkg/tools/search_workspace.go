@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Lichas/maxclaw/internal/rag"
+)
+
+const (
+	defaultWorkspaceSearchLimit = 5
+	maxWorkspaceSearchLimit     = 20
+	workspaceSnippetChars       = 600
+)
+
+// SearchWorkspaceTool 工作区检索工具：调用前增量更新索引，再做 BM25 + 向量混合排序
+type SearchWorkspaceTool struct {
+	BaseTool
+	options rag.Options
+
+	mu      sync.Mutex
+	indexes map[string]*rag.Index
+}
+
+// NewSearchWorkspaceTool 创建工作区检索工具；options.Embedder 为空时只做关键词检索
+func NewSearchWorkspaceTool(options rag.Options) *SearchWorkspaceTool {
+	return &SearchWorkspaceTool{
+		BaseTool: BaseTool{
+			name: "search_workspace",
+			description: "Search files in the workspace (notes, docs, code, PDFs and Office files) by keywords and meaning. " +
+				"Returns the best matching passages with file paths and line numbers; use read_file to open them. " +
+				"Prefer this over exec grep/find when you don't know where something is.",
+			parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "What to look for, in natural language or keywords",
+						"minLength":   1,
+						"maxLength":   500,
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of passages to return (1-20, default 5)",
+						"minimum":     1,
+						"maximum":     maxWorkspaceSearchLimit,
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Only search under this workspace-relative directory or file",
+					},
+					"mode": map[string]interface{}{
+						"type":        "string",
+						"description": "hybrid (default), keyword or semantic",
+						"enum":        []string{rag.ModeHybrid, rag.ModeKeyword, rag.ModeSemantic},
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+		options: options,
+		indexes: make(map[string]*rag.Index),
+	}
+}
+
+// Execute 执行检索
+func (t *SearchWorkspaceTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	query, _ := params["query"].(string)
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+	limit := defaultWorkspaceSearchLimit
+	if v, ok := params["limit"].(float64); ok && v >= 1 {
+		limit = min(int(v), maxWorkspaceSearchLimit)
+	}
+	scope, _ := params["path"].(string)
+	mode, _ := params["mode"].(string)
+
+	workspace, _ := RuntimeWorkspaceFrom(ctx)
+	if workspace == "" {
+		workspace = GetWorkspaceDir()
+	}
+	if workspace == "" {
+		return "", fmt.Errorf("workspace is not configured")
+	}
+	index, err := t.index(workspace)
+	if err != nil {
+		return "", err
+	}
+
+	var notes []string
+	stats, err := index.Update(ctx)
+	if err != nil {
+		if !errors.Is(err, rag.ErrEmbedding) {
+			return "", fmt.Errorf("failed to update workspace index: %w", err)
+		}
+		// 嵌入失败时文本索引仍可用
+		notes = append(notes, err.Error())
+	}
+	if stats.Truncated {
+		notes = append(notes, "workspace has more files than the index limit; some files are not searchable")
+	}
+
+	results, note, err := index.Search(ctx, query, rag.SearchOptions{Limit: limit, Path: scope, Mode: mode})
+	if err != nil {
+		return "", err
+	}
+	if note != "" {
+		notes = append(notes, note)
+	}
+
+	var sb strings.Builder
+	if len(results) == 0 {
+		sb.WriteString("No matches in the workspace for: " + query + "\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("Workspace matches for: %s\n\n", query))
+		for i, r := range results {
+			sb.WriteString(fmt.Sprintf("%d. %s", i+1, r.Path))
+			if r.Anchor != "" {
+				// 文档片段以页/章节定位
+				sb.WriteString(" — " + r.Anchor + "\n")
+			} else {
+				sb.WriteString(fmt.Sprintf(" (lines %d-%d)\n", r.StartLine, r.EndLine))
+			}
+			sb.WriteString(indentSnippet(r.Text, workspaceSnippetChars))
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString("Open a match with read_file (offset = start line - 1 for text files, page offset for documents).\n")
+	}
+	for _, n := range notes {
+		sb.WriteString("Note: " + n + "\n")
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+func (t *SearchWorkspaceTool) index(workspace string) (*rag.Index, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if idx, ok := t.indexes[workspace]; ok {
+		return idx, nil
+	}
+	idx, err := rag.Open(workspace, t.options)
+	if err != nil {
+		return nil, err
+	}
+	t.indexes[workspace] = idx
+	return idx, nil
+}
+
+// indentSnippet 截取片段并缩进，便于与结果标题区分
+func indentSnippet(text string, maxChars int) string {
+	text = strings.TrimSpace(text)
+	if len(text) > maxChars {
+		cut := maxChars
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = strings.TrimSpace(text[:cut]) + " ..."
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = "   " + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lichas/maxclaw/internal/rag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchWorkspaceToolIndexesRuntimeWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workspace, "notes"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "notes", "travel.md"),
+		[]byte("# Trip\n\nFlight to Lisbon departs at 09:40 from terminal 2.\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "todo.txt"), []byte("buy milk\n"), 0o644))
+
+	tool := NewSearchWorkspaceTool(rag.Options{IndexDir: t.TempDir()})
+	ctx := WithRuntimeWorkspace(context.Background(), workspace, true)
+
+	out, err := tool.Execute(ctx, map[string]interface{}{"query": "lisbon flight"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "1. notes/travel.md (lines 1-3)")
+	assert.Contains(t, out, "Flight to Lisbon")
+	assert.NotContains(t, out, "todo.txt")
+	// 未配置嵌入时提示只做了关键词检索
+	assert.Contains(t, out, "Note: semantic search is not configured")
+
+	// 新文件在下次调用时自动进入索引
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "packing.md"), []byte("Lisbon: pack sunscreen\n"), 0o644))
+	out, err = tool.Execute(ctx, map[string]interface{}{"query": "sunscreen", "mode": "keyword"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "packing.md")
+
+	out, err = tool.Execute(ctx, map[string]interface{}{"query": "nonexistent-term", "mode": "keyword"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "No matches in the workspace")
+
+	_, err = tool.Execute(ctx, map[string]interface{}{"query": " "})
+	assert.Error(t, err)
+}
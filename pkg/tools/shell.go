@@ -3,7 +3,6 @@ package tools
 import (
 	"context"
 	"fmt"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -46,10 +45,16 @@ type ExecTool struct {
 	WorkingDir          string
 	Timeout             time.Duration
 	RestrictToWorkspace bool
+	Sandbox             ExecSandboxOptions
 }
 
-// NewExecTool 创建 Shell 执行工具
+// NewExecTool 创建 Shell 执行工具（host 后端）
 func NewExecTool(workingDir string, timeout int, restrictToWorkspace bool) *ExecTool {
+	return NewExecToolWithSandbox(workingDir, timeout, restrictToWorkspace, ExecSandboxOptions{})
+}
+
+// NewExecToolWithSandbox 创建使用指定执行后端的 Shell 执行工具
+func NewExecToolWithSandbox(workingDir string, timeout int, restrictToWorkspace bool, sandbox ExecSandboxOptions) *ExecTool {
 	if timeout <= 0 {
 		timeout = 60
 	}
@@ -78,6 +83,7 @@ func NewExecTool(workingDir string, timeout int, restrictToWorkspace bool) *Exec
 		WorkingDir:          workingDir,
 		Timeout:             time.Duration(timeout) * time.Second,
 		RestrictToWorkspace: restrictToWorkspace,
+		Sandbox:             sandbox,
 	}
 
 	return tool
@@ -95,9 +101,25 @@ func (t *ExecTool) Execute(ctx context.Context, params map[string]interface{}) (
 		return "", err
 	}
 
+	backend, err := NewExecBackend(ResolveExecBackendName(t.Sandbox, RuntimeExecutionModeFrom(ctx)))
+	if err != nil {
+		return "", err
+	}
+	if err := backend.Available(); err != nil {
+		return "", fmt.Errorf("exec backend %s unavailable: %w", backend.Name(), err)
+	}
+
 	// 确定工作目录
 	workDir := t.WorkingDir
-	if t.RestrictToWorkspace && workDir != "" {
+	if backend.Sandboxed() && workDir != "" {
+		// 沙箱后端通过挂载限制可写范围，需要绝对路径
+		abs, err := cleanAbsPath(workDir)
+		if err != nil {
+			return "", fmt.Errorf("invalid workspace: %w", err)
+		}
+		workDir = abs
+	}
+	if t.RestrictToWorkspace && workDir != "" && !backend.Sandboxed() {
 		workspace, err := cleanAbsPath(workDir)
 		if err != nil {
 			return "", fmt.Errorf("invalid workspace: %w", err)
@@ -125,18 +147,27 @@ func (t *ExecTool) Execute(ctx context.Context, params map[string]interface{}) (
 	defer cancel()
 
 	// 执行命令
-	cmd := exec.CommandContext(execCtx, "sh", "-c", command)
-	if workDir != "" {
-		cmd.Dir = workDir
+	cmd, cleanup, err := backend.Prepare(execCtx, ExecRequest{
+		Command:  command,
+		WorkDir:  workDir,
+		Network:  t.Sandbox.networkFor(backend),
+		CPUs:     t.Sandbox.CPUs,
+		MemoryMB: t.Sandbox.MemoryMB,
+		Image:    t.Sandbox.Image,
+	})
+	if err != nil {
+		return "", err
 	}
 
-	output, err := cmd.CombinedOutput()
+	// 截断输出（默认限制 10KB）
+	output := &cappedBuffer{limit: t.Sandbox.maxOutput()}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	err = cmd.Run()
+	outputStr := output.String()
 
-	// 截断输出（限制 10KB）
-	maxOutputSize := 10 * 1024
-	outputStr := string(output)
-	if len(outputStr) > maxOutputSize {
-		outputStr = outputStr[:maxOutputSize] + "\n... (output truncated)"
+	if execCtx.Err() != nil && cleanup != nil {
+		cleanup()
 	}
 
 	if err != nil {
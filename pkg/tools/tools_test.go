@@ -1,9 +1,13 @@
 package tools
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -224,6 +228,47 @@ func TestReadFileTool(t *testing.T) {
 	})
 }
 
+func TestReadFileToolExtractsDocuments(t *testing.T) {
+	tmpDir := t.TempDir()
+	SetAllowedDir(tmpDir)
+	SetWorkspaceDir(tmpDir)
+	t.Cleanup(func() {
+		SetAllowedDir("")
+		SetWorkspaceDir("")
+	})
+
+	var body strings.Builder
+	for i := 1; i <= 3; i++ {
+		if i > 1 {
+			body.WriteString(`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`)
+		}
+		fmt.Fprintf(&body, `<w:p><w:r><w:t>Content of page %d</w:t></w:r></w:p>`, i)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("word/document.xml")
+	require.NoError(t, err)
+	_, err = w.Write([]byte(`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		body.String() + `</w:body></w:document>`))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	docPath := filepath.Join(tmpDir, "notes.docx")
+	require.NoError(t, os.WriteFile(docPath, buf.Bytes(), 0644))
+
+	tool := NewReadFileTool()
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"path": docPath, "limit": 1})
+	require.NoError(t, err)
+	assert.Equal(t, "[DOCX document, 3 pages]\n\n## Page 1\n\nContent of page 1\n\n... (showing pages 1-1 of 3; use offset=1 to continue)", result)
+
+	result, err = tool.Execute(context.Background(), map[string]interface{}{"path": docPath, "offset": float64(1)})
+	require.NoError(t, err)
+	assert.Contains(t, result, "## Page 2\n\nContent of page 2\n\n## Page 3\n\nContent of page 3")
+	assert.NotContains(t, result, "page 1")
+
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"path": docPath, "offset": 3})
+	assert.ErrorContains(t, err, "exceeds document length")
+}
+
 func TestWriteFileTool(t *testing.T) {
 	tmpDir := t.TempDir()
 	SetAllowedDir(tmpDir)
@@ -527,7 +572,7 @@ func TestMessageTool(t *testing.T) {
 	})
 }
 
-func TestExtractTextFromHTML(t *testing.T) {
+func TestExtractHTMLStripsScriptsAndStyles(t *testing.T) {
 	html := `<html>
 		<head><script>alert('test');</script><style>body{color:red}</style></head>
 		<body>
@@ -537,7 +582,7 @@ func TestExtractTextFromHTML(t *testing.T) {
 		</body>
 	</html>`
 
-	result := extractTextFromHTML(html)
+	result := ExtractHTML(html, HTMLExtractOptions{Mode: ExtractFull}).Format()
 	assert.Contains(t, result, "Title")
 	assert.Contains(t, result, "This is a paragraph.")
 	assert.Contains(t, result, "Another block")
@@ -545,3 +590,32 @@ func TestExtractTextFromHTML(t *testing.T) {
 	assert.NotContains(t, result, "alert")
 	assert.NotContains(t, result, "<style>")
 }
+
+func TestEditFileToolAmbiguousMatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	SetAllowedDir(tmpDir)
+	t.Cleanup(func() { SetAllowedDir("") })
+
+	testFile := filepath.Join(tmpDir, "dup.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("foo\nbar\nfoo\n"), 0644))
+	tool := NewEditFileTool()
+
+	_, err := tool.Execute(context.Background(), map[string]interface{}{
+		"path":       testFile,
+		"old_string": "foo",
+		"new_string": "baz",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 matches at lines 1, 3")
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{
+		"path":        testFile,
+		"old_string":  "foo",
+		"new_string":  "baz",
+		"replace_all": true,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result, "-foo")
+	content, _ := os.ReadFile(testFile)
+	assert.Equal(t, "baz\nbar\nbaz\n", string(content))
+}
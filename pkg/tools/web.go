@@ -6,125 +6,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 )
 
-// WebSearchTool 网页搜索工具
-type WebSearchTool struct {
-	BaseTool
-	APIKey     string
-	MaxResults int
-}
-
-// NewWebSearchTool 创建网页搜索工具
-func NewWebSearchTool(apiKey string, maxResults int) *WebSearchTool {
-	if maxResults <= 0 {
-		maxResults = 5
-	}
-
-	return &WebSearchTool{
-		BaseTool: BaseTool{
-			name:        "web_search",
-			description: "Search the web using Brave Search API. Use for finding current information, news, or research topics.",
-			parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"query": map[string]interface{}{
-						"type":        "string",
-						"description": "Search query",
-						"minLength":   1,
-						"maxLength":   500,
-					},
-					"count": map[string]interface{}{
-						"type":        "integer",
-						"description": "Number of results to return (1-10)",
-						"minimum":     1,
-						"maximum":     10,
-					},
-				},
-				"required": []string{"query"},
-			},
-		},
-		APIKey:     apiKey,
-		MaxResults: maxResults,
-	}
-}
-
-// Execute 执行网页搜索
-func (t *WebSearchTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
-	query, _ := params["query"].(string)
-	if query == "" {
-		return "", fmt.Errorf("query is required")
-	}
-
-	if t.APIKey == "" {
-		return "", fmt.Errorf("web search API key not configured")
-	}
-
-	count := t.MaxResults
-	if v, ok := params["count"].(float64); ok {
-		c := int(v)
-		if c > 0 && c <= 10 {
-			count = c
-		}
-	}
-
-	// Brave Search API
-	apiURL := fmt.Sprintf("https://api.search.brave.com/res/v1/web/search?q=%s&count=%d",
-		url.QueryEscape(query), count)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("X-Subscription-Token", t.APIKey)
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("search request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("search API returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var result struct {
-		Web struct {
-			Results []struct {
-				Title string `json:"title"`
-				URL   string `json:"url"`
-				Desc  string `json:"description"`
-			}
-		} `json:"web"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to parse search result: %w", err)
-	}
-
-	if len(result.Web.Results) == 0 {
-		return "No results found for: " + query, nil
-	}
-
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("Search results for: %s\n\n", query))
-	for i, r := range result.Web.Results {
-		sb.WriteString(fmt.Sprintf("%d. %s\n   URL: %s\n   %s\n\n",
-			i+1, r.Title, r.URL, r.Desc))
-	}
-
-	return sb.String(), nil
-}
-
 // WebFetchTool 网页抓取工具
 type WebFetchTool struct {
 	BaseTool
@@ -170,6 +57,8 @@ const (
 	defaultChromeProfileName     = "chrome"
 	defaultChromeChannel         = "chrome"
 	defaultChromeLaunchTimeoutMs = 15000
+	// maxWebFetchBodySize HTTP 模式读取的响应体上限，超出部分丢弃
+	maxWebFetchBodySize = 10 << 20
 )
 
 // NewWebFetchTool 创建网页抓取工具
@@ -178,7 +67,7 @@ func NewWebFetchTool(options WebFetchOptions) *WebFetchTool {
 	return &WebFetchTool{
 		BaseTool: BaseTool{
 			name:        "web_fetch",
-			description: "Fetch and extract text content from a web page. Use for reading documentation, articles, or any web content.",
+			description: "Fetch a web page and return its main content as Markdown (headings, links, tables and code blocks preserved) with title, byline, published date and canonical URL. Use for reading documentation, articles, or any web content.",
 			parameters: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -228,6 +117,15 @@ func NewWebFetchTool(options WebFetchOptions) *WebFetchTool {
 						"type":        "string",
 						"description": "Wait until page text no longer contains this string",
 					},
+					"extract": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{ExtractArticle, ExtractFull, ExtractLinks},
+						"description": "article (default): main content as Markdown without navigation/footer; full: whole page as Markdown; links: list of page links",
+					},
+					"link_references": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Render links as numbered references [text][n] listed at the end instead of inline",
+					},
 				},
 				"required": []string{"url"},
 			},
@@ -251,6 +149,15 @@ func (t *WebFetchTool) Execute(ctx context.Context, params map[string]interface{
 		}
 	}
 
+	if _, err := resolveHTMLExtractOptions(params); err != nil {
+		return "", err
+	}
+	if guard := EgressGuardFrom(ctx); guard != nil {
+		if err := guard.CheckURL(ctx, t.name, fetchURL); err != nil {
+			return "", err
+		}
+	}
+
 	mode := strings.ToLower(strings.TrimSpace(t.options.Mode))
 	if mode == "" {
 		mode = "http"
@@ -276,8 +183,9 @@ func (t *WebFetchTool) executeHTTPFetch(ctx context.Context, fetchURL string, ma
 
 	req.Header.Set("User-Agent", t.options.UserAgent)
 
+	timeout := time.Duration(resolveWebFetchTimeoutSec(params, t.options.TimeoutSec)) * time.Second
 	client := &http.Client{
-		Timeout: time.Duration(resolveWebFetchTimeoutSec(params, t.options.TimeoutSec)) * time.Second,
+		Timeout: timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= 5 {
 				return fmt.Errorf("too many redirects")
@@ -285,6 +193,9 @@ func (t *WebFetchTool) executeHTTPFetch(ctx context.Context, fetchURL string, ma
 			return nil
 		},
 	}
+	if guard := EgressGuardFrom(ctx); guard != nil {
+		client = guard.HTTPClient(t.name, timeout)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -299,23 +210,29 @@ func (t *WebFetchTool) executeHTTPFetch(ctx context.Context, fetchURL string, ma
 	contentType := resp.Header.Get("Content-Type")
 	if strings.Contains(contentType, "application/json") {
 		// JSON content
-		body, err := io.ReadAll(resp.Body)
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxWebFetchBodySize))
 		if err != nil {
 			return "", fmt.Errorf("failed to read body: %w", err)
 		}
 		return truncateText(string(body), maxLength), nil
 	}
 
-	// HTML content - simple text extraction
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWebFetchBodySize))
 	if err != nil {
 		return "", fmt.Errorf("failed to read body: %w", err)
 	}
+	if !looksLikeHTML(contentType, body) {
+		return truncateText(strings.TrimSpace(string(body)), maxLength), nil
+	}
 
-	// Simple HTML to text conversion
-	text := extractTextFromHTML(string(body))
-
-	return truncateText(text, maxLength), nil
+	opts, _ := resolveHTMLExtractOptions(params)
+	opts.BaseURL = resp.Request.URL.String()
+	page := ExtractHTML(string(body), opts)
+	if strings.TrimSpace(page.Markdown) == "" {
+		// 空内容交给 auto 模式回退到浏览器
+		return "", nil
+	}
+	return truncateText(page.Format(), maxLength), nil
 }
 
 func (t *WebFetchTool) executeBrowserFetch(ctx context.Context, fetchURL string, maxLength int, mode string, params map[string]interface{}) (string, error) {
@@ -350,6 +267,16 @@ func (t *WebFetchTool) executeBrowserFetch(ctx context.Context, fetchURL string,
 		WaitForText:     resolveWebFetchStringOption(params, "wait_for_text", t.options.WaitForText),
 		WaitForNoText:   resolveWebFetchStringOption(params, "wait_for_no_text", t.options.WaitForNoText),
 	}
+	guard := EgressGuardFrom(ctx)
+	if guard != nil {
+		// 浏览器的所有请求（重定向、子资源）经本地代理逐个检查
+		proxy, err := guard.StartBrowserProxy(t.name)
+		if err != nil {
+			return "", err
+		}
+		defer proxy.Close()
+		req.Proxy = proxy.URL()
+	}
 	if mode == "chrome" {
 		req.Chrome = &browserChromeRequest{
 			CDPEndpoint:      t.options.Chrome.CDPEndpoint,
@@ -362,6 +289,9 @@ func (t *WebFetchTool) executeBrowserFetch(ctx context.Context, fetchURL string,
 			HostUserDataDir:  t.options.Chrome.HostUserDataDir,
 			LaunchTimeoutMs:  t.options.Chrome.LaunchTimeoutMs,
 		}
+		if guard != nil {
+			req.Chrome.withoutCDP()
+		}
 	}
 	payload, err := json.Marshal(req)
 	if err != nil {
@@ -383,6 +313,24 @@ func (t *WebFetchTool) executeBrowserFetch(ctx context.Context, fetchURL string,
 		}
 		return "", fmt.Errorf("browser fetch error: %s", result.Error)
 	}
+	// 最终地址再检查一次，落在受限地址上的页面内容不返回
+	if guard != nil && strings.TrimSpace(result.URL) != "" {
+		if err := guard.CheckURL(ctx, t.name, result.URL); err != nil {
+			return "", err
+		}
+	}
+
+	if strings.TrimSpace(result.HTML) != "" {
+		opts, _ := resolveHTMLExtractOptions(params)
+		opts.BaseURL = firstNonEmpty(result.URL, fetchURL)
+		page := ExtractHTML(result.HTML, opts)
+		if strings.TrimSpace(page.Markdown) != "" {
+			if page.Metadata.Title == "" {
+				page.Metadata.Title = result.Title
+			}
+			return truncateText(page.Format(), maxLength), nil
+		}
+	}
 
 	text := strings.TrimSpace(result.Text)
 	if result.Title != "" {
@@ -396,6 +344,10 @@ func (t *WebFetchTool) executeAutoFetch(ctx context.Context, fetchURL string, ma
 	if httpErr == nil && !shouldFallbackToBrowserFetch(httpText) {
 		return httpText, nil
 	}
+	// 被出站策略拦截（如重定向到内网）时不能改用浏览器重试
+	if IsEgressBlocked(httpErr) {
+		return "", httpErr
+	}
 
 	chromeText, chromeErr := t.executeBrowserFetch(ctx, fetchURL, maxLength, "chrome", params)
 	if chromeErr == nil {
@@ -435,6 +387,7 @@ type browserFetchRequest struct {
 	WaitForSelector string                `json:"waitForSelector,omitempty"`
 	WaitForText     string                `json:"waitForText,omitempty"`
 	WaitForNoText   string                `json:"waitForNoText,omitempty"`
+	Proxy           string                `json:"proxy,omitempty"`
 	Chrome          *browserChromeRequest `json:"chrome,omitempty"`
 }
 
@@ -450,11 +403,22 @@ type browserChromeRequest struct {
 	LaunchTimeoutMs  int    `json:"launchTimeoutMs,omitempty"`
 }
 
+// withoutCDP 出站守卫生效时不接管 CDP 浏览器（无法为其设置代理），改为启动经守卫代理出站的托管 profile
+func (c *browserChromeRequest) withoutCDP() {
+	if c == nil {
+		return
+	}
+	c.CDPEndpoint = ""
+	c.AutoStartCDP = false
+	c.TakeoverExisting = false
+}
+
 type browserFetchResult struct {
 	OK    bool   `json:"ok"`
 	URL   string `json:"url,omitempty"`
 	Title string `json:"title,omitempty"`
 	Text  string `json:"text,omitempty"`
+	HTML  string `json:"html,omitempty"`
 	Error string `json:"error,omitempty"`
 }
 
@@ -624,97 +588,26 @@ func truncateText(text string, maxLength int) string {
 	return text[:maxLength] + "\n\n... (content truncated)"
 }
 
-// extractTextFromHTML 简单的 HTML 到文本提取
-func extractTextFromHTML(html string) string {
-	// 移除 script 和 style 标签及其内容
-	html = removeTag(html, "script")
-	html = removeTag(html, "style")
-
-	// 替换常见标签为换行
-	replacements := map[string]string{
-		"</p>":   "\n\n",
-		"<br>":   "\n",
-		"<br/>":  "\n",
-		"<br />": "\n",
-		"</div>": "\n",
-		"</h1>":  "\n\n",
-		"</h2>":  "\n\n",
-		"</h3>":  "\n\n",
-		"</h4>":  "\n\n",
-		"</h5>":  "\n\n",
-		"</h6>":  "\n\n",
-		"</li>":  "\n",
-	}
-
-	for tag, replacement := range replacements {
-		html = strings.ReplaceAll(html, tag, replacement)
-	}
-
-	// 移除所有其他 HTML 标签
-	inTag := false
-	var result strings.Builder
-	for _, r := range html {
-		if r == '<' {
-			inTag = true
-			continue
-		}
-		if r == '>' {
-			inTag = false
-			continue
-		}
-		if !inTag {
-			result.WriteRune(r)
-		}
-	}
-
-	// 规范化空白字符
-	text := result.String()
-	text = strings.ReplaceAll(text, "&nbsp;", " ")
-	text = strings.ReplaceAll(text, "&lt;", "<")
-	text = strings.ReplaceAll(text, "&gt;", ">")
-	text = strings.ReplaceAll(text, "&amp;", "&")
-	text = strings.ReplaceAll(text, "&quot;", `"`)
-
-	// 合并多个换行
-	for strings.Contains(text, "\n\n\n") {
-		text = strings.ReplaceAll(text, "\n\n\n", "\n\n")
+// resolveHTMLExtractOptions 读取 extract / link_references 参数
+func resolveHTMLExtractOptions(params map[string]interface{}) (HTMLExtractOptions, error) {
+	raw, _ := params["extract"].(string)
+	mode, err := NormalizeExtractMode(raw)
+	if err != nil {
+		return HTMLExtractOptions{}, err
 	}
-
-	return strings.TrimSpace(text)
+	refs, _ := params["link_references"].(bool)
+	return HTMLExtractOptions{Mode: mode, ReferenceLinks: refs}, nil
 }
 
-// removeTag 移除指定标签及其内容
-func removeTag(html, tag string) string {
-	startTag := "<" + tag
-	endTag := "</" + tag + ">"
-
-	for {
-		start := strings.Index(strings.ToLower(html), startTag)
-		if start == -1 {
-			break
-		}
-
-		end := strings.Index(html[start:], ">")
-		if end == -1 {
-			break
-		}
-		end += start + 1
-
-		// 检查是否是自闭合标签
-		if html[end-2:end-1] == "/" {
-			html = html[:start] + html[end:]
-			continue
-		}
-
-		// 查找结束标签
-		endStart := strings.Index(strings.ToLower(html[end:]), endTag)
-		if endStart == -1 {
-			break
-		}
-		endStart += end
-
-		html = html[:start] + html[endStart+len(endTag):]
+// looksLikeHTML 根据 Content-Type 或内容开头判断是否为 HTML
+func looksLikeHTML(contentType string, body []byte) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "html") {
+		return true
 	}
-
-	return html
+	if contentType != "" && !strings.HasPrefix(contentType, "application/octet-stream") {
+		return false
+	}
+	head := strings.ToLower(strings.TrimSpace(string(body[:min(len(body), 512)])))
+	return strings.HasPrefix(head, "<!doctype html") || strings.HasPrefix(head, "<html") || strings.Contains(head, "<body")
 }
@@ -0,0 +1,771 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 搜索后端名称
+const (
+	SearchProviderBrave      = "brave"
+	SearchProviderSearXNG    = "searxng"
+	SearchProviderTavily     = "tavily"
+	SearchProviderBing       = "bing"
+	SearchProviderGoogle     = "google"
+	SearchProviderSerper     = "serper"
+	SearchProviderDuckDuckGo = "duckduckgo"
+)
+
+const (
+	defaultWebSearchTimeoutSec = 10
+	defaultDeepFetchTop        = 3
+	maxDeepFetchTop            = 5
+	defaultDeepMaxChars        = 3000
+)
+
+// defaultSearchProviderOrder 未显式配置 providers 时的尝试顺序（仅包含已配置的后端，DuckDuckGo 兜底）
+var defaultSearchProviderOrder = []string{
+	SearchProviderBrave,
+	SearchProviderTavily,
+	SearchProviderSerper,
+	SearchProviderBing,
+	SearchProviderGoogle,
+	SearchProviderSearXNG,
+	SearchProviderDuckDuckGo,
+}
+
+// SearchResult 单条搜索结果
+type SearchResult struct {
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Snippet  string `json:"snippet,omitempty"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// SearchProvider 搜索后端
+type SearchProvider interface {
+	Name() string
+	Search(ctx context.Context, query string, count int) ([]SearchResult, error)
+}
+
+// SearchBackendOptions 单个后端的凭据与地址
+type SearchBackendOptions struct {
+	APIKey string
+	// BaseURL 覆盖 API 地址；SearXNG 为实例地址
+	BaseURL string
+	// EngineID Google Programmable Search 的 cx
+	EngineID string
+}
+
+// WebSearchOptions 网页搜索选项
+type WebSearchOptions struct {
+	// Providers 按顺序尝试的后端；为空时按默认顺序使用所有已配置的后端并以 DuckDuckGo 兜底
+	Providers    []string
+	Backends     map[string]SearchBackendOptions
+	MaxResults   int
+	TimeoutSec   int
+	UserAgent    string
+	DeepFetchTop int
+	DeepMaxChars int
+}
+
+// normalizeSearchProvider 统一后端名称（ddg / searx / google_pse 等别名）
+func normalizeSearchProvider(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "brave":
+		return SearchProviderBrave
+	case "searxng", "searx":
+		return SearchProviderSearXNG
+	case "tavily":
+		return SearchProviderTavily
+	case "bing":
+		return SearchProviderBing
+	case "google", "google_pse", "googlepse", "pse":
+		return SearchProviderGoogle
+	case "serper":
+		return SearchProviderSerper
+	case "duckduckgo", "ddg":
+		return SearchProviderDuckDuckGo
+	default:
+		return ""
+	}
+}
+
+// ValidateWebSearchOptions 检查 providers 中的后端名称
+func ValidateWebSearchOptions(opts WebSearchOptions) error {
+	for _, name := range opts.Providers {
+		if normalizeSearchProvider(name) == "" {
+			return fmt.Errorf("unknown search provider %q", name)
+		}
+	}
+	for name := range opts.Backends {
+		if normalizeSearchProvider(name) == "" {
+			return fmt.Errorf("unknown search provider %q", name)
+		}
+	}
+	return nil
+}
+
+func (o WebSearchOptions) backend(name string) SearchBackendOptions {
+	if b, ok := o.Backends[name]; ok {
+		return b
+	}
+	for key, b := range o.Backends {
+		if normalizeSearchProvider(key) == name {
+			return b
+		}
+	}
+	return SearchBackendOptions{}
+}
+
+// searchBackendConfigured 后端是否具备所需凭据
+func searchBackendConfigured(name string, b SearchBackendOptions) bool {
+	switch name {
+	case SearchProviderBrave, SearchProviderTavily, SearchProviderBing, SearchProviderSerper:
+		return strings.TrimSpace(b.APIKey) != ""
+	case SearchProviderGoogle:
+		return strings.TrimSpace(b.APIKey) != "" && strings.TrimSpace(b.EngineID) != ""
+	case SearchProviderSearXNG:
+		return strings.TrimSpace(b.BaseURL) != ""
+	case SearchProviderDuckDuckGo:
+		return true
+	default:
+		return false
+	}
+}
+
+// ResolveSearchProviders 返回实际尝试的后端顺序（跳过未配置的后端）
+func ResolveSearchProviders(opts WebSearchOptions) []string {
+	order := defaultSearchProviderOrder
+	if len(opts.Providers) > 0 {
+		order = opts.Providers
+	}
+	seen := make(map[string]bool, len(order))
+	resolved := make([]string, 0, len(order))
+	for _, raw := range order {
+		name := normalizeSearchProvider(raw)
+		if name == "" || seen[name] || !searchBackendConfigured(name, opts.backend(name)) {
+			continue
+		}
+		seen[name] = true
+		resolved = append(resolved, name)
+	}
+	return resolved
+}
+
+// NewSearchProvider 创建指定名称的搜索后端
+func NewSearchProvider(name string, b SearchBackendOptions, client *http.Client, userAgent string) (SearchProvider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: defaultWebSearchTimeoutSec * time.Second}
+	}
+	name = normalizeSearchProvider(name)
+	if name == "" {
+		return nil, fmt.Errorf("unknown search provider")
+	}
+	if !searchBackendConfigured(name, b) {
+		return nil, fmt.Errorf("search provider %s is not configured", name)
+	}
+	base := httpSearchBackend{name: name, opts: b, client: client, userAgent: userAgent}
+	switch name {
+	case SearchProviderBrave:
+		return &braveSearch{base}, nil
+	case SearchProviderSearXNG:
+		return &searxngSearch{base}, nil
+	case SearchProviderTavily:
+		return &tavilySearch{base}, nil
+	case SearchProviderBing:
+		return &bingSearch{base}, nil
+	case SearchProviderGoogle:
+		return &googleSearch{base}, nil
+	case SearchProviderSerper:
+		return &serperSearch{base}, nil
+	default:
+		return &duckDuckGoSearch{base}, nil
+	}
+}
+
+// WebSearchTool 网页搜索工具
+type WebSearchTool struct {
+	BaseTool
+	options WebSearchOptions
+	client  *http.Client
+	// fetcher 深度模式下抓取结果页面（通常为 web_fetch 工具）
+	fetcher Tool
+}
+
+// NewWebSearchTool 创建仅使用 Brave（无 key 时回退 DuckDuckGo）的网页搜索工具
+func NewWebSearchTool(apiKey string, maxResults int) *WebSearchTool {
+	return NewWebSearchToolWithOptions(WebSearchOptions{
+		Backends:   map[string]SearchBackendOptions{SearchProviderBrave: {APIKey: apiKey}},
+		MaxResults: maxResults,
+	}, nil)
+}
+
+// NewWebSearchToolWithOptions 创建多后端网页搜索工具；fetcher 为空时深度模式使用默认 HTTP 抓取
+func NewWebSearchToolWithOptions(opts WebSearchOptions, fetcher Tool) *WebSearchTool {
+	if opts.MaxResults <= 0 {
+		opts.MaxResults = 5
+	}
+	if opts.TimeoutSec <= 0 {
+		opts.TimeoutSec = defaultWebSearchTimeoutSec
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = defaultWebFetchUserAgent
+	}
+	if opts.DeepFetchTop <= 0 {
+		opts.DeepFetchTop = defaultDeepFetchTop
+	}
+	if opts.DeepMaxChars <= 0 {
+		opts.DeepMaxChars = defaultDeepMaxChars
+	}
+	if fetcher == nil {
+		fetcher = NewWebFetchTool(WebFetchOptions{UserAgent: opts.UserAgent, TimeoutSec: opts.TimeoutSec})
+	}
+
+	return &WebSearchTool{
+		BaseTool: BaseTool{
+			name: "web_search",
+			description: "Search the web. Use for finding current information, news, or research topics. " +
+				"Set deep=true to also fetch and return the text of the top results.",
+			parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Search query",
+						"minLength":   1,
+						"maxLength":   500,
+					},
+					"count": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of results to return (1-10)",
+						"minimum":     1,
+						"maximum":     10,
+					},
+					"deep": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Fetch the top results and include their page text (default: false)",
+					},
+					"fetch_top": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of results to fetch in deep mode (1-5)",
+						"minimum":     1,
+						"maximum":     maxDeepFetchTop,
+					},
+					"provider": map[string]interface{}{
+						"type":        "string",
+						"description": "Use a specific configured search backend instead of the fallback order",
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+		options: opts,
+		client:  &http.Client{Timeout: time.Duration(opts.TimeoutSec) * time.Second},
+		fetcher: fetcher,
+	}
+}
+
+// Execute 执行网页搜索
+func (t *WebSearchTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	query, _ := params["query"].(string)
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+
+	count := t.options.MaxResults
+	if v, ok := params["count"].(float64); ok {
+		c := int(v)
+		if c > 0 && c <= 10 {
+			count = c
+		}
+	}
+
+	providers := ResolveSearchProviders(t.options)
+	if requested, _ := params["provider"].(string); strings.TrimSpace(requested) != "" {
+		name := normalizeSearchProvider(requested)
+		if name == "" || !searchBackendConfigured(name, t.options.backend(name)) {
+			return "", fmt.Errorf("search provider %q is not configured", requested)
+		}
+		providers = []string{name}
+	}
+	if len(providers) == 0 {
+		return "", fmt.Errorf("no web search provider configured")
+	}
+
+	results, used, failures, err := t.search(ctx, providers, query, count)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "No results found for: " + query, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Search results for: %s (via %s)\n\n", query, strings.Join(used, ", ")))
+	for i, r := range results {
+		sb.WriteString(fmt.Sprintf("%d. %s\n   URL: %s\n   %s\n\n", i+1, r.Title, r.URL, r.Snippet))
+	}
+	if len(failures) > 0 {
+		sb.WriteString(fmt.Sprintf("(skipped providers: %s)\n\n", strings.Join(failures, "; ")))
+	}
+
+	if deep, _ := params["deep"].(bool); deep {
+		top := t.options.DeepFetchTop
+		if v, ok := params["fetch_top"].(float64); ok && int(v) > 0 {
+			top = int(v)
+		}
+		if top > maxDeepFetchTop {
+			top = maxDeepFetchTop
+		}
+		sb.WriteString(t.fetchPages(ctx, results, top))
+	}
+
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// search 按顺序尝试后端，结果不足 count 时继续下一个后端并去重合并
+func (t *WebSearchTool) search(ctx context.Context, providers []string, query string, count int) ([]SearchResult, []string, []string, error) {
+	var (
+		results []SearchResult
+		used    []string
+		errs    []string
+	)
+	seen := make(map[string]bool)
+	for _, name := range providers {
+		provider, err := NewSearchProvider(name, t.options.backend(name), t.client, t.options.UserAgent)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		found, err := provider.Search(ctx, query, count)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		added := 0
+		for _, r := range found {
+			key := normalizeResultURL(r.URL)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			r.Provider = name
+			results = append(results, r)
+			added++
+			if len(results) >= count {
+				break
+			}
+		}
+		if added > 0 {
+			used = append(used, name)
+		}
+		if len(results) >= count {
+			break
+		}
+	}
+	if len(results) == 0 && len(errs) > 0 {
+		return nil, nil, nil, fmt.Errorf("web search failed: %s", strings.Join(errs, "; "))
+	}
+	return results, used, errs, nil
+}
+
+// fetchPages 并发抓取前 top 条结果的正文
+func (t *WebSearchTool) fetchPages(ctx context.Context, results []SearchResult, top int) string {
+	if top > len(results) {
+		top = len(results)
+	}
+	pages := make([]string, top)
+	var wg sync.WaitGroup
+	for i := 0; i < top; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			text, err := t.fetcher.Execute(ctx, map[string]interface{}{
+				"url":        results[i].URL,
+				"max_length": float64(t.options.DeepMaxChars),
+			})
+			if err != nil {
+				text = fmt.Sprintf("(fetch failed: %v)", err)
+			}
+			pages[i] = fmt.Sprintf("## [%d] %s\nURL: %s\n\n%s\n\n", i+1, results[i].Title, results[i].URL, strings.TrimSpace(text))
+		}(i)
+	}
+	wg.Wait()
+	return "--- Page contents ---\n\n" + strings.Join(pages, "")
+}
+
+// normalizeResultURL 生成用于去重的 URL 键：忽略协议、www.、片段、跟踪参数与末尾斜杠
+func normalizeResultURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(raw)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "utm_") || lower == "fbclid" || lower == "gclid" || lower == "ref" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var qs []string
+	for _, key := range keys {
+		for _, v := range query[key] {
+			qs = append(qs, key+"="+v)
+		}
+	}
+	key := host + strings.TrimRight(u.EscapedPath(), "/")
+	if len(qs) > 0 {
+		key += "?" + strings.Join(qs, "&")
+	}
+	return key
+}
+
+// httpSearchBackend 各后端共用的 HTTP 请求逻辑
+type httpSearchBackend struct {
+	name      string
+	opts      SearchBackendOptions
+	client    *http.Client
+	userAgent string
+}
+
+func (b httpSearchBackend) Name() string { return b.name }
+
+func (b httpSearchBackend) endpoint(defaultURL string) string {
+	if base := strings.TrimSpace(b.opts.BaseURL); base != "" {
+		return base
+	}
+	return defaultURL
+}
+
+// do 发送请求并读取响应体（最多 2MB）
+func (b httpSearchBackend) do(req *http.Request) ([]byte, error) {
+	if req.Header.Get("User-Agent") == "" && b.userAgent != "" {
+		req.Header.Set("User-Agent", b.userAgent)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, truncateText(strings.TrimSpace(string(body)), 200))
+	}
+	return body, nil
+}
+
+func (b httpSearchBackend) getJSON(ctx context.Context, endpoint string, query url.Values, headers map[string]string, out interface{}) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	q := u.Query()
+	for key, values := range query {
+		for _, v := range values {
+			q.Add(key, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, v := range headers {
+		req.Header.Set(key, v)
+	}
+	body, err := b.do(req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse search result: %w", err)
+	}
+	return nil
+}
+
+func (b httpSearchBackend) postJSON(ctx context.Context, endpoint string, payload interface{}, headers map[string]string, out interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for key, v := range headers {
+		req.Header.Set(key, v)
+	}
+	body, err := b.do(req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse search result: %w", err)
+	}
+	return nil
+}
+
+// braveSearch Brave Search API
+type braveSearch struct{ httpSearchBackend }
+
+func (b *braveSearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	var result struct {
+		Web struct {
+			Results []struct {
+				Title string `json:"title"`
+				URL   string `json:"url"`
+				Desc  string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	err := b.getJSON(ctx, b.endpoint("https://api.search.brave.com/res/v1/web/search"),
+		url.Values{"q": {query}, "count": {fmt.Sprint(count)}},
+		map[string]string{"X-Subscription-Token": b.opts.APIKey}, &result)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SearchResult, 0, len(result.Web.Results))
+	for _, r := range result.Web.Results {
+		out = append(out, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Desc})
+	}
+	return out, nil
+}
+
+// searxngSearch 自建 SearXNG 实例（需开启 json 输出格式）
+type searxngSearch struct{ httpSearchBackend }
+
+func (b *searxngSearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	endpoint := strings.TrimRight(b.endpoint(""), "/")
+	if !strings.HasSuffix(endpoint, "/search") {
+		endpoint += "/search"
+	}
+	headers := map[string]string{}
+	if b.opts.APIKey != "" {
+		headers["Authorization"] = "Bearer " + b.opts.APIKey
+	}
+	var result struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := b.getJSON(ctx, endpoint, url.Values{"q": {query}, "format": {"json"}}, headers, &result); err != nil {
+		return nil, err
+	}
+	out := make([]SearchResult, 0, len(result.Results))
+	for _, r := range result.Results {
+		out = append(out, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Content})
+		if len(out) >= count {
+			break
+		}
+	}
+	return out, nil
+}
+
+// tavilySearch Tavily Search API
+type tavilySearch struct{ httpSearchBackend }
+
+func (b *tavilySearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	var result struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	err := b.postJSON(ctx, b.endpoint("https://api.tavily.com/search"),
+		map[string]interface{}{"query": query, "max_results": count},
+		map[string]string{"Authorization": "Bearer " + b.opts.APIKey}, &result)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SearchResult, 0, len(result.Results))
+	for _, r := range result.Results {
+		out = append(out, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return out, nil
+}
+
+// bingSearch Bing Web Search API
+type bingSearch struct{ httpSearchBackend }
+
+func (b *bingSearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	var result struct {
+		WebPages struct {
+			Value []struct {
+				Name    string `json:"name"`
+				URL     string `json:"url"`
+				Snippet string `json:"snippet"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	err := b.getJSON(ctx, b.endpoint("https://api.bing.microsoft.com/v7.0/search"),
+		url.Values{"q": {query}, "count": {fmt.Sprint(count)}},
+		map[string]string{"Ocp-Apim-Subscription-Key": b.opts.APIKey}, &result)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SearchResult, 0, len(result.WebPages.Value))
+	for _, r := range result.WebPages.Value {
+		out = append(out, SearchResult{Title: r.Name, URL: r.URL, Snippet: r.Snippet})
+	}
+	return out, nil
+}
+
+// googleSearch Google Programmable Search (Custom Search JSON API)
+type googleSearch struct{ httpSearchBackend }
+
+func (b *googleSearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	var result struct {
+		Items []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"items"`
+	}
+	err := b.getJSON(ctx, b.endpoint("https://www.googleapis.com/customsearch/v1"),
+		url.Values{"key": {b.opts.APIKey}, "cx": {b.opts.EngineID}, "q": {query}, "num": {fmt.Sprint(count)}},
+		nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SearchResult, 0, len(result.Items))
+	for _, r := range result.Items {
+		out = append(out, SearchResult{Title: r.Title, URL: r.Link, Snippet: r.Snippet})
+	}
+	return out, nil
+}
+
+// serperSearch Serper.dev Google Search API
+type serperSearch struct{ httpSearchBackend }
+
+func (b *serperSearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	var result struct {
+		Organic []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"organic"`
+	}
+	err := b.postJSON(ctx, b.endpoint("https://google.serper.dev/search"),
+		map[string]interface{}{"q": query, "num": count},
+		map[string]string{"X-API-KEY": b.opts.APIKey}, &result)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SearchResult, 0, len(result.Organic))
+	for _, r := range result.Organic {
+		out = append(out, SearchResult{Title: r.Title, URL: r.Link, Snippet: r.Snippet})
+	}
+	return out, nil
+}
+
+var (
+	ddgResultLinkRe = regexp.MustCompile(`(?is)<a[^>]+class="[^"]*result__a[^"]*"[^>]*href="([^"]+)"[^>]*>(.*?)</a>`)
+	ddgSnippetRe    = regexp.MustCompile(`(?is)<(?:a|div|td)[^>]+class="[^"]*result__snippet[^"]*"[^>]*>(.*?)</(?:a|div|td)>`)
+	htmlTagRe       = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// duckDuckGoSearch 无需 key 的 DuckDuckGo HTML 结果页解析
+type duckDuckGoSearch struct{ httpSearchBackend }
+
+func (b *duckDuckGoSearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	form := url.Values{"q": {query}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint("https://html.duckduckgo.com/html/"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "text/html")
+	body, err := b.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return parseDuckDuckGoHTML(string(body), count), nil
+}
+
+func parseDuckDuckGoHTML(page string, count int) []SearchResult {
+	links := ddgResultLinkRe.FindAllStringSubmatchIndex(page, -1)
+	out := make([]SearchResult, 0, len(links))
+	for i, m := range links {
+		href := html.UnescapeString(page[m[2]:m[3]])
+		target := decodeDuckDuckGoRedirect(href)
+		if target == "" {
+			continue
+		}
+		// 摘要位于当前链接与下一条结果之间
+		end := len(page)
+		if i+1 < len(links) {
+			end = links[i+1][0]
+		}
+		snippet := ""
+		if sm := ddgSnippetRe.FindStringSubmatch(page[m[1]:end]); sm != nil {
+			snippet = cleanHTMLFragment(sm[1])
+		}
+		out = append(out, SearchResult{
+			Title:   cleanHTMLFragment(page[m[4]:m[5]]),
+			URL:     target,
+			Snippet: snippet,
+		})
+		if len(out) >= count {
+			break
+		}
+	}
+	return out
+}
+
+// decodeDuckDuckGoRedirect 还原 //duckduckgo.com/l/?uddg=<url> 跳转链接，并过滤广告
+func decodeDuckDuckGoRedirect(href string) string {
+	if strings.HasPrefix(href, "//") {
+		href = "https:" + href
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if strings.HasSuffix(u.Host, "duckduckgo.com") {
+		if u.Path == "/y.js" {
+			return ""
+		}
+		if target := u.Query().Get("uddg"); target != "" {
+			return target
+		}
+		return ""
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+func cleanHTMLFragment(s string) string {
+	s = htmlTagRe.ReplaceAllString(s, "")
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
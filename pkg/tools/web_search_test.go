@@ -0,0 +1,224 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestSearchProvidersParseResponses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/brave":
+			assert.Equal(t, "brave-key", r.Header.Get("X-Subscription-Token"))
+			assert.Equal(t, "golang", r.URL.Query().Get("q"))
+			writeTestJSON(w, map[string]interface{}{"web": map[string]interface{}{"results": []map[string]string{
+				{"title": "Brave Go", "url": "https://go.dev/", "description": "brave snippet"},
+			}}})
+		case "/searxng/search":
+			assert.Equal(t, "json", r.URL.Query().Get("format"))
+			writeTestJSON(w, map[string]interface{}{"results": []map[string]string{
+				{"title": "Searx Go", "url": "https://go.dev/", "content": "searx snippet"},
+			}})
+		case "/tavily":
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "Bearer tavily-key", r.Header.Get("Authorization"))
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "golang", body["query"])
+			writeTestJSON(w, map[string]interface{}{"results": []map[string]string{
+				{"title": "Tavily Go", "url": "https://go.dev/", "content": "tavily snippet"},
+			}})
+		case "/bing":
+			assert.Equal(t, "bing-key", r.Header.Get("Ocp-Apim-Subscription-Key"))
+			writeTestJSON(w, map[string]interface{}{"webPages": map[string]interface{}{"value": []map[string]string{
+				{"name": "Bing Go", "url": "https://go.dev/", "snippet": "bing snippet"},
+			}}})
+		case "/google":
+			assert.Equal(t, "google-key", r.URL.Query().Get("key"))
+			assert.Equal(t, "cx-1", r.URL.Query().Get("cx"))
+			writeTestJSON(w, map[string]interface{}{"items": []map[string]string{
+				{"title": "Google Go", "link": "https://go.dev/", "snippet": "google snippet"},
+			}})
+		case "/serper":
+			assert.Equal(t, "serper-key", r.Header.Get("X-API-KEY"))
+			writeTestJSON(w, map[string]interface{}{"organic": []map[string]string{
+				{"title": "Serper Go", "link": "https://go.dev/", "snippet": "serper snippet"},
+			}})
+		case "/ddg":
+			assert.Equal(t, http.MethodPost, r.Method)
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "golang", r.PostForm.Get("q"))
+			fmt.Fprint(w, `<div class="result"><a rel="nofollow" class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2F&amp;rut=x">DDG <b>Go</b></a>
+<a class="result__snippet" href="#">ddg &amp; snippet</a></div>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		name    string
+		backend SearchBackendOptions
+		title   string
+	}{
+		{SearchProviderBrave, SearchBackendOptions{APIKey: "brave-key", BaseURL: srv.URL + "/brave"}, "Brave Go"},
+		{SearchProviderSearXNG, SearchBackendOptions{BaseURL: srv.URL + "/searxng"}, "Searx Go"},
+		{SearchProviderTavily, SearchBackendOptions{APIKey: "tavily-key", BaseURL: srv.URL + "/tavily"}, "Tavily Go"},
+		{SearchProviderBing, SearchBackendOptions{APIKey: "bing-key", BaseURL: srv.URL + "/bing"}, "Bing Go"},
+		{SearchProviderGoogle, SearchBackendOptions{APIKey: "google-key", EngineID: "cx-1", BaseURL: srv.URL + "/google"}, "Google Go"},
+		{SearchProviderSerper, SearchBackendOptions{APIKey: "serper-key", BaseURL: srv.URL + "/serper"}, "Serper Go"},
+		{SearchProviderDuckDuckGo, SearchBackendOptions{BaseURL: srv.URL + "/ddg"}, "DDG Go"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, err := NewSearchProvider(tc.name, tc.backend, srv.Client(), "test-agent")
+			require.NoError(t, err)
+			results, err := provider.Search(context.Background(), "golang", 5)
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			assert.Equal(t, tc.title, results[0].Title)
+			assert.Equal(t, "https://go.dev/", results[0].URL)
+			assert.NotEmpty(t, results[0].Snippet)
+		})
+	}
+}
+
+func TestNewSearchProviderRequiresCredentials(t *testing.T) {
+	_, err := NewSearchProvider(SearchProviderGoogle, SearchBackendOptions{APIKey: "k"}, nil, "")
+	assert.Error(t, err, "google needs an engine id")
+	_, err = NewSearchProvider(SearchProviderSearXNG, SearchBackendOptions{}, nil, "")
+	assert.Error(t, err)
+	_, err = NewSearchProvider("yahoo", SearchBackendOptions{}, nil, "")
+	assert.Error(t, err)
+	_, err = NewSearchProvider("ddg", SearchBackendOptions{}, nil, "")
+	assert.NoError(t, err)
+}
+
+func TestResolveSearchProviders(t *testing.T) {
+	opts := WebSearchOptions{Backends: map[string]SearchBackendOptions{
+		"searxng": {BaseURL: "http://searx.local"},
+		"brave":   {APIKey: "k"},
+		"bing":    {},
+	}}
+	assert.Equal(t, []string{"brave", "searxng", "duckduckgo"}, ResolveSearchProviders(opts))
+
+	opts.Providers = []string{"searx", "bing", "brave"}
+	assert.Equal(t, []string{"searxng", "brave"}, ResolveSearchProviders(opts), "explicit order, unconfigured skipped, no implicit fallback")
+
+	assert.Error(t, ValidateWebSearchOptions(WebSearchOptions{Providers: []string{"altavista"}}))
+	assert.NoError(t, ValidateWebSearchOptions(opts))
+}
+
+func TestWebSearchToolFallbackAndDedup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/brave":
+			http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+		case "/searxng/search":
+			writeTestJSON(w, map[string]interface{}{"results": []map[string]string{
+				{"title": "One", "url": "https://example.com/a"},
+				{"title": "One again", "url": "http://www.example.com/a/?utm_source=x#top"},
+				{"title": "Two", "url": "https://example.com/b"},
+			}})
+		case "/ddg":
+			fmt.Fprint(w, `<a class="result__a" href="https://example.com/b">Two dup</a>
+<a class="result__a" href="https://example.com/c">Three</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tool := NewWebSearchToolWithOptions(WebSearchOptions{
+		Providers: []string{"brave", "searxng", "duckduckgo"},
+		Backends: map[string]SearchBackendOptions{
+			"brave":      {APIKey: "k", BaseURL: srv.URL + "/brave"},
+			"searxng":    {BaseURL: srv.URL + "/searxng"},
+			"duckduckgo": {BaseURL: srv.URL + "/ddg"},
+		},
+		MaxResults: 3,
+	}, nil)
+
+	out, err := tool.Execute(context.Background(), map[string]interface{}{"query": "example"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "(via searxng, duckduckgo)")
+	assert.Contains(t, out, "1. One\n")
+	assert.Contains(t, out, "2. Two\n")
+	assert.Contains(t, out, "3. Three\n")
+	assert.NotContains(t, out, "One again")
+	assert.NotContains(t, out, "Two dup")
+	assert.Contains(t, out, "brave: status 429")
+
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"query": "example", "provider": "bing"})
+	assert.Error(t, err)
+}
+
+func TestWebSearchToolAllProvidersFail(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	tool := NewWebSearchToolWithOptions(WebSearchOptions{
+		Providers: []string{"searxng"},
+		Backends:  map[string]SearchBackendOptions{"searxng": {BaseURL: srv.URL}},
+	}, nil)
+	_, err := tool.Execute(context.Background(), map[string]interface{}{"query": "x"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "searxng: status 502")
+}
+
+func TestWebSearchToolDeepMode(t *testing.T) {
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search":
+			writeTestJSON(w, map[string]interface{}{"results": []map[string]string{
+				{"title": "Page A", "url": srvURL + "/page/a"},
+				{"title": "Page B", "url": srvURL + "/page/b"},
+				{"title": "Page C", "url": srvURL + "/page/c"},
+			}})
+		case "/page/a", "/page/b", "/page/c":
+			w.Header().Set("Content-Type", "text/html")
+			name := strings.TrimPrefix(r.URL.Path, "/page/")
+			fmt.Fprintf(w, "<html><body><p>Body of page %s with enough text to look like a real article.</p></body></html>", name)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	tool := NewWebSearchToolWithOptions(WebSearchOptions{
+		Providers: []string{"searxng"},
+		Backends:  map[string]SearchBackendOptions{"searxng": {BaseURL: srv.URL}},
+	}, nil)
+	out, err := tool.Execute(context.Background(), map[string]interface{}{
+		"query":     "pages",
+		"deep":      true,
+		"fetch_top": float64(2),
+	})
+	require.NoError(t, err)
+	assert.Contains(t, out, "--- Page contents ---")
+	assert.Contains(t, out, "Body of page a")
+	assert.Contains(t, out, "Body of page b")
+	assert.NotContains(t, out, "Body of page c")
+}
+
+func TestNormalizeResultURL(t *testing.T) {
+	assert.Equal(t, normalizeResultURL("https://www.Example.com/x/?b=2&a=1&utm_medium=m#f"), normalizeResultURL("http://example.com/x?a=1&b=2"))
+	assert.NotEqual(t, normalizeResultURL("https://example.com/x?id=1"), normalizeResultURL("https://example.com/x?id=2"))
+}
@@ -0,0 +1,10 @@
+package xh
+import ("testing";"strings";"time";"golang.org/x/net/html")
+func TestX(t *testing.T){
+ for _,n:=range []int{20000,40000,80000}{
+  s:=strings.Repeat("<div>",n)
+  st:=time.Now(); html.Parse(strings.NewReader(s)); t.Log(n,time.Since(st))
+  s=strings.Repeat("<script>x</script>",n)
+  st=time.Now(); html.Parse(strings.NewReader(s)); t.Log("script",n,time.Since(st))
+ }
+}
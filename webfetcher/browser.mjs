@@ -498,6 +498,26 @@ async function writeSessionState(sessionId, state) {
   await fs.writeFile(file, JSON.stringify(state, null, 2), 'utf8');
 }
 
+// proxyOptions 出站代理（gateway 的出站守卫代理）；CDP 接管的已有浏览器无法设置代理，
+// 因此有代理时不接管 CDP，改用启动的托管 profile
+// <-loopback> 让回环地址也走代理，否则 Chromium 默认直连 localhost / 127.0.0.1
+function hasProxy(req) {
+  return typeof req.proxy === 'string' && req.proxy.trim() !== '';
+}
+
+function proxyOptions(req) {
+  return hasProxy(req)
+    ? { proxy: { server: req.proxy.trim(), bypass: '<-loopback>' } }
+    : {};
+}
+
+// proxyArgs 有代理时禁止 WebRTC 走不经代理的 UDP
+function proxyArgs(req) {
+  return hasProxy(req)
+    ? ['--force-webrtc-ip-handling-policy=disable_non_proxied_udp']
+    : [];
+}
+
 function normalizeChromeConfig(raw) {
   const input = raw && typeof raw === 'object' ? raw : {};
   const profileName = sanitizeProfileName(input.profileName);
@@ -536,6 +556,7 @@ function normalizeRequest(raw) {
     maxChars: Math.min(50000, Math.max(200, asInt(raw.maxChars, DEFAULT_MAX_CHARS))),
     timeoutMs: Math.min(180000, Math.max(1000, asInt(raw.timeoutMs, DEFAULT_TIMEOUT_MS))),
     sessionId: sanitizeSessionId(raw.sessionId || 'default'),
+    proxy: typeof raw.proxy === 'string' ? raw.proxy.trim() : '',
     chrome: normalizeChromeConfig(raw.chrome),
   };
 }
@@ -569,7 +590,7 @@ async function restorePageIfNeeded(req, page, state) {
 }
 
 async function openBrowserContext(req) {
-  const chrome = req.chrome;
+  const chrome = hasProxy(req) ? { ...req.chrome, cdpEndpoint: '', autoStartCDP: false } : req.chrome;
   const warnings = [];
 
   if (chrome.cdpEndpoint) {
@@ -604,9 +625,10 @@ async function openBrowserContext(req) {
   const userDataDir = resolveChromeUserDataDir(chrome.userDataDir, chrome.profileName);
   await fs.mkdir(userDataDir, { recursive: true });
   const context = await chromium.launchPersistentContext(userDataDir, {
+    ...proxyOptions(req),
     headless: chrome.headless,
     channel: chrome.channel,
-    args: DEFAULT_CHROME_ARGS,
+    args: [...DEFAULT_CHROME_ARGS, ...proxyArgs(req)],
     viewport: { width: 1360, height: 900 },
   });
   return { mode: 'profile', browser: null, context, warnings };
@@ -1031,6 +1053,9 @@ async function main() {
   try {
     const state = await readSessionState(req.sessionId);
     if (req.action === 'open') {
+      if (hasProxy(req)) {
+        throw new Error('open is not available while the egress proxy is set');
+      }
       const result = await launchManagedBrowserWindow(req, state, []);
       await writeSessionState(req.sessionId, state);
       writeResult({ ok: true, summary: result.summary, data: result.data });
@@ -12,6 +12,7 @@ const DEFAULT_CDP_LAUNCH_TIMEOUT_MS = 15000;
 const DEFAULT_RENDER_WAIT_MS = 600;
 const DEFAULT_SMART_WAIT_MS = 4000;
 const DEFAULT_STABLE_WAIT_MS = 500;
+const MAX_HTML_CHARS = 4 * 1024 * 1024;
 const DEFAULT_CHROME_ARGS = [
   '--disable-sync',
   '--disable-background-networking',
@@ -303,6 +304,26 @@ async function ensureHostChromeCDP(chrome) {
   return { started: true };
 }
 
+// proxyOptions 出站代理（gateway 的出站守卫代理）；CDP 接管的已有浏览器无法设置代理，
+// 因此有代理时不接管 CDP，改用启动的托管 profile
+// <-loopback> 让回环地址也走代理，否则 Chromium 默认直连 localhost / 127.0.0.1
+function hasProxy(req) {
+  return typeof req.proxy === 'string' && req.proxy.trim() !== '';
+}
+
+function proxyOptions(req) {
+  return hasProxy(req)
+    ? { proxy: { server: req.proxy.trim(), bypass: '<-loopback>' } }
+    : {};
+}
+
+// proxyArgs 有代理时禁止 WebRTC 走不经代理的 UDP
+function proxyArgs(req) {
+  return hasProxy(req)
+    ? ['--force-webrtc-ip-handling-policy=disable_non_proxied_udp']
+    : [];
+}
+
 function normalizeChromeConfig(raw) {
   const input = raw && typeof raw === 'object' ? raw : {};
   const profileName = sanitizeProfileName(input.profileName);
@@ -353,6 +374,7 @@ function normalizeRequest(raw) {
     waitForSelector: typeof raw.waitForSelector === 'string' ? raw.waitForSelector.trim() : '',
     waitForText: typeof raw.waitForText === 'string' ? raw.waitForText.trim() : '',
     waitForNoText: typeof raw.waitForNoText === 'string' ? raw.waitForNoText.trim() : '',
+    proxy: typeof raw.proxy === 'string' ? raw.proxy.trim() : '',
     chrome: normalizeChromeConfig(raw.chrome),
   };
 }
@@ -487,13 +509,20 @@ async function readPage(page, req) {
     return { title: pageTitle, text: merged };
   });
 
-  return { url: req.url, title: normalizeText(title), text: normalizeText(text) };
+  // 原始 HTML 交给 Go 侧统一做正文提取与 Markdown 转换
+  const html = await page.content().catch(() => '');
+  return {
+    url: page.url() || req.url,
+    title: normalizeText(title),
+    text: normalizeText(text),
+    html: html.length > MAX_HTML_CHARS ? html.slice(0, MAX_HTML_CHARS) : html,
+  };
 }
 
 async function fetchWithBrowserMode(req) {
   let browser;
   try {
-    browser = await chromium.launch({ headless: true });
+    browser = await chromium.launch({ headless: true, ...proxyOptions(req), args: proxyArgs(req) });
     const context = await browser.newContext(browserContextOptions(req));
     const page = await context.newPage();
     return await readPage(page, req);
@@ -533,9 +562,10 @@ async function fetchWithChromeProfile(req, chrome) {
   try {
     context = await chromium.launchPersistentContext(userDataDir, {
       ...browserContextOptions(req),
+      ...proxyOptions(req),
       channel: chrome.channel,
       headless: chrome.headless,
-      args: DEFAULT_CHROME_ARGS,
+      args: [...DEFAULT_CHROME_ARGS, ...proxyArgs(req)],
     });
     const page = await context.newPage();
     return await readPage(page, req);
@@ -547,7 +577,7 @@ async function fetchWithChromeProfile(req, chrome) {
 }
 
 async function fetchWithChromeMode(req) {
-  const chrome = req.chrome;
+  const chrome = hasProxy(req) ? { ...req.chrome, cdpEndpoint: '', autoStartCDP: false } : req.chrome;
   const warnings = [];
 
   if (chrome.cdpEndpoint) {
@@ -624,7 +654,7 @@ async function main() {
       });
       return;
     }
-    writeResult({ ok: true, url, title, text });
+    writeResult({ ok: true, url: result.url || url, title, text, html: result.html || '' });
   } catch (err) {
     const message = errorMessage(err);
     if (normalized.mode === 'chrome' && normalized.chrome.cdpEndpoint) {
@@ -44,10 +44,23 @@ type SessionSummary = {
   lastMessage?: string;
 };
 
+type TimelineActivity = {
+  type: string;
+  summary: string;
+  detail?: string;
+};
+
+type TimelineEntry = {
+  kind: string;
+  activity?: TimelineActivity;
+  text?: string;
+};
+
 type SessionMessage = {
   role: string;
   content: string;
   timestamp: string;
+  timeline?: TimelineEntry[];
 };
 
 type SessionDetail = {
@@ -55,6 +68,30 @@ type SessionDetail = {
   messages: SessionMessage[];
 };
 
+type AgentProfile = {
+  name: string;
+  description?: string;
+  model: string;
+  workspace: string;
+  default: boolean;
+  active: boolean;
+};
+
+type ProcessInfo = {
+  id: string;
+  kind: string;
+  name?: string;
+  agent: string;
+  sessionKey: string;
+  command: string;
+  backend: string;
+  pid: number;
+  running: boolean;
+  exitCode?: number;
+  startedAt: string;
+  outputBytes: number;
+};
+
 async function fetchJSON<T>(url: string, options?: RequestInit): Promise<T> {
   const res = await fetch(url, {
     headers: { 'Content-Type': 'application/json' },
@@ -79,6 +116,7 @@ const translations = {
     statusTab: 'Status',
     chatTab: 'Chat',
     sessionsTab: 'Sessions',
+    processesTab: 'Processes',
     settingsTab: 'Settings',
     workspace: 'Workspace',
     model: 'Model',
@@ -94,10 +132,16 @@ const translations = {
     qrWaiting: 'QR appears here when bridge is ready',
     telegramQrWaiting: 'QR appears after token is verified',
     sessionLabel: 'Session',
+    agentLabel: 'Agent',
     noMessages: 'No messages yet.',
     sendPlaceholder: 'Send a message to maxclaw...',
     send: 'Send',
     noSessions: 'No sessions found.',
+    noProcesses: 'No background processes or shells.',
+    killProcess: 'Kill',
+    showOutput: 'Output',
+    running: 'running',
+    exited: 'exited',
     messages: 'messages',
     workspaceHint: 'Changes require gateway restart.',
     save: 'Save',
@@ -123,6 +167,7 @@ const translations = {
     statusTab: '状态',
     chatTab: '对话',
     sessionsTab: '会话',
+    processesTab: '进程',
     settingsTab: '设置',
     workspace: '工作区',
     model: '模型',
@@ -138,10 +183,16 @@ const translations = {
     qrWaiting: 'Bridge 就绪后二维码会显示在这里',
     telegramQrWaiting: 'Token 验证后显示二维码',
     sessionLabel: '会话',
+    agentLabel: '代理',
     noMessages: '暂无消息。',
     sendPlaceholder: '发送消息给 maxclaw...',
     send: '发送',
     noSessions: '暂无会话记录。',
+    noProcesses: '暂无后台进程或 shell。',
+    killProcess: '终止',
+    showOutput: '输出',
+    running: '运行中',
+    exited: '已退出',
     messages: '条消息',
     workspaceHint: '修改后需重启 gateway 生效。',
     save: '保存',
@@ -174,7 +225,11 @@ export default function App() {
   const [status, setStatus] = useState<Status | null>(null);
   const [sessions, setSessions] = useState<SessionSummary[]>([]);
   const [selectedSession, setSelectedSession] = useState('webui:default');
+  const [agents, setAgents] = useState<AgentProfile[]>([]);
+  const [selectedAgent, setSelectedAgent] = useState('default');
   const [sessionDetail, setSessionDetail] = useState<SessionDetail | null>(null);
+  const [processes, setProcesses] = useState<ProcessInfo[]>([]);
+  const [processOutput, setProcessOutput] = useState<{ id: string; output: string } | null>(null);
   const [message, setMessage] = useState('');
   const [loading, setLoading] = useState(false);
   const [notice, setNotice] = useState<string | null>(null);
@@ -193,13 +248,17 @@ export default function App() {
     return sessions.map((s) => ({ key: s.key, label: s.key }));
   }, [sessions]);
 
+  const agentQuery = `agent=${encodeURIComponent(selectedAgent)}`;
+
   const loadAll = async () => {
-    const [statusRes, sessionsRes, configRes] = await Promise.all([
+    const [statusRes, sessionsRes, configRes, agentsRes] = await Promise.all([
       fetchJSON<Status>('/api/status'),
-      fetchJSON<{ sessions: SessionSummary[] }>('/api/sessions'),
+      fetchJSON<{ sessions: SessionSummary[] }>(`/api/sessions?${agentQuery}`),
       fetchJSON<Record<string, unknown>>('/api/config'),
+      fetchJSON<{ agents: AgentProfile[] }>('/api/agents'),
     ]);
     setStatus(statusRes);
+    setAgents((agentsRes.agents || []).filter((a) => a.active));
     setSessions(sessionsRes.sessions || []);
     const jsonPretty = JSON.stringify(configRes, null, 2);
     setConfigText(jsonPretty);
@@ -230,21 +289,27 @@ export default function App() {
 
   useEffect(() => {
     loadAll().catch((err) => setNotice((err as Error).message));
+    refreshProcesses().catch(() => undefined);
     const timer = setInterval(() => {
       fetchJSON<Status>('/api/status')
         .then((data) => setStatus(data))
         .catch(() => undefined);
       refreshSessions().catch(() => undefined);
+      refreshProcesses().catch(() => undefined);
     }, 5000);
     return () => clearInterval(timer);
   }, []);
 
   useEffect(() => {
     if (!selectedSession) return;
-    fetchJSON<SessionDetail>(`/api/sessions/${encodeURIComponent(selectedSession)}`)
+    fetchJSON<SessionDetail>(`/api/sessions/${encodeURIComponent(selectedSession)}?${agentQuery}`)
       .then((data) => setSessionDetail(data))
       .catch((err) => setNotice((err as Error).message));
-  }, [selectedSession]);
+  }, [selectedSession, selectedAgent]);
+
+  useEffect(() => {
+    refreshSessions().catch(() => undefined);
+  }, [selectedAgent]);
 
   useEffect(() => {
     const qr = status?.whatsapp?.qr;
@@ -268,9 +333,33 @@ export default function App() {
       .catch(() => setTelegramQrDataUrl(''));
   }, [status?.telegram?.link]);
 
+  const refreshProcesses = async () => {
+    const data = await fetchJSON<{ processes: ProcessInfo[] }>('/api/processes');
+    setProcesses(data.processes || []);
+  };
+
+  const showProcessOutput = async (id: string) => {
+    try {
+      const data = await fetchJSON<{ output: string }>(`/api/processes/${encodeURIComponent(id)}/output`);
+      setProcessOutput({ id, output: data.output });
+    } catch (err) {
+      setNotice((err as Error).message);
+    }
+  };
+
+  const killProcess = async (id: string) => {
+    try {
+      await fetchJSON(`/api/processes/${encodeURIComponent(id)}/kill`, { method: 'POST' });
+      if (processOutput?.id === id) setProcessOutput(null);
+      await refreshProcesses();
+    } catch (err) {
+      setNotice((err as Error).message);
+    }
+  };
+
   const refreshSessions = async () => {
     try {
-      const data = await fetchJSON<{ sessions: SessionSummary[] }>('/api/sessions');
+      const data = await fetchJSON<{ sessions: SessionSummary[] }>(`/api/sessions?${agentQuery}`);
       setSessions(data.sessions || []);
     } catch (err) {
       setNotice((err as Error).message);
@@ -282,7 +371,7 @@ export default function App() {
     try {
       const [statusRes, sessionsRes] = await Promise.all([
         fetchJSON<Status>('/api/status'),
-        fetchJSON<{ sessions: SessionSummary[] }>('/api/sessions'),
+        fetchJSON<{ sessions: SessionSummary[] }>(`/api/sessions?${agentQuery}`),
       ]);
       setStatus(statusRes);
       setSessions(sessionsRes.sessions || []);
@@ -305,6 +394,7 @@ export default function App() {
           content: message.trim(),
           channel: 'webui',
           chatId: selectedSession,
+          agent: selectedAgent,
         }),
       });
       setMessage('');
@@ -314,7 +404,9 @@ export default function App() {
           : `Response received (${res.sessionKey})`,
       );
       await refreshSessions();
-      const detail = await fetchJSON<SessionDetail>(`/api/sessions/${encodeURIComponent(res.sessionKey)}`);
+      const detail = await fetchJSON<SessionDetail>(
+        `/api/sessions/${encodeURIComponent(res.sessionKey)}?${agentQuery}`,
+      );
       setSessionDetail(detail);
     } catch (err) {
       setNotice((err as Error).message);
@@ -485,6 +577,7 @@ export default function App() {
           <Tabs.Trigger value="status">{copy.statusTab}</Tabs.Trigger>
           <Tabs.Trigger value="chat">{copy.chatTab}</Tabs.Trigger>
           <Tabs.Trigger value="sessions">{copy.sessionsTab}</Tabs.Trigger>
+          <Tabs.Trigger value="processes">{copy.processesTab}</Tabs.Trigger>
           <Tabs.Trigger value="settings">{copy.settingsTab}</Tabs.Trigger>
         </Tabs.List>
 
@@ -567,13 +660,35 @@ export default function App() {
                   ))}
                 </select>
               </label>
+              {agents.length > 1 && (
+                <label>
+                  {copy.agentLabel}
+                  <select value={selectedAgent} onChange={(e) => setSelectedAgent(e.target.value)}>
+                    {agents.map((a) => (
+                      <option key={a.name} value={a.name} title={a.description || a.model}>
+                        {a.name} · {a.model}
+                      </option>
+                    ))}
+                  </select>
+                </label>
+              )}
             </div>
             <div className="chat-history">
               {sessionDetail?.messages?.length ? (
                 sessionDetail.messages.map((msg, idx) => (
                   <div key={idx} className={`chat-line ${msg.role}`}>
                     <span className="role">{msg.role}</span>
-                    <span className="content">{msg.content}</span>
+                    <span className="content">
+                      {msg.content}
+                      {msg.timeline
+                        ?.filter((entry) => entry.activity?.type === 'file_diff')
+                        .map((entry, diffIdx) => (
+                          <details key={diffIdx} className="file-diff">
+                            <summary>{entry.activity?.summary}</summary>
+                            <pre>{entry.activity?.detail}</pre>
+                          </details>
+                        ))}
+                    </span>
                     <span className="time">{new Date(msg.timestamp).toLocaleString()}</span>
                   </div>
                 ))
@@ -613,6 +728,34 @@ export default function App() {
           </div>
         </Tabs.Content>
 
+        <Tabs.Content value="processes" className="tab-content">
+          <div className="session-list">
+            {processes.length === 0 && <div className="empty">{copy.noProcesses}</div>}
+            {processes.map((p) => (
+              <div key={p.id} className="session-card">
+                <h4>
+                  {p.name || p.id} · {p.running ? copy.running : `${copy.exited} (${p.exitCode ?? '?'})`}
+                </h4>
+                <p>
+                  <code>{p.command}</code>
+                </p>
+                <span className="label">
+                  {p.agent} · {p.sessionKey} · {p.backend} · pid {p.pid}
+                </span>
+                <div className="process-actions">
+                  <button className="secondary small" onClick={() => showProcessOutput(p.id)}>
+                    {copy.showOutput}
+                  </button>
+                  <button className="secondary small" onClick={() => killProcess(p.id)}>
+                    {copy.killProcess}
+                  </button>
+                </div>
+              </div>
+            ))}
+          </div>
+          {processOutput && <pre className="process-output">{processOutput.output || '—'}</pre>}
+        </Tabs.Content>
+
         <Tabs.Content value="settings" className="tab-content">
           <div className="settings-layout">
             <div className="settings-side">